@@ -28,6 +28,9 @@ const DOConfigPath = ".config/digitalocean.json"
 // format specific to Mantle.
 type DOProfile struct {
 	AccessToken string `json:"token"`
+	// Spaces access keys, for uploading images
+	SpacesAccessKey string `json:"spaces_access_key,omitempty"`
+	SpacesSecretKey string `json:"spaces_secret_key,omitempty"`
 }
 
 // ReadDOConfig decodes a DigitalOcean config file, which is a custom format
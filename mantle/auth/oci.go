@@ -0,0 +1,85 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"fmt"
+	"os/user"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/ini.v1"
+)
+
+const OCIConfigPath = ".oci/config"
+
+// OCIProfile represents a profile of an Oracle Cloud Infrastructure
+// config file, as written by `oci setup config`.
+type OCIProfile struct {
+	User        string
+	Fingerprint string
+	KeyFile     string
+	Tenancy     string
+	Region      string
+}
+
+// ReadOCIConfig decodes an Oracle Cloud Infrastructure config file.
+//
+// If path is empty, $HOME/.oci/config is read.
+func ReadOCIConfig(path string) (map[string]OCIProfile, error) {
+	user, err := user.Current()
+	if err != nil {
+		return nil, err
+	}
+	if path == "" {
+		path = filepath.Join(user.HomeDir, OCIConfigPath)
+	}
+
+	cfg, err := ini.Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Profiles inherit the settings of the DEFAULT profile
+	defaults := cfg.Section(ini.DefaultSection)
+	profiles := make(map[string]OCIProfile)
+	for _, section := range cfg.Sections() {
+		if section.Name() == ini.DefaultSection && len(section.Keys()) == 0 {
+			continue
+		}
+		get := func(key string) string {
+			if section.HasKey(key) {
+				return section.Key(key).String()
+			}
+			return defaults.Key(key).String()
+		}
+		keyFile := get("key_file")
+		if strings.HasPrefix(keyFile, "~/") {
+			keyFile = filepath.Join(user.HomeDir, keyFile[2:])
+		}
+		profiles[section.Name()] = OCIProfile{
+			User:        get("user"),
+			Fingerprint: get("fingerprint"),
+			KeyFile:     keyFile,
+			Tenancy:     get("tenancy"),
+			Region:      get("region"),
+		}
+	}
+	if len(profiles) == 0 {
+		return nil, fmt.Errorf("OCI config %q contains no profiles", path)
+	}
+
+	return profiles, nil
+}
@@ -170,15 +170,16 @@ func runDevShellSSH(ctx context.Context, builder *platform.QemuBuilder, conf *co
 	signal.Notify(sigintChan, os.Interrupt)
 
 	var ip string
-	if err = util.Retry(6, 5*time.Second,
-		func() error {
-			var err error
-			ip, err = inst.SSHAddress()
-			if err != nil {
-				return err
-			}
-			return nil
-		}); err != nil {
+	err = util.Backoff{
+		Delay:      500 * time.Millisecond,
+		MaxDelay:   5 * time.Second,
+		MaxElapsed: 30 * time.Second,
+	}.Do(context.Background(), func() error {
+		var err error
+		ip, err = inst.SSHAddress()
+		return err
+	})
+	if err != nil {
 		return errors.Wrapf(err, "awaiting ssh address")
 	}
 
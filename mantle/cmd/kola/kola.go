@@ -24,6 +24,7 @@ import (
 	"sort"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/coreos/pkg/capnslog"
 	"github.com/pkg/errors"
@@ -34,6 +35,7 @@ import (
 	"github.com/coreos/coreos-assembler/mantle/harness/reporters"
 	"github.com/coreos/coreos-assembler/mantle/harness/testresult"
 	"github.com/coreos/coreos-assembler/mantle/kola"
+	"github.com/coreos/coreos-assembler/mantle/kola/events"
 	"github.com/coreos/coreos-assembler/mantle/kola/register"
 	"github.com/coreos/coreos-assembler/mantle/system"
 	"github.com/coreos/coreos-assembler/mantle/util"
@@ -114,34 +116,54 @@ This can be useful for e.g. serving locally built OSTree repos to qemu.
 		SilenceUsage: true,
 	}
 
+	cmdCleanup = &cobra.Command{
+		Use:   "cleanup [DIR...]",
+		Short: "Reap leftovers of interrupted runs",
+		Long: `Kill the QEMU processes and remove the temporary directories which
+interrupted runs leaked, as recorded in the output directories in the
+given directories (default: where output directories are created by
+default). kola run does this at startup too.
+`,
+		RunE: runCleanup,
+
+		SilenceUsage: true,
+	}
+
 	listJSON           bool
+	listDurations      bool
 	listPlatform       string
 	listDistro         string
 	httpPort           int
 	findParentImage    bool
 	qemuImageDir       string
 	qemuImageDirIsTemp bool
+	upgradeChain       []string
+	upgradeBarriers    []string
+	upgradeChainDir    string
 
 	runExternals      []string
 	runMultiply       int
 	runRerunFlag      bool
 	allowRerunSuccess string
+	runFromManifest   string
 
 	nonexclusiveWrapperMatch = regexp.MustCompile(`^non-exclusive-test-bucket-[0-9]$`)
 )
 
 func init() {
 	root.AddCommand(cmdRun)
-	cmdRun.Flags().StringArrayVarP(&runExternals, "exttest", "E", nil, "Externally defined tests (will be found in DIR/tests/kola)")
+	cmdRun.Flags().StringArrayVarP(&runExternals, "exttest", "E", nil, "Externally defined tests (will be found in DIR/tests/kola, or in /usr/lib/coreos-assembler/tests/kola of an oci://IMAGE)")
 	cmdRun.Flags().IntVar(&runMultiply, "multiply", 0, "Run the provided tests N times (useful to find race conditions)")
 	cmdRun.Flags().BoolVar(&runRerunFlag, "rerun", false, "re-run failed tests once")
 	cmdRun.Flags().StringVar(&allowRerunSuccess, "allow-rerun-success", "", "Allow kola test run to be successful when tests with given 'tags=...[,...]' pass during re-run")
+	cmdRun.Flags().StringVar(&runFromManifest, "from-manifest", "", "Run exactly the tests of an earlier run, with its build and flags, from its run-manifest.json or output directory")
 
 	root.AddCommand(cmdList)
-	cmdList.Flags().StringArrayVarP(&runExternals, "exttest", "E", nil, "Externally defined tests in directory")
+	cmdList.Flags().StringArrayVarP(&runExternals, "exttest", "E", nil, "Externally defined tests in directory, or oci://IMAGE")
 	cmdList.Flags().BoolVar(&listJSON, "json", false, "format output in JSON")
 	cmdList.Flags().StringVarP(&listPlatform, "platform", "p", "all", "filter output by platform")
 	cmdList.Flags().StringVarP(&listDistro, "distro", "b", "all", "filter output by distro")
+	cmdList.Flags().BoolVar(&listDurations, "durations", false, "include the mean duration of previous passing runs")
 
 	root.AddCommand(cmdHTTPServer)
 	cmdHTTPServer.Flags().IntVarP(&httpPort, "port", "P", 8000, "Listen on provided port")
@@ -149,12 +171,16 @@ func init() {
 	root.AddCommand(cmdRunUpgrade)
 	cmdRunUpgrade.Flags().BoolVar(&findParentImage, "find-parent-image", false, "automatically find parent image if not provided -- note on qemu, this will download the image")
 	cmdRunUpgrade.Flags().StringVar(&qemuImageDir, "qemu-image-dir", "", "directory in which to cache QEMU images if --fetch-parent-image is enabled")
+	cmdRunUpgrade.Flags().StringSliceVar(&upgradeChain, "upgrade-chain", nil, "Builds to upgrade through, oldest first, as versions on the build's stream or URLs of build directories: machines start from the first, then upgrade to each of the rest and finally the build")
+	cmdRunUpgrade.Flags().StringSliceVar(&upgradeBarriers, "upgrade-barrier", nil, "Versions in --upgrade-chain which are barrier releases; machines skip the others when a later build is available (default all of them)")
 	cmdRunUpgrade.Flags().BoolVar(&runRerunFlag, "rerun", false, "re-run failed tests once")
 	cmdRunUpgrade.Flags().StringVar(&allowRerunSuccess, "allow-rerun-success", "", "Allow kola test run to be successful when tests with given 'tags=...[,...]' pass during re-run")
 
 	root.AddCommand(cmdRerun)
 
 	root.AddCommand(cmdNcpu)
+
+	root.AddCommand(cmdCleanup)
 }
 
 func main() {
@@ -162,6 +188,13 @@ func main() {
 }
 
 func preRun(cmd *cobra.Command, args []string) error {
+	if runFromManifest != "" {
+		if err := applyRunManifest(cmd, args); err != nil {
+			return err
+		}
+	}
+	kola.RunFlags = manifestFlags(cmd)
+
 	err := syncOptions()
 	if err != nil {
 		return err
@@ -185,6 +218,12 @@ func registerExternals() error {
 		return err
 	}
 	for _, d := range runExternals {
+		if image, ok := strings.CutPrefix(d, kola.ExternalTestsImagePrefix); ok {
+			if err := kola.RegisterExternalTestsFromImage(image); err != nil {
+				return err
+			}
+			continue
+		}
 		if d == "." {
 			cwd, err := os.Getwd()
 			if err != nil {
@@ -202,7 +241,9 @@ func registerExternals() error {
 
 func runRun(cmd *cobra.Command, args []string) error {
 	var patterns []string
-	if len(args) == 0 {
+	if kola.FromManifest != nil {
+		patterns = kola.FromManifest.Tests
+	} else if len(args) == 0 {
 		patterns = []string{"*"} // run all tests by default
 	} else {
 		patterns = args
@@ -237,6 +278,21 @@ func runRerun(cmd *cobra.Command, args []string) error {
 	return kolaRunPatterns(patterns, false)
 }
 
+// startControl serves the control API if --control-address is set,
+// returning a function to stop it.
+func startControl() (func(), error) {
+	if controlAddress == "" {
+		if controlInfo != "" {
+			return nil, fmt.Errorf("--control-info requires --control-address")
+		}
+		return func() {}, nil
+	}
+	if err := kola.StartControl(controlAddress, controlInfo); err != nil {
+		return nil, err
+	}
+	return kola.StopControl, nil
+}
+
 // parseRerunSuccess converts rerun specification into a tags
 func parseRerunSuccess() ([]string, error) {
 	// In the future we may extend format to something like: <SELECTOR>[:<OPTIONS>]
@@ -274,6 +330,19 @@ func kolaRunPatterns(patterns []string, rerun bool) error {
 		return err
 	}
 
+	if eventStream != "" {
+		if err := events.Open(eventStream); err != nil {
+			return err
+		}
+		defer events.Close()
+	}
+
+	stopControl, err := startControl()
+	if err != nil {
+		return err
+	}
+	defer stopControl()
+
 	runErr := kola.RunTests(patterns, runMultiply, rerun, rerunSuccessTags, kolaPlatform, outputDir)
 
 	// needs to be after RunTests() because harness empties the directory
@@ -314,6 +383,11 @@ func writeProps() error {
 		Size   string `json:"size"`
 		Image  string `json:"image"`
 	}
+	type Equinix struct {
+		Metro         string `json:"metro"`
+		Plan          string `json:"plan"`
+		IPXEScriptURL string `json:"ipxeScriptURL"`
+	}
 	type ESX struct {
 		Server     string `json:"server"`
 		BaseVMName string `json:"base_vm_name"`
@@ -322,16 +396,38 @@ func writeProps() error {
 		Image       string `json:"image"`
 		MachineType string `json:"type"`
 	}
+	type Hetzner struct {
+		Location   string `json:"location"`
+		ServerType string `json:"serverType"`
+		Image      string `json:"image"`
+	}
+	type OCI struct {
+		Region string `json:"region"`
+		Image  string `json:"image"`
+		Shape  string `json:"shape"`
+	}
 	type OpenStack struct {
 		Region string `json:"region"`
 		Image  string `json:"image"`
 		Flavor string `json:"flavor"`
 	}
+	type PowerVS struct {
+		ServiceInstance string  `json:"serviceInstance"`
+		Image           string  `json:"image"`
+		SysType         string  `json:"sysType"`
+		Processors      float64 `json:"processors"`
+		Memory          float64 `json:"memory"`
+	}
 	type QEMU struct {
 		Image     string `json:"image"`
 		ImageSize string `json:"imageSize"`
 		Swtpm     bool   `json:"swtpm"`
 	}
+	type Vultr struct {
+		Region string `json:"region"`
+		Plan   string `json:"plan"`
+		Image  string `json:"image"`
+	}
 	return enc.Encode(&struct {
 		Cmdline     []string  `json:"cmdline"`
 		Platform    string    `json:"platform"`
@@ -341,10 +437,15 @@ func writeProps() error {
 		AWS         AWS       `json:"aws"`
 		Azure       Azure     `json:"azure"`
 		DO          DO        `json:"do"`
+		Equinix     Equinix   `json:"equinix"`
 		ESX         ESX       `json:"esx"`
 		GCP         GCP       `json:"gcp"`
+		Hetzner     Hetzner   `json:"hetzner"`
+		OCI         OCI       `json:"oci"`
 		OpenStack   OpenStack `json:"openstack"`
+		PowerVS     PowerVS   `json:"powervs"`
 		QEMU        QEMU      `json:"qemu"`
+		Vultr       Vultr     `json:"vultr"`
 	}{
 		Cmdline:     os.Args,
 		Platform:    kolaPlatform,
@@ -370,6 +471,11 @@ func writeProps() error {
 			Size:   kola.DOOptions.Size,
 			Image:  kola.DOOptions.Image,
 		},
+		Equinix: Equinix{
+			Metro:         kola.EquinixOptions.Metro,
+			Plan:          kola.EquinixOptions.Plan,
+			IPXEScriptURL: kola.EquinixOptions.IPXEScriptURL,
+		},
 		ESX: ESX{
 			Server:     kola.ESXOptions.Server,
 			BaseVMName: kola.ESXOptions.BaseVMName,
@@ -378,16 +484,38 @@ func writeProps() error {
 			Image:       kola.GCPOptions.Image,
 			MachineType: kola.GCPOptions.MachineType,
 		},
+		Hetzner: Hetzner{
+			Location:   kola.HetznerOptions.Location,
+			ServerType: kola.HetznerOptions.ServerType,
+			Image:      kola.HetznerOptions.Image,
+		},
+		OCI: OCI{
+			Region: kola.OCIOptions.Region,
+			Image:  kola.OCIOptions.Image,
+			Shape:  kola.OCIOptions.Shape,
+		},
 		OpenStack: OpenStack{
 			Region: kola.OpenStackOptions.Region,
 			Image:  kola.OpenStackOptions.Image,
 			Flavor: kola.OpenStackOptions.Flavor,
 		},
+		PowerVS: PowerVS{
+			ServiceInstance: kola.PowerVSOptions.PowerVSServiceInstance,
+			Image:           kola.PowerVSOptions.PowerVSImage,
+			SysType:         kola.PowerVSOptions.PowerVSSysType,
+			Processors:      kola.PowerVSOptions.PowerVSProcessors,
+			Memory:          kola.PowerVSOptions.PowerVSMemory,
+		},
 		QEMU: QEMU{
 			Image:     kola.QEMUOptions.DiskImage,
 			ImageSize: kola.QEMUOptions.DiskSize,
 			Swtpm:     kola.QEMUOptions.Swtpm,
 		},
+		Vultr: Vultr{
+			Region: kola.VultrOptions.Region,
+			Plan:   kola.VultrOptions.Plan,
+			Image:  kola.VultrOptions.Image,
+		},
 	})
 }
 
@@ -395,6 +523,17 @@ func runList(cmd *cobra.Command, args []string) error {
 	if err := registerExternals(); err != nil {
 		return err
 	}
+	var durations *kola.TestDurations
+	if listDurations {
+		var err error
+		path := kola.DurationsDB
+		if path == "" {
+			path = kola.DefaultDurationsDBPath()
+		}
+		if durations, err = kola.LoadTestDurations(path); err != nil {
+			return errors.Wrapf(err, "loading test durations")
+		}
+	}
 	var testlist []*item
 	for name, test := range register.Tests {
 		item := &item{
@@ -406,7 +545,22 @@ func runList(cmd *cobra.Command, args []string) error {
 			test.Distros,
 			test.ExcludeDistros,
 			test.Tags,
-			test.Description}
+			test.Description,
+			""}
+		if durations != nil {
+			var d time.Duration
+			var ok bool
+			if listPlatform == "all" {
+				d, ok = durations.LongestEstimate(name)
+			} else {
+				d, ok = durations.Estimate(listPlatform, name)
+			}
+			if ok {
+				item.Duration = d.Round(time.Second).String()
+			} else if !listJSON {
+				item.Duration = "-"
+			}
+		}
 		item.updateValues()
 		testlist = append(testlist, item)
 	}
@@ -445,7 +599,11 @@ func runList(cmd *cobra.Command, args []string) error {
 	if !listJSON {
 		var w = tabwriter.NewWriter(os.Stdout, 0, 8, 0, '\t', 0)
 
-		fmt.Fprintln(w, "Test Name\tPlatforms\tArchitectures\tDistributions\tTags")
+		if listDurations {
+			fmt.Fprintln(w, "Test Name\tPlatforms\tArchitectures\tDistributions\tTags\tDuration")
+		} else {
+			fmt.Fprintln(w, "Test Name\tPlatforms\tArchitectures\tDistributions\tTags")
+		}
 		fmt.Fprintln(w, "\t")
 		for _, item := range newtestlist {
 			fmt.Fprintf(w, "%v\n", item)
@@ -472,6 +630,7 @@ type item struct {
 	ExcludeDistros       []string `json:"-"`
 	Tags                 []string
 	Description          string
+	Duration             string `json:",omitempty"`
 }
 
 func (i *item) updateValues() {
@@ -511,6 +670,9 @@ func (i *item) updateValues() {
 }
 
 func (i item) String() string {
+	if listDurations {
+		return fmt.Sprintf("%v\t%v\t%v\t%v\t%v\t%v", i.Name, i.Platforms, i.Architectures, i.Distros, i.Tags, i.Duration)
+	}
 	return fmt.Sprintf("%v\t%v\t%v\t%v\t%v", i.Name, i.Platforms, i.Architectures, i.Distros, i.Tags)
 }
 
@@ -539,11 +701,21 @@ func preRunUpgrade(cmd *cobra.Command, args []string) error {
 	}
 
 	if findParentImage {
+		if len(upgradeChain) > 0 {
+			return errors.New("--find-parent-image and --upgrade-chain are mutually exclusive")
+		}
 		err = syncFindParentImageOptions()
 		if err != nil {
 			runUpgradeCleanup()
 			return err
 		}
+	} else if len(upgradeChain) > 0 {
+		if err := syncUpgradeChainOptions(); err != nil {
+			runUpgradeCleanup()
+			return err
+		}
+	} else if len(upgradeBarriers) > 0 {
+		return errors.New("--upgrade-barrier requires --upgrade-chain")
 	}
 
 	return nil
@@ -553,6 +725,69 @@ func runUpgradeCleanup() {
 	if qemuImageDir != "" && qemuImageDirIsTemp {
 		os.RemoveAll(qemuImageDir)
 	}
+	if upgradeChainDir != "" {
+		os.RemoveAll(upgradeChainDir)
+	}
+}
+
+// syncUpgradeChainOptions starts machines from the first build of
+// --upgrade-chain, and sets up the rest as hops.
+func syncUpgradeChainOptions() error {
+	var stream string
+	if kola.CosaBuild.Meta.BuildRef != "" {
+		stream = filepath.Base(kola.CosaBuild.Meta.BuildRef)
+	}
+	var builds []*cosa.Build
+	var urls []string
+	for _, entry := range upgradeChain {
+		baseURL := entry
+		if !strings.Contains(entry, "://") {
+			if kola.Options.Distribution != "fcos" || stream == "" {
+				return fmt.Errorf("can't find build %s of the upgrade chain on the build's stream; pass its URL", entry)
+			}
+			baseURL = fcos.GetCosaBuildURL(stream, entry, kola.Options.CosaBuildArch)
+		}
+		baseURL = strings.TrimSuffix(baseURL, "/") + "/"
+		build, err := cosa.FetchAndParseBuild(baseURL + "meta.json")
+		if err != nil {
+			return fmt.Errorf("fetching build %s of the upgrade chain: %w", entry, err)
+		}
+		builds = append(builds, build)
+		urls = append(urls, baseURL)
+	}
+
+	barriers := make(map[string]bool)
+	for _, version := range upgradeBarriers {
+		barriers[version] = true
+	}
+	var err error
+	if upgradeChainDir, err = os.MkdirTemp("/var/tmp", "kola-upgrade-chain"); err != nil {
+		return err
+	}
+	kola.UpgradeChain = nil
+	for i, build := range builds[1:] {
+		hop := kola.UpgradeHop{
+			Build: &util.LocalBuild{
+				Dir:       filepath.Join(upgradeChainDir, build.BuildID),
+				Arch:      kola.Options.CosaBuildArch,
+				Meta:      build,
+				RemoteURL: urls[i+1],
+			},
+			Barrier: len(upgradeBarriers) == 0 || barriers[build.BuildID] || barriers[build.OstreeVersion],
+		}
+		if err := os.Mkdir(hop.Build.Dir, 0777); err != nil {
+			return err
+		}
+		delete(barriers, build.BuildID)
+		delete(barriers, build.OstreeVersion)
+		kola.UpgradeChain = append(kola.UpgradeChain, hop)
+	}
+	for version := range barriers {
+		return fmt.Errorf("barrier %s isn't an upgrade hop", version)
+	}
+
+	// rhcos builds are published unsigned
+	return useStartingBuild(urls[0], builds[0], kola.Options.Distribution == "rhcos")
 }
 
 // syncFindParentImageOptions handles --find-parent-image automagic.
@@ -597,7 +832,13 @@ func syncFindParentImageOptions() error {
 	if err != nil {
 		return err
 	}
+	return useStartingBuild(parentBaseURL, parentCosaBuild, skipSignature)
+}
 
+// useStartingBuild sets the platform-specific options to start machines
+// from the build at baseURL.
+func useStartingBuild(parentBaseURL string, parentCosaBuild *cosa.Build, skipSignature bool) error {
+	var err error
 	// Here we handle the --fetch-parent-image --> platform-specific options
 	// based on its cosa build metadata
 	switch kolaPlatform {
@@ -708,6 +949,18 @@ func runRunUpgrade(cmd *cobra.Command, args []string) error {
 	return runErr
 }
 
+func runCleanup(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		args = []string{kola.DefaultOutputBaseDir()}
+	}
+	for _, dir := range args {
+		if err := kola.ReapLeftovers(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func runNcpu(cmd *cobra.Command, args []string) error {
 	count, err := system.GetProcessors()
 	if err != nil {
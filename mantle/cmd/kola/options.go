@@ -21,6 +21,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	coreosarch "github.com/coreos/stream-metadata-go/arch"
 	"github.com/coreos/stream-metadata-go/stream"
@@ -28,8 +29,13 @@ import (
 
 	"github.com/coreos/coreos-assembler/mantle/auth"
 	"github.com/coreos/coreos-assembler/mantle/fcos"
+	"github.com/coreos/coreos-assembler/mantle/harness/artifacts"
+	"github.com/coreos/coreos-assembler/mantle/harness/reporters"
 	"github.com/coreos/coreos-assembler/mantle/kola"
 	"github.com/coreos/coreos-assembler/mantle/platform"
+	"github.com/coreos/coreos-assembler/mantle/platform/api/azure"
+	"github.com/coreos/coreos-assembler/mantle/platform/conf"
+	"github.com/coreos/coreos-assembler/mantle/platform/machine/external"
 	"github.com/coreos/coreos-assembler/mantle/rhcos"
 	"github.com/coreos/coreos-assembler/mantle/system"
 	"github.com/coreos/coreos-assembler/mantle/util"
@@ -39,9 +45,22 @@ var (
 	outputDir         string
 	kolaPlatform      string
 	kolaParallelArg   string
+	eventStream       string
+	controlAddress    string
+	controlInfo       string
+	retainPolicies    []string
+	retainMaxSize     string
+	imageCacheDir     string
+	imageCacheSize    string
+	resourceTags      []string
+	requiredTags      []string
+	redactPatterns    []string
+	perfProfilePath   string
 	kolaArchitectures = []string{"amd64"}
-	kolaPlatforms     = []string{"aws", "azure", "do", "esx", "gcp", "openstack", "qemu", "qemu-iso"}
+	kolaPlatforms     = []string{"aws", "azure", "do", "equinix", "esx", "gcp", "hetzner", "oci", "openstack", "powervs", "qemu", "qemu-iso", "vultr"}
 	kolaDistros       = []string{"fcos", "rhcos", "scos"}
+	// defaultRetention applies unless overridden by --retain
+	defaultRetention = []string{"disk=on-failure", "dump=on-failure"}
 )
 
 func init() {
@@ -52,10 +71,11 @@ func init() {
 
 	// general options
 	sv(&outputDir, "output-dir", "", "Temporary output directory for test data and logs")
-	root.PersistentFlags().StringVarP(&kolaPlatform, "platform", "p", "", "VM platform: "+strings.Join(kolaPlatforms, ", "))
+	root.PersistentFlags().StringVarP(&kolaPlatform, "platform", "p", "", "VM platform: "+strings.Join(kolaPlatforms, ", ")+", or one provided by a "+external.DriverPrefix+"<platform> driver in $PATH")
 	root.PersistentFlags().StringVarP(&kola.Options.Distribution, "distro", "b", "", "Distribution: "+strings.Join(kolaDistros, ", "))
-	root.PersistentFlags().StringVarP(&kolaParallelArg, "parallel", "j", "1", "number of tests to run in parallel, or \"auto\" to match CPU count")
+	root.PersistentFlags().StringVarP(&kolaParallelArg, "parallel", "j", "1", "number of tests to run in parallel, or \"auto\" to match CPU count, lowered to what the tests' recorded durations can use")
 	sv(&kola.TAPFile, "tapfile", "", "file to write TAP results to")
+	ssv(&kola.ReportFormats, "report-format", []string{"json"}, "Report formats to write to the reports directory in addition to report.json: "+strings.Join(reporters.Formats, ", "))
 	root.PersistentFlags().BoolVarP(&kola.Options.UseWarnExitCode77, "on-warn-failure-exit-77", "", false, "Exit with code 77 if 'warn: true' tests fail")
 	sv(&kola.Options.BaseName, "basename", "kola", "Cluster name prefix")
 	ss("debug-systemd-unit", []string{}, "full-unit-name.service to enable SYSTEMD_LOG_LEVEL=debug on. Can be specified multiple times.")
@@ -64,15 +84,45 @@ func init() {
 	bv(&kola.ForceRunPlatformIndependent, "run-platform-independent", false, "Run tests that claim platform independence")
 	ssv(&kola.Tags, "tag", []string{}, "Test tag to run. Can be specified multiple times.")
 	sv(&kola.Sharding, "sharding", "", "Provide e.g. 'hash:m/n' where m and n are integers, 1 <= m <= n.  Only tests hashing to m will be run.")
+	sv(&kola.Shard, "shard", "", "Provide e.g. 'n/m' where n and m are integers, 1 <= n <= m.  Only the n-th of m duration-balanced partitions of the tests will be run.")
+	sv(&kola.ShardDurations, "shard-durations", "", "Path to a test duration database (see --durations-db) or a report.json from a previous run used to balance --shard; must be the same for all shards")
+	sv(&kola.FlakyTestsFile, "flaky-tests", "", "YAML file of test patterns to retry on failure, like kola-denylist.yaml (default \"<workdir>/src/config/kola-flaky.yaml\")")
+	sv(&kola.InstanceProfilesFile, "instance-profiles", "", "YAML file of the instance types of cloud platforms, with their memory, CPUs and nested virtualization support, from which machines needing more than the default instance type are sized (default \"<workdir>/src/config/kola-instance-profiles.yaml\")")
+	sv(&kola.WatchdogPolicyFile, "watchdog-policy", "", "YAML file setting whether SELinux denials, failed units and core dumps in machines' journals fail tests or warn, and which to ignore (default \"<workdir>/src/config/kola-watchdogs.yaml\")")
+	root.PersistentFlags().IntVar(&kola.FlakyRetries, "flaky-retries", 2, "Number of times to retry failures of flaky tests before counting them as failed")
+	sv(&kola.DurationsDB, "durations-db", "", "Path to the historical test duration database used to schedule tests (default \"<workdir>/tmp/kola/test-durations.json\")")
+	sv(&eventStream, "event-stream", "", "Write a JSON event per line describing test progress to a file, or to a listening unix socket given as 'unix:<path>'")
+	sv(&controlAddress, "control-address", "", "Serve an HTTP API for listing, destroying and gathering logs from the session's machines on this loopback address, e.g. 127.0.0.1:0")
+	sv(&controlInfo, "control-info", "", "Write the URL and token of the --control-address API as JSON to this file rather than logging them")
+	bv(&kola.FailureBundles, "failure-bundle", true, "Gather the journal, console and other diagnostics from the machines of failed tests into <test>-failure.tar.gz in their output directories")
+	bv(&kola.StreamConsoles, "stream-console", false, "Log the console lines of tests' machines with their output as they appear")
+	bv(&kola.GatherOnFailure, "gather-on-failure", false, "Run sos report or the --gather-command on the machines of failed tests and copy out what it collects")
+	sv(&kola.GatherCommand, "gather-command", "", "Command to gather diagnostics with --gather-on-failure, which must leave them in $KOLA_GATHER_DIR (default depends on the distro)")
+	ssv(&retainPolicies, "retain", nil, "Retention policy for an artifact class, as CLASS=POLICY; classes are "+artifactClasses()+", policies always, on-failure or never (default "+strings.Join(defaultRetention, ",")+", and always for the rest). Can be specified multiple times.")
+	sv(&retainMaxSize, "retain-max-size", "", "Cap on the total size of the artifacts kept in the output directory, e.g. 50G; artifacts past it are removed (default unlimited)")
+	sv(&imageCacheDir, "image-cache-dir", "", "Directory to keep decompressed copies of the build's compressed images in, shared by concurrent runs (default \"<workdir>/cache/kola-images\")")
+	sv(&imageCacheSize, "image-cache-size", "", "Cap on the size of --image-cache-dir, e.g. 50G; the least recently used images no run is using are removed past it (default unlimited)")
+	bv(&kola.RegistryTLS, "registry-tls", false, "Serve the registry fixture over HTTPS, with a CA made for it")
+	bv(&kola.RegistryAuth, "registry-auth", false, "Require credentials of clients of the registry fixture")
+	root.PersistentFlags().StringArrayVar(&kola.RegistrySeeds, "registry-seed", nil, "OCI archive to import into the registry fixture as REPO[:TAG]=PATH, tagged by its ref names without a tag. Can be specified multiple times.")
+	bv(&kola.Options.MergeJournals, "merge-journals", false, "Also write the journals of all machines of multi-machine tests, interleaved by time, to journal.txt in their output directories")
 	bv(&kola.Options.SSHOnTestFailure, "ssh-on-test-failure", false, "SSH into a machine when tests fail")
 	sv(&kola.Options.Stream, "stream", "", "CoreOS stream ID (e.g. for Fedora CoreOS: stable, testing, next)")
 	sv(&kola.Options.CosaWorkdir, "workdir", "", "coreos-assembler working directory")
 	sv(&kola.Options.CosaBuildId, "build", "", "coreos-assembler build ID (or e.g. -1, -2, for previous builds)")
 	sv(&kola.Options.CosaBuildArch, "arch", coreosarch.CurrentRpmArch(), "The target architecture of the build")
+	sv(&kola.Options.CosaBuildsURL, "builds-url", "", "URL of the builds directory in an object store, from which artifacts missing from the local build are fetched")
 	sv(&kola.Options.AppendButane, "append-butane", "", "Path to Butane config which is merged with test code")
 	sv(&kola.Options.AppendIgnition, "append-ignition", "", "Path to Ignition config which is merged with test code")
+	ssv(&resourceTags, "resource-tag", nil, "Tag, or label, as KEY=VALUE to stamp on the cloud resources created for tests. Can be specified multiple times.")
+	ssv(&requiredTags, "require-resource-tag", nil, "Key of a --resource-tag which must be given, e.g. cost-center. Can be specified multiple times.")
+	root.PersistentFlags().StringArrayVar(&redactPatterns, "redact", nil, "Regular expression matching secrets, in config strings or the contents of files, to redact from the configs written to the output directory, in addition to password hashes, private keys and credentials. Can be specified multiple times.")
+	sv(&kola.Options.UserDataURL, "userdata-url", "", "Base URL reaching --userdata-serve-address from machines, from which configs too large for a platform's userdata are served; they're given pointer configs fetching them instead and checking their SHA-512. The configs, which may hold secrets, are served over unauthenticated plain HTTP at unguessable paths, so only use a network you trust")
+	sv(&kola.Options.UserDataServeAddress, "userdata-serve-address", "", "Address to serve configs too large for userdata on, such as :8080; required with --userdata-url")
+	root.PersistentFlags().DurationVar(&kola.Options.ResourceLifetime, "resource-lifetime", 5*time.Hour, "How long after their creation cloud resources are tagged to expire, after which garbage collection deletes them (0 for never)")
 	// we make this a percentage to avoid having to deal with floats
 	root.PersistentFlags().UintVar(&kola.Options.ExtendTimeoutPercent, "extend-timeout-percentage", 0, "Extend all test timeouts by N percent")
+	sv(&perfProfilePath, "performance-profile", "", "YAML file of a performance profile tuning all machines with the realtime kernel, hugepages, CPU isolation or a tuned profile; see docs/kola.md")
 	// rhcos-specific options
 	sv(&kola.Options.OSContainer, "oscontainer", "", "oscontainer image pullspec for pivot (RHCOS only)")
 
@@ -89,7 +139,12 @@ func init() {
 	// See https://github.com/openshift/installer/issues/2919 for example
 	sv(&kola.AWSOptions.InstanceType, "aws-type", "", "AWS instance type")
 	sv(&kola.AWSOptions.SecurityGroup, "aws-sg", "kola", "AWS security group name")
-	sv(&kola.AWSOptions.IAMInstanceProfile, "aws-iam-profile", "kola", "AWS IAM instance profile name")
+	sv(&kola.AWSOptions.IAMInstanceProfile, "aws-iam-profile", "kola", "AWS IAM instance profile name, created if it doesn't exist; empty for none")
+	bv(&kola.AWSOptions.Spot, "aws-spot", false, "Launch AWS machines as spot instances")
+	sv(&kola.AWSOptions.SpotMaxPrice, "aws-spot-max-price", "", "Maximum hourly price of AWS spot instances (default on-demand price)")
+	bv(&kola.AWSOptions.SpotFallback, "aws-spot-fallback", true, "Launch on-demand AWS machines if there's no spot capacity")
+	bv(&kola.AWSOptions.IMDSv2Only, "aws-imdsv2-only", false, "Launch AWS machines whose instance metadata service requires IMDSv2 session tokens")
+	root.PersistentFlags().Int64Var(&kola.AWSOptions.IMDSHopLimit, "aws-imds-hop-limit", 0, "Hop limit of IMDSv2 token responses on AWS machines (default AWS's)")
 
 	// azure-specific options
 	sv(&kola.AzureOptions.AzureCredentials, "azure-credentials", "", "Azure credentials file location (default \"~/"+auth.AzureCredentialsPath+"\")")
@@ -101,6 +156,9 @@ func init() {
 	sv(&kola.AzureOptions.Location, "azure-location", "westus", "Azure location (default \"westus\"")
 	sv(&kola.AzureOptions.Size, "azure-size", "Standard_D2s_v3", "Azure machine size (default \"Standard_D2s_v3\")")
 	sv(&kola.AzureOptions.AvailabilityZone, "azure-availability-zone", "1", "Azure Availability Zone (default \"1\")")
+	sv(&kola.AzureOptions.SecurityType, "azure-security-type", "", "Azure VM security type, TrustedLaunch or ConfidentialVM, booting with secure boot and a vTPM")
+	bv(&kola.AzureOptions.AcceleratedNetworking, "azure-accelerated-networking", true, "Enable Azure Accelerated Networking on the machines' NICs")
+	sv(&kola.AzureOptions.EphemeralOSDisk, "azure-ephemeral-os-disk", "", "Place the machines' OS disks on their local CacheDisk or ResourceDisk")
 
 	// do-specific options
 	sv(&kola.DOOptions.ConfigPath, "do-config-file", "", "DigitalOcean config file (default \"~/"+auth.DOConfigPath+"\")")
@@ -116,6 +174,10 @@ func init() {
 	sv(&kola.ESXOptions.Profile, "esx-profile", "", "ESX profile (default \"default\")")
 	sv(&kola.ESXOptions.BaseVMName, "esx-base-vm", "", "ESX base VM name")
 
+	// external platform driver options
+	sv(&kola.ExternalOptions.Driver, "platform-driver", "", "Path to an out-of-tree platform driver; see the mantle/platform/machine/external package for the protocol (default \""+external.DriverPrefix+"<platform>\" in $PATH)")
+	ssv(&kola.ExternalOptions.DriverOptions, "platform-driver-opt", []string{}, "key=value option to pass to the platform driver. Can be specified multiple times.")
+
 	// gcp-specific options
 	sv(&kola.GCPOptions.Image, "gcp-image", "", "GCP image, full api endpoints names are accepted if resource is in a different project")
 	sv(&kola.GCPOptions.Project, "gcp-project", "fedora-coreos-devel", "GCP project name")
@@ -127,19 +189,65 @@ func init() {
 	bv(&kola.GCPOptions.ServiceAuth, "gcp-service-auth", false, "for non-interactive auth when running within GCP")
 	sv(&kola.GCPOptions.JSONKeyFile, "gcp-json-key", "", "use a service account's JSON key for authentication (default \"~/"+auth.GCPConfigPath+"\")")
 	sv(&kola.GCPOptions.ConfidentialType, "gcp-confidential-type", "", "create confidential instances: sev, sev_snp, tdx")
+	ssv(&kola.GCPOptions.ShieldedVM, "gcp-shielded-vm", []string{}, "create Shielded VM instances with only these features enabled: secure-boot, vtpm, integrity-monitoring")
+
+	// equinix-specific options
+	sv(&kola.EquinixOptions.Token, "equinix-token", "", "Equinix Metal API token (default $METAL_AUTH_TOKEN)")
+	sv(&kola.EquinixOptions.Project, "equinix-project", "", "Equinix Metal project ID (default $METAL_PROJECT_ID)")
+	sv(&kola.EquinixOptions.Metro, "equinix-metro", "da", "Equinix Metal metro")
+	sv(&kola.EquinixOptions.Plan, "equinix-plan", "c3.small.x86", "Equinix Metal server plan")
+	sv(&kola.EquinixOptions.IPXEScriptURL, "equinix-ipxe-script-url", "", "URL of the iPXE script servers boot, as written by 'ore equinix ipxe-script' (default served with --equinix-serve-address)")
+	sv(&kola.EquinixOptions.ArtifactURL, "equinix-artifact-url", "", "Base URL of the build's live kernel, initramfs and rootfs")
+	sv(&kola.EquinixOptions.ServeAddress, "equinix-serve-address", "", "Address to serve the live artifacts and iPXE script on, which --equinix-artifact-url must reach")
+	sv(&kola.EquinixOptions.InstallDevice, "equinix-install-device", "", "Disk for the live system to install to, e.g. /dev/sda (default run the live system)")
+	sv(&kola.EquinixOptions.Console, "equinix-console", "", "Kernel console argument of the servers (default ttyS1,115200n8, or ttyAMA0,115200 on aarch64)")
+
+	// hetzner-specific options
+	sv(&kola.HetznerOptions.Token, "hetzner-token", "", "Hetzner Cloud API token (default $HCLOUD_TOKEN)")
+	sv(&kola.HetznerOptions.Location, "hetzner-location", "fsn1", "Hetzner Cloud location")
+	sv(&kola.HetznerOptions.ServerType, "hetzner-server-type", "cx22", "Hetzner Cloud server type")
+	sv(&kola.HetznerOptions.Image, "hetzner-image", "", "Hetzner Cloud image ID")
+
+	// oci-specific options
+	sv(&kola.OCIOptions.ConfigPath, "oci-config-file", "", "OCI config file (default \"~/"+auth.OCIConfigPath+"\")")
+	sv(&kola.OCIOptions.Profile, "oci-profile", "", "OCI profile (default \"DEFAULT\")")
+	sv(&kola.OCIOptions.Region, "oci-region", "", "OCI region (default the profile's)")
+	sv(&kola.OCIOptions.CompartmentID, "oci-compartment", "", "OCI compartment OCID (default the tenancy's root compartment)")
+	sv(&kola.OCIOptions.Image, "oci-image", "", "OCI image OCID")
+	sv(&kola.OCIOptions.Shape, "oci-shape", "VM.Standard.E4.Flex", "OCI instance shape")
+	root.PersistentFlags().Float64Var(&kola.OCIOptions.OCPUs, "oci-ocpus", 1, "OCPUs of instances of flexible shapes")
+	root.PersistentFlags().Float64Var(&kola.OCIOptions.MemoryGB, "oci-memory", 8, "memory in GB of instances of flexible shapes")
+	sv(&kola.OCIOptions.SubnetID, "oci-subnet", "", "OCID of the subnet instances are attached to, which must allow SSH")
+	sv(&kola.OCIOptions.AvailabilityDomain, "oci-availability-domain", "", "OCI availability domain (default the region's first)")
 
 	// openstack-specific options
 	sv(&kola.OpenStackOptions.ConfigPath, "openstack-config-file", "", "Path to a clouds.yaml formatted OpenStack config file. The underlying library defaults to ./clouds.yaml")
 	sv(&kola.OpenStackOptions.Profile, "openstack-profile", "", "OpenStack profile within clouds.yaml (default \"openstack\")")
 	sv(&kola.OpenStackOptions.Region, "openstack-region", "", "OpenStack region")
 	sv(&kola.OpenStackOptions.Image, "openstack-image", "", "OpenStack image ref")
-	sv(&kola.OpenStackOptions.Flavor, "openstack-flavor", "1", "OpenStack flavor ref")
-	sv(&kola.OpenStackOptions.Network, "openstack-network", "", "OpenStack network")
+	sv(&kola.OpenStackOptions.Flavor, "openstack-flavor", "", "OpenStack flavor ref (default: the smallest flavor with enough memory)")
+	root.PersistentFlags().IntVar(&kola.OpenStackOptions.MinRAM, "openstack-min-ram", 2048, "Minimum memory in MiB of the flavor chosen if --openstack-flavor is unset")
+	sv(&kola.OpenStackOptions.Network, "openstack-network", "", "OpenStack network (default: the first active network which isn't external)")
 	sv(&kola.OpenStackOptions.Domain, "openstack-domain", "", "OpenStack domain ID")
 	sv(&kola.OpenStackOptions.FloatingIPNetwork, "openstack-floating-ip-network", "", "OpenStack network to use when creating a floating IP")
 
+	// powervs-specific options
+	sv(&kola.PowerVSOptions.CredentialsFile, "ibmcloud-credentials-file", "", "IBM Cloud API key file (default \"~/.bluemix/apikey.json\")")
+	sv(&kola.PowerVSOptions.ApiKey, "ibmcloud-api-key", "", "IBM Cloud API key (overrides the credentials file)")
+	sv(&kola.PowerVSOptions.PowerVSServiceInstance, "powervs-service-instance", "", "GUID of the Power Virtual Server workspace")
+	sv(&kola.PowerVSOptions.PowerVSImage, "powervs-image", "", "Power Virtual Server image ID or name")
+	sv(&kola.PowerVSOptions.PowerVSNetwork, "powervs-network", "", "Power Virtual Server network ID or name (default a public network named \"kola\", created if missing)")
+	sv(&kola.PowerVSOptions.PowerVSSysType, "powervs-sys-type", "s922", "Power Virtual Server machine type")
+	sv(&kola.PowerVSOptions.PowerVSProcType, "powervs-proc-type", "shared", "Power Virtual Server processor type: shared, capped, dedicated")
+	root.PersistentFlags().Float64Var(&kola.PowerVSOptions.PowerVSProcessors, "powervs-processors", 0.5, "Power Virtual Server processors")
+	root.PersistentFlags().Float64Var(&kola.PowerVSOptions.PowerVSMemory, "powervs-memory", 4, "Power Virtual Server memory in GiB")
+	sv(&kola.PowerVSOptions.PowerVSStorageType, "powervs-storage-type", "tier3", "Power Virtual Server storage tier")
+
 	// QEMU-specific options
-	sv(&kola.QEMUOptions.Firmware, "qemu-firmware", "", "Boot firmware: bios,uefi,uefi-secure (default bios)")
+	sv(&kola.QEMUOptions.Firmware, "qemu-firmware", "", "Boot firmware: bios,uefi,uefi-secure (default bios); slof,opal on ppc64le (default slof)")
+	sv(&kola.QEMUOptions.FirmwareCode, "qemu-firmware-code", "", "Boot this UEFI firmware image rather than the distro's")
+	sv(&kola.QEMUOptions.FirmwareVars, "qemu-firmware-vars", "", "Start UEFI machines with a copy of this variable store rather than the distro's template")
+	sv(&kola.QEMUOptions.PetitbootImage, "qemu-petitboot-image", "", "petitboot zImage to boot as the bootloader with the opal firmware")
 	sv(&kola.QEMUOptions.DiskImage, "qemu-image", "", "path to CoreOS disk image")
 	sv(&kola.QEMUOptions.DiskSize, "qemu-size", "", "Resize target disk via qemu-img resize [+]SIZE")
 	sv(&kola.QEMUOptions.DriveOpts, "qemu-drive-opts", "", "Arbitrary options to append to qemu -drive for primary disk")
@@ -149,8 +257,12 @@ func init() {
 	bv(&kola.QEMUOptions.Native4k, "qemu-native-4k", false, "Force 4k sectors for main disk")
 	bv(&kola.QEMUOptions.Disk512e, "qemu-512e", false, "Force 512e layout for main disk")
 	bv(&kola.QEMUOptions.Nvme, "qemu-nvme", false, "Use NVMe for main disk")
+	bv(&kola.QEMUOptions.Dasd, "qemu-dasd", false, "Give main disk the geometry and 4k blocks of an ECKD DASD; it's still a virtio disk (s390x only)")
+	bv(&kola.QEMUOptions.NoCloud, "qemu-nocloud", false, "Also provide machines a cloud-init NoCloud seed with the SSH keys, for distros provisioned by cloud-init")
 	bv(&kola.QEMUOptions.Swtpm, "qemu-swtpm", true, "Create temporary software TPM")
 	ssv(&kola.QEMUOptions.BindRO, "qemu-bind-ro", nil, "Inject a host directory; this does not automatically mount in the guest")
+	root.PersistentFlags().DurationVar(&kola.QEMUOptions.TelemetryInterval, "qemu-telemetry-interval", 0, "Sample each machine's CPU, memory and disk usage at this interval into telemetry.json (0 disables)")
+	bv(&kola.QEMUOptions.BootMetrics, "qemu-boot-metrics", false, "Write how long each machine took to reach GRUB, the kernel, the initramfs and SSH to boot-metrics.json once it has booted")
 
 	sv(&kola.QEMUIsoOptions.IsoPath, "qemu-iso", "", "path to CoreOS ISO image")
 	bv(&kola.QEMUIsoOptions.AsDisk, "qemu-iso-as-disk", false, "attach ISO image as regular disk")
@@ -160,6 +272,26 @@ func init() {
 	sv(&kola.QEMUOptions.SecureExecutionHostKey, "qemu-secex-hostkey", "", "Path to Secure Execution HKD certificate")
 	// s390x CEX-specific options
 	bv(&kola.QEMUOptions.Cex, "qemu-cex", false, "Attach CEX device to guest")
+	bv(&kola.QEMUOptions.Kdump, "qemu-kdump", false, "Enable kdump, saving the vmcore of a kernel crash to the machine's output directory")
+
+	// options for reusing an existing machine
+	sv(&kola.ReuseOptions.Host, "reuse-machine", "", "Run the tests which don't need to provision a machine on this existing one, as HOST[:PORT]; implies --platform=reuse")
+	sv(&kola.ReuseOptions.KeyFile, "reuse-machine-key", "", "SSH private key the reused machine accepts")
+	sv(&kola.ReuseOptions.User, "reuse-machine-user", "core", "User to log into the reused machine as")
+
+	// vultr-specific options
+	sv(&kola.VultrOptions.Token, "vultr-token", "", "Vultr API key (default $VULTR_API_KEY)")
+	sv(&kola.VultrOptions.Region, "vultr-region", "ewr", "Vultr region")
+	sv(&kola.VultrOptions.Plan, "vultr-plan", "vc2-2c-4gb", "Vultr plan")
+	sv(&kola.VultrOptions.Image, "vultr-image", "", "Vultr snapshot ID")
+}
+
+func artifactClasses() string {
+	var classes []string
+	for _, c := range artifacts.Classes {
+		classes = append(classes, string(c))
+	}
+	return strings.Join(classes, ", ")
 }
 
 // Sync up the command line options if there is dependency
@@ -177,6 +309,13 @@ func syncOptionsImpl(useCosa bool) error {
 		kolaPlatform = "qemu-iso"
 	}
 
+	if kola.ReuseOptions.Host != "" {
+		if kolaPlatform != "" && kolaPlatform != "reuse" {
+			return fmt.Errorf("--reuse-machine can't be used with platform %s", kolaPlatform)
+		}
+		kolaPlatform = "reuse"
+	}
+
 	if kolaPlatform == "" && kola.QEMUIsoOptions.IsoPath != "" {
 		kolaPlatform = "qemu-iso"
 	}
@@ -197,6 +336,7 @@ func syncOptionsImpl(useCosa bool) error {
 			return fmt.Errorf("detecting CPU count: %w", err)
 		}
 		kola.TestParallelism = int(ncpu)
+		kola.AutoParallelism = true
 	} else {
 		parallel, err := strconv.ParseInt(kolaParallelArg, 10, 32)
 		if err != nil {
@@ -205,6 +345,72 @@ func syncOptionsImpl(useCosa bool) error {
 		kola.TestParallelism = int(parallel)
 	}
 
+	kola.Options.Tags = make(map[string]string)
+	for _, tag := range resourceTags {
+		k, v, ok := strings.Cut(tag, "=")
+		if !ok || k == "" {
+			return fmt.Errorf("--resource-tag %q isn't KEY=VALUE", tag)
+		}
+		kola.Options.Tags[k] = v
+	}
+	for _, k := range requiredTags {
+		if _, ok := kola.Options.Tags[k]; !ok {
+			return fmt.Errorf("--resource-tag %s=VALUE is required", k)
+		}
+	}
+
+	if kola.Options.UserDataURL != "" && kola.Options.UserDataServeAddress == "" {
+		return fmt.Errorf("--userdata-url requires --userdata-serve-address")
+	}
+
+	if perfProfilePath != "" {
+		profile, err := platform.LoadPerformanceProfile(perfProfilePath)
+		if err != nil {
+			return fmt.Errorf("loading --performance-profile: %w", err)
+		}
+		kola.Options.PerformanceProfile = profile
+	}
+
+	for _, pattern := range redactPatterns {
+		if err := conf.AddRedactPattern(pattern); err != nil {
+			return fmt.Errorf("parsing --redact: %w", err)
+		}
+	}
+
+	// Later policies for a class override earlier ones
+	retention, err := artifacts.ParseRetention(append(defaultRetention, retainPolicies...))
+	if err != nil {
+		return fmt.Errorf("parsing --retain: %w", err)
+	}
+	if retainMaxSize != "" {
+		if retention.MaxBytes, err = artifacts.ParseSize(retainMaxSize); err != nil {
+			return fmt.Errorf("parsing --retain-max-size: %w", err)
+		}
+	}
+	kola.Artifacts = artifacts.NewManager(retention)
+
+	if kola.QEMUOptions.Dasd && kola.Options.CosaBuildArch != "s390x" {
+		return fmt.Errorf("DASD geometry is only supported on s390x")
+	}
+
+	if (kola.QEMUOptions.Firmware == "slof" || kola.QEMUOptions.Firmware == "opal") && kola.Options.CosaBuildArch != "ppc64le" {
+		return fmt.Errorf("%s firmware is only supported on ppc64le", kola.QEMUOptions.Firmware)
+	}
+	if kola.QEMUOptions.Firmware == "opal" && kola.QEMUOptions.PetitbootImage == "" {
+		return fmt.Errorf("opal firmware requires --qemu-petitboot-image")
+	}
+
+	if kola.AWSOptions.IMDSHopLimit < 0 || kola.AWSOptions.IMDSHopLimit > 64 {
+		return fmt.Errorf("--aws-imds-hop-limit must be between 1 and 64")
+	}
+
+	if _, err := azure.ParseSecurityType(kola.AzureOptions.SecurityType); err != nil {
+		return fmt.Errorf("parsing --azure-security-type: %w", err)
+	}
+	if _, err := azure.ParseEphemeralOSDisk(kola.AzureOptions.EphemeralOSDisk); err != nil {
+		return fmt.Errorf("parsing --azure-ephemeral-os-disk: %w", err)
+	}
+
 	// native 4k requires a UEFI bootloader
 	if kola.QEMUOptions.Native4k && kola.QEMUOptions.Firmware == "bios" {
 		return fmt.Errorf("native 4k requires uefi firmware")
@@ -218,8 +424,20 @@ func syncOptionsImpl(useCosa bool) error {
 		}
 	}
 
-	if err := validateOption("platform", kolaPlatform, kolaPlatforms); err != nil {
-		return err
+	// Platforms which aren't built in may be provided by a driver in $PATH
+	if kolaPlatform == "reuse" {
+		if kola.ReuseOptions.Host == "" {
+			return fmt.Errorf("platform reuse requires --reuse-machine")
+		}
+	} else if kola.ExternalOptions.Driver == "" && !kola.HasString(kolaPlatform, kolaPlatforms) {
+		if driver, err := external.FindDriver(kolaPlatform); err == nil {
+			kola.ExternalOptions.Driver = driver
+		}
+	}
+	if kola.ExternalOptions.Driver == "" && kolaPlatform != "reuse" {
+		if err := validateOption("platform", kolaPlatform, kolaPlatforms); err != nil {
+			return err
+		}
 	}
 
 	// Choose an appropriate AWS instance type for the target architecture
@@ -337,6 +555,17 @@ func syncOptionsImpl(useCosa bool) error {
 		}
 	}
 
+	if foundCosa && kola.Options.CosaBuildsURL != "" {
+		kola.CosaBuild.RemoteURL = fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(kola.Options.CosaBuildsURL, "/"),
+			kola.CosaBuild.Meta.BuildID, kola.CosaBuild.Arch)
+	}
+
+	if foundCosa {
+		if err := setupImageCache(); err != nil {
+			return err
+		}
+	}
+
 	if foundCosa && useCosa {
 		if err := syncCosaOptions(); err != nil {
 			return err
@@ -372,6 +601,27 @@ func syncOptions() error {
 	return syncOptionsImpl(true)
 }
 
+// setupImageCache sets up the cache of the build's decompressed images.
+func setupImageCache() error {
+	dir := imageCacheDir
+	if dir == "" {
+		dir = filepath.Join(kola.Options.CosaWorkdir, "cache/kola-images")
+	}
+	var maxBytes int64
+	if imageCacheSize != "" {
+		var err error
+		if maxBytes, err = artifacts.ParseSize(imageCacheSize); err != nil {
+			return fmt.Errorf("parsing --image-cache-size: %w", err)
+		}
+	}
+	cache, err := util.NewImageCache(dir, maxBytes)
+	if err != nil {
+		return fmt.Errorf("creating image cache: %w", err)
+	}
+	kola.ImageCache = cache
+	return nil
+}
+
 // syncCosaOptions sets unset platform-specific
 // options that can be derived from the cosa build metadata
 func syncCosaOptions() error {
@@ -385,6 +635,28 @@ func syncCosaOptions() error {
 		}
 		if kola.QEMUOptions.DiskImage == "" && kola.CosaBuild.Meta.BuildArtifacts.Qemu != nil {
 			kola.QEMUOptions.DiskImage = filepath.Join(kola.CosaBuild.Dir, kola.CosaBuild.Meta.BuildArtifacts.Qemu.Path)
+			if util.CompressionSuffix(kola.QEMUOptions.DiskImage) != "" {
+				image, err := kola.CosaBuild.RequireDecompressedArtifact("qemu", kola.ImageCache)
+				if err != nil {
+					return err
+				}
+				kola.QEMUOptions.DiskImage = image
+			}
+		}
+	case "equinix":
+		// Serve the live artifacts from the build
+		artifacts := kola.CosaBuild.Meta.BuildArtifacts
+		if kola.EquinixOptions.ArtifactDir == "" {
+			kola.EquinixOptions.ArtifactDir = kola.CosaBuild.Dir
+		}
+		if kola.EquinixOptions.Kernel == "" && artifacts.LiveKernel != nil {
+			kola.EquinixOptions.Kernel = artifacts.LiveKernel.Path
+		}
+		if kola.EquinixOptions.Initramfs == "" && artifacts.LiveInitramfs != nil {
+			kola.EquinixOptions.Initramfs = artifacts.LiveInitramfs.Path
+		}
+		if kola.EquinixOptions.Rootfs == "" && artifacts.LiveRootfs != nil {
+			kola.EquinixOptions.Rootfs = artifacts.LiveRootfs.Path
 		}
 	case "qemu-iso":
 		if kola.QEMUIsoOptions.IsoPath == "" && kola.CosaBuild.Meta.BuildArtifacts.LiveIso != nil {
@@ -412,6 +684,20 @@ func syncCosaOptions() error {
 		}
 	}
 
+	// Refuse configs the build's Ignition can't parse before booting
+	// machines which would fail on them
+	release, err := kola.CosaBuild.PackageVersion("ignition")
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if release != "" {
+		max, err := conf.MaxSpecVersion(release)
+		if err != nil {
+			return err
+		}
+		kola.Options.MaxIgnitionSpec = &max
+	}
+
 	if kola.Options.Distribution == "" {
 		distro, err := util.TargetDistro(kola.CosaBuild.Meta)
 		if err != nil {
@@ -57,6 +57,7 @@ var (
 	ignitionFragments []string
 	bindro            []string
 	bindrw            []string
+	bindImages        []string
 
 	directIgnition            bool
 	forceConfigInjection      bool
@@ -67,6 +68,8 @@ var (
 
 	consoleFile string
 
+	firmwareVarsFile string
+
 	sshCommand string
 
 	additionalNics int
@@ -97,9 +100,11 @@ func init() {
 	cmdQemuExec.Flags().StringVarP(&butane, "butane", "B", "", "Path to Butane config")
 	cmdQemuExec.Flags().StringArrayVar(&bindro, "bind-ro", nil, "Mount $hostpath,$guestpath readonly; for example --bind-ro=/path/on/host,/var/mnt/guest)")
 	cmdQemuExec.Flags().StringArrayVar(&bindrw, "bind-rw", nil, "Mount $hostpath,$guestpath writable; for example --bind-rw=/path/on/host,/var/mnt/guest)")
+	cmdQemuExec.Flags().StringArrayVar(&bindImages, "bind-image", nil, "Pack $hostpath into a squashfs image attached as a disk and mount it at $guestpath readonly; for example --bind-image=/path/on/host,/var/mnt/guest")
 	cmdQemuExec.Flags().BoolVarP(&forceConfigInjection, "inject-ignition", "", false, "Force injecting Ignition config using guestfs")
 	cmdQemuExec.Flags().BoolVar(&propagateInitramfsFailure, "propagate-initramfs-failure", false, "Error out if the system fails in the initramfs")
 	cmdQemuExec.Flags().StringVarP(&consoleFile, "console-to-file", "", "", "Filepath in which to save serial console logs")
+	cmdQemuExec.Flags().StringVar(&firmwareVarsFile, "firmware-vars-file", "", "Keep the UEFI variable store in this file, creating it if needed, so that it persists across runs")
 	cmdQemuExec.Flags().IntVarP(&additionalNics, "additional-nics", "", 0, "Number of additional NICs to add")
 	cmdQemuExec.Flags().StringVarP(&sshCommand, "ssh-command", "x", "", "Command to execute instead of spawning a shell")
 	cmdQemuExec.Flags().StringVarP(&netboot, "netboot", "", "", "Filepath to BOOTP program (e.g. PXELINUX/GRUB binary or iPXE script")
@@ -251,6 +256,9 @@ func runQemuExec(cmd *cobra.Command, args []string) error {
 	if len(bindrw) > 0 && directIgnition {
 		return fmt.Errorf("Cannot use --bind-rw with --ignition-direct")
 	}
+	if len(bindImages) > 0 && directIgnition {
+		return fmt.Errorf("Cannot use --bind-image with --ignition-direct")
+	}
 
 	builder := platform.NewQemuBuilder()
 	defer builder.Close()
@@ -319,6 +327,18 @@ func runQemuExec(cmd *cobra.Command, args []string) error {
 		ensureConfig()
 		config.MountHost(dest, false)
 	}
+	for i, b := range bindImages {
+		src, dest, err := parseBindOpt(b)
+		if err != nil {
+			return err
+		}
+		name := fmt.Sprintf("bind-image%d", i)
+		if err := builder.AddDirectoryImage(src, name, platform.DirectoryImageSquashfs); err != nil {
+			return err
+		}
+		ensureConfig()
+		config.MountDevice(platform.DirectoryImageDevice(name), dest)
+	}
 	builder.ForceConfigInjection = forceConfigInjection
 	if len(firstbootkargs) > 0 {
 		builder.AppendFirstbootKernelArgs = firstbootkargs
@@ -328,6 +348,10 @@ func runQemuExec(cmd *cobra.Command, args []string) error {
 	if kola.QEMUOptions.Firmware != "" {
 		builder.Firmware = kola.QEMUOptions.Firmware
 	}
+	builder.FirmwareCode = kola.QEMUOptions.FirmwareCode
+	builder.FirmwareVars = kola.QEMUOptions.FirmwareVars
+	builder.PetitbootImage = kola.QEMUOptions.PetitbootImage
+	builder.FirmwareVarsFile = firmwareVarsFile
 	if kola.QEMUOptions.DiskImage != "" && netboot == "" {
 		if err := builder.AddBootDisk(buildDiskFromOptions()); err != nil {
 			return err
@@ -0,0 +1,319 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kballard/go-shellquote"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/coreos/coreos-assembler/mantle/harness/reporters"
+	"github.com/coreos/coreos-assembler/mantle/harness/testresult"
+	"github.com/coreos/coreos-assembler/mantle/kola"
+	"github.com/coreos/coreos-assembler/mantle/system/exec"
+)
+
+// defaultRemoteBuilderImage is the image podman builders run cosa from.
+const defaultRemoteBuilderImage = "quay.io/coreos-assembler/coreos-assembler:latest"
+
+// localTestisoFlags are the testiso flags which describe the local run
+// rather than the tests, so aren't passed on to remote builders.
+var localTestisoFlags = map[string]bool{
+	"remote-builder":       true,
+	"remote-builder-image": true,
+	"output-dir":           true,
+	"workdir":              true,
+	"build":                true,
+	"arch":                 true,
+}
+
+// remoteBuilder is a host of another architecture which testiso runs that
+// architecture's tests on.
+type remoteBuilder struct {
+	Arch string
+	// SSH is the [user@]host of a builder reached with ssh, which has
+	// cosa installed, and Port its SSH port if not the default.
+	SSH  string
+	Port string
+	// Connection is the podman-remote connection of a builder which runs
+	// cosa from Image.
+	Connection string
+	Image      string
+	// Workdir is the cosa workdir on the builder.
+	Workdir string
+}
+
+// parseRemoteBuilder parses a builder spec: ARCH=ssh://[USER@]HOST[:PORT]/WORKDIR
+// or ARCH=podman://CONNECTION/WORKDIR.
+func parseRemoteBuilder(spec, image string) (remoteBuilder, error) {
+	arch, target, ok := strings.Cut(spec, "=")
+	if !ok || arch == "" {
+		return remoteBuilder{}, fmt.Errorf("remote builder %q is not ARCH=TARGET", spec)
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return remoteBuilder{}, fmt.Errorf("remote builder %q: %w", spec, err)
+	}
+	if u.Host == "" || u.Path == "" || u.Path == "/" {
+		return remoteBuilder{}, fmt.Errorf("remote builder %q has no host or workdir", spec)
+	}
+	b := remoteBuilder{Arch: arch, Workdir: u.Path}
+	switch u.Scheme {
+	case "ssh":
+		b.SSH = u.Hostname()
+		if u.User != nil {
+			b.SSH = u.User.Username() + "@" + b.SSH
+		}
+		b.Port = u.Port()
+	case "podman":
+		b.Connection = u.Host
+		b.Image = image
+	default:
+		return remoteBuilder{}, fmt.Errorf("remote builder %q: unknown scheme %q; valid schemes: ssh, podman", spec, u.Scheme)
+	}
+	return b, nil
+}
+
+func (b remoteBuilder) String() string {
+	if b.Connection != "" {
+		return fmt.Sprintf("podman://%s%s", b.Connection, b.Workdir)
+	}
+	if b.Port != "" {
+		return fmt.Sprintf("ssh://%s:%s%s", b.SSH, b.Port, b.Workdir)
+	}
+	return fmt.Sprintf("ssh://%s%s", b.SSH, b.Workdir)
+}
+
+// command returns a command running args in the builder's workdir. cosa
+// commands are run as `cosa ARGS` over ssh, and as the container's
+// command with podman; others are run in the container's shell.
+func (b remoteBuilder) command(cosa bool, args ...string) *exec.ExecCmd {
+	var argv []string
+	if b.Connection != "" {
+		argv = []string{"podman", "--connection", b.Connection, "run", "--rm", "--privileged",
+			"--security-opt", "label=disable", "-v", b.Workdir + ":/srv", "-w", "/srv", b.Image}
+		if !cosa {
+			argv = append(argv, "shell")
+		}
+		argv = append(argv, args...)
+	} else {
+		argv = []string{"ssh", "-o", "BatchMode=yes"}
+		if b.Port != "" {
+			argv = append(argv, "-p", b.Port)
+		}
+		if cosa {
+			args = append([]string{"cosa"}, args...)
+		}
+		argv = append(argv, b.SSH, fmt.Sprintf("cd %s && %s", shellquote.Join(b.Workdir), shellquote.Join(args...)))
+	}
+	return exec.Command(argv[0], argv[1:]...)
+}
+
+// forwardedTestisoFlags returns the flags the user set on the command line
+// for passing on to remote builders. Paths must be valid on the builders.
+func forwardedTestisoFlags(cmd *cobra.Command) []string {
+	var args []string
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		if localTestisoFlags[f.Name] {
+			return
+		}
+		if slice, ok := f.Value.(pflag.SliceValue); ok {
+			for _, v := range slice.GetSlice() {
+				// String slices are parsed as CSV, so quote elements
+				// which would otherwise be split or rejected
+				if f.Value.Type() == "stringSlice" && strings.ContainsAny(v, ",\"\r\n") {
+					v = `"` + strings.ReplaceAll(v, `"`, `""`) + `"`
+				}
+				args = append(args, fmt.Sprintf("--%s=%s", f.Name, v))
+			}
+			return
+		}
+		args = append(args, fmt.Sprintf("--%s=%s", f.Name, f.Value.String()))
+	})
+	return args
+}
+
+// remoteRun is a remote builder running its architecture's tests.
+type remoteRun struct {
+	builder remoteBuilder
+	tests   []string
+	// outdir is where the builder's output directory is copied
+	outdir string
+	err    error
+}
+
+// run runs the tests on the builder, logging its output to <outdir>.log,
+// and copies its output directory to outdir.
+func (r *remoteRun) run(buildID string, flags []string) error {
+	b := r.builder
+	log, err := os.Create(r.outdir + ".log")
+	if err != nil {
+		return err
+	}
+	defer log.Close()
+	runLogged := func(cmd *exec.ExecCmd) error {
+		cmd.Stdout = log
+		cmd.Stderr = log
+		fmt.Fprintf(log, "+ %s\n", shellquote.Join(cmd.Args...))
+		return cmd.Run()
+	}
+
+	if kola.Options.CosaBuildsURL != "" {
+		fetch := b.command(true, "buildfetch", "--url", kola.Options.CosaBuildsURL, "--build", buildID, "--arch", b.Arch)
+		if err := runLogged(fetch); err != nil {
+			return fmt.Errorf("fetching build %s on %s: %w", buildID, b, err)
+		}
+	}
+
+	remoteOutdir := fmt.Sprintf("tmp/kola-testiso-%s-%s", b.Arch, time.Now().UTC().Format("20060102-150405"))
+	args := append([]string{"kola", "testiso", "--build", buildID, "--output-dir", remoteOutdir}, flags...)
+	// testiso exits nonzero when tests fail, which the report records
+	runErr := runLogged(b.command(true, append(args, r.tests...)...))
+	defer func() {
+		if err := runLogged(b.command(false, "rm", "-rf", remoteOutdir)); err != nil {
+			plog.Warningf("Removing %s on %s: %v", remoteOutdir, b, err)
+		}
+	}()
+
+	if err := os.MkdirAll(r.outdir, 0777); err != nil {
+		return err
+	}
+	if err := r.copyOutput(remoteOutdir, log); err != nil {
+		return fmt.Errorf("copying output from %s: %w", b, err)
+	}
+	if _, err := os.Stat(filepath.Join(r.outdir, "reports/report.json")); err != nil {
+		return fmt.Errorf("running tests on %s: %v (see %s.log)", b, runErr, r.outdir)
+	}
+	return nil
+}
+
+// copyOutput streams the builder's output directory into outdir as a
+// tarball.
+func (r *remoteRun) copyOutput(remoteOutdir string, log io.Writer) error {
+	send := r.builder.command(false, "tar", "-C", remoteOutdir, "-cf", "-", ".")
+	send.Stderr = log
+	receive := exec.Command("tar", "-C", r.outdir, "-xf", "-")
+	receive.Stderr = log
+	pipe, err := send.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	receive.Stdin = pipe
+	if err := send.Start(); err != nil {
+		return err
+	}
+	receiveErr := receive.Run()
+	if err := send.Wait(); err != nil {
+		return err
+	}
+	return receiveErr
+}
+
+// merge adds the builder's results to the reports, as ARCH/TEST, returning
+// whether any failed.
+func (r *remoteRun) merge(reporter reporters.Reporters) bool {
+	arch := r.builder.Arch
+	if r.err != nil {
+		reporter.ReportTest(arch, []string{}, testresult.Fail, 0, []byte(r.err.Error()))
+		printResult(arch, 0, r.err)
+		return true
+	}
+	report, err := reporters.DeserialiseReport(filepath.Join(r.outdir, "reports/report.json"))
+	if err != nil {
+		reporter.ReportTest(arch, []string{}, testresult.Fail, 0, []byte(err.Error()))
+		printResult(arch, 0, err)
+		return true
+	}
+	failed := false
+	for _, test := range report.Tests {
+		name := arch + "/" + test.Name
+		reporter.ReportTest(name, prefixAll(arch+"/", test.Subtests), test.Result, test.Duration, []byte(test.Output))
+		var testErr error
+		if test.Result == testresult.Fail {
+			testErr = fmt.Errorf("%s", test.Output)
+			failed = true
+		}
+		printResult(name, test.Duration, testErr)
+	}
+	return failed
+}
+
+func prefixAll(prefix string, names []string) []string {
+	prefixed := make([]string, 0, len(names))
+	for _, name := range names {
+		prefixed = append(prefixed, prefix+name)
+	}
+	return prefixed
+}
+
+// startRemoteRuns starts running the tests matching the patterns on each
+// builder in the background, returning a function which waits for them
+// and merges their results into the reports, or nil if no builder has
+// tests to run.
+func startRemoteRuns(builders []remoteBuilder, patterns []string, flags []string) (func(reporters.Reporters) bool, error) {
+	var runs []*remoteRun
+	for _, b := range builders {
+		tests := getAllTests(kola.CosaBuild, b.Arch)
+		if len(patterns) != 0 {
+			var err error
+			if tests, err = filterTests(tests, patterns); err != nil {
+				return nil, err
+			}
+		}
+		if len(tests) == 0 {
+			continue
+		}
+		sort.Strings(tests)
+		runs = append(runs, &remoteRun{
+			builder: b,
+			tests:   tests,
+			outdir:  filepath.Join(outputDir, b.Arch),
+		})
+	}
+
+	if len(runs) == 0 {
+		return nil, nil
+	}
+
+	done := make(chan *remoteRun)
+	for _, r := range runs {
+		fmt.Printf("Running %d tests on %s builder %s\n", len(r.tests), r.builder.Arch, r.builder)
+		go func(r *remoteRun) {
+			r.err = r.run(kola.CosaBuild.Meta.BuildID, flags)
+			done <- r
+		}(r)
+	}
+	return func(reporter reporters.Reporters) bool {
+		for range runs {
+			<-done
+		}
+		failed := false
+		for _, r := range runs {
+			if r.merge(reporter) {
+				failed = true
+			}
+		}
+		return failed
+	}, nil
+}
@@ -0,0 +1,130 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestParseRemoteBuilder(t *testing.T) {
+	for _, tt := range []struct {
+		spec    string
+		builder remoteBuilder
+		valid   bool
+	}{
+		{
+			spec:    "aarch64=ssh://arm-host/srv/cosa",
+			builder: remoteBuilder{Arch: "aarch64", SSH: "arm-host", Workdir: "/srv/cosa"},
+			valid:   true,
+		},
+		{
+			spec:    "aarch64=ssh://builder@arm-host:2222/srv/cosa",
+			builder: remoteBuilder{Arch: "aarch64", SSH: "builder@arm-host", Port: "2222", Workdir: "/srv/cosa"},
+			valid:   true,
+		},
+		{
+			spec:    "s390x=podman://z-host/srv/cosa",
+			builder: remoteBuilder{Arch: "s390x", Connection: "z-host", Image: "quay.io/example/cosa", Workdir: "/srv/cosa"},
+			valid:   true,
+		},
+		{spec: "ssh://arm-host/srv/cosa"},
+		{spec: "=ssh://arm-host/srv/cosa"},
+		{spec: "aarch64=ssh://arm-host"},
+		{spec: "aarch64=ssh://arm-host/"},
+		{spec: "aarch64=ssh:///srv/cosa"},
+		{spec: "aarch64=podman://z-host"},
+		{spec: "aarch64=http://arm-host/srv/cosa"},
+		{spec: "aarch64=arm-host:/srv/cosa"},
+	} {
+		t.Run(tt.spec, func(t *testing.T) {
+			b, err := parseRemoteBuilder(tt.spec, "quay.io/example/cosa")
+			if valid := err == nil; valid != tt.valid {
+				t.Fatalf("got error %v, expected valid %v", err, tt.valid)
+			}
+			if tt.valid && b != tt.builder {
+				t.Errorf("got %+v, expected %+v", b, tt.builder)
+			}
+		})
+	}
+}
+
+// remoteTestisoCommand returns a command with testiso-like flags.
+func remoteTestisoCommand() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Flags().StringSlice("instrument", nil, "")
+	cmd.Flags().StringArray("post-install-tests", nil, "")
+	cmd.Flags().String("tftp-faults", "", "")
+	cmd.Flags().Bool("inst-insecure", false, "")
+	cmd.Flags().String("output-dir", "", "")
+	cmd.Flags().StringArray("remote-builder", nil, "")
+	return cmd
+}
+
+func TestForwardedTestisoFlags(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		args []string
+	}{
+		{"none", nil},
+		{"scalars", []string{"--inst-insecure", "--tftp-faults", "drop-every=50,fail=^/?initrd"}},
+		{"local", []string{"--output-dir", "tmp/out", "--remote-builder", "aarch64=ssh://arm-host/srv/cosa"}},
+		{"slices", []string{"--instrument", "console,debug", "--post-install-tests", "ext.config.*"}},
+		{"commas in elements", []string{"--instrument", `"a,b",c`, "--post-install-tests", "a,b", "--post-install-tests", "c"}},
+		{"quotes in elements", []string{"--instrument", `"say ""hi""",x`}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			local := remoteTestisoCommand()
+			if err := local.ParseFlags(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			remote := remoteTestisoCommand()
+			if err := remote.ParseFlags(forwardedTestisoFlags(local)); err != nil {
+				t.Fatalf("parsing forwarded flags: %v", err)
+			}
+			for _, name := range []string{"instrument", "post-install-tests", "tftp-faults", "inst-insecure"} {
+				l, r := local.Flags().Lookup(name), remote.Flags().Lookup(name)
+				if l.Value.String() != r.Value.String() || l.Changed != r.Changed {
+					t.Errorf("%s: got %v, expected %v", name, r.Value, l.Value)
+				}
+			}
+			for _, name := range []string{"output-dir", "remote-builder"} {
+				if remote.Flags().Lookup(name).Changed {
+					t.Errorf("local flag %s forwarded", name)
+				}
+			}
+		})
+	}
+}
+
+func TestForwardedTestisoFlagsElements(t *testing.T) {
+	local := remoteTestisoCommand()
+	if err := local.ParseFlags([]string{"--instrument", `"a,b",c`}); err != nil {
+		t.Fatal(err)
+	}
+	remote := remoteTestisoCommand()
+	if err := remote.ParseFlags(forwardedTestisoFlags(local)); err != nil {
+		t.Fatal(err)
+	}
+	got, err := remote.Flags().GetStringSlice("instrument")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"a,b", "c"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %q, expected %q", got, expected)
+	}
+}
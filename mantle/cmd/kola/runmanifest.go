@@ -0,0 +1,124 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/coreos/coreos-assembler/mantle/kola"
+)
+
+// unrecordedRunFlags are the flags which aren't recorded in a run's
+// manifest, because they describe where it ran rather than what, because
+// the manifest records them itself, or because they only affect which
+// tests are selected, which the manifest records exactly.
+var unrecordedRunFlags = map[string]bool{
+	"from-manifest":   true,
+	"output-dir":      true,
+	"workdir":         true,
+	"event-stream":    true,
+	"control-address": true,
+	"control-info":    true,
+	"platform":        true,
+	"arch":            true,
+	"distro":          true,
+	"build":           true,
+	"shard":           true,
+	"shard-durations": true,
+	"sharding":        true,
+	"denylist-test":   true,
+}
+
+// manifestFlags returns the flags the user set on the command line for
+// recording in the run's manifest, leaving out credentials.
+func manifestFlags(cmd *cobra.Command) []string {
+	var args []string
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		if unrecordedRunFlags[f.Name] || strings.Contains(f.Name, "token") || strings.Contains(f.Name, "api-key") {
+			return
+		}
+		if slice, ok := f.Value.(pflag.SliceValue); ok {
+			for _, v := range slice.GetSlice() {
+				args = append(args, fmt.Sprintf("--%s=%s", f.Name, v))
+			}
+			return
+		}
+		args = append(args, fmt.Sprintf("--%s=%s", f.Name, f.Value.String()))
+	})
+	return args
+}
+
+// applyRunManifest loads the --from-manifest manifest and sets the flags
+// of its run which weren't given on the command line, which take
+// precedence.
+func applyRunManifest(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("--from-manifest runs the tests of the manifest; don't also give test patterns")
+	}
+	for _, name := range []string{"shard", "sharding"} {
+		if cmd.Flags().Changed(name) {
+			return fmt.Errorf("--from-manifest and --%s are mutually exclusive", name)
+		}
+	}
+	m, err := kola.LoadRunManifest(runFromManifest)
+	if err != nil {
+		return err
+	}
+
+	given := make(map[string]bool)
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		given[f.Name] = true
+	})
+	set := func(name, value string) error {
+		if given[name] || value == "" {
+			return nil
+		}
+		if cmd.Flags().Lookup(name) == nil {
+			plog.Warningf("Ignoring --%s of the manifest's run, which kola run doesn't have", name)
+			return nil
+		}
+		if err := cmd.Flags().Set(name, value); err != nil {
+			return fmt.Errorf("setting --%s from the manifest: %v", name, err)
+		}
+		return nil
+	}
+	for _, flag := range []struct{ name, value string }{
+		{"platform", m.Platform},
+		{"arch", m.Arch},
+		{"distro", m.Distribution},
+		{"build", m.Build},
+	} {
+		if err := set(flag.name, flag.value); err != nil {
+			return err
+		}
+	}
+	for _, arg := range m.Flags {
+		name, value, ok := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		if !ok {
+			return fmt.Errorf("malformed flag %q in manifest", arg)
+		}
+		if err := set(name, value); err != nil {
+			return err
+		}
+	}
+
+	kola.FromManifest = m
+	plog.Noticef("Running the %d tests of the manifest on %s", len(m.Tests), m.Platform)
+	return nil
+}
@@ -21,6 +21,7 @@ import (
 	"os"
 	"os/user"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -57,6 +58,10 @@ var (
 	spawnSSHKeys        []string
 	spawnJSONInfoFd     int
 	spawnSSHCommand     string
+	spawnForwards       []string
+	spawnBindRO         []string
+	spawnBindRW         []string
+	spawnSSHConfig      string
 )
 
 func init() {
@@ -73,6 +78,10 @@ func init() {
 	cmdSpawn.Flags().BoolVarP(&spawnSetSSHKeys, "keys", "k", false, "add SSH keys from --key options")
 	cmdSpawn.Flags().StringSliceVar(&spawnSSHKeys, "key", nil, "path to SSH public key (default: SSH agent + ~/.ssh/id_{rsa,dsa,ecdsa,ed25519}.pub)")
 	cmdSpawn.Flags().StringVarP(&spawnSSHCommand, "ssh-command", "x", "", "Command to execute instead of spawning a shell")
+	cmdSpawn.Flags().StringArrayVar(&spawnForwards, "forward", nil, "Forward a host port to a guest port, as [HOSTPORT:]GUESTPORT; without HOSTPORT, a free port is chosen (QEMU only)")
+	cmdSpawn.Flags().StringArrayVar(&spawnBindRO, "bind-ro", nil, "Mount $hostpath,$guestpath readonly; for example --bind-ro=/path/on/host,/var/mnt/guest (QEMU only)")
+	cmdSpawn.Flags().StringArrayVar(&spawnBindRW, "bind-rw", nil, "Mount $hostpath,$guestpath writable; for example --bind-rw=/path/on/host,/var/mnt/guest (QEMU only)")
+	cmdSpawn.Flags().StringVar(&spawnSSHConfig, "ssh-config", "", "Write an OpenSSH config for reaching the machines to this path (default \"<output-dir>/ssh_config\" if they're kept)")
 	root.AddCommand(cmdSpawn)
 }
 
@@ -98,6 +107,29 @@ func runSpawn(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("Cannot use --reconnect on non-qemu platforms %v", kolaPlatform)
 	}
 
+	forwards, err := parseSpawnForwards(spawnForwards)
+	if err != nil {
+		return err
+	}
+	var mounts []platform.BindMount
+	for _, b := range spawnBindRO {
+		src, dest, err := parseBindOpt(b)
+		if err != nil {
+			return err
+		}
+		mounts = append(mounts, platform.BindMount{Source: src, Dest: dest, ReadOnly: true})
+	}
+	for _, b := range spawnBindRW {
+		src, dest, err := parseBindOpt(b)
+		if err != nil {
+			return err
+		}
+		mounts = append(mounts, platform.BindMount{Source: src, Dest: dest})
+	}
+	if (len(forwards) > 0 || len(mounts) > 0) && kolaPlatform != "qemu" {
+		return fmt.Errorf("Cannot use --forward, --bind-ro or --bind-rw on platform %v", kolaPlatform)
+	}
+
 	var userdata *conf.UserData
 	if spawnUserData != "" {
 		userbytes, err := os.ReadFile(spawnUserData)
@@ -106,6 +138,9 @@ func runSpawn(cmd *cobra.Command, args []string) error {
 		}
 		userdata = conf.Unknown(string(userbytes))
 	}
+	if len(mounts) > 0 && userdata == nil {
+		userdata = conf.EmptyIgnition()
+	}
 	if spawnSetSSHKeys {
 		if userdata == nil {
 			userdata = conf.EmptyIgnition()
@@ -124,6 +159,12 @@ func runSpawn(cmd *cobra.Command, args []string) error {
 		return errors.Wrapf(err, "Setup failed")
 	}
 
+	stopControl, err := startControl()
+	if err != nil {
+		return err
+	}
+	defer stopControl()
+
 	flight, err := kola.NewFlight(kolaPlatform)
 	if err != nil {
 		return errors.Wrapf(err, "Flight failed")
@@ -154,6 +195,7 @@ func runSpawn(cmd *cobra.Command, args []string) error {
 		defer jsonInfoFile.Close()
 	}
 
+	var machines []platform.Machine
 	var someMach platform.Machine
 	// XXX: should spawn in parallel
 	for i := 0; i < spawnNodeCount; i++ {
@@ -163,7 +205,7 @@ func runSpawn(cmd *cobra.Command, args []string) error {
 			fmt.Println("Spawning machine...")
 		}
 		// use qemu-specific interface only if needed
-		if strings.HasPrefix(kolaPlatform, "qemu") && (spawnMachineOptions != "" || !spawnRemove) {
+		if strings.HasPrefix(kolaPlatform, "qemu") && (spawnMachineOptions != "" || !spawnRemove || len(forwards) > 0 || len(mounts) > 0) {
 			machineOpts := platform.QemuMachineOptions{
 				DisablePDeathSig: !spawnRemove,
 			}
@@ -178,6 +220,15 @@ func runSpawn(cmd *cobra.Command, args []string) error {
 					return errors.Wrapf(err, "Could not unmarshal machine options")
 				}
 			}
+			if len(forwards) > 0 {
+				if len(machineOpts.HostForwardPorts) == 0 {
+					machineOpts.HostForwardPorts = []platform.HostForwardPort{
+						{Service: "ssh", HostPort: 0, GuestPort: 22},
+					}
+				}
+				machineOpts.HostForwardPorts = append(machineOpts.HostForwardPorts, forwards...)
+			}
+			machineOpts.BindMounts = append(machineOpts.BindMounts, mounts...)
 
 			switch qc := cluster.(type) {
 			case *qemu.Cluster:
@@ -195,6 +246,9 @@ func runSpawn(cmd *cobra.Command, args []string) error {
 		if spawnVerbose {
 			fmt.Printf("Machine %v spawned at %v\n", mach.ID(), mach.IP())
 		}
+		if len(forwards) > 0 {
+			printForwardedPorts(mach)
+		}
 		if jsonInfoFile != nil {
 			if err := platform.WriteJSONInfo(mach, jsonInfoFile); err != nil {
 				return fmt.Errorf("Failed writing JSON info: %v", err)
@@ -202,6 +256,21 @@ func runSpawn(cmd *cobra.Command, args []string) error {
 		}
 
 		someMach = mach
+		machines = append(machines, mach)
+	}
+
+	sshConfig := spawnSSHConfig
+	if sshConfig == "" && !spawnRemove {
+		sshConfig = filepath.Join(outputDir, "ssh_config")
+	}
+	if sshConfig != "" {
+		if err := writeSpawnSSHConfig(sshConfig, machines); err != nil {
+			return errors.Wrapf(err, "Writing SSH config failed")
+		}
+		fmt.Printf("Connect with: ssh -F %s %s\n", sshConfig, someMach.ID())
+		if !spawnRemove && !spawnSetSSHKeys {
+			plog.Warningf("The machines only accept kola's own SSH key, which is gone once kola exits; pass --keys to add yours")
+		}
 	}
 
 	if spawnSSHCommand != "" {
@@ -301,3 +370,66 @@ func addSSHKeys(userdata *conf.UserData) (*conf.UserData, error) {
 	}
 	return userdata, nil
 }
+
+// parseSpawnForwards parses --forward options, [HOSTPORT:]GUESTPORT.
+func parseSpawnForwards(specs []string) ([]platform.HostForwardPort, error) {
+	var forwards []platform.HostForwardPort
+	for _, spec := range specs {
+		host, guest, ok := strings.Cut(spec, ":")
+		if !ok {
+			host, guest = "0", spec
+		}
+		hostPort, err := strconv.Atoi(host)
+		if err != nil || hostPort < 0 || hostPort > 65535 {
+			return nil, fmt.Errorf("invalid host port in --forward %q", spec)
+		}
+		guestPort, err := strconv.Atoi(guest)
+		if err != nil || guestPort <= 0 || guestPort > 65535 {
+			return nil, fmt.Errorf("invalid guest port in --forward %q", spec)
+		}
+		forwards = append(forwards, platform.HostForwardPort{
+			Service:   fmt.Sprintf("forward-%d", guestPort),
+			HostPort:  hostPort,
+			GuestPort: guestPort,
+		})
+	}
+	return forwards, nil
+}
+
+// printForwardedPorts prints the host ports forwarded to the machine's
+// ports, which may have been chosen as it was created.
+func printForwardedPorts(m platform.Machine) {
+	fm, ok := m.(interface {
+		HostForwardedPorts() []platform.HostForwardPort
+	})
+	if !ok {
+		return
+	}
+	for _, p := range fm.HostForwardedPorts() {
+		if strings.HasPrefix(p.Service, "forward-") {
+			fmt.Printf("Forwarding localhost:%d to port %d of %v\n", p.HostPort, p.GuestPort, m.ID())
+		}
+	}
+}
+
+// writeSpawnSSHConfig writes an OpenSSH config with a host named by each
+// machine's ID, so that they can be reached after kola exits.
+func writeSpawnSSHConfig(path string, machines []platform.Machine) error {
+	var b strings.Builder
+	for _, m := range machines {
+		host, port, err := net.SplitHostPort(m.IP())
+		if err != nil {
+			host, port = m.IP(), "22"
+		}
+		fmt.Fprintf(&b, "Host %s\n", m.ID())
+		fmt.Fprintf(&b, "  HostName %s\n", host)
+		fmt.Fprintf(&b, "  Port %s\n", port)
+		fmt.Fprintf(&b, "  User core\n")
+		// Machines are recreated with the same addresses, and their
+		// host keys are new each time
+		fmt.Fprintf(&b, "  StrictHostKeyChecking no\n")
+		fmt.Fprintf(&b, "  UserKnownHostsFile /dev/null\n")
+		fmt.Fprintf(&b, "  LogLevel ERROR\n\n")
+	}
+	return os.WriteFile(path, []byte(b.String()), 0644)
+}
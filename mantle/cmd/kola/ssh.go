@@ -0,0 +1,163 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/coreos/coreos-assembler/mantle/kola/control"
+)
+
+var cmdSSH = &cobra.Command{
+	RunE:  runSSH,
+	Use:   "ssh <machine-id|test-name> [-- command...]",
+	Short: "SSH into a machine of a running kola session",
+	Long: `SSH into a machine of a running kola run or kola spawn.
+
+The machine is looked up through the control API of the session, whose URL
+and token are read from the --control-info file it was started with. It may
+be named by its ID or by the name of the test it was created for, when the
+test has a single machine. The SSH agent holding the session's key and the
+machine's address, including the forwarded port of QEMU machines on usermode
+networking, are passed to ssh, along with the command to run, if any.
+`,
+	Example: `  kola run --control-address 127.0.0.1:0 --control-info control.json basic &
+  kola ssh --control-info control.json basic
+  kola ssh --control-info control.json basic -- journalctl -b`,
+	Args:         cobra.MinimumNArgs(1),
+	SilenceUsage: true,
+}
+
+func init() {
+	root.AddCommand(cmdSSH)
+}
+
+func runSSH(cmd *cobra.Command, args []string) error {
+	if controlInfo == "" {
+		return fmt.Errorf("--control-info is required to find the session's machines")
+	}
+	if cmd.ArgsLenAtDash() > 1 {
+		return fmt.Errorf("expected a single machine ID or test name before --")
+	}
+	m, err := findControlMachine(controlInfo, args[0])
+	if err != nil {
+		return err
+	}
+
+	host, port, err := net.SplitHostPort(m.IP)
+	if err != nil {
+		host, port = m.IP, "22"
+	}
+	sshArgs := []string{
+		"-o", "User=core",
+		"-o", "StrictHostKeyChecking=no",
+		"-o", "UserKnownHostsFile=/dev/null",
+		"-o", "CheckHostIP=no",
+		"-o", "LogLevel=ERROR",
+		"-o", "PreferredAuthentications=publickey",
+		"-p", port,
+	}
+	if m.SSHAuthSock != "" {
+		sshArgs = append(sshArgs, "-o", "IdentityAgent="+m.SSHAuthSock)
+	}
+	sshArgs = append(sshArgs, host)
+	if len(args) > 1 {
+		sshArgs = append(sshArgs, "--")
+		sshArgs = append(sshArgs, args[1:]...)
+	}
+
+	ssh := exec.Command("ssh", sshArgs...)
+	ssh.Stdin = os.Stdin
+	ssh.Stdout = os.Stdout
+	ssh.Stderr = os.Stderr
+	if err := ssh.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			os.Exit(exitErr.ExitCode())
+		}
+		return fmt.Errorf("running ssh: %v", err)
+	}
+	return nil
+}
+
+// findControlMachine asks the control API described by infoPath for the
+// machine with the ID name, or else the only machine of the test name.
+func findControlMachine(infoPath, name string) (control.Machine, error) {
+	buf, err := os.ReadFile(infoPath)
+	if err != nil {
+		return control.Machine{}, err
+	}
+	var info control.Info
+	if err := json.Unmarshal(buf, &info); err != nil {
+		return control.Machine{}, fmt.Errorf("parsing %s: %v", infoPath, err)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, info.URL+"/v1/clusters", nil)
+	if err != nil {
+		return control.Machine{}, err
+	}
+	req.Header.Set("Authorization", "Bearer "+info.Token)
+	client := http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return control.Machine{}, fmt.Errorf("querying control API at %s (is the session still running?): %v", info.URL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&apiErr); err != nil || apiErr.Error == "" {
+			apiErr.Error = resp.Status
+		}
+		return control.Machine{}, fmt.Errorf("querying control API: %s", apiErr.Error)
+	}
+	var clusters []control.Cluster
+	if err := json.NewDecoder(resp.Body).Decode(&clusters); err != nil {
+		return control.Machine{}, fmt.Errorf("parsing control API response: %v", err)
+	}
+
+	var byTest []control.Machine
+	for _, c := range clusters {
+		for _, m := range c.Machines {
+			if m.ID == name {
+				return m, nil
+			}
+			if m.Test == name {
+				byTest = append(byTest, m)
+			}
+		}
+	}
+	switch len(byTest) {
+	case 0:
+		return control.Machine{}, fmt.Errorf("no running machine or test named %q", name)
+	case 1:
+		return byTest[0], nil
+	default:
+		var ids []string
+		for _, m := range byTest {
+			ids = append(ids, m.ID)
+		}
+		return control.Machine{}, fmt.Errorf("test %q has %d machines; pick one of: %s", name, len(ids), strings.Join(ids, ", "))
+	}
+}
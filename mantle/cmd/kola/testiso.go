@@ -23,6 +23,7 @@ import (
 	"bufio"
 	"context"
 	_ "embed"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -32,9 +33,11 @@ import (
 	"time"
 
 	"github.com/coreos/coreos-assembler/mantle/harness"
+	"github.com/coreos/coreos-assembler/mantle/harness/artifacts"
 	"github.com/coreos/coreos-assembler/mantle/harness/reporters"
 	"github.com/coreos/coreos-assembler/mantle/harness/testresult"
 	"github.com/coreos/coreos-assembler/mantle/platform/conf"
+	"github.com/coreos/coreos-assembler/mantle/platform/instrument"
 	"github.com/coreos/coreos-assembler/mantle/util"
 	coreosarch "github.com/coreos/stream-metadata-go/arch"
 	"github.com/pkg/errors"
@@ -42,7 +45,9 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/coreos/coreos-assembler/mantle/kola"
+	"github.com/coreos/coreos-assembler/mantle/network/tftp"
 	"github.com/coreos/coreos-assembler/mantle/platform"
+	"github.com/coreos/coreos-assembler/mantle/system/exec"
 )
 
 var (
@@ -55,25 +60,62 @@ var (
 		SilenceUsage: true,
 	}
 
-	instInsecure bool
+	instInsecure     bool
+	verifySignatures bool
+	metalSignature   string
 
 	pxeKernelArgs []string
+	probes        []string
+
+	installerImage   string
+	registryAuthFile string
+	hostInstaller    string
 
 	console bool
 
-	addNmKeyfile     bool
-	enable4k         bool
-	enableMultipath  bool
-	enableUefi       bool
-	enableUefiSecure bool
-	isOffline        bool
-	isISOFromRAM     bool
+	postInstallTests []string
+	verifyDisk       bool
+
+	nativeTFTP bool
+	tftpFaults string
+
+	remoteBuilders     []string
+	remoteBuilderImage string
+
+	addNmKeyfile       bool
+	enable4k           bool
+	enableEckdGeometry bool
+	enableMultipath    bool
+	enableOpal         bool
+	enableUefi         bool
+	enableUefiSecure   bool
+	isOffline          bool
+	isISOFromRAM       bool
+	corruptSignature   bool
+	corruptOsmet       bool
+	// rootSha256, if set, is checked against the root partition
+	// coreos-installer wrote
+	rootSha256 string
 
 	// These tests only run on RHCOS
 	tests_RHCOS_uefi = []string{
 		"iso-fips.uefi",
 	}
 
+	// These tests only run with --verify-signatures. They install with a
+	// corrupted signature of the metal image, which must fail. The
+	// firmware of the architecture is appended.
+	tests_badsig = []string{
+		"iso-install-badsig",
+		"pxe-online-install-badsig",
+	}
+	badsigFirmwares = map[string]string{
+		"x86_64":  "bios",
+		"aarch64": "uefi",
+		"ppc64le": "ppcfw",
+		"s390x":   "s390fw",
+	}
+
 	// The iso-as-disk tests are only supported in x86_64 because other
 	// architectures don't have the required hybrid partition table.
 	tests_x86_64 = []string{
@@ -81,17 +123,23 @@ var (
 		"iso-as-disk.uefi",
 		"iso-as-disk.uefi-secure",
 		"iso-as-disk.4k.uefi",
+		"iso-container-install.bios",
+		"iso-container-install.uefi",
+		"iso-customize-install.nm.bios",
 		"iso-install.bios",
 		"iso-live-login.bios",
 		"iso-live-login.uefi",
 		"iso-live-login.uefi-secure",
 		"iso-live-login.4k.uefi",
+		"iso-offline-customize-install.uefi",
 		"iso-offline-install.bios",
+		"iso-offline-install-badosmet.bios",
 		"iso-offline-install.mpath.bios",
 		"iso-offline-install-fromram.4k.uefi",
 		"iso-offline-install-iscsi.ibft.uefi",
 		"iso-offline-install-iscsi.ibft-with-mpath.bios",
 		"iso-offline-install-iscsi.manual.bios",
+		"iso-osmet-vs-url-install.bios",
 		"miniso-install.bios",
 		"miniso-install.nm.bios",
 		"miniso-install.4k.uefi",
@@ -103,11 +151,14 @@ var (
 	}
 	tests_s390x = []string{
 		"iso-live-login.s390fw",
+		"iso-offline-customize-install.s390fw",
 		"iso-offline-install.s390fw",
 		"iso-offline-install.mpath.s390fw",
 		"iso-offline-install.4k.s390fw",
+		"iso-offline-install.eckd-geometry.s390fw",
 		"pxe-online-install.rootfs-appended.s390fw",
 		"pxe-offline-install.s390fw",
+		"pxe-offline-install.eckd-geometry.s390fw",
 		"miniso-install.s390fw",
 		"miniso-install.nm.s390fw",
 		"miniso-install.4k.nm.s390fw",
@@ -118,6 +169,7 @@ var (
 	}
 	tests_ppc64le = []string{
 		"iso-live-login.ppcfw",
+		"iso-offline-customize-install.ppcfw",
 		"iso-offline-install.ppcfw",
 		"iso-offline-install.mpath.ppcfw",
 		"iso-offline-install-fromram.4k.ppcfw",
@@ -132,12 +184,22 @@ var (
 		//"iso-offline-install-iscsi.ibft-with-mpath.ppcfw",
 		//"iso-offline-install-iscsi.manual.ppcfw",
 	}
+	// These tests boot the PowerNV machine, with petitboot as the
+	// bootloader, so they only run with --qemu-petitboot-image
+	tests_ppc64le_opal = []string{
+		"pxe-offline-install.opal",
+		"pxe-online-install.opal",
+	}
 	tests_aarch64 = []string{
+		"iso-container-install.uefi",
 		"iso-live-login.uefi",
 		"iso-live-login.4k.uefi",
+		"iso-offline-customize-install.uefi",
 		"iso-offline-install.uefi",
+		"iso-offline-install-badosmet.uefi",
 		"iso-offline-install.mpath.uefi",
 		"iso-offline-install-fromram.4k.uefi",
+		"iso-osmet-vs-url-install.uefi",
 		"miniso-install.uefi",
 		"miniso-install.nm.uefi",
 		"miniso-install.4k.uefi",
@@ -166,6 +228,7 @@ Requires=dev-virtio\\x2dports-testisocompletion.device
 OnFailure=emergency.target
 OnFailureJobMode=isolate
 Before=coreos-installer.service
+Before=coreos-installer-container.service
 [Service]
 Type=oneshot
 RemainAfterExit=yes
@@ -203,11 +266,22 @@ OnFailureJobMode=isolate
 [Service]
 Type=oneshot
 RemainAfterExit=yes
-ExecStart=/bin/sh -c '/usr/bin/echo %s >/dev/virtio-ports/testisocompletion && systemctl poweroff'
+ExecStart=/bin/sh -c '/usr/bin/echo %s >/dev/virtio-ports/testisocompletion'
+ExecStart=/usr/bin/systemctl --no-block poweroff
 [Install]
 RequiredBy=multi-user.target
 `, signalCompleteString)
 
+// completionUnit returns the unit of the installed system which signals
+// completion. It stays up for the --post-install-tests, rather than
+// powering off.
+func completionUnit() string {
+	if len(postInstallTests) > 0 {
+		return strings.Replace(signalCompletionUnit, "ExecStart=/usr/bin/systemctl --no-block poweroff\n", "", 1)
+	}
+	return signalCompletionUnit
+}
+
 var signalEmergencyString = "coreos-installer-test-entered-emergency-target"
 var signalFailureUnit = fmt.Sprintf(`[Unit]
 Description=TestISO Signal Failure
@@ -221,32 +295,6 @@ ExecStart=/bin/sh -c '/usr/bin/echo %s >/dev/virtio-ports/testisocompletion && s
 RequiredBy=emergency.target
 `, signalEmergencyString)
 
-var checkNoIgnition = `[Unit]
-Description=TestISO Verify No Ignition Config
-OnFailure=emergency.target
-OnFailureJobMode=isolate
-Before=coreos-test-installer.service
-After=coreos-ignition-firstboot-complete.service
-RequiresMountsFor=/boot
-[Service]
-Type=oneshot
-RemainAfterExit=yes
-ExecStart=/bin/sh -c '[ ! -e /boot/ignition ]'
-[Install]
-RequiredBy=multi-user.target`
-
-var multipathedRoot = `[Unit]
-Description=TestISO Verify Multipathed Root
-OnFailure=emergency.target
-OnFailureJobMode=isolate
-Before=coreos-test-installer.service
-[Service]
-Type=oneshot
-RemainAfterExit=yes
-ExecStart=/bin/bash -c '[[ $(findmnt -nvro SOURCE /sysroot) == /dev/mapper/mpatha4 ]]'
-[Install]
-RequiredBy=multi-user.target`
-
 // This test is broken. Please fix!
 // https://github.com/coreos/coreos-assembler/issues/3554
 var verifyNoEFIBootEntry = `[Unit]
@@ -283,6 +331,40 @@ ExecStart=bash -c "if mountpoint /run/media/iso 2>/dev/null; then exit 1; fi"
 [Install]
 RequiredBy=coreos-installer.target`
 
+// corruptOsmetUnit overwrites part of the live system's osmet data before
+// coreos-installer unpacks the metal image from it. It fails if there's
+// none, since the install wouldn't then test anything.
+var corruptOsmetUnit = `[Unit]
+Description=TestISO Corrupt osmet Data
+Before=coreos-installer.service
+[Service]
+Type=oneshot
+RemainAfterExit=yes
+StandardOutput=kmsg+console
+StandardError=kmsg+console
+ExecStart=/bin/sh -c 'ls /run/coreos-installer/osmet/*.osmet'
+ExecStart=/bin/sh -c 'for f in /run/coreos-installer/osmet/*.osmet; do dd if=/dev/urandom of=$$f bs=1M count=1 seek=8 conv=notrunc; done'
+[Install]
+RequiredBy=coreos-installer.service`
+
+// verifyRootUnit checks the root partition coreos-installer wrote against
+// the sha256 of the metal image's.
+var verifyRootUnit = `[Unit]
+Description=TestISO Verify Installed Root Partition
+OnFailure=emergency.target
+OnFailureJobMode=isolate
+After=coreos-installer.service
+Before=coreos-installer.target
+[Service]
+Type=oneshot
+RemainAfterExit=yes
+StandardOutput=kmsg+console
+StandardError=kmsg+console
+ExecStart=/usr/bin/udevadm settle
+ExecStart=/bin/bash -c '[[ $(sha256sum </dev/disk/by-partlabel/root) == "%s  -" ]]'
+[Install]
+RequiredBy=coreos-installer.target`
+
 var nmConnectionId = "CoreOS DHCP"
 var nmConnectionFile = "coreos-dhcp.nmconnection"
 var nmConnection = fmt.Sprintf(`[connection]
@@ -338,12 +420,53 @@ var iscsi_butane_config string
 
 func init() {
 	cmdTestIso.Flags().BoolVarP(&instInsecure, "inst-insecure", "S", false, "Do not verify signature on metal image")
+	cmdTestIso.Flags().BoolVar(&verifySignatures, "verify-signatures", false, "Verify the signature on the metal image even for development builds, and run the tests installing with a corrupted one")
+	cmdTestIso.Flags().StringVar(&metalSignature, "metal-sig", "", "GPG signature of the metal image to verify it with (default the image's .sig in the build)")
 	cmdTestIso.Flags().BoolVar(&console, "console", false, "Connect qemu console to terminal, turn off automatic initramfs failure checking")
 	cmdTestIso.Flags().StringSliceVar(&pxeKernelArgs, "pxe-kargs", nil, "Additional kernel arguments for PXE")
+	cmdTestIso.Flags().StringSliceVar(&probes, "instrument", nil, "Probes to add to the live system, of "+strings.Join(instrument.Probes, ", ")+" (default autologin and console, and debug if $COSA_TESTISO_DEBUG is set); boot-started is always added")
+	cmdTestIso.Flags().StringVar(&installerImage, "installer-image", instrument.DefaultInstallerImage, "coreos-installer container image the iso-container-install tests install with")
+	cmdTestIso.Flags().StringVar(&registryAuthFile, "registry-auth-file", "", "registry auth file the live system pulls --installer-image with")
+	cmdTestIso.Flags().StringVar(&hostInstaller, "host-installer", "", "coreos-installer which modifies ISOs on the host: the path of a binary, or oci://IMAGE to run it from a container image (default coreos-installer in $PATH)")
+	cmdTestIso.Flags().StringArrayVar(&postInstallTests, "post-install-tests", nil, "kola tests (glob patterns) to run against the installed system of the PXE and ISO install tests; only those which can run on a reused machine are")
+	cmdTestIso.Flags().BoolVar(&verifyDisk, "verify-disk", false, "Once the installed system powers off, compare the partitions of the disk the install and first boot don't write to against the metal image")
+	cmdTestIso.Flags().BoolVar(&nativeTFTP, "native-tftp", false, "Serve PXE installs over TFTP with kola's server, which logs each transfer, rather than QEMU's; needs to bind port 69 on the host's loopback")
+	cmdTestIso.Flags().StringVar(&tftpFaults, "tftp-faults", "", "Failures to inject into --native-tftp transfers, as drop-every=N, delay=DURATION and fail=REGEX, comma-separated")
+	cmdTestIso.Flags().StringArrayVar(&remoteBuilders, "remote-builder", nil, "Run the tests of another architecture on a remote builder, as ARCH=ssh://[USER@]HOST[:PORT]/WORKDIR or ARCH=podman://CONNECTION/WORKDIR, with its output in <output-dir>/ARCH; can be specified multiple times")
+	cmdTestIso.Flags().StringVar(&remoteBuilderImage, "remote-builder-image", defaultRemoteBuilderImage, "coreos-assembler container image podman remote builders run")
+	cmdTestIso.Flags().StringArrayVarP(&runExternals, "exttest", "E", nil, "Externally defined tests for --post-install-tests (will be found in DIR/tests/kola, or in /usr/lib/coreos-assembler/tests/kola of an oci://IMAGE)")
 
 	root.AddCommand(cmdTestIso)
 }
 
+// testisoManifest records what a testiso run tested with, in manifest.json
+// in the output directory.
+type testisoManifest struct {
+	Build string `json:"build"`
+	Arch  string `json:"arch"`
+	// HostInstaller is the coreos-installer which modified the ISOs
+	HostInstaller        string `json:"host-installer"`
+	HostInstallerVersion string `json:"host-installer-version"`
+}
+
+func writeRunManifest(dir string) error {
+	version, err := platform.HostInstaller.Version()
+	if err != nil {
+		return err
+	}
+	plog.Noticef("Modifying ISOs with %s (%s)", version, platform.HostInstaller)
+	buf, err := json.MarshalIndent(testisoManifest{
+		Build:                kola.CosaBuild.Meta.BuildID,
+		Arch:                 coreosarch.CurrentRpmArch(),
+		HostInstaller:        platform.HostInstaller.String(),
+		HostInstallerVersion: version,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "manifest.json"), append(buf, '\n'), 0644)
+}
+
 func liveArtifactExistsInBuild() error {
 
 	if kola.CosaBuild.Meta.BuildArtifacts.LiveIso == nil || kola.CosaBuild.Meta.BuildArtifacts.LiveKernel == nil {
@@ -352,8 +475,7 @@ func liveArtifactExistsInBuild() error {
 	return nil
 }
 
-func getAllTests(build *util.LocalBuild) []string {
-	arch := coreosarch.CurrentRpmArch()
+func getAllTests(build *util.LocalBuild, arch string) []string {
 	var tests []string
 	switch arch {
 	case "x86_64":
@@ -368,6 +490,14 @@ func getAllTests(build *util.LocalBuild) []string {
 	if kola.CosaBuild.Meta.Name == "rhcos" && arch != "s390x" && arch != "ppc64le" {
 		tests = append(tests, tests_RHCOS_uefi...)
 	}
+	if arch == "ppc64le" && kola.QEMUOptions.PetitbootImage != "" {
+		tests = append(tests, tests_ppc64le_opal...)
+	}
+	if verifySignatures {
+		for _, test := range tests_badsig {
+			tests = append(tests, test+"."+badsigFirmwares[arch])
+		}
+	}
 	return tests
 }
 
@@ -377,7 +507,12 @@ func newBaseQemuBuilder(outdir string) (*platform.QemuBuilder, error) {
 		builder.Firmware = "uefi-secure"
 	} else if enableUefi {
 		builder.Firmware = "uefi"
+	} else if enableOpal {
+		builder.Firmware = "opal"
 	}
+	builder.FirmwareCode = kola.QEMUOptions.FirmwareCode
+	builder.FirmwareVars = kola.QEMUOptions.FirmwareVars
+	builder.PetitbootImage = kola.QEMUOptions.PetitbootImage
 
 	if err := os.MkdirAll(outdir, 0755); err != nil {
 		return nil, err
@@ -438,11 +573,11 @@ func forwardJournal(outdir string, builder *platform.QemuBuilder, config *conf.C
 	return nil
 }
 
-func newQemuBuilderWithDisk(outdir string) (*platform.QemuBuilder, *conf.Conf, error) {
+func newQemuBuilderWithDisk(outdir string) (*platform.QemuBuilder, *platform.Disk, *conf.Conf, error) {
 	builder, config, err := newQemuBuilder(outdir)
 
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	sectorSize := 0
@@ -455,20 +590,23 @@ func newQemuBuilderWithDisk(outdir string) (*platform.QemuBuilder, *conf.Conf, e
 		SectorSize:    sectorSize,
 		MultiPathDisk: enableMultipath,
 	}
+	if enableEckdGeometry {
+		disk.Channel = "dasd"
+	}
 
 	//TBD: see if we can remove this and just use AddDisk and inject bootindex during startup
 	if coreosarch.CurrentRpmArch() == "s390x" || coreosarch.CurrentRpmArch() == "aarch64" {
 		// s390x and aarch64 need to use bootindex as they don't support boot once
 		if err := builder.AddDisk(&disk); err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 	} else {
 		if err := builder.AddPrimaryDisk(&disk); err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 	}
 
-	return builder, config, nil
+	return builder, &disk, config, nil
 }
 
 // See similar semantics in the `filterTests` of `kola.go`.
@@ -488,11 +626,28 @@ func runTestIso(cmd *cobra.Command, args []string) (err error) {
 	if kola.CosaBuild == nil {
 		return fmt.Errorf("Must provide --build")
 	}
-	tests := getAllTests(kola.CosaBuild)
+	var builders []remoteBuilder
+	for _, spec := range remoteBuilders {
+		b, err := parseRemoteBuilder(spec, remoteBuilderImage)
+		if err != nil {
+			return err
+		}
+		if b.Arch == coreosarch.CurrentRpmArch() {
+			return fmt.Errorf("remote builder %s is of the local architecture", spec)
+		}
+		for _, other := range builders {
+			if other.Arch == b.Arch {
+				return fmt.Errorf("multiple remote builders for %s", b.Arch)
+			}
+		}
+		builders = append(builders, b)
+	}
+
+	tests := getAllTests(kola.CosaBuild, coreosarch.CurrentRpmArch())
 	if len(args) != 0 {
 		if tests, err = filterTests(tests, args); err != nil {
 			return err
-		} else if len(tests) == 0 {
+		} else if len(tests) == 0 && len(builders) == 0 {
 			return harness.SuiteEmpty
 		}
 	}
@@ -520,38 +675,101 @@ func runTestIso(cmd *cobra.Command, args []string) (err error) {
 		}
 	}
 
+	platform.HostInstaller, err = platform.ParseCoreOSInstaller(hostInstaller)
+	if err != nil {
+		return err
+	}
+
 	// note this reassigns a *global*
 	outputDir, err = kola.SetupOutputDir(outputDir, "testiso")
 	if err != nil {
 		return err
 	}
 
+	if err := writeRunManifest(outputDir); err != nil {
+		return err
+	}
+
+	if len(postInstallTests) > 0 {
+		if verifyDisk {
+			return fmt.Errorf("--verify-disk and --post-install-tests are mutually exclusive")
+		}
+		if err := registerExternals(); err != nil {
+			return err
+		}
+	}
+
 	// see similar code in suite.go
 	reportDir := filepath.Join(outputDir, "reports")
 	if err := os.Mkdir(reportDir, 0777); err != nil {
 		return err
 	}
 
-	reporter := reporters.NewJSONReporter("report.json", "testiso", "")
+	reporter, err := reporters.ForFormats(kola.ReportFormats, "testiso", "")
+	if err != nil {
+		return err
+	}
 	defer func() {
 		if reportErr := reporter.Output(reportDir); reportErr != nil && err != nil {
 			err = reportErr
 		}
 	}()
 
+	var waitRemoteRuns func(reporters.Reporters) bool
+	if len(builders) > 0 {
+		if waitRemoteRuns, err = startRemoteRuns(builders, args, forwardedTestisoFlags(cmd)); err != nil {
+			return err
+		}
+		if waitRemoteRuns == nil && len(tests) == 0 {
+			return harness.SuiteEmpty
+		}
+	}
+
 	baseInst := platform.Install{
 		CosaBuild:  kola.CosaBuild,
 		NmKeyfiles: make(map[string]string),
+		ImageCache: kola.ImageCache,
+	}
+
+	if cmd.Flags().Changed("instrument") {
+		opts, err := instrument.Parse(probes)
+		if err != nil {
+			return err
+		}
+		baseInst.Instrument = &opts
+	}
+
+	containerInstaller := instrument.ContainerInstaller{Image: installerImage}
+	if registryAuthFile != "" {
+		auth, err := os.ReadFile(registryAuthFile)
+		if err != nil {
+			return errors.Wrapf(err, "reading registry auth file")
+		}
+		containerInstaller.Auth = string(auth)
 	}
 
 	if instInsecure {
+		if verifySignatures {
+			return fmt.Errorf("--inst-insecure and --verify-signatures are mutually exclusive")
+		}
 		baseInst.Insecure = true
 		fmt.Printf("Ignoring verification of signature on metal image\n")
 	}
+	baseInst.SignatureFile = metalSignature
+
+	if tftpFaults != "" {
+		if !nativeTFTP {
+			return fmt.Errorf("--tftp-faults requires --native-tftp")
+		}
+		if baseInst.TFTPFaults, err = tftp.ParseFaults(tftpFaults); err != nil {
+			return errors.Wrapf(err, "parsing --tftp-faults")
+		}
+	}
+	baseInst.NativeTFTP = nativeTFTP
 
 	// Ignore signing verification by default when running with development build
 	// https://github.com/coreos/fedora-coreos-tracker/issues/908
-	if !baseInst.Insecure && strings.Contains(kola.CosaBuild.Meta.BuildID, ".dev.") {
+	if !baseInst.Insecure && !verifySignatures && strings.Contains(kola.CosaBuild.Meta.BuildID, ".dev.") {
 		baseInst.Insecure = true
 		fmt.Printf("Detected development build; disabling signature verification\n")
 	}
@@ -569,10 +787,15 @@ func runTestIso(cmd *cobra.Command, args []string) (err error) {
 
 		addNmKeyfile = false
 		enable4k = false
+		enableEckdGeometry = false
 		enableMultipath = false
 		enableUefi = false
+		enableOpal = false
 		enableUefiSecure = false
 		isOffline = false
+		corruptSignature = false
+		corruptOsmet = false
+		rootSha256 = ""
 		inst := baseInst // Pretend this is Rust and I wrote .copy()
 
 		fmt.Printf("Running test: %s\n", test)
@@ -584,6 +807,13 @@ func runTestIso(cmd *cobra.Command, args []string) (err error) {
 			enable4k = true
 			inst.Native4k = true
 		}
+		// A virtio disk with the geometry of a DASD, which is always 4k,
+		// so this also implies the 4k native image
+		if kola.HasString("eckd-geometry", components) {
+			enableEckdGeometry = true
+			enable4k = true
+			inst.Native4k = true
+		}
 		if kola.HasString("nm", components) {
 			addNmKeyfile = true
 		}
@@ -595,6 +825,8 @@ func runTestIso(cmd *cobra.Command, args []string) (err error) {
 			enableUefiSecure = true
 		} else if kola.HasString("uefi", components) {
 			enableUefi = true
+		} else if kola.HasString("opal", components) {
+			enableOpal = true
 		}
 		// For offline it is a part of the first component. i.e. for
 		// iso-offline-install.bios we need to search for 'offline' in
@@ -608,9 +840,15 @@ func runTestIso(cmd *cobra.Command, args []string) (err error) {
 		if kola.HasString("fromram", strings.Split(components[0], "-")) {
 			isISOFromRAM = true
 		}
+		if kola.HasString("badsig", strings.Split(components[0], "-")) {
+			corruptSignature = true
+		}
+		if kola.HasString("badosmet", strings.Split(components[0], "-")) {
+			corruptOsmet = true
+		}
 
 		switch components[0] {
-		case "pxe-offline-install", "pxe-online-install":
+		case "pxe-offline-install", "pxe-online-install", "pxe-online-install-badsig":
 			duration, err = testPXE(ctx, inst, filepath.Join(outputDir, test))
 		case "iso-as-disk":
 			duration, err = testAsDisk(ctx, filepath.Join(outputDir, test))
@@ -618,10 +856,18 @@ func runTestIso(cmd *cobra.Command, args []string) (err error) {
 			duration, err = testLiveLogin(ctx, filepath.Join(outputDir, test))
 		case "iso-fips":
 			duration, err = testLiveFIPS(ctx, filepath.Join(outputDir, test))
-		case "iso-install", "iso-offline-install", "iso-offline-install-fromram":
+		case "iso-install", "iso-offline-install", "iso-offline-install-fromram", "iso-install-badsig", "iso-offline-install-badosmet":
 			duration, err = testLiveIso(ctx, inst, filepath.Join(outputDir, test), false)
 		case "miniso-install":
 			duration, err = testLiveIso(ctx, inst, filepath.Join(outputDir, test), true)
+		case "iso-container-install":
+			inst.Container = &containerInstaller
+			duration, err = testLiveIso(ctx, inst, filepath.Join(outputDir, test), false)
+		case "iso-osmet-vs-url-install":
+			duration, err = testOsmetVsURL(ctx, inst, filepath.Join(outputDir, test))
+		case "iso-customize-install", "iso-offline-customize-install":
+			inst.Customize = true
+			duration, err = testLiveIso(ctx, inst, filepath.Join(outputDir, test), false)
 		case "iso-offline-install-iscsi":
 			var butane_config string
 			switch components[1] {
@@ -645,12 +891,17 @@ func runTestIso(cmd *cobra.Command, args []string) (err error) {
 			result = testresult.Fail
 			output = []byte(err.Error())
 		}
+		applyRetention(test, filepath.Join(outputDir, test), err != nil)
 		reporter.ReportTest(test, []string{}, result, duration, output)
 		if printResult(test, duration, err) {
 			atLeastOneFailed = true
 		}
 	}
 
+	if waitRemoteRuns != nil && waitRemoteRuns(reporter) {
+		atLeastOneFailed = true
+	}
+
 	reporter.SetResult(testresult.Pass)
 	if atLeastOneFailed {
 		reporter.SetResult(testresult.Fail)
@@ -660,6 +911,39 @@ func runTestIso(cmd *cobra.Command, args []string) (err error) {
 	return nil
 }
 
+// testisoArtifacts are the files a test leaves in its output directory
+// which are subject to the retention policy.
+var testisoArtifacts = []struct {
+	pattern string
+	class   artifacts.Class
+}{
+	{"*.txt", artifacts.Log},
+	{"*.ign", artifacts.Log},
+}
+
+// applyRetention removes what the retention policy doesn't keep of the
+// test's artifacts.
+func applyRetention(test, outdir string, failed bool) {
+	if kola.Artifacts == nil {
+		return
+	}
+	for _, a := range testisoArtifacts {
+		matches, _ := filepath.Glob(filepath.Join(outdir, a.pattern))
+		for _, match := range matches {
+			kola.Artifacts.Register(test, match, a.class)
+		}
+	}
+	removed, err := kola.Artifacts.Finish(test, failed)
+	for _, r := range removed {
+		if r.Capped {
+			fmt.Printf("Removed %s artifact %s (%d bytes): %s\n", r.Class, r.Path, r.Size, r.Reason)
+		}
+	}
+	if err != nil {
+		plog.Warningf("Applying artifact retention policy to %s: %v", test, err)
+	}
+}
+
 func awaitCompletion(ctx context.Context, inst *platform.QemuInstance, outdir string, qchan *os.File, booterrchan chan error, expected []string) (time.Duration, error) {
 	start := time.Now()
 	errchan := make(chan error)
@@ -801,16 +1085,29 @@ func testPXE(ctx context.Context, inst platform.Install, outdir string) (time.Du
 	}
 	defer os.RemoveAll(tmpd)
 
-	sshPubKeyBuf, _, err := util.CreateSSHAuthorizedKey(tmpd)
+	sshPubKeyBuf, sshKeyPath, err := util.CreateSSHAuthorizedKey(tmpd)
 	if err != nil {
 		return 0, errors.Wrapf(err, "creating SSH AuthorizedKey")
 	}
+	if len(postInstallTests) > 0 {
+		inst.SSHKeyFile = sshKeyPath
+	}
+	if corruptSignature {
+		if inst.SignatureFile, err = writeCorruptSignature(inst, tmpd); err != nil {
+			return 0, err
+		}
+	}
 
-	builder, virtioJournalConfig, err := newQemuBuilderWithDisk(outdir)
+	builder, disk, virtioJournalConfig, err := newQemuBuilderWithDisk(outdir)
 	if err != nil {
 		return 0, errors.Wrapf(err, "creating QemuBuilder")
 	}
 	inst.Builder = builder
+	inst.Runner = &exec.Runner{
+		Timeout:   platform.HelperRunner.Timeout,
+		Log:       os.Stderr,
+		AuditFile: filepath.Join(outdir, "commands.log"),
+	}
 	completionChannel, err := inst.Builder.VirtioChannelRead("testisocompletion")
 	if err != nil {
 		return 0, errors.Wrapf(err, "setting up virtio-serial channel")
@@ -830,21 +1127,24 @@ func testPXE(ctx context.Context, inst platform.Install, outdir string) (time.Du
 	}
 
 	targetConfig := *virtioJournalConfig
-	targetConfig.AddSystemdUnit("coreos-test-installer.service", signalCompletionUnit, conf.Enable)
+	targetConfig.AddSystemdUnit(instrument.InstallCompletionUnit, completionUnit(), conf.Enable)
 	targetConfig.AddSystemdUnit("coreos-test-entered-emergency-target.service", signalFailureUnit, conf.Enable)
-	targetConfig.AddSystemdUnit("coreos-test-installer-no-ignition.service", checkNoIgnition, conf.Enable)
 
 	mach, err := inst.PXE(pxeKernelArgs, liveConfig, targetConfig, isOffline)
 	if err != nil {
 		return 0, errors.Wrapf(err, "running PXE")
 	}
-	defer func() {
-		if err := mach.Destroy(); err != nil {
-			plog.Errorf("Failed to destroy PXE: %v", err)
-		}
-	}()
+	defer mach.Destroy()
+	mach.OutputDir = outdir
 
-	return awaitCompletion(ctx, mach.QemuInst, outdir, completionChannel, mach.BootStartedErrorChannel, []string{liveOKSignal, signalCompleteString})
+	duration, err := awaitCompletion(ctx, mach.QemuInst, outdir, completionChannel, mach.BootStartedErrorChannel, installSignals())
+	if err != nil || installFails() {
+		return duration, err
+	}
+	if err := verifyInstalledDisk(inst, mach, disk); err != nil {
+		return duration, err
+	}
+	return duration, runPostInstallTests(mach, sshKeyPath, outdir)
 }
 
 func testLiveIso(ctx context.Context, inst platform.Install, outdir string, minimal bool) (time.Duration, error) {
@@ -854,16 +1154,29 @@ func testLiveIso(ctx context.Context, inst platform.Install, outdir string, mini
 	}
 	defer os.RemoveAll(tmpd)
 
-	sshPubKeyBuf, _, err := util.CreateSSHAuthorizedKey(tmpd)
+	sshPubKeyBuf, sshKeyPath, err := util.CreateSSHAuthorizedKey(tmpd)
 	if err != nil {
 		return 0, err
 	}
+	if len(postInstallTests) > 0 {
+		inst.SSHKeyFile = sshKeyPath
+	}
+	if corruptSignature {
+		if inst.SignatureFile, err = writeCorruptSignature(inst, tmpd); err != nil {
+			return 0, err
+		}
+	}
 
-	builder, virtioJournalConfig, err := newQemuBuilderWithDisk(outdir)
+	builder, disk, virtioJournalConfig, err := newQemuBuilderWithDisk(outdir)
 	if err != nil {
 		return 0, err
 	}
 	inst.Builder = builder
+	inst.Runner = &exec.Runner{
+		Timeout:   platform.HelperRunner.Timeout,
+		Log:       os.Stderr,
+		AuditFile: filepath.Join(outdir, "commands.log"),
+	}
 	completionChannel, err := inst.Builder.VirtioChannelRead("testisocompletion")
 	if err != nil {
 		return 0, err
@@ -881,12 +1194,8 @@ func testLiveIso(ctx context.Context, inst platform.Install, outdir string, mini
 	liveConfig.AddSystemdUnit("coreos-test-entered-emergency-target.service", signalFailureUnit, conf.Enable)
 
 	targetConfig := *virtioJournalConfig
-	targetConfig.AddSystemdUnit("coreos-test-installer.service", signalCompletionUnit, conf.Enable)
+	targetConfig.AddSystemdUnit(instrument.InstallCompletionUnit, completionUnit(), conf.Enable)
 	targetConfig.AddSystemdUnit("coreos-test-entered-emergency-target.service", signalFailureUnit, conf.Enable)
-	targetConfig.AddSystemdUnit("coreos-test-installer-no-ignition.service", checkNoIgnition, conf.Enable)
-	if inst.MultiPathDisk {
-		targetConfig.AddSystemdUnit("coreos-test-installer-multipathed.service", multipathedRoot, conf.Enable)
-	}
 
 	if addNmKeyfile {
 		liveConfig.AddSystemdUnit("coreos-test-nm-keyfile.service", verifyNmKeyfile, conf.Enable)
@@ -901,18 +1210,134 @@ func testLiveIso(ctx context.Context, inst platform.Install, outdir string, mini
 	if isISOFromRAM {
 		isoKernelArgs = append(isoKernelArgs, liveISOFromRAMKarg)
 	}
+	if corruptOsmet {
+		liveConfig.AddSystemdUnit("coreos-test-corrupt-osmet.service", corruptOsmetUnit, conf.Enable)
+	}
+	if rootSha256 != "" {
+		liveConfig.AddSystemdUnit("coreos-test-verify-root.service", fmt.Sprintf(verifyRootUnit, rootSha256), conf.Enable)
+	}
 
 	mach, err := inst.InstallViaISOEmbed(isoKernelArgs, liveConfig, targetConfig, outdir, isOffline, minimal)
 	if err != nil {
 		return 0, errors.Wrapf(err, "running iso install")
 	}
-	defer func() {
-		if err := mach.Destroy(); err != nil {
-			plog.Errorf("Failed to destroy iso: %v", err)
+	defer mach.Destroy()
+
+	duration, err := awaitCompletion(ctx, mach.QemuInst, outdir, completionChannel, mach.BootStartedErrorChannel, installSignals())
+	if err != nil || installFails() {
+		return duration, err
+	}
+	if err := verifyInstalledDisk(inst, mach, disk); err != nil {
+		return duration, err
+	}
+	return duration, runPostInstallTests(mach, sshKeyPath, outdir)
+}
+
+// testOsmetVsURL installs offline, unpacking the metal image from the live
+// system's osmet data, and online, fetching it from its URL, and checks
+// that both write the root partition of the metal image.
+func testOsmetVsURL(ctx context.Context, inst platform.Install, outdir string) (time.Duration, error) {
+	metal, err := kola.CosaBuild.RequireDecompressedArtifact("metal", kola.ImageCache)
+	if err != nil {
+		return 0, err
+	}
+	sums, err := platform.PartitionChecksums(metal, "raw", 0)
+	if err != nil {
+		return 0, err
+	}
+	if sums["root"] == "" {
+		return 0, fmt.Errorf("no root partition in %s", metal)
+	}
+	rootSha256 = sums["root"]
+
+	var total time.Duration
+	for _, offline := range []bool{true, false} {
+		isOffline = offline
+		subdir := "url"
+		if offline {
+			subdir = "osmet"
 		}
+		duration, err := testLiveIso(ctx, inst, filepath.Join(outdir, subdir), false)
+		total += duration
+		if err != nil {
+			return total, errors.Wrapf(err, "%s install", subdir)
+		}
+	}
+	return total, nil
+}
+
+// installFails returns whether the install is expected to fail: for a
+// corrupted signature, coreos-installer must reject the image, and for
+// corrupted osmet data, offline installs have nothing to fall back to.
+func installFails() bool {
+	return corruptSignature || corruptOsmet
+}
+
+// installSignals returns the signals an install test expects on the
+// completion channel: the live system starting, then the installed system
+// completing, or for a corrupted signature, the live system entering the
+// emergency target once coreos-installer fails.
+func installSignals() []string {
+	if installFails() {
+		return []string{liveOKSignal, signalEmergencyString}
+	}
+	return []string{liveOKSignal, signalCompleteString}
+}
+
+// writeCorruptSignature writes a corrupted copy of the metal image's
+// signature to dir, or if it has none, one which isn't a signature at
+// all, and returns its path.
+func writeCorruptSignature(inst platform.Install, dir string) (string, error) {
+	sig := []byte("not a signature\n")
+	if path := inst.MetalSignature(); path != "" {
+		var err error
+		if sig, err = os.ReadFile(path); err != nil {
+			return "", err
+		}
+		if len(sig) > 0 {
+			sig[len(sig)-1] ^= 0xff
+		}
+	}
+	path := filepath.Join(dir, "corrupt.sig")
+	return path, os.WriteFile(path, sig, 0644)
+}
+
+// verifyInstalledDisk compares the installed disk against the metal image
+// with --verify-disk, once the installed system has powered off.
+func verifyInstalledDisk(inst platform.Install, mach *platform.InstalledMachine, disk *platform.Disk) error {
+	if !verifyDisk {
+		return nil
+	}
+	exited := make(chan struct{})
+	go func() {
+		_ = mach.QemuInst.Wait()
+		close(exited)
 	}()
+	select {
+	case <-exited:
+	case <-time.After(2 * time.Minute):
+		return fmt.Errorf("timed out waiting for the installed system to power off")
+	}
+	return inst.VerifyInstalledDisk(disk)
+}
 
-	return awaitCompletion(ctx, mach.QemuInst, outdir, completionChannel, mach.BootStartedErrorChannel, []string{liveOKSignal, signalCompleteString})
+// runPostInstallTests runs the --post-install-tests against the installed
+// system, as a machine reused by kola, with their output in the test's
+// post-install directory.
+func runPostInstallTests(mach *platform.InstalledMachine, sshKeyPath, outdir string) error {
+	if len(postInstallTests) == 0 {
+		return nil
+	}
+	if err := mach.Start(); err != nil {
+		return errors.Wrapf(err, "connecting to installed system")
+	}
+	kola.ReuseOptions.Host = mach.IP()
+	kola.ReuseOptions.KeyFile = sshKeyPath
+	kola.ReuseOptions.User = "core"
+	if err := kola.RunTests(postInstallTests, 0, false, nil, "reuse", filepath.Join(outdir, "post-install")); err != nil {
+		return errors.Wrapf(err, "post-install tests")
+	}
+	return nil
 }
 
 // testLiveFIPS verifies that adding fips=1 to the ISO results in a FIPS mode system
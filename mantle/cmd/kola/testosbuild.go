@@ -0,0 +1,197 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/coreos/coreos-assembler/mantle/harness"
+	"github.com/coreos/coreos-assembler/mantle/harness/reporters"
+	"github.com/coreos/coreos-assembler/mantle/harness/testresult"
+	"github.com/coreos/coreos-assembler/mantle/kola"
+	"github.com/coreos/coreos-assembler/mantle/platform"
+	"github.com/coreos/coreos-assembler/mantle/platform/conf"
+	"github.com/coreos/coreos-assembler/mantle/platform/osbuild"
+	"github.com/coreos/coreos-assembler/mantle/system/exec"
+)
+
+var (
+	cmdTestOSBuild = &cobra.Command{
+		RunE:    runTestOSBuild,
+		PreRunE: preRun,
+		Use:     "testosbuild [platform...]",
+		Short:   "Build images from a CoreOS build's osbuild manifests and boot them",
+		Long: `Build the images of the platforms, by default qemu, metal and metal4k,
+by running osbuild on the manifests as cosa osbuild does, and boot each
+with QEMU until the system finishes booting.
+
+The manifests are preprocessed with the config cosa osbuild wrote to
+tmp/runvm-osbuild-config-<build>.json if given with --osbuild-config, or
+otherwise one deploying the build's OSTree container. osbuild needs the
+privileges it has in the cosa container.`,
+
+		SilenceUsage: true,
+	}
+
+	osbuildManifestDir string
+	osbuildConfigPath  string
+	osbuildStoreDir    string
+	osbuildTimeout     time.Duration
+	osbuildBootTimeout time.Duration
+)
+
+func init() {
+	cmdTestOSBuild.Flags().StringVar(&osbuildManifestDir, "manifest-dir", osbuild.DefaultManifestDir, "directory of the osbuild manifests")
+	cmdTestOSBuild.Flags().StringVar(&osbuildConfigPath, "osbuild-config", "", "config written by cosa osbuild to preprocess the manifests with")
+	cmdTestOSBuild.Flags().StringVar(&osbuildStoreDir, "osbuild-store", "", "osbuild object store to reuse across runs (default temporary)")
+	cmdTestOSBuild.Flags().DurationVar(&osbuildTimeout, "osbuild-timeout", osbuild.DefaultTimeout, "how long osbuild may run for each image")
+	cmdTestOSBuild.Flags().DurationVar(&osbuildBootTimeout, "boot-timeout", 10*time.Minute, "how long each image may take to boot")
+
+	root.AddCommand(cmdTestOSBuild)
+}
+
+func runTestOSBuild(cmd *cobra.Command, args []string) (err error) {
+	if kola.CosaBuild == nil {
+		return fmt.Errorf("Must provide --build")
+	}
+	platforms := args
+	if len(platforms) == 0 {
+		platforms = osbuild.PlatformNames()
+	}
+	for _, p := range platforms {
+		if _, ok := osbuild.Platforms[p]; !ok {
+			return fmt.Errorf("unsupported platform %q; platforms are %s", p, strings.Join(osbuild.PlatformNames(), ", "))
+		}
+	}
+
+	var config *osbuild.Config
+	if osbuildConfigPath != "" {
+		config, err = osbuild.LoadConfig(osbuildConfigPath)
+	} else {
+		config, err = osbuild.ConfigFromBuild(kola.CosaBuild)
+	}
+	if err != nil {
+		return err
+	}
+
+	// note this reassigns a *global*
+	outputDir, err = kola.SetupOutputDir(outputDir, "testosbuild")
+	if err != nil {
+		return err
+	}
+	reportDir := filepath.Join(outputDir, "reports")
+	if err := os.Mkdir(reportDir, 0777); err != nil {
+		return err
+	}
+	reporter, err := reporters.ForFormats(kola.ReportFormats, "testosbuild", "")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if reportErr := reporter.Output(reportDir); reportErr != nil && err != nil {
+			err = reportErr
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	atLeastOneFailed := false
+	for _, p := range platforms {
+		test := "osbuild." + p
+		fmt.Printf("Running test: %s\n", test)
+		start := time.Now()
+		err := testOSBuild(ctx, config, p, filepath.Join(outputDir, test))
+		duration := time.Since(start)
+
+		result := testresult.Pass
+		output := []byte{}
+		if err != nil {
+			result = testresult.Fail
+			output = []byte(err.Error())
+		}
+		reporter.ReportTest(test, []string{}, result, duration, output)
+		if printResult(test, duration, err) {
+			atLeastOneFailed = true
+		}
+	}
+
+	reporter.SetResult(testresult.Pass)
+	if atLeastOneFailed {
+		reporter.SetResult(testresult.Fail)
+		return harness.SuiteFailed
+	}
+	return nil
+}
+
+// testOSBuild builds the image of the platform and boots it, leaving the
+// manifest, the commands run and the logs of the boot in outdir.
+func testOSBuild(ctx context.Context, config *osbuild.Config, p, outdir string) error {
+	if err := os.MkdirAll(outdir, 0755); err != nil {
+		return err
+	}
+	img, err := osbuild.Build(ctx, osbuild.Options{
+		ManifestDir: osbuildManifestDir,
+		Config:      config,
+		Platform:    p,
+		StoreDir:    osbuildStoreDir,
+		Runner: &exec.Runner{
+			Timeout:   osbuildTimeout,
+			Log:       os.Stderr,
+			AuditFile: filepath.Join(outdir, "commands.log"),
+		},
+	}, outdir)
+	if err != nil {
+		return err
+	}
+	// the image is only needed to boot
+	defer os.Remove(img.Path)
+
+	builder := platform.NewQemuBuilder()
+	defer builder.Close()
+	builder.ConsoleFile = filepath.Join(outdir, "console.txt")
+	if kola.QEMUOptions.Memory != "" {
+		parsedMem, err := strconv.ParseInt(kola.QEMUOptions.Memory, 10, 32)
+		if err != nil {
+			return err
+		}
+		builder.MemoryMiB = int(parsedMem)
+	}
+	if kola.QEMUOptions.Firmware != "" {
+		builder.Firmware = kola.QEMUOptions.Firmware
+	}
+	builder.FirmwareCode = kola.QEMUOptions.FirmwareCode
+	builder.FirmwareVars = kola.QEMUOptions.FirmwareVars
+	builder.PetitbootImage = kola.QEMUOptions.PetitbootImage
+	bootConfig, err := conf.EmptyIgnition().Render(conf.FailWarnings)
+	if err != nil {
+		return err
+	}
+	if err := forwardJournal(outdir, builder, bootConfig); err != nil {
+		return err
+	}
+	if err := img.Boot(ctx, builder, bootConfig, osbuildBootTimeout); err != nil {
+		return fmt.Errorf("booting %s image: %v", p, err)
+	}
+	return nil
+}
@@ -140,6 +140,13 @@ var (
 		SilenceUsage: true,
 	}
 
+	cmdRPC = &cobra.Command{
+		Use:          "rpc METHOD [PARAMS]",
+		Short:        "Call the harness from a test using protocol 2, with JSON params",
+		RunE:         runRPC,
+		SilenceUsage: true,
+	}
+
 	cmdHttpd = &cobra.Command{
 		Use:   "httpd",
 		Short: "Start an HTTP server to serve the contents of the file system",
@@ -245,11 +252,14 @@ func dispatchRunExtUnit(ctx context.Context, unitname string, sdconn *systemddbu
 	}
 }
 
-func initiateReboot(mark string) error {
+func initiateReboot(mark string, rpc bool) error {
 	systemdjournal.Print(systemdjournal.PriInfo, "Processing reboot request")
 	res := kola.KoletResult{
 		Reboot: string(mark),
 	}
+	if rpc {
+		return writeKoletMessage(kola.KoletMessage{Result: &res})
+	}
 	buf, err := json.Marshal(&res)
 	if err != nil {
 		return errors.Wrapf(err, "serializing KoletResult")
@@ -271,6 +281,7 @@ func mkfifo(path string) error {
 
 func runExtUnit(cmd *cobra.Command, args []string) error {
 	rebootOff, _ := cmd.Flags().GetBool("deny-reboots")
+	rpc, _ := cmd.Flags().GetBool("rpc")
 	// Write the autopkgtest wrappers
 	if err := os.WriteFile(autopkgTestRebootPath, []byte(autopkgtestRebootScript), 0755); err != nil {
 		return err
@@ -311,6 +322,16 @@ func runExtUnit(cmd *cobra.Command, args []string) error {
 	if !strings.HasSuffix(unitname, ".service") {
 		unitname = unitname + ".service"
 	}
+	// Serve the RPC channel before the test starts
+	var rpcReboots chan kola.KoletResult
+	if rpc {
+		server, err := startRPCServer(unitname, rebootOff)
+		if err != nil {
+			return err
+		}
+		defer server.Close()
+		rpcReboots = server.reboots
+	}
 	sdconn, err := systemddbus.NewSystemConnectionContext(ctx)
 	if err != nil {
 		return errors.Wrapf(err, "systemd connection")
@@ -343,7 +364,9 @@ func runExtUnit(cmd *cobra.Command, args []string) error {
 		case err := <-errChan:
 			return err
 		case reboot := <-rebootChan:
-			return initiateReboot(reboot)
+			return initiateReboot(reboot, rpc)
+		case res := <-rpcReboots:
+			return writeKoletMessage(kola.KoletMessage{Result: &res})
 		case m := <-unitevents:
 			for n := range m {
 				if n == unitname {
@@ -410,7 +433,10 @@ func main() {
 	registerTestMap(register.UpgradeTests)
 	root.AddCommand(cmdRun)
 	cmdRunExtUnit.Flags().Bool("deny-reboots", false, "disable reboot requests")
+	cmdRunExtUnit.Flags().Bool("rpc", false, "serve the RPC channel of a test using protocol 2, relaying it over stdin and stdout")
 	root.AddCommand(cmdRunExtUnit)
+	cmdRPC.Args = cobra.RangeArgs(1, 2)
+	root.AddCommand(cmdRPC)
 	cmdReboot.Args = cobra.ExactArgs(1)
 	root.AddCommand(cmdReboot)
 	cmdHttpd.Flags().StringP("port", "", "80", "port")
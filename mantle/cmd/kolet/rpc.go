@@ -0,0 +1,232 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+// RPC channel
+// ---
+//
+// Tests using version 2 of the external test protocol talk to the harness
+// over JSON-RPC, on a unix socket kolet serves for the duration of
+// run-test-unit.  Each line written to the socket is a request, answered
+// with a line.  kolet relays requests to the harness over the login
+// session, as lines on its stdout which the harness answers on its stdin,
+// one at a time.
+//
+// Reboots are the exception: kolet appends any kernel arguments itself,
+// answers, and then exits with a result asking the harness to reboot the
+// machine, like the reboot request FIFO but without waiting for the
+// acknowledgement, since it's the harness which reboots.
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	systemdjournal "github.com/coreos/go-systemd/v22/journal"
+	"github.com/spf13/cobra"
+
+	"github.com/coreos/coreos-assembler/mantle/kola"
+)
+
+// rpcServer serves the RPC channel of a test.
+type rpcServer struct {
+	listener    net.Listener
+	denyReboots bool
+	// reboots receives the result of a reboot request, after which kolet
+	// exits
+	reboots chan kola.KoletResult
+
+	// mu serializes writing to the harness and reading its answers
+	mu sync.Mutex
+	in *bufio.Reader
+}
+
+func startRPCServer(unit string, denyReboots bool) (*rpcServer, error) {
+	path := kola.RPCSocketPath(unit)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("listening on %s: %w", path, err)
+	}
+	s := &rpcServer{
+		listener:    l,
+		denyReboots: denyReboots,
+		reboots:     make(chan kola.KoletResult, 1),
+		in:          bufio.NewReader(os.Stdin),
+	}
+	go s.serve()
+	return s, nil
+}
+
+func (s *rpcServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.serveConn(conn)
+	}
+}
+
+func (s *rpcServer) serveConn(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(strings.TrimSpace(string(line))) > 0 {
+			var reboot *kola.KoletResult
+			var resp []byte
+			var req kola.RPCRequest
+			if err := json.Unmarshal(line, &req); err != nil {
+				resp = rpcErrorResponse(nil, kola.RPCErrParse, err.Error())
+			} else if req.Method == kola.RPCReboot {
+				resp, reboot = s.reboot(&req)
+			} else {
+				resp = s.forward(&req)
+			}
+			if _, err := conn.Write(append(resp, '\n')); err != nil {
+				return
+			}
+			if reboot != nil {
+				s.reboots <- *reboot
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// forward relays a request to the harness and returns its answer.
+func (s *rpcServer) forward(req *kola.RPCRequest) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := writeKoletMessage(kola.KoletMessage{Request: req}); err != nil {
+		return rpcErrorResponse(req.ID, kola.RPCErrInternal, err.Error())
+	}
+	line, err := s.in.ReadBytes('\n')
+	if err != nil {
+		return rpcErrorResponse(req.ID, kola.RPCErrInternal, fmt.Sprintf("reading answer from harness: %v", err))
+	}
+	return []byte(strings.TrimSpace(string(line)))
+}
+
+// reboot appends the requested kernel arguments, returning the answer and
+// the result to exit with.
+func (s *rpcServer) reboot(req *kola.RPCRequest) ([]byte, *kola.KoletResult) {
+	if s.denyReboots {
+		return rpcErrorResponse(req.ID, kola.RPCErrInternal, "reboots are not supported for this test"), nil
+	}
+	var params kola.RebootParams
+	if err := json.Unmarshal(req.Params, &params); err != nil || params.Mark == "" {
+		return rpcErrorResponse(req.ID, kola.RPCErrInvalidParams, "reboot requires a mark"), nil
+	}
+	if len(params.KernelArgs) > 0 {
+		args := []string{"kargs"}
+		for _, karg := range params.KernelArgs {
+			args = append(args, "--append="+karg)
+		}
+		if out, err := exec.Command("rpm-ostree", args...).CombinedOutput(); err != nil {
+			return rpcErrorResponse(req.ID, kola.RPCErrInternal, fmt.Sprintf("appending kernel arguments: %v: %s", err, out)), nil
+		}
+	}
+	systemdjournal.Print(systemdjournal.PriInfo, "Requesting reboot with mark: %s", params.Mark)
+	buf, _ := json.Marshal(kola.RPCResponse{JSONRPC: "2.0", ID: req.ID, Result: struct{}{}})
+	return buf, &kola.KoletResult{Reboot: params.Mark, HarnessReboot: true}
+}
+
+func (s *rpcServer) Close() error {
+	return s.listener.Close()
+}
+
+// writeKoletMessage writes a line for the harness to stdout.
+func writeKoletMessage(msg kola.KoletMessage) error {
+	buf, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(append(buf, '\n'))
+	return err
+}
+
+func rpcErrorResponse(id json.RawMessage, code int, message string) []byte {
+	buf, _ := json.Marshal(kola.RPCResponse{
+		JSONRPC: "2.0",
+		ID:      id,
+		Error:   &kola.RPCError{Code: code, Message: message},
+	})
+	return buf
+}
+
+// runRPC calls the harness from a test, printing the result.
+func runRPC(cmd *cobra.Command, args []string) error {
+	path := os.Getenv("KOLA_RPC_SOCKET")
+	if path == "" {
+		return fmt.Errorf("$KOLA_RPC_SOCKET isn't set; is the test using protocol %d?", kola.ExternalProtocolRPC)
+	}
+	req := kola.RPCRequest{JSONRPC: "2.0", ID: json.RawMessage("1"), Method: args[0]}
+	if len(args) > 1 {
+		if !json.Valid([]byte(args[1])) {
+			return fmt.Errorf("params %q aren't JSON", args[1])
+		}
+		req.Params = json.RawMessage(args[1])
+	}
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	buf, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	if _, err := conn.Write(append(buf, '\n')); err != nil {
+		return err
+	}
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil {
+		return fmt.Errorf("reading answer: %w", err)
+	}
+	var resp struct {
+		Result json.RawMessage `json:"result"`
+		Error  *kola.RPCError  `json:"error"`
+	}
+	if err := json.Unmarshal(line, &resp); err != nil {
+		return fmt.Errorf("parsing answer %s: %w", line, err)
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("%s: %s", args[0], resp.Error.Message)
+	}
+	if len(resp.Result) > 0 {
+		fmt.Println(string(resp.Result))
+	}
+	if args[0] == kola.RPCReboot {
+		// Wait for the harness to reboot the machine
+		select {}
+	}
+	return nil
+}
@@ -39,6 +39,8 @@ var (
 	profileName     string
 	accessKeyID     string
 	secretAccessKey string
+	partitionFlags  []string
+	partitions      map[string]aws.PartitionOptions
 )
 
 func init() {
@@ -52,15 +54,27 @@ func init() {
 	AWS.PersistentFlags().StringVar(&accessKeyID, "access-id", "", "AWS access key")
 	AWS.PersistentFlags().StringVar(&secretAccessKey, "secret-key", "", "AWS secret key")
 	AWS.PersistentFlags().StringVar(&region, "region", defaultRegion, "AWS region")
+	AWS.PersistentFlags().StringArrayVar(&partitionFlags, "partition", nil, "settings for regions in a partition such as aws-us-gov or aws-cn, as PARTITION:KEY=VALUE[,KEY=VALUE...] with keys credentials-file, profile, access-id, secret-key, bucket and kms-key")
 	cli.WrapPreRun(AWS, preflightCheck)
 }
 
 func preflightCheck(cmd *cobra.Command, args []string) error {
+	partitions = make(map[string]aws.PartitionOptions)
+	for _, s := range partitionFlags {
+		id, opts, err := aws.ParsePartitionOptions(s)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(2)
+		}
+		partitions[id] = opts
+	}
+
 	plog.Debugf("Running AWS Preflight check. Region: %v", region)
 	api, err := aws.New(&aws.Options{
 		Region:          region,
 		CredentialsFile: credentialsFile,
 		Profile:         profileName,
+		Partitions:      partitions,
 		Options:         &platform.Options{},
 	})
 	if err != nil {
@@ -76,3 +90,12 @@ func preflightCheck(cmd *cobra.Command, args []string) error {
 	API = api
 	return nil
 }
+
+// partitionOptions returns the settings for the partition of the region.
+func partitionOptions(region string) (aws.PartitionOptions, error) {
+	id, err := aws.PartitionForRegion(region)
+	if err != nil {
+		return aws.PartitionOptions{}, err
+	}
+	return partitions[id], nil
+}
@@ -30,6 +30,9 @@ var (
 		Short: "Copy AWS image between regions",
 		Long: `Copy an AWS image to one or more regions.
 
+The regions must be in the partition of the image's region. If the
+partition has a kms-key, the copies are encrypted with it.
+
 After a successful run, the final line of output will be a line of JSON describing the resources created.
 `,
 		RunE: runCopyImage,
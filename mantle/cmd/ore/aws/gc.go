@@ -20,6 +20,8 @@ import (
 	"time"
 
 	"github.com/spf13/cobra"
+
+	"github.com/coreos/coreos-assembler/mantle/platform"
 )
 
 var (
@@ -41,7 +43,7 @@ func init() {
 }
 
 func runGC(cmd *cobra.Command, args []string) error {
-	err := API.GC(gcDuration)
+	err := API.GC(platform.GCOptions{GracePeriod: gcDuration})
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Couldn't gc: %v\n", err)
 		os.Exit(1)
@@ -16,6 +16,7 @@ package aws
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 
 	"github.com/spf13/cobra"
@@ -40,7 +41,21 @@ func init() {
 
 func runInitialize(cmd *cobra.Command, args []string) error {
 	if bucket == "" {
-		bucket = defaultBucketNameForRegion(region)
+		partition, err := partitionOptions(region)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		if partition.Bucket != "" {
+			u, err := url.Parse(partition.Bucket)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "parsing the partition's bucket: %v\n", err)
+				os.Exit(1)
+			}
+			bucket = u.Host
+		} else {
+			bucket = defaultBucketNameForRegion(region)
+		}
 	}
 
 	err := API.InitializeBucket(bucket)
@@ -0,0 +1,310 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aws
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/coreos/stream-metadata-go/fedoracoreos"
+	metadata "github.com/coreos/stream-metadata-go/stream"
+	"github.com/spf13/cobra"
+
+	"github.com/coreos/coreos-assembler/mantle/platform"
+	"github.com/coreos/coreos-assembler/mantle/platform/api/aws"
+	"github.com/coreos/coreos-assembler/mantle/streammeta"
+)
+
+var (
+	cmdPruneAMIs = &cobra.Command{
+		Use:   "prune-amis --name-prefix PREFIX (--stream STREAM | --keep AMI | --keep-file FILE)...",
+		Short: "Delete old AMIs and snapshots in AWS",
+		Long: `Deregister the AMIs in the region whose names start with a prefix and
+which are older than a duration, except those in published stream
+metadata or a keep-list, and delete their snapshots. Snapshots with a
+matching Name tag which no remaining AMI uses are deleted too.
+
+Streams are given as paths, URLs, or the names of Fedora CoreOS streams.
+A keep-file lists one AMI ID per line, with # starting a comment.`,
+		RunE: runPruneAMIs,
+
+		SilenceUsage: true,
+	}
+
+	pruneStreams    []string
+	pruneKeep       []string
+	pruneKeepFiles  []string
+	pruneNamePrefix []string
+	pruneOlderThan  time.Duration
+	pruneDryRun     bool
+	pruneKeepPublic bool
+	pruneRate       float64
+	pruneLimit      int
+	pruneReportPath string
+)
+
+func init() {
+	AWS.AddCommand(cmdPruneAMIs)
+	cmdPruneAMIs.Flags().StringArrayVar(&pruneStreams, "stream", nil, "keep the AMIs in stream metadata (path, URL, or FCOS stream name)")
+	cmdPruneAMIs.Flags().StringArrayVar(&pruneKeep, "keep", nil, "keep an AMI")
+	cmdPruneAMIs.Flags().StringArrayVar(&pruneKeepFiles, "keep-file", nil, "keep the AMIs listed in a file")
+	cmdPruneAMIs.Flags().StringArrayVar(&pruneNamePrefix, "name-prefix", nil, "only prune AMIs and snapshots whose names start with this")
+	cmdPruneAMIs.Flags().DurationVar(&pruneOlderThan, "older-than", 30*24*time.Hour, "how old AMIs and snapshots must be before they're pruned")
+	cmdPruneAMIs.Flags().BoolVar(&pruneDryRun, "dry-run", false, "report what would be deleted without deleting it")
+	cmdPruneAMIs.Flags().BoolVar(&pruneKeepPublic, "keep-public", true, "keep public AMIs")
+	cmdPruneAMIs.Flags().Float64Var(&pruneRate, "rate", 2, "maximum deletions per second")
+	cmdPruneAMIs.Flags().IntVar(&pruneLimit, "limit", 0, "maximum AMIs to deregister (0 for no limit)")
+	cmdPruneAMIs.Flags().StringVar(&pruneReportPath, "report", "", "write a JSON report to this path")
+}
+
+type pruneReport struct {
+	DryRun    bool                  `json:"dryRun"`
+	Region    string                `json:"region"`
+	OlderThan string                `json:"olderThan"`
+	Kept      []string              `json:"kept"`
+	Resources []platform.GCResource `json:"resources"`
+	Error     string                `json:"error,omitempty"`
+}
+
+func runPruneAMIs(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("unexpected arguments")
+	}
+	if len(pruneNamePrefix) == 0 {
+		return fmt.Errorf("--name-prefix is required")
+	}
+	for _, prefix := range pruneNamePrefix {
+		if prefix == "" {
+			return fmt.Errorf("--name-prefix can't be empty")
+		}
+	}
+	if len(pruneStreams)+len(pruneKeep)+len(pruneKeepFiles) == 0 {
+		return fmt.Errorf("at least one of --stream, --keep, or --keep-file is required")
+	}
+	if pruneRate <= 0 {
+		return fmt.Errorf("--rate must be positive")
+	}
+	if pruneLimit < 0 {
+		return fmt.Errorf("--limit can't be negative")
+	}
+
+	keep, err := pruneKeepList()
+	if err != nil {
+		return err
+	}
+
+	// Orphaned snapshots are checked against every AMI, in case one not
+	// matching the prefixes uses them
+	all, err := API.ListOwnedImages(nil)
+	if err != nil {
+		return err
+	}
+	var images []aws.OwnedImage
+	for _, image := range all {
+		for _, prefix := range pruneNamePrefix {
+			if strings.HasPrefix(image.Name, prefix) {
+				images = append(images, image)
+				break
+			}
+		}
+	}
+	snapshots, err := API.ListOwnedSnapshots(pruneNamePrefix)
+	if err != nil {
+		return err
+	}
+
+	report := &platform.GCReport{}
+	opts := platform.GCOptions{
+		GracePeriod: pruneOlderThan,
+		DryRun:      pruneDryRun,
+		Report:      report,
+	}
+	throttle := time.NewTicker(time.Duration(float64(time.Second) / pruneRate))
+	defer throttle.Stop()
+	collect := func(kind, id string, created time.Time, del func() error) error {
+		if !pruneDryRun {
+			<-throttle.C
+		}
+		return opts.Collect(kind, id, created, del)
+	}
+
+	r := pruneReport{
+		DryRun:    pruneDryRun,
+		Region:    region,
+		OlderThan: pruneOlderThan.String(),
+	}
+	err = pruneImages(images, all, snapshots, keep, pruneKeepPublic, pruneLimit, opts, collect, &r)
+	r.Resources = report.Resources()
+	if err != nil {
+		r.Error = err.Error()
+	}
+
+	verb := "Deleted"
+	if pruneDryRun {
+		verb = "Would delete"
+	}
+	fmt.Printf("%s %d resources, kept %d AMIs\n", verb, len(r.Resources), len(r.Kept))
+	for _, res := range r.Resources {
+		fmt.Printf("  %s %s (created %s)\n", res.Kind, res.ID, res.Created.Format(time.RFC3339))
+	}
+	if pruneReportPath != "" {
+		data, merr := json.MarshalIndent(r, "", "  ")
+		if merr != nil {
+			return merr
+		}
+		if werr := os.WriteFile(pruneReportPath, append(data, '\n'), 0644); werr != nil {
+			return fmt.Errorf("writing report: %v", werr)
+		}
+	}
+	return err
+}
+
+// pruneImages deregisters the AMIs which are garbage and deletes their
+// snapshots, then the snapshots none of all the account's AMIs use. Public
+// AMIs are kept if keepPublic is set, and if limit is nonzero, at most
+// that many AMIs are deregistered. The AMIs kept are added to the report.
+func pruneImages(images, all []aws.OwnedImage, snapshots []aws.OwnedSnapshot, keep map[string]bool, keepPublic bool, limit int, opts platform.GCOptions, collect func(kind, id string, created time.Time, del func() error) error, r *pruneReport) error {
+	sort.Slice(images, func(i, j int) bool {
+		return images[i].Created.Before(images[j].Created)
+	})
+
+	var garbage []aws.OwnedImage
+	inUse := make(map[string]bool)
+	for _, image := range images {
+		switch {
+		case keep[image.ID], keepPublic && image.Public:
+			r.Kept = append(r.Kept, image.ID)
+		case !opts.IsGarbage(image.Created, nil):
+		case limit > 0 && len(garbage) >= limit:
+		default:
+			garbage = append(garbage, image)
+			continue
+		}
+		for _, id := range image.SnapshotIDs {
+			inUse[id] = true
+		}
+	}
+	sort.Strings(r.Kept)
+
+	deleted := make(map[string]bool)
+	for _, image := range garbage {
+		err := collect("AMI", image.ID, image.Created, func() error {
+			return API.RemoveByAmiTag(image.ID, true)
+		})
+		if err != nil {
+			return fmt.Errorf("deregistering %s: %v", image.ID, err)
+		}
+		for _, id := range image.SnapshotIDs {
+			if inUse[id] || deleted[id] {
+				continue
+			}
+			err := collect("snapshot", id, image.Created, func() error {
+				return API.RemoveBySnapshotTag(id, true)
+			})
+			if err != nil {
+				return fmt.Errorf("deleting snapshot %s of %s: %v", id, image.ID, err)
+			}
+			deleted[id] = true
+		}
+	}
+
+	// Snapshots imported for AMIs which were never registered, or whose
+	// AMIs were deregistered without them
+	for _, snapshot := range snapshots {
+		if inUse[snapshot.ID] || deleted[snapshot.ID] || !opts.IsGarbage(snapshot.Created, nil) {
+			continue
+		}
+		if pruneUsedBy(all, snapshot.ID) {
+			continue
+		}
+		err := collect("snapshot", snapshot.ID, snapshot.Created, func() error {
+			return API.RemoveBySnapshotTag(snapshot.ID, true)
+		})
+		if err != nil {
+			return fmt.Errorf("deleting snapshot %s: %v", snapshot.ID, err)
+		}
+	}
+	return nil
+}
+
+// pruneUsedBy reports whether any of the AMIs uses the snapshot.
+func pruneUsedBy(images []aws.OwnedImage, snapshotID string) bool {
+	for _, image := range images {
+		for _, id := range image.SnapshotIDs {
+			if id == snapshotID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// pruneKeepList returns the AMI IDs to keep from the streams, --keep,
+// and the keep-files.
+func pruneKeepList() (map[string]bool, error) {
+	keep := make(map[string]bool)
+	for _, id := range pruneKeep {
+		keep[id] = true
+	}
+	for _, path := range pruneKeepFiles {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line, _, _ := strings.Cut(scanner.Text(), "#")
+			if id := strings.TrimSpace(line); id != "" {
+				keep[id] = true
+			}
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %v", path, err)
+		}
+	}
+	for _, source := range pruneStreams {
+		if !strings.Contains(source, "/") && !strings.HasSuffix(source, ".json") {
+			u := fedoracoreos.GetStreamURL(source)
+			source = u.String()
+		}
+		data, err := streammeta.ReadSource(source)
+		if err != nil {
+			return nil, err
+		}
+		// Only the AMIs are needed, so unlike with ore stream validate,
+		// unknown fields are ignored
+		var st metadata.Stream
+		if err := json.Unmarshal(data, &st); err != nil {
+			return nil, fmt.Errorf("parsing %s: %v", source, err)
+		}
+		ids := streammeta.AWSImages(&st)
+		if len(ids) == 0 {
+			// Most likely the wrong file; keeping nothing would prune
+			// everything
+			return nil, fmt.Errorf("stream %s has no AWS images", source)
+		}
+		for _, id := range ids {
+			keep[id] = true
+		}
+	}
+	return keep, nil
+}
@@ -0,0 +1,129 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aws
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/coreos/coreos-assembler/mantle/platform"
+	"github.com/coreos/coreos-assembler/mantle/platform/api/aws"
+)
+
+func TestPruneImages(t *testing.T) {
+	old := time.Now().Add(-30 * 24 * time.Hour)
+	recent := time.Now().Add(-time.Hour)
+
+	for _, tt := range []struct {
+		name       string
+		images     []aws.OwnedImage
+		others     []aws.OwnedImage
+		snapshots  []aws.OwnedSnapshot
+		keep       map[string]bool
+		keepPublic bool
+		limit      int
+		collected  []string
+		kept       []string
+	}{
+		{
+			name: "garbage AMI and its snapshot",
+			images: []aws.OwnedImage{
+				{ID: "ami-1", Created: old, SnapshotIDs: []string{"snap-1"}},
+			},
+			snapshots: []aws.OwnedSnapshot{{ID: "snap-1", Created: old}},
+			collected: []string{"AMI ami-1", "snapshot snap-1"},
+		},
+		{
+			name: "kept AMI's snapshot survives",
+			images: []aws.OwnedImage{
+				{ID: "ami-1", Created: old, SnapshotIDs: []string{"snap-1"}},
+				{ID: "ami-2", Created: old.Add(time.Hour), SnapshotIDs: []string{"snap-1", "snap-2"}},
+			},
+			snapshots: []aws.OwnedSnapshot{{ID: "snap-1", Created: old}, {ID: "snap-2", Created: old}},
+			keep:      map[string]bool{"ami-2": true},
+			collected: []string{"AMI ami-1"},
+			kept:      []string{"ami-2"},
+		},
+		{
+			name: "public AMI kept",
+			images: []aws.OwnedImage{
+				{ID: "ami-1", Created: old, Public: true, SnapshotIDs: []string{"snap-1"}},
+			},
+			keepPublic: true,
+			kept:       []string{"ami-1"},
+		},
+		{
+			name: "public AMI pruned without keep-public",
+			images: []aws.OwnedImage{
+				{ID: "ami-1", Created: old, Public: true, SnapshotIDs: []string{"snap-1"}},
+			},
+			collected: []string{"AMI ami-1", "snapshot snap-1"},
+		},
+		{
+			name: "recent AMI not garbage",
+			images: []aws.OwnedImage{
+				{ID: "ami-1", Created: recent, SnapshotIDs: []string{"snap-1"}},
+			},
+			snapshots: []aws.OwnedSnapshot{{ID: "snap-1", Created: old}},
+		},
+		{
+			name: "AMI held back by limit keeps its snapshots",
+			images: []aws.OwnedImage{
+				{ID: "ami-2", Created: old.Add(time.Hour), SnapshotIDs: []string{"snap-2"}},
+				{ID: "ami-1", Created: old, SnapshotIDs: []string{"snap-1"}},
+			},
+			snapshots: []aws.OwnedSnapshot{{ID: "snap-1", Created: old}, {ID: "snap-2", Created: old}},
+			limit:     1,
+			collected: []string{"AMI ami-1", "snapshot snap-1"},
+		},
+		{
+			name: "orphan snapshot deleted",
+			snapshots: []aws.OwnedSnapshot{
+				{ID: "snap-1", Created: old},
+				{ID: "snap-2", Created: recent},
+			},
+			collected: []string{"snapshot snap-1"},
+		},
+		{
+			name: "orphan snapshot used by AMI outside prefix",
+			others: []aws.OwnedImage{
+				{ID: "ami-9", Name: "other", Created: old, SnapshotIDs: []string{"snap-1"}},
+			},
+			snapshots: []aws.OwnedSnapshot{{ID: "snap-1", Created: old}},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			all := append(append([]aws.OwnedImage{}, tt.images...), tt.others...)
+			opts := platform.GCOptions{GracePeriod: 24 * time.Hour}
+			var collected []string
+			collect := func(kind, id string, created time.Time, del func() error) error {
+				collected = append(collected, kind+" "+id)
+				return nil
+			}
+			var r pruneReport
+			err := pruneImages(tt.images, all, tt.snapshots, tt.keep, tt.keepPublic, tt.limit, opts, collect, &r)
+			if err != nil {
+				t.Fatalf("got error %v", err)
+			}
+			if !reflect.DeepEqual(collected, tt.collected) {
+				t.Errorf("collected %v, expected %v", collected, tt.collected)
+			}
+			if !reflect.DeepEqual(r.Kept, tt.kept) {
+				t.Errorf("kept %v, expected %v", r.Kept, tt.kept)
+			}
+		})
+	}
+}
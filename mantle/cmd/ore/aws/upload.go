@@ -75,7 +75,7 @@ After a successful run, the final line of output will be a line of JSON describi
 func init() {
 	AWS.AddCommand(cmdUpload)
 	cmdUpload.Flags().StringVar(&uploadSourceObject, "source-object", "", "'s3://' URI pointing to image data (default: same as upload)")
-	cmdUpload.Flags().StringVar(&uploadBucket, "bucket", "", "s3://bucket/prefix/ (defaults to a regional bucket and prefix defaults to $USER/board/name)")
+	cmdUpload.Flags().StringVar(&uploadBucket, "bucket", "", "s3://bucket/prefix/ (defaults to the partition's bucket or a regional bucket and prefix defaults to $USER/board/name)")
 	cmdUpload.Flags().StringVar(&uploadImageName, "name", "", "name of uploaded image")
 	cmdUpload.Flags().StringVar(&uploadImageArchitecture, "arch", "", "The target architecture for the AMI")
 	cmdUpload.Flags().StringVar(&uploadFile, "file", "", "path to CoreOS image")
@@ -172,7 +172,19 @@ func runUpload(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	var err error
+	partition, err := partitionOptions(region)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	if uploadPublic && partition.KMSKeyID != "" {
+		fmt.Fprintf(os.Stderr, "--public can't be used with images encrypted by the partition's kms-key\n")
+		os.Exit(2)
+	}
+	if uploadBucket == "" {
+		uploadBucket = partition.Bucket
+	}
+
 	var s3URL *url.URL
 	if uploadSourceObject != "" {
 		s3URL, err = url.Parse(uploadSourceObject)
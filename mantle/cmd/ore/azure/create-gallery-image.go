@@ -20,14 +20,17 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute"
 	"github.com/spf13/cobra"
+
+	"github.com/coreos/coreos-assembler/mantle/platform/api/azure"
 )
 
 var (
 	cmdCreateGalleryImage = &cobra.Command{
 		Use:     "create-gallery-image",
 		Short:   "Create Azure Gallery image",
-		Long:    "Create Azure Gallery image from a blob url",
+		Long:    "Create Azure Gallery image from a blob url or managed disk",
 		RunE:    runCreateGalleryImage,
 		Aliases: []string{"create-gallery-image-arm"},
 
@@ -37,6 +40,7 @@ var (
 	galleryImageName string
 	galleryName      string
 	architecture     string
+	securityType     string
 )
 
 func init() {
@@ -45,15 +49,23 @@ func init() {
 	sv(&galleryImageName, "gallery-image-name", "", "gallery image name")
 	sv(&galleryName, "gallery-name", "kola", "gallery name")
 	sv(&blobUrl, "image-blob", "", "source blob url")
+	sv(&imageDisk, "image-disk", "", "resource ID of the source managed disk, as from upload-disk")
 	sv(&resourceGroup, "resource-group", "kola", "resource group name")
 	sv(&architecture, "arch", "", "The target architecture for the image")
+	sv(&securityType, "security-type", "", "VM security type required by the image, TrustedLaunch or ConfidentialVM")
 
 	Azure.AddCommand(cmdCreateGalleryImage)
 }
 
 func runCreateGalleryImage(cmd *cobra.Command, args []string) error {
-	if blobUrl == "" {
-		fmt.Fprintf(os.Stderr, "must supply --image-blob\n")
+	if blobUrl == "" && imageDisk == "" {
+		fmt.Fprintf(os.Stderr, "must supply --image-blob or --image-disk\n")
+		os.Exit(1)
+	}
+
+	secType, err := azure.ParseSecurityType(securityType)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
 		os.Exit(1)
 	}
 
@@ -62,7 +74,12 @@ func runCreateGalleryImage(cmd *cobra.Command, args []string) error {
 		os.Exit(1)
 	}
 
-	img, err := api.CreateImage(galleryImageName, resourceGroup, blobUrl)
+	var img armcompute.Image
+	if imageDisk != "" {
+		img, err = api.CreateImageFromDisk(galleryImageName, resourceGroup, imageDisk)
+	} else {
+		img, err = api.CreateImage(galleryImageName, resourceGroup, blobUrl)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Couldn't create Azure image: %v\n", err)
 		os.Exit(1)
@@ -73,7 +90,7 @@ func runCreateGalleryImage(cmd *cobra.Command, args []string) error {
 	}
 	sourceImageId := *img.ID
 
-	galleryImage, err := api.CreateGalleryImage(galleryImageName, galleryName, resourceGroup, sourceImageId, architecture)
+	galleryImage, err := api.CreateGalleryImage(galleryImageName, galleryName, resourceGroup, sourceImageId, architecture, secType)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Couldn't create Azure Shared Image Gallery image: %v\n", err)
 		os.Exit(1)
@@ -0,0 +1,172 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azure
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/coreos/coreos-assembler/mantle/platform/api/azure"
+)
+
+var (
+	cmdCreateImageVersion = &cobra.Command{
+		Use:   "create-image-version",
+		Short: "Create Azure Gallery image version",
+		Long: `Create a version of an existing Azure Shared Image Gallery image definition.
+
+The version is created from a managed image, given by --source-image, a
+managed disk, given by --image-disk, or a VHD page blob, given by
+--image-blob. Each --target-region is
+REGION[:REPLICAS[:STORAGE-ACCOUNT-TYPE]], such as eastus2:3:Standard_ZRS,
+and the version is always replicated to the region it's created in too.`,
+		RunE: runCreateImageVersion,
+
+		SilenceUsage: true,
+	}
+
+	newImageVersion     string
+	sourceImageID       string
+	storageAccountGroup string
+	endOfLife           string
+	excludeFromLatest   bool
+)
+
+func init() {
+	sv := cmdCreateImageVersion.Flags().StringVar
+
+	sv(&galleryImageName, "gallery-image-name", "", "gallery image definition name")
+	sv(&galleryName, "gallery-name", "kola", "gallery name")
+	sv(&resourceGroup, "resource-group", "kola", "resource group name")
+	sv(&newImageVersion, "gallery-image-version", "", "version to create, such as 1.2.3")
+	sv(&sourceImageID, "source-image", "", "resource ID of the managed image to create the version from")
+	sv(&imageDisk, "image-disk", "", "resource ID of the managed disk to create the version from, as from upload-disk")
+	sv(&blobUrl, "image-blob", "", "URL of the VHD page blob to create the version from")
+	sv(&storageAccountGroup, "storage-account-resource-group", "", "resource group of the blob's storage account (default --resource-group)")
+	sv(&endOfLife, "end-of-life", "", "end-of-life date of the version, as YYYY-MM-DD or RFC 3339")
+	cmdCreateImageVersion.Flags().BoolVar(&excludeFromLatest, "exclude-from-latest", false, "don't use the version for VMs which ask for the latest one")
+	cmdCreateImageVersion.Flags().StringSliceVar(&targetRegions, "target-region", nil, "region to replicate to, as REGION[:REPLICAS[:STORAGE-ACCOUNT-TYPE]]; can be specified multiple times")
+	cmdCreateImageVersion.Flags().Int32Var(&replicaCount, "replica-count", 0, "default number of replicas per region")
+	sv(&storageAccountType, "storage-account-type", "", "default storage account type: Standard_LRS, Standard_ZRS or Premium_LRS")
+
+	Azure.AddCommand(cmdCreateImageVersion)
+}
+
+// parseEndOfLife parses a date, as midnight UTC, or an RFC 3339 time.
+func parseEndOfLife(s string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid end-of-life %q; expected YYYY-MM-DD or RFC 3339", s)
+	}
+	return t, nil
+}
+
+// blobStorageAccount returns the name of the storage account of the blob
+// at blobURL, the first label of its host name.
+func blobStorageAccount(blobURL string) (string, error) {
+	u, err := url.Parse(blobURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid blob URL %q: %v", blobURL, err)
+	}
+	account, _, _ := strings.Cut(u.Hostname(), ".")
+	if account == "" {
+		return "", fmt.Errorf("invalid blob URL %q: no storage account", blobURL)
+	}
+	return account, nil
+}
+
+func runCreateImageVersion(cmd *cobra.Command, args []string) error {
+	if galleryImageName == "" {
+		return fmt.Errorf("must supply --gallery-image-name")
+	}
+	if newImageVersion == "" {
+		return fmt.Errorf("must supply --gallery-image-version")
+	}
+	sources := 0
+	for _, source := range []string{sourceImageID, imageDisk, blobUrl} {
+		if source != "" {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return fmt.Errorf("must supply one of --source-image, --image-disk and --image-blob")
+	}
+	if replicaCount < 0 {
+		return fmt.Errorf("invalid --replica-count %d", replicaCount)
+	}
+	opts := azure.GalleryImageVersionOptions{
+		SourceImageID:      sourceImageID,
+		SourceDiskID:       imageDisk,
+		ExcludeFromLatest:  excludeFromLatest,
+		ReplicaCount:       replicaCount,
+		StorageAccountType: storageAccountType,
+	}
+	if endOfLife != "" {
+		t, err := parseEndOfLife(endOfLife)
+		if err != nil {
+			return err
+		}
+		opts.EndOfLife = t
+	}
+	for _, s := range targetRegions {
+		target, err := parseTargetRegion(s)
+		if err != nil {
+			return err
+		}
+		opts.Targets = append(opts.Targets, target)
+	}
+
+	if err := api.SetupClients(); err != nil {
+		return fmt.Errorf("setting up clients: %v", err)
+	}
+
+	if blobUrl != "" {
+		account, err := blobStorageAccount(blobUrl)
+		if err != nil {
+			return err
+		}
+		group := storageAccountGroup
+		if group == "" {
+			group = resourceGroup
+		}
+		opts.SourceBlobURL = blobUrl
+		opts.SourceStorageAccountID = api.StorageAccountID(group, account)
+	}
+
+	img, err := api.CreateGalleryImageVersion(galleryImageName, galleryName, resourceGroup, newImageVersion, opts)
+	if err != nil {
+		return fmt.Errorf("Couldn't create gallery image version: %v", err)
+	}
+	err = json.NewEncoder(os.Stdout).Encode(&struct {
+		ID       *string
+		Location *string
+	}{
+		ID:       img.ID,
+		Location: img.Location,
+	})
+	if err != nil {
+		return fmt.Errorf("Couldn't encode result: %v", err)
+	}
+	return nil
+}
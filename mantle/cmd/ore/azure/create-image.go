@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute"
 	"github.com/spf13/cobra"
 )
 
@@ -27,7 +28,7 @@ var (
 	cmdCreateImage = &cobra.Command{
 		Use:     "create-image",
 		Short:   "Create Azure image",
-		Long:    "Create Azure image from a blob url",
+		Long:    "Create Azure image from a blob url or managed disk",
 		RunE:    runCreateImage,
 		Aliases: []string{"create-image-arm"},
 
@@ -36,6 +37,7 @@ var (
 
 	imageName     string
 	blobUrl       string
+	imageDisk     string
 	resourceGroup string
 )
 
@@ -44,6 +46,7 @@ func init() {
 
 	sv(&imageName, "image-name", "", "image name")
 	sv(&blobUrl, "image-blob", "", "source blob url")
+	sv(&imageDisk, "image-disk", "", "resource ID of the source managed disk, as from upload-disk")
 	sv(&resourceGroup, "resource-group", "kola", "resource group name")
 
 	Azure.AddCommand(cmdCreateImage)
@@ -54,7 +57,13 @@ func runCreateImage(cmd *cobra.Command, args []string) error {
 		fmt.Fprintf(os.Stderr, "setting up clients: %v\n", err)
 		os.Exit(1)
 	}
-	img, err := api.CreateImage(imageName, resourceGroup, blobUrl)
+	var img armcompute.Image
+	var err error
+	if imageDisk != "" {
+		img, err = api.CreateImageFromDisk(imageName, resourceGroup, imageDisk)
+	} else {
+		img, err = api.CreateImage(imageName, resourceGroup, blobUrl)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Couldn't create image: %v\n", err)
 		os.Exit(1)
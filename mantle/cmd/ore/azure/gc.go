@@ -20,24 +20,35 @@ import (
 	"time"
 
 	"github.com/spf13/cobra"
+
+	"github.com/coreos/coreos-assembler/mantle/platform"
+	"github.com/coreos/coreos-assembler/mantle/platform/api/azure"
 )
 
 var (
 	cmdGC = &cobra.Command{
 		Use:   "gc",
 		Short: "GC resources in Azure",
-		Long:  `Delete instances created over the given duration ago`,
-		RunE:  runGC,
+		Long: `Delete resources created over the given duration ago.
+
+This deletes the kola-cluster resource groups, and the disks, images,
+gallery image versions and blobs which ore created in the given resource
+group and storage container.`,
+		RunE: runGC,
 
 		SilenceUsage: true,
 	}
 
-	gcDuration time.Duration
+	gcDuration  time.Duration
+	gcLocations azure.GCLocations
 )
 
 func init() {
 	Azure.AddCommand(cmdGC)
 	cmdGC.Flags().DurationVar(&gcDuration, "duration", 5*time.Hour, "how old resources must be before they're considered garbage")
+	cmdGC.Flags().StringVar(&gcLocations.ResourceGroup, "resource-group", "kola", "resource group to collect disks, images and galleries from; empty for none")
+	cmdGC.Flags().StringVar(&gcLocations.StorageAccount, "storage-account", "kola", "storage account to collect blobs from; empty for none")
+	cmdGC.Flags().StringVar(&gcLocations.Container, "container", "vhds", "storage container to collect blobs from")
 }
 
 func runGC(cmd *cobra.Command, args []string) error {
@@ -45,7 +56,7 @@ func runGC(cmd *cobra.Command, args []string) error {
 		fmt.Fprintf(os.Stderr, "setting up clients: %v\n", err)
 		os.Exit(1)
 	}
-	err := api.GC(gcDuration)
+	err := api.GC(platform.GCOptions{GracePeriod: gcDuration}, gcLocations)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Couldn't gc: %v\n", err)
 		os.Exit(1)
@@ -0,0 +1,49 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azure
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdGetGallerySharing = &cobra.Command{
+		Use:   "get-gallery-sharing",
+		Short: "Show who an Azure Gallery is shared with",
+		Long: `Print who an Azure Shared Image Gallery is shared with as JSON, including
+the public names of a community gallery.`,
+		RunE: runGetGallerySharing,
+
+		SilenceUsage: true,
+	}
+)
+
+func init() {
+	sv := cmdGetGallerySharing.Flags().StringVar
+
+	sv(&galleryName, "gallery-name", "kola", "gallery name")
+	sv(&resourceGroup, "resource-group", "kola", "resource group name")
+
+	Azure.AddCommand(cmdGetGallerySharing)
+}
+
+func runGetGallerySharing(cmd *cobra.Command, args []string) error {
+	if err := api.SetupClients(); err != nil {
+		return fmt.Errorf("setting up clients: %v", err)
+	}
+	return printGallerySharing()
+}
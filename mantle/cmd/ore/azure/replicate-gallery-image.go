@@ -0,0 +1,156 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azure
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/coreos/coreos-assembler/mantle/platform/api/azure"
+)
+
+var (
+	cmdReplicateGalleryImage = &cobra.Command{
+		Use:   "replicate-gallery-image",
+		Short: "Replicate Azure Gallery image",
+		Long: `Set the regions an Azure Shared Image Gallery image version is replicated to.
+
+Each --target-region is REGION[:REPLICAS[:STORAGE-ACCOUNT-TYPE]], such as
+eastus2:3:Standard_ZRS, and the given regions replace those the version is
+currently replicated to, except for the region it was created in, which is
+always kept.`,
+		RunE: runReplicateGalleryImage,
+
+		SilenceUsage: true,
+	}
+
+	galleryImageVersion string
+	targetRegions       []string
+	replicaCount        int32
+	storageAccountType  string
+	waitForReplication  bool
+)
+
+func init() {
+	sv := cmdReplicateGalleryImage.Flags().StringVar
+
+	sv(&galleryImageName, "gallery-image-name", "", "gallery image name")
+	sv(&galleryName, "gallery-name", "kola", "gallery name")
+	sv(&resourceGroup, "resource-group", "kola", "resource group name")
+	sv(&galleryImageVersion, "gallery-image-version", "1.0.0", "gallery image version")
+	cmdReplicateGalleryImage.Flags().StringSliceVar(&targetRegions, "target-region", nil, "region to replicate to, as REGION[:REPLICAS[:STORAGE-ACCOUNT-TYPE]]; can be specified multiple times")
+	cmdReplicateGalleryImage.Flags().Int32Var(&replicaCount, "replica-count", 0, "default number of replicas per region (default unchanged)")
+	sv(&storageAccountType, "storage-account-type", "", "default storage account type for target regions: Standard_LRS, Standard_ZRS or Premium_LRS (default unchanged)")
+	cmdReplicateGalleryImage.Flags().BoolVar(&waitForReplication, "wait", false, "wait for replication to finish")
+
+	Azure.AddCommand(cmdReplicateGalleryImage)
+}
+
+// parseTargetRegion parses REGION[:REPLICAS[:STORAGE-ACCOUNT-TYPE]].
+func parseTargetRegion(s string) (azure.ReplicationTarget, error) {
+	fields := strings.Split(s, ":")
+	if fields[0] == "" || len(fields) > 3 {
+		return azure.ReplicationTarget{}, fmt.Errorf("invalid target region %q", s)
+	}
+	target := azure.ReplicationTarget{Region: fields[0], StorageAccountType: storageAccountType}
+	if len(fields) > 1 && fields[1] != "" {
+		count, err := strconv.ParseInt(fields[1], 10, 32)
+		if err != nil || count < 1 {
+			return azure.ReplicationTarget{}, fmt.Errorf("invalid replica count in target region %q", s)
+		}
+		target.ReplicaCount = int32(count)
+	}
+	if len(fields) > 2 {
+		target.StorageAccountType = fields[2]
+	}
+	if target.StorageAccountType != "" {
+		if _, err := azure.ParseStorageAccountType(target.StorageAccountType); err != nil {
+			return azure.ReplicationTarget{}, err
+		}
+	}
+	return target, nil
+}
+
+func runReplicateGalleryImage(cmd *cobra.Command, args []string) error {
+	if galleryImageName == "" {
+		return fmt.Errorf("must supply --gallery-image-name")
+	}
+	if len(targetRegions) == 0 {
+		return fmt.Errorf("must supply --target-region")
+	}
+	if replicaCount < 0 {
+		return fmt.Errorf("invalid --replica-count %d", replicaCount)
+	}
+	var targets []azure.ReplicationTarget
+	for _, s := range targetRegions {
+		target, err := parseTargetRegion(s)
+		if err != nil {
+			return err
+		}
+		targets = append(targets, target)
+	}
+
+	if err := api.SetupClients(); err != nil {
+		return fmt.Errorf("setting up clients: %v", err)
+	}
+
+	img, err := api.ReplicateGalleryImageVersion(galleryImageName, galleryName, resourceGroup, galleryImageVersion, targets, replicaCount, waitForReplication)
+	if err != nil {
+		return fmt.Errorf("Couldn't replicate gallery image: %v", err)
+	}
+
+	type region struct {
+		Name     string
+		Replicas *int32  `json:",omitempty"`
+		State    *string `json:",omitempty"`
+	}
+	var regions []region
+	if img.Properties != nil && img.Properties.PublishingProfile != nil {
+		for _, r := range img.Properties.PublishingProfile.TargetRegions {
+			if r.Name == nil {
+				continue
+			}
+			regions = append(regions, region{Name: *r.Name, Replicas: r.RegionalReplicaCount})
+		}
+	}
+	if img.Properties != nil && img.Properties.ReplicationStatus != nil {
+		for _, s := range img.Properties.ReplicationStatus.Summary {
+			if s.Region == nil || s.State == nil {
+				continue
+			}
+			for i := range regions {
+				if azure.SameRegion(regions[i].Name, *s.Region) {
+					regions[i].State = (*string)(s.State)
+				}
+			}
+		}
+	}
+	err = json.NewEncoder(os.Stdout).Encode(&struct {
+		ID      *string
+		Regions []region
+	}{
+		ID:      img.ID,
+		Regions: regions,
+	})
+	if err != nil {
+		return fmt.Errorf("Couldn't encode result: %v", err)
+	}
+	return nil
+}
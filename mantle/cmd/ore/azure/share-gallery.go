@@ -0,0 +1,120 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azure
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/coreos/coreos-assembler/mantle/platform/api/azure"
+)
+
+var (
+	cmdShareGallery = &cobra.Command{
+		Use:   "share-gallery",
+		Short: "Share Azure Gallery",
+		Long: `Share an Azure Shared Image Gallery publicly as a community gallery, or
+directly with subscriptions and tenants.
+
+With --community, the gallery's images become usable by anyone through its
+public name, which is printed along with who the gallery is shared with.
+Otherwise the gallery is shared with each --subscription and --tenant, or
+with --unshare, stops being shared with them. --reset makes the gallery
+private again.`,
+		RunE: runShareGallery,
+
+		SilenceUsage: true,
+	}
+
+	shareCommunity     bool
+	communityGallery   azure.CommunityGallery
+	shareSubscriptions []string
+	shareTenants       []string
+	unshareGallery     bool
+	resetSharing       bool
+)
+
+func init() {
+	sv := cmdShareGallery.Flags().StringVar
+	bv := cmdShareGallery.Flags().BoolVar
+	ssv := cmdShareGallery.Flags().StringSliceVar
+
+	sv(&galleryName, "gallery-name", "kola", "gallery name")
+	sv(&resourceGroup, "resource-group", "kola", "resource group name")
+	bv(&shareCommunity, "community", false, "share the gallery publicly as a community gallery")
+	sv(&communityGallery.PublisherURI, "publisher-uri", "", "community gallery publisher URI")
+	sv(&communityGallery.PublisherContact, "publisher-contact", "", "community gallery publisher contact email")
+	sv(&communityGallery.Eula, "eula", "", "community gallery EULA URI")
+	sv(&communityGallery.PublicNamePrefix, "public-name-prefix", "", "prefix of the community gallery's public name")
+	ssv(&shareSubscriptions, "subscription", nil, "subscription ID to share the gallery with; can be specified multiple times")
+	ssv(&shareTenants, "tenant", nil, "tenant ID to share the gallery with; can be specified multiple times")
+	bv(&unshareGallery, "unshare", false, "stop sharing the gallery with the given subscriptions and tenants")
+	bv(&resetSharing, "reset", false, "make the gallery private")
+
+	Azure.AddCommand(cmdShareGallery)
+}
+
+func runShareGallery(cmd *cobra.Command, args []string) error {
+	direct := len(shareSubscriptions) > 0 || len(shareTenants) > 0
+	switch {
+	case resetSharing && (shareCommunity || direct || unshareGallery):
+		return fmt.Errorf("--reset can't be combined with other sharing options")
+	case shareCommunity && (direct || unshareGallery):
+		return fmt.Errorf("--community can't be combined with direct sharing options")
+	case shareCommunity:
+		if communityGallery.PublisherURI == "" || communityGallery.PublisherContact == "" || communityGallery.Eula == "" || communityGallery.PublicNamePrefix == "" {
+			return fmt.Errorf("--community requires --publisher-uri, --publisher-contact, --eula and --public-name-prefix")
+		}
+	case unshareGallery && !direct:
+		return fmt.Errorf("--unshare requires --subscription or --tenant")
+	case !resetSharing && !direct:
+		return fmt.Errorf("must supply --community, --subscription, --tenant or --reset")
+	}
+
+	if err := api.SetupClients(); err != nil {
+		return fmt.Errorf("setting up clients: %v", err)
+	}
+
+	var err error
+	switch {
+	case resetSharing:
+		err = api.ResetGallerySharing(galleryName, resourceGroup)
+	case shareCommunity:
+		err = api.ShareGalleryWithCommunity(galleryName, resourceGroup, communityGallery)
+	case unshareGallery:
+		err = api.UnshareGallery(galleryName, resourceGroup, shareSubscriptions, shareTenants)
+	default:
+		err = api.ShareGallery(galleryName, resourceGroup, shareSubscriptions, shareTenants)
+	}
+	if err != nil {
+		return fmt.Errorf("Couldn't update sharing of gallery %q: %v", galleryName, err)
+	}
+	return printGallerySharing()
+}
+
+// printGallerySharing prints who the gallery is shared with as JSON.
+func printGallerySharing() error {
+	sharing, err := api.GetGallerySharing(galleryName, resourceGroup)
+	if err != nil {
+		return fmt.Errorf("Couldn't get sharing of gallery %q: %v", galleryName, err)
+	}
+	if err := json.NewEncoder(os.Stdout).Encode(&sharing); err != nil {
+		return fmt.Errorf("Couldn't encode result: %v", err)
+	}
+	return nil
+}
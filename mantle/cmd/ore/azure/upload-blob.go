@@ -22,12 +22,18 @@ import (
 	"strings"
 
 	"github.com/spf13/cobra"
+
+	"github.com/coreos/coreos-assembler/mantle/platform/api/azure"
 )
 
 var (
 	cmdUploadBlob = &cobra.Command{
-		Use:     "upload-blob",
-		Short:   "Upload a blob to Azure storage",
+		Use:   "upload-blob",
+		Short: "Upload a blob to Azure storage",
+		Long: `Upload a VHD to an Azure page blob.
+
+If the blob is an interrupted upload of the same file, the upload is
+resumed.`,
 		Run:     runUploadBlob,
 		Aliases: []string{"upload-blob-arm"},
 	}
@@ -40,6 +46,7 @@ var (
 		vhd         string
 		overwrite   bool
 		validate    bool
+		parallel    int
 	}
 )
 
@@ -49,6 +56,7 @@ func init() {
 
 	bv(&ubo.overwrite, "overwrite", false, "overwrite blob")
 	bv(&ubo.validate, "validate", true, "validate blob as VHD file")
+	cmdUploadBlob.Flags().IntVar(&ubo.parallel, "parallel", 8, "number of chunks to upload at once")
 
 	sv(&ubo.storageacct, "storage-account", "kola", "storage account name")
 	sv(&ubo.container, "container", "vhds", "container name")
@@ -92,16 +100,14 @@ func runUploadBlob(cmd *cobra.Command, args []string) {
 	k := kr.Keys
 	key := k[0].Value
 
-	exists, err := api.PageBlobExists(ubo.storageacct, *key, ubo.container, ubo.blob)
-	if err != nil {
-		plog.Fatalf("Detecting if blob exists failed: %v", err)
-	}
-	if exists && !ubo.overwrite {
+	// An interrupted upload of the same file is resumed
+	err = api.UploadPageBlob(ubo.storageacct, *key, ubo.vhd, ubo.container, ubo.blob, azure.UploadOptions{
+		Parallelism: ubo.parallel,
+		Overwrite:   ubo.overwrite,
+	})
+	if err == azure.ErrBlobExists {
 		plog.Fatalf("The blob exists. Pass --overwrite to force upload.")
-	}
-
-	err = api.UploadPageBlob(ubo.storageacct, *key, ubo.vhd, ubo.container, ubo.blob)
-	if err != nil {
+	} else if err != nil {
 		plog.Fatalf("Uploading blob failed: %v", err)
 	}
 
@@ -0,0 +1,99 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azure
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/coreos/coreos-assembler/mantle/platform/api/azure"
+)
+
+var (
+	cmdUploadDisk = &cobra.Command{
+		Use:   "upload-disk",
+		Short: "Upload a VHD to an Azure managed disk",
+		Long: `Upload a fixed VHD directly to a new Azure managed disk.
+
+Unlike upload-blob, no storage account is needed: the disk is created for
+upload, written through a SAS URL, and committed. Images and gallery image
+versions are created from it with --image-disk. If the disk is an
+interrupted upload of the same file, the upload is resumed.`,
+		Run: runUploadDisk,
+	}
+
+	// upload disk options
+	udo struct {
+		disk      string
+		vhd       string
+		overwrite bool
+		validate  bool
+		parallel  int
+	}
+)
+
+func init() {
+	bv := cmdUploadDisk.Flags().BoolVar
+	sv := cmdUploadDisk.Flags().StringVar
+
+	bv(&udo.overwrite, "overwrite", false, "overwrite disk")
+	bv(&udo.validate, "validate", true, "validate file as VHD")
+	cmdUploadDisk.Flags().IntVar(&udo.parallel, "parallel", 8, "number of chunks to upload at once")
+
+	sv(&udo.disk, "disk-name", "", "name of the disk")
+	sv(&udo.vhd, "file", "", "path to CoreOS VHD image")
+	sv(&resourceGroup, "resource-group", "kola", "resource group name to create the disk in")
+
+	Azure.AddCommand(cmdUploadDisk)
+}
+
+func runUploadDisk(cmd *cobra.Command, args []string) {
+	if udo.vhd == "" {
+		plog.Fatal("--file is required")
+	}
+	if udo.disk == "" {
+		plog.Fatal("--disk-name is required")
+	}
+	if udo.validate && !strings.HasSuffix(strings.ToLower(udo.vhd), ".vhd") {
+		plog.Fatalf("Image should end with .vhd")
+	}
+
+	if err := api.SetupClients(); err != nil {
+		plog.Fatalf("setting up clients: %v\n", err)
+	}
+
+	// An interrupted upload of the same file is resumed
+	id, err := api.UploadDisk(udo.disk, resourceGroup, udo.vhd, azure.UploadOptions{
+		Parallelism: udo.parallel,
+		Overwrite:   udo.overwrite,
+	})
+	if err == azure.ErrDiskExists {
+		plog.Fatalf("The disk exists. Pass --overwrite to force upload.")
+	} else if err != nil {
+		plog.Fatalf("Uploading disk failed: %v", err)
+	}
+
+	err = json.NewEncoder(os.Stdout).Encode(&struct {
+		ID string
+	}{
+		ID: id,
+	})
+	if err != nil {
+		plog.Fatal(err)
+	}
+}
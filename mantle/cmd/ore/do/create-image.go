@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/pborman/uuid"
@@ -32,13 +33,21 @@ var (
 	cmdCreateImage = &cobra.Command{
 		Use:   "create-image [options]",
 		Short: "Create image",
-		Long:  `Create an image.`,
-		RunE:  runCreateImage,
+		Long: `Create an image.
+
+With --file, the image is uploaded to the Spaces bucket given by --bucket
+and imported from there as a custom image, and the uploaded object is
+deleted once it's imported.`,
+		RunE: runCreateImage,
 
 		SilenceUsage: true,
 	}
 
-	customImage bool
+	customImage   bool
+	imageFile     string
+	spacesBucket  string
+	keepObject    bool
+	imageLifetime time.Duration
 )
 
 func init() {
@@ -47,6 +56,11 @@ func init() {
 	cmdCreateImage.Flags().StringVarP(&imageName, "name", "n", "", "image name")
 	cmdCreateImage.Flags().StringVarP(&imageURL, "url", "u", "", "image source URL (e.g. \"https://stable.release.core-os.net/amd64-usr/current/coreos_production_digitalocean_image.bin.bz2\"")
 	cmdCreateImage.Flags().BoolVarP(&customImage, "custom", "", false, "create a \"custom image\" (which supports DHCP) rather than a snapshot of a distribution image (which doesn't)")
+	cmdCreateImage.Flags().StringVar(&imageFile, "file", "", "upload a qcow2 image, optionally compressed, to Spaces and create a custom image from it")
+	cmdCreateImage.Flags().StringVar(&spacesBucket, "bucket", "", "Spaces bucket to upload --file to")
+	cmdCreateImage.Flags().StringVar(&options.SpacesRegion, "spaces-region", "", "region slug of the Spaces bucket (default --region)")
+	cmdCreateImage.Flags().BoolVar(&keepObject, "keep-object", false, "keep the uploaded object after creating the image")
+	cmdCreateImage.Flags().DurationVar(&imageLifetime, "lifetime", 0, "tag the image to expire after the given duration, so gc deletes it (default never)")
 }
 
 func runCreateImage(cmd *cobra.Command, args []string) error {
@@ -67,6 +81,15 @@ func createImage() error {
 	if imageName == "" {
 		return fmt.Errorf("Image name must be specified")
 	}
+	if imageFile != "" {
+		if imageURL != "" {
+			return fmt.Errorf("Only one of image URL and file may be specified")
+		}
+		if spacesBucket == "" {
+			return fmt.Errorf("Spaces bucket must be specified to upload a file")
+		}
+		return uploadCustomImage()
+	}
 	if imageURL == "" {
 		return fmt.Errorf("Image URL must be specified")
 	}
@@ -81,13 +104,45 @@ func createImage() error {
 func createCustomImage() error {
 	ctx := context.Background()
 
-	if _, err := API.CreateCustomImage(ctx, imageName, imageURL); err != nil {
+	image, err := API.CreateCustomImage(ctx, imageName, imageURL, imageTags())
+	if err != nil {
 		return fmt.Errorf("couldn't create image: %v", err)
 	}
 
+	fmt.Println(image.ID)
 	return nil
 }
 
+func uploadCustomImage() error {
+	f, err := os.Open(imageFile)
+	if err != nil {
+		return fmt.Errorf("couldn't open image file: %v", err)
+	}
+	defer f.Close()
+
+	key := imageName + filepath.Ext(imageFile)
+	// The URL only needs to last until the import finishes
+	imageURL, err = API.UploadObject(f, spacesBucket, key, 2*time.Hour)
+	if err != nil {
+		return err
+	}
+	if !keepObject {
+		defer func() {
+			if err := API.DeleteObject(spacesBucket, key); err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+			}
+		}()
+	}
+
+	return createCustomImage()
+}
+
+// imageTags returns the tags of created images: created-by, and when
+// they expire if --lifetime is given.
+func imageTags() map[string]string {
+	return platform.ResourceTags(&platform.Options{ResourceLifetime: imageLifetime}, "")
+}
+
 func createSnapshot() error {
 	// set smallest available size, so the image will run on any size droplet
 	options.Size = "512mb"
@@ -103,7 +158,7 @@ func createSnapshot() error {
 	if err != nil {
 		return err
 	}
-	keyID, err := API.AddKey(ctx, "ore-"+uuid.New(), key)
+	keyID, err := API.AddRunKey(ctx, "ore-"+uuid.New(), key)
 	if err != nil {
 		return err
 	}
@@ -114,7 +169,7 @@ func createSnapshot() error {
 		}
 	}()
 
-	droplet, err := API.CreateDroplet(ctx, imageName+"-install", keyID, userdata)
+	droplet, err := API.CreateDroplet(ctx, imageName+"-install", keyID, userdata, nil)
 	if err != nil {
 		return fmt.Errorf("couldn't create droplet: %v", err)
 	}
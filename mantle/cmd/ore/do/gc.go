@@ -21,6 +21,8 @@ import (
 	"time"
 
 	"github.com/spf13/cobra"
+
+	"github.com/coreos/coreos-assembler/mantle/platform"
 )
 
 var (
@@ -47,7 +49,7 @@ func runGC(cmd *cobra.Command, args []string) error {
 		os.Exit(2)
 	}
 
-	if err := API.GC(context.Background(), gcDuration); err != nil {
+	if err := API.GC(context.Background(), platform.GCOptions{GracePeriod: gcDuration}); err != nil {
 		fmt.Fprintf(os.Stderr, "%v\n", err)
 		os.Exit(1)
 	}
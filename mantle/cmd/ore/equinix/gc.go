@@ -0,0 +1,71 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package equinix
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/coreos/coreos-assembler/mantle/platform"
+	"github.com/coreos/coreos-assembler/mantle/platform/api/equinix"
+)
+
+var (
+	cmdGC = &cobra.Command{
+		Use:   "gc",
+		Short: "GC resources in Equinix Metal",
+		Long:  `Delete servers created over the given duration ago.`,
+		RunE:  runGC,
+
+		SilenceUsage: true,
+	}
+
+	gcDuration time.Duration
+)
+
+func init() {
+	Equinix.AddCommand(cmdGC)
+	cmdGC.Flags().StringVar(&options.Token, "token", "", "API token (default $METAL_AUTH_TOKEN)")
+	cmdGC.Flags().StringVar(&options.Project, "project", "", "project ID (default $METAL_PROJECT_ID)")
+	cmdGC.Flags().DurationVar(&gcDuration, "duration", 5*time.Hour, "how old resources must be before they're considered garbage")
+}
+
+func runGC(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		fmt.Fprintf(os.Stderr, "Unrecognized args in equinix gc cmd: %v\n", args)
+		os.Exit(2)
+	}
+
+	plog.Debugf("Running Equinix Metal preflight check")
+	api, err := equinix.New(&options)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not create Equinix Metal client: %v\n", err)
+		os.Exit(1)
+	}
+	if err := api.PreflightCheck(); err != nil {
+		fmt.Fprintf(os.Stderr, "could not complete Equinix Metal preflight check: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := api.GC(platform.GCOptions{GracePeriod: gcDuration}); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	return nil
+}
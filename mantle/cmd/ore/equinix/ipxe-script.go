@@ -0,0 +1,72 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package equinix
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/coreos/coreos-assembler/mantle/platform"
+	"github.com/coreos/coreos-assembler/mantle/platform/api/equinix"
+)
+
+var (
+	cmdIPXEScript = &cobra.Command{
+		Use:   "ipxe-script",
+		Short: "Write the iPXE script for Equinix Metal servers",
+		Long: `Write the iPXE script which boots Equinix Metal servers from a build's
+live artifacts, to be uploaded next to them and given to kola with
+--equinix-ipxe-script-url.
+
+The servers fetch their Ignition config from the user data. With
+--install-device, the live system installs to the disk without a config,
+and the installed system fetches it on its first boot.`,
+		RunE: runIPXEScript,
+
+		SilenceUsage: true,
+	}
+
+	arch string
+)
+
+func init() {
+	Equinix.AddCommand(cmdIPXEScript)
+	sv := cmdIPXEScript.Flags().StringVar
+	sv(&options.ArtifactURL, "artifact-url", "", "base URL of the live artifacts")
+	sv(&options.Kernel, "kernel", "", "live kernel, a path relative to --artifact-url")
+	sv(&options.Initramfs, "initramfs", "", "live initramfs, a path relative to --artifact-url")
+	sv(&options.Rootfs, "rootfs", "", "live rootfs, a path relative to --artifact-url")
+	sv(&options.InstallDevice, "install-device", "", "disk to install to, e.g. /dev/sda (default run the live system)")
+	sv(&options.Console, "console", "", "kernel console argument (default ttyS1,115200n8, or ttyAMA0,115200 on aarch64)")
+	sv(&arch, "arch", "x86_64", "architecture of the servers")
+}
+
+func runIPXEScript(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		fmt.Fprintf(os.Stderr, "Unrecognized args in equinix ipxe-script cmd: %v\n", args)
+		os.Exit(2)
+	}
+
+	options.Options = &platform.Options{CosaBuildArch: arch}
+	script, err := equinix.IPXEScript(&options)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(2)
+	}
+	fmt.Print(script)
+	return nil
+}
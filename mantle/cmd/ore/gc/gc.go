@@ -0,0 +1,208 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gc
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/pkg/capnslog"
+	"github.com/spf13/cobra"
+
+	"github.com/coreos/coreos-assembler/mantle/platform"
+)
+
+var (
+	plog = capnslog.NewPackageLogger("github.com/coreos/coreos-assembler/mantle", "ore/gc")
+
+	GC = &cobra.Command{
+		Use:   "gc",
+		Short: "GC resources on several platforms",
+		Long: `Delete the resources mantle created on several platforms over the given
+duration ago, running each platform's garbage collector in parallel.
+
+Only resources with all of the given tags, or labels, are deleted. On
+platforms whose tags are bare names, a tag matches a name of KEY:VALUE, and
+resources which can't be tagged are only deleted if no tags are given.
+Resources with an expires tag, as kola stamps them, are deleted once it has
+passed regardless of the duration.
+
+A report of what was deleted on each platform, or would have been with
+--dry-run, is printed, and optionally written as JSON.`,
+		RunE: runGC,
+
+		SilenceUsage: true,
+	}
+
+	allPlatforms bool
+	platforms    []string
+	duration     time.Duration
+	tags         []string
+	dryRun       bool
+	parallel     int
+	reportPath   string
+)
+
+func init() {
+	GC.Flags().BoolVar(&allPlatforms, "all-platforms", false, "collect on every platform: "+strings.Join(platformNames(), ", "))
+	GC.Flags().StringSliceVarP(&platforms, "platform", "p", nil, "platform to collect on; may be repeated")
+	GC.Flags().DurationVar(&duration, "duration", 5*time.Hour, "how old resources must be before they're considered garbage")
+	GC.Flags().StringSliceVar(&tags, "tag", nil, "tag garbage must have, as KEY=VALUE; may be repeated")
+	GC.Flags().BoolVar(&dryRun, "dry-run", false, "report the garbage without deleting it")
+	GC.Flags().IntVar(&parallel, "parallel", 4, "number of platforms to collect on at once")
+	GC.Flags().StringVar(&reportPath, "report", "", "write the report as JSON to this path")
+	addPlatformFlags(GC)
+}
+
+// platformReport is what was collected on a platform.
+type platformReport struct {
+	Platform  string                `json:"platform"`
+	Error     string                `json:"error,omitempty"`
+	Resources []platform.GCResource `json:"resources"`
+}
+
+type report struct {
+	DryRun      bool              `json:"dryRun"`
+	GracePeriod string            `json:"gracePeriod"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Platforms   []platformReport  `json:"platforms"`
+}
+
+func runGC(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		fmt.Fprintf(os.Stderr, "Unrecognized args in gc cmd: %v\n", args)
+		os.Exit(2)
+	}
+	if allPlatforms == (len(platforms) > 0) {
+		fmt.Fprintf(os.Stderr, "Exactly one of --all-platforms or --platform is required\n")
+		os.Exit(2)
+	}
+	if allPlatforms {
+		platforms = platformNames()
+	}
+	for _, name := range platforms {
+		if _, ok := collectors[name]; !ok {
+			fmt.Fprintf(os.Stderr, "Unknown platform %q; expected one of %s\n", name, strings.Join(platformNames(), ", "))
+			os.Exit(2)
+		}
+	}
+	if parallel < 1 {
+		fmt.Fprintf(os.Stderr, "--parallel must be at least 1\n")
+		os.Exit(2)
+	}
+	tagMap := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		key, value, ok := strings.Cut(tag, "=")
+		if !ok {
+			fmt.Fprintf(os.Stderr, "invalid tag %q; expected KEY=VALUE\n", tag)
+			os.Exit(2)
+		}
+		tagMap[key] = value
+	}
+
+	r := report{
+		DryRun:      dryRun,
+		GracePeriod: duration.String(),
+		Tags:        tagMap,
+		Platforms:   make([]platformReport, len(platforms)),
+	}
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, name := range platforms {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			opts := platform.GCOptions{
+				GracePeriod: duration,
+				Tags:        tagMap,
+				DryRun:      dryRun,
+				Report:      &platform.GCReport{},
+			}
+			plog.Infof("Collecting garbage on %s", name)
+			err := collectors[name](opts)
+			r.Platforms[i] = platformReport{
+				Platform:  name,
+				Resources: opts.Report.Resources(),
+			}
+			if err != nil {
+				plog.Errorf("Collecting garbage on %s: %v", name, err)
+				r.Platforms[i].Error = err.Error()
+			}
+		}(i, name)
+	}
+	wg.Wait()
+
+	failed := printReport(r)
+	if reportPath != "" {
+		if err := writeReport(r, reportPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Couldn't write report: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+	return nil
+}
+
+// printReport prints the report, and returns whether collecting garbage
+// failed on any platform.
+func printReport(r report) bool {
+	verb := "Deleted"
+	if r.DryRun {
+		verb = "Would delete"
+	}
+	failed := false
+	for _, p := range r.Platforms {
+		fmt.Printf("%s: %s %d resources\n", p.Platform, verb, len(p.Resources))
+		for _, res := range p.Resources {
+			if res.Created.IsZero() {
+				fmt.Printf("  %s %s\n", res.Kind, res.ID)
+			} else {
+				fmt.Printf("  %s %s (created %s)\n", res.Kind, res.ID, res.Created.Format(time.RFC3339))
+			}
+		}
+		if p.Error != "" {
+			fmt.Printf("  failed: %s\n", p.Error)
+			failed = true
+		}
+	}
+	return failed
+}
+
+func writeReport(r report, path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+func platformNames() []string {
+	var names []string
+	for name := range collectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
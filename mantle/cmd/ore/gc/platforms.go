@@ -0,0 +1,189 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gc
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/coreos/coreos-assembler/mantle/auth"
+	"github.com/coreos/coreos-assembler/mantle/platform"
+	"github.com/coreos/coreos-assembler/mantle/platform/api/aws"
+	"github.com/coreos/coreos-assembler/mantle/platform/api/azure"
+	"github.com/coreos/coreos-assembler/mantle/platform/api/do"
+	"github.com/coreos/coreos-assembler/mantle/platform/api/equinix"
+	"github.com/coreos/coreos-assembler/mantle/platform/api/gcloud"
+	"github.com/coreos/coreos-assembler/mantle/platform/api/hetzner"
+	"github.com/coreos/coreos-assembler/mantle/platform/api/ibmcloud"
+	"github.com/coreos/coreos-assembler/mantle/platform/api/openstack"
+	"github.com/coreos/coreos-assembler/mantle/platform/api/vultr"
+)
+
+// collectors collect the garbage on each platform, using the settings
+// from the flags addPlatformFlags adds.
+var collectors = map[string]func(platform.GCOptions) error{
+	"aws":       gcAWS,
+	"azure":     gcAzure,
+	"do":        gcDO,
+	"equinix":   gcEquinix,
+	"gcp":       gcGCP,
+	"hetzner":   gcHetzner,
+	"openstack": gcOpenStack,
+	"powervs":   gcPowerVS,
+	"vultr":     gcVultr,
+}
+
+var (
+	awsOptions       = aws.Options{Options: &platform.Options{}}
+	awsRegions       []string
+	azureOptions     = azure.Options{Options: &platform.Options{}}
+	azureLocations   azure.GCLocations
+	doOptions        = do.Options{Options: &platform.Options{}}
+	equinixOptions   = equinix.Options{Options: &platform.Options{}}
+	gcpOptions       = gcloud.Options{Options: &platform.Options{}}
+	hetznerOptions   = hetzner.Options{Options: &platform.Options{}}
+	openstackOptions = openstack.Options{Options: &platform.Options{}}
+	powervsOptions   = ibmcloud.Options{Options: &platform.Options{}}
+	vultrOptions     = vultr.Options{Options: &platform.Options{}}
+)
+
+func addPlatformFlags(cmd *cobra.Command) {
+	defaultRegion := os.Getenv("AWS_REGION")
+	if defaultRegion == "" {
+		defaultRegion = "us-west-2"
+	}
+
+	sv := cmd.Flags().StringVar
+	sv(&awsOptions.CredentialsFile, "aws-credentials-file", "", "AWS credentials file")
+	sv(&awsOptions.Profile, "aws-profile", "", "AWS profile name")
+	cmd.Flags().StringSliceVar(&awsRegions, "aws-region", []string{defaultRegion}, "AWS region; may be repeated")
+	sv(&azureOptions.AzureCredentials, "azure-credentials", "", "Azure credentials file location (default \"~/"+auth.AzureCredentialsPath+"\")")
+	sv(&azureLocations.ResourceGroup, "azure-resource-group", "kola", "Azure resource group to collect disks, images and galleries from; empty for none")
+	sv(&azureLocations.StorageAccount, "azure-storage-account", "kola", "Azure storage account to collect blobs from; empty for none")
+	sv(&azureLocations.Container, "azure-container", "vhds", "Azure storage container to collect blobs from")
+	sv(&doOptions.ConfigPath, "do-config-file", "", "DigitalOcean config file (default \"~/"+auth.DOConfigPath+"\")")
+	sv(&doOptions.Profile, "do-profile", "", "DigitalOcean profile (default \"default\")")
+	sv(&equinixOptions.Token, "equinix-token", "", "Equinix Metal API token (default $METAL_AUTH_TOKEN)")
+	sv(&equinixOptions.Project, "equinix-project", "", "Equinix Metal project ID (default $METAL_PROJECT_ID)")
+	sv(&gcpOptions.Project, "gcp-project", "fedora-coreos-devel", "GCP project")
+	sv(&gcpOptions.Zone, "gcp-zone", "us-central1-a", "GCP zone")
+	sv(&gcpOptions.JSONKeyFile, "gcp-json-key", "", "use a GCP service account's JSON key for authentication")
+	cmd.Flags().BoolVar(&gcpOptions.ServiceAuth, "gcp-service-auth", false, "use non-interactive GCP auth when running within GCP")
+	sv(&hetznerOptions.Token, "hetzner-token", "", "Hetzner Cloud API token (default $HCLOUD_TOKEN)")
+	sv(&openstackOptions.ConfigPath, "openstack-config-file", "", "Path to a clouds.yaml formatted OpenStack config file. The underlying library defaults to ./clouds.yaml")
+	sv(&openstackOptions.Profile, "openstack-profile", "", "OpenStack profile within clouds.yaml (default \"openstack\")")
+	sv(&openstackOptions.Region, "openstack-region", "", "Override the OpenStack region from the config.")
+	sv(&powervsOptions.CredentialsFile, "ibmcloud-credentials-file", "", "IBM Cloud API key file")
+	sv(&powervsOptions.PowerVSServiceInstance, "powervs-service-instance", "", "GUID of the Power Virtual Server workspace")
+	sv(&vultrOptions.Token, "vultr-token", "", "Vultr API key (default $VULTR_API_KEY)")
+}
+
+func gcAWS(opts platform.GCOptions) error {
+	for _, region := range awsRegions {
+		regionOptions := awsOptions
+		regionOptions.Region = region
+		api, err := aws.New(&regionOptions)
+		if err != nil {
+			return fmt.Errorf("creating client for %s: %v", region, err)
+		}
+		if err := api.GC(opts); err != nil {
+			return fmt.Errorf("%s: %v", region, err)
+		}
+	}
+	return nil
+}
+
+func gcAzure(opts platform.GCOptions) error {
+	api, err := azure.New(&azureOptions)
+	if err != nil {
+		return fmt.Errorf("creating client: %v", err)
+	}
+	if err := api.SetupClients(); err != nil {
+		return fmt.Errorf("setting up clients: %v", err)
+	}
+	return api.GC(opts, azureLocations)
+}
+
+func gcDO(opts platform.GCOptions) error {
+	api, err := do.New(&doOptions)
+	if err != nil {
+		return fmt.Errorf("creating client: %v", err)
+	}
+	return api.GC(context.Background(), opts)
+}
+
+func gcEquinix(opts platform.GCOptions) error {
+	api, err := equinix.New(&equinixOptions)
+	if err != nil {
+		return fmt.Errorf("creating client: %v", err)
+	}
+	return api.GC(opts)
+}
+
+func gcGCP(opts platform.GCOptions) error {
+	api, err := gcloud.New(&gcpOptions)
+	if err != nil {
+		return fmt.Errorf("creating client: %v", err)
+	}
+	return api.GC(opts)
+}
+
+func gcHetzner(opts platform.GCOptions) error {
+	api, err := hetzner.New(&hetznerOptions)
+	if err != nil {
+		return fmt.Errorf("creating client: %v", err)
+	}
+	return api.GC(opts)
+}
+
+func gcOpenStack(opts platform.GCOptions) error {
+	api, err := openstack.New(&openstackOptions)
+	if err != nil {
+		return fmt.Errorf("creating client: %v", err)
+	}
+	return api.GC(opts)
+}
+
+func gcPowerVS(opts platform.GCOptions) error {
+	if powervsOptions.PowerVSServiceInstance == "" {
+		return fmt.Errorf("--powervs-service-instance is required")
+	}
+	if powervsOptions.ApiKey == "" {
+		key, err := ibmcloud.LoadAPIKey(powervsOptions.CredentialsFile)
+		if err != nil {
+			return fmt.Errorf("loading API key: %v", err)
+		}
+		powervsOptions.ApiKey = key
+	}
+	api, err := ibmcloud.New(&powervsOptions)
+	if err != nil {
+		return fmt.Errorf("creating client: %v", err)
+	}
+	if err := api.NewPowerVSClient(powervsOptions.PowerVSServiceInstance); err != nil {
+		return err
+	}
+	return api.PowerVSGC(opts)
+}
+
+func gcVultr(opts platform.GCOptions) error {
+	api, err := vultr.New(&vultrOptions)
+	if err != nil {
+		return fmt.Errorf("creating client: %v", err)
+	}
+	return api.GC(opts)
+}
@@ -20,6 +20,8 @@ import (
 	"time"
 
 	"github.com/spf13/cobra"
+
+	"github.com/coreos/coreos-assembler/mantle/platform"
 )
 
 var (
@@ -46,7 +48,7 @@ func runGC(cmd *cobra.Command, args []string) error {
 		os.Exit(2)
 	}
 
-	if err := api.GC(gcDuration); err != nil {
+	if err := api.GC(platform.GCOptions{GracePeriod: gcDuration}); err != nil {
 		fmt.Fprintf(os.Stderr, "%v\n", err)
 		os.Exit(1)
 	}
@@ -48,6 +48,8 @@ var (
 	uploadCreateImage      bool
 	uploadPublic           bool
 	uploadImageLicenses    []string
+	uploadGuestOSFeatures  []string
+	uploadPromote          bool
 )
 
 func init() {
@@ -72,6 +74,11 @@ func init() {
 	cmdUpload.Flags().StringSliceVar(
 		&uploadImageLicenses, "license", []string{},
 		"License to attach to image. Can be specified multiple times.")
+	cmdUpload.Flags().StringVar(&uploadImageFamily, "family", "", "Image family to create the image in")
+	cmdUpload.Flags().BoolVar(&uploadPromote, "promote", false, "Deprecate the other images in the family in favor of the new one")
+	cmdUpload.Flags().StringSliceVar(
+		&uploadGuestOSFeatures, "guest-os-feature", []string{},
+		"Guest OS feature to enable on top of the defaults for the architecture. Can be specified multiple times.")
 	GCloud.AddCommand(cmdUpload)
 }
 
@@ -80,6 +87,10 @@ func runUpload(cmd *cobra.Command, args []string) {
 		fmt.Fprintf(os.Stderr, "Unrecognized args in plume upload cmd: %v\n", args)
 		os.Exit(2)
 	}
+	if uploadPromote && (uploadImageFamily == "" || !uploadCreateImage) {
+		fmt.Fprintf(os.Stderr, "--promote requires --family and --create-image\n")
+		os.Exit(2)
+	}
 
 	gsURL, err := url.Parse(uploadBucket)
 	if err != nil {
@@ -151,6 +162,8 @@ func runUpload(cmd *cobra.Command, args []string) {
 			Family:       uploadImageFamily,
 			SourceImage:  imageStorageURL,
 			Description:  uploadImageDescription,
+
+			GuestOSFeatures: uploadGuestOSFeatures,
 		}
 		if len(uploadImageLicenses) > 0 {
 			spec.Licenses = uploadImageLicenses
@@ -198,6 +211,15 @@ func runUpload(cmd *cobra.Command, args []string) {
 				os.Exit(1)
 			}
 		}
+
+		if uploadPromote {
+			fmt.Printf("Promoting image in family %v: %v\n", uploadImageFamily, imageNameGCP)
+			err = api.PromoteImage(ctx, imageNameGCP, uploadImageFamily)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Promoting GCP image failed: %v\n", err)
+				os.Exit(1)
+			}
+		}
 	}
 
 	if uploadWriteUrl != "" {
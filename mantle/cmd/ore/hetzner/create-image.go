@@ -0,0 +1,89 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hetzner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdCreateImage = &cobra.Command{
+		Use:   "create-image",
+		Short: "Create an image",
+		Long: `Create a snapshot from a raw disk image, which may be xz compressed.
+
+Hetzner Cloud can't import images, so the image is written to the disk of a
+temporary server booted into its rescue system, which is then snapshotted.
+The server type must be of the image's architecture, such as cx22 for x86_64
+or cax11 for aarch64.
+
+After a successful run, the final line of output will be the ID of the
+snapshot.
+`,
+		RunE: runCreateImage,
+
+		SilenceUsage: true,
+	}
+
+	createImageFile   string
+	createImageName   string
+	createImageLabels []string
+)
+
+func init() {
+	Hetzner.AddCommand(cmdCreateImage)
+	cmdCreateImage.Flags().StringVar(&createImageFile, "file", "", "path to the raw disk image")
+	cmdCreateImage.Flags().StringVar(&createImageName, "name", "", "snapshot description (default the file name)")
+	cmdCreateImage.Flags().StringSliceVar(&createImageLabels, "label", nil, "snapshot label, as KEY=VALUE")
+	cmdCreateImage.Flags().StringVar(&options.ServerType, "server-type", "cx22", "type of the server the image is written with")
+}
+
+func runCreateImage(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		fmt.Fprintf(os.Stderr, "Unrecognized args in hetzner create-image cmd: %v\n", args)
+		os.Exit(2)
+	}
+	if createImageFile == "" {
+		fmt.Fprintf(os.Stderr, "--file is required\n")
+		os.Exit(2)
+	}
+	if createImageName == "" {
+		createImageName = strings.TrimSuffix(filepath.Base(createImageFile), ".xz")
+	}
+	labels := map[string]string{
+		"created-by": "mantle",
+	}
+	for _, label := range createImageLabels {
+		key, value, ok := strings.Cut(label, "=")
+		if !ok {
+			fmt.Fprintf(os.Stderr, "invalid label %q; expected KEY=VALUE\n", label)
+			os.Exit(2)
+		}
+		labels[key] = value
+	}
+
+	id, err := API.UploadImage(createImageName, createImageFile, labels)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Couldn't create image: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(id)
+	return nil
+}
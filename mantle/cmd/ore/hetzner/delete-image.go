@@ -0,0 +1,74 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hetzner
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdDeleteImage = &cobra.Command{
+		Use:   "delete-image",
+		Short: "Delete an image",
+		Long:  `Delete a snapshot, or all those matching a label selector.`,
+		RunE:  runDeleteImage,
+
+		SilenceUsage: true,
+	}
+
+	deleteImageID       int64
+	deleteImageSelector string
+)
+
+func init() {
+	Hetzner.AddCommand(cmdDeleteImage)
+	cmdDeleteImage.Flags().Int64Var(&deleteImageID, "image", 0, "snapshot ID")
+	cmdDeleteImage.Flags().StringVar(&deleteImageSelector, "selector", "", "label selector of the snapshots to delete")
+}
+
+func runDeleteImage(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		fmt.Fprintf(os.Stderr, "Unrecognized args in hetzner delete-image cmd: %v\n", args)
+		os.Exit(2)
+	}
+	if (deleteImageID == 0) == (deleteImageSelector == "") {
+		fmt.Fprintf(os.Stderr, "Exactly one of --image or --selector is required\n")
+		os.Exit(2)
+	}
+
+	ids := []int64{deleteImageID}
+	if deleteImageSelector != "" {
+		images, err := API.ListImages(deleteImageSelector)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Couldn't list images: %v\n", err)
+			os.Exit(1)
+		}
+		ids = ids[:0]
+		for _, image := range images {
+			ids = append(ids, image.ID)
+		}
+	}
+	for _, id := range ids {
+		plog.Infof("Deleting snapshot %d", id)
+		if err := API.DeleteImage(id); err != nil {
+			fmt.Fprintf(os.Stderr, "Couldn't delete image %d: %v\n", id, err)
+			os.Exit(1)
+		}
+	}
+	return nil
+}
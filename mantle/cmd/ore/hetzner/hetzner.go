@@ -0,0 +1,58 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hetzner
+
+import (
+	"fmt"
+
+	"github.com/coreos/pkg/capnslog"
+	"github.com/spf13/cobra"
+
+	"github.com/coreos/coreos-assembler/mantle/cli"
+	"github.com/coreos/coreos-assembler/mantle/platform/api/hetzner"
+)
+
+var (
+	plog = capnslog.NewPackageLogger("github.com/coreos/coreos-assembler/mantle", "ore/hetzner")
+
+	Hetzner = &cobra.Command{
+		Use:   "hetzner [command]",
+		Short: "Hetzner Cloud image utilities",
+	}
+
+	API     *hetzner.API
+	options hetzner.Options
+)
+
+func init() {
+	Hetzner.PersistentFlags().StringVar(&options.Token, "token", "", "API token (default $HCLOUD_TOKEN)")
+	Hetzner.PersistentFlags().StringVar(&options.Location, "location", "fsn1", "location")
+	cli.WrapPreRun(Hetzner, preflightCheck)
+}
+
+func preflightCheck(cmd *cobra.Command, args []string) error {
+	plog.Debugf("Running Hetzner preflight check")
+	api, err := hetzner.New(&options)
+	if err != nil {
+		return fmt.Errorf("could not create Hetzner client: %v", err)
+	}
+	if err := api.PreflightCheck(); err != nil {
+		return fmt.Errorf("could not complete Hetzner preflight check: %v", err)
+	}
+
+	plog.Debugf("Preflight check success; we have liftoff")
+	API = api
+	return nil
+}
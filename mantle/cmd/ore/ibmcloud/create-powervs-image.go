@@ -0,0 +1,112 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ibmcloud
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/coreos/coreos-assembler/mantle/platform/api/ibmcloud"
+)
+
+var (
+	cmdCreatePowerVSImage = &cobra.Command{
+		Use:   "create-powervs-image",
+		Short: "Create a Power Virtual Server image",
+		Long: `Import an image uploaded to cloud object storage into a Power Virtual Server workspace.
+
+The image is an .ova.gz uploaded with the upload command. Unless the bucket
+is public, HMAC credentials for it must be given, since Power Virtual Server
+can't use an API key to read it.
+
+After a successful run, the final line of output will be the ID of the image.
+`,
+		Example: `  ore ibmcloud create-powervs-image --region=us-south \
+	  --service-instance=7845d372-d4e1-46b8-91fc-41051c984601 \
+	  --bucket=coreos-dev-image-ibmcloud-us-south \
+	  --object=rhcos-powervs.ova.gz --name=rhcos-418`,
+		RunE: runCreatePowerVSImage,
+
+		SilenceUsage: true,
+	}
+
+	powervsImportOpts ibmcloud.PowerVSImportOptions
+	powervsImageName  string
+	powervsForce      bool
+)
+
+func init() {
+	IbmCloud.AddCommand(cmdCreatePowerVSImage)
+	addPowerVSFlags(cmdCreatePowerVSImage)
+	cmdCreatePowerVSImage.Flags().StringVar(&powervsImportOpts.Bucket, "bucket", "", "bucket holding the image; defaults to a regional bucket")
+	cmdCreatePowerVSImage.Flags().StringVar(&powervsImportOpts.Object, "object", "", "name of the image in the bucket")
+	cmdCreatePowerVSImage.Flags().StringVar(&powervsImportOpts.AccessKey, "access-key", "", "HMAC access key ID for the bucket")
+	cmdCreatePowerVSImage.Flags().StringVar(&powervsImportOpts.SecretKey, "secret-key", "", "HMAC secret access key for the bucket")
+	cmdCreatePowerVSImage.Flags().StringVar(&powervsImportOpts.StorageType, "storage-type", "tier3", "storage tier of the image")
+	cmdCreatePowerVSImage.Flags().StringVar(&powervsImageName, "name", "", "name of the image; defaults to the object name")
+	cmdCreatePowerVSImage.Flags().BoolVar(&powervsForce, "force", false, "replace any existing image of the same name")
+}
+
+func runCreatePowerVSImage(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		fmt.Fprintf(os.Stderr, "Unrecognized args in ibmcloud create-powervs-image cmd: %v\n", args)
+		os.Exit(2)
+	}
+	if powervsImportOpts.Object == "" {
+		fmt.Fprintf(os.Stderr, "specify --object\n")
+		os.Exit(2)
+	}
+	if (powervsImportOpts.AccessKey == "") != (powervsImportOpts.SecretKey == "") {
+		fmt.Fprintf(os.Stderr, "specify both --access-key and --secret-key, or neither\n")
+		os.Exit(2)
+	}
+	if powervsImportOpts.Bucket == "" {
+		powervsImportOpts.Bucket = defaultBucketNameForRegion(region)
+	}
+	powervsImportOpts.Region = region
+	if powervsImageName == "" {
+		powervsImageName = powervsImportOpts.Object
+	}
+
+	if err := newPowerVSClient(); err != nil {
+		return err
+	}
+
+	existing, err := API.FindPowerVSImage(powervsImageName)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		if !powervsForce {
+			plog.Infof("Image %s already exists; skipping import since --force was not set", powervsImageName)
+			fmt.Println(existing.ID)
+			return nil
+		}
+		plog.Infof("Deleting existing image %s", existing.ID)
+		if err := API.DeletePowerVSImage(existing.ID); err != nil {
+			return err
+		}
+	}
+
+	id, err := API.ImportPowerVSImage(powervsImageName, powervsImportOpts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Couldn't create image: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(id)
+	return nil
+}
@@ -0,0 +1,64 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ibmcloud
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdDeletePowerVSImage = &cobra.Command{
+		Use:   "delete-powervs-image",
+		Short: "Delete a Power Virtual Server image",
+		RunE:  runDeletePowerVSImage,
+
+		SilenceUsage: true,
+	}
+
+	powervsDeleteImage string
+)
+
+func init() {
+	IbmCloud.AddCommand(cmdDeletePowerVSImage)
+	addPowerVSFlags(cmdDeletePowerVSImage)
+	cmdDeletePowerVSImage.Flags().StringVar(&powervsDeleteImage, "image", "", "ID or name of the image")
+}
+
+func runDeletePowerVSImage(cmd *cobra.Command, args []string) error {
+	if powervsDeleteImage == "" {
+		fmt.Fprintf(os.Stderr, "specify --image\n")
+		os.Exit(2)
+	}
+	if err := newPowerVSClient(); err != nil {
+		return err
+	}
+
+	image, err := API.FindPowerVSImage(powervsDeleteImage)
+	if err != nil {
+		return err
+	}
+	if image == nil {
+		plog.Infof("Image %s doesn't exist", powervsDeleteImage)
+		return nil
+	}
+	if err := API.DeletePowerVSImage(image.ID); err != nil {
+		fmt.Fprintf(os.Stderr, "Couldn't delete image: %v\n", err)
+		os.Exit(1)
+	}
+	return nil
+}
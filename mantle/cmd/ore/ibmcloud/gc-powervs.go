@@ -0,0 +1,55 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ibmcloud
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/coreos/coreos-assembler/mantle/platform"
+)
+
+var (
+	cmdGCPowerVS = &cobra.Command{
+		Use:   "gc-powervs",
+		Short: "GC resources in a Power Virtual Server workspace",
+		Long:  `Delete instances created by kola over the given duration ago`,
+		RunE:  runGCPowerVS,
+
+		SilenceUsage: true,
+	}
+
+	powervsGCDuration time.Duration
+)
+
+func init() {
+	IbmCloud.AddCommand(cmdGCPowerVS)
+	addPowerVSFlags(cmdGCPowerVS)
+	cmdGCPowerVS.Flags().DurationVar(&powervsGCDuration, "duration", 5*time.Hour, "how old resources must be before they're considered garbage")
+}
+
+func runGCPowerVS(cmd *cobra.Command, args []string) error {
+	if err := newPowerVSClient(); err != nil {
+		return err
+	}
+	if err := API.PowerVSGC(platform.GCOptions{GracePeriod: powervsGCDuration}); err != nil {
+		fmt.Fprintf(os.Stderr, "Couldn't gc: %v\n", err)
+		os.Exit(1)
+	}
+	return nil
+}
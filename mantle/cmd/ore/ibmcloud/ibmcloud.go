@@ -17,11 +17,8 @@
 package ibmcloud
 
 import (
-	"encoding/json"
 	"fmt"
-	"io"
 	"os"
-	"os/user"
 	"path/filepath"
 
 	"github.com/coreos/coreos-assembler/mantle/cli"
@@ -31,13 +28,6 @@ import (
 	"github.com/spf13/cobra"
 )
 
-type apiKeyFile struct {
-	Name        string `json:"name,omitempty"`
-	Description string `json:"description,omitempty"`
-	CreatedAt   string `json:"createdAt,omitempty"`
-	ApiKey      string `json:"apikey"`
-}
-
 var (
 	plog = capnslog.NewPackageLogger("github.com/coreos/coreos-assembler/mantle", "ore/ibmcloud")
 
@@ -50,6 +40,10 @@ var (
 	region          string
 	credentialsFile string
 	apiKey          string
+
+	// powervsServiceInstance is the GUID of the Power Virtual Server
+	// workspace the powervs commands act on
+	powervsServiceInstance string
 )
 
 func init() {
@@ -69,44 +63,17 @@ func preflightCheck(cmd *cobra.Command, args []string) error {
 
 	// if api key is not specified search the credentials file
 	if apiKey == "" {
-		// check if credentials file exists
 		if credentialsFile != "" {
 			credentialsFile, _ = filepath.Abs(credentialsFile)
 		} else {
 			plog.Debugf("credentials file not provided - checking default file")
-			currentUser, err := user.Current()
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "error getting current user info: %v\n", err)
-				os.Exit(1)
-			}
-			homedir := currentUser.HomeDir
-			credentialsFile = filepath.Join(homedir, ".bluemix/apikey.json")
-		}
-
-		file, err := os.Open(credentialsFile)
-		if err != nil {
-			if os.IsNotExist(err) {
-				fmt.Fprintf(os.Stderr, "credentials file does not exist: %v\n", err)
-				os.Exit(1)
-			} else {
-				fmt.Fprintf(os.Stderr, "could not open credentials file: %v\n", err)
-				os.Exit(1)
-			}
 		}
-		defer file.Close()
-
-		var apiKeyValues apiKeyFile
-		bytes, err := io.ReadAll(file)
+		var err error
+		apiKey, err = ibmcloud.LoadAPIKey(credentialsFile)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "could not read apikey file: %v\n", err)
+			fmt.Fprintf(os.Stderr, "could not load api key: %v\n", err)
 			os.Exit(1)
 		}
-		err = json.Unmarshal(bytes, &apiKeyValues)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "could not parse api key json file: %v\n", err)
-			os.Exit(1)
-		}
-		apiKey = apiKeyValues.ApiKey
 	}
 
 	api, err := ibmcloud.New(&ibmcloud.Options{
@@ -122,3 +89,18 @@ func preflightCheck(cmd *cobra.Command, args []string) error {
 	API = api
 	return nil
 }
+
+// addPowerVSFlags adds the flags of the commands acting on a Power Virtual
+// Server workspace.
+func addPowerVSFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&powervsServiceInstance, "service-instance", os.Getenv("POWERVS_SERVICE_INSTANCE"), "GUID of the Power Virtual Server workspace")
+}
+
+// newPowerVSClient creates the client for the workspace given by the
+// --service-instance flag.
+func newPowerVSClient() error {
+	if powervsServiceInstance == "" {
+		return fmt.Errorf("specify --service-instance")
+	}
+	return API.NewPowerVSClient(powervsServiceInstance)
+}
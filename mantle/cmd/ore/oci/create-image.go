@@ -0,0 +1,81 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oci
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/coreos/coreos-assembler/mantle/platform/api/oci"
+)
+
+var (
+	cmdCreateImage = &cobra.Command{
+		Use:   "create-image",
+		Short: "Create an image",
+		Long: `Import a qcow2 image uploaded to Object Storage as a custom image.
+
+The launch mode sets the devices instances of the image get: NATIVE for NVMe
+and SR-IOV, PARAVIRTUALIZED for virtio, EMULATED for emulated devices, or
+CUSTOM.
+
+After a successful run, the final line of output will be the OCID of the image.
+`,
+		RunE: runCreateImage,
+
+		SilenceUsage: true,
+	}
+
+	createImageName       string
+	createImageObject     string
+	createImageLaunchMode string
+)
+
+func init() {
+	OCI.AddCommand(cmdCreateImage)
+	cmdCreateImage.Flags().StringVar(&bucket, "bucket", "", "Object Storage bucket holding the image")
+	cmdCreateImage.Flags().StringVar(&createImageObject, "object", "", "object name of the image")
+	cmdCreateImage.Flags().StringVar(&createImageName, "name", "", "image name (default the object name)")
+	cmdCreateImage.Flags().StringVar(&createImageLaunchMode, "launch-mode", "PARAVIRTUALIZED", "launch mode: NATIVE, PARAVIRTUALIZED, EMULATED, CUSTOM")
+}
+
+func runCreateImage(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		fmt.Fprintf(os.Stderr, "Unrecognized args in oci create-image cmd: %v\n", args)
+		os.Exit(2)
+	}
+	if bucket == "" || createImageObject == "" {
+		fmt.Fprintf(os.Stderr, "--bucket and --object are required\n")
+		os.Exit(2)
+	}
+	launchMode, err := oci.ParseLaunchMode(createImageLaunchMode)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(2)
+	}
+	if createImageName == "" {
+		createImageName = createImageObject
+	}
+
+	id, err := API.CreateImage(createImageName, bucket, createImageObject, launchMode)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Couldn't create image: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(id)
+	return nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oci
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdDeleteImage = &cobra.Command{
+		Use:   "delete-image",
+		Short: "Delete an image",
+		Long:  `Delete an image, and optionally the object it was imported from.`,
+		RunE:  runDeleteImage,
+
+		SilenceUsage: true,
+	}
+
+	deleteImageID     string
+	deleteImageObject string
+)
+
+func init() {
+	OCI.AddCommand(cmdDeleteImage)
+	cmdDeleteImage.Flags().StringVar(&deleteImageID, "image", "", "image OCID")
+	cmdDeleteImage.Flags().StringVar(&bucket, "bucket", "", "Object Storage bucket holding the object to delete")
+	cmdDeleteImage.Flags().StringVar(&deleteImageObject, "object", "", "object to delete along with the image")
+}
+
+func runDeleteImage(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		fmt.Fprintf(os.Stderr, "Unrecognized args in oci delete-image cmd: %v\n", args)
+		os.Exit(2)
+	}
+	if deleteImageID == "" && deleteImageObject == "" {
+		fmt.Fprintf(os.Stderr, "--image or --object is required\n")
+		os.Exit(2)
+	}
+	if deleteImageObject != "" && bucket == "" {
+		fmt.Fprintf(os.Stderr, "--object requires --bucket\n")
+		os.Exit(2)
+	}
+
+	if deleteImageID != "" {
+		if err := API.DeleteImage(deleteImageID); err != nil {
+			fmt.Fprintf(os.Stderr, "Couldn't delete image: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if deleteImageObject != "" {
+		if err := API.DeleteObject(bucket, deleteImageObject); err != nil {
+			fmt.Fprintf(os.Stderr, "Couldn't delete object: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	return nil
+}
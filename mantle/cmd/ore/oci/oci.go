@@ -0,0 +1,63 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oci
+
+import (
+	"fmt"
+
+	"github.com/coreos/pkg/capnslog"
+	"github.com/spf13/cobra"
+
+	"github.com/coreos/coreos-assembler/mantle/auth"
+	"github.com/coreos/coreos-assembler/mantle/cli"
+	"github.com/coreos/coreos-assembler/mantle/platform/api/oci"
+)
+
+var (
+	plog = capnslog.NewPackageLogger("github.com/coreos/coreos-assembler/mantle", "ore/oci")
+
+	OCI = &cobra.Command{
+		Use:   "oci [command]",
+		Short: "Oracle Cloud Infrastructure image utilities",
+	}
+
+	API     *oci.API
+	options oci.Options
+
+	bucket string
+)
+
+func init() {
+	OCI.PersistentFlags().StringVar(&options.ConfigPath, "config-file", "", "config file (default \"~/"+auth.OCIConfigPath+"\")")
+	OCI.PersistentFlags().StringVar(&options.Profile, "profile", "", "profile (default \"DEFAULT\")")
+	OCI.PersistentFlags().StringVar(&options.Region, "region", "", "region (default the profile's)")
+	OCI.PersistentFlags().StringVar(&options.CompartmentID, "compartment", "", "compartment OCID (default the tenancy's root compartment)")
+	cli.WrapPreRun(OCI, preflightCheck)
+}
+
+func preflightCheck(cmd *cobra.Command, args []string) error {
+	plog.Debugf("Running OCI preflight check")
+	api, err := oci.New(&options)
+	if err != nil {
+		return fmt.Errorf("could not create OCI client: %v", err)
+	}
+	if err := api.PreflightCheck(); err != nil {
+		return fmt.Errorf("could not complete OCI preflight check: %v", err)
+	}
+
+	plog.Debugf("Preflight check success; we have liftoff")
+	API = api
+	return nil
+}
@@ -0,0 +1,71 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oci
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdUpload = &cobra.Command{
+		Use:   "upload",
+		Short: "Upload an image to Object Storage",
+		Long: `Upload a qcow2 image to an Object Storage bucket, from which create-image can import it.
+
+After a successful run, the final line of output will be the name of the object.
+`,
+		Example: `  ore oci upload --bucket=fcos-images --file=fedora-coreos-oraclecloud.x86_64.qcow2`,
+		RunE:    runUpload,
+
+		SilenceUsage: true,
+	}
+
+	uploadFile   string
+	uploadObject string
+	uploadForce  bool
+)
+
+func init() {
+	OCI.AddCommand(cmdUpload)
+	cmdUpload.Flags().StringVar(&bucket, "bucket", "", "Object Storage bucket")
+	cmdUpload.Flags().StringVar(&uploadFile, "file", "", "path to the qcow2 image")
+	cmdUpload.Flags().StringVar(&uploadObject, "name", "", "object name (default the file's name)")
+	cmdUpload.Flags().BoolVar(&uploadForce, "force", false, "overwrite an existing object")
+}
+
+func runUpload(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		fmt.Fprintf(os.Stderr, "Unrecognized args in oci upload cmd: %v\n", args)
+		os.Exit(2)
+	}
+	if bucket == "" || uploadFile == "" {
+		fmt.Fprintf(os.Stderr, "--bucket and --file are required\n")
+		os.Exit(2)
+	}
+	if uploadObject == "" {
+		uploadObject = filepath.Base(uploadFile)
+	}
+
+	if err := API.UploadObject(uploadFile, bucket, uploadObject, uploadForce); err != nil {
+		fmt.Fprintf(os.Stderr, "Couldn't upload image: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(uploadObject)
+	return nil
+}
@@ -29,6 +29,10 @@ var (
 		Short: "Create image on OpenStack",
 		Long: `Upload an image to OpenStack.
 
+The qcow2 image given by --file is uploaded to Glance. Alternatively, or as
+a fallback if the upload fails, Glance can download it from --url with its
+web-download import method.
+
 After a successful run, the final line of output will be the ID of the image.
 `,
 		RunE: runCreate,
@@ -37,6 +41,7 @@ After a successful run, the final line of output will be the ID of the image.
 	}
 
 	path       string
+	url        string
 	name       string
 	arch       string
 	visibility string
@@ -47,17 +52,18 @@ func init() {
 	OpenStack.AddCommand(cmdCreate)
 	cmdCreate.Flags().StringVar(&arch, "arch", coreosarch.CurrentRpmArch(), "The architecture of the image")
 	cmdCreate.Flags().StringVar(&path, "file", "", "path to OpenStack image")
+	cmdCreate.Flags().StringVar(&url, "url", "", "URL Glance can download the OpenStack image from")
 	cmdCreate.Flags().StringVar(&name, "name", "", "image name")
 	cmdCreate.Flags().StringVar(&visibility, "visibility", "private", "Image visibility within OpenStack")
 	cmdCreate.Flags().BoolVar(&protected, "protected", false, "Image deletion protection")
 }
 
 func runCreate(cmd *cobra.Command, args []string) error {
-	if path == "" {
-		fmt.Fprintf(os.Stderr, "--file is required\n")
+	if path == "" && url == "" {
+		fmt.Fprintf(os.Stderr, "--file or --url is required\n")
 		os.Exit(1)
 	}
-	id, err := API.UploadImage(name, path, arch, visibility, protected)
+	id, err := API.UploadImage(name, path, url, arch, visibility, protected)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Couldn't create image: %v\n", err)
 		os.Exit(1)
@@ -0,0 +1,116 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package publish
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/coreos/coreos-assembler/mantle/platform/api/aws"
+)
+
+// publishAWS shares the AMI, and then copies it to the regions which don't
+// have an image of its name yet. The copies inherit its permissions, but
+// those copied before are shared too.
+func publishAWS(c *converger, img AWSImage) error {
+	apis := make(map[string]*aws.API)
+	api := func(region string) (*aws.API, error) {
+		if a, ok := apis[region]; ok {
+			return a, nil
+		}
+		opts := awsOptions
+		opts.Region = region
+		a, err := aws.New(&opts)
+		if err != nil {
+			return nil, fmt.Errorf("creating client for %s: %v", region, err)
+		}
+		apis[region] = a
+		return a, nil
+	}
+
+	source, err := api(img.Region)
+	if err != nil {
+		return err
+	}
+	image, err := source.DescribeImage(img.Image)
+	if err != nil {
+		return err
+	}
+	if err := shareAMI(c, source, img.Region, img.Image, img); err != nil {
+		return err
+	}
+
+	var copies []string
+	amis := make(map[string]string)
+	for _, region := range img.Regions {
+		if region == img.Region {
+			continue
+		}
+		a, err := api(region)
+		if err != nil {
+			return err
+		}
+		ami, err := a.FindImage(*image.Name)
+		if err != nil {
+			return fmt.Errorf("%s: %v", region, err)
+		}
+		if ami == "" {
+			copies = append(copies, region)
+		} else {
+			amis[region] = ami
+		}
+	}
+	if len(copies) > 0 {
+		err := c.change(func() error {
+			return source.CopyImage(img.Image, copies, func(string, aws.ImageData) {})
+		}, "copy %s to %s", img.Image, strings.Join(copies, ", "))
+		if err != nil {
+			return err
+		}
+	}
+	for _, region := range img.Regions {
+		ami, ok := amis[region]
+		if !ok {
+			continue
+		}
+		if err := shareAMI(c, apis[region], region, ami, img); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shareAMI grants the launch permissions the plan asks for which the AMI
+// lacks.
+func shareAMI(c *converger, api *aws.API, region, ami string, img AWSImage) error {
+	userIDs, public, err := api.ImageLaunchPermissions(ami)
+	if err != nil {
+		return fmt.Errorf("%s: %v", region, err)
+	}
+	if m := missing(img.LaunchPermissions, userIDs); len(m) > 0 {
+		err := c.change(func() error {
+			return api.ShareImage(ami, m)
+		}, "share %s in %s with %s", ami, region, strings.Join(m, ", "))
+		if err != nil {
+			return err
+		}
+	}
+	if img.Public && !public {
+		return c.change(func() error {
+			return api.PublishImage(ami)
+		}, "make %s in %s public", ami, region)
+	}
+	return nil
+}
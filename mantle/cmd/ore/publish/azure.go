@@ -0,0 +1,147 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package publish
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute"
+
+	"github.com/coreos/coreos-assembler/mantle/platform/api/azure"
+)
+
+// publishAzure creates the gallery image if it doesn't exist, replicates
+// it to the regions of the plan, and shares its gallery.
+func publishAzure(c *converger, img AzureImage) error {
+	opts := azureOptions
+	if img.Location != "" {
+		opts.Location = img.Location
+	}
+	api, err := azure.New(&opts)
+	if err != nil {
+		return fmt.Errorf("creating client: %v", err)
+	}
+	if err := api.SetupClients(); err != nil {
+		return fmt.Errorf("setting up clients: %v", err)
+	}
+
+	version, err := api.FindGalleryImageVersion(img.Image, img.Gallery, img.ResourceGroup, img.Version)
+	if err != nil {
+		return err
+	}
+	created := false
+	if version == nil {
+		if img.SourceImage == "" {
+			return fmt.Errorf("version %s of gallery image %s doesn't exist, and there's no source-image to create it from", img.Version, img.Image)
+		}
+		securityType, err := azure.ParseSecurityType(img.SecurityType)
+		if err != nil {
+			return err
+		}
+		err = c.change(func() error {
+			v, err := api.CreateGalleryImage(img.Image, img.Gallery, img.ResourceGroup, img.SourceImage, img.Architecture, securityType)
+			version = &v
+			return err
+		}, "create gallery image %s/%s from %s", img.Gallery, img.Image, img.SourceImage)
+		if err != nil {
+			return err
+		}
+		created = true
+	}
+
+	if len(img.Regions) > 0 && (version == nil || !replicatedTo(version, img.Regions)) {
+		var targets []azure.ReplicationTarget
+		var names []string
+		for _, r := range img.Regions {
+			targets = append(targets, azure.ReplicationTarget{
+				Region:             r.Name,
+				ReplicaCount:       r.Replicas,
+				StorageAccountType: r.StorageAccountType,
+			})
+			names = append(names, r.Name)
+		}
+		err := c.change(func() error {
+			_, err := api.ReplicateGalleryImageVersion(img.Image, img.Gallery, img.ResourceGroup, img.Version, targets, 0, img.Wait)
+			return err
+		}, "replicate %s/%s %s to %s", img.Gallery, img.Image, img.Version, strings.Join(names, ", "))
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(img.ShareSubscriptions) == 0 && len(img.ShareTenants) == 0 {
+		return nil
+	}
+	var sharing azure.GallerySharing
+	// A gallery a dry run would have created can't be looked up
+	if !(created && c.dryRun) {
+		sharing, err = api.GetGallerySharing(img.Gallery, img.ResourceGroup)
+		if err != nil {
+			return err
+		}
+	}
+	subscriptions := missing(img.ShareSubscriptions, sharing.Subscriptions)
+	tenants := missing(img.ShareTenants, sharing.Tenants)
+	if len(subscriptions) == 0 && len(tenants) == 0 {
+		return nil
+	}
+	return c.change(func() error {
+		return api.ShareGallery(img.Gallery, img.ResourceGroup, subscriptions, tenants)
+	}, "share gallery %s with %s", img.Gallery, strings.Join(append(subscriptions, tenants...), ", "))
+}
+
+// replicatedTo returns whether the gallery image version is replicated to
+// exactly the regions, besides the one it was created in, as
+// ReplicateGalleryImageVersion would replicate it.
+func replicatedTo(version *armcompute.GalleryImageVersion, regions []AzureRegion) bool {
+	if version.Properties == nil || version.Properties.PublishingProfile == nil {
+		return false
+	}
+	current := version.Properties.PublishingProfile.TargetRegions
+	for _, want := range regions {
+		found := false
+		for _, have := range current {
+			if have.Name == nil || !azure.SameRegion(want.Name, *have.Name) {
+				continue
+			}
+			found = true
+			if want.Replicas > 0 && (have.RegionalReplicaCount == nil || *have.RegionalReplicaCount != want.Replicas) {
+				return false
+			}
+			if want.StorageAccountType != "" && (have.StorageAccountType == nil || string(*have.StorageAccountType) != want.StorageAccountType) {
+				return false
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, have := range current {
+		if have.Name == nil || (version.Location != nil && azure.SameRegion(*have.Name, *version.Location)) {
+			continue
+		}
+		wanted := false
+		for _, want := range regions {
+			if azure.SameRegion(want.Name, *have.Name) {
+				wanted = true
+			}
+		}
+		if !wanted {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,173 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package publish
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"google.golang.org/api/compute/v1"
+
+	"github.com/coreos/coreos-assembler/mantle/platform/api/gcloud"
+)
+
+// publishGCP creates the image if it doesn't exist, puts it in its family,
+// makes it public and promotes it as the plan asks, and then deprecates
+// the other images.
+func publishGCP(c *converger, img GCPImage) error {
+	opts := gcpOptions
+	if img.Project != "" {
+		opts.Project = img.Project
+	}
+	api, err := gcloud.New(&opts)
+	if err != nil {
+		return fmt.Errorf("creating client: %v", err)
+	}
+
+	image, err := api.FindImage(img.Image)
+	if err != nil {
+		return err
+	}
+	if image == nil {
+		if img.Source == "" {
+			return fmt.Errorf("image doesn't exist, and there's no source to create it from")
+		}
+		err := c.change(func() error {
+			_, pending, err := api.CreateImage(&gcloud.ImageSpec{
+				Architecture: img.Architecture,
+				SourceImage:  img.Source,
+				Family:       img.Family,
+				Name:         img.Image,
+				Description:  img.Description,
+				Licenses:     img.Licenses,
+			}, false)
+			if err != nil {
+				return err
+			}
+			return pending.Wait()
+		}, "create image %s from %s", img.Image, img.Source)
+		if err != nil {
+			return err
+		}
+	} else if img.Family != "" && image.Family != img.Family {
+		description := image.Description
+		if img.Description != "" {
+			description = img.Description
+		}
+		err := c.change(func() error {
+			pending, err := api.UpdateImage(img.Image, img.Family, description)
+			if err != nil {
+				return err
+			}
+			return pending.Wait()
+		}, "move image %s to family %s", img.Image, img.Family)
+		if err != nil {
+			return err
+		}
+	}
+
+	if img.Public {
+		public := false
+		if image != nil {
+			public, err = api.IsImagePublic(img.Image)
+			if err != nil {
+				return err
+			}
+		}
+		if !public {
+			err := c.change(func() error {
+				return api.SetImagePublic(img.Image)
+			}, "make image %s public", img.Image)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if img.Promote {
+		promoted, err := isPromoted(api, img.Image, img.Family, image)
+		if err != nil {
+			return err
+		}
+		if !promoted {
+			err := c.change(func() error {
+				return api.PromoteImage(context.Background(), img.Image, img.Family)
+			}, "promote image %s in family %s", img.Image, img.Family)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, d := range img.Deprecate {
+		other, err := api.FindImage(d.Image)
+		if err != nil {
+			return err
+		}
+		if other == nil {
+			return fmt.Errorf("image %s to deprecate doesn't exist", d.Image)
+		}
+		if hasDeprecationState(other, d) {
+			continue
+		}
+		err = c.change(func() error {
+			pending, err := api.DeprecateImage(d.Image, gcloud.DeprecationState(d.State), d.Replacement)
+			if err != nil {
+				return err
+			}
+			return pending.Wait()
+		}, "set deprecation state of image %s to %s", d.Image, d.State)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isPromoted returns whether the image is the only active one in its
+// family, as PromoteImage leaves it. The image is nil if it doesn't exist
+// yet.
+func isPromoted(api *gcloud.API, name, family string, image *compute.Image) (bool, error) {
+	if image == nil || !isActive(image) {
+		return false, nil
+	}
+	images, err := api.ListImages(context.Background(), "", family)
+	if err != nil {
+		return false, err
+	}
+	for _, other := range images {
+		if other.Name != name && isActive(other) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// nolint (see the comment in PromoteImage on the Deprecated field)
+func isActive(image *compute.Image) bool {
+	return image.Deprecated == nil || image.Deprecated.State == string(gcloud.DeprecationStateActive)
+}
+
+// nolint (see the comment in PromoteImage on the Deprecated field)
+func hasDeprecationState(image *compute.Image, d GCPDeprecation) bool {
+	if d.State == string(gcloud.DeprecationStateActive) {
+		return isActive(image)
+	}
+	if image.Deprecated == nil || image.Deprecated.State != d.State {
+		return false
+	}
+	return d.Replacement == "" || path.Base(image.Deprecated.Replacement) == path.Base(d.Replacement)
+}
@@ -0,0 +1,203 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package publish
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/coreos/coreos-assembler/mantle/platform/api/aws"
+	"github.com/coreos/coreos-assembler/mantle/platform/api/azure"
+	"github.com/coreos/coreos-assembler/mantle/platform/api/gcloud"
+)
+
+// Plan describes where a build's images are published, and who they're
+// shared with. It's read from YAML, or JSON.
+type Plan struct {
+	AWS   []AWSImage   `yaml:"aws"`
+	Azure []AzureImage `yaml:"azure"`
+	GCP   []GCPImage   `yaml:"gcp"`
+}
+
+// AWSImage is an AMI and the regions of its partition it's copied to.
+// Partitions each need an AMI uploaded to them.
+type AWSImage struct {
+	// Region and Image are the AMI the copies are made from.
+	Region string `yaml:"region"`
+	Image  string `yaml:"image"`
+	// Regions are those the AMI is copied to.
+	Regions []string `yaml:"regions"`
+	// LaunchPermissions are the accounts the AMI and its copies are
+	// shared with.
+	LaunchPermissions []string `yaml:"launch-permissions"`
+	// Public makes the AMI and its copies launchable by anyone.
+	Public bool `yaml:"public"`
+}
+
+// AzureImage is a gallery image version, the regions it's replicated to,
+// and who its gallery is shared with.
+type AzureImage struct {
+	ResourceGroup string `yaml:"resource-group"`
+	Gallery       string `yaml:"gallery"`
+	Image         string `yaml:"image"`
+	// Version defaults to 1.0.0, the version images are created as.
+	Version string `yaml:"version"`
+	// Location is where the gallery image is created.
+	Location string `yaml:"location"`
+	// SourceImage is the ID of the managed image the gallery image is
+	// created from if it doesn't exist.
+	SourceImage  string `yaml:"source-image"`
+	Architecture string `yaml:"architecture"`
+	SecurityType string `yaml:"security-type"`
+	// Regions replace those the version is replicated to, except for the
+	// one it was created in.
+	Regions []AzureRegion `yaml:"regions"`
+	// Wait waits for replication to finish.
+	Wait bool `yaml:"wait"`
+	// ShareSubscriptions and ShareTenants are shared the gallery.
+	ShareSubscriptions []string `yaml:"share-subscriptions"`
+	ShareTenants       []string `yaml:"share-tenants"`
+}
+
+// AzureRegion is a region a gallery image version is replicated to.
+type AzureRegion struct {
+	Name string `yaml:"name"`
+	// Replicas and StorageAccountType default to those of the version.
+	Replicas           int32  `yaml:"replicas"`
+	StorageAccountType string `yaml:"storage-account-type"`
+}
+
+// GCPImage is an image, its family, and the images it deprecates.
+type GCPImage struct {
+	// Project defaults to that of --gcp-project.
+	Project string `yaml:"project"`
+	Image   string `yaml:"image"`
+	// Source is the gs:// URL of the tarball the image is created from
+	// if it doesn't exist.
+	Source       string   `yaml:"source"`
+	Family       string   `yaml:"family"`
+	Description  string   `yaml:"description"`
+	Architecture string   `yaml:"architecture"`
+	Licenses     []string `yaml:"licenses"`
+	// Public makes the image usable by all authenticated users.
+	Public bool `yaml:"public"`
+	// Promote makes the image the active one of its family, deprecating
+	// the family's other images.
+	Promote bool `yaml:"promote"`
+	// Deprecate sets the deprecation state of other images.
+	Deprecate []GCPDeprecation `yaml:"deprecate"`
+}
+
+// GCPDeprecation is the deprecation state of an image.
+type GCPDeprecation struct {
+	Image string `yaml:"image"`
+	// State is ACTIVE, DEPRECATED, OBSOLETE or DELETED.
+	State       string `yaml:"state"`
+	Replacement string `yaml:"replacement"`
+}
+
+// loadPlan reads and validates the plan at path.
+func loadPlan(path string) (*Plan, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var plan Plan
+	if err := yaml.UnmarshalStrict(buf, &plan); err != nil {
+		return nil, fmt.Errorf("parsing %s: %v", path, err)
+	}
+	if err := plan.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return &plan, nil
+}
+
+func (p *Plan) validate() error {
+	for i, img := range p.AWS {
+		if img.Region == "" || img.Image == "" {
+			return fmt.Errorf("aws[%d]: region and image are required", i)
+		}
+		partition, err := aws.PartitionForRegion(img.Region)
+		if err != nil {
+			return fmt.Errorf("aws[%d]: %v", i, err)
+		}
+		seen := make(map[string]bool)
+		for _, region := range img.Regions {
+			if seen[region] {
+				return fmt.Errorf("aws[%d]: region %s is listed twice", i, region)
+			}
+			seen[region] = true
+			other, err := aws.PartitionForRegion(region)
+			if err != nil {
+				return fmt.Errorf("aws[%d]: %v", i, err)
+			}
+			if other != partition {
+				return fmt.Errorf("aws[%d]: region %s isn't in the %s partition of %s; give it an image of its own", i, region, partition, img.Region)
+			}
+		}
+	}
+	for i := range p.Azure {
+		img := &p.Azure[i]
+		if img.ResourceGroup == "" || img.Gallery == "" || img.Image == "" {
+			return fmt.Errorf("azure[%d]: resource-group, gallery and image are required", i)
+		}
+		if img.Version == "" {
+			img.Version = "1.0.0"
+		}
+		if img.SourceImage != "" && img.Version != "1.0.0" {
+			return fmt.Errorf("azure[%d]: gallery images are created as version 1.0.0, not %s", i, img.Version)
+		}
+		if _, err := azure.ParseSecurityType(img.SecurityType); err != nil {
+			return fmt.Errorf("azure[%d]: %v", i, err)
+		}
+		seen := make(map[string]bool)
+		for _, r := range img.Regions {
+			if r.Name == "" || r.Replicas < 0 {
+				return fmt.Errorf("azure[%d]: invalid region %+v", i, r)
+			}
+			name := strings.ToLower(r.Name)
+			if seen[name] {
+				return fmt.Errorf("azure[%d]: region %s is listed twice", i, r.Name)
+			}
+			seen[name] = true
+			if r.StorageAccountType != "" {
+				if _, err := azure.ParseStorageAccountType(r.StorageAccountType); err != nil {
+					return fmt.Errorf("azure[%d]: %v", i, err)
+				}
+			}
+		}
+	}
+	for i, img := range p.GCP {
+		if img.Image == "" {
+			return fmt.Errorf("gcp[%d]: image is required", i)
+		}
+		if img.Promote && img.Family == "" {
+			return fmt.Errorf("gcp[%d]: promote requires a family", i)
+		}
+		for j := range img.Deprecate {
+			d := &p.GCP[i].Deprecate[j]
+			d.State = strings.ToUpper(d.State)
+			switch gcloud.DeprecationState(d.State) {
+			case gcloud.DeprecationStateActive, gcloud.DeprecationStateDeprecated, gcloud.DeprecationStateObsolete, gcloud.DeprecationStateDeleted:
+			default:
+				return fmt.Errorf("gcp[%d]: invalid deprecation state %q for %s", i, d.State, d.Image)
+			}
+		}
+	}
+	return nil
+}
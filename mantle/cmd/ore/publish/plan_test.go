@@ -0,0 +1,178 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package publish
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The example plan in docs/mantle/ore.md.
+const examplePlan = `
+aws:
+  - region: us-east-1
+    image: ami-0123456789abcdef0
+    regions: [us-east-2, us-west-2]
+    launch-permissions: ["123456789012"]
+    public: true
+azure:
+  - resource-group: images
+    gallery: fedoracoreos
+    image: fedora-coreos-40.20240416.3.1-x86_64
+    location: eastus
+    source-image: /subscriptions/.../images/fedora-coreos-40.20240416.3.1-x86_64
+    regions:
+      - name: westus2
+        replicas: 2
+        storage-account-type: Standard_ZRS
+    share-subscriptions: [00000000-0000-0000-0000-000000000000]
+gcp:
+  - project: fedora-coreos-cloud
+    image: fedora-coreos-40-20240416-3-1-gcp-x86-64
+    source: gs://fcos-builds/fedora-coreos-40.20240416.3.1-gcp.x86_64.tar.gz
+    family: fedora-coreos-stable
+    public: true
+    promote: true
+    deprecate:
+      - image: fedora-coreos-39-20240322-3-0-gcp-x86-64
+        state: obsolete
+`
+
+func writePlan(t *testing.T, plan string) string {
+	path := filepath.Join(t.TempDir(), "plan.yaml")
+	if err := os.WriteFile(path, []byte(plan), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPlan(t *testing.T) {
+	plan, err := loadPlan(writePlan(t, examplePlan))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.AWS) != 1 || len(plan.Azure) != 1 || len(plan.GCP) != 1 {
+		t.Fatalf("got plan %+v", plan)
+	}
+	if got := plan.AWS[0].Regions; len(got) != 2 || got[1] != "us-west-2" {
+		t.Errorf("got AWS regions %v", got)
+	}
+	// Defaults are filled in and states normalized.
+	if got := plan.Azure[0].Version; got != "1.0.0" {
+		t.Errorf("got Azure version %q, expected 1.0.0", got)
+	}
+	if got := plan.Azure[0].Regions[0]; got.Replicas != 2 || got.StorageAccountType != "Standard_ZRS" {
+		t.Errorf("got Azure region %+v", got)
+	}
+	if got := plan.GCP[0].Deprecate[0].State; got != "OBSOLETE" {
+		t.Errorf("got deprecation state %q, expected OBSOLETE", got)
+	}
+
+	// JSON is YAML too.
+	if _, err := loadPlan(writePlan(t, `{"gcp": [{"image": "fcos"}]}`)); err != nil {
+		t.Errorf("JSON plan: %v", err)
+	}
+}
+
+func TestLoadPlanErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		plan string
+		err  string
+	}{
+		{
+			name: "unknown field",
+			plan: "gcp:\n  - image: fcos\n    famliy: stable\n",
+			err:  "field famliy not found",
+		},
+		{
+			name: "aws missing image",
+			plan: "aws:\n  - region: us-east-1\n",
+			err:  "aws[0]: region and image are required",
+		},
+		{
+			name: "aws unknown region",
+			plan: "aws:\n  - region: mars-north-1\n    image: ami-1\n",
+			err:  `aws[0]: could not find the partition of region "mars-north-1"`,
+		},
+		{
+			name: "aws other partition",
+			plan: "aws:\n  - region: us-east-1\n    image: ami-1\n    regions: [cn-north-1]\n",
+			err:  "aws[0]: region cn-north-1 isn't in the aws partition of us-east-1",
+		},
+		{
+			name: "aws duplicate region",
+			plan: "aws:\n  - region: us-east-1\n    image: ami-1\n    regions: [us-east-2, us-west-2, us-east-2]\n",
+			err:  "aws[0]: region us-east-2 is listed twice",
+		},
+		{
+			name: "azure missing gallery",
+			plan: "azure:\n  - resource-group: images\n    image: fcos\n",
+			err:  "azure[0]: resource-group, gallery and image are required",
+		},
+		{
+			name: "azure created version",
+			plan: "azure:\n  - resource-group: images\n    gallery: g\n    image: fcos\n    version: 2.0.0\n    source-image: /images/fcos\n",
+			err:  "azure[0]: gallery images are created as version 1.0.0, not 2.0.0",
+		},
+		{
+			name: "azure security type",
+			plan: "azure:\n  - resource-group: images\n    gallery: g\n    image: fcos\n    security-type: Secure\n",
+			err:  `azure[0]: unknown security type "Secure"`,
+		},
+		{
+			name: "azure region without name",
+			plan: "azure:\n  - resource-group: images\n    gallery: g\n    image: fcos\n    regions:\n      - replicas: 1\n",
+			err:  "azure[0]: invalid region",
+		},
+		{
+			name: "azure duplicate region",
+			plan: "azure:\n  - resource-group: images\n    gallery: g\n    image: fcos\n    regions:\n      - name: westus2\n      - name: WestUS2\n",
+			err:  "azure[0]: region WestUS2 is listed twice",
+		},
+		{
+			name: "azure storage account type",
+			plan: "azure:\n  - resource-group: images\n    gallery: g\n    image: fcos\n    regions:\n      - name: westus2\n        storage-account-type: Floppy\n",
+			err:  `azure[0]: unsupported storage account type "Floppy"`,
+		},
+		{
+			name: "gcp missing image",
+			plan: "gcp:\n  - family: stable\n",
+			err:  "gcp[0]: image is required",
+		},
+		{
+			name: "gcp promote without family",
+			plan: "gcp:\n  - image: fcos\n    promote: true\n",
+			err:  "gcp[0]: promote requires a family",
+		},
+		{
+			name: "gcp deprecation state",
+			plan: "gcp:\n  - image: fcos\n  - image: fcos-2\n    deprecate:\n      - image: fcos-1\n        state: retired\n",
+			err:  `gcp[1]: invalid deprecation state "RETIRED" for fcos-1`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadPlan(writePlan(t, tt.plan))
+			if err == nil {
+				t.Fatalf("expected error containing %q", tt.err)
+			}
+			if !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("got error %q, expected it to contain %q", err, tt.err)
+			}
+		})
+	}
+}
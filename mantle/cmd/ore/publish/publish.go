@@ -0,0 +1,184 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package publish
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/coreos/pkg/capnslog"
+	"github.com/spf13/cobra"
+
+	"github.com/coreos/coreos-assembler/mantle/auth"
+	"github.com/coreos/coreos-assembler/mantle/platform"
+	"github.com/coreos/coreos-assembler/mantle/platform/api/aws"
+	"github.com/coreos/coreos-assembler/mantle/platform/api/azure"
+	"github.com/coreos/coreos-assembler/mantle/platform/api/gcloud"
+)
+
+var (
+	plog = capnslog.NewPackageLogger("github.com/coreos/coreos-assembler/mantle", "ore/publish")
+
+	Publish = &cobra.Command{
+		Use:   "publish --plan <file>",
+		Short: "Publish images according to a plan",
+		Long: `Converge AWS, Azure and GCP to a plan of where a build's images are
+published: the regions AMIs are copied to, the galleries and regions of
+Azure images, the families GCP images are promoted in, and who they're
+all shared with.
+
+Only the changes needed are made, so a plan can be applied again after a
+failure, or extended and reapplied. With --dry-run, the changes are
+printed without being made. Nothing is ever deleted or unshared.
+
+See docs/mantle/ore.md for the format of the plan.`,
+		RunE: runPublish,
+
+		SilenceUsage: true,
+	}
+
+	planPath       string
+	dryRun         bool
+	partitionFlags []string
+
+	awsOptions   = aws.Options{Options: &platform.Options{}}
+	azureOptions = azure.Options{Options: &platform.Options{}}
+	gcpOptions   = gcloud.Options{Options: &platform.Options{}}
+)
+
+func init() {
+	sv := Publish.Flags().StringVar
+	sv(&planPath, "plan", "", "YAML or JSON plan to converge to")
+	Publish.Flags().BoolVar(&dryRun, "dry-run", false, "print the changes needed without making them")
+	sv(&awsOptions.CredentialsFile, "aws-credentials-file", "", "AWS credentials file")
+	sv(&awsOptions.Profile, "aws-profile", "", "AWS profile name")
+	Publish.Flags().StringArrayVar(&partitionFlags, "aws-partition", nil, "settings for regions in a partition such as aws-us-gov or aws-cn, as PARTITION:KEY=VALUE[,KEY=VALUE...] with keys credentials-file, profile, access-id, secret-key and kms-key")
+	sv(&azureOptions.AzureCredentials, "azure-credentials", "", "Azure credentials file location (default \"~/"+auth.AzureCredentialsPath+"\")")
+	sv(&azureOptions.Publisher, "azure-publisher", "CoreOS", "Azure image publisher")
+	sv(&azureOptions.Location, "azure-location", "westus", "Azure location gallery images are created in, unless the plan gives one")
+	sv(&gcpOptions.Project, "gcp-project", "fedora-coreos-cloud", "GCP project, unless the plan gives one")
+	sv(&gcpOptions.JSONKeyFile, "gcp-json-key", "", "use a GCP service account's JSON key for authentication")
+	Publish.Flags().BoolVar(&gcpOptions.ServiceAuth, "gcp-service-auth", false, "use non-interactive GCP auth when running within GCP")
+}
+
+// change is a change made, or with --dry-run to be made, to converge a
+// platform to the plan.
+type change struct {
+	platform    string
+	description string
+}
+
+// converger records the changes made to a platform.
+type converger struct {
+	platform string
+	dryRun   bool
+	changes  *[]change
+}
+
+// change makes the change described by calling fn, unless this is a dry
+// run.
+func (c *converger) change(fn func() error, format string, args ...interface{}) error {
+	description := fmt.Sprintf(format, args...)
+	if !c.dryRun {
+		plog.Noticef("%s: %s", c.platform, description)
+		if err := fn(); err != nil {
+			return fmt.Errorf("couldn't %s: %v", description, err)
+		}
+	}
+	*c.changes = append(*c.changes, change{platform: c.platform, description: description})
+	return nil
+}
+
+func runPublish(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		fmt.Fprintf(os.Stderr, "Unrecognized args in publish cmd: %v\n", args)
+		os.Exit(2)
+	}
+	if planPath == "" {
+		fmt.Fprintf(os.Stderr, "--plan is required\n")
+		os.Exit(2)
+	}
+	awsOptions.Partitions = make(map[string]aws.PartitionOptions)
+	for _, s := range partitionFlags {
+		id, opts, err := aws.ParsePartitionOptions(s)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(2)
+		}
+		awsOptions.Partitions[id] = opts
+	}
+	plan, err := loadPlan(planPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Couldn't load plan: %v\n", err)
+		os.Exit(2)
+	}
+
+	var changes []change
+	converge := func(platform, image string, fn func(*converger) error) error {
+		c := &converger{platform: platform, dryRun: dryRun, changes: &changes}
+		if err := fn(c); err != nil {
+			return fmt.Errorf("%s: %s: %v", platform, image, err)
+		}
+		return nil
+	}
+	for _, img := range plan.AWS {
+		err = converge("aws", img.Image, func(c *converger) error { return publishAWS(c, img) })
+		if err != nil {
+			break
+		}
+	}
+	for _, img := range plan.Azure {
+		if err != nil {
+			break
+		}
+		err = converge("azure", img.Image, func(c *converger) error { return publishAzure(c, img) })
+	}
+	for _, img := range plan.GCP {
+		if err != nil {
+			break
+		}
+		err = converge("gcp", img.Image, func(c *converger) error { return publishGCP(c, img) })
+	}
+
+	verb := "Made"
+	if dryRun {
+		verb = "Would make"
+	}
+	fmt.Printf("%s %d changes\n", verb, len(changes))
+	for _, c := range changes {
+		fmt.Printf("  %s: %s\n", c.platform, c.description)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Couldn't converge to plan: %v\n", err)
+		os.Exit(1)
+	}
+	return nil
+}
+
+// missing returns the elements of want which aren't in have.
+func missing(want, have []string) []string {
+	seen := make(map[string]bool, len(have))
+	for _, s := range have {
+		seen[s] = true
+	}
+	var m []string
+	for _, s := range want {
+		if !seen[s] {
+			m = append(m, s)
+			seen[s] = true
+		}
+	}
+	return m
+}
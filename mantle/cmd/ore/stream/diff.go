@@ -0,0 +1,90 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/coreos/stream-metadata-go/fedoracoreos"
+	metadata "github.com/coreos/stream-metadata-go/stream"
+	"github.com/spf13/cobra"
+
+	"github.com/coreos/coreos-assembler/mantle/streammeta"
+)
+
+var (
+	cmdDiff = &cobra.Command{
+		Use:   "diff [--published URL] STREAM.JSON",
+		Short: "Compare stream metadata with the published stream",
+		Long: `Print the changes from the published stream to the stream metadata,
+given as a path or URL, ignoring the metadata section. By default, the
+published stream is the Fedora CoreOS stream of the same name.`,
+		RunE: runDiff,
+
+		SilenceUsage: true,
+	}
+
+	published string
+)
+
+func init() {
+	Stream.AddCommand(cmdDiff)
+	cmdDiff.Flags().StringVar(&published, "published", "", "path or URL of the published stream")
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected one stream metadata file")
+	}
+	data, err := streammeta.ReadSource(args[0])
+	if err != nil {
+		return err
+	}
+	var st metadata.Stream
+	if err := json.Unmarshal(data, &st); err != nil {
+		return fmt.Errorf("parsing %s: %v", args[0], err)
+	}
+
+	source := published
+	if source == "" {
+		u := fedoracoreos.GetStreamURL(st.Stream)
+		source = u.String()
+	}
+	data, err = streammeta.ReadSource(source)
+	if err != nil {
+		return err
+	}
+	// The published stream may have fields newer than those known here,
+	// so unlike with validate, they're ignored
+	var old metadata.Stream
+	if err := json.Unmarshal(data, &old); err != nil {
+		return fmt.Errorf("parsing published stream %s: %v", source, err)
+	}
+
+	changes, err := streammeta.Diff(&old, &st)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		fmt.Printf("No changes from %s\n", source)
+		return nil
+	}
+	fmt.Printf("%d changes from %s\n", len(changes), source)
+	for _, c := range changes {
+		fmt.Println(c)
+	}
+	return nil
+}
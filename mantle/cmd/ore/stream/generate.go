@@ -0,0 +1,129 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/coreos/stream-metadata-go/release"
+	metadata "github.com/coreos/stream-metadata-go/stream"
+	"github.com/spf13/cobra"
+
+	"github.com/coreos/coreos-assembler/mantle/streammeta"
+	"github.com/coreos/coreos-assembler/mantle/version"
+	cosa "github.com/coreos/coreos-assembler/pkg/builds"
+)
+
+var (
+	cmdGenerate = &cobra.Command{
+		Use:   "generate [options] META.JSON...",
+		Short: "Generate stream metadata from coreos-assembler builds",
+		Long: `Generate stream metadata from the meta.json of builds, given as paths
+or URLs. Builds with the same ID are the architectures of a release; the
+stream has the architectures of all the releases.
+
+With --target, the stream in the file is updated in place with the
+builds' architectures; otherwise, a new stream is printed.`,
+		RunE: runGenerate,
+
+		SilenceUsage: true,
+	}
+
+	generateOpts  streammeta.ReleaseOptions
+	generateName  string
+	target        string
+	releaseOutput string
+)
+
+func init() {
+	Stream.AddCommand(cmdGenerate)
+	cmdGenerate.Flags().StringVar(&generateName, "name", "", "stream name (default is the branch of the builds' config)")
+	cmdGenerate.Flags().StringVar(&generateOpts.BaseURL, "url", streammeta.DefaultBaseURL, "prefix of artifact URLs")
+	cmdGenerate.Flags().BoolVar(&generateOpts.NoSignatures, "no-signatures", false, "omit signatures, e.g. for pre-release stream metadata")
+	cmdGenerate.Flags().StringVar(&target, "target", "", "update the stream in this file in place")
+	cmdGenerate.Flags().StringVar(&releaseOutput, "release-output", "", "also write the release of a single build ID, as release.json, to this file")
+}
+
+func runGenerate(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("no builds given")
+	}
+	generateOpts.Stream = generateName
+
+	var releases []*release.Release
+	byID := make(map[string]*release.Release)
+	for _, arg := range args {
+		data, err := streammeta.ReadSource(arg)
+		if err != nil {
+			return err
+		}
+		var build cosa.Build
+		if err := json.Unmarshal(data, &build); err != nil {
+			return fmt.Errorf("parsing %s: %v", arg, err)
+		}
+		rel, ok := byID[build.BuildID]
+		if !ok {
+			rel = &release.Release{}
+			byID[build.BuildID] = rel
+			releases = append(releases, rel)
+		}
+		if err := streammeta.AddBuild(rel, &build, generateOpts); err != nil {
+			return fmt.Errorf("%s: %v", arg, err)
+		}
+		plog.Infof("Added build %s for %s", build.BuildID, build.Architecture)
+	}
+
+	if releaseOutput != "" {
+		if len(releases) != 1 {
+			return fmt.Errorf("--release-output needs builds with a single ID, not %d", len(releases))
+		}
+		if err := writeJSON(releaseOutput, releases[0]); err != nil {
+			return err
+		}
+	}
+
+	var base *metadata.Stream
+	if target != "" {
+		data, err := os.ReadFile(target)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, &base); err != nil {
+			return fmt.Errorf("parsing %s: %v", target, err)
+		}
+	}
+	name := generateName
+	if name == "" {
+		name = releases[0].Stream
+	}
+	st, err := streammeta.Stream(name, base, releases, "ore stream generate "+version.Version)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	if _, errs := streammeta.Validate(data); len(errs) > 0 {
+		for _, err := range errs {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+		}
+		return fmt.Errorf("generated stream metadata is invalid")
+	}
+	return writeJSON(target, st)
+}
@@ -0,0 +1,64 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stream
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/coreos/coreos-assembler/mantle/streammeta"
+)
+
+var (
+	cmdValidate = &cobra.Command{
+		Use:   "validate STREAM.JSON...",
+		Short: "Validate stream metadata",
+		Long: `Check that stream metadata, given as paths or URLs, matches the
+stream-metadata-go schema and is complete.`,
+		RunE: runValidate,
+
+		SilenceUsage: true,
+	}
+)
+
+func init() {
+	Stream.AddCommand(cmdValidate)
+}
+
+func runValidate(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("no stream metadata given")
+	}
+	invalid := 0
+	for _, arg := range args {
+		data, err := streammeta.ReadSource(arg)
+		if err != nil {
+			return err
+		}
+		_, errs := streammeta.Validate(data)
+		for _, err := range errs {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", arg, err)
+		}
+		if len(errs) > 0 {
+			invalid++
+		}
+	}
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d stream metadata files are invalid", invalid, len(args))
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vultr
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdCreateImage = &cobra.Command{
+		Use:   "create-image",
+		Short: "Create an image",
+		Long: `Create a snapshot from a raw disk image at a publicly readable URL.
+
+After a successful run, the final line of output will be the ID of the
+snapshot.
+`,
+		RunE: runCreateImage,
+
+		SilenceUsage: true,
+	}
+
+	createImageURL  string
+	createImageName string
+)
+
+func init() {
+	Vultr.AddCommand(cmdCreateImage)
+	cmdCreateImage.Flags().StringVar(&createImageURL, "url", "", "URL of the raw disk image")
+	cmdCreateImage.Flags().StringVar(&createImageName, "name", "", "snapshot description")
+}
+
+func runCreateImage(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		fmt.Fprintf(os.Stderr, "Unrecognized args in vultr create-image cmd: %v\n", args)
+		os.Exit(2)
+	}
+	if createImageURL == "" {
+		fmt.Fprintf(os.Stderr, "--url is required\n")
+		os.Exit(2)
+	}
+
+	snapshot, err := API.CreateSnapshotFromURL(createImageURL, createImageName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Couldn't create image: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(snapshot.ID)
+	return nil
+}
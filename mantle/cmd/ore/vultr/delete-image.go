@@ -0,0 +1,57 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vultr
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdDeleteImage = &cobra.Command{
+		Use:   "delete-image",
+		Short: "Delete an image",
+		Long:  `Delete a snapshot.`,
+		RunE:  runDeleteImage,
+
+		SilenceUsage: true,
+	}
+
+	deleteImageID string
+)
+
+func init() {
+	Vultr.AddCommand(cmdDeleteImage)
+	cmdDeleteImage.Flags().StringVar(&deleteImageID, "image", "", "snapshot ID")
+}
+
+func runDeleteImage(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		fmt.Fprintf(os.Stderr, "Unrecognized args in vultr delete-image cmd: %v\n", args)
+		os.Exit(2)
+	}
+	if deleteImageID == "" {
+		fmt.Fprintf(os.Stderr, "--image is required\n")
+		os.Exit(2)
+	}
+
+	if err := API.DeleteSnapshot(deleteImageID); err != nil {
+		fmt.Fprintf(os.Stderr, "Couldn't delete image: %v\n", err)
+		os.Exit(1)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vultr
+
+import (
+	"fmt"
+
+	"github.com/coreos/pkg/capnslog"
+	"github.com/spf13/cobra"
+
+	"github.com/coreos/coreos-assembler/mantle/cli"
+	"github.com/coreos/coreos-assembler/mantle/platform/api/vultr"
+)
+
+var (
+	plog = capnslog.NewPackageLogger("github.com/coreos/coreos-assembler/mantle", "ore/vultr")
+
+	Vultr = &cobra.Command{
+		Use:   "vultr [command]",
+		Short: "Vultr image utilities",
+	}
+
+	API     *vultr.API
+	options vultr.Options
+)
+
+func init() {
+	Vultr.PersistentFlags().StringVar(&options.Token, "token", "", "API key (default $VULTR_API_KEY)")
+	cli.WrapPreRun(Vultr, preflightCheck)
+}
+
+func preflightCheck(cmd *cobra.Command, args []string) error {
+	plog.Debugf("Running Vultr preflight check")
+	api, err := vultr.New(&options)
+	if err != nil {
+		return fmt.Errorf("could not create Vultr client: %v", err)
+	}
+	if err := api.PreflightCheck(); err != nil {
+		return fmt.Errorf("could not complete Vultr preflight check: %v", err)
+	}
+
+	plog.Debugf("Preflight check success; we have liftoff")
+	API = api
+	return nil
+}
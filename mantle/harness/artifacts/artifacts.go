@@ -0,0 +1,256 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package artifacts decides which of the files produced by tests are kept
+// in the output directory once the tests finish.
+//
+// Tests register what they produce with a class, and when a test finishes
+// its artifacts are kept or removed according to the policy for their class
+// and whether the test failed. A size cap can also bound the total size of
+// the artifacts kept across a run.
+package artifacts
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Class is a kind of artifact, which determines its retention policy.
+type Class string
+
+const (
+	// Disk is a disk or ISO image.
+	Disk Class = "disk"
+	// Log is a console log, journal or similar.
+	Log Class = "log"
+	// Bundle is a collection of diagnostics, such as a failure bundle
+	// or sos report.
+	Bundle Class = "bundle"
+	// Dump is a kernel crash dump.
+	Dump Class = "dump"
+)
+
+// Classes are the known classes, for validating options.
+var Classes = []Class{Disk, Log, Bundle, Dump}
+
+// Policy says when the artifacts of a class are kept.
+type Policy int
+
+const (
+	// Always keeps the artifacts.
+	Always Policy = iota
+	// OnFailure keeps the artifacts only if the test failed.
+	OnFailure
+	// Never removes the artifacts.
+	Never
+)
+
+var policyNames = map[Policy]string{
+	Always:    "always",
+	OnFailure: "on-failure",
+	Never:     "never",
+}
+
+func (p Policy) String() string {
+	if name, ok := policyNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("Policy(%d)", int(p))
+}
+
+// ParsePolicy parses "always", "on-failure" or "never".
+func ParsePolicy(s string) (Policy, error) {
+	for p, name := range policyNames {
+		if s == name {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown retention policy %q; expected always, on-failure or never", s)
+}
+
+// Retention governs which artifacts are kept.
+type Retention struct {
+	// Policies are the policies by class; classes without one are
+	// always kept.
+	Policies map[Class]Policy
+	// MaxBytes, if nonzero, caps the total size of the artifacts kept.
+	// Artifacts which would take the total over it are removed, even
+	// if their policy would keep them.
+	MaxBytes int64
+}
+
+// ParseRetention parses policies of the form CLASS=POLICY, such as
+// "disk=on-failure".
+func ParseRetention(policies []string) (Retention, error) {
+	r := Retention{Policies: make(map[Class]Policy)}
+	for _, s := range policies {
+		class, policy, ok := strings.Cut(s, "=")
+		if !ok {
+			return r, fmt.Errorf("invalid retention policy %q; expected CLASS=POLICY", s)
+		}
+		if !knownClass(Class(class)) {
+			return r, fmt.Errorf("unknown artifact class %q in %q", class, s)
+		}
+		p, err := ParsePolicy(policy)
+		if err != nil {
+			return r, err
+		}
+		r.Policies[Class(class)] = p
+	}
+	return r, nil
+}
+
+func knownClass(class Class) bool {
+	for _, c := range Classes {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseSize parses a size in bytes with an optional binary suffix, K, M,
+// G or T, such as "50G".
+func ParseSize(s string) (int64, error) {
+	num := strings.TrimSuffix(strings.ToUpper(s), "B")
+	shift := 0
+	if i := strings.IndexAny(num, "KMGT"); i >= 0 && i == len(num)-1 {
+		shift = 10 * (strings.IndexByte("KMGT", num[i]) + 1)
+		num = num[:i]
+	}
+	n, err := strconv.ParseInt(num, 10, 64)
+	if err != nil || n < 0 || n > (1<<62)>>shift {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n << shift, nil
+}
+
+// Removal records an artifact which was removed, and why.
+type Removal struct {
+	Path   string
+	Class  Class
+	Size   int64
+	Reason string
+	// Capped is set if the artifact was removed only to stay under the
+	// size cap.
+	Capped bool
+}
+
+type artifact struct {
+	path  string
+	class Class
+}
+
+// Manager tracks the artifacts of a run's tests. It's safe for concurrent
+// use.
+type Manager struct {
+	retention Retention
+
+	mu       sync.Mutex
+	pending  map[string][]artifact // by test name
+	retained int64
+}
+
+// NewManager returns a Manager which applies r.
+func NewManager(r Retention) *Manager {
+	return &Manager{
+		retention: r,
+		pending:   make(map[string][]artifact),
+	}
+}
+
+// Register records that test produced the file or directory at path. It
+// needn't exist yet; if it doesn't exist when the test finishes, it's
+// ignored.
+func (m *Manager) Register(test, path string, class Class) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pending[test] = append(m.pending[test], artifact{path: path, class: class})
+}
+
+// Finish applies the retention policy to the artifacts of test, in the
+// order they were registered, and returns those it removed.
+func (m *Manager) Finish(test string, failed bool) ([]Removal, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	artifacts := m.pending[test]
+	delete(m.pending, test)
+
+	var removed []Removal
+	var firstErr error
+	for _, a := range artifacts {
+		size, err := diskUsage(a.path)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		var reason string
+		capped := false
+		switch m.retention.Policies[a.class] {
+		case Never:
+			reason = "never retained"
+		case OnFailure:
+			if !failed {
+				reason = "test passed"
+			}
+		}
+		if reason == "" && m.retention.MaxBytes > 0 && m.retained+size > m.retention.MaxBytes {
+			reason = fmt.Sprintf("over the %d byte cap", m.retention.MaxBytes)
+			capped = true
+		}
+		if reason == "" {
+			m.retained += size
+			continue
+		}
+
+		if err := os.RemoveAll(a.path); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		removed = append(removed, Removal{Path: a.path, Class: a.class, Size: size, Reason: reason, Capped: capped})
+	}
+	return removed, firstErr
+}
+
+// diskUsage returns the size of the file, or of the files under the
+// directory, at path.
+func diskUsage(path string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
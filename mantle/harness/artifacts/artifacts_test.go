@@ -0,0 +1,158 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package artifacts
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseRetention(t *testing.T) {
+	r, err := ParseRetention([]string{"disk=on-failure", "log=always", "dump=never"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := map[Class]Policy{Disk: OnFailure, Log: Always, Dump: Never}
+	if !reflect.DeepEqual(r.Policies, expect) {
+		t.Errorf("%v != %v", r.Policies, expect)
+	}
+
+	for _, bad := range []string{"disk", "disk=sometimes", "core=never"} {
+		if _, err := ParseRetention([]string{bad}); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	for s, expect := range map[string]int64{
+		"0":     0,
+		"512":   512,
+		"512B":  512,
+		"4k":    4 << 10,
+		"50G":   50 << 30,
+		"50GB":  50 << 30,
+		"2T":    2 << 40,
+		"100MB": 100 << 20,
+	} {
+		if n, err := ParseSize(s); err != nil {
+			t.Errorf("%q: %v", s, err)
+		} else if n != expect {
+			t.Errorf("%q: expected %d, got %d", s, expect, n)
+		}
+	}
+	for _, bad := range []string{"", "G", "-1", "1.5G", "10P", "G10"} {
+		if _, err := ParseSize(bad); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
+
+func writeArtifact(t *testing.T, path string, size int) {
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func TestFinish(t *testing.T) {
+	dir := t.TempDir()
+	disk := filepath.Join(dir, "disk.img")
+	log := filepath.Join(dir, "console.txt")
+	dump := filepath.Join(dir, "kdump")
+	writeArtifact(t, disk, 100)
+	writeArtifact(t, log, 10)
+	writeArtifact(t, filepath.Join(dump, "vmcore"), 50)
+
+	m := NewManager(Retention{Policies: map[Class]Policy{Disk: OnFailure, Dump: Never}})
+	m.Register("test", disk, Disk)
+	m.Register("test", log, Log)
+	m.Register("test", dump, Dump)
+	m.Register("test", filepath.Join(dir, "missing"), Bundle)
+	removed, err := m.Finish("test", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []Removal{
+		{Path: disk, Class: Disk, Size: 100, Reason: "test passed"},
+		{Path: dump, Class: Dump, Size: 50, Reason: "never retained"},
+	}
+	if !reflect.DeepEqual(removed, expect) {
+		t.Errorf("%+v != %+v", removed, expect)
+	}
+	if exists(disk) || exists(dump) || !exists(log) {
+		t.Error("wrong artifacts removed")
+	}
+
+	// Finishing again is a no-op
+	if removed, err := m.Finish("test", false); err != nil || len(removed) != 0 {
+		t.Errorf("expected nothing removed, got %v, %v", removed, err)
+	}
+}
+
+func TestFinishFailed(t *testing.T) {
+	dir := t.TempDir()
+	disk := filepath.Join(dir, "disk.img")
+	writeArtifact(t, disk, 100)
+
+	m := NewManager(Retention{Policies: map[Class]Policy{Disk: OnFailure}})
+	m.Register("test", disk, Disk)
+	if removed, err := m.Finish("test", true); err != nil || len(removed) != 0 {
+		t.Errorf("expected nothing removed, got %v, %v", removed, err)
+	}
+	if !exists(disk) {
+		t.Error("artifact of failed test removed")
+	}
+}
+
+func TestFinishCapped(t *testing.T) {
+	dir := t.TempDir()
+	m := NewManager(Retention{MaxBytes: 150})
+	var paths []string
+	for _, test := range []string{"a", "b", "c"} {
+		p := filepath.Join(dir, test, "disk.img")
+		writeArtifact(t, p, 60)
+		m.Register(test, p, Disk)
+		paths = append(paths, p)
+	}
+
+	for i, test := range []string{"a", "b", "c"} {
+		removed, err := m.Finish(test, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i < 2 {
+			if len(removed) != 0 {
+				t.Errorf("%s: unexpected removals %+v", test, removed)
+			}
+			continue
+		}
+		if len(removed) != 1 || !removed[0].Capped || removed[0].Path != paths[i] {
+			t.Errorf("%s: expected %s to be removed for the cap, got %+v", test, paths[i], removed)
+		}
+	}
+	if !exists(paths[0]) || !exists(paths[1]) || exists(paths[2]) {
+		t.Error("wrong artifacts removed")
+	}
+}
@@ -29,6 +29,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/coreos/coreos-assembler/mantle/harness/artifacts"
 	"github.com/coreos/coreos-assembler/mantle/harness/reporters"
 	"github.com/coreos/coreos-assembler/mantle/harness/testresult"
 )
@@ -69,7 +70,7 @@ type H struct {
 	start    time.Time // Time test started
 	duration time.Duration
 	released bool      // Indicates whether the test has already released its parallel slot
-	barrier  chan bool // To signal parallel subtests they may start.
+	ready    chan bool // To signal a parallel test it may start.
 	signal   chan bool // To signal a test is done.
 	sub      []*H      // Queue of subtests to be run in parallel.
 	subtests []string  // All subtests of this test
@@ -475,6 +476,37 @@ func (h *H) TempFile(prefix string) *os.File {
 	return tmp
 }
 
+// RegisterArtifact records that the test produced the file or directory
+// at path, relative to OutputDir if it isn't absolute, so it's subject to
+// the suite's retention policy for class when the test finishes.
+func (h *H) RegisterArtifact(path string, class artifacts.Class) {
+	if h.suite.opts.Artifacts == nil {
+		return
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(h.suite.outputPath(h.name), path)
+	}
+	h.suite.opts.Artifacts.Register(h.name, path, class)
+}
+
+// applyRetention removes the test's artifacts which the retention policy
+// doesn't keep.
+func (t *H) applyRetention() {
+	if t.suite.opts.Artifacts == nil {
+		return
+	}
+	removed, err := t.suite.opts.Artifacts.Finish(t.name, t.Failed())
+	for _, r := range removed {
+		// Only worth mentioning if the policy would have kept it
+		if r.Capped {
+			t.log(fmt.Sprintf("Removed %s artifact %s (%d bytes): %s", r.Class, r.Path, r.Size, r.Reason))
+		}
+	}
+	if err != nil {
+		t.log(fmt.Sprintf("Failed to apply artifact retention policy: %v", err))
+	}
+}
+
 // Parallel signals that this test is to be run in parallel with (and only with)
 // other parallel tests.
 func (t *H) Parallel() {
@@ -491,9 +523,8 @@ func (t *H) Parallel() {
 	// Add to the list of tests to be released by the parent.
 	t.parent.sub = append(t.parent.sub, t)
 
-	t.signal <- true   // Release calling test.
-	<-t.parent.barrier // Wait for the parent test to complete.
-	t.suite.waitParallel()
+	t.signal <- true // Release calling test.
+	<-t.ready        // Wait for the parent test to complete and hand us a slot.
 	t.start = time.Now()
 }
 
@@ -529,8 +560,13 @@ func tRunner(t *H, fn func(t *H)) {
 			// Run parallel subtests.
 			// Decrease the running count for this test.
 			t.Release()
-			// Release the parallel subtests.
-			close(t.barrier)
+			// Release the parallel subtests in the order they were queued,
+			// acquiring a slot for each in turn, so that tests which were
+			// started first also get to run first.
+			for _, sub := range t.sub {
+				t.suite.waitParallel()
+				sub.ready <- true
+			}
 			// Wait for subtests to complete.
 			for _, sub := range t.sub {
 				<-sub.signal
@@ -544,6 +580,7 @@ func tRunner(t *H, fn func(t *H)) {
 			// test. See comment in Run method.
 			t.Release()
 		}
+		t.applyRetention()
 		t.report() // Report after all subtests have finished.
 
 		// Do not lock t.done to allow race detector to detect race in case
@@ -578,7 +615,7 @@ func (t *H) RunTimeout(name string, f func(t *H), timeout time.Duration) bool {
 	}
 
 	t = &H{
-		barrier:   make(chan bool),
+		ready:     make(chan bool),
 		signal:    make(chan bool),
 		name:      testName,
 		suite:     t.suite,
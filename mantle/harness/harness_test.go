@@ -27,6 +27,8 @@ import (
 	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/coreos/coreos-assembler/mantle/harness/artifacts"
 )
 
 func TestMain(m *testing.M) {
@@ -490,3 +492,46 @@ func TestTempFile(t *testing.T) {
 		t.Errorf("%q missing %q prefix", second, "second")
 	}
 }
+
+func TestArtifactRetention(t *testing.T) {
+	suitedir := t.TempDir()
+
+	write := func(h *H, name string) {
+		if err := os.WriteFile(filepath.Join(h.OutputDir(), name), []byte("data"), 0644); err != nil {
+			h.Fatal(err)
+		}
+		h.RegisterArtifact(name, artifacts.Disk)
+	}
+	opts := Options{
+		OutputDir: suitedir,
+		Artifacts: artifacts.NewManager(artifacts.Retention{
+			Policies: map[artifacts.Class]artifacts.Policy{artifacts.Disk: artifacts.OnFailure},
+		}),
+	}
+	suite := NewSuite(opts, Tests{
+		"Pass": &HarnessTest{
+			run:     func(h *H) { write(h, "disk.img") },
+			timeout: DefaultTimeoutFlag,
+		},
+		"Fail": &HarnessTest{
+			run: func(h *H) {
+				write(h, "disk.img")
+				h.Fail()
+			},
+			timeout: DefaultTimeoutFlag,
+		},
+	})
+
+	buf := &bytes.Buffer{}
+	if err := suite.runTests(buf, nil); err != SuiteFailed {
+		t.Log("\n" + buf.String())
+		t.Fatalf("expected %v, got %v", SuiteFailed, err)
+	}
+
+	if _, err := os.Stat(filepath.Join(suitedir, "Pass", "disk.img")); !os.IsNotExist(err) {
+		t.Errorf("artifact of passing test wasn't removed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(suitedir, "Fail", "disk.img")); err != nil {
+		t.Errorf("artifact of failing test wasn't kept: %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reporters
+
+import (
+	"path/filepath"
+	"sort"
+)
+
+// artifactPatterns are the machine logs worth linking from a report,
+// relative to a test's output directory. kola keeps them in a
+// subdirectory per machine, testiso directly in the test's directory.
+// External tests can also attach files of their own to artifacts.
+var artifactPatterns = []string{
+	"console.txt",
+	"journal.txt",
+	"*/console.txt",
+	"*/journal.txt",
+	"artifacts/*",
+}
+
+// testArtifacts returns the absolute paths of the machine logs of a test,
+// given the directory the reports are being written to, which is a
+// sibling of the tests' output directories.
+func testArtifacts(reportDir, name string) []string {
+	testDir := filepath.Join(filepath.Dir(reportDir), name)
+	var ret []string
+	for _, pattern := range artifactPatterns {
+		matches, err := filepath.Glob(filepath.Join(testDir, pattern))
+		if err != nil {
+			continue
+		}
+		for _, match := range matches {
+			if abs, err := filepath.Abs(match); err == nil {
+				match = abs
+			}
+			ret = append(ret, match)
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
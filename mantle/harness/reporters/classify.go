@@ -0,0 +1,154 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reporters
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/coreos/coreos-assembler/mantle/harness/testresult"
+)
+
+// FailureClass is the analyzer's guess at whose fault a test failure was,
+// so that retry policies and dashboards can treat infrastructure flakes
+// differently from real regressions.
+type FailureClass string
+
+const (
+	// ClassInfrastructure is a failure of the environment the test ran in,
+	// like SSH timing out or a cloud running out of quota.
+	ClassInfrastructure FailureClass = "infrastructure"
+	// ClassProduct is a failure of the OS under test, like a failed unit
+	// or a kernel panic.
+	ClassProduct FailureClass = "product"
+	// ClassTest is a failed assertion with nothing wrong with the
+	// machines, which suggests a bug in the test itself.
+	ClassTest FailureClass = "test"
+)
+
+// Classification is why a test is believed to have failed. Evidence is the
+// line the reason was recognized from and Source the log it was found in,
+// either "output" or the path of a machine log.
+type Classification struct {
+	Class    FailureClass `json:"class"`
+	Reason   string       `json:"reason"`
+	Evidence string       `json:"evidence,omitempty"`
+	Source   string       `json:"source,omitempty"`
+}
+
+type failurePattern struct {
+	re     *regexp.Regexp
+	reason string
+}
+
+// productPatterns recognize the OS misbehaving, in either a test's output
+// or its machines' consoles. They're checked before infrastructurePatterns,
+// since e.g. a kernel panic also makes SSH time out.
+var productPatterns = []failurePattern{
+	{regexp.MustCompile(`Kernel panic - not syncing.*`), "kernel panic"},
+	{regexp.MustCompile(`Oops:.*|BUG: unable to handle.*`), "kernel oops"},
+	{regexp.MustCompile(`Ignition failed.*|Failed to start .*Ignition.*|ignition-[a-z-]+\.service: Failed.*`), "Ignition failure"},
+	{regexp.MustCompile(`dracut: Refusing to continue.*|Entering emergency mode.*|You are in emergency mode.*|entered emergency\.target.*`), "emergency target"},
+	{regexp.MustCompile(`some systemd units failed.*|systemd unit \S+ has \d+ restarts|Failed to start .*|\S+\.service: Failed with result.*`), "failed unit"},
+	{regexp.MustCompile(`segfault at .*|\(core dumped\).*|Process \d+ \(.*\) of user \d+ dumped core.*`), "crash"},
+}
+
+// infrastructurePatterns recognize the environment misbehaving. They're
+// only checked in a test's output, since machine logs don't see it.
+var infrastructurePatterns = []failurePattern{
+	{regexp.MustCompile(`(?i).*(quota|InstanceLimitExceeded|InsufficientInstanceCapacity|ZONE_RESOURCE_POOL_EXHAUSTED|SkuNotAvailable|AllocationFailed|OperationNotAllowed.*cores).*`), "cloud quota or capacity"},
+	{regexp.MustCompile(`.*(RequestLimitExceeded|Throttling|rateLimitExceeded|TooManyRequests|429 Too Many Requests).*`), "cloud API rate limit"},
+	{regexp.MustCompile(`.*(ssh: handshake failed|dial tcp \S+: (i/o timeout|connect: connection refused|connect: no route to host)|ssh: .*timed? ?out).*`), "SSH timeout"},
+	{regexp.MustCompile(`.*(context deadline exceeded|TLS handshake timeout|503 Service Unavailable|502 Bad Gateway).*`), "cloud API unavailable"},
+	{regexp.MustCompile(`Cluster failed.*`), "cluster setup"},
+}
+
+// classifyLogs returns the first product pattern matching the test's
+// output or one of logs, then the first infrastructure pattern matching its
+// output, and finally ClassTest.
+func classifyLogs(output string, logs []string) Classification {
+	sources := []string{"output"}
+	texts := []string{output}
+	for _, path := range logs {
+		buf, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		sources = append(sources, path)
+		texts = append(texts, string(buf))
+	}
+	for _, p := range productPatterns {
+		for i, text := range texts {
+			if match := p.re.FindString(text); match != "" {
+				return Classification{Class: ClassProduct, Reason: p.reason, Evidence: strings.TrimSpace(match), Source: sources[i]}
+			}
+		}
+	}
+	for _, p := range infrastructurePatterns {
+		if match := p.re.FindString(output); match != "" {
+			return Classification{Class: ClassInfrastructure, Reason: p.reason, Evidence: strings.TrimSpace(match), Source: "output"}
+		}
+	}
+	return Classification{Class: ClassTest, Reason: "assertion failed with no machine anomaly"}
+}
+
+// consoleLogs returns the machine consoles of a test, given the directory
+// the reports are in. Subtests share their parent's machines.
+func consoleLogs(reportDir, name string) []string {
+	base, _, _ := strings.Cut(name, "/")
+	var logs []string
+	for _, path := range testArtifacts(reportDir, base) {
+		if filepath.Base(path) == "console.txt" {
+			logs = append(logs, path)
+		}
+	}
+	return logs
+}
+
+// ClassifyFailures rewrites the report.json in reportDir to classify each
+// failed or flaked test, and returns how many tests there are of each
+// class.
+func ClassifyFailures(reportDir string) (map[FailureClass]int, error) {
+	filename := filepath.Join(reportDir, "report.json")
+	data, err := DeserialiseReport(filename)
+	if err != nil {
+		return nil, err
+	}
+	counts := map[FailureClass]int{}
+	for i := range data.Tests {
+		test := &data.Tests[i]
+		if test.Result != testresult.Fail && test.Result != testresult.Flake {
+			test.Classification = nil
+			continue
+		}
+		c := classifyLogs(test.Output, consoleLogs(reportDir, test.Name))
+		test.Classification = &c
+		counts[c.Class]++
+	}
+	data.FailureClasses = counts
+	if len(counts) == 0 {
+		data.FailureClasses = nil
+	}
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return counts, json.NewEncoder(f).Encode(data)
+}
@@ -0,0 +1,89 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reporters
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coreos/coreos-assembler/mantle/harness/testresult"
+)
+
+func TestClassifyFailures(t *testing.T) {
+	outputDir := t.TempDir()
+	reportDir := filepath.Join(outputDir, "reports")
+	if err := os.Mkdir(reportDir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	machineDir := filepath.Join(outputDir, "panicked", "machine1")
+	if err := os.MkdirAll(machineDir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	console := "[    4.2] Kernel panic - not syncing: VFS: Unable to mount root fs\n"
+	if err := os.WriteFile(filepath.Join(machineDir, "console.txt"), []byte(console), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reps, err := ForFormats(nil, "aws", "1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reps.ReportTest("passed", nil, testresult.Pass, time.Second, []byte("all good\n"))
+	reps.ReportTest("panicked", nil, testresult.Fail, time.Second, []byte("    harness.go:1: ssh: handshake failed: EOF\n"))
+	reps.ReportTest("quota", nil, testresult.Fail, time.Second, []byte("    harness.go:1: Cluster failed starting machines: VcpuLimitExceeded: You have requested more vCPU capacity than your current vCPU limit (quota) allows\n"))
+	reps.ReportTest("units", nil, testresult.Flake, time.Second, []byte("    harness.go:1: some systemd units failed: foo.service\n"))
+	reps.ReportTest("assertion", nil, testresult.Fail, time.Second, []byte("    harness.go:1: expected 2 got 3\n"))
+	reps.SetResult(testresult.Fail)
+	if err := reps.Output(reportDir); err != nil {
+		t.Fatal(err)
+	}
+
+	counts, err := ClassifyFailures(reportDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts[ClassProduct] != 2 || counts[ClassInfrastructure] != 1 || counts[ClassTest] != 1 {
+		t.Errorf("unexpected counts: %v", counts)
+	}
+
+	data, err := DeserialiseReport(filepath.Join(reportDir, "report.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]Classification{
+		"panicked":  {Class: ClassProduct, Reason: "kernel panic", Source: filepath.Join(machineDir, "console.txt")},
+		"quota":     {Class: ClassInfrastructure, Reason: "cloud quota or capacity", Source: "output"},
+		"units":     {Class: ClassProduct, Reason: "failed unit", Source: "output"},
+		"assertion": {Class: ClassTest},
+	}
+	for _, test := range data.Tests {
+		want, ok := expected[test.Name]
+		if !ok {
+			if test.Classification != nil {
+				t.Errorf("%s: unexpected classification %+v", test.Name, test.Classification)
+			}
+			continue
+		}
+		got := test.Classification
+		if got == nil || got.Class != want.Class || (want.Reason != "" && got.Reason != want.Reason) || got.Source != want.Source {
+			t.Errorf("%s: expected %+v, got %+v", test.Name, want, got)
+		}
+	}
+	if data.FailureClasses[ClassProduct] != 2 {
+		t.Errorf("counts not saved in report: %v", data.FailureClasses)
+	}
+}
@@ -19,6 +19,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -30,6 +31,10 @@ type jsonReporter struct {
 	Result   testresult.TestResult `json:"result"`
 	filename string
 
+	// FailureClasses counts the failed tests of each class, once
+	// ClassifyFailures has run.
+	FailureClasses map[FailureClass]int `json:"failureClasses,omitempty"`
+
 	// Context variables
 	Platform string `json:"platform"`
 	Version  string `json:"version"`
@@ -43,6 +48,8 @@ type jsonTest struct {
 	Result   testresult.TestResult `json:"result"`
 	Duration time.Duration         `json:"duration"`
 	Output   string                `json:"output"`
+
+	Classification *Classification `json:"classification,omitempty"`
 }
 
 func DeserialiseReport(filename string) (*jsonReporter, error) {
@@ -62,6 +69,60 @@ func DeserialiseReport(filename string) (*jsonReporter, error) {
 	return &data, err
 }
 
+// MarkFlaked rewrites a report so that failed tests for which flaked
+// returns true are reported as flakes. A failed test is also marked as a
+// flake if all of its failed subtests were. Returns whether any failures
+// remain.
+func MarkFlaked(filename string, flaked func(name string) bool) (bool, error) {
+	data, err := DeserialiseReport(filename)
+	if err != nil {
+		return false, err
+	}
+	for i := range data.Tests {
+		if data.Tests[i].Result == testresult.Fail && flaked(data.Tests[i].Name) {
+			data.Tests[i].Result = testresult.Flake
+		}
+	}
+	for i := range data.Tests {
+		test := &data.Tests[i]
+		if test.Result != testresult.Fail {
+			continue
+		}
+		prefix := test.Name + "/"
+		sawFlake, sawFail := false, false
+		for _, sub := range data.Tests {
+			if !strings.HasPrefix(sub.Name, prefix) {
+				continue
+			}
+			switch sub.Result {
+			case testresult.Flake:
+				sawFlake = true
+			case testresult.Fail:
+				sawFail = true
+			}
+		}
+		if sawFlake && !sawFail {
+			test.Result = testresult.Flake
+		}
+	}
+	failed := false
+	for _, test := range data.Tests {
+		if test.Result == testresult.Fail {
+			failed = true
+		}
+	}
+	if !failed && data.Result == testresult.Fail {
+		data.Result = testresult.Pass
+	}
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	return failed, json.NewEncoder(f).Encode(data)
+}
+
 func NewJSONReporter(filename, platform, version string) *jsonReporter {
 	return &jsonReporter{
 		Platform: platform,
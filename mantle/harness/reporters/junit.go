@@ -0,0 +1,192 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reporters
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/coreos-assembler/mantle/harness/testresult"
+)
+
+type junitReporter struct {
+	filename string
+	platform string
+	version  string
+	start    time.Time
+
+	tests []jsonTest
+
+	mutex sync.Mutex
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Cases      []junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure"`
+	// flakyFailure is the Maven Surefire extension understood by Jenkins
+	FlakyFailure *junitMessage `xml:"flakyFailure"`
+	Skipped      *junitMessage `xml:"skipped"`
+	SystemOut    string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr,omitempty"`
+	Body    string `xml:",chardata"`
+}
+
+// NewJUnitReporter returns a reporter which writes JUnit XML. Each test's
+// output is attached as its system-out, followed by links to its machines'
+// console and journal logs in the [[ATTACHMENT|path]] form recognized by
+// Jenkins.
+func NewJUnitReporter(filename, platform, version string) *junitReporter {
+	return &junitReporter{
+		filename: filename,
+		platform: platform,
+		version:  version,
+		start:    time.Now(),
+	}
+}
+
+func (r *junitReporter) ReportTest(name string, subtests []string, result testresult.TestResult, duration time.Duration, b []byte) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.tests = append(r.tests, jsonTest{
+		Name:     name,
+		Subtests: subtests,
+		Result:   result,
+		Duration: duration,
+		Output:   string(b),
+	})
+}
+
+func (r *junitReporter) SetResult(testresult.TestResult) {}
+
+func (r *junitReporter) Output(path string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	suite := junitTestSuite{
+		Name:      r.platform,
+		Timestamp: r.start.UTC().Format(time.RFC3339),
+	}
+	if r.version != "" {
+		suite.Properties = []junitProperty{{Name: "version", Value: r.version}}
+	}
+	var total time.Duration
+	for _, test := range r.tests {
+		tc := junitTestCase{
+			Name:      test.Name,
+			ClassName: r.platform,
+			Time:      junitSeconds(test.Duration),
+		}
+		switch test.Result {
+		case testresult.Fail:
+			tc.Failure = &junitMessage{Message: firstLine(test.Output), Body: test.Output}
+			suite.Failures++
+		case testresult.Flake:
+			tc.FlakyFailure = &junitMessage{Message: "failed, then passed on retry", Body: test.Output}
+		case testresult.Skip:
+			tc.Skipped = &junitMessage{Message: firstLine(test.Output)}
+			suite.Skipped++
+		}
+		var out strings.Builder
+		out.WriteString(test.Output)
+		for _, artifact := range testArtifacts(path, test.Name) {
+			fmt.Fprintf(&out, "\n[[ATTACHMENT|%s]]", artifact)
+		}
+		tc.SystemOut = out.String()
+		suite.Cases = append(suite.Cases, tc)
+		// Subtests are already counted in their parent's duration
+		if !strings.Contains(test.Name, "/") {
+			total += test.Duration
+		}
+	}
+	suite.Tests = len(suite.Cases)
+	suite.Time = junitSeconds(total)
+
+	suites := junitTestSuites{
+		Name:     "kola",
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Skipped:  suite.Skipped,
+		Time:     suite.Time,
+		Suites:   []junitTestSuite{suite},
+	}
+
+	f, err := os.Create(filepath.Join(path, r.filename))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.WriteString(xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(f)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suites); err != nil {
+		return err
+	}
+	_, err = f.WriteString("\n")
+	return err
+}
+
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// firstLine returns the first non-empty line of a test's output, for use
+// as a summary of why it failed or was skipped.
+func firstLine(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
@@ -15,6 +15,9 @@
 package reporters
 
 import (
+	"fmt"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/coreos/coreos-assembler/mantle/harness/testresult"
@@ -49,3 +52,49 @@ type Reporter interface {
 	Output(string) error
 	SetResult(testresult.TestResult)
 }
+
+// Formats are the report formats which can be requested in addition to
+// report.json, which is always written since kola reads it back.
+var Formats = []string{"json", "junit", "tap"}
+
+// ForFormats returns reporters writing report.json plus any of the other
+// requested formats: report.xml for "junit" and report.tap for "tap".
+func ForFormats(formats []string, platform, version string) (Reporters, error) {
+	reps := Reporters{NewJSONReporter("report.json", platform, version)}
+	seen := map[string]bool{"json": true}
+	for _, format := range formats {
+		if seen[format] {
+			continue
+		}
+		seen[format] = true
+		switch format {
+		case "junit":
+			reps = append(reps, NewJUnitReporter("report.xml", platform, version))
+		case "tap":
+			reps = append(reps, NewTAPReporter("report.tap"))
+		default:
+			return nil, fmt.Errorf("unknown report format %q; valid formats: %s", format, strings.Join(Formats, ", "))
+		}
+	}
+	return reps, nil
+}
+
+// Regenerate rewrites the reports in reportDir other than report.json from
+// its contents, e.g. after MarkFlaked has updated it.
+func Regenerate(reportDir string, formats []string) error {
+	data, err := DeserialiseReport(filepath.Join(reportDir, "report.json"))
+	if err != nil {
+		return err
+	}
+	reps, err := ForFormats(formats, data.Platform, data.Version)
+	if err != nil {
+		return err
+	}
+	// Leave report.json itself alone
+	reps = reps[1:]
+	for _, test := range data.Tests {
+		reps.ReportTest(test.Name, test.Subtests, test.Result, test.Duration, []byte(test.Output))
+	}
+	reps.SetResult(data.Result)
+	return reps.Output(reportDir)
+}
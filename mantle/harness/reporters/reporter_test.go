@@ -0,0 +1,149 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reporters
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coreos/coreos-assembler/mantle/harness/testresult"
+)
+
+func writeReports(t *testing.T, formats []string) string {
+	outputDir := t.TempDir()
+	reportDir := filepath.Join(outputDir, "reports")
+	if err := os.Mkdir(reportDir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	machineDir := filepath.Join(outputDir, "basic", "machine1")
+	if err := os.MkdirAll(machineDir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(machineDir, "console.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reps, err := ForFormats(formats, "qemu", "1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reps.ReportTest("basic", nil, testresult.Pass, 2*time.Second, []byte("all good\n"))
+	reps.ReportTest("broken", nil, testresult.Fail, time.Second, []byte("    harness.go:1: boom\n    more\n"))
+	reps.ReportTest("skipped", nil, testresult.Skip, 0, []byte("not on qemu\n"))
+	reps.SetResult(testresult.Fail)
+	if err := reps.Output(reportDir); err != nil {
+		t.Fatal(err)
+	}
+	return reportDir
+}
+
+func TestForFormats(t *testing.T) {
+	if _, err := ForFormats([]string{"xunit"}, "qemu", ""); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+	reps, err := ForFormats([]string{"json", "junit", "tap", "junit"}, "qemu", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reps) != 3 {
+		t.Errorf("expected 3 reporters, got %d", len(reps))
+	}
+}
+
+func TestJUnitReporter(t *testing.T) {
+	reportDir := writeReports(t, []string{"junit"})
+	buf, err := os.ReadFile(filepath.Join(reportDir, "report.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var suites junitTestSuites
+	if err := xml.Unmarshal(buf, &suites); err != nil {
+		t.Fatal(err)
+	}
+	if suites.Tests != 3 || suites.Failures != 1 || suites.Skipped != 1 {
+		t.Errorf("unexpected counts: %d tests, %d failures, %d skipped", suites.Tests, suites.Failures, suites.Skipped)
+	}
+	cases := suites.Suites[0].Cases
+	if cases[0].Time != "2.000" {
+		t.Errorf("unexpected time %q", cases[0].Time)
+	}
+	if !strings.Contains(cases[0].SystemOut, "[[ATTACHMENT|") || !strings.Contains(cases[0].SystemOut, "machine1/console.txt]]") {
+		t.Errorf("console log not attached: %q", cases[0].SystemOut)
+	}
+	if cases[1].Failure == nil || cases[1].Failure.Message != "harness.go:1: boom" {
+		t.Errorf("unexpected failure: %+v", cases[1].Failure)
+	}
+	if cases[2].Skipped == nil {
+		t.Error("expected skipped test to be marked as such")
+	}
+}
+
+func TestTAPReporter(t *testing.T) {
+	reportDir := writeReports(t, []string{"tap"})
+	buf, err := os.ReadFile(filepath.Join(reportDir, "report.tap"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(buf)
+	for _, expected := range []string{
+		"TAP version 13\n1..3\n",
+		"ok 1 - basic\n  ---\n  duration_ms: 2000\n",
+		"not ok 2 - broken\n",
+		"    harness.go:1: boom\n",
+		"ok 3 - skipped # SKIP not on qemu\n",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("expected %q in:\n%s", expected, out)
+		}
+	}
+}
+
+func TestRegenerate(t *testing.T) {
+	reportDir := writeReports(t, []string{"junit", "tap"})
+	failed, err := MarkFlaked(filepath.Join(reportDir, "report.json"), func(name string) bool {
+		return name == "broken"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed {
+		t.Error("expected no failures to remain")
+	}
+	if err := Regenerate(reportDir, []string{"junit", "tap"}); err != nil {
+		t.Fatal(err)
+	}
+	buf, err := os.ReadFile(filepath.Join(reportDir, "report.tap"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(buf), "ok 2 - broken # flaked") {
+		t.Errorf("flake not reflected in TAP report:\n%s", buf)
+	}
+	buf, err = os.ReadFile(filepath.Join(reportDir, "report.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var suites junitTestSuites
+	if err := xml.Unmarshal(buf, &suites); err != nil {
+		t.Fatal(err)
+	}
+	if suites.Failures != 0 || suites.Suites[0].Cases[1].FlakyFailure == nil {
+		t.Errorf("flake not reflected in JUnit report:\n%s", buf)
+	}
+}
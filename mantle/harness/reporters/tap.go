@@ -0,0 +1,99 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reporters
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/coreos-assembler/mantle/harness/testresult"
+)
+
+type tapReporter struct {
+	filename string
+	tests    []jsonTest
+
+	mutex sync.Mutex
+}
+
+// NewTAPReporter returns a reporter which writes TAP version 13. Unlike the
+// test.tap the harness writes as it goes, each test point carries a YAML
+// block with the test's duration, output and machine logs.
+func NewTAPReporter(filename string) *tapReporter {
+	return &tapReporter{filename: filename}
+}
+
+func (r *tapReporter) ReportTest(name string, subtests []string, result testresult.TestResult, duration time.Duration, b []byte) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.tests = append(r.tests, jsonTest{
+		Name:     name,
+		Subtests: subtests,
+		Result:   result,
+		Duration: duration,
+		Output:   string(b),
+	})
+}
+
+func (r *tapReporter) SetResult(testresult.TestResult) {}
+
+func (r *tapReporter) Output(path string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	f, err := os.Create(filepath.Join(path, r.filename))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	fmt.Fprintf(w, "TAP version 13\n1..%d\n", len(r.tests))
+	for i, test := range r.tests {
+		switch test.Result {
+		case testresult.Fail:
+			fmt.Fprintf(w, "not ok %d - %s\n", i+1, test.Name)
+		case testresult.Skip:
+			fmt.Fprintf(w, "ok %d - %s # SKIP %s\n", i+1, test.Name, firstLine(test.Output))
+		case testresult.Flake:
+			fmt.Fprintf(w, "ok %d - %s # flaked: passed on retry\n", i+1, test.Name)
+		default:
+			fmt.Fprintf(w, "ok %d - %s\n", i+1, test.Name)
+		}
+		fmt.Fprintf(w, "  ---\n")
+		fmt.Fprintf(w, "  duration_ms: %d\n", test.Duration.Milliseconds())
+		fmt.Fprintf(w, "  result: %s\n", test.Result)
+		if artifacts := testArtifacts(path, test.Name); len(artifacts) > 0 {
+			fmt.Fprintf(w, "  logs:\n")
+			for _, artifact := range artifacts {
+				fmt.Fprintf(w, "    - %q\n", artifact)
+			}
+		}
+		if output := strings.TrimRight(test.Output, "\n"); output != "" {
+			fmt.Fprintf(w, "  output: |\n")
+			for _, line := range strings.Split(output, "\n") {
+				fmt.Fprintf(w, "    %s\n", line)
+			}
+		}
+		fmt.Fprintf(w, "  ...\n")
+	}
+	return w.Flush()
+}
@@ -31,6 +31,7 @@ import (
 
 	"github.com/pkg/errors"
 
+	"github.com/coreos/coreos-assembler/mantle/harness/artifacts"
 	"github.com/coreos/coreos-assembler/mantle/harness/reporters"
 	"github.com/coreos/coreos-assembler/mantle/harness/testresult"
 )
@@ -79,6 +80,10 @@ type Options struct {
 	Sharding string
 
 	Reporters reporters.Reporters
+
+	// Artifacts, if set, applies a retention policy to the artifacts
+	// registered by tests when they finish.
+	Artifacts *artifacts.Manager
 }
 
 // FlagSet can be used to setup options via command line flags.
@@ -281,7 +286,6 @@ func (s *Suite) runTests(out, tap io.Writer) error {
 	s.running = 1 // Set the count to 1 for the main (sequential) test.
 	t := &H{
 		signal:    make(chan bool),
-		barrier:   make(chan bool),
 		w:         out,
 		tap:       tap,
 		suite:     s,
@@ -291,7 +295,8 @@ func (s *Suite) runTests(out, tap io.Writer) error {
 		timeout: defaultTimeout,
 	}
 	tRunner(t, func(t *H) {
-		for name, htest := range s.tests {
+		for _, name := range s.tests.scheduled() {
+			htest := s.tests[name]
 			t.RunTimeout(name, htest.run, htest.timeout)
 		}
 		// Run catching the signal rather than the tRunner as a separate
@@ -16,7 +16,11 @@
 package harness
 
 import (
+	"bytes"
+	"reflect"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestSuiteParallelism(t *testing.T) {
@@ -111,3 +115,30 @@ func TestSuiteParallelism(t *testing.T) {
 		}
 	}
 }
+
+func TestSuiteStartOrder(t *testing.T) {
+	var mu sync.Mutex
+	var started []string
+	var tests Tests
+	for _, name := range []string{"a", "b", "c", "d"} {
+		name := name
+		tests.Add(name, func(h *H) {
+			h.Parallel()
+			mu.Lock()
+			started = append(started, name)
+			mu.Unlock()
+		}, time.Minute)
+	}
+	tests.SetEstimate("c", 3*time.Minute)
+	tests.SetEstimate("a", 2*time.Minute)
+
+	suite := NewSuite(Options{Parallel: 1}, tests)
+	buf := &bytes.Buffer{}
+	if err := suite.runTests(buf, nil); err != nil {
+		t.Fatalf("%v\n%s", err, buf.String())
+	}
+	expect := []string{"c", "a", "b", "d"}
+	if !reflect.DeepEqual(started, expect) {
+		t.Errorf("got %v wanted %v", started, expect)
+	}
+}
@@ -16,6 +16,7 @@ package harness
 
 import (
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/coreos/coreos-assembler/mantle/lang/maps"
@@ -28,6 +29,8 @@ type HarnessTest struct {
 	run Test
 	// time after which test will timeout in minutes
 	timeout time.Duration
+	// expected run time, used to start the longest tests first
+	estimate time.Duration
 }
 
 // Tests is a set of test functions and timeouts that can be given to a Suite.
@@ -48,3 +51,20 @@ func (ts *Tests) Add(name string, test Test, timeout time.Duration) {
 func (ts Tests) List() []string {
 	return maps.NaturalKeys(ts)
 }
+
+// SetEstimate records how long the named test is expected to take. Tests
+// are started longest first; those without an estimate start last.
+func (ts Tests) SetEstimate(name string, estimate time.Duration) {
+	if test, ok := ts[name]; ok {
+		test.estimate = estimate
+	}
+}
+
+// scheduled returns the test names in the order they should be started.
+func (ts Tests) scheduled() []string {
+	names := ts.List()
+	sort.SliceStable(names, func(i, j int) bool {
+		return ts[names[i]].estimate > ts[names[j]].estimate
+	})
+	return names
+}
@@ -51,3 +51,20 @@ func TestTestsList(t *testing.T) {
 		t.Errorf("got %v wanted %v", list, expect)
 	}
 }
+
+func TestTestsScheduled(t *testing.T) {
+	var ts Tests
+	ts.Add("short", nil, 0)
+	ts.Add("unknown", nil, 0)
+	ts.Add("long", nil, 0)
+	ts.Add("alsolong", nil, 0)
+	ts.SetEstimate("short", 1)
+	ts.SetEstimate("long", 10)
+	ts.SetEstimate("alsolong", 10)
+	ts.SetEstimate("missing", 5)
+	list := ts.scheduled()
+	expect := []string{"alsolong", "long", "short", "unknown"}
+	if !reflect.DeepEqual(list, expect) {
+		t.Errorf("got %v wanted %v", list, expect)
+	}
+}
@@ -21,6 +21,8 @@ const (
 	Warn TestResult = "WARN"
 	Skip TestResult = "SKIP"
 	Pass TestResult = "PASS"
+	// Flake is a failure which passed on retry
+	Flake TestResult = "FLAKE"
 )
 
 type TestResult string
@@ -38,7 +40,7 @@ func (s TestResult) Display() string {
 
 	if s == Fail {
 		return red + string(s) + reset
-	} else if s == Warn {
+	} else if s == Warn || s == Flake {
 		return yellow + string(s) + reset
 	} else if s == Skip {
 		return blue + string(s) + reset
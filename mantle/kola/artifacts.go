@@ -0,0 +1,58 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kola
+
+import (
+	"path/filepath"
+
+	"github.com/coreos/coreos-assembler/mantle/harness"
+	"github.com/coreos/coreos-assembler/mantle/harness/artifacts"
+)
+
+// testArtifacts are the files a test leaves in its output directory which
+// are subject to the retention policy, relative to it. Machines keep
+// theirs in a directory named after their ID.
+var testArtifacts = []struct {
+	pattern string
+	class   artifacts.Class
+}{
+	{"*-failure.tar.gz", artifacts.Bundle},
+	{"journal.txt", artifacts.Log},
+	{"*/console.txt", artifacts.Log},
+	{"*/journal.txt", artifacts.Log},
+	{"*/journal-raw.txt.gz", artifacts.Log},
+	{"*/telemetry.json", artifacts.Log},
+	{"*/gather", artifacts.Bundle},
+	{"*/kdump", artifacts.Dump},
+}
+
+// registerArtifacts registers what the test left in its output directory,
+// once its machines are destroyed.
+func registerArtifacts(h *harness.H) {
+	if Artifacts == nil {
+		return
+	}
+	dir := h.OutputDir()
+	for _, a := range testArtifacts {
+		matches, err := filepath.Glob(filepath.Join(dir, a.pattern))
+		if err != nil {
+			// Only for a bad pattern
+			panic(err)
+		}
+		for _, match := range matches {
+			h.RegisterArtifact(match, a.class)
+		}
+	}
+}
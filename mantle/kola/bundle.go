@@ -0,0 +1,140 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kola
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/coreos/coreos-assembler/mantle/harness"
+	"github.com/coreos/coreos-assembler/mantle/platform"
+)
+
+// failureBundleTimeout bounds each command run to gather a failure bundle,
+// so a wedged machine doesn't hold up the run.
+const failureBundleTimeout = 30 * time.Second
+
+// failureBundleCommands are run on each machine of a failed test, with
+// their output saved under the machine's ID in the bundle. Either
+// networkd or NetworkManager will be missing, so expect one to fail.
+var failureBundleCommands = []struct {
+	file string
+	cmd  string
+}{
+	{"journal.export", "sudo journalctl --no-pager -o export"},
+	{"dmesg.txt", "sudo dmesg"},
+	{"networkd.txt", "networkctl status --all --no-pager"},
+	{"NetworkManager.txt", "nmcli device show && nmcli connection show"},
+	{"ignition-result.json", "sudo cat /etc/.ignition-result.json"},
+}
+
+// failureBundle holds diagnostics gathered from the machines of a failed
+// test, for triaging it without a rerun.
+type failureBundle struct {
+	files map[string][]byte
+}
+
+// collectFailureBundle gathers what it can from the machines, which must
+// still be running.
+func collectFailureBundle(machines []platform.Machine) *failureBundle {
+	b := &failureBundle{files: make(map[string][]byte)}
+	for _, m := range machines {
+		for _, c := range failureBundleCommands {
+			var out bytes.Buffer
+			ctx, cancel := context.WithTimeout(context.Background(), failureBundleTimeout)
+			err := platform.RunCommand(ctx, m, c.cmd, &out, nil)
+			cancel()
+			if err != nil {
+				plog.Debugf("gathering %s from %s for failure bundle: %v", c.file, m.ID(), err)
+				var exitErr *platform.ExitError
+				if !errors.As(err, &exitErr) {
+					// Not worth waiting on the rest if SSH is broken
+					break
+				}
+				continue
+			}
+			b.files[path.Join(m.ID(), c.file)] = out.Bytes()
+		}
+	}
+	return b
+}
+
+// addConsoles adds the machines' console output, which is only available
+// once they're destroyed.
+func (b *failureBundle) addConsoles(consoles map[string]string) {
+	for id, output := range consoles {
+		if output != "" {
+			b.files[path.Join(id, "console.txt")] = []byte(output)
+		}
+	}
+}
+
+// failureBundlePath names the bundle after the test and puts it in the
+// test's output directory.
+func failureBundlePath(h *harness.H) string {
+	name := strings.ReplaceAll(h.Name(), "/", "-") + "-failure.tar.gz"
+	return filepath.Join(h.OutputDir(), name)
+}
+
+// write saves the bundle as a gzipped tarball.
+func (b *failureBundle) write(p string) error {
+	top := strings.TrimSuffix(filepath.Base(p), ".tar.gz")
+	names := make([]string, 0, len(b.files))
+	for name := range b.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	f, err := os.Create(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+	for _, name := range names {
+		data := b.files[name]
+		hdr := &tar.Header{
+			Name:    path.Join(top, name),
+			Mode:    0644,
+			Size:    int64(len(data)),
+			ModTime: now,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -16,15 +16,19 @@ package cluster
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/kballard/go-shellquote"
 
 	"github.com/coreos/coreos-assembler/mantle/harness"
+	"github.com/coreos/coreos-assembler/mantle/kola/events"
 	"github.com/coreos/coreos-assembler/mantle/platform"
 	"github.com/coreos/pkg/capnslog"
 	"github.com/pkg/errors"
@@ -40,6 +44,8 @@ type TestCluster struct {
 	*harness.H
 	platform.Cluster
 	NativeFuncs []string
+	// Fixtures are the shared fixtures provisioned in the cluster
+	Fixtures *FixtureSet
 
 	// If set to true and a sub-test fails all future sub-tests will be skipped
 	FailFast   bool
@@ -52,11 +58,11 @@ func (t *TestCluster) Run(name string, f func(c TestCluster)) bool {
 		return t.H.Run(name, func(h *harness.H) {
 			func(c TestCluster) {
 				c.Skip("A previous test has already failed")
-			}(TestCluster{H: h, Cluster: t.Cluster})
+			}(TestCluster{H: h, Cluster: t.Cluster, Fixtures: t.Fixtures})
 		})
 	}
 	t.hasFailure = !t.H.Run(name, func(h *harness.H) {
-		f(TestCluster{H: h, Cluster: t.Cluster})
+		f(TestCluster{H: h, Cluster: t.Cluster, Fixtures: t.Fixtures})
 	})
 	return !t.hasFailure
 
@@ -138,9 +144,13 @@ func DropFile(machines []platform.Machine, localPath string) error {
 func (t *TestCluster) SSH(m platform.Machine, cmd string) ([]byte, error) {
 	var stdout, stderr []byte
 	var err error
+	start := time.Now()
 	f := func() {
 		stdout, stderr, err = m.SSH(cmd)
 	}
+	defer func() {
+		t.emitSSHEvent(m, cmd, start, err)
+	}()
 
 	errMsg := fmt.Sprintf("ssh: %s", cmd)
 	// If f does not before the test timeout, the RunWithExecTimeoutCheck
@@ -154,6 +164,73 @@ func (t *TestCluster) SSH(m platform.Machine, cmd string) ([]byte, error) {
 	return stdout, err
 }
 
+// SSHContext runs cmd on m like SSH, except that the command is killed if
+// ctx is done first, and its stdout and stderr are logged line by line as
+// they're produced. If the command fails, the error is a
+// *platform.ExitError.
+func (t *TestCluster) SSHContext(ctx context.Context, m platform.Machine, cmd string) ([]byte, error) {
+	// Kill the command if the test times out
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var stdout bytes.Buffer
+	var err error
+	start := time.Now()
+	outLog := &lineLogger{log: t.Log}
+	errLog := &lineLogger{log: t.Log}
+	f := func() {
+		err = platform.RunCommand(ctx, m, cmd, io.MultiWriter(&stdout, outLog), errLog)
+		outLog.flush()
+		errLog.flush()
+	}
+	defer func() {
+		t.emitSSHEvent(m, cmd, start, err)
+	}()
+
+	t.H.RunWithExecTimeoutCheck(f, fmt.Sprintf("ssh: %s", cmd))
+	return bytes.TrimSpace(stdout.Bytes()), err
+}
+
+func (t *TestCluster) emitSSHEvent(m platform.Machine, cmd string, start time.Time, err error) {
+	e := events.Event{
+		Type:     events.SSHCommand,
+		Test:     t.H.Name(),
+		Machine:  m.ID(),
+		Command:  cmd,
+		Duration: time.Since(start).Seconds(),
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	events.Emit(e)
+}
+
+// lineLogger logs what's written to it a line at a time.
+type lineLogger struct {
+	log func(args ...interface{})
+	buf []byte
+}
+
+func (l *lineLogger) Write(b []byte) (int, error) {
+	l.buf = append(l.buf, b...)
+	for {
+		i := bytes.IndexByte(l.buf, '\n')
+		if i < 0 {
+			return len(b), nil
+		}
+		l.log(string(l.buf[:i]))
+		l.buf = l.buf[i+1:]
+	}
+}
+
+// flush logs any final unterminated line.
+func (l *lineLogger) flush() {
+	if len(l.buf) > 0 {
+		l.log(string(l.buf))
+		l.buf = nil
+	}
+}
+
 func (t *TestCluster) SSHf(m platform.Machine, f string, args ...interface{}) ([]byte, error) {
 	return t.SSH(m, fmt.Sprintf(f, args...))
 }
@@ -0,0 +1,202 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Fixture is a piece of shared setup, such as a container registry seeded
+// with images or a machine serving NFS, which tests declare a need for
+// rather than each provisioning it themselves. A fixture is set up at most
+// once per cluster and torn down when the last test using it finishes.
+type Fixture struct {
+	Name string
+	// Requires lists fixtures which must be set up before this one
+	Requires []string
+	// Setup provisions the fixture. The returned value is what tests get
+	// from TestCluster.Fixture(), e.g. the machine which was created.
+	Setup func(c TestCluster) (interface{}, error)
+	// Teardown, if set, cleans up after the fixture. There's no need to
+	// destroy machines created by Setup; that happens along with the
+	// cluster.
+	Teardown func(c TestCluster, value interface{}) error
+}
+
+type activeFixture struct {
+	value interface{}
+	refs  int
+}
+
+// FixtureSet tracks the fixtures provisioned in a single cluster.
+type FixtureSet struct {
+	registry map[string]*Fixture
+
+	// setupMu serializes setup and teardown; mu only guards active, so
+	// that a fixture's Setup can look up the fixtures it requires.
+	setupMu sync.Mutex
+	mu      sync.Mutex
+	active  map[string]*activeFixture
+}
+
+// NewFixtureSet returns an empty set, able to provision the fixtures in
+// registry.
+func NewFixtureSet(registry map[string]*Fixture) *FixtureSet {
+	return &FixtureSet{
+		registry: registry,
+		active:   make(map[string]*activeFixture),
+	}
+}
+
+// resolve returns names and everything they require, with requirements
+// ordered before the fixtures needing them.
+func (s *FixtureSet) resolve(names []string) ([]string, error) {
+	var order []string
+	done := make(map[string]bool)
+	visiting := make(map[string]bool)
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		if done[name] {
+			return nil
+		}
+		path = append(path, name)
+		if visiting[name] {
+			return fmt.Errorf("fixture dependency cycle: %s", strings.Join(path, " -> "))
+		}
+		f, ok := s.registry[name]
+		if !ok {
+			return fmt.Errorf("unknown fixture %q", name)
+		}
+		visiting[name] = true
+		for _, req := range f.Requires {
+			if err := visit(req, path); err != nil {
+				return err
+			}
+		}
+		visiting[name] = false
+		done[name] = true
+		order = append(order, name)
+		return nil
+	}
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// Acquire sets up any of the named fixtures, and those they require, which
+// aren't already active, and takes a reference on each. On error, nothing
+// remains acquired. Every successful call must be paired with a Release of
+// the same names.
+func (s *FixtureSet) Acquire(c TestCluster, names []string) error {
+	order, err := s.resolve(names)
+	if err != nil {
+		return err
+	}
+
+	s.setupMu.Lock()
+	defer s.setupMu.Unlock()
+	for i, name := range order {
+		s.mu.Lock()
+		a, ok := s.active[name]
+		if ok {
+			a.refs++
+		}
+		s.mu.Unlock()
+		if ok {
+			continue
+		}
+		c.Logf("Setting up fixture %s", name)
+		value, err := s.registry[name].Setup(c)
+		if err != nil {
+			s.release(c, order[:i])
+			return errors.Wrapf(err, "setting up fixture %s", name)
+		}
+		s.mu.Lock()
+		s.active[name] = &activeFixture{value: value, refs: 1}
+		s.mu.Unlock()
+	}
+	return nil
+}
+
+// Release drops a reference on each of the named fixtures and those they
+// require, tearing down any which are no longer used.
+func (s *FixtureSet) Release(c TestCluster, names []string) {
+	order, err := s.resolve(names)
+	if err != nil {
+		// Acquire would have failed too
+		return
+	}
+
+	s.setupMu.Lock()
+	defer s.setupMu.Unlock()
+	s.release(c, order)
+}
+
+// release drops references in reverse order, so that fixtures are torn down
+// before what they require.
+func (s *FixtureSet) release(c TestCluster, order []string) {
+	for i := len(order) - 1; i >= 0; i-- {
+		name := order[i]
+		s.mu.Lock()
+		a, ok := s.active[name]
+		if ok {
+			a.refs--
+		}
+		s.mu.Unlock()
+		if !ok || a.refs > 0 {
+			continue
+		}
+		// Leave it visible to its own teardown
+		if teardown := s.registry[name].Teardown; teardown != nil {
+			c.Logf("Tearing down fixture %s", name)
+			if err := teardown(c, a.value); err != nil {
+				c.Errorf("tearing down fixture %s: %v", name, err)
+			}
+		}
+		s.mu.Lock()
+		delete(s.active, name)
+		s.mu.Unlock()
+	}
+}
+
+// value returns the value of an active fixture.
+func (s *FixtureSet) value(name string) (interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok := s.active[name]
+	if !ok {
+		return nil, false
+	}
+	return a.value, true
+}
+
+// Fixture returns the value provided by a fixture the test declared, such
+// as the machine it set up. The test fails if the fixture isn't active.
+func (t *TestCluster) Fixture(name string) interface{} {
+	if t.Fixtures != nil {
+		if value, ok := t.Fixtures.value(name); ok {
+			return value
+		}
+	}
+	t.Fatalf("fixture %s is not set up; is it listed in the test's Fixtures?", name)
+	return nil
+}
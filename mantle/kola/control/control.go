@@ -0,0 +1,352 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package control serves an HTTP API for observing and controlling a
+// running kola session, so that IDE integrations and the cosa web UI can
+// list its clusters and machines, read their consoles, and destroy them or
+// gather their logs without scraping kola's output.
+//
+// The API only listens on loopback addresses, and every request must carry
+// the server's token as "Authorization: Bearer <token>".
+//
+//	GET    /v1/clusters                  clusters and their machines
+//	GET    /v1/machines/{id}?lines=N     a machine and the tail of its console
+//	DELETE /v1/machines/{id}             destroy a machine
+//	POST   /v1/machines/{id}/gather      gather a machine's logs
+package control
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/pkg/capnslog"
+	"github.com/pkg/errors"
+
+	"github.com/coreos/coreos-assembler/mantle/platform"
+)
+
+var plog = capnslog.NewPackageLogger("github.com/coreos/coreos-assembler/mantle", "kola/control")
+
+// defaultConsoleLines is how much of a machine's console is returned unless
+// the request asks for more or less.
+const defaultConsoleLines = 50
+
+// Info is how clients reach a Server.
+type Info struct {
+	URL   string `json:"url"`
+	Token string `json:"token"`
+}
+
+// Cluster describes a cluster of a flight.
+type Cluster struct {
+	Name     string    `json:"name"`
+	Platform string    `json:"platform"`
+	Machines []Machine `json:"machines"`
+}
+
+// Machine describes a machine of a cluster. Test is the name of the test
+// the machine's cluster was created for, if any. SSHAuthSock is the socket of
+// the SSH agent holding the keys the machine accepts, for platforms whose
+// flights have one; Console is only set when a single machine is requested
+// and its platform can read its console while it runs.
+type Machine struct {
+	ID          string  `json:"id"`
+	Cluster     string  `json:"cluster"`
+	Platform    string  `json:"platform"`
+	Test        string  `json:"test,omitempty"`
+	IP          string  `json:"ip"`
+	PrivateIP   string  `json:"privateIP"`
+	OutputDir   string  `json:"outputDir"`
+	SSHAuthSock string  `json:"sshAuthSock,omitempty"`
+	Console     *string `json:"console,omitempty"`
+}
+
+// GatherFunc gathers logs from a machine, returning the directory they were
+// copied to.
+type GatherFunc func(m platform.Machine) (string, error)
+
+// Server serves the API for the flights added to it.
+type Server struct {
+	info   Info
+	srv    *http.Server
+	gather GatherFunc
+
+	mu      sync.Mutex
+	flights []platform.Flight
+}
+
+// Listen starts serving the API on address, which must be a loopback
+// address such as "127.0.0.1:0". gather may be nil, in which case gathering
+// logs isn't supported.
+func Listen(address string, gather GatherFunc) (*Server, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing control address %q", address)
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return nil, fmt.Errorf("control address %q is not a loopback address", address)
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, errors.Wrapf(err, "generating control token")
+	}
+
+	l, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, errors.Wrapf(err, "listening on %s", address)
+	}
+
+	s := &Server{
+		info: Info{
+			URL:   "http://" + l.Addr().String(),
+			Token: hex.EncodeToString(buf),
+		},
+		gather: gather,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/clusters", s.listClusters)
+	mux.HandleFunc("GET /v1/machines/{id}", s.getMachine)
+	mux.HandleFunc("DELETE /v1/machines/{id}", s.destroyMachine)
+	mux.HandleFunc("POST /v1/machines/{id}/gather", s.gatherMachine)
+	s.srv = &http.Server{
+		Handler:           s.authorize(mux),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		if err := s.srv.Serve(l); err != nil && err != http.ErrServerClosed {
+			plog.Errorf("Serving control API: %v", err)
+		}
+	}()
+	return s, nil
+}
+
+// Info returns how to reach the server.
+func (s *Server) Info() Info {
+	return s.info
+}
+
+// WriteInfo writes the server's Info as JSON to path, readable only by the
+// current user since it holds the token.
+func (s *Server) WriteInfo(path string) error {
+	buf, err := json.MarshalIndent(s.info, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(buf, '\n'), 0600)
+}
+
+// AddFlight makes the clusters and machines of flight visible through the
+// API.
+func (s *Server) AddFlight(flight platform.Flight) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flights = append(s.flights, flight)
+}
+
+// Close stops serving the API, waiting briefly for requests in progress.
+func (s *Server) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.srv.Shutdown(ctx)
+}
+
+func (s *Server) authorize(next http.Handler) http.Handler {
+	want := []byte("Bearer " + s.info.Token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+type machineRef struct {
+	machine  platform.Machine
+	cluster  platform.Cluster
+	sshAgent string
+}
+
+// machines returns the machines of every flight, ordered by cluster and
+// then ID.
+func (s *Server) machines() []machineRef {
+	s.mu.Lock()
+	flights := append([]platform.Flight(nil), s.flights...)
+	s.mu.Unlock()
+
+	var refs []machineRef
+	for _, f := range flights {
+		var sock string
+		if a, ok := f.(interface{ SSHAgentSocket() string }); ok {
+			sock = a.SSHAgentSocket()
+		}
+		for _, c := range f.Clusters() {
+			for _, m := range c.Machines() {
+				refs = append(refs, machineRef{machine: m, cluster: c, sshAgent: sock})
+			}
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		if a, b := refs[i].cluster.Name(), refs[j].cluster.Name(); a != b {
+			return a < b
+		}
+		return refs[i].machine.ID() < refs[j].machine.ID()
+	})
+	return refs
+}
+
+func (s *Server) lookup(w http.ResponseWriter, r *http.Request) (machineRef, bool) {
+	id := r.PathValue("id")
+	for _, ref := range s.machines() {
+		if ref.machine.ID() == id {
+			return ref, true
+		}
+	}
+	writeError(w, http.StatusNotFound, fmt.Errorf("no machine %q", id))
+	return machineRef{}, false
+}
+
+func (ref machineRef) describe() Machine {
+	m := ref.machine
+	return Machine{
+		ID:          m.ID(),
+		Cluster:     ref.cluster.Name(),
+		Platform:    string(ref.cluster.Platform()),
+		Test:        m.RuntimeConf().TestName,
+		IP:          m.IP(),
+		PrivateIP:   m.PrivateIP(),
+		OutputDir:   filepath.Join(m.RuntimeConf().OutputDir, m.ID()),
+		SSHAuthSock: ref.sshAgent,
+	}
+}
+
+func (s *Server) listClusters(w http.ResponseWriter, r *http.Request) {
+	clusters := []Cluster{}
+	for _, ref := range s.machines() {
+		name := ref.cluster.Name()
+		if len(clusters) == 0 || clusters[len(clusters)-1].Name != name {
+			clusters = append(clusters, Cluster{
+				Name:     name,
+				Platform: string(ref.cluster.Platform()),
+				Machines: []Machine{},
+			})
+		}
+		c := &clusters[len(clusters)-1]
+		c.Machines = append(c.Machines, ref.describe())
+	}
+	writeJSON(w, http.StatusOK, clusters)
+}
+
+func (s *Server) getMachine(w http.ResponseWriter, r *http.Request) {
+	lines := defaultConsoleLines
+	if v := r.URL.Query().Get("lines"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid lines %q", v))
+			return
+		}
+		lines = n
+	}
+	ref, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	desc := ref.describe()
+	if cr, ok := ref.machine.(platform.ConsoleReader); ok {
+		console, err := cr.CurrentConsole()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, errors.Wrapf(err, "reading console of %s", desc.ID))
+			return
+		}
+		tail := tailLines(console, lines)
+		desc.Console = &tail
+	}
+	writeJSON(w, http.StatusOK, desc)
+}
+
+func (s *Server) destroyMachine(w http.ResponseWriter, r *http.Request) {
+	ref, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	plog.Noticef("Destroying %s at the request of the control API", ref.machine.ID())
+	// Go through the cluster so that the harness tearing it down at the
+	// same time doesn't destroy the machine again
+	if c, ok := ref.cluster.(interface{ DestroyMachine(platform.Machine) }); ok {
+		c.DestroyMachine(ref.machine)
+	} else {
+		ref.machine.Destroy()
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) gatherMachine(w http.ResponseWriter, r *http.Request) {
+	if s.gather == nil {
+		writeError(w, http.StatusNotImplemented, errors.New("gathering logs is not supported"))
+		return
+	}
+	ref, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	dir, err := s.gather(ref.machine)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, errors.Wrapf(err, "gathering from %s", ref.machine.ID()))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"dir": dir})
+}
+
+// tailLines returns the last n lines of s.
+func tailLines(s string, n int) string {
+	s = strings.TrimSuffix(s, "\n")
+	if n == 0 || s == "" {
+		return ""
+	}
+	lines := strings.Split(s, "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		plog.Warningf("Writing control API response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,229 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package control
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/coreos/coreos-assembler/mantle/platform"
+)
+
+type fakeMachine struct {
+	platform.Machine
+	id string
+
+	mu        sync.Mutex
+	destroyed int
+}
+
+func (m *fakeMachine) ID() string        { return m.id }
+func (m *fakeMachine) IP() string        { return "127.0.0.1:2222" }
+func (m *fakeMachine) PrivateIP() string { return "10.0.2.15" }
+func (m *fakeMachine) RuntimeConf() platform.RuntimeConfig {
+	return platform.RuntimeConfig{OutputDir: "/out/basic", TestName: "basic"}
+}
+func (m *fakeMachine) CurrentConsole() (string, error) { return "one\ntwo\nthree\n", nil }
+func (m *fakeMachine) Destroy()                        { m.mu.Lock(); m.destroyed++; m.mu.Unlock() }
+func (m *fakeMachine) destroyCount() int               { m.mu.Lock(); defer m.mu.Unlock(); return m.destroyed }
+
+type fakeCluster struct {
+	platform.Cluster
+	machines []platform.Machine
+
+	mu     sync.Mutex
+	routed int
+}
+
+func (c *fakeCluster) DestroyMachine(m platform.Machine) {
+	c.mu.Lock()
+	c.routed++
+	c.mu.Unlock()
+	m.Destroy()
+}
+
+func (c *fakeCluster) Name() string                 { return "kola-test-cluster" }
+func (c *fakeCluster) Platform() platform.Name      { return "qemu" }
+func (c *fakeCluster) Machines() []platform.Machine { return c.machines }
+
+type fakeFlight struct {
+	platform.Flight
+	clusters []platform.Cluster
+}
+
+func (f *fakeFlight) Clusters() []platform.Cluster { return f.clusters }
+
+func newTestServer(t *testing.T) (*Server, *fakeMachine, *fakeCluster) {
+	t.Helper()
+	s, err := Listen("127.0.0.1:0", nil)
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	m := &fakeMachine{id: "m1"}
+	c := &fakeCluster{machines: []platform.Machine{m}}
+	s.AddFlight(&fakeFlight{clusters: []platform.Cluster{c}})
+	return s, m, c
+}
+
+func do(t *testing.T, s *Server, method, path, token string) (*http.Response, map[string]interface{}) {
+	t.Helper()
+	req, err := http.NewRequest(method, s.Info().URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var body map[string]interface{}
+	if resp.StatusCode != http.StatusNoContent && !strings.HasSuffix(path, "/clusters") {
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("decoding %s %s: %v", method, path, err)
+		}
+	}
+	return resp, body
+}
+
+func TestListenLoopbackOnly(t *testing.T) {
+	for _, address := range []string{"0.0.0.0:0", "192.0.2.1:0", ":0", "example.com:0", "127.0.0.1"} {
+		if s, err := Listen(address, nil); err == nil {
+			s.Close()
+			t.Errorf("Listen(%q) succeeded", address)
+		}
+	}
+	for _, address := range []string{"127.0.0.1:0", "localhost:0"} {
+		s, err := Listen(address, nil)
+		if err != nil {
+			t.Errorf("Listen(%q): %v", address, err)
+			continue
+		}
+		s.Close()
+	}
+}
+
+func TestAuthorization(t *testing.T) {
+	s, _, _ := newTestServer(t)
+	for _, token := range []string{"", "wrong", s.Info().Token + "x"} {
+		resp, body := do(t, s, http.MethodGet, "/v1/machines/m1", token)
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("token %q: status %d, want %d", token, resp.StatusCode, http.StatusUnauthorized)
+		}
+		if body["error"] == nil {
+			t.Errorf("token %q: no error in response", token)
+		}
+	}
+	if resp, _ := do(t, s, http.MethodGet, "/v1/machines/m1", s.Info().Token); resp.StatusCode != http.StatusOK {
+		t.Errorf("valid token: status %d", resp.StatusCode)
+	}
+}
+
+func TestListClusters(t *testing.T) {
+	s, _, _ := newTestServer(t)
+	req, _ := http.NewRequest(http.MethodGet, s.Info().URL+"/v1/clusters", nil)
+	req.Header.Set("Authorization", "Bearer "+s.Info().Token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var clusters []Cluster
+	if err := json.NewDecoder(resp.Body).Decode(&clusters); err != nil {
+		t.Fatal(err)
+	}
+	if len(clusters) != 1 || len(clusters[0].Machines) != 1 {
+		t.Fatalf("got %+v", clusters)
+	}
+	m := clusters[0].Machines[0]
+	if m.ID != "m1" || m.Test != "basic" || m.IP != "127.0.0.1:2222" || m.OutputDir != "/out/basic/m1" || m.Console != nil {
+		t.Errorf("got %+v", m)
+	}
+}
+
+func TestGetMachine(t *testing.T) {
+	s, _, _ := newTestServer(t)
+	resp, body := do(t, s, http.MethodGet, "/v1/machines/m1?lines=2", s.Info().Token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+	if body["console"] != "two\nthree\n" {
+		t.Errorf("console %q", body["console"])
+	}
+	if resp, _ := do(t, s, http.MethodGet, "/v1/machines/m1?lines=-1", s.Info().Token); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("negative lines: status %d", resp.StatusCode)
+	}
+}
+
+func TestUnknownMachine(t *testing.T) {
+	s, m, _ := newTestServer(t)
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		resp, body := do(t, s, method, "/v1/machines/nope", s.Info().Token)
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("%s: status %d, want %d", method, resp.StatusCode, http.StatusNotFound)
+		}
+		if body["error"] == nil {
+			t.Errorf("%s: no error in response", method)
+		}
+	}
+	if m.destroyCount() != 0 {
+		t.Errorf("m1 destroyed")
+	}
+}
+
+func TestDestroyMachine(t *testing.T) {
+	s, m, c := newTestServer(t)
+	resp, _ := do(t, s, http.MethodDelete, "/v1/machines/m1", s.Info().Token)
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("status %d, want %d", resp.StatusCode, http.StatusNoContent)
+	}
+	if m.destroyCount() != 1 {
+		t.Errorf("destroyed %d times", m.destroyCount())
+	}
+	if c.routed != 1 {
+		t.Errorf("destroy wasn't routed through the cluster")
+	}
+}
+
+func TestGatherUnsupported(t *testing.T) {
+	s, _, _ := newTestServer(t)
+	if resp, _ := do(t, s, http.MethodPost, "/v1/machines/m1/gather", s.Info().Token); resp.StatusCode != http.StatusNotImplemented {
+		t.Errorf("status %d, want %d", resp.StatusCode, http.StatusNotImplemented)
+	}
+}
+
+func TestTailLines(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		n    int
+		want string
+	}{
+		{"a\nb\nc\n", 2, "b\nc\n"},
+		{"a\nb\nc", 2, "b\nc\n"},
+		{"a\nb\n", 5, "a\nb\n"},
+		{"a\nb\n", 0, ""},
+		{"", 3, ""},
+	} {
+		if got := tailLines(tt.in, tt.n); got != tt.want {
+			t.Errorf("tailLines(%q, %d) = %q, want %q", tt.in, tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kola
+
+import (
+	"errors"
+	"path/filepath"
+
+	"github.com/coreos/coreos-assembler/mantle/kola/control"
+	"github.com/coreos/coreos-assembler/mantle/platform"
+)
+
+// controlServer serves the control API for the flights created while it
+// runs; see StartControl.
+var controlServer *control.Server
+
+// StartControl serves the control API on address, a loopback address such
+// as 127.0.0.1:0, for every flight created until StopControl. Its URL and
+// token are written to infoFile if that is set, or logged otherwise.
+func StartControl(address, infoFile string) error {
+	s, err := control.Listen(address, gatherForControl)
+	if err != nil {
+		return err
+	}
+	if infoFile != "" {
+		if err := s.WriteInfo(infoFile); err != nil {
+			s.Close()
+			return err
+		}
+		plog.Noticef("Control API listening on %s; token in %s", s.Info().URL, infoFile)
+	} else {
+		plog.Noticef("Control API listening on %s with token %s", s.Info().URL, s.Info().Token)
+	}
+	controlServer = s
+	return nil
+}
+
+// StopControl stops serving the control API, if it was started.
+func StopControl() {
+	if controlServer == nil {
+		return
+	}
+	if err := controlServer.Close(); err != nil {
+		plog.Warningf("Stopping control API: %v", err)
+	}
+	controlServer = nil
+}
+
+func gatherForControl(m platform.Machine) (string, error) {
+	cmd := gatherCommand()
+	if cmd == "" {
+		return "", errors.New("no gather command for this distribution; set --gather-command")
+	}
+	if err := gatherFromMachine(m, cmd); err != nil {
+		return "", err
+	}
+	return filepath.Join(m.RuntimeConf().OutputDir, m.ID(), "gather"), nil
+}
@@ -0,0 +1,197 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kola
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/coreos/coreos-assembler/mantle/harness/reporters"
+	"github.com/coreos/coreos-assembler/mantle/harness/testresult"
+)
+
+// durationsWindow is the number of most recent runs a test's mean duration
+// approximately reflects.
+const durationsWindow = 10
+
+// DurationsDB is the path to the historical test-duration database. If
+// empty, DefaultDurationsDBPath() is used.
+var DurationsDB string
+
+// TestDuration is the duration history of a single test.
+type TestDuration struct {
+	Mean    time.Duration `json:"mean"`
+	Last    time.Duration `json:"last"`
+	Samples int           `json:"samples"`
+}
+
+// TestDurations is a persistent record of how long tests took to pass,
+// keyed by platform and then by test name.
+type TestDurations struct {
+	Platforms map[string]map[string]*TestDuration `json:"platforms"`
+}
+
+// DefaultDurationsDBPath returns where the duration database lives when not
+// overridden; this is alongside the default output directories so that it
+// survives between runs.
+func DefaultDurationsDBPath() string {
+	if Options.CosaWorkdir != "" {
+		return filepath.Join(Options.CosaWorkdir, "tmp/kola/test-durations.json")
+	}
+	return filepath.Join("_kola_temp", "test-durations.json")
+}
+
+func durationsDBPath() string {
+	if DurationsDB != "" {
+		return DurationsDB
+	}
+	return DefaultDurationsDBPath()
+}
+
+func newTestDurations() *TestDurations {
+	return &TestDurations{Platforms: make(map[string]map[string]*TestDuration)}
+}
+
+// LoadTestDurations reads the test durations at path, which is either a
+// duration database or a report.json from a previous run, whose passing
+// tests are recorded as a single run on its platform. A missing file is
+// treated as empty.
+func LoadTestDurations(path string) (*TestDurations, error) {
+	db := newTestDurations()
+	buf, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return db, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var report struct {
+		Platform string          `json:"platform"`
+		Tests    json.RawMessage `json:"tests"`
+	}
+	if err := json.Unmarshal(buf, &report); err != nil {
+		return nil, err
+	}
+	if report.Tests != nil {
+		if err := db.RecordReport(report.Platform, path); err != nil {
+			return nil, err
+		}
+		return db, nil
+	}
+	if err := json.Unmarshal(buf, db); err != nil {
+		return nil, err
+	}
+	if db.Platforms == nil {
+		db.Platforms = make(map[string]map[string]*TestDuration)
+	}
+	return db, nil
+}
+
+// Save atomically writes the database to path. Concurrent runs sharing it
+// each write a whole database, so one of their updates wins.
+func (db *TestDurations) Save(path string) error {
+	buf, err := json.MarshalIndent(db, "", "  ")
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(buf); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Estimate returns the expected duration of a test on a platform.
+func (db *TestDurations) Estimate(platform, name string) (time.Duration, bool) {
+	if d, ok := db.Platforms[platform][name]; ok {
+		return d.Mean, true
+	}
+	return 0, false
+}
+
+// LongestEstimate returns the largest expected duration of a test across
+// all platforms.
+func (db *TestDurations) LongestEstimate(name string) (time.Duration, bool) {
+	var ret time.Duration
+	found := false
+	for _, tests := range db.Platforms {
+		if d, ok := tests[name]; ok && d.Mean >= ret {
+			ret = d.Mean
+			found = true
+		}
+	}
+	return ret, found
+}
+
+// Record adds a passing run of a test to its history.
+func (db *TestDurations) Record(platform, name string, d time.Duration) {
+	tests, ok := db.Platforms[platform]
+	if !ok {
+		tests = make(map[string]*TestDuration)
+		db.Platforms[platform] = tests
+	}
+	td, ok := tests[name]
+	if !ok {
+		td = &TestDuration{}
+		tests[name] = td
+	}
+	td.Samples++
+	td.Last = d
+	n := td.Samples
+	if n > durationsWindow {
+		n = durationsWindow
+	}
+	td.Mean += (d - td.Mean) / time.Duration(n)
+}
+
+// RecordReport adds the durations of the tests which passed in a
+// report.json. Non-exclusive tests are recorded under their own name
+// rather than that of their wrapper.
+func (db *TestDurations) RecordReport(platform, path string) error {
+	data, err := reporters.DeserialiseReport(path)
+	if err != nil {
+		return err
+	}
+	for _, test := range data.Tests {
+		name := GetBaseTestName(test.Name)
+		// Skip wrappers as well as native subtests of exclusive tests
+		if name == "" || strings.Contains(name, "/") {
+			continue
+		}
+		if test.Result != testresult.Pass || test.Duration <= 0 {
+			continue
+		}
+		db.Record(platform, name, test.Duration)
+	}
+	return nil
+}
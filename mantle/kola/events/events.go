@@ -0,0 +1,158 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package events emits a machine-readable stream of what kola is doing, so
+// that dashboards and CI pipelines can follow a run live without having to
+// parse its human-oriented output.
+//
+// The stream is newline-delimited JSON, one Event per line, written either
+// to a file or to a listening unix socket.
+package events
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/pkg/capnslog"
+	"github.com/pkg/errors"
+)
+
+var plog = capnslog.NewPackageLogger("github.com/coreos/coreos-assembler/mantle", "kola/events")
+
+// Type identifies what an Event describes.
+type Type string
+
+const (
+	TestStarted   Type = "test-started"
+	TestPassed    Type = "test-passed"
+	TestFailed    Type = "test-failed"
+	TestSkipped   Type = "test-skipped"
+	MachineBooted Type = "machine-booted"
+	SSHCommand    Type = "ssh-command"
+)
+
+// ErrorClass categorizes why a test failed, so that consumers can tell
+// infrastructure problems apart from genuine test failures.
+type ErrorClass string
+
+const (
+	// ErrorCluster means the platform cluster could not be created.
+	ErrorCluster ErrorClass = "cluster"
+	// ErrorMachine means the platform failed to create the machines.
+	ErrorMachine ErrorClass = "machine"
+	// ErrorBoot means the machines never became reachable over SSH.
+	ErrorBoot ErrorClass = "boot"
+	// ErrorSetup means copying kolet or test dependencies failed.
+	ErrorSetup ErrorClass = "setup"
+	// ErrorTimeout means the test exceeded its timeout.
+	ErrorTimeout ErrorClass = "timeout"
+	// ErrorConsole means the test passed, but bad lines were found in
+	// the console or journal afterwards.
+	ErrorConsole ErrorClass = "console"
+	// ErrorTest means the test itself failed.
+	ErrorTest ErrorClass = "test"
+)
+
+// Event is a single entry in the stream. Fields which don't apply to the
+// event's type are omitted.
+type Event struct {
+	Time     time.Time `json:"time"`
+	Type     Type      `json:"type"`
+	Platform string    `json:"platform,omitempty"`
+	Test     string    `json:"test,omitempty"`
+	Machine  string    `json:"machine,omitempty"`
+	Command  string    `json:"command,omitempty"`
+	// Duration is in seconds
+	Duration   float64    `json:"duration,omitempty"`
+	ErrorClass ErrorClass `json:"error-class,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// writeTimeout bounds how long a slow socket reader can stall the run.
+const writeTimeout = 5 * time.Second
+
+var (
+	mu  sync.Mutex
+	out io.WriteCloser
+	enc *json.Encoder
+)
+
+// Open starts emitting events to target, which is either a file path or
+// "unix:" followed by the path of a listening unix socket. Files are
+// appended to.
+func Open(target string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if out != nil {
+		return errors.New("event stream already open")
+	}
+	var w io.WriteCloser
+	if path, ok := strings.CutPrefix(target, "unix:"); ok {
+		conn, err := net.Dial("unix", path)
+		if err != nil {
+			return errors.Wrapf(err, "connecting to event socket %s", path)
+		}
+		w = conn
+	} else {
+		f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return errors.Wrapf(err, "opening event file %s", target)
+		}
+		w = f
+	}
+	out = w
+	enc = json.NewEncoder(w)
+	return nil
+}
+
+// Close stops emitting events.
+func Close() error {
+	mu.Lock()
+	defer mu.Unlock()
+	if out == nil {
+		return nil
+	}
+	err := out.Close()
+	out = nil
+	enc = nil
+	return err
+}
+
+// Emit writes an event to the stream, if one is open. Errors are logged
+// rather than returned; a consumer going away shouldn't affect the run, so
+// the stream is closed after the first failed write.
+func Emit(e Event) {
+	mu.Lock()
+	defer mu.Unlock()
+	if enc == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	if conn, ok := out.(net.Conn); ok {
+		_ = conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	}
+	if err := enc.Encode(e); err != nil {
+		plog.Errorf("Writing to event stream, disabling it: %v", err)
+		out.Close()
+		out = nil
+		enc = nil
+	}
+}
@@ -0,0 +1,327 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kola
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kballard/go-shellquote"
+	"github.com/pkg/errors"
+
+	"github.com/coreos/coreos-assembler/mantle/kola/cluster"
+	"github.com/coreos/coreos-assembler/mantle/platform"
+)
+
+// ExternalProtocolRPC is the version of the external test protocol in which
+// tests can talk to the harness over JSON-RPC while they run, chosen with
+// "protocol": 2 in kola.json. In version 1 tests can only request reboots
+// the autopkgtest way, and otherwise only report their exit code.
+const ExternalProtocolRPC = 2
+
+// kolaRPCSocketEnv points tests using ExternalProtocolRPC at their socket.
+const kolaRPCSocketEnv = "KOLA_RPC_SOCKET"
+
+// maxKoletMessage bounds a line kolet writes to the harness, which may
+// carry a subtest's output.
+const maxKoletMessage = 16 << 20
+
+// The methods tests can call over the RPC channel
+const (
+	// RPCSubtest reports the result of a subtest, with SubtestParams.
+	RPCSubtest = "subtest"
+	// RPCArtifact copies a file from the machine to the test's output
+	// directory, with ArtifactParams.
+	RPCArtifact = "artifact"
+	// RPCReboot reboots the machine, with RebootParams. The call returns
+	// before the reboot, which the test must wait for.
+	RPCReboot = "reboot"
+	// RPCAddDisk attaches a new empty disk to the machine, with
+	// AddDiskParams, returning its device path as "device".
+	RPCAddDisk = "addDisk"
+)
+
+// JSON-RPC 2.0 error codes
+const (
+	RPCErrParse          = -32700
+	RPCErrMethodNotFound = -32601
+	RPCErrInvalidParams  = -32602
+	RPCErrInternal       = -32603
+)
+
+// RPCRequest is a JSON-RPC 2.0 request from a test.
+type RPCRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// RPCResponse is a JSON-RPC 2.0 response to a test.
+type RPCResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// RPCError is the error of a failed RPCRequest.
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// SubtestParams are the parameters of RPCSubtest. Result is "pass", "fail"
+// or "skip", and Output is logged, or is the reason for skipping.
+type SubtestParams struct {
+	Name   string `json:"name"`
+	Result string `json:"result"`
+	Output string `json:"output,omitempty"`
+}
+
+// ArtifactParams are the parameters of RPCArtifact. Name defaults to the
+// base name of Path.
+type ArtifactParams struct {
+	Path string `json:"path"`
+	Name string `json:"name,omitempty"`
+}
+
+// RebootParams are the parameters of RPCReboot. Mark is passed to the
+// test after the reboot as $AUTOPKGTEST_REBOOT_MARK, and KernelArgs are
+// appended to the kernel command line first.
+type RebootParams struct {
+	Mark       string   `json:"mark"`
+	KernelArgs []string `json:"kargs,omitempty"`
+}
+
+// AddDiskParams are the parameters of RPCAddDisk. Size is e.g. "5G".
+type AddDiskParams struct {
+	Size string `json:"size"`
+}
+
+// KoletMessage is a line kolet writes to the harness for a test using
+// ExternalProtocolRPC: either a request for the harness to answer, with
+// an RPCResponse line on kolet's stdin, or kolet's result once it's done.
+type KoletMessage struct {
+	Request *RPCRequest  `json:"request,omitempty"`
+	Result  *KoletResult `json:"result,omitempty"`
+}
+
+// RPCSocketPath returns the socket kolet serves the RPC channel of the test
+// run by unit on.
+func RPCSocketPath(unit string) string {
+	return filepath.Join("/run/kolet", strings.TrimSuffix(unit, ".service")+".sock")
+}
+
+// rpcHandler answers the requests of an external test.
+type rpcHandler struct {
+	c    *cluster.TestCluster
+	mach platform.Machine
+	// exclusive is whether the test has the machine to itself, without
+	// which it can't change the machine
+	exclusive bool
+}
+
+// runKoletRPC runs kolet, answering the requests of the test until kolet
+// exits, and returns kolet's result.
+func runKoletRPC(c *cluster.TestCluster, mach platform.Machine, cmd string, exclusive bool) (KoletResult, error) {
+	var res KoletResult
+	client, err := mach.SSHClient()
+	if err != nil {
+		return res, errors.Wrapf(err, "creating SSH client")
+	}
+	defer client.Close()
+	session, err := client.NewSession()
+	if err != nil {
+		return res, errors.Wrapf(err, "creating SSH session")
+	}
+	defer session.Close()
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		return res, err
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return res, err
+	}
+	var stderr bytes.Buffer
+	session.Stderr = &stderr
+	if err := session.Start(cmd); err != nil {
+		return res, errors.Wrapf(err, "starting kolet")
+	}
+
+	h := rpcHandler{c: c, mach: mach, exclusive: exclusive}
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), maxKoletMessage)
+	for scanner.Scan() {
+		var msg KoletMessage
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			return res, errors.Wrapf(err, "parsing kolet message %s", scanner.Text())
+		}
+		if msg.Result != nil {
+			res = *msg.Result
+		}
+		if msg.Request != nil {
+			buf, err := json.Marshal(h.handle(msg.Request))
+			if err != nil {
+				return res, err
+			}
+			if _, err := stdin.Write(append(buf, '\n')); err != nil {
+				return res, errors.Wrapf(err, "answering kolet")
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return res, errors.Wrapf(err, "reading from kolet")
+	}
+	if err := session.Wait(); err != nil {
+		return res, errors.Wrapf(err, "kolet run-test-unit failed: %s", stderr.String())
+	}
+	return res, nil
+}
+
+func (h *rpcHandler) handle(req *RPCRequest) RPCResponse {
+	resp := RPCResponse{JSONRPC: "2.0", ID: req.ID}
+	var err error
+	switch req.Method {
+	case RPCSubtest:
+		var p SubtestParams
+		if err = decodeParams(req, &p); err == nil {
+			resp.Result, err = h.subtest(p)
+		}
+	case RPCArtifact:
+		var p ArtifactParams
+		if err = decodeParams(req, &p); err == nil {
+			resp.Result, err = h.artifact(p)
+		}
+	case RPCAddDisk:
+		var p AddDiskParams
+		if err = decodeParams(req, &p); err == nil {
+			resp.Result, err = h.addDisk(p)
+		}
+	default:
+		err = &RPCError{Code: RPCErrMethodNotFound, Message: fmt.Sprintf("unknown method %q", req.Method)}
+	}
+	if err != nil {
+		rpcErr, ok := err.(*RPCError)
+		if !ok {
+			rpcErr = &RPCError{Code: RPCErrInternal, Message: err.Error()}
+		}
+		resp.Result = nil
+		resp.Error = rpcErr
+	}
+	return resp
+}
+
+func (e *RPCError) Error() string {
+	return e.Message
+}
+
+func decodeParams(req *RPCRequest, params interface{}) error {
+	if len(req.Params) == 0 {
+		return &RPCError{Code: RPCErrInvalidParams, Message: fmt.Sprintf("%s requires params", req.Method)}
+	}
+	dec := json.NewDecoder(bytes.NewReader(req.Params))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(params); err != nil {
+		return &RPCError{Code: RPCErrInvalidParams, Message: fmt.Sprintf("%s: %v", req.Method, err)}
+	}
+	return nil
+}
+
+func invalidParams(format string, args ...interface{}) error {
+	return &RPCError{Code: RPCErrInvalidParams, Message: fmt.Sprintf(format, args...)}
+}
+
+// subtest reports a subtest of the test with the given result.
+func (h *rpcHandler) subtest(p SubtestParams) (interface{}, error) {
+	if p.Name == "" {
+		return nil, invalidParams("subtest has no name")
+	}
+	if p.Result != "pass" && p.Result != "fail" && p.Result != "skip" {
+		return nil, invalidParams("subtest %s has result %q rather than pass, fail or skip", p.Name, p.Result)
+	}
+	h.c.Run(p.Name, func(c cluster.TestCluster) {
+		switch p.Result {
+		case "fail":
+			if p.Output == "" {
+				p.Output = "failed"
+			}
+			c.Error(p.Output)
+		case "skip":
+			c.Skip(p.Output)
+		default:
+			if p.Output != "" {
+				c.Log(p.Output)
+			}
+		}
+	})
+	return struct{}{}, nil
+}
+
+// artifact copies a file from the machine to the artifacts directory in the
+// test's output directory.
+func (h *rpcHandler) artifact(p ArtifactParams) (interface{}, error) {
+	if !filepath.IsAbs(p.Path) {
+		return nil, invalidParams("artifact path %q isn't absolute", p.Path)
+	}
+	if p.Name == "" {
+		p.Name = filepath.Base(p.Path)
+	}
+	if p.Name != filepath.Base(p.Name) || p.Name == "." || p.Name == ".." {
+		return nil, invalidParams("artifact name %q isn't a file name", p.Name)
+	}
+	dir := filepath.Join(h.c.OutputDir(), "artifacts")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	dest := filepath.Join(dir, p.Name)
+	f, err := os.Create(dest)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var stderr bytes.Buffer
+	if err := platform.RunCommand(context.Background(), h.mach, "sudo cat "+shellquote.Join(p.Path), f, &stderr); err != nil {
+		os.Remove(dest)
+		return nil, fmt.Errorf("copying %s: %v: %s", p.Path, err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return map[string]string{"path": dest}, nil
+}
+
+// addDisk attaches a new disk to the machine, if its platform can.
+func (h *rpcHandler) addDisk(p AddDiskParams) (interface{}, error) {
+	if p.Size == "" {
+		return nil, invalidParams("addDisk requires a size")
+	}
+	if !h.exclusive {
+		return nil, fmt.Errorf("non-exclusive tests can't add disks")
+	}
+	hp, ok := h.mach.(platform.DiskHotplugger)
+	if !ok {
+		return nil, fmt.Errorf("platform %s can't add disks to running machines", h.c.Platform())
+	}
+	device, err := hp.HotplugDisk(p.Size)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"device": device}, nil
+}
@@ -0,0 +1,263 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kola
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/containers/image/v5/docker/reference"
+	coreosarch "github.com/coreos/stream-metadata-go/arch"
+	"github.com/pkg/errors"
+)
+
+// ExternalTestsImagePrefix marks an external test location as a container
+// image rather than a local directory.
+const ExternalTestsImagePrefix = "oci://"
+
+// imageTestsDir is where tests are looked for inside images; it's the same
+// layout as tests installed alongside cosa itself, so images can be built
+// by copying a tests/kola directory there.
+const imageTestsDir = "usr/lib/coreos-assembler"
+
+type ociDescriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+}
+
+type ociIndex struct {
+	Manifests []ociDescriptor `json:"manifests"`
+}
+
+type ociManifest struct {
+	Layers []ociDescriptor `json:"layers"`
+}
+
+// RegisterExternalTestsFromImage pulls a container image, e.g.
+// quay.io/foo/tests:latest, and registers the external tests it carries in
+// /usr/lib/coreos-assembler/tests/kola. Tests are named after the last
+// component of the image's repository, like those in a directory are named
+// after the directory.
+func RegisterExternalTestsFromImage(image string) error {
+	ref, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return errors.Wrapf(err, "parsing image reference %s", image)
+	}
+	ref = reference.TagNameOnly(ref)
+
+	dir := filepath.Join(externalTestsImageCacheDir(), strings.NewReplacer("/", "_", ":", "_", "@", "_").Replace(ref.String()))
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	layout := filepath.Join(dir, "oci")
+	rootfs := filepath.Join(dir, "rootfs")
+	if err := os.MkdirAll(rootfs, 0755); err != nil {
+		return err
+	}
+
+	plog.Noticef("Pulling external tests from %s", ref)
+	args := []string{"copy", "--quiet"}
+	if Options.CosaBuildArch != "" {
+		args = append(args, "--override-arch", coreosarch.GoArch(Options.CosaBuildArch))
+	}
+	args = append(args, "docker://"+ref.String(), "oci:"+layout)
+	cmd := exec.Command("skopeo", args...)
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return errors.Wrapf(err, "pulling %s", ref)
+	}
+
+	if err := extractOCILayout(layout, rootfs); err != nil {
+		return errors.Wrapf(err, "extracting %s", ref)
+	}
+	if _, err := os.Stat(filepath.Join(rootfs, imageTestsDir, "tests/kola")); os.IsNotExist(err) {
+		return fmt.Errorf("image %s has no tests in /%s/tests/kola", ref, imageTestsDir)
+	}
+	prefix := fmt.Sprintf("ext.%s", path.Base(reference.Path(ref)))
+	return RegisterExternalTestsWithPrefix(filepath.Join(rootfs, imageTestsDir), prefix)
+}
+
+// externalTestsImageCacheDir returns where pulled test images are unpacked;
+// they need to stay around for the duration of the run.
+func externalTestsImageCacheDir() string {
+	if Options.CosaWorkdir != "" {
+		return filepath.Join(Options.CosaWorkdir, "tmp/kola/exttest-images")
+	}
+	return filepath.Join("_kola_temp", "exttest-images")
+}
+
+func readOCIBlob(layout, digest string, v interface{}) error {
+	algo, hex, ok := strings.Cut(digest, ":")
+	if !ok {
+		return fmt.Errorf("malformed digest %q", digest)
+	}
+	buf, err := os.ReadFile(filepath.Join(layout, "blobs", algo, hex))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(buf, v)
+}
+
+// extractOCILayout applies the layers of the single image in an OCI layout
+// directory to dest. Only the tests directory is extracted.
+func extractOCILayout(layout, dest string) error {
+	var index ociIndex
+	buf, err := os.ReadFile(filepath.Join(layout, "index.json"))
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(buf, &index); err != nil {
+		return errors.Wrapf(err, "parsing index.json")
+	}
+	if len(index.Manifests) != 1 {
+		return fmt.Errorf("expected one manifest in OCI layout, found %d", len(index.Manifests))
+	}
+	var manifest ociManifest
+	if err := readOCIBlob(layout, index.Manifests[0].Digest, &manifest); err != nil {
+		return errors.Wrapf(err, "reading manifest")
+	}
+	for _, layer := range manifest.Layers {
+		algo, hex, _ := strings.Cut(layer.Digest, ":")
+		if err := extractLayer(filepath.Join(layout, "blobs", algo, hex), layer.MediaType, dest); err != nil {
+			return errors.Wrapf(err, "extracting layer %s", layer.Digest)
+		}
+	}
+	return nil
+}
+
+// extractLayer applies a single layer tarball to dest, honouring whiteouts.
+func extractLayer(blob, mediaType, dest string) error {
+	f, err := os.Open(blob)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	switch {
+	case strings.HasSuffix(mediaType, "+gzip"), strings.HasSuffix(mediaType, ".gzip"):
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	case strings.HasSuffix(mediaType, "+zstd"), strings.HasSuffix(mediaType, ".zstd"):
+		return fmt.Errorf("unsupported layer compression %s", mediaType)
+	}
+
+	wanted := imageTestsDir + "/tests/"
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		name := strings.TrimPrefix(path.Clean("/"+hdr.Name), "/")
+		if !strings.HasPrefix(name+"/", wanted) {
+			continue
+		}
+		target := filepath.Join(dest, name)
+		base := path.Base(name)
+		if base == ".wh..wh..opq" {
+			// Opaque directory: drop what earlier layers put there
+			entries, err := os.ReadDir(filepath.Dir(target))
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			for _, e := range entries {
+				if err := os.RemoveAll(filepath.Join(filepath.Dir(target), e.Name())); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if strings.HasPrefix(base, ".wh.") {
+			if err := os.RemoveAll(filepath.Join(filepath.Dir(target), strings.TrimPrefix(base, ".wh."))); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		mode := os.FileMode(hdr.Mode).Perm()
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, mode|0700); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.RemoveAll(target); err != nil {
+				return err
+			}
+			out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(out, tr); err != nil {
+				out.Close()
+				return err
+			}
+			if err := out.Close(); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			// Only allow links within the tests directory, since they're
+			// resolved on the host
+			resolved := path.Clean(path.Join(path.Dir(name), hdr.Linkname))
+			if path.IsAbs(hdr.Linkname) {
+				resolved = strings.TrimPrefix(path.Clean(hdr.Linkname), "/")
+			}
+			if !strings.HasPrefix(resolved+"/", wanted) {
+				plog.Debugf("Skipping %s in test image: symlink points outside of tests directory", name)
+				continue
+			}
+			linkname, err := filepath.Rel(filepath.Dir(target), filepath.Join(dest, resolved))
+			if err != nil {
+				return err
+			}
+			if err := os.RemoveAll(target); err != nil {
+				return err
+			}
+			if err := os.Symlink(linkname, target); err != nil {
+				return err
+			}
+		case tar.TypeLink:
+			linkname := strings.TrimPrefix(path.Clean("/"+hdr.Linkname), "/")
+			if !strings.HasPrefix(linkname, wanted) {
+				return fmt.Errorf("hardlink %s points outside of tests directory", name)
+			}
+			if err := os.RemoveAll(target); err != nil {
+				return err
+			}
+			if err := os.Link(filepath.Join(dest, linkname), target); err != nil {
+				return err
+			}
+		default:
+			plog.Debugf("Skipping %s in test image: unsupported file type", name)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kola
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/coreos/coreos-assembler/mantle/harness"
+	"github.com/coreos/coreos-assembler/mantle/harness/reporters"
+	"github.com/coreos/coreos-assembler/mantle/harness/testresult"
+	"github.com/coreos/coreos-assembler/mantle/kola/register"
+)
+
+// FlakyTag marks a test as known to fail intermittently. Failures of such
+// tests are retried, and reported as flaked rather than failed if a retry
+// passes.
+const FlakyTag = "flaky"
+
+var (
+	// FlakyTestsFile is a YAML file of test patterns to treat as flaky, in
+	// addition to those tagged with FlakyTag. Defaults to
+	// src/config/kola-flaky.yaml in the workdir.
+	FlakyTestsFile string
+	// FlakyRetries is how many times a failed flaky test is retried
+	FlakyRetries int
+
+	// flakyTests are the patterns parsed from FlakyTestsFile
+	flakyTests []string
+	// retryingFlakyTests is set while retrying, so retries aren't nested
+	retryingFlakyTests bool
+)
+
+type FlakyListObj struct {
+	Pattern   string   `yaml:"pattern"`
+	Tracker   string   `yaml:"tracker"`
+	Arches    []string `yaml:"arches"`
+	Platforms []string `yaml:"platforms"`
+}
+
+// parseFlakyListYaml loads the patterns of tests to treat as flaky on this
+// arch and platform.
+func parseFlakyListYaml(pltfrm string) error {
+	flakyTests = nil
+
+	path := FlakyTestsFile
+	if path == "" {
+		path = filepath.Join(Options.CosaWorkdir, "src/config/kola-flaky.yaml")
+	}
+	buf, err := os.ReadFile(path)
+	if os.IsNotExist(err) && FlakyTestsFile == "" {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	var objs []FlakyListObj
+	if err := yaml.Unmarshal(buf, &objs); err != nil {
+		return err
+	}
+
+	for _, obj := range objs {
+		if len(obj.Arches) > 0 && !HasString(Options.CosaBuildArch, obj.Arches) {
+			continue
+		}
+		if len(obj.Platforms) > 0 && !HasString(pltfrm, obj.Platforms) {
+			continue
+		}
+		plog.Debugf("Will retry failures of kola test pattern %q as flaky", obj.Pattern)
+		if obj.Tracker != "" {
+			plog.Debugf("Flaky test tracker: %s", obj.Tracker)
+		}
+		flakyTests = append(flakyTests, obj.Pattern)
+	}
+	return nil
+}
+
+// isFlaky returns whether failures of the test should be retried.
+func isFlaky(t *register.Test) bool {
+	if HasString(FlakyTag, t.Tags) {
+		return true
+	}
+	for _, pattern := range flakyTests {
+		found, err := filepath.Match(pattern, t.Name)
+		if err != nil {
+			plog.Fatal(err)
+		}
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+// reportResults returns the result of each test in a report.json, keyed by
+// test name. Non-exclusive tests are keyed by their own name rather than
+// that of their wrapper.
+func reportResults(path string) (map[string]testresult.TestResult, error) {
+	data, err := reporters.DeserialiseReport(path)
+	if err != nil {
+		return nil, err
+	}
+	ret := make(map[string]testresult.TestResult)
+	for _, test := range data.Tests {
+		name := GetBaseTestName(test.Name)
+		if name == "" || strings.Contains(name, "/") {
+			continue
+		}
+		ret[name] = test.Result
+	}
+	return ret, nil
+}
+
+// retryFlakyTests reruns failed flaky tests up to FlakyRetries times each.
+// Tests that eventually pass are reported as flaked in the run's
+// report.json; if that accounts for all of the failures, the run is
+// considered to have passed. Returns the names of the tests that flaked,
+// along with the updated run error.
+func retryFlakyTests(testsBank map[string]*register.Test, multiply int, pltfrm, outputDir string, runErr error) (map[string]bool, error) {
+	if runErr != harness.SuiteFailed || FlakyRetries <= 0 || retryingFlakyTests {
+		return nil, runErr
+	}
+	toRetry := make(map[string]*register.Test)
+	for name, t := range getRerunnable(testsBank, testResults.getResults()) {
+		if isFlaky(t) {
+			toRetry[name] = t
+		}
+	}
+	if len(toRetry) == 0 {
+		return nil, runErr
+	}
+
+	retryingFlakyTests = true
+	defer func() { retryingFlakyTests = false }()
+
+	flaked := make(map[string]bool)
+	for attempt := 1; attempt <= FlakyRetries && len(toRetry) > 0; attempt++ {
+		fmt.Printf("\n\n======== Retrying flaky tests (attempt %d of %d) ========\n\n", attempt, FlakyRetries)
+		retryDir := filepath.Join(outputDir, fmt.Sprintf("flaky-retry-%d", attempt))
+		// The outcome is read back from the report below
+		_ = runProvidedTests(toRetry, []string{"*"}, multiply, false, nil, pltfrm, retryDir, "")
+		results, err := reportResults(filepath.Join(retryDir, "reports", "report.json"))
+		if err != nil {
+			plog.Errorf("Reading results of flaky test retry: %v", err)
+			break
+		}
+		for name := range toRetry {
+			if results[name] == testresult.Pass {
+				flaked[name] = true
+				delete(toRetry, name)
+			}
+		}
+	}
+	if len(flaked) == 0 {
+		return nil, runErr
+	}
+
+	var names []string
+	for name := range flaked {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Printf("⚠️  Flaky tests which passed on retry: %s\n", strings.Join(names, ", "))
+
+	isFlaked := func(name string) bool {
+		// Native subtests of exclusive tests flake along with their parent
+		base, _, _ := strings.Cut(GetBaseTestName(name), "/")
+		return flaked[base]
+	}
+	reportDir := filepath.Join(outputDir, "reports")
+	failed, err := reporters.MarkFlaked(filepath.Join(reportDir, "report.json"), isFlaked)
+	if err != nil {
+		plog.Errorf("Marking flaked tests in report: %v", err)
+		return flaked, runErr
+	}
+	if err := reporters.Regenerate(reportDir, ReportFormats); err != nil {
+		plog.Errorf("Updating reports with flaked tests: %v", err)
+	}
+	if !failed {
+		return flaked, nil
+	}
+	return flaked, runErr
+}
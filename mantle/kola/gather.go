@@ -0,0 +1,101 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kola
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/kballard/go-shellquote"
+
+	"github.com/coreos/coreos-assembler/mantle/platform"
+)
+
+const (
+	// gatherDir is where gather commands leave what they collect.
+	gatherDir = "/var/tmp/kola-gather"
+
+	// gatherTimeout bounds a gather command; sos report takes a few
+	// minutes on a healthy machine.
+	gatherTimeout = 15 * time.Minute
+)
+
+// GatherCommands are the commands run on the machines of failed tests
+// with --gather-on-failure, by distro. They run as the SSH user, and must
+// leave whatever they collect in $KOLA_GATHER_DIR, which exists and is
+// empty.
+var GatherCommands = map[string]string{
+	// Fedora CoreOS doesn't ship sos, so run it from a toolbox image
+	// against the host, as support would.
+	"fcos":  `sudo podman run --rm --privileged --net=host --pid=host --ipc=host -v /:/host -v "$KOLA_GATHER_DIR":/gather registry.fedoraproject.org/fedora-toolbox sh -c 'dnf -qy install sos && sos report --batch --quiet --sysroot /host --tmp-dir /gather'`,
+	"rhcos": `sudo sos report --batch --quiet --tmp-dir "$KOLA_GATHER_DIR"`,
+	"scos":  `sudo sos report --batch --quiet --tmp-dir "$KOLA_GATHER_DIR"`,
+}
+
+// gatherCommand returns the gather command for the distro being tested,
+// or "" if there is none.
+func gatherCommand() string {
+	if GatherCommand != "" {
+		return GatherCommand
+	}
+	return GatherCommands[Options.Distribution]
+}
+
+// gatherOnFailure runs the gather command on each of the machines and
+// copies what it collects to the gather directory in the machine's output
+// directory.
+func gatherOnFailure(machines []platform.Machine) {
+	cmd := gatherCommand()
+	if cmd == "" {
+		plog.Warningf("No gather command for distro %q; use --gather-command", Options.Distribution)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, m := range machines {
+		wg.Add(1)
+		go func(m platform.Machine) {
+			defer wg.Done()
+			if err := gatherFromMachine(m, cmd); err != nil {
+				plog.Errorf("Gathering diagnostics from %s: %v", m.ID(), err)
+			}
+		}(m)
+	}
+	wg.Wait()
+}
+
+func gatherFromMachine(m platform.Machine, cmd string) error {
+	plog.Infof("Gathering diagnostics from %s", m.ID())
+	dir := shellquote.Join(gatherDir)
+	script := fmt.Sprintf("sudo rm -rf %[1]s && mkdir -p %[1]s && export KOLA_GATHER_DIR=%[1]s && %[2]s", dir, cmd)
+
+	var stderr bytes.Buffer
+	ctx, cancel := context.WithTimeout(context.Background(), gatherTimeout)
+	defer cancel()
+	if err := platform.RunCommand(ctx, m, script, nil, &stderr); err != nil {
+		return fmt.Errorf("%v: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	dest := filepath.Join(m.RuntimeConf().OutputDir, m.ID(), "gather")
+	if err := m.Download(gatherDir, dest); err != nil {
+		return err
+	}
+	plog.Infof("Saved diagnostics from %s to %s", m.ID(), dest)
+	return nil
+}
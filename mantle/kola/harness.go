@@ -23,6 +23,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -34,27 +35,42 @@ import (
 	"gopkg.in/yaml.v2"
 
 	"github.com/coreos/coreos-assembler/mantle/harness"
+	"github.com/coreos/coreos-assembler/mantle/harness/artifacts"
 	"github.com/coreos/coreos-assembler/mantle/harness/reporters"
 	"github.com/coreos/coreos-assembler/mantle/kola/cluster"
+	"github.com/coreos/coreos-assembler/mantle/kola/events"
 	"github.com/coreos/coreos-assembler/mantle/kola/register"
 	"github.com/coreos/coreos-assembler/mantle/network"
 	"github.com/coreos/coreos-assembler/mantle/platform"
 	awsapi "github.com/coreos/coreos-assembler/mantle/platform/api/aws"
 	azureapi "github.com/coreos/coreos-assembler/mantle/platform/api/azure"
 	doapi "github.com/coreos/coreos-assembler/mantle/platform/api/do"
+	equinixapi "github.com/coreos/coreos-assembler/mantle/platform/api/equinix"
 	esxapi "github.com/coreos/coreos-assembler/mantle/platform/api/esx"
 	gcloudapi "github.com/coreos/coreos-assembler/mantle/platform/api/gcloud"
+	hetznerapi "github.com/coreos/coreos-assembler/mantle/platform/api/hetzner"
+	ibmcloudapi "github.com/coreos/coreos-assembler/mantle/platform/api/ibmcloud"
+	ociapi "github.com/coreos/coreos-assembler/mantle/platform/api/oci"
 	openstackapi "github.com/coreos/coreos-assembler/mantle/platform/api/openstack"
+	vultrapi "github.com/coreos/coreos-assembler/mantle/platform/api/vultr"
 	"github.com/coreos/coreos-assembler/mantle/platform/conf"
 	"github.com/coreos/coreos-assembler/mantle/platform/machine/aws"
 	"github.com/coreos/coreos-assembler/mantle/platform/machine/azure"
 	"github.com/coreos/coreos-assembler/mantle/platform/machine/do"
+	"github.com/coreos/coreos-assembler/mantle/platform/machine/equinix"
 	"github.com/coreos/coreos-assembler/mantle/platform/machine/esx"
+	"github.com/coreos/coreos-assembler/mantle/platform/machine/external"
 	"github.com/coreos/coreos-assembler/mantle/platform/machine/gcloud"
+	"github.com/coreos/coreos-assembler/mantle/platform/machine/hetzner"
+	"github.com/coreos/coreos-assembler/mantle/platform/machine/oci"
 	"github.com/coreos/coreos-assembler/mantle/platform/machine/openstack"
+	"github.com/coreos/coreos-assembler/mantle/platform/machine/powervs"
 	"github.com/coreos/coreos-assembler/mantle/platform/machine/qemu"
 	"github.com/coreos/coreos-assembler/mantle/platform/machine/qemuiso"
+	"github.com/coreos/coreos-assembler/mantle/platform/machine/reuse"
+	"github.com/coreos/coreos-assembler/mantle/platform/machine/vultr"
 	"github.com/coreos/coreos-assembler/mantle/system"
+	"github.com/coreos/coreos-assembler/mantle/system/cleanup"
 	"github.com/coreos/coreos-assembler/mantle/util"
 )
 
@@ -78,6 +94,11 @@ const InstalledTestDefaultTest = "test.sh"
 // Specifying this in the tags list is required to denote a need for Internet access
 const NeedsInternetTag = "needs-internet"
 
+// OfflineTag marks tests whose machines must not try to reach the Internet,
+// as if air-gapped; on QEMU their traffic is audited and any attempt fails
+// the test.
+const OfflineTag = "offline"
+
 // PlatformIndependentTag is currently equivalent to platform: qemu, but that may change in the future.
 // For more, see the doc in external-tests.md.
 const PlatformIndependentTag = "platform-independent"
@@ -110,20 +131,54 @@ var (
 	AWSOptions       = awsapi.Options{Options: &Options}       // glue to set platform options from main
 	AzureOptions     = azureapi.Options{Options: &Options}     // glue to set platform options from main
 	DOOptions        = doapi.Options{Options: &Options}        // glue to set platform options from main
+	EquinixOptions   = equinixapi.Options{Options: &Options}   // glue to set platform options from main
 	ESXOptions       = esxapi.Options{Options: &Options}       // glue to set platform options from main
+	ExternalOptions  = external.Options{Options: &Options}     // glue to set platform options from main
 	GCPOptions       = gcloudapi.Options{Options: &Options}    // glue to set platform options from main
+	HetznerOptions   = hetznerapi.Options{Options: &Options}   // glue to set platform options from main
+	OCIOptions       = ociapi.Options{Options: &Options}       // glue to set platform options from main
 	OpenStackOptions = openstackapi.Options{Options: &Options} // glue to set platform options from main
+	PowerVSOptions   = ibmcloudapi.Options{Options: &Options}  // glue to set platform options from main
 	QEMUOptions      = qemu.Options{Options: &Options}         // glue to set platform options from main
+	ReuseOptions     = reuse.Options{Options: &Options}        // glue to set platform options from main
 	QEMUIsoOptions   = qemuiso.Options{Options: &Options}      // glue to set platform options from main
+	VultrOptions     = vultrapi.Options{Options: &Options}     // glue to set platform options from main
 
 	CosaBuild *util.LocalBuild // this is a parsed cosa build
-
-	TestParallelism int    //glue var to set test parallelism from main
-	TAPFile         string // if not "", write TAP results here
-	NoNet           bool   // Disable tests requiring Internet
+	// ImageCache keeps decompressed copies of CosaBuild's compressed images
+	ImageCache *util.ImageCache
+
+	TestParallelism int      //glue var to set test parallelism from main
+	TAPFile         string   // if not "", write TAP results here
+	ReportFormats   []string // formats to write reports in besides report.json
+	NoNet           bool     // Disable tests requiring Internet
+	// AutoParallelism is whether TestParallelism was chosen by kola rather
+	// than the user, in which case it's lowered to what the tests can use.
+	AutoParallelism bool
 	// ForceRunPlatformIndependent will cause tests that claim platform-independence to run
 	ForceRunPlatformIndependent bool
 
+	// FailureBundles gathers diagnostics from the machines of failed tests
+	FailureBundles bool
+	// StreamConsoles logs the console lines of tests' machines with their
+	// output as they appear
+	StreamConsoles bool
+	// GatherOnFailure runs the distro's entry in GatherCommands, or
+	// GatherCommand if set, on the machines of failed tests
+	GatherOnFailure bool
+	GatherCommand   string
+	// RegistryTLS and RegistryAuth make the registry fixture serve HTTPS
+	// and require credentials
+	RegistryTLS  bool
+	RegistryAuth bool
+	// RegistrySeeds are OCI archives, as REPO[:TAG]=PATH, imported into
+	// the registry fixture
+	RegistrySeeds []string
+
+	// Artifacts, if set, decides which of the files tests leave in the
+	// output directory are kept
+	Artifacts *artifacts.Manager
+
 	// SkipConsoleWarnings is set via SkipConsoleWarningsTag in kola-denylist.yaml
 	SkipConsoleWarnings bool
 	DenylistedTests     []string // tests which are on the denylist
@@ -132,6 +187,13 @@ var (
 
 	// Sharding is a string of the form: hash:m/n where m and n are integers to run only tests which hash to m.
 	Sharding string
+	// Shard is a string of the form: n/m where n and m are integers to run only the n-th of m
+	// partitions of the selected tests, balanced by ShardDurations.
+	Shard string
+	// ShardDurations is the path to a duration database or a report.json from a previous run used
+	// to estimate test durations when partitioning for Shard. All shards of a run must be given the
+	// same file.
+	ShardDurations string
 
 	extTestNum  = 1 // Assigns a unique number to each non-exclusive external test
 	testResults protectedTestResults
@@ -256,6 +318,10 @@ const (
 // KoletResult is serialized JSON passed from kolet to the harness
 type KoletResult struct {
 	Reboot string
+	// HarnessReboot is set for reboots requested over the RPC channel,
+	// which the harness performs itself rather than acknowledging for the
+	// test to
+	HarnessReboot bool `json:",omitempty"`
 }
 
 const KoletExtTestUnit = "kola-runext"
@@ -286,6 +352,14 @@ func (p *protectedTestResults) getResults() []*harness.H {
 type NativeRunner func(funcName string, m platform.Machine) error
 
 func NewFlight(pltfrm string) (flight platform.Flight, err error) {
+	// An external driver takes precedence, so that it can also stand in
+	// for a built-in platform
+	if ExternalOptions.Driver != "" {
+		return external.NewFlight(&ExternalOptions, pltfrm)
+	}
+	if err := loadInstanceProfiles(pltfrm); err != nil {
+		return nil, err
+	}
 	switch pltfrm {
 	case "aws":
 		flight, err = aws.NewFlight(&AWSOptions)
@@ -293,19 +367,34 @@ func NewFlight(pltfrm string) (flight platform.Flight, err error) {
 		flight, err = azure.NewFlight(&AzureOptions)
 	case "do":
 		flight, err = do.NewFlight(&DOOptions)
+	case "equinix":
+		flight, err = equinix.NewFlight(&EquinixOptions)
 	case "esx":
 		flight, err = esx.NewFlight(&ESXOptions)
 	case "gcp":
 		flight, err = gcloud.NewFlight(&GCPOptions)
+	case "hetzner":
+		flight, err = hetzner.NewFlight(&HetznerOptions)
+	case "oci":
+		flight, err = oci.NewFlight(&OCIOptions)
 	case "openstack":
 		flight, err = openstack.NewFlight(&OpenStackOptions)
+	case "powervs":
+		flight, err = powervs.NewFlight(&PowerVSOptions)
 	case "qemu":
 		flight, err = qemu.NewFlight(&QEMUOptions)
 	case "qemu-iso":
 		flight, err = qemuiso.NewFlight(&QEMUIsoOptions)
+	case "reuse":
+		flight, err = reuse.NewFlight(&ReuseOptions)
+	case "vultr":
+		flight, err = vultr.NewFlight(&VultrOptions)
 	default:
 		err = fmt.Errorf("invalid platform %q", pltfrm)
 	}
+	if err == nil && controlServer != nil {
+		controlServer.AddFlight(flight)
+	}
 	return
 }
 
@@ -341,6 +430,30 @@ func testRequiresInternet(test *register.Test) bool {
 	return HasString(NeedsInternetTag, test.Tags)
 }
 
+// checkEgress fails the test if its destroyed machines tried to reach the
+// Internet; machines whose traffic isn't captured can't be checked.
+func checkEgress(h *harness.H, machines []platform.Machine) {
+	for _, m := range machines {
+		auditor, ok := m.(platform.EgressAuditor)
+		if !ok {
+			plog.Debugf("Can't audit egress of machine %s", m.ID())
+			continue
+		}
+		attempts, err := auditor.EgressAttempts()
+		if err != nil {
+			h.Errorf("Auditing egress of machine %s: %v", m.ID(), err)
+			continue
+		}
+		for _, a := range attempts {
+			h.Errorf("Machine %s tried to reach the Internet: %s", m.ID(), a)
+		}
+	}
+}
+
+func testOffline(test *register.Test) bool {
+	return HasString(OfflineTag, test.Tags)
+}
+
 func testSecureBoot(test *register.Test) bool {
 	return HasString(secureBoot, test.Tags)
 }
@@ -586,7 +699,7 @@ func filterTests(tests map[string]*register.Test, patterns []string, pltfrm stri
 		// But in the future, we should optimize this so that an overall
 		// test planner/scheduler knows to run the test at most once or twice.
 		// Platform independent tests could also run on AWS sometimes for example.
-		if !ForceRunPlatformIndependent {
+		if !ForceRunPlatformIndependent && pltfrm != "reuse" {
 			for _, tag := range t.Tags {
 				if tag == PlatformIndependentTag {
 					t.Platforms = []string{defaultPlatformIndependentPlatform}
@@ -612,7 +725,12 @@ func filterTests(tests map[string]*register.Test, patterns []string, pltfrm stri
 		if allowed, excluded := isAllowed(Options.Distribution, t.Distros, t.ExcludeDistros); !allowed || excluded {
 			continue
 		}
-		if pltfrm == "qemu" {
+		if pltfrm == "reuse" && !reusable(t) {
+			plog.Debugf("Skipping test that can't run on a reused machine: %s", t.Name)
+			continue
+		}
+		// tests with a firmware matrix choose their firmwares themselves
+		if pltfrm == "qemu" && len(t.FirmwareMatrix) == 0 {
 			if allowed, excluded := isAllowed(QEMUOptions.Firmware, t.Firmwares, t.ExcludeFirmwares); !allowed || excluded {
 				continue
 			}
@@ -637,6 +755,13 @@ func filterTests(tests map[string]*register.Test, patterns []string, pltfrm stri
 	return r, nil
 }
 
+// reusable returns whether the test can run on a reused machine, which
+// only non-exclusive tests that don't configure the machine nor need
+// fixtures on the host can, so that they don't disturb it.
+func reusable(t *register.Test) bool {
+	return t.NonExclusive && t.UserData == nil && len(t.Fixtures) == 0
+}
+
 func filterDenylistedTests(tests map[string]*register.Test) (map[string]*register.Test, error) {
 	r := make(map[string]*register.Test)
 	for name, t := range tests {
@@ -688,7 +813,7 @@ func filterDenylistedTests(tests map[string]*register.Test) (map[string]*registe
 // register tests in their init() function.  outputDir is where various test
 // logs and data will be written for analysis after the test run. If it already
 // exists it will be erased!
-func runProvidedTests(testsBank map[string]*register.Test, patterns []string, multiply int, rerun bool, rerunSuccessTags []string, pltfrm, outputDir string) error {
+func runProvidedTests(testsBank map[string]*register.Test, patterns []string, multiply int, rerun bool, rerunSuccessTags []string, pltfrm, outputDir, shard string) error {
 	var versionStr string
 
 	// Avoid incurring cost of starting machine in getClusterSemver when
@@ -697,9 +822,18 @@ func runProvidedTests(testsBank map[string]*register.Test, patterns []string, mu
 	// 2) glob is an exact match which means minVersion will be ignored
 	//    either way
 
-	// Add denylisted tests in kola-denylist.yaml to DenylistedTests
-	err := ParseDenyListYaml(pltfrm)
-	if err != nil {
+	// Add denylisted tests in kola-denylist.yaml to DenylistedTests,
+	// unless repeating a run which already did
+	var err error
+	if FromManifest != nil {
+		FromManifest.applyPolicies()
+	} else if err = ParseDenyListYaml(pltfrm); err != nil {
+		plog.Fatal(err)
+	}
+	if err := parseFlakyListYaml(pltfrm); err != nil {
+		plog.Fatal(err)
+	}
+	if err := parseWatchdogPolicy(); err != nil {
 		plog.Fatal(err)
 	}
 
@@ -733,10 +867,44 @@ func runProvidedTests(testsBank map[string]*register.Test, patterns []string, mu
 		return nil
 	}
 
+	// Partition before bucketing non-exclusive tests, since which tests end
+	// up in which bucket isn't stable across runs.
+	if shard != "" {
+		if Sharding != "" {
+			plog.Fatalf("--shard and --sharding are mutually exclusive")
+		}
+		durations := newTestDurations()
+		if ShardDurations != "" {
+			if _, err := os.Stat(ShardDurations); err != nil {
+				plog.Fatalf("Loading test durations: %v", err)
+			}
+			if durations, err = LoadTestDurations(ShardDurations); err != nil {
+				plog.Fatalf("Loading test durations: %v", err)
+			}
+		}
+		tests, err = partitionTests(tests, shard, durations, pltfrm)
+		if err != nil {
+			plog.Fatalf("%v", err)
+		}
+		if len(tests) == 0 {
+			fmt.Printf("There are no tests to run in shard %s. Output in %v\n", shard, outputDir)
+			return nil
+		}
+	}
+
+	selected := make([]string, 0, len(tests))
+	for name := range tests {
+		selected = append(selected, name)
+	}
+
 	flight, err := NewFlight(pltfrm)
 	if err != nil {
 		plog.Fatalf("Flight failed: %v", err)
 	}
+	if arch := Options.CosaBuildArch; !flight.Capabilities().SupportsArch(arch) {
+		flight.Destroy()
+		plog.Fatalf("Platform %s doesn't support architecture %s", pltfrm, arch)
+	}
 	defer flight.Destroy()
 	// Generate non-exclusive test wrapper (run multiple tests in one VM)
 	var nonExclusiveTests []*register.Test
@@ -750,12 +918,17 @@ func runProvidedTests(testsBank map[string]*register.Test, patterns []string, mu
 		}
 	}
 
+	var buckets [][]*register.Test
 	if len(nonExclusiveTests) == 1 {
 		// If there is only one test then it can just be run by itself
 		// so add it back to the tests map.
 		tests[nonExclusiveTests[0].Name] = nonExclusiveTests[0]
 	} else if len(nonExclusiveTests) > 0 {
-		buckets := createTestBuckets(nonExclusiveTests)
+		if FromManifest != nil {
+			buckets = manifestBuckets(nonExclusiveTests)
+		} else {
+			buckets = createTestBuckets(nonExclusiveTests)
+		}
 		numBuckets := len(buckets)
 		for i := 0; i < numBuckets; {
 			// This test does not need to be registered since it is temporarily
@@ -807,14 +980,17 @@ func runProvidedTests(testsBank map[string]*register.Test, patterns []string, mu
 		plog.Fatalf("%v", err)
 	}
 
+	reps, err := reporters.ForFormats(ReportFormats, pltfrm, versionStr)
+	if err != nil {
+		return err
+	}
 	opts := harness.Options{
 		OutputDir: outputDir,
 		Parallel:  TestParallelism,
 		Sharding:  Sharding,
 		Verbose:   true,
-		Reporters: reporters.Reporters{
-			reporters.NewJSONReporter("report.json", pltfrm, versionStr),
-		},
+		Reporters: reps,
+		Artifacts: Artifacts,
 	}
 
 	var htests harness.Tests
@@ -832,6 +1008,21 @@ func runProvidedTests(testsBank map[string]*register.Test, patterns []string, mu
 		htests.Add(test.Name, run, (test.Timeout*time.Duration(100+(Options.ExtendTimeoutPercent)))/100)
 	}
 
+	// Start the longest tests first, and don't bring up more machines than
+	// the longest test leaves room for.
+	durations, err := LoadTestDurations(durationsDBPath())
+	if err != nil {
+		plog.Warningf("Ignoring test durations: %v", err)
+	} else {
+		estimates := estimateTestDurations(tests, durations, pltfrm)
+		for name, d := range estimates {
+			htests.SetEstimate(name, d)
+		}
+		if AutoParallelism && len(estimates) == len(tests) {
+			opts.Parallel = sizeParallelism(opts.Parallel, estimates)
+		}
+	}
+
 	handleSuiteErrors := func(outputDir string, suiteErr error) error {
 		caughtTestError := suiteErr != nil
 
@@ -854,16 +1045,41 @@ func runProvidedTests(testsBank map[string]*register.Test, patterns []string, mu
 
 	suite := harness.NewSuite(opts, htests)
 	runErr := suite.Run()
+	// needs to be after the suite runs because it empties the directory
+	if err := writeRunManifest(outputDir, pltfrm, selected, buckets); err != nil {
+		plog.Warningf("Writing run manifest: %v", err)
+	}
+	flaked, runErr := retryFlakyTests(testsBank, multiply, pltfrm, outputDir, runErr)
+	classifyFailures(filepath.Join(outputDir, "reports"))
 	runErr = handleSuiteErrors(outputDir, runErr)
 
+	// Renamed tests from --multiply would only clutter the history
+	if durations != nil && multiply <= 1 {
+		if err := durations.RecordReport(pltfrm, filepath.Join(outputDir, "reports", "report.json")); err != nil {
+			plog.Warningf("Recording test durations: %v", err)
+		} else if err := durations.Save(durationsDBPath()); err != nil {
+			plog.Warningf("Saving test durations: %v", err)
+		}
+	}
+
 	detectedFailedWarnTrueTests := len(getWarnTrueFailedTests(testResults.getResults())) != 0
 
 	testsToRerun := getRerunnable(testsBank, testResults.getResults())
-	numFailedTests := len(testsToRerun)
+	numFailedTests := 0
+	for name, t := range testsToRerun {
+		if !flaked[name] {
+			numFailedTests++
+		}
+		// Flaky tests have already been retried
+		if FlakyRetries > 0 && isFlaky(t) {
+			delete(testsToRerun, name)
+		}
+	}
 	if len(testsToRerun) > 0 && rerun {
 		newOutputDir := filepath.Join(outputDir, "rerun")
 		fmt.Printf("\n\n======== Re-running failed tests (flake detection) ========\n\n")
-		reRunErr := runProvidedTests(testsToRerun, []string{"*"}, multiply, false, rerunSuccessTags, pltfrm, newOutputDir)
+		// testsToRerun are already limited to this shard
+		reRunErr := runProvidedTests(testsToRerun, []string{"*"}, multiply, false, rerunSuccessTags, pltfrm, newOutputDir, "")
 		if reRunErr == nil && allTestsAllowRerunSuccess(testsToRerun, rerunSuccessTags) {
 			runErr = nil       // reset to success since all tests allowed rerun success
 			numFailedTests = 0 // zero out the tally of failed tests
@@ -881,6 +1097,25 @@ func runProvidedTests(testsBank map[string]*register.Test, patterns []string, mu
 	}
 }
 
+// classifyFailures records in the report why each failed test is believed
+// to have failed, and prints how many failed for each reason.
+func classifyFailures(reportDir string) {
+	counts, err := reporters.ClassifyFailures(reportDir)
+	if err != nil {
+		plog.Warningf("Classifying test failures: %v", err)
+		return
+	}
+	var summary []string
+	for _, class := range []reporters.FailureClass{reporters.ClassProduct, reporters.ClassInfrastructure, reporters.ClassTest} {
+		if counts[class] > 0 {
+			summary = append(summary, fmt.Sprintf("%d %s", counts[class], class))
+		}
+	}
+	if len(summary) > 0 {
+		fmt.Printf("Failures by suspected cause: %s\n", strings.Join(summary, ", "))
+	}
+}
+
 func getWarnTrueFailedTests(tests []*harness.H) []string {
 	var warnTrueFailedTests []string
 	for _, test := range tests {
@@ -1007,11 +1242,11 @@ func getRerunnable(testsBank map[string]*register.Test, testResults []*harness.H
 }
 
 func RunTests(patterns []string, multiply int, rerun bool, rerunSuccessTags []string, pltfrm, outputDir string) error {
-	return runProvidedTests(register.Tests, patterns, multiply, rerun, rerunSuccessTags, pltfrm, outputDir)
+	return runProvidedTests(register.Tests, patterns, multiply, rerun, rerunSuccessTags, pltfrm, outputDir, Shard)
 }
 
 func RunUpgradeTests(patterns []string, rerun bool, pltfrm, outputDir string) error {
-	return runProvidedTests(register.UpgradeTests, patterns, 0, rerun, nil, pltfrm, outputDir)
+	return runProvidedTests(register.UpgradeTests, patterns, 0, rerun, nil, pltfrm, outputDir, Shard)
 }
 
 // externalTestMeta is parsed from kola.json in external tests
@@ -1025,6 +1260,8 @@ type externalTestMeta struct {
 	PrimaryDisk               string   `json:"primaryDisk,omitempty"               yaml:"primaryDisk,omitempty"`
 	InjectContainer           bool     `json:"injectContainer,omitempty"           yaml:"injectContainer,omitempty"`
 	MinMemory                 int      `json:"minMemory,omitempty"                 yaml:"minMemory,omitempty"`
+	MinCPUs                   int      `json:"minCPUs,omitempty"                   yaml:"minCPUs,omitempty"`
+	NestedVirt                bool     `json:"nestedVirt,omitempty"                yaml:"nestedVirt,omitempty"`
 	MinDiskSize               int      `json:"minDisk,omitempty"                   yaml:"minDisk,omitempty"`
 	AdditionalNics            int      `json:"additionalNics,omitempty"            yaml:"additionalNics,omitempty"`
 	AppendKernelArgs          string   `json:"appendKernelArgs,omitempty"          yaml:"appendKernelArgs,omitempty"`
@@ -1035,7 +1272,11 @@ type externalTestMeta struct {
 	AllowConfigWarnings       bool     `json:"allowConfigWarnings"                 yaml:"allowConfigWarnings"`
 	NoInstanceCreds           bool     `json:"noInstanceCreds"                     yaml:"noInstanceCreds"`
 	InstanceType              string   `json:"instanceType"                        yaml:"instanceType"`
+	Protocol                  int      `json:"protocol,omitempty"                  yaml:"protocol,omitempty"`
+	Firmwares                 []string `json:"firmwares,omitempty"                 yaml:"firmwares,omitempty"`
 	Description               string   `json:"description"                         yaml:"description"`
+
+	PerformanceProfile *platform.PerformanceProfile `json:"performanceProfile,omitempty" yaml:"performanceProfile,omitempty"`
 }
 
 // metadataFromTestBinary extracts JSON-in-comment like:
@@ -1098,7 +1339,7 @@ func metadataFromTestBinary(executable string) (*externalTestMeta, error) {
 // runExternalTest is an implementation of the "external" test framework.
 // See README-kola-ext.md as well as the comments in kolet.go for reboot
 // handling.
-func runExternalTest(c cluster.TestCluster, mach platform.Machine, testNum int) error {
+func runExternalTest(c cluster.TestCluster, mach platform.Machine, testNum, protocol int) error {
 	var previousRebootState string
 	for {
 		bootID, err := platform.GetMachineBootId(mach)
@@ -1115,26 +1356,35 @@ func runExternalTest(c cluster.TestCluster, mach platform.Machine, testNum int)
 			}
 		}
 
-		var cmd string
+		args := []string{"sudo", "/usr/local/bin/kolet", "run-test-unit"}
+		unit := fmt.Sprintf("%s.service", KoletExtTestUnit)
 		if testNum != 0 {
 			// This is a non-exclusive test
-			unit := fmt.Sprintf("%s-%d.service", KoletExtTestUnit, testNum)
+			unit = fmt.Sprintf("%s-%d.service", KoletExtTestUnit, testNum)
 			// Reboot requests are disabled for non-exclusive tests
-			cmd = fmt.Sprintf("sudo /usr/local/bin/kolet run-test-unit --deny-reboots %s", shellquote.Join(unit))
-		} else {
-			unit := fmt.Sprintf("%s.service", KoletExtTestUnit)
-			cmd = fmt.Sprintf("sudo /usr/local/bin/kolet run-test-unit %s", shellquote.Join(unit))
+			args = append(args, "--deny-reboots")
 		}
-		stdout, stderr, err := mach.SSH(cmd)
-		if err != nil {
-			return errors.Wrapf(err, "kolet run-test-unit failed: %s %s", string(stdout), string(stderr))
+		if protocol == ExternalProtocolRPC {
+			args = append(args, "--rpc")
 		}
+		cmd := shellquote.Join(append(args, unit)...)
 
 		koletRes := KoletResult{}
-		if len(stdout) > 0 {
-			err = json.Unmarshal(stdout, &koletRes)
+		if protocol == ExternalProtocolRPC {
+			koletRes, err = runKoletRPC(&c, mach, cmd, testNum == 0)
+			if err != nil {
+				return err
+			}
+		} else {
+			stdout, stderr, err := mach.SSH(cmd)
 			if err != nil {
-				return errors.Wrapf(err, "parsing kolet json %s", string(stdout))
+				return errors.Wrapf(err, "kolet run-test-unit failed: %s %s", string(stdout), string(stderr))
+			}
+			if len(stdout) > 0 {
+				err = json.Unmarshal(stdout, &koletRes)
+				if err != nil {
+					return errors.Wrapf(err, "parsing kolet json %s", string(stdout))
+				}
 			}
 		}
 		// If no  reboot is requested, we're done
@@ -1145,12 +1395,18 @@ func runExternalTest(c cluster.TestCluster, mach platform.Machine, testNum int)
 		// A reboot is requested
 		previousRebootState = koletRes.Reboot
 		plog.Debugf("Reboot request with mark='%s'", previousRebootState)
-		// This signals to the subject that we have saved the mark, and the subject
-		// can proceed with rebooting.  We stop sshd to ensure that the wait below
-		// doesn't log in while ssh is shutting down.
-		_, _, err = mach.SSH(fmt.Sprintf("sudo /bin/sh -c 'systemctl stop sshd && echo > %s'", KoletRebootAckFifo))
-		if err != nil {
-			return errors.Wrapf(err, "failed to acknowledge reboot")
+		if koletRes.HarnessReboot {
+			if err := platform.StartReboot(mach); err != nil {
+				return err
+			}
+		} else {
+			// This signals to the subject that we have saved the mark, and the subject
+			// can proceed with rebooting.  We stop sshd to ensure that the wait below
+			// doesn't log in while ssh is shutting down.
+			_, _, err = mach.SSH(fmt.Sprintf("sudo /bin/sh -c 'systemctl stop sshd && echo > %s'", KoletRebootAckFifo))
+			if err != nil {
+				return errors.Wrapf(err, "failed to acknowledge reboot")
+			}
 		}
 		plog.Debug("Waiting for reboot")
 		err = mach.WaitForReboot(120*time.Second, bootID)
@@ -1171,6 +1427,15 @@ func registerExternalTest(testname, executable, dependencydir string, userdata *
 		targetMeta = &metaCopy
 	}
 
+	if targetMeta.Protocol < 0 || targetMeta.Protocol > ExternalProtocolRPC {
+		return fmt.Errorf("%s: unknown protocol %d", testname, targetMeta.Protocol)
+	}
+	for _, firmware := range targetMeta.Firmwares {
+		if !HasString(firmware, []string{"bios", "uefi", "uefi-secure"}) {
+			return fmt.Errorf("%s: unknown firmware %q", testname, firmware)
+		}
+	}
+
 	warningsAction := conf.FailWarnings
 	if targetMeta.AllowConfigWarnings {
 		warningsAction = conf.IgnoreWarnings
@@ -1208,6 +1473,9 @@ Environment=KOLA_TEST_EXE=%s
 Environment=%s=%s
 ExecStart=%s
 `, unitName, testname, base, kolaExtBinDataEnv, destDataDir, remotepath)
+	if targetMeta.Protocol == ExternalProtocolRPC {
+		unit += fmt.Sprintf("Environment=%s=%s\n", kolaRPCSocketEnv, RPCSocketPath(unitName))
+	}
 	if targetMeta.InjectContainer {
 		if CosaBuild == nil {
 			return fmt.Errorf("test %v uses injectContainer, but no cosa build found", testname)
@@ -1239,11 +1507,15 @@ ExecStart=%s
 		PrimaryDisk:               targetMeta.PrimaryDisk,
 		InjectContainer:           targetMeta.InjectContainer,
 		MinMemory:                 targetMeta.MinMemory,
+		MinCPUs:                   targetMeta.MinCPUs,
+		NestedVirt:                targetMeta.NestedVirt,
 		MinDiskSize:               targetMeta.MinDiskSize,
 		AdditionalNics:            targetMeta.AdditionalNics,
 		AppendKernelArgs:          targetMeta.AppendKernelArgs,
 		AppendFirstbootKernelArgs: targetMeta.AppendFirstbootKernelArgs,
+		PerformanceProfile:        targetMeta.PerformanceProfile,
 		InstanceType:              targetMeta.InstanceType,
+		FirmwareMatrix:            targetMeta.Firmwares,
 		NonExclusive:              !targetMeta.Exclusive,
 		Conflicts:                 targetMeta.Conflicts,
 
@@ -1251,7 +1523,7 @@ ExecStart=%s
 			mach := c.Machines()[0]
 			plog.Debugf("Running kolet")
 
-			err := runExternalTest(c, mach, num)
+			err := runExternalTest(c, mach, num, targetMeta.Protocol)
 			if err != nil {
 				out, stderr, suberr := mach.SSH(fmt.Sprintf("sudo systemctl status --lines=40 %s", shellquote.Join(unitName)))
 				if len(out) > 0 {
@@ -1597,6 +1869,130 @@ func shardTests(tests map[string]*register.Test, sharding string) (map[string]*r
 	return ret, nil
 }
 
+// Estimated durations for tests missing from the durations report. An
+// exclusive test pays for provisioning its own machine, whereas non-exclusive
+// tests share one.
+const (
+	defaultExclusiveTestDuration    = 2 * time.Minute
+	defaultNonExclusiveTestDuration = 10 * time.Second
+)
+
+// partitionTests splits tests into m shards of roughly equal estimated
+// duration and returns the n-th. Tests are assigned longest first to the
+// least loaded shard, with ties broken by name, so that every shard computes
+// the same partition given the same tests and durations. Tests are estimated
+// from their durations on pltfrm or, failing that, on any platform.
+func partitionTests(tests map[string]*register.Test, shard string, durations *TestDurations, pltfrm string) (map[string]*register.Test, error) {
+	parts := strings.SplitN(shard, "/", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid shard syntax: %s", shard)
+	}
+	n, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid shard syntax '%s': %w", shard, err)
+	}
+	m, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid shard syntax '%s': %w", shard, err)
+	}
+	if n > m || m < 1 || n < 1 {
+		return nil, fmt.Errorf("invalid shard in '%s'", shard)
+	}
+
+	estimate := func(t *register.Test) time.Duration {
+		if d, ok := durations.Estimate(pltfrm, t.Name); ok {
+			return d
+		}
+		if d, ok := durations.LongestEstimate(t.Name); ok {
+			return d
+		}
+		if t.NonExclusive {
+			return defaultNonExclusiveTestDuration
+		}
+		return defaultExclusiveTestDuration
+	}
+	var sorted []*register.Test
+	for _, t := range tests {
+		sorted = append(sorted, t)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		di, dj := estimate(sorted[i]), estimate(sorted[j])
+		if di != dj {
+			return di > dj
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	loads := make([]time.Duration, m)
+	ret := make(map[string]*register.Test)
+	for _, t := range sorted {
+		target := 0
+		for i := range loads {
+			if loads[i] < loads[target] {
+				target = i
+			}
+		}
+		loads[target] += estimate(t)
+		if target == n-1 {
+			ret[t.Name] = t
+		}
+	}
+	plog.Infof("Shard %s: %d of %d tests, estimated %v", shard, len(ret), len(tests), loads[n-1])
+	return ret, nil
+}
+
+// estimateTestDurations returns the expected duration of each test for
+// which there is history. Non-exclusive wrappers are estimated as the sum of
+// their subtests, if all of them are known.
+func estimateTestDurations(tests map[string]*register.Test, durations *TestDurations, pltfrm string) map[string]time.Duration {
+	ret := make(map[string]time.Duration)
+	for name, test := range tests {
+		if d, ok := durations.Estimate(pltfrm, GetBaseTestName(name)); ok {
+			ret[name] = d
+			continue
+		}
+		if !nonexclusiveWrapperMatch.MatchString(name) {
+			continue
+		}
+		var total time.Duration
+		known := true
+		for _, subtest := range test.Subtests {
+			d, ok := durations.Estimate(pltfrm, subtest)
+			if !ok {
+				known = false
+				break
+			}
+			total += d
+		}
+		if known {
+			ret[name] = total
+		}
+	}
+	return ret
+}
+
+// sizeParallelism lowers parallel to the number of machines that can be kept
+// busy: once the longest test is started, more slots than are needed to get
+// through the rest of the tests in the meantime won't shorten the run.
+func sizeParallelism(parallel int, estimates map[string]time.Duration) int {
+	var total, longest time.Duration
+	for _, d := range estimates {
+		total += d
+		if d > longest {
+			longest = d
+		}
+	}
+	if longest <= 0 {
+		return parallel
+	}
+	useful := int((total + longest - 1) / longest)
+	if parallel > useful {
+		plog.Noticef("Reducing parallelism from %d to %d based on test durations", parallel, useful)
+		return useful
+	}
+	return parallel
+}
+
 // Create a parent test that runs non-exclusive tests as subtests
 func makeNonExclusiveTest(bucket int, tests []*register.Test, flight platform.Flight) register.Test {
 	// Parse test flags and gather configs
@@ -1605,8 +2001,16 @@ func makeNonExclusiveTest(bucket int, tests []*register.Test, flight platform.Fl
 	var nonExclusiveTestConfs []*conf.Conf
 	dependencyDirs := make(register.DepDirMap)
 	var subtests []string
+	// The wrapper holds on to the fixtures of all its tests, so that
+	// they're only set up once rather than per test
+	var fixtures []string
 	for _, test := range tests {
 		subtests = append(subtests, test.Name)
+		for _, f := range test.Fixtures {
+			if !HasString(f, fixtures) {
+				fixtures = append(fixtures, f)
+			}
+		}
 		if test.HasFlag(register.NoSSHKeyInMetadata) || test.HasFlag(register.NoSSHKeyInUserData) {
 			plog.Fatalf("Non-exclusive test %v cannot have NoSSHKeyIn* flag", test.Name)
 		}
@@ -1619,6 +2023,15 @@ func makeNonExclusiveTest(bucket int, tests []*register.Test, flight platform.Fl
 		if test.AppendKernelArgs != "" {
 			plog.Fatalf("Non-exclusive test %v cannot have AppendKernelArgs", test.Name)
 		}
+		if test.PerformanceProfile != nil {
+			plog.Fatalf("Non-exclusive test %v cannot have PerformanceProfile", test.Name)
+		}
+		if testOffline(test) {
+			plog.Fatalf("Non-exclusive test %v cannot have %s tag", test.Name, OfflineTag)
+		}
+		if test.Firmware != "" || len(test.FirmwareMatrix) > 0 {
+			plog.Fatalf("Non-exclusive test %v cannot have firmwares", test.Name)
+		}
 		if !internetAccess && testRequiresInternet(test) {
 			tags = append(tags, NeedsInternetTag)
 			internetAccess = true
@@ -1651,6 +2064,8 @@ func makeNonExclusiveTest(bucket int, tests []*register.Test, flight platform.Fl
 				run := func(h *harness.H) {
 					tcluster.H.NonExclusiveTestStarted()
 					testResults.add(h)
+					errClass := events.ErrorSetup
+					defer emitTestEvents(h, string(flight.Platform()), &errClass)()
 					// tcluster has a reference to the wrapper's harness
 					// We need a new TestCluster that has a reference to the
 					// subtest being ran
@@ -1658,8 +2073,9 @@ func makeNonExclusiveTest(bucket int, tests []*register.Test, flight platform.Fl
 					// functions such as TestCluster.SSH, since these functions
 					// internally use harness.RunWithExecTimeoutCheck
 					newTC := cluster.TestCluster{
-						H:       h,
-						Cluster: tcluster.Cluster,
+						H:        h,
+						Cluster:  tcluster.Cluster,
+						Fixtures: tcluster.Fixtures,
 					}
 					// Install external test executable
 					if t.ExternalTest != "" {
@@ -1670,7 +2086,14 @@ func makeNonExclusiveTest(bucket int, tests []*register.Test, flight platform.Fl
 					if IsWarningOnFailure(t.Name) {
 						newTC.H.WarningOnFailure()
 					}
+					if len(t.Fixtures) > 0 {
+						if err := newTC.Fixtures.Acquire(newTC, t.Fixtures); err != nil {
+							h.Fatal(err)
+						}
+						defer newTC.Fixtures.Release(newTC, t.Fixtures)
+					}
 
+					errClass = events.ErrorTest
 					t.Run(newTC)
 				}
 				// Each non-exclusive test is run as a subtest of this wrapper test
@@ -1688,41 +2111,179 @@ func makeNonExclusiveTest(bucket int, tests []*register.Test, flight platform.Fl
 		ClusterSize:   1,
 		Tags:          tags,
 		DependencyDir: dependencyDirs,
+		Fixtures:      fixtures,
 	}
 
 	return nonExclusiveWrapper
 }
 
+// emitTestEvents reports to the event stream that a test has started, and
+// returns a function which reports its outcome. If the test failed, *class
+// at that point is taken as the reason.
+func emitTestEvents(h *harness.H, pltfrm string, class *events.ErrorClass) func() {
+	start := time.Now()
+	events.Emit(events.Event{Type: events.TestStarted, Platform: pltfrm, Test: h.Name()})
+	return func() {
+		e := events.Event{
+			Platform: pltfrm,
+			Test:     h.Name(),
+			Duration: time.Since(start).Seconds(),
+		}
+		switch {
+		case h.Skipped():
+			e.Type = events.TestSkipped
+		case h.Failed():
+			e.Type = events.TestFailed
+			e.ErrorClass = *class
+			if h.TimedOut() {
+				e.ErrorClass = events.ErrorTimeout
+			}
+		default:
+			e.Type = events.TestPassed
+		}
+		events.Emit(e)
+	}
+}
+
+// ignitionKernelArgs returns the kernel arguments the test's machines get
+// from Ignition rather than having them appended as they're created: all of
+// them if the test creates its machines itself, which the harness can't
+// pass options to, or the platform can't append them.
+func ignitionKernelArgs(t *register.Test, caps platform.Capabilities) []string {
+	if t.ClusterSize > 0 && caps.Has(platform.CapKernelArgs) {
+		return nil
+	}
+	return strings.Fields(t.AppendKernelArgs)
+}
+
+// testMachineOptions returns the options the test's cluster is created
+// with on a platform with the capabilities.
+func testMachineOptions(t *register.Test, caps platform.Capabilities) platform.MachineOptions {
+	options := platform.MachineOptions{
+		MultiPathDisk:             t.MultiPathDisk,
+		PrimaryDisk:               t.PrimaryDisk,
+		AdditionalDisks:           t.AdditionalDisks,
+		MinMemory:                 t.MinMemory,
+		MinCPUs:                   t.MinCPUs,
+		NestedVirt:                t.NestedVirt,
+		MinDiskSize:               t.MinDiskSize,
+		AdditionalNics:            t.AdditionalNics,
+		AppendKernelArgs:          t.AppendKernelArgs,
+		AppendFirstbootKernelArgs: t.AppendFirstbootKernelArgs,
+		SkipStartMachine:          true,
+		InstanceType:              t.InstanceType,
+	}
+	if t.Firmware != "" {
+		options.Firmware = t.Firmware
+	} else if testSecureBoot(t) {
+		options.Firmware = "uefi-secure"
+	}
+	if len(ignitionKernelArgs(t, caps)) > 0 {
+		options.AppendKernelArgs = ""
+	}
+	return options
+}
+
+// runFirmwareMatrix runs the test with each firmware of its matrix, as
+// subtests which get a cluster of their own.
+func runFirmwareMatrix(h *harness.H, t *register.Test, pltfrm string, flight platform.Flight) {
+	h.Parallel()
+	for _, firmware := range t.FirmwareMatrix {
+		ft := *t
+		ft.Name = t.Name + "/" + firmware
+		ft.Firmware = firmware
+		ft.FirmwareMatrix = nil
+		h.RunTimeout(firmware, func(h *harness.H) {
+			runTest(h, &ft, pltfrm, flight)
+		}, t.Timeout)
+	}
+}
+
+// testNeeds returns the capabilities the platform needs for the test: those
+// it requires, and those its cluster's machine options imply.
+func testNeeds(t *register.Test, caps platform.Capabilities) []platform.Capability {
+	needs := append([]platform.Capability(nil), t.Requires...)
+	if t.ClusterSize > 0 {
+		needs = append(needs, testMachineOptions(t, caps).Needs()...)
+	}
+	return needs
+}
+
 // runTest is a harness for running a single test.
 // outputDir is where various test logs and data will be written for
 // analysis after the test run. It should already exist.
 func runTest(h *harness.H, t *register.Test, pltfrm string, flight platform.Flight) {
+	if len(t.FirmwareMatrix) > 0 {
+		runFirmwareMatrix(h, t, pltfrm, flight)
+		return
+	}
+
 	h.Parallel()
 	h.SetSubtests(t.Subtests)
 
+	errClass := events.ErrorCluster
+	defer emitTestEvents(h, pltfrm, &errClass)()
+
 	rconf := &platform.RuntimeConfig{
 		AllowFailedUnits:   testSkipBaseChecks(t),
 		InternetAccess:     testRequiresInternet(t),
+		AuditEgress:        testOffline(t),
 		NoInstanceCreds:    t.HasFlag(register.NoInstanceCreds),
 		NoSSHKeyInMetadata: t.HasFlag(register.NoSSHKeyInMetadata),
 		NoSSHKeyInUserData: t.HasFlag(register.NoSSHKeyInUserData),
 		OutputDir:          h.OutputDir(),
 		SSHOnTestFailure:   Options.SSHOnTestFailure,
+		MergeJournals:      Options.MergeJournals && t.ClusterSize > 1,
 		WarningsAction:     conf.FailWarnings,
 		EarlyRelease:       h.Release,
+		TestName:           t.Name,
+		PerformanceProfile: t.PerformanceProfile,
 	}
 	if t.HasFlag(register.AllowConfigWarnings) {
 		rconf.WarningsAction = conf.IgnoreWarnings
 	}
+	if rconf.AuditEgress && rconf.InternetAccess {
+		h.Fatalf("Test cannot have both %s and %s tags", OfflineTag, NeedsInternetTag)
+	}
+
+	caps := flight.Capabilities()
+	if missing := caps.Missing(testNeeds(t, caps)); missing != "" {
+		h.Skipf("Platform %s doesn't support %s", pltfrm, missing)
+	}
+	if missing := testInstanceProfileMissing(t, pltfrm, testMachineOptions(t, caps)); missing != "" {
+		h.Skipf("Platform %s: %s", pltfrm, missing)
+	}
+	rconf.KernelArgs = ignitionKernelArgs(t, caps)
 
 	var c platform.Cluster
 	c, err := flight.NewCluster(rconf)
 	if err != nil {
 		h.Fatalf("Cluster failed: %v", err)
 	}
+	waitConsoles := func() {}
 	defer func() {
 		h.StopExecTimer()
+		// Gather diagnostics while the machines are still up
+		var bundle *failureBundle
+		if FailureBundles && h.Failed() {
+			bundle = collectFailureBundle(c.Machines())
+		}
+		if GatherOnFailure && h.Failed() {
+			gatherOnFailure(c.Machines())
+		}
+		machines := c.Machines()
 		c.Destroy()
+		waitConsoles()
+		if rconf.AuditEgress {
+			checkEgress(h, machines)
+		}
+		if bundle != nil {
+			bundle.addConsoles(c.ConsoleOutput())
+			if err := bundle.write(failureBundlePath(h)); err != nil {
+				plog.Errorf("writing failure bundle for %s: %v", h.Name(), err)
+			}
+		}
+		registerArtifacts(h)
 		if h.TimedOut() {
 			// We'll allow tests that time out to succeed on rerun.
 			markTestForRerunSuccess(t, "Test timed out.")
@@ -1731,6 +2292,9 @@ func runTest(h *harness.H, t *register.Test, pltfrm string, flight platform.Flig
 			plog.Debugf("Skipping base checks for %s", t.Name)
 			return
 		}
+		if !h.Failed() {
+			errClass = events.ErrorConsole
+		}
 		handleConsoleChecks := func(logtype, id, output string) {
 			warnOnly, badlines := CheckConsole([]byte(output), t)
 			if SkipConsoleWarnings {
@@ -1749,32 +2313,24 @@ func runTest(h *harness.H, t *register.Test, pltfrm string, flight platform.Flig
 		}
 		for id, output := range c.JournalOutput() {
 			handleConsoleChecks("journal", id, output)
+			for _, finding := range watchdogPolicy.CheckWatchdogs(t.Name, output) {
+				if finding.Action == WatchdogFail {
+					h.Errorf("Found %s on machine %s", finding, id)
+				} else {
+					plog.Warningf("Found %s on machine %s of %s", finding, id, t.Name)
+				}
+			}
 		}
 	}()
 
 	if t.ClusterSize > 0 {
 		var userdata *conf.UserData = t.UserData
-
-		options := platform.MachineOptions{
-			MultiPathDisk:             t.MultiPathDisk,
-			PrimaryDisk:               t.PrimaryDisk,
-			AdditionalDisks:           t.AdditionalDisks,
-			MinMemory:                 t.MinMemory,
-			MinDiskSize:               t.MinDiskSize,
-			AdditionalNics:            t.AdditionalNics,
-			AppendKernelArgs:          t.AppendKernelArgs,
-			AppendFirstbootKernelArgs: t.AppendFirstbootKernelArgs,
-			SkipStartMachine:          true,
-			InstanceType:              t.InstanceType,
-		}
-
-		if testSecureBoot(t) {
-			options.Firmware = "uefi-secure"
-		}
+		options := testMachineOptions(t, caps)
 
 		// Providers sometimes fail to bring up a machine within a
 		// reasonable time frame. Let's try twice and then bail if
 		// it doesn't work.
+		errClass = events.ErrorMachine
 		err := util.Retry(2, 1*time.Second, func() error {
 			var err error
 			_, err = platform.NewMachines(c, userdata, t.ClusterSize, options)
@@ -1789,6 +2345,9 @@ func runTest(h *harness.H, t *register.Test, pltfrm string, flight platform.Flig
 			markTestForRerunSuccess(t, "Platform failed starting machines.")
 			h.Fatalf("Cluster failed starting machines: %v", err)
 		}
+		if StreamConsoles {
+			waitConsoles = streamConsoles(h, c.Machines())
+		}
 	}
 
 	// pass along all registered native functions
@@ -1803,6 +2362,7 @@ func runTest(h *harness.H, t *register.Test, pltfrm string, flight platform.Flig
 		Cluster:     c,
 		NativeFuncs: names,
 		FailFast:    t.FailFast,
+		Fixtures:    cluster.NewFixtureSet(register.Fixtures),
 	}
 
 	if IsWarningOnFailure(t.Name) {
@@ -1821,6 +2381,8 @@ func runTest(h *harness.H, t *register.Test, pltfrm string, flight platform.Flig
 	// We do all of this so that the time it takes to run Ignition can
 	// be included in our test execution timeout.
 	h.StartExecTimer()
+	errClass = events.ErrorBoot
+	bootStart := time.Now()
 	for _, mach := range tcluster.Machines() {
 		plog.Debugf("Trying to StartMachine() %v", mach.ID())
 		var err error
@@ -1830,9 +2392,24 @@ func runTest(h *harness.H, t *register.Test, pltfrm string, flight platform.Flig
 		if err != nil {
 			h.Fatal(errors.Wrapf(err, "mach.Start() failed"))
 		}
+		events.Emit(events.Event{
+			Type:     events.MachineBooted,
+			Platform: pltfrm,
+			Test:     h.Name(),
+			Machine:  mach.ID(),
+			Duration: time.Since(bootStart).Seconds(),
+		})
+		if err := checkKernelArgs(mach, strings.Fields(t.AppendKernelArgs)); err != nil {
+			h.Fatal(err)
+		}
+		inventoryPath := filepath.Join(mach.RuntimeConf().OutputDir, mach.ID(), platform.InventoryFile)
+		if err := platform.WriteInventory(mach, inventoryPath); err != nil {
+			plog.Warningf("Failed to gather inventory for %s: %v", mach.ID(), err)
+		}
 	}
 
 	// drop kolet binary on machines
+	errClass = events.ErrorSetup
 	if t.ExternalTest != "" || t.NativeFuncs != nil {
 		if err := ScpKolet(tcluster.Machines()); err != nil {
 			h.Fatal(err)
@@ -1884,13 +2461,20 @@ func runTest(h *harness.H, t *register.Test, pltfrm string, flight platform.Flig
 		for key, dest := range t.DependencyDir {
 			dir := t.DependencyDir.DirFromKey(key)
 			for _, mach := range tcluster.Machines() {
-				if err := platform.CopyDirToMachine(dir, mach, dest); err != nil {
+				if err := mach.Upload(dir, dest); err != nil {
 					h.Fatal(errors.Wrapf(err, "copying dependencies %s to %s", dir, mach.ID()))
 				}
 			}
 		}
 	}
 
+	if len(t.Fixtures) > 0 {
+		if err := tcluster.Fixtures.Acquire(tcluster, t.Fixtures); err != nil {
+			h.Fatal(err)
+		}
+		defer tcluster.Fixtures.Release(tcluster, t.Fixtures)
+	}
+
 	defer func() {
 		// give some time for the remote journal to be flushed so it can be read
 		// before we run the deferred machine destruction
@@ -1898,9 +2482,56 @@ func runTest(h *harness.H, t *register.Test, pltfrm string, flight platform.Flig
 	}()
 
 	// run test
+	errClass = events.ErrorTest
 	t.Run(tcluster)
 }
 
+// streamConsoles logs the console lines of the machines which support
+// following them to h as they appear, and returns a function which waits
+// for them to end, once the machines are destroyed.
+func streamConsoles(h *harness.H, machines []platform.Machine) func() {
+	var wg sync.WaitGroup
+	for _, m := range machines {
+		streamer, ok := m.(platform.ConsoleStreamer)
+		if !ok {
+			continue
+		}
+		wg.Add(1)
+		go func(id string, stream io.ReadCloser) {
+			defer wg.Done()
+			defer stream.Close()
+			scanner := bufio.NewScanner(stream)
+			for scanner.Scan() {
+				line, err := platform.ParseConsoleLine(scanner.Text())
+				if err != nil {
+					continue
+				}
+				h.Logf("console %s: %s", id, line.Text)
+			}
+		}(m.ID(), streamer.ConsoleStream())
+	}
+	return wg.Wait
+}
+
+// checkKernelArgs returns an error if the machine didn't boot with all of
+// the kernel arguments.
+func checkKernelArgs(m platform.Machine, args []string) error {
+	if len(args) == 0 {
+		return nil
+	}
+	out, stderr, err := m.SSH("cat /proc/cmdline")
+	if err != nil {
+		return fmt.Errorf("reading kernel command line of %s: %v: %s", m.ID(), err, stderr)
+	}
+	cmdline := strings.Fields(string(out))
+	for _, arg := range args {
+		if !HasString(arg, cmdline) {
+			return fmt.Errorf("machine %s booted without kernel argument %s: %s", m.ID(), arg, out)
+		}
+	}
+	return nil
+}
+
 // ScpKolet searches for a kolet binary and copies it to the machines.
 // Write initially to a .partial file in the same directory and then
 // rename since systemd.path units may be watching and we don't want
@@ -1979,12 +2610,31 @@ func CheckConsole(output []byte, t *register.Test) (bool, []string) {
 	return warnOnly, badlines
 }
 
+// DefaultOutputBaseDir returns the directory which output directories are
+// created in by default.
+func DefaultOutputBaseDir() string {
+	if Options.CosaWorkdir != "" {
+		return filepath.Join(Options.CosaWorkdir, "tmp/kola")
+	}
+	return "_kola_temp"
+}
+
+// ReapLeftovers kills the processes and removes the temporary directories
+// which interrupted runs with output directories in base leaked.
+func ReapLeftovers(base string) error {
+	reaped, err := cleanup.ReapAll(base)
+	for _, r := range reaped {
+		plog.Warningf("Reaped %s leaked by an interrupted run", r)
+	}
+	return err
+}
+
 func SetupOutputDir(outputDir, platform string) (string, error) {
 	defaulted := outputDir == ""
 
 	var defaultBaseDirName string
-	if defaulted && Options.CosaWorkdir != "" {
-		defaultBaseDirName = filepath.Join(Options.CosaWorkdir, "tmp/kola")
+	if defaulted {
+		defaultBaseDirName = DefaultOutputBaseDir()
 	} else {
 		defaultBaseDirName = "_kola_temp"
 	}
@@ -2001,11 +2651,21 @@ func SetupOutputDir(outputDir, platform string) (string, error) {
 		network.DefaultSSHDir = defaultBaseDirName
 	}
 
+	// reap what interrupted runs next to this one leaked
+	if err := ReapLeftovers(filepath.Dir(filepath.Clean(outputDir))); err != nil {
+		plog.Warningf("Reaping leftovers of interrupted runs: %v", err)
+	}
+
 	outputDir, err := harness.CleanOutputDir(outputDir)
 	if err != nil {
 		return "", err
 	}
 
+	// FIXME pass this down better than global state
+	if cleanup.Default, err = cleanup.Open(outputDir); err != nil {
+		return "", err
+	}
+
 	if defaulted {
 		tempLinkPath := filepath.Join(outputDir, "latest")
 		linkPath := filepath.Join(defaultBaseDirName, platform+"-latest")
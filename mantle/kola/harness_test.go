@@ -0,0 +1,234 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kola
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/coreos/coreos-assembler/mantle/kola/register"
+)
+
+// shardTestSet returns the tests numbered in perm, in that order, every
+// third of them non-exclusive, along with durations for the even ones.
+func shardTestSet(perm []int) (map[string]*register.Test, *TestDurations) {
+	tests := make(map[string]*register.Test)
+	durations := newTestDurations()
+	for _, i := range perm {
+		name := fmt.Sprintf("test%02d", i)
+		tests[name] = &register.Test{Name: name, NonExclusive: i%3 == 0}
+		if i%2 == 0 {
+			durations.Record("qemu", name, time.Duration(i%7+1)*time.Minute)
+		}
+	}
+	return tests, durations
+}
+
+func shardNames(tests map[string]*register.Test) []string {
+	var names []string
+	for name := range tests {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestPartitionTestsSyntax(t *testing.T) {
+	tests, durations := shardTestSet([]int{0, 1, 2})
+	for _, tt := range []struct {
+		shard string
+		valid bool
+	}{
+		{"1/1", true},
+		{"1/3", true},
+		{"3/3", true},
+		{"", false},
+		{"1", false},
+		{"a/3", false},
+		{"1/b", false},
+		{"0/3", false},
+		{"4/3", false},
+		{"-1/3", false},
+		{"1/0", false},
+		{"1/3/3", false},
+		{" 1/3", false},
+	} {
+		t.Run(tt.shard, func(t *testing.T) {
+			_, err := partitionTests(tests, tt.shard, durations, "qemu")
+			if valid := err == nil; valid != tt.valid {
+				t.Errorf("got error %v, expected valid %v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestPartitionTestsCoverage(t *testing.T) {
+	for _, tt := range []struct {
+		tests  int
+		shards int
+	}{
+		{0, 2},
+		{1, 1},
+		{1, 3},
+		{5, 5},
+		{20, 3},
+		{20, 7},
+	} {
+		t.Run(fmt.Sprintf("%d/%d", tt.tests, tt.shards), func(t *testing.T) {
+			var perm []int
+			for i := 0; i < tt.tests; i++ {
+				perm = append(perm, i)
+			}
+			tests, durations := shardTestSet(perm)
+			seen := make(map[string]int)
+			for n := 1; n <= tt.shards; n++ {
+				shard, err := partitionTests(tests, fmt.Sprintf("%d/%d", n, tt.shards), durations, "qemu")
+				if err != nil {
+					t.Fatal(err)
+				}
+				for name, test := range shard {
+					if tests[name] != test {
+						t.Errorf("shard %d has %s, which isn't an input test", n, name)
+					}
+					if prev, ok := seen[name]; ok {
+						t.Errorf("%s in shards %d and %d", name, prev, n)
+					}
+					seen[name] = n
+				}
+			}
+			if len(seen) != len(tests) {
+				t.Errorf("got %d tests across shards, expected %d", len(seen), len(tests))
+			}
+		})
+	}
+}
+
+func TestPartitionTestsStable(t *testing.T) {
+	const n = 20
+	rng := rand.New(rand.NewSource(1))
+	var want [][]string
+	for run := 0; run < 10; run++ {
+		tests, durations := shardTestSet(rng.Perm(n))
+		var got [][]string
+		for i := 1; i <= 4; i++ {
+			shard, err := partitionTests(tests, fmt.Sprintf("%d/4", i), durations, "qemu")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, shardNames(shard))
+		}
+		if run == 0 {
+			want = got
+		} else if !reflect.DeepEqual(got, want) {
+			t.Fatalf("got %v, expected %v", got, want)
+		}
+	}
+}
+
+func TestEstimateTestDurations(t *testing.T) {
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "empty.json")
+	if err := os.WriteFile(empty, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	history := newTestDurations()
+	history.Record("qemu", "basic", 2*time.Minute)
+	history.Record("qemu", "ext.config.a", 10*time.Second)
+	history.Record("qemu", "ext.config.b", 20*time.Second)
+	history.Record("aws", "ext.config.c", time.Minute)
+	path := filepath.Join(dir, "test-durations.json")
+	if err := history.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]*register.Test{
+		"basic":   {Name: "basic"},
+		"unknown": {Name: "unknown"},
+		// Only known on another platform
+		"ext.config.c": {Name: "ext.config.c"},
+		"non-exclusive-test-bucket-0": {
+			Name:     "non-exclusive-test-bucket-0",
+			Subtests: []string{"ext.config.a", "ext.config.b"},
+		},
+		"non-exclusive-test-bucket-1": {
+			Name:     "non-exclusive-test-bucket-1",
+			Subtests: []string{"ext.config.a", "ext.config.c"},
+		},
+	}
+	for _, tt := range []struct {
+		name      string
+		path      string
+		estimates map[string]time.Duration
+	}{
+		{
+			name:      "missing",
+			path:      filepath.Join(dir, "missing.json"),
+			estimates: map[string]time.Duration{},
+		},
+		{
+			name:      "empty",
+			path:      empty,
+			estimates: map[string]time.Duration{},
+		},
+		{
+			name: "history",
+			path: path,
+			estimates: map[string]time.Duration{
+				"basic":                       2 * time.Minute,
+				"non-exclusive-test-bucket-0": 30 * time.Second,
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			durations, err := LoadTestDurations(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			estimates := estimateTestDurations(tests, durations, "qemu")
+			if !reflect.DeepEqual(estimates, tt.estimates) {
+				t.Errorf("got %v, expected %v", estimates, tt.estimates)
+			}
+		})
+	}
+}
+
+func TestSizeParallelism(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		parallel  int
+		estimates map[string]time.Duration
+		expected  int
+	}{
+		{"no estimates", 8, map[string]time.Duration{}, 8},
+		{"zero estimates", 8, map[string]time.Duration{"a": 0, "b": 0}, 8},
+		{"one test", 8, map[string]time.Duration{"a": time.Minute}, 1},
+		{"equal tests", 8, map[string]time.Duration{"a": time.Minute, "b": time.Minute, "c": time.Minute}, 3},
+		{"long test", 8, map[string]time.Duration{"a": 4 * time.Minute, "b": time.Minute, "c": time.Minute, "d": time.Minute}, 2},
+		{"rounded up", 8, map[string]time.Duration{"a": 4 * time.Minute, "b": 3 * time.Minute, "c": 2 * time.Minute}, 3},
+		{"already lower", 2, map[string]time.Duration{"a": time.Minute, "b": time.Minute, "c": time.Minute}, 2},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if parallel := sizeParallelism(tt.parallel, tt.estimates); parallel != tt.expected {
+				t.Errorf("got %d, expected %d", parallel, tt.expected)
+			}
+		})
+	}
+}
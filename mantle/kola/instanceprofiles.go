@@ -0,0 +1,128 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kola
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/coreos/coreos-assembler/mantle/kola/register"
+	"github.com/coreos/coreos-assembler/mantle/platform"
+)
+
+// InstanceProfilesFile is a YAML file mapping platforms to the instance
+// profiles machines needing more than their default instance type are
+// given, replacing the built-in profiles of the platforms it lists.
+// Defaults to src/config/kola-instance-profiles.yaml in the workdir.
+var InstanceProfilesFile string
+
+// defaultInstanceProfiles are the built-in instance profiles of each
+// platform, smallest first, starting with the default instance types
+// chosen for each architecture in cmd/kola.
+var defaultInstanceProfiles = map[string][]platform.InstanceProfile{
+	"aws": {
+		{Type: "m5.large", Arch: "x86_64", Memory: 8192, CPUs: 2},
+		{Type: "m5.xlarge", Arch: "x86_64", Memory: 16384, CPUs: 4},
+		{Type: "m5.2xlarge", Arch: "x86_64", Memory: 32768, CPUs: 8},
+		{Type: "m5.metal", Arch: "x86_64", Memory: 393216, CPUs: 96, NestedVirt: true},
+		{Type: "c6g.xlarge", Arch: "aarch64", Memory: 8192, CPUs: 4},
+		{Type: "m6g.xlarge", Arch: "aarch64", Memory: 16384, CPUs: 4},
+		{Type: "m6g.2xlarge", Arch: "aarch64", Memory: 32768, CPUs: 8},
+		{Type: "c6g.metal", Arch: "aarch64", Memory: 131072, CPUs: 64, NestedVirt: true},
+	},
+	"azure": {
+		{Type: "Standard_D2s_v3", Arch: "x86_64", Memory: 8192, CPUs: 2, NestedVirt: true},
+		{Type: "Standard_D4s_v3", Arch: "x86_64", Memory: 16384, CPUs: 4, NestedVirt: true},
+		{Type: "Standard_D8s_v3", Arch: "x86_64", Memory: 32768, CPUs: 8, NestedVirt: true},
+		{Type: "Standard_D2ps_v5", Arch: "aarch64", Memory: 8192, CPUs: 2},
+		{Type: "Standard_D4ps_v5", Arch: "aarch64", Memory: 16384, CPUs: 4},
+		{Type: "Standard_D8ps_v5", Arch: "aarch64", Memory: 32768, CPUs: 8},
+	},
+	"gcp": {
+		{Type: "n1-standard-1", Arch: "x86_64", Memory: 3840, CPUs: 1, NestedVirt: true},
+		{Type: "n1-standard-2", Arch: "x86_64", Memory: 7680, CPUs: 2, NestedVirt: true},
+		{Type: "n1-standard-4", Arch: "x86_64", Memory: 15360, CPUs: 4, NestedVirt: true},
+		{Type: "n1-standard-8", Arch: "x86_64", Memory: 30720, CPUs: 8, NestedVirt: true},
+		{Type: "t2a-standard-1", Arch: "aarch64", Memory: 4096, CPUs: 1},
+		{Type: "t2a-standard-2", Arch: "aarch64", Memory: 8192, CPUs: 2},
+		{Type: "t2a-standard-4", Arch: "aarch64", Memory: 16384, CPUs: 4},
+		{Type: "t2a-standard-8", Arch: "aarch64", Memory: 32768, CPUs: 8},
+	},
+}
+
+// loadInstanceProfiles sets the platform's instance profiles in Options,
+// from InstanceProfilesFile if it lists the platform.
+func loadInstanceProfiles(pltfrm string) error {
+	Options.InstanceProfiles = defaultInstanceProfiles[pltfrm]
+
+	path := InstanceProfilesFile
+	if path == "" {
+		path = filepath.Join(Options.CosaWorkdir, "src/config/kola-instance-profiles.yaml")
+	}
+	buf, err := os.ReadFile(path)
+	if os.IsNotExist(err) && InstanceProfilesFile == "" {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	var profiles map[string][]platform.InstanceProfile
+	if err := yaml.UnmarshalStrict(buf, &profiles); err != nil {
+		return fmt.Errorf("parsing %s: %v", path, err)
+	}
+	for name, list := range profiles {
+		for i, p := range list {
+			if p.Type == "" {
+				return fmt.Errorf("%s: %s profile %d has no type", path, name, i)
+			}
+			if p.Memory <= 0 || p.CPUs <= 0 {
+				return fmt.Errorf("%s: %s profile %s needs its memory and cpus", path, name, p.Type)
+			}
+		}
+	}
+	if list, ok := profiles[pltfrm]; ok {
+		Options.InstanceProfiles = list
+	}
+	return nil
+}
+
+// defaultInstanceType returns the instance type of the platform's
+// machines when tests don't need another, if it has instance profiles.
+func defaultInstanceType(pltfrm string) string {
+	switch pltfrm {
+	case "aws":
+		return AWSOptions.InstanceType
+	case "azure":
+		return AzureOptions.Size
+	case "gcp":
+		return GCPOptions.MachineType
+	}
+	return ""
+}
+
+// testInstanceProfileMissing returns why none of the platform's instance
+// profiles fits the test's machines, or "" if one does or they don't
+// need one.
+func testInstanceProfileMissing(t *register.Test, pltfrm string, options platform.MachineOptions) string {
+	if t.ClusterSize == 0 {
+		return ""
+	}
+	if _, err := platform.ResolveInstanceType(Options.InstanceProfiles, Options.CosaBuildArch, defaultInstanceType(pltfrm), options); err != nil {
+		return err.Error()
+	}
+	return ""
+}
@@ -22,7 +22,7 @@ import (
 type DepDirMap map[string]string
 
 // In the case of non-exclusive tests, some tests may have the same dependency dir
-// as other tests. Since the tests will be run in one VM, the CopyDirToMachine func
+// as other tests. Since the tests will be run in one VM, Machine.Upload
 // will only be able to sync to contents of dependency dir for one of the tests.
 
 // We will use DepDirMap to keep track of which dependency dir maps to which destination
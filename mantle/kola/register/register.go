@@ -19,6 +19,7 @@ import (
 	"time"
 
 	"github.com/coreos/coreos-assembler/mantle/kola/cluster"
+	"github.com/coreos/coreos-assembler/mantle/platform"
 	"github.com/coreos/coreos-assembler/mantle/platform/conf"
 )
 
@@ -88,6 +89,13 @@ type Test struct {
 	// Minimum amount of memory in MB required for test.
 	MinMemory int
 
+	// Minimum number of CPUs required for test.
+	MinCPUs int
+
+	// NestedVirt, if set, gives the machines nested virtualization, so
+	// that they can run virtual machines themselves.
+	NestedVirt bool
+
 	// Minimum amount of primary disk in GB required for test. Deprecated in favour
 	// of PrimaryDisk.
 	MinDiskSize int
@@ -101,6 +109,20 @@ type Test struct {
 	// Additional first boot kernel arguments to append to the defaults.
 	AppendFirstbootKernelArgs string
 
+	// Firmware the machines boot with, e.g. "uefi-secure" -- defaults to
+	// the platform's.
+	Firmware string
+
+	// FirmwareMatrix, if set, runs the test once per firmware in it (e.g.
+	// ["bios", "uefi", "uefi-secure"]), each as a subtest named by the
+	// firmware with its own cluster, output and result.
+	FirmwareMatrix []string
+
+	// PerformanceProfile, if set, tunes the machines with the realtime
+	// kernel, hugepages, CPU isolation or a tuned profile. It overrides
+	// kola's --performance-profile.
+	PerformanceProfile *platform.PerformanceProfile
+
 	// ExternalTest is a path to a binary that will be uploaded
 	ExternalTest string
 	// DependencyDir is a path to directory that will be uploaded, normally used by external tests
@@ -119,8 +141,20 @@ type Test struct {
 	Conflicts []string
 
 	// If provided, this test will be run on the target instance type.
-	// This overrides the instance type set with `kola run`
+	// This overrides the instance type set with `kola run`, and the one
+	// the platform's instance profiles give for MinMemory, MinCPUs and
+	// NestedVirt
 	InstanceType string
+
+	// Requires lists capabilities the platform must have for the test,
+	// besides those implied by the fields above such as AdditionalDisks.
+	// The test is skipped on platforms lacking any of them.
+	Requires []platform.Capability
+
+	// Fixtures are the names of registered fixtures the test needs. They
+	// are set up before the test runs, shared with any other test in the
+	// same cluster needing them, and available via TestCluster.Fixture().
+	Fixtures []string
 }
 
 // Registered tests that run as part of `kola run` live here. Mapping of names
@@ -131,6 +165,10 @@ var Tests = map[string]*Test{}
 // names to tests.
 var UpgradeTests = map[string]*Test{}
 
+// Registered fixtures which tests can declare in Test.Fixtures. Mapping of
+// names to fixtures.
+var Fixtures = map[string]*cluster.Fixture{}
+
 // Register is usually called via init() functions and is how kola test
 // harnesses knows which tests it can choose from. Panics if existing name is
 // registered
@@ -153,6 +191,15 @@ func RegisterUpgradeTest(t *Test) {
 	Register(UpgradeTests, t)
 }
 
+// RegisterFixture makes a fixture available to tests. Panics if existing
+// name is registered.
+func RegisterFixture(f *cluster.Fixture) {
+	if _, ok := Fixtures[f.Name]; ok {
+		panic(fmt.Sprintf("fixture %v already registered", f.Name))
+	}
+	Fixtures[f.Name] = f
+}
+
 func (t *Test) HasFlag(flag Flag) bool {
 	for _, f := range t.Flags {
 		if f == flag {
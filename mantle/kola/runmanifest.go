@@ -0,0 +1,153 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kola
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/coreos/coreos-assembler/mantle/kola/register"
+)
+
+// runManifestName is the file in a run's output directory its manifest is
+// written to.
+const runManifestName = "run-manifest.json"
+
+// RunManifest records exactly what a run ran, so that `kola run
+// --from-manifest` can run the same selection again later, possibly on
+// another builder, to reproduce its failures. Tests are the tests selected
+// after filtering, denylisting and sharding, and Buckets how the
+// non-exclusive ones among them were grouped onto machines. The denylist
+// is the one the run resolved from kola-denylist.yaml and --denylist-test,
+// and Flags are the other flags given on its command line as --NAME=VALUE.
+type RunManifest struct {
+	Platform     string     `json:"platform"`
+	Arch         string     `json:"arch"`
+	Distribution string     `json:"distro,omitempty"`
+	Build        string     `json:"build,omitempty"`
+	Tests        []string   `json:"tests"`
+	Buckets      [][]string `json:"buckets,omitempty"`
+
+	Denylist            []string `json:"denylist,omitempty"`
+	WarnOnError         []string `json:"warnOnError,omitempty"`
+	SkipConsoleWarnings bool     `json:"skipConsoleWarnings,omitempty"`
+
+	Flags []string `json:"flags,omitempty"`
+}
+
+var (
+	// RunFlags are the flags of the run, as --NAME=VALUE, recorded in its
+	// manifest.
+	RunFlags []string
+	// FromManifest is the manifest of an earlier run whose selection is
+	// being run again.
+	FromManifest *RunManifest
+)
+
+// LoadRunManifest reads the manifest written by an earlier run, given
+// either its path or the run's output directory.
+func LoadRunManifest(path string) (*RunManifest, error) {
+	if fi, err := os.Stat(path); err == nil && fi.IsDir() {
+		path = filepath.Join(path, runManifestName)
+	}
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m RunManifest
+	if err := json.Unmarshal(buf, &m); err != nil {
+		return nil, errors.Wrapf(err, "parsing run manifest %s", path)
+	}
+	if len(m.Tests) == 0 {
+		return nil, errors.Errorf("run manifest %s has no tests", path)
+	}
+	return &m, nil
+}
+
+// applyPolicies denies and warns on the tests the manifest's run did,
+// instead of resolving kola-denylist.yaml again.
+func (m *RunManifest) applyPolicies() {
+	DenylistedTests = append([]string(nil), m.Denylist...)
+	WarnOnErrorTests = append([]string(nil), m.WarnOnError...)
+	SkipConsoleWarnings = m.SkipConsoleWarnings
+}
+
+// manifestBuckets groups non-exclusive tests as FromManifest did, leaving
+// any tests it didn't bucket, e.g. for a rerun of part of a bucket, to
+// createTestBuckets.
+func manifestBuckets(tests []*register.Test) [][]*register.Test {
+	byName := make(map[string]*register.Test)
+	for _, t := range tests {
+		byName[t.Name] = t
+	}
+	var buckets [][]*register.Test
+	for _, names := range FromManifest.Buckets {
+		var bucket []*register.Test
+		for _, name := range names {
+			if t, ok := byName[name]; ok {
+				bucket = append(bucket, t)
+				delete(byName, name)
+			}
+		}
+		if len(bucket) > 0 {
+			buckets = append(buckets, bucket)
+		}
+	}
+	var rest []*register.Test
+	for _, t := range tests {
+		if _, ok := byName[t.Name]; ok {
+			rest = append(rest, t)
+		}
+	}
+	if len(rest) > 0 {
+		buckets = append(buckets, createTestBuckets(rest)...)
+	}
+	return buckets
+}
+
+// writeRunManifest writes the manifest of a run of tests, with the
+// non-exclusive ones grouped into buckets, to its output directory.
+func writeRunManifest(outputDir, pltfrm string, tests []string, buckets [][]*register.Test) error {
+	m := RunManifest{
+		Platform:            pltfrm,
+		Arch:                Options.CosaBuildArch,
+		Distribution:        Options.Distribution,
+		Tests:               append([]string(nil), tests...),
+		Denylist:            DenylistedTests,
+		WarnOnError:         WarnOnErrorTests,
+		SkipConsoleWarnings: SkipConsoleWarnings,
+		Flags:               RunFlags,
+	}
+	if CosaBuild != nil {
+		m.Build = CosaBuild.Meta.BuildID
+	}
+	sort.Strings(m.Tests)
+	for _, bucket := range buckets {
+		var names []string
+		for _, t := range bucket {
+			names = append(names, t.Name)
+		}
+		m.Buckets = append(m.Buckets, names)
+	}
+	buf, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(outputDir, runManifestName), append(buf, '\n'), 0644)
+}
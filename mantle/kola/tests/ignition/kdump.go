@@ -130,17 +130,18 @@ func setupSSHMachine(c cluster.TestCluster) SshServer {
 
 	// Inject the public key previously created as an
 	// authorized key
-	ignition := conf.Ignition(fmt.Sprintf(`{
-       "ignition": { "version": "3.4.0" },
-       "passwd":{
-         "users":[
-           {
-             "name":"core",
-             "sshAuthorizedKeys": ["%s"]
-            }
-          ]
-       }
-    }`, strings.TrimSpace(string(pubkeyBuf))))
+	config, err := conf.Ignition(`{"ignition": {"version": "3.4.0"}}`).Render(conf.FailWarnings)
+	if err != nil {
+		c.Fatal(err)
+	}
+	err = config.AddUser(conf.User{
+		Name:              "core",
+		SSHAuthorizedKeys: []string{strings.TrimSpace(string(pubkeyBuf))},
+	})
+	if err != nil {
+		c.Fatal(err)
+	}
+	ignition := conf.Ignition(config.String())
 
 	// start the machine
 	switch c := c.Cluster.(type) {
@@ -138,39 +138,32 @@ func setupTangMachine(c cluster.TestCluster) ut.TangServer {
 
 func runTest(c cluster.TestCluster, tpm2 bool, threshold int, killTangAfterFirstBoot bool) {
 	tangd := setupTangMachine(c)
-	ignition := conf.Ignition(fmt.Sprintf(`{
-		"ignition": {
-			"version": "3.2.0"
-		},
-		"storage": {
-			"luks": [
-				{
-					"name": "root",
-					"device": "/dev/disk/by-label/root",
-					"clevis": {
-						"tpm2": %v,
-						"tang": [
-							{
-								"url": "http://%s",
-								"thumbprint": "%s"
-							}
-						],
-						"threshold": %d
-					},
-					"label": "root",
-					"wipeVolume": true
-				}
-			],
-			"filesystems": [
-				{
-					"device": "/dev/mapper/root",
-					"format": "xfs",
-					"wipeFilesystem": true,
-					"label": "root"
-				}
-			]
-		}
-	}`, tpm2, tangd.Address, tangd.Thumbprint, threshold))
+	config, err := conf.EmptyIgnition().Render(conf.FailWarnings)
+	if err != nil {
+		c.Fatal(err)
+	}
+	err = config.AddLuksDevice(conf.Luks{
+		Name:       "root",
+		Device:     "/dev/disk/by-label/root",
+		Label:      "root",
+		WipeVolume: true,
+		TPM2:       tpm2,
+		Tang:       []conf.Tang{{URL: "http://" + tangd.Address, Thumbprint: tangd.Thumbprint}},
+		Threshold:  threshold,
+	})
+	if err != nil {
+		c.Fatal(err)
+	}
+	err = config.AddFilesystem(conf.Filesystem{
+		Device:         "/dev/mapper/root",
+		Format:         "xfs",
+		Label:          "root",
+		WipeFilesystem: true,
+	})
+	if err != nil {
+		c.Fatal(err)
+	}
+	ignition := conf.Ignition(config.String())
 
 	opts := platform.MachineOptions{
 		MinMemory: 4096,
@@ -202,37 +195,33 @@ func runCexTest(c cluster.TestCluster) {
 		c.Skip("No CEX device found in KOLA_CEX_UUID env var")
 	}
 
-	ignition := conf.Ignition(`{
-		"ignition": {
-			"version": "3.5.0"
-		},
-		"kernelArguments": {
-			"shouldExist": [
-				"rd.luks.key=/etc/luks/cex.key"
-		]
-		},
-		"storage": {
-			"luks": [
-				{
-					"name": "root",
-					"device": "/dev/disk/by-label/root",
-					"cex": {
-						"enabled": true
-					},
-					"label": "root",
-					"wipeVolume": true
-				}
-			],
-			"filesystems": [
-				{
-					"device": "/dev/mapper/root",
-					"format": "xfs",
-					"wipeFilesystem": true,
-					"label": "root"
-				}
-			]
-		}
-	}`)
+	config, err := conf.Ignition(`{"ignition": {"version": "3.5.0"}}`).Render(conf.FailWarnings)
+	if err != nil {
+		c.Fatal(err)
+	}
+	if err := config.SetKernelArguments([]string{"rd.luks.key=/etc/luks/cex.key"}, nil); err != nil {
+		c.Fatal(err)
+	}
+	err = config.AddLuksDevice(conf.Luks{
+		Name:       "root",
+		Device:     "/dev/disk/by-label/root",
+		Label:      "root",
+		WipeVolume: true,
+		CEX:        true,
+	})
+	if err != nil {
+		c.Fatal(err)
+	}
+	err = config.AddFilesystem(conf.Filesystem{
+		Device:         "/dev/mapper/root",
+		Format:         "xfs",
+		Label:          "root",
+		WipeFilesystem: true,
+	})
+	if err != nil {
+		c.Fatal(err)
+	}
+	ignition := conf.Ignition(config.String())
 
 	opts := platform.QemuMachineOptions{
 		Cex: true,
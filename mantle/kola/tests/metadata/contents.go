@@ -17,8 +17,10 @@ package metadata
 import (
 	"strings"
 
+	"github.com/coreos/coreos-assembler/mantle/kola"
 	"github.com/coreos/coreos-assembler/mantle/kola/cluster"
 	"github.com/coreos/coreos-assembler/mantle/kola/register"
+	"github.com/coreos/coreos-assembler/mantle/kola/tests/util"
 	"github.com/coreos/coreos-assembler/mantle/platform/conf"
 )
 
@@ -47,6 +49,18 @@ func init() {
 		Distros:     []string{"fcos"},
 	})
 
+	// Run with --aws-imdsv2-only to check the machines cope with IMDSv1
+	// being disabled
+	register.RegisterTest(&register.Test{
+		Name:        "fcos.metadata.aws.imds",
+		Description: "Verify Afterburn fetches the metadata and SSH keys through IMDSv2 on AWS.",
+		Run:         verifyAWSIMDS,
+		ClusterSize: 1,
+		Platforms:   []string{"aws"},
+		UserData:    enableMetadataService,
+		Distros:     []string{"fcos"},
+	})
+
 	register.RegisterTest(&register.Test{
 		Name:        "fcos.metadata.azure",
 		Description: "Verify the metadata on Azure.",
@@ -62,6 +76,10 @@ func verifyAWS(c cluster.TestCluster) {
 	verify(c, "AFTERBURN_AWS_IPV4_LOCAL", "AFTERBURN_AWS_IPV4_PUBLIC", "AFTERBURN_AWS_HOSTNAME")
 }
 
+func verifyAWSIMDS(c cluster.TestCluster) {
+	util.AWSIMDSSanityTest(c, c.Machines()[0], kola.AWSOptions.IMDSv2Only, true)
+}
+
 func verifyAzure(c cluster.TestCluster) {
 	verify(c, "AFTERBURN_AZURE_IPV4_DYNAMIC")
 	// kola tests do not spawn machines behind a load balancer on Azure
@@ -0,0 +1,121 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package misc
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/coreos/coreos-assembler/mantle/kola/cluster"
+	"github.com/coreos/coreos-assembler/mantle/kola/register"
+	"github.com/coreos/coreos-assembler/mantle/kola/tests/util"
+	"github.com/coreos/coreos-assembler/mantle/platform"
+	"github.com/coreos/coreos-assembler/mantle/platform/conf"
+	"github.com/coreos/coreos-assembler/mantle/platform/machine/qemu"
+)
+
+func init() {
+	register.RegisterTest(&register.Test{
+		Run:         runBootupdUpdateTest,
+		ClusterSize: 0,
+		Name:        "coreos.bootupd.update",
+		Description: "Verify bootupd updates an outdated EFI bootloader in the ESP, and the machine boots from the same boot entry afterwards.",
+		Platforms:   []string{"qemu"},
+		// bootupd only manages the ESP on these
+		Architectures: []string{"x86_64", "aarch64"},
+		Tags:          []string{"bootupd", "reprovision"},
+		Timeout:       15 * time.Minute,
+	})
+}
+
+// bootupdDowngradeEFI makes bootupd take the EFI component as installed
+// from an older payload: it backdates it and removes the shim fallback from
+// both the ESP and bootupd's record of what it installed there, so that
+// updating must add the file back. It prints the path of the file removed,
+// relative to the ESP.
+const bootupdDowngradeEFI = `set -euo pipefail
+state=/boot/bootupd-state.json
+f=$(jq -r '.installed.EFI.filetree.children | keys[] | select(test("^BOOT/fb.*\\.efi$"; "i"))' $state | head -n1)
+if [ -z "$f" ]; then
+	echo "no shim fallback in ${state}" >&2
+	exit 1
+fi
+jq --arg f "$f" 'del(.installed.EFI.filetree.children[$f])
+	| .installed.EFI.meta.timestamp = "2020-01-01T00:00:00Z"
+	| .installed.EFI.meta.version = "kola-outdated"' $state > /tmp/bootupd-state.json
+ro=$(findmnt -no OPTIONS /boot | grep -q '^ro' && echo 1 || true)
+[ -z "$ro" ] || mount -o remount,rw /boot
+cp /tmp/bootupd-state.json $state
+[ -z "$ro" ] || mount -o remount,ro /boot
+mkdir -p /run/kola-esp
+mount ${ESP} /run/kola-esp
+rm "/run/kola-esp/EFI/$f"
+umount /run/kola-esp
+echo "EFI/$f"
+`
+
+func runBootupdUpdateTest(c cluster.TestCluster) {
+	options := platform.QemuMachineOptions{
+		Firmware: "uefi",
+		// Keep the boot entries the machine creates across its reboots,
+		// and in the output for inspection
+		FirmwareVarsFile: filepath.Join(c.H.OutputDir(), "efivars.fd"),
+	}
+	m, err := c.Cluster.(*qemu.Cluster).NewMachineWithQemuOptions(conf.EmptyIgnition(), options)
+	if err != nil {
+		c.Fatal(err)
+	}
+	if _, err := c.SSH(m, "command -v bootupctl"); err != nil {
+		c.Skip("bootupd isn't installed")
+	}
+
+	before := util.ESPFiles(c, m)
+	entries := util.GetEFIBootEntries(c, m)
+	if entries.CurrentLabel() == "" {
+		c.Fatalf("machine didn't boot from a known boot entry: %+v", entries)
+	}
+
+	removed := strings.TrimSpace(string(c.MustSSHf(m, "sudo ESP=%s bash -s <<'EOF'\n%sEOF", util.ESPDevice, bootupdDowngradeEFI)))
+	c.Logf("Removed %s from the ESP", removed)
+
+	c.RunLogged("update", func(c cluster.TestCluster) {
+		c.AssertCmdOutputContains(m, "sudo bootupctl status", "Update: Available")
+		c.AssertCmdOutputContains(m, "sudo bootupctl update", "Updated EFI")
+	})
+
+	c.RunLogged("esp", func(c cluster.TestCluster) {
+		after := util.ESPFiles(c, m)
+		if _, ok := after[removed]; !ok {
+			c.Fatalf("bootupd didn't restore %s", removed)
+		}
+		for path, sum := range before {
+			if after[path] != sum {
+				c.Errorf("%s differs from before the downgrade", path)
+			}
+		}
+	})
+
+	c.RunLogged("reboot", func(c cluster.TestCluster) {
+		if err := m.Reboot(); err != nil {
+			c.Fatalf("rebooting after update: %v", err)
+		}
+		c.AssertCmdOutputContains(m, "sudo bootupctl status", "At latest version")
+		rebooted := util.GetEFIBootEntries(c, m)
+		if rebooted.CurrentLabel() != entries.CurrentLabel() {
+			c.Fatalf("booted from %q after the update, expected %q", rebooted.CurrentLabel(), entries.CurrentLabel())
+		}
+	})
+}
@@ -0,0 +1,206 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package misc
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/coreos/coreos-assembler/mantle/kola/cluster"
+	"github.com/coreos/coreos-assembler/mantle/kola/register"
+	"github.com/coreos/coreos-assembler/mantle/kola/tests/util"
+	"github.com/coreos/coreos-assembler/mantle/platform"
+	"github.com/coreos/coreos-assembler/mantle/platform/conf"
+	"github.com/coreos/coreos-assembler/mantle/platform/local"
+)
+
+func init() {
+	register.RegisterTest(&register.Test{
+		Run:         NetworkLab,
+		ClusterSize: 0,
+		Name:        "coreos.network.lab",
+		Description: "Verify machines on a lab network get their addresses, names and files from its DHCP, DNS and HTTP servers.",
+		Platforms:   []string{"qemu"},
+		Fixtures:    []string{util.LabFixture},
+		// creating the lab's network namespace needs privileges
+		RequiredTag: "lab",
+	})
+	register.RegisterTest(&register.Test{
+		Run:         NetworkLabJumboVLAN,
+		ClusterSize: 0,
+		Name:        "coreos.network.lab.jumbo-vlan",
+		Description: "Verify jumbo frames pass on a lab network and a VLAN on it, configured with NetworkManager keyfiles.",
+		Platforms:   []string{"qemu"},
+		RequiredTag: "lab",
+	})
+}
+
+// labNicUUID is the UUID of the connection of the NIC on the lab network,
+// which VLANs are on.
+const labNicUUID = "9a3ac1a4-6f35-4b52-9d6a-0d7e2a4b2f10"
+
+// labNicConfig configures the machine's NIC on the lab network to use only
+// the lab's DNS server, rather than also QEMU's user-mode one, which
+// doesn't know the lab's names, with the MTU if it's set, and adds
+// connections for the VLANs as labvlan<ID>.
+func labNicConfig(host *local.LabHost, domain string, mtu int, vlans []int) *conf.UserData {
+	mtuConfig := ""
+	if mtu > 0 {
+		mtuConfig = fmt.Sprintf("mtu=%d\n", mtu)
+	}
+	names := []string{"lab"}
+	keyfiles := []string{
+		fmt.Sprintf(`[connection]
+id=lab
+uuid=%s
+type=ethernet
+
+[ethernet]
+mac-address=%s
+%s
+[ipv4]
+method=auto
+dns-priority=-1
+dns-search=%s
+`, labNicUUID, host.MAC, mtuConfig, domain),
+	}
+	for _, id := range vlans {
+		name := fmt.Sprintf("labvlan%d", id)
+		names = append(names, name)
+		keyfiles = append(keyfiles, fmt.Sprintf(`[connection]
+id=%s
+type=vlan
+interface-name=%s
+
+[vlan]
+id=%d
+parent=%s
+
+[ethernet]
+%s
+[ipv4]
+method=auto
+never-default=true
+`, name, name, id, labNicUUID, mtuConfig))
+	}
+	var files []string
+	for i, keyfile := range keyfiles {
+		files = append(files, fmt.Sprintf(`{
+				"path": "/etc/NetworkManager/system-connections/%s.nmconnection",
+				"contents": { "source": "data:text/plain;base64,%s" },
+				"mode": 384
+			}`, names[i], base64.StdEncoding.EncodeToString([]byte(keyfile))))
+	}
+	return conf.Ignition(fmt.Sprintf(`{
+		"ignition": {
+			"version": "3.2.0"
+		},
+		"storage": {
+			"files": [%s]
+		}
+	}`, strings.Join(files, ", ")))
+}
+
+func NetworkLab(c cluster.TestCluster) {
+	lab := c.Fixture(util.LabFixture).(*util.LabNetwork)
+	if err := os.WriteFile(filepath.Join(lab.Dir, "hello"), []byte("hello from the lab\n"), 0644); err != nil {
+		c.Fatal(err)
+	}
+
+	var machines []platform.Machine
+	var hosts []*local.LabHost
+	for _, name := range []string{"node1", "node2"} {
+		host, err := lab.AddHost(name)
+		if err != nil {
+			c.Fatal(err)
+		}
+		m, err := lab.NewMachine(c, host, labNicConfig(host, lab.Domain, 0, nil), platform.QemuMachineOptions{})
+		if err != nil {
+			c.Fatal(err)
+		}
+		machines = append(machines, m)
+		hosts = append(hosts, host)
+	}
+
+	for i, m := range machines {
+		host, other := hosts[i], hosts[1-i]
+		c.RunLogged(host.Name+"/address", func(c cluster.TestCluster) {
+			out := string(c.MustSSHf(m, "ip -4 -o addr show to %s", host.IP))
+			if !strings.Contains(out, host.IP.String()) {
+				c.Fatalf("%s didn't get its static lease %s", host.Name, host.IP)
+			}
+		})
+		c.RunLogged(host.Name+"/dns", func(c cluster.TestCluster) {
+			out := string(c.MustSSHf(m, "getent hosts %s.%s", other.Name, lab.Domain))
+			if fields := strings.Fields(out); len(fields) == 0 || fields[0] != other.IP.String() {
+				c.Fatalf("%s resolved %s as %q, expected %s", host.Name, other.Name, out, other.IP)
+			}
+			// the search domain comes from the connection
+			c.RunCmdSyncf(m, "ping -c 1 -W 5 %s", other.Name)
+		})
+		c.RunLogged(host.Name+"/http", func(c cluster.TestCluster) {
+			out := string(c.MustSSHf(m, "curl -sSf %s/hello", lab.HTTPURL))
+			if out != "hello from the lab" {
+				c.Fatalf("got %q from the lab's HTTP server", out)
+			}
+		})
+	}
+}
+
+func NetworkLabJumboVLAN(c cluster.TestCluster) {
+	const (
+		mtu  = 9000
+		vlan = 100
+	)
+	lab, err := util.NewLabNetwork(c, local.LabOptions{
+		MTU:   mtu,
+		VLANs: []local.LabVLAN{{ID: vlan, Subnet: "192.168.200.0/24"}},
+	})
+	if err != nil {
+		c.Fatal(err)
+	}
+	defer lab.Close()
+	vlanGateway, err := lab.VLANGateway(vlan)
+	if err != nil {
+		c.Fatal(err)
+	}
+	host, err := lab.AddHost("node")
+	if err != nil {
+		c.Fatal(err)
+	}
+	m, err := lab.NewMachine(c, host, labNicConfig(host, lab.Domain, mtu, []int{vlan}), platform.QemuMachineOptions{})
+	if err != nil {
+		c.Fatal(err)
+	}
+
+	c.RunLogged("vlan-address", func(c cluster.TestCluster) {
+		c.RunCmdSync(m, "nm-online -t 60 -s")
+		out := string(c.MustSSHf(m, "ip -4 -o addr show dev labvlan%d", vlan))
+		if !strings.Contains(out, "192.168.200.") {
+			c.Fatalf("VLAN %d didn't get an address: %q", vlan, out)
+		}
+	})
+	// a frame filling the MTU, which mustn't be fragmented
+	size := mtu - 28
+	c.RunLogged("jumbo", func(c cluster.TestCluster) {
+		c.RunCmdSyncf(m, "ping -c 1 -W 5 -M do -s %d %s", size, lab.Gateway)
+	})
+	c.RunLogged("jumbo-vlan", func(c cluster.TestCluster) {
+		c.RunCmdSyncf(m, "ping -c 1 -W 5 -M do -s %d %s", size, vlanGateway)
+	})
+}
@@ -22,6 +22,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/coreos/coreos-assembler/mantle/kola"
 	"github.com/coreos/coreos-assembler/mantle/kola/cluster"
 	"github.com/coreos/coreos-assembler/mantle/kola/register"
 	"github.com/coreos/coreos-assembler/mantle/platform"
@@ -48,6 +49,33 @@ func init() {
 		Description:    "Verify that networking is not started in the initramfs on the second boot.",
 		ExcludeDistros: []string{"fcos", "rhcos"},
 	})
+	// An air-gapped machine has its own time and update servers, so the
+	// services which would reach public ones are masked.
+	register.RegisterTest(&register.Test{
+		Run:         NetworkOffline,
+		ClusterSize: 1,
+		Name:        "coreos.network.offline",
+		Description: "Verify a machine without Internet access boots without trying to reach it.",
+		Platforms:   []string{"qemu"},
+		Tags:        []string{kola.OfflineTag},
+		UserData: conf.Ignition(`{
+			"ignition": {
+				"version": "3.0.0"
+			},
+			"systemd": {
+				"units": [
+					{
+						"name": "chronyd.service",
+						"mask": true
+					},
+					{
+						"name": "zincati.service",
+						"mask": true
+					}
+				]
+			}
+		}`),
+	})
 	// This test follows the same network configuration used on https://github.com/RHsyseng/rhcos-slb
 	register.RegisterTest(&register.Test{
 		Run:         NetworkAdditionalNics,
@@ -68,7 +96,6 @@ func init() {
 		Description:    "Verify init-interfaces script works in both fresh setup and reboot.",
 		Timeout:        40 * time.Minute,
 		Distros:        []string{"rhcos"},
-		Platforms:      []string{"qemu"},
 		RequiredTag:    "openshift",
 		AdditionalNics: 2,
 		UserData:       userdata,
@@ -174,6 +201,13 @@ func NetworkListeners(c cluster.TestCluster) {
 
 // NetworkInitramfsSecondBoot verifies that networking is not started in the initramfs on the second boot.
 // https://github.com/coreos/bugs/issues/1768
+// NetworkOffline waits for the machine to finish booting; the harness
+// checks that it didn't try to reach the Internet meanwhile.
+func NetworkOffline(c cluster.TestCluster) {
+	m := c.Machines()[0]
+	c.RunCmdSync(m, "systemctl is-system-running --wait")
+}
+
 func NetworkInitramfsSecondBoot(c cluster.TestCluster) {
 	m := c.Machines()[0]
 
@@ -0,0 +1,54 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package misc
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/coreos/coreos-assembler/mantle/kola/cluster"
+	"github.com/coreos/coreos-assembler/mantle/kola/register"
+	"github.com/coreos/coreos-assembler/mantle/kola/tests/util"
+	"github.com/coreos/coreos-assembler/mantle/platform"
+)
+
+var performanceProfile = &platform.PerformanceProfile{
+	Hugepages:    64,
+	HugepageSize: "2M",
+	IsolatedCPUs: "1",
+}
+
+func init() {
+	register.RegisterTest(&register.Test{
+		Run:                PerformanceProfile,
+		ClusterSize:        1,
+		Name:               "coreos.performance-profile",
+		Description:        "Verify that machines can be booted with hugepages reserved and CPUs isolated.",
+		PerformanceProfile: performanceProfile,
+		// 2M hugepages aren't available everywhere
+		Architectures: []string{"x86_64", "aarch64"},
+	})
+}
+
+func PerformanceProfile(c cluster.TestCluster) {
+	m := c.Machines()[0]
+	// only QEMU machines are given the CPUs the profile needs
+	nproc := strings.TrimSpace(string(c.MustSSH(m, "nproc")))
+	if n, err := strconv.Atoi(nproc); err != nil || n < performanceProfile.MinCPUs() {
+		c.Skipf("Machine has %s CPUs, fewer than the %d needed", nproc, performanceProfile.MinCPUs())
+	}
+	// kola's --performance-profile doesn't override the test's
+	util.CheckPerformanceProfile(c, m, performanceProfile)
+}
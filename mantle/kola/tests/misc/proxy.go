@@ -0,0 +1,99 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package misc
+
+import (
+	"strings"
+
+	"github.com/coreos/coreos-assembler/mantle/kola"
+	"github.com/coreos/coreos-assembler/mantle/kola/cluster"
+	"github.com/coreos/coreos-assembler/mantle/kola/register"
+	"github.com/coreos/coreos-assembler/mantle/kola/tests/util"
+	"github.com/coreos/coreos-assembler/mantle/platform"
+	"github.com/coreos/coreos-assembler/mantle/platform/machine/qemu"
+)
+
+func init() {
+	register.RegisterTest(&register.Test{
+		Run:         ProxyMITM,
+		ClusterSize: 0,
+		Name:        "coreos.proxy.mitm",
+		Description: "Verify services, curl, podman and rpm-ostree work behind a proxy intercepting HTTPS.",
+		Platforms:   []string{"qemu"},
+		Fixtures:    []string{util.ProxyMITMFixture},
+		// the proxy reaches the Internet
+		Tags: []string{kola.NeedsInternetTag},
+	})
+}
+
+// proxied returns whether a request after the proxy's first n was to the
+// domain.
+func proxied(s *util.ProxyServer, n int, domain string) bool {
+	for _, r := range s.Requests()[n:] {
+		if strings.Contains(r, domain) {
+			return true
+		}
+	}
+	return false
+}
+
+func ProxyMITM(c cluster.TestCluster) {
+	s := c.Fixture(util.ProxyMITMFixture).(*util.ProxyServer)
+	m, err := c.Cluster.(*qemu.Cluster).NewMachineWithQemuOptions(nil, platform.QemuMachineOptions{
+		Proxy: &s.Config,
+	})
+	if err != nil {
+		c.Fatal(err)
+	}
+
+	c.RunLogged("environment", func(c cluster.TestCluster) {
+		// services such as zincati and rpm-ostreed get it from systemd
+		if out := string(c.MustSSH(m, "sudo systemd-run --quiet --wait --pipe printenv https_proxy")); out != s.Config.HTTPSProxy {
+			c.Fatalf("services have https_proxy %q, expected %q", out, s.Config.HTTPSProxy)
+		}
+		if out := string(c.MustSSH(m, "printenv HTTPS_PROXY")); out != s.Config.HTTPSProxy {
+			c.Fatalf("SSH sessions have HTTPS_PROXY %q, expected %q", out, s.Config.HTTPSProxy)
+		}
+	})
+
+	c.RunLogged("curl", func(c cluster.TestCluster) {
+		// the intercepted certificate is only trusted if the CA was added
+		n := len(s.Requests())
+		c.RunCmdSync(m, "curl -sS -o /dev/null https://quay.io/v2/")
+		if !proxied(s, n, "quay.io") {
+			c.Fatalf("curl didn't go through the proxy: %v", s.Requests())
+		}
+	})
+
+	c.RunLogged("podman", func(c cluster.TestCluster) {
+		n := len(s.Requests())
+		c.RunCmdSync(m, "sudo podman pull -q quay.io/libpod/alpine:latest")
+		if !proxied(s, n, "quay.io") {
+			c.Fatalf("podman didn't go through the proxy: %v", s.Requests())
+		}
+	})
+
+	c.RunLogged("rpm-ostree", func(c cluster.TestCluster) {
+		// RHCOS has no repos enabled
+		if c.Distribution() != "fcos" {
+			c.Skip("no repos to refresh")
+		}
+		n := len(s.Requests())
+		c.RunCmdSync(m, "sudo rpm-ostree refresh-md")
+		if !proxied(s, n, "fedoraproject.org") {
+			c.Fatalf("rpm-ostree didn't go through the proxy: %v", s.Requests())
+		}
+	})
+}
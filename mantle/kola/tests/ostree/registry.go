@@ -0,0 +1,97 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ostree
+
+import (
+	"github.com/coreos/coreos-assembler/mantle/kola"
+	"github.com/coreos/coreos-assembler/mantle/kola/cluster"
+	"github.com/coreos/coreos-assembler/mantle/kola/register"
+	"github.com/coreos/coreos-assembler/mantle/kola/tests/util"
+	"github.com/coreos/coreos-assembler/mantle/platform"
+)
+
+func init() {
+	register.RegisterTest(&register.Test{
+		Run:         ostreeRegistryTest,
+		ClusterSize: 1,
+		Name:        "ostree.registry",
+		Description: "Verify that the build can be deployed from a registry with rpm-ostree and bootc, and that signed images are verified.",
+		FailFast:    true,
+		Tags:        []string{"ostree"},
+		// the registry is served from the host
+		Platforms: []string{"qemu"},
+		Fixtures:  []string{util.RegistryFixture},
+	})
+}
+
+// ostreeRegistryTest deploys the build from the registry fixture, served
+// with TLS and credentials if kola's --registry-tls and --registry-auth
+// are given.
+func ostreeRegistryTest(c cluster.TestCluster) {
+	m := c.Machines()[0]
+
+	server := c.Fixture(util.RegistryFixture).(*util.RegistryServer)
+	image := server.Repo + ":latest"
+	bootcImage, err := server.Push("bootc", false)
+	if err != nil {
+		c.Fatal(err)
+	}
+	signedImage, err := server.Push("signed", true)
+	if err != nil {
+		c.Fatal(err)
+	}
+	server.Configure(c, m)
+
+	c.Run("rpm-ostree", func(c cluster.TestCluster) {
+		ref := "ostree-unverified-registry:" + image
+		c.RunCmdSyncf(m, "sudo rpm-ostree rebase %s", ref)
+		checkRegistryDeployment(c, m, ref)
+	})
+
+	c.Run("bootc", func(c cluster.TestCluster) {
+		if _, err := c.SSH(m, "command -v bootc"); err != nil {
+			c.Skip("bootc isn't installed")
+		}
+		c.RunCmdSyncf(m, "sudo bootc switch %s", bootcImage)
+		checkRegistryDeployment(c, m, "ostree-unverified-registry:"+bootcImage)
+	})
+
+	c.Run("signed", func(c cluster.TestCluster) {
+		if _, err := c.SSHf(m, "sudo rpm-ostree rebase ostree-image-signed:docker://%s", image); err == nil {
+			c.Fatal("Rebased to an unsigned image requiring a signature")
+		}
+		ref := "ostree-image-signed:docker://" + signedImage
+		c.RunCmdSyncf(m, "sudo rpm-ostree rebase %s", ref)
+		checkRegistryDeployment(c, m, ref)
+	})
+}
+
+// checkRegistryDeployment reboots the machine into the staged deployment
+// and checks that it's the build, deployed from the image reference.
+func checkRegistryDeployment(c cluster.TestCluster, m platform.Machine, ref string) {
+	if err := m.Reboot(); err != nil {
+		c.Fatalf("Failed to reboot machine: %v", err)
+	}
+	d, err := util.GetBootedDeployment(c, m)
+	if err != nil {
+		c.Fatal(err)
+	}
+	if d.ContainerImageReference != ref {
+		c.Fatalf("Got booted image reference=%s expected=%s", d.ContainerImageReference, ref)
+	}
+	if d.Version != kola.CosaBuild.Meta.OstreeVersion {
+		c.Fatalf("Got booted version=%s expected=%s", d.Version, kola.CosaBuild.Meta.OstreeVersion)
+	}
+}
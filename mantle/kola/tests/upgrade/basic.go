@@ -150,36 +150,33 @@ func fcosUpgradeBasic(c cluster.TestCluster) {
 	usingContainer := booted.ContainerImageReference != ""
 	sourceContainerRef := fmt.Sprintf("ostree-unverified-image:oci-archive:%s:latest", containerImageFilename)
 
+	hops := upgradeHops(c)
+
 	c.Run("setup", func(c cluster.TestCluster) {
-		ostreeref := kola.CosaBuild.Meta.BuildRef
+		tmprepo := workdir + "/repo-bare"
+		if !usingContainer {
+			// TODO: https://github.com/ostreedev/ostree-rs-ext/issues/34
+			c.RunCmdSyncf(m, "ostree --repo=%s init --mode=bare-user", tmprepo)
+			c.RunCmdSyncf(m, "ostree --repo=%s init --mode=archive", ostreeRepo)
+		}
+		// the hops first, so that the build's commit ends up at the ref
+		for i := range hops {
+			hops[i].containerRef = dropOstree(c, m, hops[i].archive, hops[i].ref, usingContainer, tmprepo)
+		}
 		// this is the only heavy-weight part, though remember this test is
 		// optimized for qemu testing locally where this won't leave localhost at
 		// all. cloud testing should mostly be a pipeline thing, where the infra
 		// connection should be much faster
 		ostreeTarPath := filepath.Join(kola.CosaBuild.Dir, containerImageFilename)
-		if err := cluster.DropFile(c.Machines(), ostreeTarPath); err != nil {
-			c.Fatal(err)
-		}
-
-		// Keep any changes around here in sync with tests/rhcos/upgrade.go too!
-
-		// See https://github.com/coreos/fedora-coreos-tracker/issues/812
-		if usingContainer {
-			// In the container path we'll pass this file directly, so put it outside
-			// of the user's home directory so the systemd service can find it.
-			c.RunCmdSyncf(m, "sudo mv %s /var/tmp/%s", containerImageFilename, containerImageFilename)
-			sourceContainerRef = fmt.Sprintf("ostree-unverified-image:oci-archive:/var/tmp/%s:latest", containerImageFilename)
-		} else {
-			tmprepo := workdir + "/repo-bare"
-			// TODO: https://github.com/ostreedev/ostree-rs-ext/issues/34
-			c.RunCmdSyncf(m, "ostree --repo=%s init --mode=bare-user", tmprepo)
-			c.RunCmdSyncf(m, "ostree container import --repo=%s --write-ref %s %s", tmprepo, ostreeref, sourceContainerRef)
-			c.RunCmdSyncf(m, "ostree --repo=%s init --mode=archive", ostreeRepo)
-			c.RunCmdSyncf(m, "ostree --repo=%s pull-local %s %s", ostreeRepo, tmprepo, ostreeref)
-		}
-
+		sourceContainerRef = dropOstree(c, m, ostreeTarPath, kola.CosaBuild.Meta.BuildRef, usingContainer, tmprepo)
 	})
 
+	if len(hops) > 0 {
+		c.Run("upgrade-chain", func(c cluster.TestCluster) {
+			upgradeThroughHops(c, m, graph, hops, usingContainer)
+		})
+	}
+
 	c.Run("upgrade-from-previous", func(c cluster.TestCluster) {
 		// We need to check now whether this is a within-stream update or a
 		// cross-stream rebase.
@@ -228,6 +225,102 @@ func fcosUpgradeBasic(c cluster.TestCluster) {
 	})
 }
 
+// upgradeHop is an intermediate build of the upgrade chain.
+type upgradeHop struct {
+	version string
+	commit  string
+	ref     string
+	barrier bool
+	// archive is its OSTree OCI archive on the host, and containerRef
+	// where it's imported from on the machine
+	archive      string
+	containerRef string
+}
+
+// upgradeHops fetches the OSTree archives of kola.UpgradeChain.
+func upgradeHops(c cluster.TestCluster) []upgradeHop {
+	var hops []upgradeHop
+	for _, hop := range kola.UpgradeChain {
+		archive, err := hop.Build.RequireArtifact("ostree")
+		if err != nil {
+			c.Fatalf("fetching upgrade hop %s: %v", hop.Build.Meta.BuildID, err)
+		}
+		hops = append(hops, upgradeHop{
+			version: hop.Build.Meta.OstreeVersion,
+			commit:  hop.Build.Meta.OstreeCommit,
+			ref:     hop.Build.Meta.BuildRef,
+			barrier: hop.Barrier,
+			archive: archive,
+		})
+	}
+	return hops
+}
+
+// dropOstree copies an OSTree OCI archive to the machine, and unless
+// the machine boots a container image, imports it into the repo served
+// to it at ref. Returns the container reference of the archive.
+func dropOstree(c cluster.TestCluster, m platform.Machine, archive, ref string, usingContainer bool, tmprepo string) string {
+	if err := cluster.DropFile(c.Machines(), archive); err != nil {
+		c.Fatal(err)
+	}
+	name := filepath.Base(archive)
+
+	// Keep any changes around here in sync with tests/rhcos/upgrade.go too!
+
+	// See https://github.com/coreos/fedora-coreos-tracker/issues/812
+	if usingContainer {
+		// In the container path we'll pass this file directly, so put it outside
+		// of the user's home directory so the systemd service can find it.
+		c.RunCmdSyncf(m, "sudo mv %s /var/tmp/%s", name, name)
+		return fmt.Sprintf("ostree-unverified-image:oci-archive:/var/tmp/%s:latest", name)
+	}
+	containerRef := fmt.Sprintf("ostree-unverified-image:oci-archive:%s:latest", name)
+	c.RunCmdSyncf(m, "ostree container import --repo=%s --write-ref %s %s", tmprepo, ref, containerRef)
+	c.RunCmdSyncf(m, "ostree --repo=%s pull-local %s %s", ostreeRepo, tmprepo, ref)
+	c.RunCmdSyncf(m, "rm %s", name)
+	return containerRef
+}
+
+// upgradeThroughHops upgrades the machine through the hops of the upgrade
+// chain which it should boot: the barriers, and the last hop. It serves
+// Zincati a graph like Cincinnati's, in which each release has edges to
+// the later ones up to the next barrier, so it must skip the others.
+func upgradeThroughHops(c cluster.TestCluster, m platform.Machine, graph *Graph, hops []upgradeHop, usingContainer bool) {
+	var stops []upgradeHop
+	for i, hop := range hops {
+		if hop.barrier || i == len(hops)-1 {
+			stops = append(stops, hop)
+		}
+	}
+	if usingContainer {
+		for _, stop := range stops {
+			plog.Infof("Rebasing to upgrade hop %s", stop.version)
+			rpmostreeRebase(c, m, stop.containerRef, stop.version)
+		}
+		return
+	}
+
+	d, err := util.GetBootedDeployment(c, m)
+	if err != nil {
+		c.Fatal(err)
+	}
+	if !strings.HasSuffix(d.Origin, ":"+stops[0].ref) {
+		// cross-stream, so rebase to the first stop, pinned to its commit
+		plog.Infof("Rebasing to upgrade hop %s", stops[0].version)
+		rpmostreeRebase(c, m, stops[0].ref+" "+stops[0].commit, stops[0].version)
+		for len(hops) > 0 && hops[0].version != stops[0].version {
+			hops = hops[1:]
+		}
+		hops, stops = hops[1:], stops[1:]
+	}
+	graph.seedFromMachine(c, m)
+	graph.addChain(c, m, hops)
+	for _, stop := range stops {
+		plog.Infof("Waiting for upgrade to hop %s", stop.version)
+		waitForUpgradeToVersion(c, m, stop.version)
+	}
+}
+
 // Should dedupe this with fedora-coreos-cincinnati -- we just handle the
 // bare minimum here. One question here is: why not use Cincinnati itself for
 // this? We could do this, though it'd somewhat muddle the focus of these tests
@@ -280,6 +373,32 @@ func (g *Graph) addUpdate(c cluster.TestCluster, m platform.Machine, version, pa
 	g.sync(c, m)
 }
 
+// addChain adds the hops to the graph, with edges from each release to the
+// later ones up to and including the next barrier.
+func (g *Graph) addChain(c cluster.TestCluster, m platform.Machine, hops []upgradeHop) {
+	first := len(g.Nodes)
+	for i, hop := range hops {
+		g.Nodes = append(g.Nodes, Node{
+			Version: hop.version,
+			Payload: hop.commit,
+			Metadata: map[string]string{
+				"org.fedoraproject.coreos.releases.age_index": strconv.Itoa(first + i),
+				"org.fedoraproject.coreos.scheme":             "checksum",
+			},
+		})
+	}
+	for from := 0; from < len(g.Nodes)-1; from++ {
+		for to := max(from+1, first); to < len(g.Nodes); to++ {
+			g.Edges = append(g.Edges, [2]int{from, to})
+			if hops[to-first].barrier {
+				break
+			}
+		}
+	}
+
+	g.sync(c, m)
+}
+
 func (g *Graph) sync(c cluster.TestCluster, m platform.Machine) {
 	b, err := json.Marshal(g)
 	if err != nil {
@@ -0,0 +1,109 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package upgrade
+
+import (
+	"github.com/coreos/coreos-assembler/mantle/kola"
+	"github.com/coreos/coreos-assembler/mantle/kola/cluster"
+	"github.com/coreos/coreos-assembler/mantle/kola/register"
+	"github.com/coreos/coreos-assembler/mantle/kola/tests/util"
+	"github.com/coreos/coreos-assembler/mantle/platform"
+	"github.com/coreos/coreos-assembler/mantle/platform/conf"
+)
+
+func init() {
+	register.RegisterUpgradeTest(&register.Test{
+		Run:         upgradeRollback,
+		ClusterSize: 1,
+		Name:        "upgrade.rollback",
+		Description: "Verify that the previous release can rebase to the build served over HTTP, is up to date with it, and can roll back.",
+		FailFast:    true,
+		Tags:        []string{"upgrade"},
+		// the build is served from the host
+		Platforms: []string{"qemu"},
+		UserData: conf.Ignition(`{
+			"ignition": {
+				"version": "3.0.0"
+			}
+		}`),
+	})
+}
+
+// upgradeRollback rebases the previous release to the build, from an
+// OSTree repo if it was deployed from a remote and otherwise from the
+// container image, and then rolls back to the previous release.
+func upgradeRollback(c cluster.TestCluster) {
+	m := c.Machines()[0]
+
+	previous, err := util.GetBootedDeployment(c, m)
+	if err != nil {
+		c.Fatal(err)
+	}
+	usingContainer := previous.ContainerImageReference != ""
+
+	server, err := util.ServePayload(c, !usingContainer)
+	if err != nil {
+		c.Fatal(err)
+	}
+	defer server.Close()
+
+	c.Run("rebase", func(c cluster.TestCluster) {
+		var ref string
+		if server.Ref != "" {
+			ref = server.RepoRef(c, m)
+		} else {
+			ref = server.ContainerRef(c, m)
+		}
+		runFnAndWaitForRebootIntoVersion(c, m, kola.CosaBuild.Meta.OstreeVersion, func() {
+			stopZincati(c, m)
+			c.RunCmdSyncf(m, "sudo systemd-run rpm-ostree rebase --reboot %s", ref)
+			waitForUpgradeToBeStaged(c, m)
+		})
+		if server.Ref != "" {
+			d, err := util.GetBootedDeployment(c, m)
+			if err != nil {
+				c.Fatal(err)
+			}
+			if d.Checksum != kola.CosaBuild.Meta.OstreeCommit {
+				c.Fatalf("Got booted checksum=%s expected=%s", d.Checksum, kola.CosaBuild.Meta.OstreeCommit)
+			}
+		}
+	})
+
+	c.Run("upgrade", func(c cluster.TestCluster) {
+		// the origin now tracks the served build, which is the latest
+		stopZincati(c, m)
+		c.AssertCmdOutputContains(m, "sudo rpm-ostree upgrade", "No upgrade available")
+	})
+
+	c.Run("rollback", func(c cluster.TestCluster) {
+		runFnAndWaitForRebootIntoVersion(c, m, previous.Version, func() {
+			c.RunCmdSync(m, "sudo systemd-run rpm-ostree rollback --reboot")
+		})
+		d, err := util.GetBootedDeployment(c, m)
+		if err != nil {
+			c.Fatal(err)
+		}
+		if d.Checksum != previous.Checksum {
+			c.Fatalf("Got booted checksum=%s after rollback, expected=%s", d.Checksum, previous.Checksum)
+		}
+	})
+}
+
+// stopZincati stops Zincati, if the machine has it, so that rpm-ostree
+// can be driven directly.
+func stopZincati(c cluster.TestCluster, m platform.Machine) {
+	c.RunCmdSync(m, "if systemctl -q is-active zincati.service; then sudo systemctl stop zincati.service; fi")
+}
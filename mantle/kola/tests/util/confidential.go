@@ -0,0 +1,59 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package util
+
+import (
+	"strings"
+
+	"github.com/coreos/coreos-assembler/mantle/kola/cluster"
+	"github.com/coreos/coreos-assembler/mantle/platform"
+)
+
+// ConfidentialSanityTest verifies that the machine is a confidential VM of
+// the given type, sev, sev_snp or tdx, whose memory the kernel reports as
+// encrypted, and that it can request attestation reports from the firmware.
+func ConfidentialSanityTest(c cluster.TestCluster, m platform.Machine, confidentialType string) {
+	features := c.MustSSH(m, "sudo journalctl -k -b 0 -o cat --grep 'Memory Encryption Features active'")
+	var device string
+	switch strings.ToLower(strings.ReplaceAll(confidentialType, "-", "_")) {
+	case "sev":
+		mustMatch(c, "AMD SEV", features)
+		mustNotMatch(c, "SEV-SNP", features)
+	case "sev_snp":
+		mustMatch(c, "AMD SEV.*SEV-SNP", features)
+		device = "/dev/sev-guest"
+	case "tdx":
+		mustMatch(c, "Intel TDX", features)
+		device = "/dev/tdx_guest"
+	default:
+		c.Fatalf("Unknown confidential type %q, should be: sev, sev_snp, tdx", confidentialType)
+	}
+	// SEV-SNP and TDX guests get attestation reports through a device
+	if device != "" {
+		c.RunCmdSync(m, "test -c "+device)
+	}
+}
+
+// ShieldedVMSanityTest verifies that the machine has a vTPM, and that
+// secure boot is enabled if secureBoot is set and disabled otherwise.
+func ShieldedVMSanityTest(c cluster.TestCluster, m platform.Machine, secureBoot bool) {
+	c.RunCmdSync(m, "test -c /dev/tpmrm0")
+	// The variable's data follows its 4 bytes of attributes
+	state := c.MustSSH(m, "od -An -t u1 -j 4 -N 1 /sys/firmware/efi/efivars/SecureBoot-8be4df61-93ca-11d2-aa0d-00e098032b8c")
+	if secureBoot {
+		mustMatch(c, "^ *1$", state)
+	} else {
+		mustMatch(c, "^ *0$", state)
+	}
+}
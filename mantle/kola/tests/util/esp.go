@@ -0,0 +1,97 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/coreos/coreos-assembler/mantle/kola/cluster"
+	"github.com/coreos/coreos-assembler/mantle/platform"
+)
+
+// ESPDevice is the EFI system partition of CoreOS machines' boot disk.
+const ESPDevice = "/dev/disk/by-label/EFI-SYSTEM"
+
+// efiBootEntryRe matches an entry in the output of efibootmgr, e.g.
+// "Boot0004* Fedora\tHD(...)".
+var efiBootEntryRe = regexp.MustCompile(`^Boot([0-9A-Fa-f]{4})(\*?) ([^\t]*)`)
+
+// EFIBootEntries are a machine's UEFI boot entries.
+type EFIBootEntries struct {
+	// Current is the number of the entry the machine booted from
+	Current string
+	Order   []string
+	// Labels maps the numbers of the entries to their labels
+	Labels map[string]string
+}
+
+// CurrentLabel returns the label of the entry the machine booted from.
+func (e EFIBootEntries) CurrentLabel() string {
+	return e.Labels[e.Current]
+}
+
+// MountESP mounts the ESP of m at dir, read-only unless rw is set, creating
+// dir if needed. The caller should unmount it with UnmountESP.
+func MountESP(c cluster.TestCluster, m platform.Machine, dir string, rw bool) {
+	mode := "ro"
+	if rw {
+		mode = "rw"
+	}
+	c.RunCmdSyncf(m, "sudo mkdir -p %s && sudo mount -o %s %s %s", dir, mode, ESPDevice, dir)
+}
+
+// UnmountESP unmounts the ESP mounted at dir by MountESP.
+func UnmountESP(c cluster.TestCluster, m platform.Machine, dir string) {
+	c.RunCmdSyncf(m, "sudo umount %s", dir)
+}
+
+// ESPFiles returns the SHA-256 digests of the files in the ESP of m, by
+// their paths relative to its root.
+func ESPFiles(c cluster.TestCluster, m platform.Machine) map[string]string {
+	const dir = "/run/kola-esp"
+	MountESP(c, m, dir, false)
+	out, err := c.SSHf(m, "cd %s && sudo find . -type f -exec sha256sum {} +", dir)
+	UnmountESP(c, m, dir)
+	if err != nil {
+		c.Fatalf("listing ESP files: %v", err)
+	}
+	files := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		sum, path, ok := strings.Cut(line, "  ")
+		if !ok {
+			continue
+		}
+		files[strings.TrimPrefix(path, "./")] = sum
+	}
+	return files
+}
+
+// GetEFIBootEntries returns the UEFI boot entries of m, as efibootmgr
+// reports them.
+func GetEFIBootEntries(c cluster.TestCluster, m platform.Machine) EFIBootEntries {
+	out := c.MustSSH(m, "sudo efibootmgr")
+	entries := EFIBootEntries{Labels: make(map[string]string)}
+	for _, line := range strings.Split(string(out), "\n") {
+		if v, ok := strings.CutPrefix(line, "BootCurrent: "); ok {
+			entries.Current = strings.TrimSpace(v)
+		} else if v, ok := strings.CutPrefix(line, "BootOrder: "); ok {
+			entries.Order = strings.Split(strings.TrimSpace(v), ",")
+		} else if match := efiBootEntryRe.FindStringSubmatch(line); match != nil {
+			entries.Labels[match[1]] = strings.TrimSpace(match[3])
+		}
+	}
+	return entries
+}
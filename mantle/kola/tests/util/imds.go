@@ -0,0 +1,55 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/coreos/coreos-assembler/mantle/kola/cluster"
+	"github.com/coreos/coreos-assembler/mantle/platform"
+)
+
+const imdsURL = "http://169.254.169.254/latest"
+
+// AWSIMDSSanityTest verifies that the machine reaches the EC2 instance
+// metadata service with IMDSv2 session tokens, as Afterburn does, and that
+// requests without one are refused if imdsv2Only is set. It then checks
+// that Afterburn fetched the instance's metadata and, unless the test
+// keeps them out of the metadata, its SSH keys.
+func AWSIMDSSanityTest(c cluster.TestCluster, m platform.Machine, imdsv2Only, sshKeys bool) {
+	token := strings.TrimSpace(string(c.MustSSH(m, fmt.Sprintf("curl -sSf -X PUT -H 'X-aws-ec2-metadata-token-ttl-seconds: 300' %s/api/token", imdsURL))))
+	if token == "" {
+		c.Fatalf("Got an empty IMDSv2 token")
+	}
+	id := c.MustSSH(m, fmt.Sprintf("curl -sSf -H 'X-aws-ec2-metadata-token: %s' %s/meta-data/instance-id", token, imdsURL))
+	mustMatch(c, "^i-[0-9a-f]+$", id)
+
+	status := c.MustSSH(m, fmt.Sprintf("curl -s -o /dev/null -w '%%{http_code}' %s/meta-data/instance-id", imdsURL))
+	if imdsv2Only {
+		mustMatch(c, "^401$", status)
+	} else {
+		mustMatch(c, "^200$", status)
+	}
+
+	metadata := c.MustSSH(m, "cat /run/metadata/afterburn")
+	mustMatch(c, "(?m)^AFTERBURN_AWS_INSTANCE_ID="+string(id)+"$", metadata)
+
+	if sshKeys {
+		result := c.MustSSH(m, "systemctl show -p Result afterburn-sshkeys@core.service")
+		mustMatch(c, "^Result=success$", result)
+		c.RunCmdSync(m, "test -s ~/.ssh/authorized_keys.d/afterburn")
+	}
+}
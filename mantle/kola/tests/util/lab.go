@@ -0,0 +1,96 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/coreos/coreos-assembler/mantle/kola/cluster"
+	"github.com/coreos/coreos-assembler/mantle/kola/register"
+	"github.com/coreos/coreos-assembler/mantle/platform"
+	"github.com/coreos/coreos-assembler/mantle/platform/conf"
+	"github.com/coreos/coreos-assembler/mantle/platform/local"
+	"github.com/coreos/coreos-assembler/mantle/platform/machine/qemu"
+)
+
+// LabFixture is the name of the fixture providing a lab network, a private
+// network with DHCP, DNS, TFTP and HTTP served by the host, for tests
+// provisioning machines as in a datacenter. Its value is a *LabNetwork.
+const LabFixture = "lab"
+
+func init() {
+	register.RegisterFixture(&cluster.Fixture{
+		Name: LabFixture,
+		Setup: func(c cluster.TestCluster) (interface{}, error) {
+			return NewLabNetwork(c, local.LabOptions{})
+		},
+		Teardown: func(c cluster.TestCluster, value interface{}) error {
+			return value.(*LabNetwork).Close()
+		},
+	})
+}
+
+// LabNetwork is a lab network whose TFTP and HTTP servers serve Dir, where
+// tests put the files machines fetch, such as PXE artifacts.
+type LabNetwork struct {
+	*local.Lab
+	Dir string
+}
+
+// NewLabNetwork creates a lab network for QEMU machines, serving a new Dir;
+// it needs the privileges to create network namespaces.
+func NewLabNetwork(c cluster.TestCluster, opts local.LabOptions) (*LabNetwork, error) {
+	if _, ok := c.Cluster.(*qemu.Cluster); !ok {
+		return nil, fmt.Errorf("lab networks are only supported on QEMU")
+	}
+	dir, err := os.MkdirTemp("/var/tmp", "mantle-lab-files")
+	if err != nil {
+		return nil, err
+	}
+	// world-readable, since dnsmasq drops privileges to serve TFTP
+	if err := os.Chmod(dir, 0755); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	opts.TFTPDir = dir
+	opts.HTTPDir = dir
+	lab, err := local.NewLab(opts)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	return &LabNetwork{Lab: lab, Dir: dir}, nil
+}
+
+// NewMachine creates a machine attached to the network as the host, added
+// with AddHost, besides the usual user-mode NIC tests SSH over.
+func (n *LabNetwork) NewMachine(c cluster.TestCluster, host *local.LabHost, userdata *conf.UserData, options platform.QemuMachineOptions) (platform.Machine, error) {
+	nic, err := n.Nic(host)
+	if err != nil {
+		return nil, err
+	}
+	options.TapNics = append(options.TapNics, nic)
+	return c.Cluster.(*qemu.Cluster).NewMachineWithQemuOptions(userdata, options)
+}
+
+// Close removes the network and the files it served.
+func (n *LabNetwork) Close() error {
+	err := n.Lab.Close()
+	if rmErr := os.RemoveAll(n.Dir); err == nil {
+		err = rmErr
+	}
+	return err
+}
@@ -0,0 +1,140 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/coreos/coreos-assembler/mantle/kola"
+	"github.com/coreos/coreos-assembler/mantle/kola/cluster"
+	"github.com/coreos/coreos-assembler/mantle/platform"
+	"github.com/coreos/coreos-assembler/mantle/platform/machine/qemu"
+)
+
+// payloadRemote is the OSTree remote machines pull the served repo from.
+const payloadRemote = "kola"
+
+// PayloadServer serves the build under test to QEMU machines from the
+// host over HTTP, as its OSTree container image and optionally as an
+// archive OSTree repo, so that they can upgrade to it as they would from
+// a registry or remote.
+type PayloadServer struct {
+	// URL is the base URL machines reach the server at.
+	URL string
+	// Ref is the ref of the build's commit in the repo, or empty if there's
+	// no repo.
+	Ref string
+
+	ociArchive string
+	tempdir    string
+	listener   net.Listener
+}
+
+// ServePayload serves the build under test until the server is closed. If
+// withRepo is set and the build has a ref, its commit is also served in an
+// archive repo, made from the container image with the host's ostree.
+func ServePayload(c cluster.TestCluster, withRepo bool) (*PayloadServer, error) {
+	if _, ok := c.Cluster.(*qemu.Cluster); !ok {
+		return nil, fmt.Errorf("serving the build is only supported on QEMU")
+	}
+	ociArchive := kola.CosaBuild.Meta.BuildArtifacts.Ostree.Path
+	tempdir, err := os.MkdirTemp("/var/tmp", "mantle-payload")
+	if err != nil {
+		return nil, err
+	}
+	s := &PayloadServer{
+		ociArchive: ociArchive,
+		tempdir:    tempdir,
+	}
+	if err := os.Symlink(filepath.Join(kola.CosaBuild.Dir, ociArchive), filepath.Join(tempdir, ociArchive)); err != nil {
+		s.Close()
+		return nil, err
+	}
+
+	ref := kola.CosaBuild.Meta.BuildRef
+	if withRepo && ref != "" {
+		if err := makeRepo(tempdir, ref, filepath.Join(kola.CosaBuild.Dir, ociArchive)); err != nil {
+			s.Close()
+			return nil, fmt.Errorf("making repo: %v", err)
+		}
+		s.Ref = ref
+	}
+
+	// QEMU's usermode networking forwards the host address to localhost
+	s.listener, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	s.URL = fmt.Sprintf("http://10.0.2.2:%d", s.listener.Addr().(*net.TCPAddr).Port)
+	go func() {
+		err := http.Serve(s.listener, http.FileServer(http.Dir(tempdir)))
+		plog.Debugf("stopped serving payload: %v", err)
+	}()
+	return s, nil
+}
+
+// makeRepo imports the commit in the OCI archive into an archive repo in
+// dir with the ref, through a bare-user repo since the container can't be
+// unencapsulated into archive repos.
+func makeRepo(dir, ref, ociArchive string) error {
+	repo := filepath.Join(dir, "repo")
+	cache := filepath.Join(dir, "repo-cache")
+	defer os.RemoveAll(cache)
+	runner := platform.HelperRunner
+	if _, err := runner.Run("ostree", "--repo="+cache, "init", "--mode=bare-user"); err != nil {
+		return err
+	}
+	if _, err := runner.Run("ostree", "container", "unencapsulate", "--repo="+cache, "--write-ref", ref, "ostree-unverified-image:oci-archive:"+ociArchive); err != nil {
+		return err
+	}
+	if _, err := runner.Run("ostree", "--repo="+repo, "init", "--mode=archive"); err != nil {
+		return err
+	}
+	if _, err := runner.Run("ostree", "--repo="+repo, "pull-local", cache, ref); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ContainerRef downloads the container image to the machine and returns
+// the reference rpm-ostree rebases to it with.
+func (s *PayloadServer) ContainerRef(c cluster.TestCluster, m platform.Machine) string {
+	path := "/var/tmp/" + s.ociArchive
+	c.RunCmdSyncf(m, "sudo curl -sSf -o %s %s/%s", path, s.URL, s.ociArchive)
+	return "ostree-unverified-image:oci-archive:" + path
+}
+
+// RepoRef adds the repo as a remote of the machine and returns the refspec
+// rpm-ostree rebases to the build with. The server must have a repo.
+func (s *PayloadServer) RepoRef(c cluster.TestCluster, m platform.Machine) string {
+	if s.Ref == "" {
+		c.Fatal("the build isn't served in a repo")
+	}
+	c.RunCmdSyncf(m, "sudo ostree remote add --force --no-gpg-verify %s %s/repo", payloadRemote, s.URL)
+	return payloadRemote + ":" + s.Ref
+}
+
+// Close stops serving the build and removes the repo.
+func (s *PayloadServer) Close() {
+	if s.listener != nil {
+		s.listener.Close()
+	}
+	os.RemoveAll(s.tempdir)
+}
@@ -0,0 +1,83 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/coreos/coreos-assembler/mantle/kola/cluster"
+	"github.com/coreos/coreos-assembler/mantle/platform"
+)
+
+// CheckPerformanceProfile checks that the machine is tuned by the profile:
+// that it booted with its kernel arguments and runs the realtime kernel,
+// and that its hugepages are reserved, its CPUs isolated and its tuned
+// profile active.
+func CheckPerformanceProfile(c cluster.TestCluster, m platform.Machine, p *platform.PerformanceProfile) {
+	cmdline := strings.Fields(string(c.MustSSH(m, "cat /proc/cmdline")))
+	for _, arg := range p.KernelArgs() {
+		if !slices.Contains(cmdline, arg) {
+			c.Fatalf("Kernel argument %s missing from %q", arg, strings.Join(cmdline, " "))
+		}
+	}
+
+	if p.RealtimeKernel {
+		realtime := strings.TrimSpace(string(c.MustSSH(m, "cat /sys/kernel/realtime 2>/dev/null || echo 0")))
+		if realtime != "1" {
+			c.Fatalf("Not running the realtime kernel: %s", c.MustSSH(m, "uname -r"))
+		}
+		c.AssertCmdOutputContains(m, "rpm-ostree status --booted", "kernel-rt")
+	}
+
+	if p.Hugepages > 0 {
+		kb, err := platform.HugepageSizeKB(p.HugepageSize)
+		if err != nil {
+			c.Fatal(err)
+		}
+		out := c.MustSSHf(m, "cat /sys/kernel/mm/hugepages/hugepages-%dkB/nr_hugepages", kb)
+		if n, err := strconv.Atoi(strings.TrimSpace(string(out))); err != nil || n != p.Hugepages {
+			c.Fatalf("Got %s %s hugepages, expected %d", strings.TrimSpace(string(out)), p.HugepageSize, p.Hugepages)
+		}
+	}
+
+	if p.IsolatedCPUs != "" {
+		want, err := platform.ParseCPUList(p.IsolatedCPUs)
+		if err != nil {
+			c.Fatal(err)
+		}
+		for _, file := range []string{"isolated", "nohz_full"} {
+			out := strings.TrimSpace(string(c.MustSSHf(m, "cat /sys/devices/system/cpu/%s", file)))
+			var got []int
+			if out != "" {
+				if got, err = platform.ParseCPUList(out); err != nil {
+					c.Fatal(err)
+				}
+			}
+			if fmt.Sprint(got) != fmt.Sprint(want) {
+				c.Fatalf("Got %s CPUs %q, expected %q", file, out, p.IsolatedCPUs)
+			}
+		}
+	}
+
+	if p.TunedProfile != "" {
+		out := strings.TrimSpace(string(c.MustSSH(m, "sudo tuned-adm active")))
+		if active := strings.TrimPrefix(out, "Current active profile: "); active != p.TunedProfile {
+			c.Fatalf("Got active tuned profile %q, expected %q", active, p.TunedProfile)
+		}
+	}
+}
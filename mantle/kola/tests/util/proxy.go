@@ -0,0 +1,90 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/coreos/coreos-assembler/mantle/kola/cluster"
+	"github.com/coreos/coreos-assembler/mantle/kola/register"
+	"github.com/coreos/coreos-assembler/mantle/network/proxy"
+	"github.com/coreos/coreos-assembler/mantle/platform"
+	"github.com/coreos/coreos-assembler/mantle/platform/machine/qemu"
+)
+
+const (
+	// ProxyFixture is the name of the fixture serving an HTTP proxy which
+	// tunnels HTTPS. Its value is a *ProxyServer.
+	ProxyFixture = "proxy"
+	// ProxyMITMFixture is the name of the fixture serving an HTTP proxy
+	// which intercepts HTTPS, as corporate proxies do, with a CA machines
+	// are configured to trust. Its value is a *ProxyServer.
+	ProxyMITMFixture = "proxy-mitm"
+)
+
+func init() {
+	for _, mitm := range []bool{false, true} {
+		name := ProxyFixture
+		if mitm {
+			name = ProxyMITMFixture
+		}
+		register.RegisterFixture(&cluster.Fixture{
+			Name: name,
+			Setup: func(c cluster.TestCluster) (interface{}, error) {
+				return ServeProxy(c, mitm)
+			},
+			Teardown: func(c cluster.TestCluster, value interface{}) error {
+				return value.(*ProxyServer).Close()
+			},
+		})
+	}
+}
+
+// ProxyServer is an HTTP proxy on the host, which QEMU machines reach at
+// the gateway of user-mode networking.
+type ProxyServer struct {
+	*proxy.Proxy
+	// Config configures a machine to use the proxy, with
+	// platform.QemuMachineOptions.Proxy.
+	Config platform.ProxyConfig
+}
+
+// ServeProxy starts a proxy for QEMU machines, intercepting HTTPS if mitm
+// is set.
+func ServeProxy(c cluster.TestCluster, mitm bool) (*ProxyServer, error) {
+	if _, ok := c.Cluster.(*qemu.Cluster); !ok {
+		return nil, fmt.Errorf("serving a proxy is only supported on QEMU")
+	}
+	p, err := proxy.New(proxy.Options{MITM: mitm})
+	if err != nil {
+		return nil, err
+	}
+	_, port, err := net.SplitHostPort(p.Addr)
+	if err != nil {
+		p.Close()
+		return nil, err
+	}
+	url := fmt.Sprintf("http://%s", net.JoinHostPort(qemuHost, port))
+	return &ProxyServer{
+		Proxy: p,
+		Config: platform.ProxyConfig{
+			HTTPProxy:  url,
+			HTTPSProxy: url,
+			NoProxy:    "localhost,127.0.0.1",
+			CACert:     p.CACert,
+		},
+	}, nil
+}
@@ -0,0 +1,313 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/coreos/coreos-assembler/mantle/kola"
+	"github.com/coreos/coreos-assembler/mantle/kola/cluster"
+	"github.com/coreos/coreos-assembler/mantle/kola/register"
+	"github.com/coreos/coreos-assembler/mantle/network/registry"
+	"github.com/coreos/coreos-assembler/mantle/platform"
+	"github.com/coreos/coreos-assembler/mantle/platform/machine/qemu"
+)
+
+const (
+	// qemuHost is the address QEMU's usermode networking forwards to
+	// the host's localhost.
+	qemuHost = "10.0.2.2"
+	// registryRepo is the repository the build is pushed to.
+	registryRepo = "coreos"
+	// registryKey is the name of the files the registry's CA certificate,
+	// signing key and configs are installed as on machines.
+	registryKey = "kola-registry"
+)
+
+// RegistryFixture is the name of the fixture serving a registry to tests
+// which need one. Its value is a *RegistryServer with the build pushed as
+// latest, and the archives given by kola.RegistrySeeds.
+const RegistryFixture = "registry"
+
+func init() {
+	register.RegisterFixture(&cluster.Fixture{
+		Name: RegistryFixture,
+		Setup: func(c cluster.TestCluster) (interface{}, error) {
+			s, err := ServeRegistry(c)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := s.Push("latest", false); err != nil {
+				s.Close()
+				return nil, err
+			}
+			for _, seed := range kola.RegistrySeeds {
+				if err := s.Seed(seed); err != nil {
+					s.Close()
+					return nil, err
+				}
+			}
+			return s, nil
+		},
+		Teardown: func(c cluster.TestCluster, value interface{}) error {
+			value.(*RegistryServer).Close()
+			return nil
+		},
+	})
+}
+
+// RegistryServer is an ephemeral registry on the host which the build
+// under test is pushed to, so that QEMU machines can deploy it as they
+// would from a real one. With kola.RegistryTLS and kola.RegistryAuth it
+// serves HTTPS and requires credentials.
+type RegistryServer struct {
+	// Host is the host:port machines reach the registry at.
+	Host string
+	// Repo is the repository machines pull the build from.
+	Repo string
+
+	registry *registry.Registry
+	tempdir  string
+	// localRepo is the repository the build is pushed to from the host.
+	localRepo string
+	username  string
+	password  string
+	// signed is whether an image was signed with the key in tempdir.
+	signed bool
+}
+
+// ServeRegistry serves an empty registry until it's closed.
+func ServeRegistry(c cluster.TestCluster) (*RegistryServer, error) {
+	if _, ok := c.Cluster.(*qemu.Cluster); !ok {
+		return nil, fmt.Errorf("serving a registry is only supported on QEMU")
+	}
+	tempdir, err := os.MkdirTemp("/var/tmp", "mantle-registry-client")
+	if err != nil {
+		return nil, err
+	}
+	s := &RegistryServer{tempdir: tempdir}
+	opts := registry.Options{
+		TLS:   kola.RegistryTLS,
+		Hosts: []string{qemuHost},
+	}
+	if kola.RegistryAuth {
+		s.username = "kola"
+		s.password, err = randomHex()
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+		opts.Username, opts.Password = s.username, s.password
+	}
+	s.registry, err = registry.New(opts)
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	_, port, _ := strings.Cut(s.registry.Addr, ":")
+	s.Host = qemuHost + ":" + port
+	s.Repo = s.Host + "/" + registryRepo
+	s.localRepo = s.registry.Addr + "/" + registryRepo
+
+	if s.registry.CACert != nil {
+		if err := writeFile(filepath.Join(tempdir, "certs", "ca.crt"), s.registry.CACert, 0644); err != nil {
+			s.Close()
+			return nil, err
+		}
+	}
+	if s.username != "" {
+		if err := writeFile(filepath.Join(tempdir, "auth.json"), s.authJSON(s.registry.Addr), 0600); err != nil {
+			s.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func randomHex() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func writeFile(path string, data []byte, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, mode)
+}
+
+// authJSON returns a containers auth file with the registry's credentials
+// for host.
+func (s *RegistryServer) authJSON(host string) []byte {
+	auth := base64.StdEncoding.EncodeToString([]byte(s.username + ":" + s.password))
+	data, _ := json.Marshal(map[string]interface{}{
+		"auths": map[string]interface{}{
+			host: map[string]string{"auth": auth},
+		},
+	})
+	return data
+}
+
+// registriesD returns a registries.d config attaching sigstore signatures
+// to images of the repo.
+func registriesD(repo string) []byte {
+	return []byte(fmt.Sprintf("docker:\n  %s:\n    use-sigstore-attachments: true\n", repo))
+}
+
+// Push pushes the build's container image to the tag, signing it with a
+// sigstore key made for the registry if sign is set, and returns the
+// image's reference on machines. Unsigned images are imported directly,
+// while signing them needs skopeo.
+func (s *RegistryServer) Push(tag string, sign bool) (string, error) {
+	ociArchive := filepath.Join(kola.CosaBuild.Dir, kola.CosaBuild.Meta.BuildArtifacts.Ostree.Path)
+	if !sign {
+		if err := s.registry.Import(ociArchive, registryRepo, tag); err != nil {
+			return "", fmt.Errorf("pushing build: %v", err)
+		}
+		return s.Repo + ":" + tag, nil
+	}
+
+	runner := platform.HelperRunner
+	registriesDir := filepath.Join(s.tempdir, "registries.d")
+	if !s.signed {
+		passphrase, err := randomHex()
+		if err != nil {
+			return "", err
+		}
+		if err := writeFile(filepath.Join(s.tempdir, "passphrase"), []byte(passphrase), 0600); err != nil {
+			return "", err
+		}
+		if _, err := runner.Run("skopeo", "generate-sigstore-key", "--output-prefix", filepath.Join(s.tempdir, "key"), "--passphrase-file", filepath.Join(s.tempdir, "passphrase")); err != nil {
+			return "", err
+		}
+		if err := writeFile(filepath.Join(registriesDir, "kola.yaml"), registriesD(s.localRepo), 0644); err != nil {
+			return "", err
+		}
+		s.signed = true
+	}
+	args := []string{"--registries.d", registriesDir, "copy", "--quiet"}
+	if s.registry.CACert != nil {
+		args = append(args, "--dest-cert-dir", filepath.Join(s.tempdir, "certs"))
+	} else {
+		args = append(args, "--dest-tls-verify=false")
+	}
+	if s.username != "" {
+		// not --dest-creds, since commands are logged
+		args = append(args, "--dest-authfile", filepath.Join(s.tempdir, "auth.json"))
+	}
+	args = append(args,
+		"--sign-by-sigstore-private-key", filepath.Join(s.tempdir, "key.private"),
+		"--sign-passphrase-file", filepath.Join(s.tempdir, "passphrase"),
+		"oci-archive:"+ociArchive, fmt.Sprintf("docker://%s:%s", s.localRepo, tag))
+	if _, err := runner.Run("skopeo", args...); err != nil {
+		return "", fmt.Errorf("pushing build: %v", err)
+	}
+	return s.Repo + ":" + tag, nil
+}
+
+// Seed imports an OCI archive into the registry, given as
+// REPO[:TAG]=PATH; without a tag, its images are tagged by their ref names
+// in the archive.
+func (s *RegistryServer) Seed(seed string) error {
+	ref, path, ok := strings.Cut(seed, "=")
+	if !ok || ref == "" || path == "" {
+		return fmt.Errorf("registry seed %q isn't REPO[:TAG]=PATH", seed)
+	}
+	repo, tag, _ := strings.Cut(ref, ":")
+	if err := s.registry.Import(path, repo, tag); err != nil {
+		return fmt.Errorf("seeding registry: %v", err)
+	}
+	return nil
+}
+
+// Configure makes the machine trust the registry, by its CA certificate
+// with TLS or otherwise as an insecure registry, and log into it if it
+// requires credentials. If an image was signed, the machine's policy
+// requires the repo's images to be signed by its key.
+func (s *RegistryServer) Configure(c cluster.TestCluster, m platform.Machine) {
+	host, _, _ := strings.Cut(s.Repo, "/")
+	install := func(data []byte, path string) {
+		if err := platform.InstallFile(bytes.NewReader(data), m, path); err != nil {
+			c.Fatalf("installing %s: %v", path, err)
+		}
+	}
+	if s.registry.CACert != nil {
+		install(s.registry.CACert, fmt.Sprintf("/etc/containers/certs.d/%s/ca.crt", host))
+	} else {
+		install([]byte(fmt.Sprintf("[[registry]]\nlocation = %q\ninsecure = true\n", host)),
+			fmt.Sprintf("/etc/containers/registries.conf.d/50-%s.conf", registryKey))
+	}
+	if s.username != "" {
+		// rpm-ostree and bootc don't use the containers auth file
+		install(s.authJSON(host), "/etc/ostree/auth.json")
+		c.RunCmdSync(m, "sudo chmod 0600 /etc/ostree/auth.json")
+	}
+	if !s.signed {
+		return
+	}
+
+	key, err := os.ReadFile(filepath.Join(s.tempdir, "key.pub"))
+	if err != nil {
+		c.Fatal(err)
+	}
+	keyPath := fmt.Sprintf("/etc/pki/containers/%s.pub", registryKey)
+	install(key, keyPath)
+	install(registriesD(s.Repo), fmt.Sprintf("/etc/containers/registries.d/%s.yaml", registryKey))
+	// images were signed as pushed from the host
+	localHost, _, _ := strings.Cut(s.localRepo, "/")
+	policy, err := json.Marshal(map[string]interface{}{
+		"default": []map[string]string{{"type": "insecureAcceptAnything"}},
+		"transports": map[string]interface{}{
+			"docker": map[string]interface{}{
+				s.Repo: []map[string]interface{}{{
+					"type":    "sigstoreSigned",
+					"keyPath": keyPath,
+					"signedIdentity": map[string]string{
+						"type":         "remapIdentity",
+						"prefix":       host,
+						"signedPrefix": localHost,
+					},
+				}},
+			},
+			"docker-daemon": map[string]interface{}{
+				"": []map[string]string{{"type": "insecureAcceptAnything"}},
+			},
+		},
+	})
+	if err != nil {
+		c.Fatal(err)
+	}
+	install(policy, "/etc/containers/policy.json")
+	c.RunCmdSync(m, "sudo chmod 0644 /etc/containers/policy.json "+keyPath)
+}
+
+// Close stops serving the registry and removes the images and keys.
+func (s *RegistryServer) Close() {
+	if s.registry != nil {
+		s.registry.Close()
+	}
+	os.RemoveAll(s.tempdir)
+}
@@ -0,0 +1,34 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kola
+
+import (
+	"github.com/coreos/coreos-assembler/mantle/util"
+)
+
+// UpgradeHop is an intermediate build of a multi-hop upgrade chain.
+type UpgradeHop struct {
+	// Build is the hop's metadata, with a RemoteURL its artifacts are
+	// fetched from into a scratch directory.
+	Build *util.LocalBuild
+	// Barrier is whether the hop is a barrier release, which machines
+	// upgrade to before upgrading any further. Machines skip hops which
+	// aren't when a later one is available.
+	Barrier bool
+}
+
+// UpgradeChain is the builds upgrade tests upgrade through, oldest first,
+// between the starting image and CosaBuild.
+var UpgradeChain []UpgradeHop
@@ -0,0 +1,212 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kola
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// WatchdogAction is what's done when a watchdog finds something.
+type WatchdogAction string
+
+const (
+	WatchdogFail   WatchdogAction = "fail"
+	WatchdogWarn   WatchdogAction = "warn"
+	WatchdogIgnore WatchdogAction = "ignore"
+)
+
+// WatchdogPolicyFile is a YAML file setting the action of each watchdog
+// and what they ignore. Defaults to src/config/kola-watchdogs.yaml in the
+// workdir; without it, all watchdogs warn.
+var WatchdogPolicyFile string
+
+// watchdogs scan the journals of every test's machines for problems which
+// tests don't assert on themselves. The first subexpression is what's
+// reported, so that repeats of the same problem are reported once.
+var watchdogs = []struct {
+	name  string
+	desc  string
+	match *regexp.Regexp
+}{
+	{
+		name:  "avc",
+		desc:  "SELinux denial",
+		match: regexp.MustCompile(`(avc:\s+denied\s+\{[^}]*\}.*)`),
+	},
+	{
+		name:  "failed-units",
+		desc:  "failed unit",
+		match: regexp.MustCompile(`(\S+\.(?:service|socket|mount|swap|path|timer|target)): Failed with result '[^']*'`),
+	},
+	{
+		name:  "coredumps",
+		desc:  "core dump",
+		match: regexp.MustCompile(`Process \d+ \((.*?)\) of user \d+ dumped core`),
+	},
+}
+
+// avcVolatileRe matches the fields of AVC denials which differ between
+// occurrences of the same denial.
+var avcVolatileRe = regexp.MustCompile(`\s(pid|ino|dev|ses)=\S+`)
+
+type WatchdogIgnoreObj struct {
+	Watchdog string   `yaml:"watchdog"`
+	Pattern  string   `yaml:"pattern"`
+	Tests    []string `yaml:"tests"`
+	Arches   []string `yaml:"arches"`
+	Tracker  string   `yaml:"tracker"`
+
+	re *regexp.Regexp
+}
+
+// WatchdogPolicy is the parsed WatchdogPolicyFile.
+type WatchdogPolicy struct {
+	Actions map[string]WatchdogAction `yaml:"actions"`
+	Ignore  []WatchdogIgnoreObj       `yaml:"ignore"`
+}
+
+// WatchdogFinding is a problem a watchdog found on a machine.
+type WatchdogFinding struct {
+	Desc   string
+	Detail string
+	Action WatchdogAction
+}
+
+func (f WatchdogFinding) String() string {
+	return fmt.Sprintf("%s: %s", f.Desc, f.Detail)
+}
+
+// watchdogPolicy is the policy loaded by parseWatchdogPolicy
+var watchdogPolicy = &WatchdogPolicy{}
+
+// parseWatchdogPolicy loads WatchdogPolicyFile.
+func parseWatchdogPolicy() error {
+	watchdogPolicy = &WatchdogPolicy{}
+
+	path := WatchdogPolicyFile
+	if path == "" {
+		// Without a workdir there's no default policy
+		if Options.CosaWorkdir == "" {
+			return nil
+		}
+		path = filepath.Join(Options.CosaWorkdir, "src/config/kola-watchdogs.yaml")
+	}
+	buf, err := os.ReadFile(path)
+	if os.IsNotExist(err) && WatchdogPolicyFile == "" {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	var policy WatchdogPolicy
+	if err := yaml.Unmarshal(buf, &policy); err != nil {
+		return fmt.Errorf("parsing %s: %v", path, err)
+	}
+	known := func(name string) bool {
+		for _, w := range watchdogs {
+			if w.name == name {
+				return true
+			}
+		}
+		return false
+	}
+	for name, action := range policy.Actions {
+		if !known(name) {
+			return fmt.Errorf("%s: unknown watchdog %q", path, name)
+		}
+		switch action {
+		case WatchdogFail, WatchdogWarn, WatchdogIgnore:
+		default:
+			return fmt.Errorf("%s: unknown action %q for watchdog %s", path, action, name)
+		}
+	}
+	for i := range policy.Ignore {
+		obj := &policy.Ignore[i]
+		if obj.Watchdog != "" && !known(obj.Watchdog) {
+			return fmt.Errorf("%s: unknown watchdog %q", path, obj.Watchdog)
+		}
+		if obj.re, err = regexp.Compile(obj.Pattern); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	watchdogPolicy = &policy
+	return nil
+}
+
+// ignores returns whether the policy ignores what the watchdog found in
+// the test's journal.
+func (p *WatchdogPolicy) ignores(watchdog, test, detail string) bool {
+	for _, obj := range p.Ignore {
+		if obj.Watchdog != "" && obj.Watchdog != watchdog {
+			continue
+		}
+		if len(obj.Arches) > 0 && !HasString(Options.CosaBuildArch, obj.Arches) {
+			continue
+		}
+		if len(obj.Tests) > 0 {
+			matched := false
+			for _, pattern := range obj.Tests {
+				if found, err := filepath.Match(pattern, test); err == nil && found {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				continue
+			}
+		}
+		if obj.re.MatchString(detail) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckWatchdogs returns what the watchdogs find in the journal of a
+// machine of the test, besides what the policy ignores, once each.
+func (p *WatchdogPolicy) CheckWatchdogs(test, journal string) []WatchdogFinding {
+	var findings []WatchdogFinding
+	for _, w := range watchdogs {
+		action := WatchdogWarn
+		if a, ok := p.Actions[w.name]; ok {
+			action = a
+		}
+		if action == WatchdogIgnore {
+			continue
+		}
+		seen := make(map[string]bool)
+		for _, match := range w.match.FindAllStringSubmatch(journal, -1) {
+			detail := strings.TrimSpace(match[1])
+			if w.name == "avc" {
+				detail = avcVolatileRe.ReplaceAllString(detail, "")
+			}
+			if seen[detail] || p.ignores(w.name, test, detail) {
+				continue
+			}
+			seen[detail] = true
+			findings = append(findings, WatchdogFinding{
+				Desc:   w.desc,
+				Detail: detail,
+				Action: action,
+			})
+		}
+	}
+	return findings
+}
@@ -0,0 +1,235 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kola
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const watchdogJournal = `Oct 16 07:51:13 qemu0 audit[812]: AVC avc:  denied  { read } for  pid=812 comm="foo" name="bar" dev="vda4" ino=1234 scontext=system_u:system_r:foo_t:s0 tcontext=system_u:object_r:bar_t:s0 tclass=file permissive=0
+Oct 16 07:51:14 qemu0 audit[813]: AVC avc:  denied  { read } for  pid=813 comm="foo" name="bar" dev="vda4" ino=5678 scontext=system_u:system_r:foo_t:s0 tcontext=system_u:object_r:bar_t:s0 tclass=file permissive=0
+Oct 16 07:51:15 qemu0 systemd[1]: zincati.service: Failed with result 'exit-code'.
+Oct 16 07:51:16 qemu0 systemd[1]: var-srv.mount: Failed with result 'exit-code'.
+Oct 16 07:51:17 qemu0 systemd[1]: zincati.service: Failed with result 'exit-code'.
+Oct 16 07:51:18 qemu0 systemd-coredump[900]: Process 899 (rpm-ostree) of user 0 dumped core.
+Oct 16 07:51:19 qemu0 systemd[1]: Started foo.service.
+`
+
+const watchdogAVC = `avc:  denied  { read } for  comm="foo" name="bar" scontext=system_u:system_r:foo_t:s0 tcontext=system_u:object_r:bar_t:s0 tclass=file permissive=0`
+
+func TestCheckWatchdogs(t *testing.T) {
+	Options.CosaBuildArch = "x86_64"
+	defer func() { Options.CosaBuildArch = "" }()
+
+	avc := WatchdogFinding{Desc: "SELinux denial", Detail: watchdogAVC, Action: WatchdogWarn}
+	zincati := WatchdogFinding{Desc: "failed unit", Detail: "zincati.service", Action: WatchdogWarn}
+	mount := WatchdogFinding{Desc: "failed unit", Detail: "var-srv.mount", Action: WatchdogWarn}
+	coredump := WatchdogFinding{Desc: "core dump", Detail: "rpm-ostree", Action: WatchdogWarn}
+
+	for _, tt := range []struct {
+		name     string
+		policy   WatchdogPolicy
+		test     string
+		journal  string
+		findings []WatchdogFinding
+	}{
+		{
+			name:     "default",
+			journal:  watchdogJournal,
+			findings: []WatchdogFinding{avc, zincati, mount, coredump},
+		},
+		{
+			name: "clean",
+			journal: `Oct 16 07:51:19 qemu0 systemd[1]: Started foo.service.
+Oct 16 07:51:20 qemu0 systemd[1]: foo.service: Deactivated successfully.
+`,
+		},
+		{
+			name: "actions",
+			policy: WatchdogPolicy{Actions: map[string]WatchdogAction{
+				"avc":          WatchdogFail,
+				"failed-units": WatchdogIgnore,
+			}},
+			journal: watchdogJournal,
+			findings: []WatchdogFinding{
+				{Desc: "SELinux denial", Detail: watchdogAVC, Action: WatchdogFail},
+				coredump,
+			},
+		},
+		{
+			name: "ignore pattern",
+			policy: WatchdogPolicy{Ignore: []WatchdogIgnoreObj{
+				{Pattern: `^zincati\.`},
+			}},
+			journal:  watchdogJournal,
+			findings: []WatchdogFinding{avc, mount, coredump},
+		},
+		{
+			name: "ignore other watchdog",
+			policy: WatchdogPolicy{Ignore: []WatchdogIgnoreObj{
+				{Watchdog: "coredumps", Pattern: `zincati`},
+			}},
+			journal:  watchdogJournal,
+			findings: []WatchdogFinding{avc, zincati, mount, coredump},
+		},
+		{
+			name: "ignore matching test",
+			policy: WatchdogPolicy{Ignore: []WatchdogIgnoreObj{
+				{Watchdog: "avc", Pattern: `foo_t`, Tests: []string{"ext.config.*"}},
+			}},
+			test:     "ext.config.selinux",
+			journal:  watchdogJournal,
+			findings: []WatchdogFinding{zincati, mount, coredump},
+		},
+		{
+			name: "ignore other test",
+			policy: WatchdogPolicy{Ignore: []WatchdogIgnoreObj{
+				{Watchdog: "avc", Pattern: `foo_t`, Tests: []string{"ext.config.*"}},
+			}},
+			test:     "basic",
+			journal:  watchdogJournal,
+			findings: []WatchdogFinding{avc, zincati, mount, coredump},
+		},
+		{
+			name: "ignore matching arch",
+			policy: WatchdogPolicy{Ignore: []WatchdogIgnoreObj{
+				{Watchdog: "coredumps", Pattern: `.`, Arches: []string{"s390x", "x86_64"}},
+			}},
+			journal:  watchdogJournal,
+			findings: []WatchdogFinding{avc, zincati, mount},
+		},
+		{
+			name: "ignore other arch",
+			policy: WatchdogPolicy{Ignore: []WatchdogIgnoreObj{
+				{Watchdog: "coredumps", Pattern: `.`, Arches: []string{"s390x"}},
+			}},
+			journal:  watchdogJournal,
+			findings: []WatchdogFinding{avc, zincati, mount, coredump},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "kola-watchdogs.yaml")
+			if err := os.WriteFile(path, []byte(watchdogPolicyYAML(tt.policy)), 0644); err != nil {
+				t.Fatal(err)
+			}
+			WatchdogPolicyFile = path
+			defer func() { WatchdogPolicyFile = "" }()
+			if err := parseWatchdogPolicy(); err != nil {
+				t.Fatal(err)
+			}
+			findings := watchdogPolicy.CheckWatchdogs(tt.test, tt.journal)
+			if !reflect.DeepEqual(findings, tt.findings) {
+				t.Errorf("got %v, expected %v", findings, tt.findings)
+			}
+		})
+	}
+}
+
+// watchdogPolicyYAML returns the policy as a policy file, so that it's
+// parsed the way real ones are.
+func watchdogPolicyYAML(p WatchdogPolicy) string {
+	s := "actions:\n"
+	for name, action := range p.Actions {
+		s += "  " + name + ": " + string(action) + "\n"
+	}
+	s += "ignore:\n"
+	for _, obj := range p.Ignore {
+		s += "  - pattern: '" + obj.Pattern + "'\n"
+		if obj.Watchdog != "" {
+			s += "    watchdog: " + obj.Watchdog + "\n"
+		}
+		for _, test := range obj.Tests {
+			s += "    tests: ['" + test + "']\n"
+		}
+		for i, arch := range obj.Arches {
+			if i == 0 {
+				s += "    arches:\n"
+			}
+			s += "      - " + arch + "\n"
+		}
+	}
+	return s
+}
+
+func TestParseWatchdogPolicy(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		policy string
+		valid  bool
+	}{
+		{"empty", "", true},
+		{"actions", "actions:\n  avc: fail\n  coredumps: ignore\n", true},
+		{"unknown watchdog", "actions:\n  oom: fail\n", false},
+		{"unknown action", "actions:\n  avc: panic\n", false},
+		{"ignore", "ignore:\n  - watchdog: avc\n    pattern: foo_t\n    tracker: https://example.com/1\n", true},
+		{"ignore unknown watchdog", "ignore:\n  - watchdog: oom\n    pattern: foo\n", false},
+		{"bad pattern", "ignore:\n  - pattern: '('\n", false},
+		{"bad yaml", "actions: [", false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "kola-watchdogs.yaml")
+			if err := os.WriteFile(path, []byte(tt.policy), 0644); err != nil {
+				t.Fatal(err)
+			}
+			WatchdogPolicyFile = path
+			defer func() { WatchdogPolicyFile = "" }()
+			err := parseWatchdogPolicy()
+			if valid := err == nil; valid != tt.valid {
+				t.Errorf("got error %v, expected valid %v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestParseWatchdogPolicyDefault(t *testing.T) {
+	// The default policy isn't looked for in the working directory
+	// without a workdir
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "src/config"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "src/config/kola-watchdogs.yaml"), []byte("actions:\n  avc: fail\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	for _, tt := range []struct {
+		workdir string
+		action  WatchdogAction
+	}{
+		{"", ""},
+		{dir, WatchdogFail},
+		{t.TempDir(), ""},
+	} {
+		Options.CosaWorkdir = tt.workdir
+		if err := parseWatchdogPolicy(); err != nil {
+			t.Fatal(err)
+		}
+		if action := watchdogPolicy.Actions["avc"]; action != tt.action {
+			t.Errorf("workdir %q: got action %q, expected %q", tt.workdir, action, tt.action)
+		}
+	}
+	Options.CosaWorkdir = ""
+}
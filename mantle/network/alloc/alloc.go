@@ -0,0 +1,169 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package alloc hands out host resources, TCP ports, MAC addresses and
+// subnets, which must not collide between concurrent kola and testiso runs
+// on the same host. Each allocation is a file locked with flock(2) in a
+// directory all the runs share, so it's released when the allocator is
+// closed or its process dies.
+package alloc
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// DefaultDir is the directory allocations are recorded in by default.
+const DefaultDir = "/var/tmp/mantle-alloc"
+
+// maxTries is how many random ports and MAC addresses are tried before
+// giving up.
+const maxTries = 100
+
+var ErrExhausted = errors.New("no free resources left")
+
+type Allocator struct {
+	dir string
+
+	mu   sync.Mutex
+	held map[string]*os.File
+}
+
+// New returns an allocator recording its allocations in dir, which is
+// created if it doesn't exist.
+func New(dir string) (*Allocator, error) {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return nil, err
+	}
+	// other users' runs allocate here too
+	if err := os.Chmod(dir, 0777|os.ModeSticky); err != nil && !os.IsPermission(err) {
+		return nil, err
+	}
+	return &Allocator{
+		dir:  dir,
+		held: make(map[string]*os.File),
+	}, nil
+}
+
+// claim allocates the resource called name, returning false if it's
+// already allocated, by this allocator or another.
+func (a *Allocator) claim(name string) (bool, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.held == nil {
+		return false, fmt.Errorf("allocator is closed")
+	}
+	if _, ok := a.held[name]; ok {
+		return false, nil
+	}
+	f, err := os.OpenFile(filepath.Join(a.dir, name), os.O_RDONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return false, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		if err == syscall.EWOULDBLOCK {
+			return false, nil
+		}
+		return false, fmt.Errorf("locking %s: %v", f.Name(), err)
+	}
+	a.held[name] = f
+	return true, nil
+}
+
+// Port allocates a free TCP port. It's free when allocated, but isn't kept
+// bound, so that e.g. QEMU can forward it; only other allocations are
+// guaranteed not to get it.
+func (a *Allocator) Port() (int, error) {
+	for i := 0; i < maxTries; i++ {
+		l, err := net.Listen("tcp", ":0")
+		if err != nil {
+			return 0, err
+		}
+		port := l.Addr().(*net.TCPAddr).Port
+		l.Close()
+		ok, err := a.claim(fmt.Sprintf("port-%d", port))
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			return port, nil
+		}
+	}
+	return 0, fmt.Errorf("allocating a port: %w", ErrExhausted)
+}
+
+// MAC allocates a random MAC address in QEMU's 52:54:00 prefix.
+func (a *Allocator) MAC() (net.HardwareAddr, error) {
+	for i := 0; i < maxTries; i++ {
+		mac := net.HardwareAddr{0x52, 0x54, 0x00, 0, 0, 0}
+		if _, err := rand.Read(mac[3:]); err != nil {
+			return nil, err
+		}
+		ok, err := a.claim("mac-" + strings.ReplaceAll(mac.String(), ":", "-"))
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return mac, nil
+		}
+	}
+	return nil, fmt.Errorf("allocating a MAC address: %w", ErrExhausted)
+}
+
+// Subnet allocates the first free IPv4 subnet with the prefix length ones
+// in pool, e.g. a /24 in 192.168.64.0/18.
+func (a *Allocator) Subnet(pool *net.IPNet, ones int) (*net.IPNet, error) {
+	base := pool.IP.To4()
+	poolOnes, bits := pool.Mask.Size()
+	if base == nil || bits != 32 {
+		return nil, fmt.Errorf("pool %s isn't IPv4", pool)
+	}
+	if ones < poolOnes || ones > 32 {
+		return nil, fmt.Errorf("can't allocate /%d subnets in %s", ones, pool)
+	}
+	start := binary.BigEndian.Uint32(base)
+	for i := uint64(0); i < 1<<uint(ones-poolOnes); i++ {
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, start+uint32(i<<uint(32-ones)))
+		subnet := &net.IPNet{IP: ip, Mask: net.CIDRMask(ones, 32)}
+		ok, err := a.claim(fmt.Sprintf("subnet-%s-%d", ip, ones))
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return subnet, nil
+		}
+	}
+	return nil, fmt.Errorf("allocating a /%d in %s: %w", ones, pool, ErrExhausted)
+}
+
+// Close releases all of the allocator's allocations.
+func (a *Allocator) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, f := range a.held {
+		f.Close()
+	}
+	a.held = nil
+	return nil
+}
@@ -0,0 +1,99 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alloc
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func newAllocators(t *testing.T) (*Allocator, *Allocator) {
+	dir := t.TempDir()
+	a, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { a.Close() })
+	b, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { b.Close() })
+	return a, b
+}
+
+func TestSubnet(t *testing.T) {
+	a, b := newAllocators(t)
+	_, pool, _ := net.ParseCIDR("192.168.64.0/23")
+
+	first, err := a.Subnet(pool, 24)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.String() != "192.168.64.0/24" {
+		t.Errorf("first subnet is %s", first)
+	}
+	second, err := b.Subnet(pool, 24)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.String() != "192.168.65.0/24" {
+		t.Errorf("second subnet is %s", second)
+	}
+	if _, err := a.Subnet(pool, 24); !errors.Is(err, ErrExhausted) {
+		t.Errorf("allocated a third /24 in a /23: %v", err)
+	}
+
+	// released subnets are allocated again
+	a.Close()
+	again, err := b.Subnet(pool, 24)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.String() != first.String() {
+		t.Errorf("got %s after releasing %s", again, first)
+	}
+}
+
+func TestPortsAndMACs(t *testing.T) {
+	a, b := newAllocators(t)
+	ports := make(map[int]bool)
+	macs := make(map[string]bool)
+	for i := 0; i < 20; i++ {
+		for _, alloc := range []*Allocator{a, b} {
+			port, err := alloc.Port()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ports[port] {
+				t.Errorf("port %d allocated twice", port)
+			}
+			ports[port] = true
+
+			mac, err := alloc.MAC()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if mac[0] != 0x52 || mac[1] != 0x54 || mac[2] != 0 {
+				t.Errorf("MAC %s isn't QEMU's", mac)
+			}
+			if macs[mac.String()] {
+				t.Errorf("MAC %s allocated twice", mac)
+			}
+			macs[mac.String()] = true
+		}
+	}
+}
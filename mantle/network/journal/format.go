@@ -29,9 +29,10 @@ type Formatter interface {
 }
 
 type shortWriter struct {
-	w      io.Writer
-	tz     *time.Location
-	bootid string
+	w        io.Writer
+	tz       *time.Location
+	bootid   string
+	hostname string
 }
 
 // ShortWriter writes journal entries in a format similar to journalctl's
@@ -57,13 +58,21 @@ func (s *shortWriter) WriteEntry(entry Entry) error {
 	}
 
 	if s.isReboot(entry) {
-		if _, err := io.WriteString(s.w, "-- Reboot --\n"); err != nil {
+		marker := "-- Reboot --\n"
+		if s.hostname != "" {
+			marker = fmt.Sprintf("-- Reboot of %s --\n", s.hostname)
+		}
+		if _, err := io.WriteString(s.w, marker); err != nil {
 			return err
 		}
 	}
 
 	var buf bytes.Buffer
 	buf.WriteString(realtime.In(s.tz).Format(time.StampMicro))
+	if s.hostname != "" {
+		buf.WriteByte(' ')
+		buf.WriteString(s.hostname)
+	}
 
 	// Default to equivalent of journalctl -o with-unit, because its value is
 	// trusted, and the syslog identifier (commonly when executing bash via ExecStart)
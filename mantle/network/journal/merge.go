@@ -0,0 +1,96 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journal
+
+import (
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Merger interleaves the journals of several machines by time, much as
+// journalctl does when given several journal directories.
+type Merger struct {
+	mu      sync.Mutex
+	entries []mergedEntry
+}
+
+type mergedEntry struct {
+	source   string
+	realtime time.Time
+	entry    Entry
+}
+
+func NewMerger() *Merger {
+	return &Merger{}
+}
+
+// Formatter returns a Formatter which adds the entries written to it to
+// the merged journal, as coming from source.
+func (m *Merger) Formatter(source string) Formatter {
+	return &mergeFormatter{merger: m, source: source}
+}
+
+// WriteShort writes the merged journal to w in time order, in the format
+// of ShortWriter but with the source of each entry in place of its
+// hostname.
+func (m *Merger) WriteShort(w io.Writer, tz *time.Location) error {
+	m.mu.Lock()
+	entries := append([]mergedEntry(nil), m.entries...)
+	m.mu.Unlock()
+
+	// Stable, so entries from one source with the same time keep their
+	// order.
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].realtime.Before(entries[j].realtime)
+	})
+
+	writers := make(map[string]*shortWriter)
+	for _, e := range entries {
+		sw, ok := writers[e.source]
+		if !ok {
+			sw = &shortWriter{w: w, tz: tz, hostname: e.source}
+			writers[e.source] = sw
+		}
+		if err := sw.WriteEntry(e.entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type mergeFormatter struct {
+	merger *Merger
+	source string
+}
+
+// SetTimezone does nothing; the timezone is given to WriteShort.
+func (f *mergeFormatter) SetTimezone(tz *time.Location) {}
+
+func (f *mergeFormatter) WriteEntry(entry Entry) error {
+	realtime := entry.Realtime()
+	if realtime.IsZero() {
+		return nil
+	}
+	f.merger.mu.Lock()
+	defer f.merger.mu.Unlock()
+	f.merger.entries = append(f.merger.entries, mergedEntry{
+		source:   f.source,
+		realtime: realtime,
+		entry:    entry,
+	})
+	return nil
+}
@@ -0,0 +1,70 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journal
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/kylelemons/godebug/diff"
+)
+
+func mergeTestEntry(realtime, unit, bootid, message string) Entry {
+	return Entry{
+		FIELD_REALTIME_TIMESTAMP: []byte(realtime),
+		FIELD_SYSTEMD_UNIT:       []byte(unit),
+		FIELD_BOOT_ID:            []byte(bootid),
+		FIELD_MESSAGE:            []byte(message),
+	}
+}
+
+func TestMerge(t *testing.T) {
+	m := NewMerger()
+	m1 := m.Formatter("m1")
+	m2 := m.Formatter("m2")
+	for _, w := range []struct {
+		f     Formatter
+		entry Entry
+	}{
+		{m1, mergeTestEntry("1342540861000001", "etcd.service", "a", "starting")},
+		{m1, mergeTestEntry("1342540863000000", "etcd.service", "a", "elected leader")},
+		{m1, mergeTestEntry("1342540863000000", "etcd.service", "a", "serving")},
+		{m1, mergeTestEntry("1342540870000000", "etcd.service", "b", "restarted")},
+		{m2, mergeTestEntry("1342540860000000", "etcd.service", "c", "starting")},
+		{m2, mergeTestEntry("1342540862000000", "etcd.service", "c", "joining m1")},
+		{m2, Entry{FIELD_MESSAGE: []byte("no timestamp")}},
+	} {
+		if err := w.f.WriteEntry(w.entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := m.WriteShort(&buf, time.UTC); err != nil {
+		t.Fatal(err)
+	}
+	const expect = `Jul 17 16:01:00.000000 m2 etcd.service: starting
+Jul 17 16:01:01.000001 m1 etcd.service: starting
+Jul 17 16:01:02.000000 m2 etcd.service: joining m1
+Jul 17 16:01:03.000000 m1 etcd.service: elected leader
+Jul 17 16:01:03.000000 m1 etcd.service: serving
+-- Reboot of m1 --
+Jul 17 16:01:10.000000 m1 etcd.service: restarted
+`
+	if d := diff.Diff(buf.String(), expect); d != "" {
+		t.Errorf("unexpected output:\n%s", d)
+	}
+}
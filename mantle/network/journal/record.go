@@ -26,10 +26,11 @@ import (
 )
 
 type Recorder struct {
-	formatter Formatter
-	cursor    string
-	status    chan error
-	rawFile   io.WriteCloser
+	formatter    Formatter
+	cursor       string
+	skipExisting bool
+	status       chan error
+	rawFile      io.WriteCloser
 }
 
 func NewRecorder(f Formatter, rawFile io.WriteCloser) *Recorder {
@@ -40,13 +41,23 @@ func NewRecorder(f Formatter, rawFile io.WriteCloser) *Recorder {
 	}
 }
 
+// SkipExisting makes the recorder start with the entries written after it
+// starts, rather than those of the whole boot, such as on a machine which
+// has been up long before the test.
+func (r *Recorder) SkipExisting() {
+	r.skipExisting = true
+}
+
 func (r *Recorder) journalctl() []string {
 	cmd := []string{"journalctl",
-		"--output=export", "--follow", "--lines=all"}
-	if r.cursor == "" {
-		cmd = append(cmd, "--boot")
-	} else {
-		cmd = append(cmd, "--after-cursor", r.cursor)
+		"--output=export", "--follow"}
+	switch {
+	case r.cursor != "":
+		cmd = append(cmd, "--lines=all", "--after-cursor", r.cursor)
+	case r.skipExisting:
+		cmd = append(cmd, "--lines=0")
+	default:
+		cmd = append(cmd, "--lines=all", "--boot")
 	}
 	return cmd
 }
@@ -32,6 +32,7 @@ const (
 	// commands the recorder should execute
 	journalBoot  = "journalctl --output=export --follow --lines=all --boot"
 	journalAfter = "journalctl --output=export --follow --lines=all --after-cursor " // + cursorText or cursorBinary
+	journalNew   = "journalctl --output=export --follow --lines=0"
 )
 
 type nullFormatter struct{}
@@ -121,6 +122,42 @@ func TestRecorderSSH(t *testing.T) {
 	}
 }
 
+func TestRecorderSSHSkipExisting(t *testing.T) {
+	ctx := context.Background()
+	client := mockssh.NewMockClient(func(s *mockssh.Session) {
+		if s.Exec != journalNew {
+			t.Errorf("got %q wanted %q", s.Exec, journalNew)
+		}
+		if _, err := io.WriteString(s.Stdout, exportText); err != nil {
+			t.Error(err)
+		}
+		if err := s.Exit(0); err != nil {
+			t.Error(err)
+		}
+	})
+
+	recorder := NewRecorder(nullFormatter{}, discardCloser{})
+	recorder.SkipExisting()
+	if err := recorder.RunSSH(ctx, client); err != nil {
+		t.Fatal(err)
+	}
+
+	// resuming continues after the last entry, as usual
+	client = mockssh.NewMockClient(func(s *mockssh.Session) {
+		cmd := journalAfterEsc(cursorText)
+		if s.Exec != cmd {
+			t.Errorf("got %q wanted %q", s.Exec, cmd)
+		}
+		if err := s.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	if err := recorder.RunSSH(ctx, client); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestRecorderSSHCancel(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	client := mockssh.NewMockClient(func(s *mockssh.Session) {
@@ -0,0 +1,310 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package proxy is an HTTP proxy, like a corporate one, for testing
+// machines which reach the Internet through it. It tunnels HTTPS with
+// CONNECT, or intercepts it with certificates issued by its own CA.
+package proxy
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/pkg/capnslog"
+)
+
+var plog = capnslog.NewPackageLogger("github.com/coreos/coreos-assembler/mantle", "network/proxy")
+
+// hopHeaders aren't forwarded, since they're about the connection to the
+// proxy rather than the request.
+var hopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Proxy-Connection",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// Options configure a proxy.
+type Options struct {
+	// Addr is the address to listen on, by default a random port on
+	// localhost.
+	Addr string
+	// MITM intercepts HTTPS rather than tunneling it, so that the requests
+	// are seen; clients must trust the proxy's CACert.
+	MITM bool
+	// Username and Password, if set, are required as basic
+	// Proxy-Authorization.
+	Username string
+	Password string
+	// UpstreamTLS, if set, configures the connections to servers of
+	// intercepted requests; by default, the host's roots are trusted.
+	UpstreamTLS *tls.Config
+}
+
+// Proxy is a running proxy.
+type Proxy struct {
+	// Addr is the address the proxy is listening on.
+	Addr string
+	// CACert is the PEM of the CA issuing the certificates of intercepted
+	// hosts, if the proxy intercepts HTTPS.
+	CACert []byte
+
+	opts      Options
+	ca        *certAuthority
+	listener  net.Listener
+	server    *http.Server
+	transport *http.Transport
+
+	lock     sync.Mutex
+	requests []string
+}
+
+// New starts a proxy.
+func New(opts Options) (*Proxy, error) {
+	if opts.Addr == "" {
+		opts.Addr = "127.0.0.1:0"
+	}
+	p := &Proxy{
+		opts: opts,
+		transport: &http.Transport{
+			Proxy:               nil,
+			TLSClientConfig:     opts.UpstreamTLS,
+			TLSHandshakeTimeout: 30 * time.Second,
+		},
+	}
+	if opts.MITM {
+		var err error
+		p.ca, err = newCertAuthority()
+		if err != nil {
+			return nil, fmt.Errorf("creating CA: %v", err)
+		}
+		p.CACert = p.ca.certPEM
+	}
+	l, err := net.Listen("tcp", opts.Addr)
+	if err != nil {
+		return nil, err
+	}
+	p.listener = l
+	p.Addr = l.Addr().String()
+	p.server = &http.Server{Handler: p}
+	go func() {
+		if err := p.server.Serve(l); err != http.ErrServerClosed {
+			plog.Errorf("proxy stopped: %v", err)
+		}
+	}()
+	return p, nil
+}
+
+// Close stops the proxy.
+func (p *Proxy) Close() error {
+	p.transport.CloseIdleConnections()
+	return p.server.Close()
+}
+
+// Requests returns the requests the proxy handled, in order, such as
+// "CONNECT example.com:443", or with MITM "GET https://example.com/".
+func (p *Proxy) Requests() []string {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return append([]string(nil), p.requests...)
+}
+
+func (p *Proxy) record(method, target string) {
+	plog.Debugf("%s %s", method, target)
+	p.lock.Lock()
+	p.requests = append(p.requests, method+" "+target)
+	p.lock.Unlock()
+}
+
+// authorized returns whether the request has the proxy's credentials, if
+// it requires any.
+func (p *Proxy) authorized(r *http.Request) bool {
+	if p.opts.Username == "" {
+		return true
+	}
+	auth, ok := strings.CutPrefix(r.Header.Get("Proxy-Authorization"), "Basic ")
+	if !ok {
+		return false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(auth)
+	if err != nil {
+		return false
+	}
+	username, password, _ := strings.Cut(string(decoded), ":")
+	// both are compared, so the time doesn't tell which was wrong
+	userOK := subtle.ConstantTimeCompare([]byte(username), []byte(p.opts.Username))
+	passOK := subtle.ConstantTimeCompare([]byte(password), []byte(p.opts.Password))
+	return userOK&passOK == 1
+}
+
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !p.authorized(r) {
+		w.Header().Set("Proxy-Authenticate", `Basic realm="mantle"`)
+		http.Error(w, "proxy authentication required", http.StatusProxyAuthRequired)
+		return
+	}
+	if r.Method == http.MethodConnect {
+		p.connect(w, r)
+		return
+	}
+	if !r.URL.IsAbs() {
+		http.Error(w, "not a proxy request", http.StatusBadRequest)
+		return
+	}
+	p.record(r.Method, r.URL.String())
+	p.forward(w, r)
+}
+
+// roundTrip sends the request to its server, without the headers about
+// the connection to the proxy.
+func (p *Proxy) roundTrip(r *http.Request) (*http.Response, error) {
+	out := r.Clone(r.Context())
+	out.RequestURI = ""
+	for _, h := range hopHeaders {
+		out.Header.Del(h)
+	}
+	resp, err := p.transport.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range hopHeaders {
+		resp.Header.Del(h)
+	}
+	return resp, nil
+}
+
+// forward sends the request to its server and copies back the response.
+func (p *Proxy) forward(w http.ResponseWriter, r *http.Request) {
+	resp, err := p.roundTrip(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	for k, v := range resp.Header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(resp.StatusCode)
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		plog.Debugf("copying response of %s: %v", r.URL, err)
+	}
+}
+
+// connect tunnels the connection to the requested host, or with MITM,
+// terminates its TLS and forwards the requests in it.
+func (p *Proxy) connect(w http.ResponseWriter, r *http.Request) {
+	p.record(r.Method, r.Host)
+	var upstream net.Conn
+	if p.ca == nil {
+		var err error
+		upstream, err = net.DialTimeout("tcp", r.Host, 30*time.Second)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer upstream.Close()
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "can't hijack connection", http.StatusInternalServerError)
+		return
+	}
+	conn, buf, err := hijacker.Hijack()
+	if err != nil {
+		plog.Errorf("hijacking connection: %v", err)
+		return
+	}
+	defer conn.Close()
+	if _, err := io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n"); err != nil {
+		return
+	}
+
+	if upstream == nil {
+		p.intercept(conn, r.Host)
+		return
+	}
+	done := make(chan struct{})
+	go func() {
+		// the client may have sent more than the request already
+		io.Copy(upstream, buf) //nolint // The connection is closed on errors
+		if tcp, ok := upstream.(*net.TCPConn); ok {
+			tcp.CloseWrite() //nolint // Nothing more to send anyway
+		}
+		close(done)
+	}()
+	io.Copy(conn, upstream) //nolint // The connection is closed on errors
+	conn.Close()
+	<-done
+}
+
+// intercept terminates the TLS of a CONNECT tunnel to host with a
+// certificate from the proxy's CA, and forwards the requests in it.
+func (p *Proxy) intercept(conn net.Conn, host string) {
+	hostname, _, err := net.SplitHostPort(host)
+	if err != nil {
+		hostname = host
+	}
+	tlsConn := tls.Server(conn, &tls.Config{
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			name := hello.ServerName
+			if name == "" {
+				name = hostname
+			}
+			return p.ca.issue(name)
+		},
+		MinVersion: tls.VersionTLS12,
+	})
+	defer tlsConn.Close()
+	if err := tlsConn.Handshake(); err != nil {
+		plog.Debugf("intercepting %s: %v", host, err)
+		return
+	}
+	reader := bufio.NewReader(tlsConn)
+	for {
+		r, err := http.ReadRequest(reader)
+		if err != nil {
+			return
+		}
+		r.URL.Scheme = "https"
+		r.URL.Host = host
+		p.record(r.Method, r.URL.String())
+		resp, err := p.roundTrip(r)
+		if err != nil {
+			resp = &http.Response{
+				StatusCode: http.StatusBadGateway,
+				Body:       io.NopCloser(strings.NewReader(err.Error())),
+			}
+		}
+		resp.ProtoMajor, resp.ProtoMinor = 1, 1
+		err = resp.Write(tlsConn)
+		resp.Body.Close()
+		if err != nil || r.Close {
+			return
+		}
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// get fetches target through the proxy, trusting roots if it's HTTPS.
+func get(t *testing.T, p *Proxy, proxyURL, target string, roots *x509.CertPool) (int, string) {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: &http.Transport{
+		Proxy:           http.ProxyURL(u),
+		TLSClientConfig: &tls.Config{RootCAs: roots},
+	}}
+	resp, err := client.Get(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(body)
+}
+
+func hello(w http.ResponseWriter, r *http.Request) {
+	io.WriteString(w, "hello "+r.URL.Path) //nolint
+}
+
+func TestHTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(hello))
+	defer server.Close()
+	p, err := New(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	status, body := get(t, p, "http://"+p.Addr, server.URL+"/world", nil)
+	if status != http.StatusOK || body != "hello /world" {
+		t.Fatalf("got %d %q", status, body)
+	}
+	expected := []string{"GET " + server.URL + "/world"}
+	if requests := p.Requests(); !reflect.DeepEqual(requests, expected) {
+		t.Fatalf("recorded %v, expected %v", requests, expected)
+	}
+}
+
+func TestConnect(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(hello))
+	defer server.Close()
+	p, err := New(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if p.CACert != nil {
+		t.Fatal("proxy has a CA without MITM")
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	status, body := get(t, p, "http://"+p.Addr, server.URL+"/world", roots)
+	if status != http.StatusOK || body != "hello /world" {
+		t.Fatalf("got %d %q", status, body)
+	}
+	expected := []string{"CONNECT " + strings.TrimPrefix(server.URL, "https://")}
+	if requests := p.Requests(); !reflect.DeepEqual(requests, expected) {
+		t.Fatalf("recorded %v, expected %v", requests, expected)
+	}
+}
+
+func TestMITM(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(hello))
+	defer server.Close()
+	upstreamRoots := x509.NewCertPool()
+	upstreamRoots.AddCert(server.Certificate())
+	p, err := New(Options{
+		MITM:        true,
+		UpstreamTLS: &tls.Config{RootCAs: upstreamRoots},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	// the client trusts only the proxy's CA
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(p.CACert) {
+		t.Fatal("couldn't parse CA certificate")
+	}
+	for _, path := range []string{"/one", "/two"} {
+		status, body := get(t, p, "http://"+p.Addr, server.URL+path, roots)
+		if status != http.StatusOK || body != "hello "+path {
+			t.Fatalf("got %d %q", status, body)
+		}
+	}
+	host := strings.TrimPrefix(server.URL, "https://")
+	requests := p.Requests()
+	for _, expected := range []string{"GET https://" + host + "/one", "GET https://" + host + "/two"} {
+		found := false
+		for _, r := range requests {
+			found = found || r == expected
+		}
+		if !found {
+			t.Errorf("%q not in recorded %v", expected, requests)
+		}
+	}
+}
+
+func TestAuth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(hello))
+	defer server.Close()
+	p, err := New(Options{Username: "user", Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	if status, _ := get(t, p, "http://"+p.Addr, server.URL, nil); status != http.StatusProxyAuthRequired {
+		t.Errorf("got %d without credentials", status)
+	}
+	if status, _ := get(t, p, "http://user:wrong@"+p.Addr, server.URL, nil); status != http.StatusProxyAuthRequired {
+		t.Errorf("got %d with the wrong password", status)
+	}
+	if status, body := get(t, p, "http://user:secret@"+p.Addr, server.URL+"/x", nil); status != http.StatusOK || body != "hello /x" {
+		t.Errorf("got %d %q with credentials", status, body)
+	}
+}
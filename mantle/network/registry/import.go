@@ -0,0 +1,177 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/opencontainers/go-digest"
+)
+
+// refNameAnnotation is the annotation of the manifests in an OCI layout
+// naming them.
+const refNameAnnotation = "org.opencontainers.image.ref.name"
+
+// Media types of manifests listing other manifests.
+var indexMediaTypes = map[string]bool{
+	"application/vnd.oci.image.index.v1+json":                   true,
+	"application/vnd.docker.distribution.manifest.list.v2+json": true,
+}
+
+// descriptor is the part of an OCI content descriptor the registry uses.
+type descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      digest.Digest     `json:"digest"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Import adds the images in an OCI archive, a tar of an OCI image layout
+// such as the ociarchive of a build, to the repository. They're tagged as
+// tag, which the archive must then have a single image for, or if tag is
+// empty, by their ref names in the archive.
+func (r *Registry) Import(archive, repo, tag string) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var index []byte
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("reading %s: %v", archive, err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := path.Clean(hdr.Name)
+		if name == "index.json" {
+			if index, err = io.ReadAll(tr); err != nil {
+				return fmt.Errorf("reading %s: %v", archive, err)
+			}
+			continue
+		}
+		parts := strings.Split(name, "/")
+		if len(parts) != 3 || parts[0] != "blobs" {
+			continue
+		}
+		d := digest.NewDigestFromEncoded(digest.Algorithm(parts[1]), parts[2])
+		if err := r.importBlob(d, tr); err != nil {
+			return fmt.Errorf("importing %s from %s: %v", name, archive, err)
+		}
+	}
+	if index == nil {
+		return fmt.Errorf("%s has no index.json", archive)
+	}
+
+	var idx struct {
+		Manifests []descriptor `json:"manifests"`
+	}
+	if err := json.Unmarshal(index, &idx); err != nil {
+		return fmt.Errorf("parsing index of %s: %v", archive, err)
+	}
+	if len(idx.Manifests) == 0 {
+		return fmt.Errorf("%s has no images", archive)
+	}
+	if tag != "" && len(idx.Manifests) > 1 {
+		return fmt.Errorf("%s has %d images, not one to tag as %s", archive, len(idx.Manifests), tag)
+	}
+	for _, desc := range idx.Manifests {
+		if err := r.addManifest(desc); err != nil {
+			return fmt.Errorf("importing %s: %v", archive, err)
+		}
+		t := tag
+		if t == "" {
+			t = desc.Annotations[refNameAnnotation]
+		}
+		if t != "" {
+			r.tag(repo, t, desc.Digest)
+		}
+	}
+	return nil
+}
+
+// importBlob stores the blob, unless it's already stored.
+func (r *Registry) importBlob(d digest.Digest, content io.Reader) error {
+	if _, err := r.blobPath(d); err != nil {
+		return err
+	}
+	if r.hasBlob(d) {
+		return nil
+	}
+	id, err := newUploadID()
+	if err != nil {
+		return err
+	}
+	path := r.uploadPath(id)
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, content)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return err
+	}
+	return r.commit(path, d)
+}
+
+// addManifest makes the stored manifest, and those it lists, available by
+// their digests.
+func (r *Registry) addManifest(desc descriptor) error {
+	blobPath, err := r.blobPath(desc.Digest)
+	if err != nil {
+		return err
+	}
+	if !r.hasBlob(desc.Digest) {
+		return fmt.Errorf("manifest %s missing", desc.Digest)
+	}
+	r.lock.Lock()
+	r.mediaTypes[desc.Digest] = desc.MediaType
+	r.lock.Unlock()
+	if !indexMediaTypes[desc.MediaType] {
+		return nil
+	}
+
+	data, err := os.ReadFile(blobPath)
+	if err != nil {
+		return err
+	}
+	var idx struct {
+		Manifests []descriptor `json:"manifests"`
+	}
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return fmt.Errorf("parsing manifest %s: %v", desc.Digest, err)
+	}
+	for _, child := range idx.Manifests {
+		if err := r.addManifest(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,549 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package registry is an ephemeral container registry, implementing the
+// parts of the OCI distribution spec used to push and pull images, so
+// that tests can deploy builds from a registry without an external one.
+package registry
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/pkg/capnslog"
+	"github.com/opencontainers/go-digest"
+)
+
+var plog = capnslog.NewPackageLogger("github.com/coreos/coreos-assembler/mantle", "network/registry")
+
+// maxManifestSize is the size of the largest manifest accepted.
+const maxManifestSize = 4 << 20
+
+var errDigestMismatch = errors.New("content doesn't match digest")
+
+// Options are what a registry is served with.
+type Options struct {
+	// Addr is the address to listen on, by default a free port on
+	// localhost.
+	Addr string
+	// Dir is where blobs are stored, or if empty, a temporary directory
+	// removed by Close.
+	Dir string
+	// Username and Password, if set, are required of clients with basic
+	// auth.
+	Username string
+	Password string
+	// TLS serves HTTPS, with a certificate for Hosts and localhost signed
+	// by a CA made for the registry.
+	TLS   bool
+	Hosts []string
+}
+
+// Registry is a registry serving until it's closed. Images
+// are kept in memory and blobs in its directory.
+type Registry struct {
+	// Addr is the host:port the registry listens on.
+	Addr string
+	// CACert is the PEM-encoded CA certificate clients verify the
+	// registry with, if it serves HTTPS.
+	CACert []byte
+
+	opts      Options
+	dir       string
+	removeDir bool
+	listener  net.Listener
+
+	lock sync.Mutex
+	// tags maps repositories to their tags' manifests
+	tags map[string]map[string]digest.Digest
+	// mediaTypes are the media types of manifests
+	mediaTypes map[digest.Digest]string
+}
+
+// New starts serving a registry.
+func New(opts Options) (*Registry, error) {
+	r := &Registry{
+		opts:       opts,
+		dir:        opts.Dir,
+		tags:       make(map[string]map[string]digest.Digest),
+		mediaTypes: make(map[digest.Digest]string),
+	}
+	if r.dir == "" {
+		dir, err := os.MkdirTemp("/var/tmp", "mantle-registry")
+		if err != nil {
+			return nil, err
+		}
+		r.dir = dir
+		r.removeDir = true
+	}
+	for _, d := range []string{"blobs", "uploads"} {
+		if err := os.MkdirAll(filepath.Join(r.dir, d), 0755); err != nil {
+			r.Close()
+			return nil, err
+		}
+	}
+
+	addr := opts.Addr
+	if addr == "" {
+		addr = "127.0.0.1:0"
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+	if opts.TLS {
+		config, caCert, err := newTLSConfig(opts.Hosts)
+		if err != nil {
+			listener.Close()
+			r.Close()
+			return nil, fmt.Errorf("creating certificate: %v", err)
+		}
+		r.CACert = caCert
+		listener = newTLSListener(listener, config)
+	}
+	r.listener = listener
+	r.Addr = listener.Addr().String()
+	go func() {
+		err := http.Serve(listener, r)
+		plog.Debugf("stopped serving registry: %v", err)
+	}()
+	return r, nil
+}
+
+// Close stops serving the registry, and removes its directory if it's
+// temporary.
+func (r *Registry) Close() error {
+	var err error
+	if r.listener != nil {
+		err = r.listener.Close()
+	}
+	if r.removeDir {
+		if rmErr := os.RemoveAll(r.dir); err == nil {
+			err = rmErr
+		}
+	}
+	return err
+}
+
+// registryError is an error response, as defined by the distribution spec.
+func registryError(w http.ResponseWriter, status int, code, format string, args ...interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	resp := map[string]interface{}{
+		"errors": []map[string]string{{
+			"code":    code,
+			"message": fmt.Sprintf(format, args...),
+		}},
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func (r *Registry) authorized(req *http.Request) bool {
+	if r.opts.Username == "" && r.opts.Password == "" {
+		return true
+	}
+	user, pass, ok := req.BasicAuth()
+	return ok &&
+		subtle.ConstantTimeCompare([]byte(user), []byte(r.opts.Username)) == 1 &&
+		subtle.ConstantTimeCompare([]byte(pass), []byte(r.opts.Password)) == 1
+}
+
+// cut splits the path after /v2/ into the repository and what follows sep.
+func cut(path, sep string) (string, string, bool) {
+	i := strings.LastIndex(path, sep)
+	if i <= 0 {
+		return "", "", false
+	}
+	return path[:i], path[i+len(sep):], true
+}
+
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Docker-Distribution-API-Version", "registry/2.0")
+	if !r.authorized(req) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="kola"`)
+		registryError(w, http.StatusUnauthorized, "UNAUTHORIZED", "authentication required")
+		return
+	}
+	path := req.URL.Path
+	if path == "/v2/" || path == "/v2" {
+		return
+	}
+	if !strings.HasPrefix(path, "/v2/") {
+		http.NotFound(w, req)
+		return
+	}
+	path = strings.TrimPrefix(path, "/v2/")
+
+	if name, _, ok := cut(path, "/tags/list"); ok && strings.HasSuffix(path, "/tags/list") {
+		r.listTags(w, req, name)
+	} else if name, id, ok := cut(path, "/blobs/uploads/"); ok {
+		r.upload(w, req, name, id)
+	} else if name, ok := strings.CutSuffix(path, "/blobs/uploads"); ok {
+		r.upload(w, req, name, "")
+	} else if name, ref, ok := cut(path, "/blobs/"); ok {
+		r.blob(w, req, name, ref)
+	} else if name, ref, ok := cut(path, "/manifests/"); ok {
+		r.manifest(w, req, name, ref)
+	} else {
+		http.NotFound(w, req)
+	}
+}
+
+// blobPath returns the path of the blob, or an error if the digest is
+// invalid.
+func (r *Registry) blobPath(d digest.Digest) (string, error) {
+	if err := d.Validate(); err != nil {
+		return "", err
+	}
+	return filepath.Join(r.dir, "blobs", d.Algorithm().String(), d.Encoded()), nil
+}
+
+func (r *Registry) hasBlob(d digest.Digest) bool {
+	path, err := r.blobPath(d)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(path)
+	return err == nil
+}
+
+func (r *Registry) blob(w http.ResponseWriter, req *http.Request, name, ref string) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		registryError(w, http.StatusMethodNotAllowed, "UNSUPPORTED", "%s not supported on blobs", req.Method)
+		return
+	}
+	d := digest.Digest(ref)
+	path, err := r.blobPath(d)
+	if err != nil {
+		registryError(w, http.StatusBadRequest, "DIGEST_INVALID", "%v", err)
+		return
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		registryError(w, http.StatusNotFound, "BLOB_UNKNOWN", "blob %s unknown", d)
+		return
+	}
+	defer f.Close()
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Docker-Content-Digest", d.String())
+	http.ServeContent(w, req, "", fileModTime(f), f)
+}
+
+// upload handles blob uploads, which are started with a POST, given
+// chunks with PATCH, and finished with a PUT of their digest. A POST with
+// the digest uploads a whole blob, and one with mount succeeds if the blob
+// already exists.
+func (r *Registry) upload(w http.ResponseWriter, req *http.Request, name, id string) {
+	location := func(id string) string {
+		return fmt.Sprintf("/v2/%s/blobs/uploads/%s", name, id)
+	}
+	if id == "" {
+		if req.Method != http.MethodPost {
+			registryError(w, http.StatusMethodNotAllowed, "UNSUPPORTED", "%s not supported on uploads", req.Method)
+			return
+		}
+		if mount := digest.Digest(req.URL.Query().Get("mount")); mount != "" && r.hasBlob(mount) {
+			r.blobCreated(w, name, mount)
+			return
+		}
+		id, err := newUploadID()
+		if err != nil {
+			registryError(w, http.StatusInternalServerError, "UNKNOWN", "%v", err)
+			return
+		}
+		f, err := os.Create(r.uploadPath(id))
+		if err != nil {
+			registryError(w, http.StatusInternalServerError, "UNKNOWN", "%v", err)
+			return
+		}
+		f.Close()
+		if d := req.URL.Query().Get("digest"); d != "" {
+			r.finishUpload(w, req, name, id, digest.Digest(d))
+			return
+		}
+		w.Header().Set("Location", location(id))
+		w.Header().Set("Docker-Upload-UUID", id)
+		w.Header().Set("Range", "0-0")
+		w.Header().Set("Content-Length", "0")
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	if _, err := hex.DecodeString(id); err != nil {
+		registryError(w, http.StatusNotFound, "BLOB_UPLOAD_UNKNOWN", "upload %s unknown", id)
+		return
+	}
+	path := r.uploadPath(id)
+	if _, err := os.Stat(path); err != nil {
+		registryError(w, http.StatusNotFound, "BLOB_UPLOAD_UNKNOWN", "upload %s unknown", id)
+		return
+	}
+	switch req.Method {
+	case http.MethodPatch:
+		size, err := appendBody(path, req.Body)
+		if err != nil {
+			registryError(w, http.StatusInternalServerError, "UNKNOWN", "%v", err)
+			return
+		}
+		w.Header().Set("Location", location(id))
+		w.Header().Set("Docker-Upload-UUID", id)
+		w.Header().Set("Range", uploadRange(size))
+		w.Header().Set("Content-Length", "0")
+		w.WriteHeader(http.StatusAccepted)
+	case http.MethodPut:
+		r.finishUpload(w, req, name, id, digest.Digest(req.URL.Query().Get("digest")))
+	case http.MethodGet:
+		info, err := os.Stat(path)
+		if err != nil {
+			registryError(w, http.StatusInternalServerError, "UNKNOWN", "%v", err)
+			return
+		}
+		w.Header().Set("Location", location(id))
+		w.Header().Set("Docker-Upload-UUID", id)
+		w.Header().Set("Range", uploadRange(info.Size()))
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		os.Remove(path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		registryError(w, http.StatusMethodNotAllowed, "UNSUPPORTED", "%s not supported on uploads", req.Method)
+	}
+}
+
+// finishUpload appends the body to the upload, and makes it the blob if
+// it matches the digest.
+func (r *Registry) finishUpload(w http.ResponseWriter, req *http.Request, name, id string, d digest.Digest) {
+	path := r.uploadPath(id)
+	if _, err := r.blobPath(d); err != nil {
+		registryError(w, http.StatusBadRequest, "DIGEST_INVALID", "%v", err)
+		return
+	}
+	if _, err := appendBody(path, req.Body); err != nil {
+		registryError(w, http.StatusInternalServerError, "UNKNOWN", "%v", err)
+		return
+	}
+	if err := r.commit(path, d); errors.Is(err, errDigestMismatch) {
+		registryError(w, http.StatusBadRequest, "DIGEST_INVALID", "%v", err)
+		return
+	} else if err != nil {
+		registryError(w, http.StatusInternalServerError, "UNKNOWN", "%v", err)
+		return
+	}
+	r.blobCreated(w, name, d)
+}
+
+// commit makes the file the blob if it has the digest, and otherwise
+// removes it.
+func (r *Registry) commit(path string, d digest.Digest) error {
+	blobPath, err := r.blobPath(d)
+	if err != nil {
+		os.Remove(path)
+		return err
+	}
+	actual, err := fileDigest(path, d.Algorithm())
+	if err != nil {
+		os.Remove(path)
+		return err
+	}
+	if actual != d {
+		os.Remove(path)
+		return fmt.Errorf("%w: got %s, expected %s", errDigestMismatch, actual, d)
+	}
+	if err := os.MkdirAll(filepath.Dir(blobPath), 0755); err != nil {
+		return err
+	}
+	return os.Rename(path, blobPath)
+}
+
+func (r *Registry) blobCreated(w http.ResponseWriter, name string, d digest.Digest) {
+	w.Header().Set("Location", fmt.Sprintf("/v2/%s/blobs/%s", name, d))
+	w.Header().Set("Docker-Content-Digest", d.String())
+	w.Header().Set("Content-Length", "0")
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (r *Registry) uploadPath(id string) string {
+	return filepath.Join(r.dir, "uploads", id)
+}
+
+func (r *Registry) manifest(w http.ResponseWriter, req *http.Request, name, ref string) {
+	switch req.Method {
+	case http.MethodPut:
+		data, err := io.ReadAll(io.LimitReader(req.Body, maxManifestSize+1))
+		if err != nil {
+			registryError(w, http.StatusInternalServerError, "UNKNOWN", "%v", err)
+			return
+		}
+		if len(data) > maxManifestSize {
+			registryError(w, http.StatusRequestEntityTooLarge, "SIZE_INVALID", "manifest larger than %d bytes", maxManifestSize)
+			return
+		}
+		d := digest.FromBytes(data)
+		if ref, err := digest.Parse(ref); err == nil && ref != d {
+			registryError(w, http.StatusBadRequest, "DIGEST_INVALID", "manifest has digest %s, not %s", d, ref)
+			return
+		}
+		path, _ := r.blobPath(d)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			registryError(w, http.StatusInternalServerError, "UNKNOWN", "%v", err)
+			return
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			registryError(w, http.StatusInternalServerError, "UNKNOWN", "%v", err)
+			return
+		}
+		mediaType := req.Header.Get("Content-Type")
+		if mediaType == "" {
+			var m struct {
+				MediaType string `json:"mediaType"`
+			}
+			_ = json.Unmarshal(data, &m)
+			mediaType = m.MediaType
+		}
+		r.lock.Lock()
+		r.mediaTypes[d] = mediaType
+		r.lock.Unlock()
+		if _, err := digest.Parse(ref); err != nil {
+			r.tag(name, ref, d)
+		}
+		w.Header().Set("Location", fmt.Sprintf("/v2/%s/manifests/%s", name, d))
+		w.Header().Set("Docker-Content-Digest", d.String())
+		w.Header().Set("Content-Length", "0")
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodGet, http.MethodHead:
+		r.lock.Lock()
+		d, err := digest.Parse(ref)
+		if err != nil {
+			d = r.tags[name][ref]
+		}
+		mediaType, ok := r.mediaTypes[d]
+		r.lock.Unlock()
+		if !ok {
+			registryError(w, http.StatusNotFound, "MANIFEST_UNKNOWN", "manifest %s unknown", ref)
+			return
+		}
+		path, _ := r.blobPath(d)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			registryError(w, http.StatusInternalServerError, "UNKNOWN", "%v", err)
+			return
+		}
+		if mediaType != "" {
+			w.Header().Set("Content-Type", mediaType)
+		}
+		w.Header().Set("Docker-Content-Digest", d.String())
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		if req.Method == http.MethodGet {
+			_, _ = w.Write(data)
+		}
+	default:
+		registryError(w, http.StatusMethodNotAllowed, "UNSUPPORTED", "%s not supported on manifests", req.Method)
+	}
+}
+
+// tag points the repository's tag at the manifest.
+func (r *Registry) tag(name, tag string, d digest.Digest) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.tags[name] == nil {
+		r.tags[name] = make(map[string]digest.Digest)
+	}
+	r.tags[name][tag] = d
+}
+
+func (r *Registry) listTags(w http.ResponseWriter, req *http.Request, name string) {
+	r.lock.Lock()
+	tags := []string{}
+	for tag := range r.tags[name] {
+		tags = append(tags, tag)
+	}
+	r.lock.Unlock()
+	if len(tags) == 0 {
+		registryError(w, http.StatusNotFound, "NAME_UNKNOWN", "repository %s unknown", name)
+		return
+	}
+	sort.Strings(tags)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"name": name,
+		"tags": tags,
+	})
+}
+
+func newUploadID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// appendBody appends the body to the file, returning its new size.
+func appendBody(path string, body io.Reader) (int64, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	if _, err := io.Copy(f, body); err != nil {
+		return 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// uploadRange is the Range header of an upload of size bytes.
+func uploadRange(size int64) string {
+	if size == 0 {
+		return "0-0"
+	}
+	return fmt.Sprintf("0-%d", size-1)
+}
+
+func fileDigest(path string, alg digest.Algorithm) (digest.Digest, error) {
+	if !alg.Available() {
+		return "", fmt.Errorf("unsupported digest algorithm %s", alg)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return alg.FromReader(f)
+}
+
+func fileModTime(f *os.File) (t time.Time) {
+	if info, err := f.Stat(); err == nil {
+		t = info.ModTime()
+	}
+	return
+}
@@ -0,0 +1,289 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+)
+
+type client struct {
+	t      *testing.T
+	base   string
+	client *http.Client
+	user   string
+	pass   string
+}
+
+func newClient(t *testing.T, r *Registry) *client {
+	c := &client{
+		t:      t,
+		base:   "http://" + r.Addr,
+		client: &http.Client{},
+	}
+	if r.CACert != nil {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(r.CACert) {
+			t.Fatal("couldn't parse CA certificate")
+		}
+		c.base = "https://" + r.Addr
+		c.client.Transport = &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: pool},
+		}
+	}
+	return c
+}
+
+func (c *client) do(method, path, contentType string, body []byte) (*http.Response, []byte) {
+	req, err := http.NewRequest(method, c.base+path, bytes.NewReader(body))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.user != "" {
+		req.SetBasicAuth(c.user, c.pass)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return resp, data
+}
+
+func (c *client) expect(method, path, contentType string, body []byte, status int) (*http.Response, []byte) {
+	resp, data := c.do(method, path, contentType, body)
+	if resp.StatusCode != status {
+		c.t.Fatalf("%s %s: got status %d, expected %d: %s", method, path, resp.StatusCode, status, data)
+	}
+	return resp, data
+}
+
+func newRegistry(t *testing.T, opts Options) *Registry {
+	opts.Dir = t.TempDir()
+	r, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { r.Close() })
+	return r
+}
+
+func TestBlobs(t *testing.T) {
+	c := newClient(t, newRegistry(t, Options{}))
+	c.expect("GET", "/v2/", "", nil, http.StatusOK)
+
+	blob := []byte("some layer")
+	d := digest.FromBytes(blob)
+	c.expect("HEAD", "/v2/coreos/blobs/"+d.String(), "", nil, http.StatusNotFound)
+
+	// chunked upload
+	resp, _ := c.expect("POST", "/v2/coreos/blobs/uploads/", "", nil, http.StatusAccepted)
+	location := resp.Header.Get("Location")
+	resp, _ = c.expect("PATCH", location, "application/octet-stream", blob[:4], http.StatusAccepted)
+	if r := resp.Header.Get("Range"); r != "0-3" {
+		t.Errorf("got range %q after first chunk", r)
+	}
+	location = resp.Header.Get("Location")
+	c.expect("PUT", location+"?digest="+digest.FromString("other").String(), "application/octet-stream", blob[4:], http.StatusBadRequest)
+
+	resp, _ = c.expect("POST", "/v2/coreos/blobs/uploads/", "", nil, http.StatusAccepted)
+	location = resp.Header.Get("Location")
+	c.expect("PATCH", location, "application/octet-stream", blob[:4], http.StatusAccepted)
+	resp, _ = c.expect("PUT", location+"?digest="+d.String(), "application/octet-stream", blob[4:], http.StatusCreated)
+	if got := resp.Header.Get("Docker-Content-Digest"); got != d.String() {
+		t.Errorf("got digest %q, expected %q", got, d)
+	}
+	resp, _ = c.expect("HEAD", "/v2/coreos/blobs/"+d.String(), "", nil, http.StatusOK)
+	if resp.ContentLength != int64(len(blob)) {
+		t.Errorf("got length %d, expected %d", resp.ContentLength, len(blob))
+	}
+	_, data := c.expect("GET", "/v2/coreos/blobs/"+d.String(), "", nil, http.StatusOK)
+	if !bytes.Equal(data, blob) {
+		t.Errorf("got blob %q, expected %q", data, blob)
+	}
+
+	// monolithic upload and mounting
+	other := []byte("another layer")
+	od := digest.FromBytes(other)
+	c.expect("POST", "/v2/coreos/blobs/uploads/?digest="+od.String(), "application/octet-stream", other, http.StatusCreated)
+	c.expect("GET", "/v2/coreos/blobs/"+od.String(), "", nil, http.StatusOK)
+	c.expect("POST", "/v2/fcos/blobs/uploads/?mount="+d.String()+"&from=coreos", "", nil, http.StatusCreated)
+	c.expect("GET", "/v2/coreos/blobs/invalid", "", nil, http.StatusBadRequest)
+}
+
+func TestManifests(t *testing.T) {
+	c := newClient(t, newRegistry(t, Options{}))
+	mediaType := "application/vnd.oci.image.manifest.v1+json"
+	manifest := []byte(`{"schemaVersion":2,"mediaType":"` + mediaType + `"}`)
+	d := digest.FromBytes(manifest)
+
+	c.expect("GET", "/v2/coreos/manifests/stable", "", nil, http.StatusNotFound)
+	c.expect("PUT", "/v2/coreos/manifests/"+digest.FromString("other").String(), mediaType, manifest, http.StatusBadRequest)
+	resp, _ := c.expect("PUT", "/v2/coreos/manifests/stable", mediaType, manifest, http.StatusCreated)
+	if got := resp.Header.Get("Docker-Content-Digest"); got != d.String() {
+		t.Errorf("got digest %q, expected %q", got, d)
+	}
+	c.expect("PUT", "/v2/coreos/manifests/"+d.String(), "", manifest, http.StatusCreated)
+
+	for _, ref := range []string{"stable", d.String()} {
+		resp, data := c.expect("GET", "/v2/coreos/manifests/"+ref, "", nil, http.StatusOK)
+		if !bytes.Equal(data, manifest) {
+			t.Errorf("%s: got manifest %q", ref, data)
+		}
+		if got := resp.Header.Get("Content-Type"); got != mediaType {
+			t.Errorf("%s: got media type %q", ref, got)
+		}
+		if got := resp.Header.Get("Docker-Content-Digest"); got != d.String() {
+			t.Errorf("%s: got digest %q", ref, got)
+		}
+	}
+
+	c.expect("GET", "/v2/fcos/tags/list", "", nil, http.StatusNotFound)
+	_, data := c.expect("GET", "/v2/coreos/tags/list", "", nil, http.StatusOK)
+	var tags struct {
+		Name string   `json:"name"`
+		Tags []string `json:"tags"`
+	}
+	if err := json.Unmarshal(data, &tags); err != nil {
+		t.Fatal(err)
+	}
+	if tags.Name != "coreos" || len(tags.Tags) != 1 || tags.Tags[0] != "stable" {
+		t.Errorf("got tags %+v", tags)
+	}
+}
+
+func TestAuth(t *testing.T) {
+	c := newClient(t, newRegistry(t, Options{Username: "core", Password: "secret"}))
+	resp, _ := c.expect("GET", "/v2/", "", nil, http.StatusUnauthorized)
+	if resp.Header.Get("WWW-Authenticate") == "" {
+		t.Error("no WWW-Authenticate header")
+	}
+	c.user, c.pass = "core", "wrong"
+	c.expect("GET", "/v2/coreos/tags/list", "", nil, http.StatusUnauthorized)
+	c.pass = "secret"
+	c.expect("GET", "/v2/", "", nil, http.StatusOK)
+}
+
+func TestTLS(t *testing.T) {
+	r := newRegistry(t, Options{TLS: true, Hosts: []string{"10.0.2.2"}})
+	c := newClient(t, r)
+	c.expect("GET", "/v2/", "", nil, http.StatusOK)
+
+	// the certificate is valid for the hosts given, as well as localhost
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(r.CACert)
+	conn, err := tls.Dial("tcp", r.Addr, &tls.Config{RootCAs: pool, ServerName: "10.0.2.2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	if _, err := tls.Dial("tcp", r.Addr, &tls.Config{RootCAs: pool, ServerName: "example.com"}); err == nil {
+		t.Error("certificate valid for unexpected host")
+	}
+}
+
+// writeArchive writes an OCI archive of the files.
+func writeArchive(t *testing.T, files map[string][]byte) string {
+	path := filepath.Join(t.TempDir(), "image.ociarchive")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	tw := tar.NewWriter(f)
+	for name, data := range files {
+		hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestImport(t *testing.T) {
+	r := newRegistry(t, Options{})
+	c := newClient(t, r)
+
+	layer := []byte("layer")
+	ld := digest.FromBytes(layer)
+	mediaType := "application/vnd.oci.image.manifest.v1+json"
+	manifest := []byte(`{"schemaVersion":2,"mediaType":"` + mediaType + `","layers":[{"digest":"` + ld.String() + `"}]}`)
+	md := digest.FromBytes(manifest)
+	index := []byte(`{"schemaVersion":2,"manifests":[{"mediaType":"` + mediaType + `","digest":"` + md.String() + `","annotations":{"org.opencontainers.image.ref.name":"stable"}}]}`)
+	blobs := map[string][]byte{
+		"./blobs/sha256/" + ld.Encoded(): layer,
+		"./blobs/sha256/" + md.Encoded(): manifest,
+		"./oci-layout":                   []byte(`{"imageLayoutVersion":"1.0.0"}`),
+	}
+
+	if err := r.Import(writeArchive(t, blobs), "coreos", ""); err == nil {
+		t.Error("imported archive without index")
+	}
+	blobs["./index.json"] = index
+	archive := writeArchive(t, blobs)
+	if err := r.Import(archive, "coreos", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Import(archive, "fcos", "latest"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"/v2/coreos/manifests/stable", "/v2/fcos/manifests/latest", "/v2/fcos/manifests/" + md.String()} {
+		resp, data := c.expect("GET", path, "", nil, http.StatusOK)
+		if !bytes.Equal(data, manifest) {
+			t.Errorf("%s: got manifest %q", path, data)
+		}
+		if got := resp.Header.Get("Content-Type"); got != mediaType {
+			t.Errorf("%s: got media type %q", path, got)
+		}
+	}
+	_, data := c.expect("GET", "/v2/coreos/blobs/"+ld.String(), "", nil, http.StatusOK)
+	if !bytes.Equal(data, layer) {
+		t.Errorf("got layer %q", data)
+	}
+
+	blobs["./blobs/sha256/"+ld.Encoded()] = []byte("corrupt")
+	if err := r.Import(writeArchive(t, blobs), "coreos", ""); err != nil {
+		// already stored, so not rewritten
+		t.Errorf("reimporting: %v", err)
+	}
+	blobs["./blobs/sha256/"+digest.FromString("x").Encoded()] = []byte("y")
+	if err := r.Import(writeArchive(t, blobs), "coreos", ""); err == nil {
+		t.Error("imported blob not matching its digest")
+	}
+}
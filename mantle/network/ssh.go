@@ -21,6 +21,7 @@ import (
 	"net"
 	"os"
 	"strings"
+	"sync"
 
 	"golang.org/x/crypto/ssh"
 	"golang.org/x/crypto/ssh/agent"
@@ -51,6 +52,10 @@ type SSHAgent struct {
 	sockDir      string
 	sockdirOwned bool
 	listener     *net.UnixListener
+
+	// pool holds the shared connection to each host
+	poolLock sync.Mutex
+	pool     map[string]*sharedClient
 }
 
 // NewSSHAgent constructs a new SSHAgent using dialer to create ssh
@@ -122,8 +127,9 @@ func NewSSHAgent(dialer Dialer) (*SSHAgent, error) {
 	return a, nil
 }
 
-// Close closes the unix socket of the agent.
+// Close closes the unix socket of the agent, and any shared connections.
 func (a *SSHAgent) Close() error {
+	a.closeSharedClients()
 	a.listener.Close()
 	if a.sockdirOwned {
 		return os.RemoveAll(a.sockDir)
@@ -0,0 +1,218 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package network
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/coreos/pkg/capnslog"
+	"golang.org/x/crypto/ssh"
+)
+
+var plog = capnslog.NewPackageLogger("github.com/coreos/coreos-assembler/mantle", "network")
+
+var (
+	// SSHKeepaliveInterval is how often shared connections are probed.
+	SSHKeepaliveInterval = 5 * time.Second
+	// SSHKeepaliveTimeout is how long a probe may go unanswered before
+	// the connection is considered dead.
+	SSHKeepaliveTimeout = 15 * time.Second
+	// sshReconnectBackoff are the delays between attempts to re-establish
+	// a shared connection which dropped.
+	sshReconnectBackoff = []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}
+)
+
+// sharedClient is a connection multiplexed between commands, in the
+// manner of OpenSSH's ControlMaster.
+type sharedClient struct {
+	*ssh.Client
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+func (c *sharedClient) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.Client.Close()
+	})
+}
+
+// keepalive probes the connection until it's closed, closing it if the
+// remote end stops answering.
+func (c *sharedClient) keepalive(interval, timeout time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+		}
+		errc := make(chan error, 1)
+		go func() {
+			// OpenSSH answers unknown global requests with a failure,
+			// which is just as good for proving it's alive.
+			_, _, err := c.SendRequest("keepalive@openssh.com", true, nil)
+			errc <- err
+		}()
+		select {
+		case err := <-errc:
+			if err == nil {
+				continue
+			}
+			plog.Debugf("ssh keepalive to %s failed: %v", c.RemoteAddr(), err)
+		case <-time.After(timeout):
+			plog.Debugf("ssh keepalive to %s timed out", c.RemoteAddr())
+		case <-c.done:
+			return
+		}
+		c.close()
+		return
+	}
+}
+
+// sharedClientFor returns the shared connection to host, establishing it if
+// needed. reused reports whether the connection already existed.
+func (a *SSHAgent) sharedClientFor(host string) (client *sharedClient, reused bool, err error) {
+	a.poolLock.Lock()
+	if c, ok := a.pool[host]; ok {
+		select {
+		case <-c.done:
+			delete(a.pool, host)
+		default:
+			a.poolLock.Unlock()
+			return c, true, nil
+		}
+	}
+	a.poolLock.Unlock()
+
+	// Dial without holding the lock; connecting can be slow, and
+	// commands to other hosts shouldn't wait on it.
+	sshClient, err := a.NewClient(host)
+	if err != nil {
+		return nil, false, err
+	}
+	c := &sharedClient{Client: sshClient, done: make(chan struct{})}
+
+	a.poolLock.Lock()
+	defer a.poolLock.Unlock()
+	if existing, ok := a.pool[host]; ok {
+		// Raced with another command; use its connection
+		c.close()
+		return existing, true, nil
+	}
+	if a.pool == nil {
+		a.pool = make(map[string]*sharedClient)
+	}
+	a.pool[host] = c
+	go c.keepalive(SSHKeepaliveInterval, SSHKeepaliveTimeout)
+	go func() {
+		_ = c.Wait()
+		c.close()
+		a.dropSharedClient(host, c)
+	}()
+	return c, false, nil
+}
+
+func (a *SSHAgent) dropSharedClient(host string, c *sharedClient) {
+	a.poolLock.Lock()
+	defer a.poolLock.Unlock()
+	if a.pool[host] == c {
+		delete(a.pool, host)
+	}
+}
+
+// NewSharedSession opens a session to host over a connection which is
+// kept open and shared with other sessions, rather than dialing anew for
+// each command. If a previously working connection has dropped, it is
+// re-established with backoff. The returned function must be called once
+// the session is done with, instead of closing the session.
+func (a *SSHAgent) NewSharedSession(host string) (*ssh.Session, func(), error) {
+	client, reused, err := a.sharedClientFor(host)
+	if err != nil {
+		return nil, nil, err
+	}
+	session, err := client.NewSession()
+	if err == nil {
+		return session, func() { session.Close() }, nil
+	}
+
+	var chanErr *ssh.OpenChannelError
+	if errors.As(err, &chanErr) {
+		// The connection is fine, but sshd limits the number of
+		// concurrent sessions per connection (MaxSessions), so use a
+		// connection of our own.
+		dedicated, err := a.NewClient(host)
+		if err != nil {
+			return nil, nil, err
+		}
+		session, err := dedicated.NewSession()
+		if err != nil {
+			dedicated.Close()
+			return nil, nil, err
+		}
+		return session, func() {
+			session.Close()
+			dedicated.Close()
+		}, nil
+	}
+
+	// The connection is dead
+	client.close()
+	a.dropSharedClient(host, client)
+	if !reused {
+		return nil, nil, err
+	}
+	for _, delay := range sshReconnectBackoff {
+		plog.Debugf("shared ssh connection to %s dropped (%v); reconnecting in %v", host, err, delay)
+		time.Sleep(delay)
+		client, _, err = a.sharedClientFor(host)
+		if err != nil {
+			continue
+		}
+		session, err = client.NewSession()
+		if err == nil {
+			return session, func() { session.Close() }, nil
+		}
+		client.close()
+		a.dropSharedClient(host, client)
+	}
+	return nil, nil, err
+}
+
+// CloseSharedClient closes the shared connection to host, if any. It
+// should be called when the host goes away, since its address may be
+// reused by another machine.
+func (a *SSHAgent) CloseSharedClient(host string) {
+	a.poolLock.Lock()
+	c, ok := a.pool[host]
+	delete(a.pool, host)
+	a.poolLock.Unlock()
+	if ok {
+		c.close()
+	}
+}
+
+func (a *SSHAgent) closeSharedClients() {
+	a.poolLock.Lock()
+	pool := a.pool
+	a.pool = nil
+	a.poolLock.Unlock()
+	for _, c := range pool {
+		c.close()
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package network
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// testSSHServer accepts any key and runs every command successfully.
+type testSSHServer struct {
+	listener net.Listener
+	config   *ssh.ServerConfig
+
+	mu    sync.Mutex
+	conns []net.Conn
+}
+
+func newTestSSHServer(t *testing.T) *testSSHServer {
+	hostKey, err := ssh.ParsePrivateKey(testHostKeyBytes)
+	if err != nil {
+		t.Fatalf("ParsePrivateKey failed: %v", err)
+	}
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(ssh.ConnMetadata, ssh.PublicKey) (*ssh.Permissions, error) {
+			return nil, nil
+		},
+	}
+	config.AddHostKey(hostKey)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	s := &testSSHServer{listener: listener, config: config}
+	go s.serve()
+	t.Cleanup(func() { listener.Close() })
+	return s
+}
+
+func (s *testSSHServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns = append(s.conns, conn)
+		s.mu.Unlock()
+		go func() {
+			_, chans, reqs, err := ssh.NewServerConn(conn, s.config)
+			if err != nil {
+				return
+			}
+			go ssh.DiscardRequests(reqs)
+			for newChannel := range chans {
+				channel, requests, err := newChannel.Accept()
+				if err != nil {
+					continue
+				}
+				go func() {
+					for req := range requests {
+						_ = req.Reply(req.Type == "exec", nil)
+						if req.Type == "exec" {
+							_, _ = channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{0}))
+							channel.Close()
+						}
+					}
+				}()
+			}
+		}()
+	}
+}
+
+func (s *testSSHServer) connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns)
+}
+
+// dropAll closes the server side of every connection.
+func (s *testSSHServer) dropAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+}
+
+func runShared(t *testing.T, a *SSHAgent, host string) {
+	session, done, err := a.NewSharedSession(host)
+	if err != nil {
+		t.Fatalf("NewSharedSession failed: %v", err)
+	}
+	defer done()
+	if err := session.Run("true"); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+}
+
+func TestSharedSessionReusesConnection(t *testing.T) {
+	server := newTestSSHServer(t)
+	a, err := NewSSHAgent(&net.Dialer{})
+	if err != nil {
+		t.Fatalf("NewSSHAgent failed: %v", err)
+	}
+	defer a.Close()
+
+	host := server.listener.Addr().String()
+	for i := 0; i < 5; i++ {
+		runShared(t, a, host)
+	}
+	if n := server.connections(); n != 1 {
+		t.Errorf("expected 1 connection, got %d", n)
+	}
+
+	a.CloseSharedClient(host)
+	runShared(t, a, host)
+	if n := server.connections(); n != 2 {
+		t.Errorf("expected a new connection after closing, got %d", n)
+	}
+}
+
+func TestSharedSessionReconnects(t *testing.T) {
+	saved := sshReconnectBackoff
+	sshReconnectBackoff = []time.Duration{10 * time.Millisecond}
+	defer func() { sshReconnectBackoff = saved }()
+
+	server := newTestSSHServer(t)
+	a, err := NewSSHAgent(&net.Dialer{})
+	if err != nil {
+		t.Fatalf("NewSSHAgent failed: %v", err)
+	}
+	defer a.Close()
+
+	host := server.listener.Addr().String()
+	runShared(t, a, host)
+	server.dropAll()
+	runShared(t, a, host)
+	if n := server.connections(); n != 2 {
+		t.Errorf("expected to reconnect once, got %d connections", n)
+	}
+}
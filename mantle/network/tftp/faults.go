@@ -0,0 +1,65 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tftp
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Faults are failures injected into transfers, to check how clients cope
+// with them.
+type Faults struct {
+	// DropEvery drops every Nth data packet of each transfer, which the
+	// client must notice and get resent.
+	DropEvery int
+	// Delay delays each data packet.
+	Delay time.Duration
+	// Fail fails the requests for the files it matches.
+	Fail *regexp.Regexp
+}
+
+// ParseFaults parses faults written as comma-separated KEY=VALUE pairs:
+// drop-every=N, delay=DURATION and fail=REGEX.
+func ParseFaults(spec string) (*Faults, error) {
+	var faults Faults
+	for _, field := range strings.Split(spec, ",") {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return nil, fmt.Errorf("fault %q is not KEY=VALUE", field)
+		}
+		var err error
+		switch key {
+		case "drop-every":
+			faults.DropEvery, err = strconv.Atoi(value)
+			if err == nil && faults.DropEvery < 1 {
+				err = fmt.Errorf("must be positive")
+			}
+		case "delay":
+			faults.Delay, err = time.ParseDuration(value)
+		case "fail":
+			faults.Fail, err = regexp.Compile(value)
+		default:
+			return nil, fmt.Errorf("unknown fault %q", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parsing fault %s: %w", key, err)
+		}
+	}
+	return &faults, nil
+}
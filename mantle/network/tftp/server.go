@@ -0,0 +1,542 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tftp is a read-only TFTP server (RFC 1350) for PXE installs,
+// which negotiates the block size, transfer size, timeout and window size
+// options (RFCs 2347, 2348, 2349 and 7440), reports each transfer, and can
+// inject failures into them.
+package tftp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	opRRQ   = 1
+	opWRQ   = 2
+	opDATA  = 3
+	opACK   = 4
+	opERROR = 5
+	opOACK  = 6
+)
+
+// error codes
+const (
+	errUndefined     = 0
+	errNotFound      = 1
+	errAccess        = 2
+	errIllegalOp     = 4
+	errUnknownTID    = 5
+	errOptionsDenied = 8
+)
+
+const (
+	// DefaultBlockSize is the block size of clients which don't negotiate
+	// one.
+	DefaultBlockSize = 512
+	// DefaultMaxBlockSize fills a packet on an Ethernet link with an MTU
+	// of 1500.
+	DefaultMaxBlockSize = 1468
+	// DefaultMaxWindowSize is the most blocks sent before waiting for an
+	// acknowledgement, for clients which negotiate a window.
+	DefaultMaxWindowSize = 16
+	maxBlockSize         = 65464
+)
+
+// Transfer is a report of a request the server handled.
+type Transfer struct {
+	Client     net.Addr
+	File       string
+	BlockSize  int
+	WindowSize int
+	Bytes      int64
+	// Retransmits is how many times a window timed out and was resent.
+	Retransmits int
+	Duration    time.Duration
+	// SizeQuery is whether the client only asked for the file's size,
+	// and then aborted the transfer, as PXE firmware often does.
+	SizeQuery bool
+	Err       error
+}
+
+func (t Transfer) String() string {
+	s := fmt.Sprintf("%s %s", t.Client, t.File)
+	switch {
+	case t.Err != nil:
+		s += fmt.Sprintf(": %v", t.Err)
+	case t.SizeQuery:
+		s += ": size query"
+	default:
+		s += fmt.Sprintf(": %d bytes in %s, blksize %d, windowsize %d", t.Bytes, t.Duration.Round(time.Millisecond), t.BlockSize, t.WindowSize)
+	}
+	if t.Retransmits > 0 {
+		s += fmt.Sprintf(", %d retransmits", t.Retransmits)
+	}
+	return s
+}
+
+// Server serves the files in a directory, and those its symlinks point to.
+type Server struct {
+	Root string
+	// MaxBlockSize and MaxWindowSize cap what clients negotiate.
+	MaxBlockSize  int
+	MaxWindowSize int
+	// Timeout is how long to wait for an acknowledgement, unless a client
+	// negotiates another, and Retries how many times to resend before
+	// giving up.
+	Timeout time.Duration
+	Retries int
+	// Faults, if set, are injected into transfers.
+	Faults *Faults
+	// Log, if set, is called with each transfer once it's over.
+	Log func(Transfer)
+
+	mu     sync.Mutex
+	conn   *net.UDPConn
+	active map[*net.UDPConn]bool
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// NewServer returns a server of the files in root.
+func NewServer(root string) *Server {
+	return &Server{
+		Root:          root,
+		MaxBlockSize:  DefaultMaxBlockSize,
+		MaxWindowSize: DefaultMaxWindowSize,
+		Timeout:       time.Second,
+		Retries:       5,
+	}
+}
+
+// ListenAndServe serves requests to addr until the server is closed.
+func (s *Server) ListenAndServe(addr string) error {
+	a, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return err
+	}
+	conn, err := net.ListenUDP("udp", a)
+	if err != nil {
+		return err
+	}
+	return s.Serve(conn)
+}
+
+// Serve serves requests to conn until the server is closed. Each transfer
+// gets its own socket on conn's address.
+func (s *Server) Serve(conn *net.UDPConn) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		conn.Close()
+		return net.ErrClosed
+	}
+	s.conn = conn
+	s.mu.Unlock()
+
+	buf := make([]byte, maxBlockSize+4)
+	for {
+		n, client, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+		req := append([]byte(nil), buf[:n]...)
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn.LocalAddr().(*net.UDPAddr), client, req)
+		}()
+	}
+}
+
+// Close stops serving requests, aborting the transfers in progress.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	var err error
+	if s.conn != nil {
+		err = s.conn.Close()
+	}
+	for conn := range s.active {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return err
+}
+
+// track records the socket of a transfer so that Close can abort it,
+// returning false if the server is closed.
+func (s *Server) track(conn *net.UDPConn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	if s.active == nil {
+		s.active = make(map[*net.UDPConn]bool)
+	}
+	s.active[conn] = true
+	return true
+}
+
+func (s *Server) untrack(conn *net.UDPConn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.active, conn)
+}
+
+// request is a parsed read request.
+type request struct {
+	file string
+	mode string
+	opts map[string]string
+	// optNames are the options in the order the client sent them
+	optNames []string
+}
+
+func parseRequest(p []byte) (*request, error) {
+	fields := bytes.Split(p, []byte{0})
+	// the request ends with a NUL, leaving an empty last field
+	if len(fields) < 3 || len(fields[len(fields)-1]) != 0 {
+		return nil, fmt.Errorf("malformed request")
+	}
+	fields = fields[:len(fields)-1]
+	req := &request{
+		file: string(fields[0]),
+		mode: strings.ToLower(string(fields[1])),
+		opts: make(map[string]string),
+	}
+	if len(fields)%2 != 0 {
+		return nil, fmt.Errorf("malformed request options")
+	}
+	for i := 2; i < len(fields); i += 2 {
+		name := strings.ToLower(string(fields[i]))
+		req.opts[name] = string(fields[i+1])
+		req.optNames = append(req.optNames, name)
+	}
+	return req, nil
+}
+
+func (s *Server) handle(local, client *net.UDPAddr, p []byte) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: local.IP})
+	if err != nil {
+		s.log(Transfer{Client: client, Err: err})
+		return
+	}
+	defer conn.Close()
+	if !s.track(conn) {
+		return
+	}
+	defer s.untrack(conn)
+	t := &transfer{
+		server: s,
+		conn:   conn,
+		client: client,
+		report: Transfer{
+			Client:     client,
+			BlockSize:  DefaultBlockSize,
+			WindowSize: 1,
+		},
+		timeout: s.Timeout,
+	}
+	start := time.Now()
+	t.report.Err = t.run(p)
+	t.report.Duration = time.Since(start)
+	s.log(t.report)
+}
+
+func (s *Server) log(t Transfer) {
+	if s.Log != nil {
+		s.Log(t)
+	}
+}
+
+// errClientAbort is a transfer the client aborted with an ERROR packet.
+type errClientAbort struct {
+	code uint16
+	msg  string
+}
+
+func (e *errClientAbort) Error() string {
+	return fmt.Sprintf("client aborted with error %d: %s", e.code, e.msg)
+}
+
+// transfer is a read request being served.
+type transfer struct {
+	server  *Server
+	conn    *net.UDPConn
+	client  *net.UDPAddr
+	report  Transfer
+	timeout time.Duration
+	// sent counts the data packets sent, for fault injection
+	sent int
+}
+
+func (t *transfer) run(p []byte) error {
+	if len(p) < 2 {
+		return fmt.Errorf("short packet")
+	}
+	switch op := binary.BigEndian.Uint16(p); op {
+	case opRRQ:
+	case opWRQ:
+		return t.sendError(errAccess, "server is read-only")
+	default:
+		return t.sendError(errIllegalOp, fmt.Sprintf("unexpected opcode %d", op))
+	}
+	req, err := parseRequest(p[2:])
+	if err != nil {
+		return t.sendError(errIllegalOp, err.Error())
+	}
+	t.report.File = req.file
+	if req.mode != "octet" && req.mode != "netascii" {
+		return t.sendError(errIllegalOp, fmt.Sprintf("unsupported mode %q", req.mode))
+	}
+	if faults := t.server.Faults; faults != nil && faults.Fail != nil && faults.Fail.MatchString(req.file) {
+		return t.sendError(errUndefined, "injected failure")
+	}
+
+	f, err := t.server.open(req.file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return t.sendError(errNotFound, "file not found")
+		}
+		return t.sendError(errAccess, err.Error())
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return t.sendError(errUndefined, err.Error())
+	}
+	if !info.Mode().IsRegular() {
+		return t.sendError(errNotFound, "not a regular file")
+	}
+
+	if oack := t.negotiate(req, info.Size()); len(oack) > 0 {
+		if err := t.sendOACK(req, oack); err != nil {
+			var abort *errClientAbort
+			if errors.As(err, &abort) && abort.code == errOptionsDenied {
+				t.report.SizeQuery = true
+				return nil
+			}
+			return err
+		}
+	}
+	return t.sendFile(f, info.Size())
+}
+
+// open opens the file a client requested in the root, which it may not
+// escape with "..".
+func (s *Server) open(name string) (*os.File, error) {
+	clean := path.Clean("/" + strings.ReplaceAll(name, "\\", "/"))
+	if clean == "/" {
+		return nil, os.ErrNotExist
+	}
+	return os.Open(filepath.Join(s.Root, filepath.FromSlash(clean)))
+}
+
+// negotiate applies the options the server supports, returning those to
+// acknowledge.
+func (t *transfer) negotiate(req *request, size int64) map[string]string {
+	oack := make(map[string]string)
+	if v, ok := req.opts["blksize"]; ok {
+		if n, err := strconv.Atoi(v); err == nil && n >= 8 {
+			n = min(n, t.server.MaxBlockSize, maxBlockSize)
+			t.report.BlockSize = n
+			oack["blksize"] = strconv.Itoa(n)
+		}
+	}
+	if _, ok := req.opts["tsize"]; ok {
+		oack["tsize"] = strconv.FormatInt(size, 10)
+	}
+	if v, ok := req.opts["timeout"]; ok {
+		if n, err := strconv.Atoi(v); err == nil && n >= 1 && n <= 255 {
+			t.timeout = time.Duration(n) * time.Second
+			oack["timeout"] = v
+		}
+	}
+	if v, ok := req.opts["windowsize"]; ok && t.server.MaxWindowSize > 1 {
+		if n, err := strconv.Atoi(v); err == nil && n >= 1 {
+			n = min(n, t.server.MaxWindowSize, 65535)
+			t.report.WindowSize = n
+			oack["windowsize"] = strconv.Itoa(n)
+		}
+	}
+	return oack
+}
+
+// sendOACK acknowledges the options and waits for the client to
+// acknowledge them in turn.
+func (t *transfer) sendOACK(req *request, oack map[string]string) error {
+	p := []byte{0, opOACK}
+	for _, name := range req.optNames {
+		if v, ok := oack[name]; ok {
+			p = append(p, name...)
+			p = append(p, 0)
+			p = append(p, v...)
+			p = append(p, 0)
+		}
+	}
+	for try := 0; ; try++ {
+		if try > t.server.Retries {
+			return fmt.Errorf("timed out waiting for the options to be acknowledged")
+		}
+		if try > 0 {
+			t.report.Retransmits++
+		}
+		if _, err := t.conn.WriteToUDP(p, t.client); err != nil {
+			return err
+		}
+		acked, err := t.waitACK(func(block uint16) bool { return block == 0 })
+		if err != nil || acked {
+			return err
+		}
+	}
+}
+
+// sendFile sends the file in windows of blocks, resending from the first
+// unacknowledged block on timeouts.
+func (t *transfer) sendFile(f *os.File, size int64) error {
+	bs, window := t.report.BlockSize, t.report.WindowSize
+	buf := make([]byte, bs+4)
+	// blocks are numbered from 1, and their numbers wrap around on the
+	// wire
+	base, last := int64(1), int64(-1)
+	for tries := 0; ; {
+		var sent int64
+		for n := base; n < base+int64(window) && (last < 0 || n <= last); n++ {
+			read, err := f.ReadAt(buf[4:], (n-1)*int64(bs))
+			if err != nil && err != io.EOF {
+				return t.sendError(errUndefined, err.Error())
+			}
+			if read < bs {
+				last = n
+			}
+			binary.BigEndian.PutUint16(buf, opDATA)
+			binary.BigEndian.PutUint16(buf[2:], uint16(n))
+			if err := t.sendData(buf[:4+read]); err != nil {
+				return err
+			}
+			sent++
+		}
+		var acked int64
+		ok, err := t.waitACK(func(block uint16) bool {
+			// how far past the block before the window this acknowledges
+			acked = int64(block - uint16(base-1))
+			return acked >= 1 && acked <= sent
+		})
+		if err != nil {
+			return err
+		}
+		if !ok {
+			tries++
+			if tries > t.server.Retries {
+				return fmt.Errorf("timed out waiting for block %d to be acknowledged", uint16(base))
+			}
+			t.report.Retransmits++
+			continue
+		}
+		tries = 0
+		if last >= 0 && base+acked > last {
+			// the final block is shorter
+			t.report.Bytes = size
+			return nil
+		}
+		t.report.Bytes = (base + acked - 1) * int64(bs)
+		base += acked
+	}
+}
+
+// sendData sends a data packet, subject to the faults.
+func (t *transfer) sendData(p []byte) error {
+	t.sent++
+	if faults := t.server.Faults; faults != nil {
+		if faults.Delay > 0 {
+			time.Sleep(faults.Delay)
+		}
+		if faults.DropEvery > 0 && t.sent%faults.DropEvery == 0 {
+			return nil
+		}
+	}
+	_, err := t.conn.WriteToUDP(p, t.client)
+	return err
+}
+
+// waitACK waits for an acknowledgement accepted by match, returning false
+// if it times out first. Stray and unmatched packets are ignored.
+func (t *transfer) waitACK(match func(block uint16) bool) (bool, error) {
+	buf := make([]byte, 516)
+	deadline := time.Now().Add(t.timeout)
+	for {
+		if err := t.conn.SetReadDeadline(deadline); err != nil {
+			return false, err
+		}
+		n, from, err := t.conn.ReadFromUDP(buf)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return false, nil
+			}
+			return false, err
+		}
+		if !from.IP.Equal(t.client.IP) || from.Port != t.client.Port {
+			pkt := append([]byte{0, opERROR, 0, errUnknownTID}, "unknown transfer ID\x00"...)
+			_, _ = t.conn.WriteToUDP(pkt, from)
+			continue
+		}
+		if n < 4 {
+			continue
+		}
+		switch binary.BigEndian.Uint16(buf) {
+		case opACK:
+			if match(binary.BigEndian.Uint16(buf[2:])) {
+				return true, nil
+			}
+		case opERROR:
+			return false, &errClientAbort{
+				code: binary.BigEndian.Uint16(buf[2:]),
+				msg:  strings.TrimRight(string(buf[4:n]), "\x00"),
+			}
+		}
+	}
+}
+
+// sendError sends an ERROR packet to the client, returning the error for
+// the report.
+func (t *transfer) sendError(code uint16, msg string) error {
+	p := []byte{0, opERROR}
+	p = binary.BigEndian.AppendUint16(p, code)
+	p = append(p, msg...)
+	p = append(p, 0)
+	_, _ = t.conn.WriteToUDP(p, t.client)
+	return errors.New(msg)
+}
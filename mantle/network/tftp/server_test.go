@@ -0,0 +1,238 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tftp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// startServer serves the files in a new directory, returning it and the
+// server's address.
+func startServer(t *testing.T, files map[string][]byte, configure func(*Server)) (*Server, *net.UDPAddr, *[]Transfer) {
+	root := t.TempDir()
+	for name, data := range files {
+		if err := os.MkdirAll(filepath.Join(root, filepath.Dir(name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := NewServer(root)
+	s.Timeout = 100 * time.Millisecond
+	var mu sync.Mutex
+	var transfers []Transfer
+	s.Log = func(tr Transfer) {
+		mu.Lock()
+		defer mu.Unlock()
+		transfers = append(transfers, tr)
+	}
+	if configure != nil {
+		configure(s)
+	}
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		_ = s.Serve(conn)
+	}()
+	t.Cleanup(func() {
+		s.Close()
+	})
+	return s, conn.LocalAddr().(*net.UDPAddr), &transfers
+}
+
+// get fetches a file like a client negotiating the options, returning it
+// and the options the server acknowledged.
+func get(t *testing.T, server *net.UDPAddr, file string, opts ...string) ([]byte, map[string]string, error) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	rrq := append([]byte{0, opRRQ}, file+"\x00octet\x00"...)
+	for _, opt := range opts {
+		rrq = append(rrq, opt+"\x00"...)
+	}
+	if _, err := conn.WriteToUDP(rrq, server); err != nil {
+		t.Fatal(err)
+	}
+	acked := make(map[string]string)
+	bs, window := DefaultBlockSize, 1
+	var data bytes.Buffer
+	var block uint16
+	var peer *net.UDPAddr
+	buf := make([]byte, 65536)
+	ack := func(n uint16) {
+		p := binary.BigEndian.AppendUint16([]byte{0, opACK}, n)
+		if _, err := conn.WriteToUDP(p, peer); err != nil {
+			t.Fatal(err)
+		}
+	}
+	received := 0
+	for {
+		if err := conn.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+			t.Fatal(err)
+		}
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			// like a client whose window timed out
+			if peer == nil {
+				return nil, nil, err
+			}
+			ack(block)
+			received = 0
+			continue
+		}
+		peer = from
+		switch binary.BigEndian.Uint16(buf) {
+		case opOACK:
+			fields := strings.Split(strings.TrimSuffix(string(buf[2:n]), "\x00"), "\x00")
+			for i := 0; i+1 < len(fields); i += 2 {
+				acked[fields[i]] = fields[i+1]
+			}
+			if v, ok := acked["blksize"]; ok {
+				bs, _ = strconv.Atoi(v)
+			}
+			if v, ok := acked["windowsize"]; ok {
+				window, _ = strconv.Atoi(v)
+			}
+			ack(0)
+		case opDATA:
+			if binary.BigEndian.Uint16(buf[2:]) != block+1 {
+				// out of order; acknowledge what we have
+				ack(block)
+				received = 0
+				continue
+			}
+			block++
+			data.Write(buf[4:n])
+			received++
+			if n-4 < bs {
+				ack(block)
+				return data.Bytes(), acked, nil
+			}
+			if received == window {
+				ack(block)
+				received = 0
+			}
+		case opERROR:
+			return nil, acked, fmt.Errorf("%s", strings.TrimRight(string(buf[4:n]), "\x00"))
+		}
+	}
+}
+
+func pattern(size int) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	return data
+}
+
+func TestGet(t *testing.T) {
+	files := map[string][]byte{
+		"pxelinux.0":       pattern(5000),
+		"boot/grub2/exact": pattern(2 * DefaultBlockSize),
+		"empty":            nil,
+	}
+	s, addr, transfers := startServer(t, files, nil)
+
+	for _, tt := range []struct {
+		file string
+		opts []string
+		want map[string]string
+	}{
+		{"pxelinux.0", nil, map[string]string{}},
+		{"/pxelinux.0", []string{"blksize", "100000", "tsize", "0"}, map[string]string{"blksize": "1468", "tsize": "5000"}},
+		{"pxelinux.0", []string{"blksize", "1024", "windowsize", "4"}, map[string]string{"blksize": "1024", "windowsize": "4"}},
+		{"pxelinux.0", []string{"windowsize", "100"}, map[string]string{"windowsize": "16"}},
+		{"boot/grub2/exact", []string{"windowsize", "2"}, map[string]string{"windowsize": "2"}},
+		{"empty", []string{"tsize", "0"}, map[string]string{"tsize": "0"}},
+	} {
+		data, acked, err := get(t, addr, tt.file, tt.opts...)
+		if err != nil {
+			t.Errorf("%s %v: %v", tt.file, tt.opts, err)
+			continue
+		}
+		if !bytes.Equal(data, files[strings.TrimPrefix(tt.file, "/")]) {
+			t.Errorf("%s %v: got %d bytes which don't match", tt.file, tt.opts, len(data))
+		}
+		if fmt.Sprint(acked) != fmt.Sprint(tt.want) {
+			t.Errorf("%s %v: acknowledged %v, expected %v", tt.file, tt.opts, acked, tt.want)
+		}
+	}
+
+	for _, file := range []string{"missing", "../" + filepath.Base(t.TempDir()), "boot"} {
+		if _, _, err := get(t, addr, file); err == nil {
+			t.Errorf("%s: no error", file)
+		}
+	}
+	// wait for the transfers to be reported
+	s.Close()
+	if len(*transfers) != 9 {
+		t.Fatalf("%d transfers reported, expected 9", len(*transfers))
+	}
+}
+
+func TestFaults(t *testing.T) {
+	faults, err := ParseFaults("drop-every=3,fail=^initrd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := pattern(20000)
+	s, addr, transfers := startServer(t, map[string][]byte{"kernel": data, "initrd.img": data}, func(s *Server) {
+		s.Faults = faults
+	})
+
+	got, _, err := get(t, addr, "kernel", "windowsize", "4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("got %d bytes which don't match", len(got))
+	}
+	if _, _, err := get(t, addr, "initrd.img"); err == nil || !strings.Contains(err.Error(), "injected") {
+		t.Errorf("expected an injected failure, got %v", err)
+	}
+	// wait for the transfers to be reported
+	s.Close()
+	if len(*transfers) != 2 {
+		t.Fatalf("%d transfers reported, expected 2", len(*transfers))
+	}
+	for _, tr := range *transfers {
+		if tr.File == "kernel" && (tr.Err != nil || tr.Bytes != int64(len(data)) || tr.Retransmits == 0) {
+			t.Errorf("unexpected report of kernel transfer: %s", tr)
+		}
+	}
+
+	for _, spec := range []string{"drop-every=0", "delay", "bogus=1", "fail=("} {
+		if _, err := ParseFaults(spec); err == nil {
+			t.Errorf("%s: no error", spec)
+		}
+	}
+}
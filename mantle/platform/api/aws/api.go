@@ -15,8 +15,6 @@
 package aws
 
 import (
-	"time"
-
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/client"
 	"github.com/aws/aws-sdk-go/aws/credentials"
@@ -50,10 +48,41 @@ type Options struct {
 	// AMI is the AWS AMI to launch EC2 instances with.
 	// If it is one of the special strings alpha|beta|stable, it will be resolved
 	// to an actual ID.
-	AMI                string
-	InstanceType       string
-	SecurityGroup      string
+	AMI           string
+	InstanceType  string
+	SecurityGroup string
+	// IAMInstanceProfile is the instance profile granting machines
+	// credentials, which is created if it doesn't exist. If empty,
+	// machines get no credentials.
 	IAMInstanceProfile string
+
+	// Spot launches machines as one-time spot instances, which are
+	// terminated if interrupted.
+	Spot bool
+	// SpotMaxPrice is the most to pay per hour for a spot instance; if
+	// empty, it's the on-demand price.
+	SpotMaxPrice string
+	// SpotFallback launches on-demand machines if there's no spot
+	// capacity in any zone.
+	SpotFallback bool
+
+	// IMDSv2Only launches machines whose instance metadata service only
+	// answers requests with IMDSv2 session tokens, as when IMDSv1 is
+	// disabled by policy.
+	IMDSv2Only bool
+	// IMDSHopLimit, if nonzero, is how many network hops the responses
+	// to IMDSv2 token requests may travel, such as 2 for containers
+	// reaching the service through a bridge.
+	IMDSHopLimit int64
+
+	// KMSKeyID, if set, is the KMS key which encrypts imported snapshots
+	// and copied images.
+	KMSKeyID string
+
+	// Partitions are settings by partition ID, such as aws-us-gov or
+	// aws-cn. Those for the partition of Region override the credentials
+	// and key above.
+	Partitions map[string]PartitionOptions
 }
 
 type API struct {
@@ -71,6 +100,10 @@ type API struct {
 // preflight check is recommended via api.PreflightCheck
 // Note that this method may modify Options to update the AMI ID
 func New(opts *Options) (*API, error) {
+	if err := applyPartition(opts); err != nil {
+		return nil, err
+	}
+
 	awsCfg := aws.Config{Region: aws.String(opts.Region)}
 	if opts.AccessKeyID != "" {
 		awsCfg.Credentials = credentials.NewStaticCredentials(opts.AccessKeyID, opts.SecretKey, "")
@@ -98,10 +131,10 @@ func New(opts *Options) (*API, error) {
 	return api, nil
 }
 
-// GC removes AWS resources that are at least gracePeriod old.
+// GC removes AWS resources which opts selects as garbage.
 // It attempts to only operate on resources that were created by a mantle tool.
-func (a *API) GC(gracePeriod time.Duration) error {
-	return a.gcEC2(gracePeriod)
+func (a *API) GC(opts platform.GCOptions) error {
+	return a.gcEC2(opts)
 }
 
 // PreflightCheck validates that the aws configuration provided has valid
@@ -15,6 +15,7 @@
 package aws
 
 import (
+	"context"
 	"encoding/base64"
 	"fmt"
 	"sort"
@@ -25,6 +26,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/ec2"
 
+	"github.com/coreos/coreos-assembler/mantle/platform"
 	"github.com/coreos/coreos-assembler/mantle/util"
 )
 
@@ -81,9 +83,12 @@ func (a *API) DeleteKey(name string) error {
 	return err
 }
 
-// CreateInstances creates EC2 instances with a given name tag, optional ssh key name, user data. The image ID, instance type, and security group set in the API will be used. CreateInstances will block until all instances are running and have an IP address.
-func (a *API) CreateInstances(name, keyname, userdata string, count uint64, minDiskSize int64, useInstanceProfile bool) ([]*ec2.Instance, error) {
+// CreateInstances creates EC2 instances with a given name tag, other tags, optional ssh key name, user data, and instance type, or the one set in the API if empty. The image ID and security group set in the API will be used. CreateInstances will block until all instances are running and have an IP address.
+func (a *API) CreateInstances(name, keyname, userdata, instanceType string, count uint64, minDiskSize int64, useInstanceProfile bool, tags map[string]string) ([]*ec2.Instance, error) {
 	cnt := int64(count)
+	if instanceType == "" {
+		instanceType = a.opts.InstanceType
+	}
 
 	var ud *string
 	if len(userdata) > 0 {
@@ -91,6 +96,7 @@ func (a *API) CreateInstances(name, keyname, userdata string, count uint64, minD
 		ud = &tud
 	}
 
+	useInstanceProfile = useInstanceProfile && a.opts.IAMInstanceProfile != ""
 	if useInstanceProfile {
 		err := a.ensureInstanceProfile(a.opts.IAMInstanceProfile)
 		if err != nil {
@@ -108,17 +114,36 @@ func (a *API) CreateInstances(name, keyname, userdata string, count uint64, minD
 		return nil, fmt.Errorf("error resolving vpc: %v", err)
 	}
 
-	zones, err := a.GetZonesForInstanceType(a.opts.InstanceType)
+	zones, err := a.GetZonesForInstanceType(instanceType)
 	if err != nil {
 		// Find all available zones that offer the given instance type
-		return nil, fmt.Errorf("error finding zones for instance type %v", a.opts.InstanceType)
+		return nil, fmt.Errorf("error finding zones for instance type %v", instanceType)
+	}
+
+	// Try spot instances in each zone first if requested, falling back
+	// to on-demand ones if there's no spot capacity
+	type attempt struct {
+		zone string
+		spot bool
+	}
+	var attempts []attempt
+	if a.opts.Spot {
+		for _, zone := range zones {
+			attempts = append(attempts, attempt{zone: zone, spot: true})
+		}
+	}
+	if !a.opts.Spot || a.opts.SpotFallback {
+		for _, zone := range zones {
+			attempts = append(attempts, attempt{zone: zone})
+		}
 	}
 
 	var reservations *ec2.Reservation
 
 	// Iterate over other possible zones if capacity for an instance
 	// type is exhausted
-	for zoneKey, zone := range zones {
+	for attemptKey, at := range attempts {
+		zone := at.zone
 		subnetId, err := a.getSubnetID(vpcId, zone)
 		if err != nil {
 			return nil, fmt.Errorf("error resolving subnet: %v", err)
@@ -143,7 +168,7 @@ func (a *API) CreateInstances(name, keyname, userdata string, count uint64, minD
 			MinCount:            &cnt,
 			MaxCount:            &cnt,
 			KeyName:             key,
-			InstanceType:        &a.opts.InstanceType,
+			InstanceType:        &instanceType,
 			SecurityGroupIds:    []*string{&sgId},
 			SubnetId:            &subnetId,
 			UserData:            ud,
@@ -151,7 +176,7 @@ func (a *API) CreateInstances(name, keyname, userdata string, count uint64, minD
 			TagSpecifications: []*ec2.TagSpecification{
 				{
 					ResourceType: aws.String(ec2.ResourceTypeInstance),
-					Tags: []*ec2.Tag{
+					Tags: append([]*ec2.Tag{
 						{
 							Key:   aws.String("Name"),
 							Value: aws.String(name),
@@ -160,24 +185,53 @@ func (a *API) CreateInstances(name, keyname, userdata string, count uint64, minD
 							Key:   aws.String("CreatedBy"),
 							Value: aws.String("mantle"),
 						},
-					},
+					}, ec2Tags(tags)...),
 				},
 			},
 		}
+		if a.opts.IMDSv2Only || a.opts.IMDSHopLimit > 0 {
+			metadata := &ec2.InstanceMetadataOptionsRequest{
+				HttpEndpoint: aws.String(ec2.InstanceMetadataEndpointStateEnabled),
+			}
+			if a.opts.IMDSv2Only {
+				metadata.HttpTokens = aws.String(ec2.HttpTokensStateRequired)
+			}
+			if a.opts.IMDSHopLimit > 0 {
+				metadata.HttpPutResponseHopLimit = aws.Int64(a.opts.IMDSHopLimit)
+			}
+			inst.MetadataOptions = metadata
+		}
 		if useInstanceProfile {
 			inst.IamInstanceProfile = &ec2.IamInstanceProfileSpecification{
 				Name: &a.opts.IAMInstanceProfile,
 			}
 		}
-
-		err = util.RetryConditional(5, 5*time.Second, func(err error) bool {
-			// due to AWS' eventual consistency despite ensuring that the IAM Instance
-			// Profile has been created it may not be available to ec2 yet.
-			if awsErr, ok := err.(awserr.Error); ok && (awsErr.Code() == "InvalidParameterValue" && strings.Contains(awsErr.Message(), "iamInstanceProfile.name")) {
-				return true
+		if at.spot {
+			spotOptions := &ec2.SpotMarketOptions{
+				SpotInstanceType:             aws.String(ec2.SpotInstanceTypeOneTime),
+				InstanceInterruptionBehavior: aws.String(ec2.InstanceInterruptionBehaviorTerminate),
+			}
+			if a.opts.SpotMaxPrice != "" {
+				spotOptions.MaxPrice = aws.String(a.opts.SpotMaxPrice)
 			}
-			return false
-		}, func() error {
+			inst.InstanceMarketOptions = &ec2.InstanceMarketOptionsRequest{
+				MarketType:  aws.String(ec2.MarketTypeSpot),
+				SpotOptions: spotOptions,
+			}
+		}
+
+		err = util.Backoff{
+			Delay:      2 * time.Second,
+			MaxDelay:   10 * time.Second,
+			Jitter:     0.2,
+			MaxElapsed: time.Minute,
+			ShouldRetry: func(err error) bool {
+				// due to AWS' eventual consistency despite ensuring that the IAM Instance
+				// Profile has been created it may not be available to ec2 yet.
+				awsErr, ok := err.(awserr.Error)
+				return ok && awsErr.Code() == "InvalidParameterValue" && strings.Contains(awsErr.Message(), "iamInstanceProfile.name")
+			},
+		}.Do(context.Background(), func() error {
 			var ierr error
 			reservations, ierr = a.ec2.RunInstances(&inst)
 			return ierr
@@ -188,13 +242,17 @@ func (a *API) CreateInstances(name, keyname, userdata string, count uint64, minD
 		}
 		if err != nil {
 			// Handle InsufficientInstanceCapacity error specifically
-			if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == "InsufficientInstanceCapacity" {
+			if isCapacityError(err, at.spot) {
 				// If we iterate over all possible zones and none of them have sufficient instance(s)
 				// available we will return the InsufficientInstanceCapacity error
-				if zoneKey == len(zones)-1 {
+				if attemptKey == len(attempts)-1 {
 					return nil, fmt.Errorf("all available zones tried: %v", err)
 				}
-				plog.Warningf("Insufficient instances available in zone %v. Trying the next zone\n", zone)
+				if at.spot {
+					plog.Warningf("Insufficient spot instances available in zone %v. Trying the next zone\n", zone)
+				} else {
+					plog.Warningf("Insufficient instances available in zone %v. Trying the next zone\n", zone)
+				}
 				continue
 			}
 			return nil, fmt.Errorf("error running instances: %v", err)
@@ -246,6 +304,9 @@ func (a *API) CreateInstances(name, keyname, userdata string, count uint64, minD
 	tagMap := map[string]string{
 		"CreatedBy": "mantle",
 	}
+	for k, v := range tags {
+		tagMap[k] = v
+	}
 	for _, inst := range insts {
 		if len(inst.BlockDeviceMappings) > 0 {
 			for _, mapping := range inst.BlockDeviceMappings {
@@ -263,6 +324,22 @@ func (a *API) CreateInstances(name, keyname, userdata string, count uint64, minD
 	return insts, nil
 }
 
+// isCapacityError reports whether err means there are no instances to be had
+// in the zone, for now, so another zone may be tried.
+func isCapacityError(err error, spot bool) bool {
+	awsErr, ok := err.(awserr.Error)
+	if !ok {
+		return false
+	}
+	switch awsErr.Code() {
+	case "InsufficientInstanceCapacity":
+		return true
+	case "SpotMaxPriceTooLow", "MaxSpotInstanceCountExceeded":
+		return spot
+	}
+	return false
+}
+
 // StopInstances will stop all instances provided in the ids slice and will
 // block until all instances are in the "stopped" state
 func (a *API) StopInstances(ids []string) error {
@@ -515,12 +592,10 @@ func (a *API) ReplaceRootVolume(instanceID string, deviceName string, newRootVol
 	return nil
 }
 
-// gcEC2 will terminate ec2 instances older than gracePeriod.
-// It will only operate on ec2 instances tagged with 'mantle' to avoid stomping
-// on other resources in the account.
-func (a *API) gcEC2(gracePeriod time.Duration) error {
-	durationAgo := time.Now().Add(-1 * gracePeriod)
-
+// gcEC2 will terminate ec2 instances and delete volumes which opts selects
+// as garbage. It will only operate on those tagged with 'mantle' to avoid
+// stomping on other resources in the account.
+func (a *API) gcEC2(opts platform.GCOptions) error {
 	instances, err := a.ec2.DescribeInstances(&ec2.DescribeInstancesInput{
 		Filters: []*ec2.Filter{
 			{
@@ -533,20 +608,23 @@ func (a *API) gcEC2(gracePeriod time.Duration) error {
 		return fmt.Errorf("error describing instances: %v", err)
 	}
 
-	toTerminate := []string{}
-
 	for _, reservation := range instances.Reservations {
 		for _, instance := range reservation.Instances {
-			if instance.LaunchTime.After(durationAgo) {
-				plog.Debugf("ec2: skipping instance %s due to being too new", *instance.InstanceId)
-				// Skip, still too new
+			if !opts.IsGarbage(*instance.LaunchTime, tagsFromEC2(instance.Tags)) {
+				plog.Debugf("ec2: skipping instance %s due to being too new or not matching tags", *instance.InstanceId)
 				continue
 			}
 
 			if instance.State != nil {
 				switch *instance.State.Name {
 				case ec2.InstanceStateNamePending, ec2.InstanceStateNameRunning, ec2.InstanceStateNameStopped:
-					toTerminate = append(toTerminate, *instance.InstanceId)
+					id := *instance.InstanceId
+					err := opts.Collect("instance", id, *instance.LaunchTime, func() error {
+						return a.TerminateInstances([]string{id})
+					})
+					if err != nil {
+						return fmt.Errorf("error terminating instance %s: %v", id, err)
+					}
 				case ec2.InstanceStateNameTerminated, ec2.InstanceStateNameShuttingDown:
 				default:
 					plog.Infof("ec2: skipping instance in state %s", *instance.State.Name)
@@ -557,11 +635,6 @@ func (a *API) gcEC2(gracePeriod time.Duration) error {
 		}
 	}
 
-	err = a.TerminateInstances(toTerminate)
-	if err != nil {
-		return fmt.Errorf("error terminating instances: %v", err)
-	}
-
 	volumesRes, err := a.ec2.DescribeVolumes(&ec2.DescribeVolumesInput{
 		Filters: []*ec2.Filter{
 			{
@@ -574,28 +647,52 @@ func (a *API) gcEC2(gracePeriod time.Duration) error {
 		return fmt.Errorf("error describing volumes: %v", err)
 	}
 
-	toDelete := []string{}
-
 	for _, volume := range volumesRes.Volumes {
-		if volume.CreateTime.After(durationAgo) {
-			plog.Debugf("ec2: skipping volume %s due to being too new", *volume.VolumeId)
-			// skip, still too new
+		if !opts.IsGarbage(*volume.CreateTime, tagsFromEC2(volume.Tags)) {
+			plog.Debugf("ec2: skipping volume %s due to being too new or not matching tags", *volume.VolumeId)
 			continue
 		}
 
 		if *volume.State == ec2.VolumeStateAvailable {
-			toDelete = append(toDelete, *volume.VolumeId)
+			id := *volume.VolumeId
+			err := opts.Collect("volume", id, *volume.CreateTime, func() error {
+				return a.DeleteVolumes([]string{id})
+			})
+			if err != nil {
+				return fmt.Errorf("error deleting volume %s: %v", id, err)
+			}
 		} else {
 			plog.Infof("ec2: skipping volume in state %s", *volume.State)
 		}
 	}
 
-	err = a.DeleteVolumes(toDelete)
-	if err != nil {
-		return fmt.Errorf("error deleteing volumes: %v", err)
+	return nil
+}
+
+// tagsFromEC2 returns EC2 tags as a map.
+func tagsFromEC2(tags []*ec2.Tag) map[string]string {
+	m := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		m[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
 	}
+	return m
+}
 
-	return nil
+// ec2Tags returns a map of tags as EC2 tags, sorted by key.
+func ec2Tags(tags map[string]string) []*ec2.Tag {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	ec2Tags := make([]*ec2.Tag, 0, len(keys))
+	for _, k := range keys {
+		ec2Tags = append(ec2Tags, &ec2.Tag{
+			Key:   aws.String(k),
+			Value: aws.String(tags[k]),
+		})
+	}
+	return ec2Tags
 }
 
 // TerminateInstances schedules EC2 instances to be terminated.
@@ -15,6 +15,7 @@
 package aws
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"runtime"
@@ -29,6 +30,7 @@ import (
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/iam"
 
+	"github.com/coreos/coreos-assembler/mantle/platform"
 	"github.com/coreos/coreos-assembler/mantle/util"
 )
 
@@ -160,7 +162,7 @@ func (a *API) CreateSnapshot(imageName, sourceURL string, format EC2ImageFormat)
 	}
 	s3key := strings.TrimPrefix(s3url.Path, "/")
 
-	importRes, err := a.ec2.ImportSnapshot(&ec2.ImportSnapshotInput{
+	input := &ec2.ImportSnapshotInput{
 		RoleName:    aws.String(vmImportRole),
 		Description: aws.String(imageName),
 		DiskContainer: &ec2.SnapshotDiskContainer{
@@ -171,7 +173,12 @@ func (a *API) CreateSnapshot(imageName, sourceURL string, format EC2ImageFormat)
 			},
 			Format: aws.String(string(format)),
 		},
-	})
+	}
+	if a.opts.KMSKeyID != "" {
+		input.Encrypted = aws.Bool(true)
+		input.KmsKeyId = aws.String(a.opts.KMSKeyID)
+	}
+	importRes, err := a.ec2.ImportSnapshot(input)
 	if err != nil {
 		return nil, fmt.Errorf("unable to create import snapshot task: %v", err)
 	}
@@ -353,14 +360,14 @@ func (a *API) CreateWinLiImage(snapshotID string, name string, description strin
 		return "", "", err
 	}
 
-	instanceName := util.RandomName("winli-builder")
+	instanceName := util.ResourceName("winli-builder")
 	keyname := ""
 	userdata := ""
 	count := 1
 	minDiskSize := 0
 	useInstanceProfile := false
 
-	insts, err := aa.CreateInstances(instanceName, keyname, userdata, uint64(count), int64(minDiskSize), useInstanceProfile)
+	insts, err := aa.CreateInstances(instanceName, keyname, userdata, "", uint64(count), int64(minDiskSize), useInstanceProfile, platform.ResourceTags(opts.Options, ""))
 	if err != nil {
 		return "", "", fmt.Errorf("failed to create windows server instance %q", err)
 	}
@@ -556,12 +563,13 @@ func (a *API) createImage(params *ec2.RegisterImageInput) (string, error) {
 
 	// Attempt to tag inside of a retry loop; AWS eventual consistency means that just because
 	// the FindImage call found the AMI it might not be found by the CreateTags call
-	err = util.RetryConditional(6, 5*time.Second, func(err error) bool {
-		if awserr, ok := err.(awserr.Error); ok && awserr.Code() == "InvalidAMIID.NotFound" {
-			return true
-		}
-		return false
-	}, func() error {
+	err = util.Backoff{
+		Delay:       2 * time.Second,
+		MaxDelay:    10 * time.Second,
+		Jitter:      0.2,
+		MaxElapsed:  time.Minute,
+		ShouldRetry: isAMINotFound,
+	}.Do(context.Background(), func() error {
 		// We do this even in the already-exists path in case the previous
 		// run was interrupted.
 		return a.CreateTags([]string{imageID}, map[string]string{
@@ -637,12 +645,13 @@ func (a *API) CreateImageFromInstance(params *ec2.CreateImageInput) (string, str
 
 	// Attempt to tag inside of a retry loop; AWS eventual consistency means that just because
 	// the FindImage call found the AMI it might not be found by the CreateTags call
-	err = util.RetryConditional(6, 5*time.Second, func(err error) bool {
-		if awserr, ok := err.(awserr.Error); ok && awserr.Code() == "InvalidAMIID.NotFound" {
-			return true
-		}
-		return false
-	}, func() error {
+	err = util.Backoff{
+		Delay:       2 * time.Second,
+		MaxDelay:    10 * time.Second,
+		Jitter:      0.2,
+		MaxElapsed:  time.Minute,
+		ShouldRetry: isAMINotFound,
+	}.Do(context.Background(), func() error {
 		// tag the new image and the new snapshot
 		// We do this even in the already-exists path in case the previous
 		// run was interrupted.
@@ -696,6 +705,46 @@ func (a *API) GrantLaunchPermission(imageID string, userIDs []string) error {
 	return nil
 }
 
+// ImageLaunchPermissions returns the accounts the image is shared with,
+// and whether it's public.
+func (a *API) ImageLaunchPermissions(imageID string) ([]string, bool, error) {
+	res, err := a.ec2.DescribeImageAttribute(&ec2.DescribeImageAttributeInput{
+		Attribute: aws.String("launchPermission"),
+		ImageId:   aws.String(imageID),
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("couldn't describe launch permissions: %v", err)
+	}
+	var userIDs []string
+	public := false
+	for _, p := range res.LaunchPermissions {
+		if p.UserId != nil {
+			userIDs = append(userIDs, *p.UserId)
+		}
+		if p.Group != nil && *p.Group == "all" {
+			public = true
+		}
+	}
+	return userIDs, public, nil
+}
+
+// ShareImage grants the accounts launch permission on the image and
+// create-volume permission on its snapshot.
+func (a *API) ShareImage(imageID string, userIDs []string) error {
+	image, err := a.DescribeImage(imageID)
+	if err != nil {
+		return err
+	}
+	snapshotID, err := getImageSnapshotID(image)
+	if err != nil {
+		return err
+	}
+	if err := a.GrantVolumePermission(snapshotID, userIDs); err != nil {
+		return err
+	}
+	return a.GrantLaunchPermission(imageID, userIDs)
+}
+
 func (a *API) CopyImage(sourceImageID string, regions []string, cb func(string, ImageData)) error {
 	type result struct {
 		region string
@@ -738,6 +787,21 @@ func (a *API) CopyImage(sourceImageID string, regions []string, cb func(string,
 	}
 	launchPermissions := describeAttributeRes.LaunchPermissions
 
+	// Images can't be copied between partitions
+	sourcePartition, err := PartitionForRegion(a.opts.Region)
+	if err != nil {
+		return err
+	}
+	for _, region := range regions {
+		partition, err := PartitionForRegion(region)
+		if err != nil {
+			return err
+		}
+		if partition != sourcePartition {
+			return fmt.Errorf("can't copy image from %s partition to %s in %s partition; upload it there instead", sourcePartition, region, partition)
+		}
+	}
+
 	var wg sync.WaitGroup
 	ch := make(chan result, len(regions))
 	for _, region := range regions {
@@ -782,12 +846,17 @@ func (a *API) copyImageIn(sourceRegion, sourceImageID, name, description string,
 	}
 
 	if imageID == "" {
-		copyRes, err := a.ec2.CopyImage(&ec2.CopyImageInput{
+		input := &ec2.CopyImageInput{
 			SourceRegion:  aws.String(sourceRegion),
 			SourceImageId: aws.String(sourceImageID),
 			Name:          aws.String(name),
 			Description:   aws.String(description),
-		})
+		}
+		if a.opts.KMSKeyID != "" {
+			input.Encrypted = aws.Bool(true)
+			input.KmsKeyId = aws.String(a.opts.KMSKeyID)
+		}
+		copyRes, err := a.ec2.CopyImage(input)
 		if err != nil {
 			return ImageData{}, fmt.Errorf("couldn't initiate image copy to %v: %v", a.opts.Region, err)
 		}
@@ -1015,3 +1084,10 @@ func getImageSnapshotID(image *ec2.Image) (string, error) {
 	// and it's just a sorta eventual consistency thing
 	return "", fmt.Errorf("no backing block device for %v", image.ImageId)
 }
+
+// isAMINotFound returns whether the error is AWS not having found an AMI,
+// which may just not be visible yet.
+func isAMINotFound(err error) bool {
+	awserr, ok := err.(awserr.Error)
+	return ok && awserr.Code() == "InvalidAMIID.NotFound"
+}
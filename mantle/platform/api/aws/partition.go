@@ -0,0 +1,126 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aws
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+)
+
+// PartitionOptions are the settings which differ between AWS partitions,
+// such as aws-us-gov and aws-cn, whose accounts, buckets and keys are
+// separate from those of the commercial partition.
+type PartitionOptions struct {
+	CredentialsFile string
+	Profile         string
+	AccessKeyID     string
+	SecretKey       string
+
+	// Bucket is the s3:// URL of the bucket, and optionally a prefix,
+	// uploads to the partition's regions go to.
+	Bucket string
+	// KMSKeyID, if set, is the KMS key which encrypts the snapshots
+	// imported and the images copied in the partition's regions.
+	KMSKeyID string
+}
+
+// PartitionForRegion returns the ID of the partition the region is in, such
+// as aws, aws-us-gov or aws-cn.
+func PartitionForRegion(region string) (string, error) {
+	partition, ok := endpoints.PartitionForRegion(endpoints.DefaultPartitions(), region)
+	if !ok {
+		return "", fmt.Errorf("could not find the partition of region %q", region)
+	}
+	return partition.ID(), nil
+}
+
+// ParsePartitionOptions parses options of the form
+// PARTITION:KEY=VALUE[,KEY=VALUE...], such as
+// "aws-us-gov:profile=govcloud,bucket=s3://my-gov-bucket". The keys are
+// credentials-file, profile, access-id, secret-key, bucket and kms-key.
+func ParsePartitionOptions(s string) (string, PartitionOptions, error) {
+	var opts PartitionOptions
+	id, settings, ok := strings.Cut(s, ":")
+	if !ok || settings == "" {
+		return "", opts, fmt.Errorf("invalid partition options %q; expected PARTITION:KEY=VALUE[,KEY=VALUE...]", s)
+	}
+	if !partitionExists(id) {
+		return "", opts, fmt.Errorf("unknown partition %q", id)
+	}
+	for _, setting := range strings.Split(settings, ",") {
+		key, value, ok := strings.Cut(setting, "=")
+		if !ok {
+			return "", opts, fmt.Errorf("invalid partition setting %q; expected KEY=VALUE", setting)
+		}
+		switch key {
+		case "credentials-file":
+			opts.CredentialsFile = value
+		case "profile":
+			opts.Profile = value
+		case "access-id":
+			opts.AccessKeyID = value
+		case "secret-key":
+			opts.SecretKey = value
+		case "bucket":
+			opts.Bucket = value
+		case "kms-key":
+			opts.KMSKeyID = value
+		default:
+			return "", opts, fmt.Errorf("unknown partition setting %q", key)
+		}
+	}
+	return id, opts, nil
+}
+
+func partitionExists(id string) bool {
+	for _, p := range endpoints.DefaultPartitions() {
+		if p.ID() == id {
+			return true
+		}
+	}
+	return false
+}
+
+// applyPartition overrides the credentials and key in opts with those
+// configured for the partition of its region, if any.
+func applyPartition(opts *Options) error {
+	if len(opts.Partitions) == 0 {
+		return nil
+	}
+	id, err := PartitionForRegion(opts.Region)
+	if err != nil {
+		return err
+	}
+	p, ok := opts.Partitions[id]
+	if !ok {
+		return nil
+	}
+	if p.CredentialsFile != "" {
+		opts.CredentialsFile = p.CredentialsFile
+	}
+	if p.Profile != "" {
+		opts.Profile = p.Profile
+	}
+	if p.AccessKeyID != "" {
+		opts.AccessKeyID = p.AccessKeyID
+		opts.SecretKey = p.SecretKey
+	}
+	if p.KMSKeyID != "" {
+		opts.KMSKeyID = p.KMSKeyID
+	}
+	return nil
+}
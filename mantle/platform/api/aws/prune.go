@@ -0,0 +1,121 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aws
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// OwnedImage is an AMI owned by the account.
+type OwnedImage struct {
+	ID      string
+	Name    string
+	Created time.Time
+	Public  bool
+	// SnapshotIDs are the EBS snapshots backing the AMI.
+	SnapshotIDs []string
+}
+
+// OwnedSnapshot is an EBS snapshot owned by the account.
+type OwnedSnapshot struct {
+	ID string
+	// Name is the snapshot's Name tag, which is the name of the image it
+	// was imported for.
+	Name    string
+	Created time.Time
+}
+
+// ListOwnedImages returns the AMIs owned by the account in the region
+// whose names start with one of the prefixes.
+func (a *API) ListOwnedImages(prefixes []string) ([]OwnedImage, error) {
+	var images []OwnedImage
+	err := a.ec2.DescribeImagesPages(&ec2.DescribeImagesInput{
+		Owners:  aws.StringSlice([]string{"self"}),
+		Filters: prefixFilters("name", prefixes),
+	}, func(page *ec2.DescribeImagesOutput, _ bool) bool {
+		for _, image := range page.Images {
+			img := OwnedImage{
+				ID:     aws.StringValue(image.ImageId),
+				Name:   aws.StringValue(image.Name),
+				Public: aws.BoolValue(image.Public),
+			}
+			if image.CreationDate != nil {
+				img.Created, _ = time.Parse(time.RFC3339, *image.CreationDate)
+			}
+			for _, dev := range image.BlockDeviceMappings {
+				if dev.Ebs != nil && dev.Ebs.SnapshotId != nil {
+					img.SnapshotIDs = append(img.SnapshotIDs, *dev.Ebs.SnapshotId)
+				}
+			}
+			images = append(images, img)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing images: %v", err)
+	}
+	return images, nil
+}
+
+// ListOwnedSnapshots returns the completed EBS snapshots owned by the
+// account in the region whose Name tags start with one of the prefixes.
+func (a *API) ListOwnedSnapshots(prefixes []string) ([]OwnedSnapshot, error) {
+	var snapshots []OwnedSnapshot
+	err := a.ec2.DescribeSnapshotsPages(&ec2.DescribeSnapshotsInput{
+		OwnerIds: aws.StringSlice([]string{"self"}),
+		Filters: append(prefixFilters("tag:Name", prefixes), &ec2.Filter{
+			Name:   aws.String("status"),
+			Values: aws.StringSlice([]string{"completed"}),
+		}),
+	}, func(page *ec2.DescribeSnapshotsOutput, _ bool) bool {
+		for _, snapshot := range page.Snapshots {
+			s := OwnedSnapshot{
+				ID:      aws.StringValue(snapshot.SnapshotId),
+				Created: aws.TimeValue(snapshot.StartTime),
+			}
+			for _, t := range snapshot.Tags {
+				if aws.StringValue(t.Key) == "Name" {
+					s.Name = aws.StringValue(t.Value)
+				}
+			}
+			snapshots = append(snapshots, s)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing snapshots: %v", err)
+	}
+	return snapshots, nil
+}
+
+// prefixFilters returns a filter matching values of name starting with
+// one of the prefixes, or none if there are no prefixes.
+func prefixFilters(name string, prefixes []string) []*ec2.Filter {
+	if len(prefixes) == 0 {
+		return nil
+	}
+	var values []string
+	for _, p := range prefixes {
+		values = append(values, p+"*")
+	}
+	return []*ec2.Filter{{
+		Name:   aws.String(name),
+		Values: aws.StringSlice(values),
+	}}
+}
@@ -26,10 +26,15 @@ import (
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/storage/armstorage"
+	"github.com/coreos/pkg/capnslog"
 
 	"github.com/coreos/coreos-assembler/mantle/auth"
+	"github.com/coreos/coreos-assembler/mantle/platform"
+	"github.com/coreos/coreos-assembler/mantle/util"
 )
 
+var plog = capnslog.NewPackageLogger("github.com/coreos/coreos-assembler/mantle", "platform/api/azure")
+
 type API struct {
 	azIdCred        *azidentity.DefaultAzureCredential
 	rgClient        *armresources.ResourceGroupsClient
@@ -38,6 +43,7 @@ type API struct {
 	galClient       *armcompute.GalleriesClient
 	galImgClient    *armcompute.GalleryImagesClient
 	galImgVerClient *armcompute.GalleryImageVersionsClient
+	galShareClient  *armcompute.GallerySharingProfileClient
 	diskClient      *armcompute.DisksClient
 	netClient       *armnetwork.VirtualNetworksClient
 	subClient       *armnetwork.SubnetsClient
@@ -109,6 +115,11 @@ func (a *API) SetupClients() error {
 		return err
 	}
 
+	a.galShareClient, err = armcompute.NewGallerySharingProfileClient(a.opts.SubscriptionID, a.azIdCred, nil)
+	if err != nil {
+		return err
+	}
+
 	a.diskClient, err = armcompute.NewDisksClient(a.opts.SubscriptionID, a.azIdCred, nil)
 	if err != nil {
 		return err
@@ -143,40 +154,44 @@ func (a *API) SetupClients() error {
 	return err
 }
 
-func (a *API) GC(gracePeriod time.Duration) error {
-	durationAgo := time.Now().Add(-1 * gracePeriod)
-
+// GC deletes the kola-cluster resource groups, and the disks, images,
+// gallery image versions and blobs mantle created in the places locations
+// names, which opts selects as garbage.
+func (a *API) GC(opts platform.GCOptions, locations GCLocations) error {
 	resourceGroups, err := a.ListResourceGroups()
 	if err != nil {
 		return fmt.Errorf("listing resource groups: %v", err)
 	}
 
 	for _, l := range resourceGroups {
-		if strings.HasPrefix(*l.Name, "kola-cluster") {
-			terminate := false
-			if l.Tags == nil || l.Tags["createdAt"] == nil {
-				// If the group name starts with kola-cluster and has
-				// no tags OR no createdAt then it failed to properly
-				// get created and we should clean it up.
-				// https://github.com/coreos/coreos-assembler/issues/3057
-				terminate = true
-			} else {
-				timeCreated, err := time.Parse(time.RFC3339, *l.Tags["createdAt"])
-				if err != nil {
-					return fmt.Errorf("error parsing time: %v", err)
-				}
-				if !timeCreated.After(durationAgo) {
-					// If the group is older than specified time then gc
-					terminate = true
-				}
-			}
-			if terminate {
-				if err = a.TerminateResourceGroup(*l.Name); err != nil {
-					return err
-				}
+		if !strings.HasPrefix(*l.Name, "kola-cluster") {
+			continue
+		}
+		// If the group name starts with kola-cluster and has no
+		// createdAt tag then it failed to properly get created and
+		// we should clean it up once its name says it's old enough, or
+		// whatever its age if its name predates util.ResourceName.
+		// https://github.com/coreos/coreos-assembler/issues/3057
+		var created time.Time
+		if createdAt := tag(l.Tags, "createdAt"); createdAt != "" {
+			created, err = time.Parse(time.RFC3339, createdAt)
+			if err != nil {
+				return fmt.Errorf("error parsing time: %v", err)
 			}
+		} else if info, ok := util.ParseResourceName(*l.Name); ok && info.FromMantle() {
+			created = info.Created
+		}
+		if !opts.IsGarbage(created, stringTags(l.Tags)) {
+			continue
+		}
+		name := *l.Name
+		err := opts.Collect("resource group", name, created, func() error {
+			return a.TerminateResourceGroup(name)
+		})
+		if err != nil {
+			return err
 		}
 	}
 
-	return nil
+	return a.gcResources(opts, locations)
 }
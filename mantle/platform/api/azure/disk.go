@@ -17,12 +17,18 @@ package azure
 
 import (
 	"context"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/pageblob"
 
+	"github.com/coreos/coreos-assembler/mantle/platform"
 	"github.com/coreos/coreos-assembler/mantle/util"
 )
 
@@ -34,9 +40,7 @@ func (a *API) CreateDisk(name, resourceGroup string, sizeGB int32, sku armcomput
 	poller, err := a.diskClient.BeginCreateOrUpdate(ctx, resourceGroup, name, armcompute.Disk{
 		Location: &a.opts.Location,
 		Zones:    []*string{&a.opts.AvailabilityZone},
-		Tags: map[string]*string{
-			"createdBy": to.Ptr("mantle"),
-		},
+		Tags:     a.resourceTags(nil),
 		SKU: &armcompute.DiskSKU{
 			Name: to.Ptr(sku),
 		},
@@ -64,6 +68,151 @@ func (a *API) CreateDisk(name, resourceGroup string, sizeGB int32, sku armcomput
 	return *diskResponse.Disk.ID, nil
 }
 
+// ErrDiskExists is returned by UploadDisk if the disk exists and isn't an
+// interrupted upload of the same file.
+var ErrDiskExists = errors.New("disk exists")
+
+// uploadSASDuration is how long the write access UploadDisk is granted to
+// a disk lasts, in seconds.
+const uploadSASDuration = 24 * 60 * 60
+
+// UploadDisk uploads a fixed VHD file directly to a new managed disk,
+// rather than to a page blob in a storage account, and returns the disk's
+// ID. The disk is created for upload, written through a SAS URL it grants,
+// and committed by revoking the access. If the disk is an interrupted
+// upload of the same file, only the chunks it lacks are uploaded.
+func (a *API) UploadDisk(name, resourceGroup, file string, opts UploadOptions) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	size := fi.Size()
+	// A fixed VHD is the disk followed by a 512-byte footer, and managed
+	// disks are whole MiBs.
+	if size < 512 || (size-512)%(1024*1024) != 0 {
+		return "", fmt.Errorf("%s isn't a fixed VHD whose disk is a whole number of MiB", file)
+	}
+
+	sum, err := fileMD5(f)
+	if err != nil {
+		return "", err
+	}
+	sumHex := hex.EncodeToString(sum)
+
+	// Create the disk, unless we're resuming an upload to it
+	ctx := context.Background()
+	resume := false
+	resp, err := a.diskClient.Get(ctx, resourceGroup, name, nil)
+	switch {
+	case isNotFound(err):
+		err = a.createUploadDisk(ctx, name, resourceGroup, size, sumHex)
+	case err != nil:
+		return "", err
+	case tag(resp.Tags, uploadMD5Key) == sumHex && diskState(resp.Disk) == armcompute.DiskStateUnattached:
+		plog.Infof("Disk %s is already uploaded", name)
+		return *resp.ID, nil
+	case tag(resp.Tags, uploadMD5Key) == sumHex && (diskState(resp.Disk) == armcompute.DiskStateReadyToUpload || diskState(resp.Disk) == armcompute.DiskStateActiveUpload):
+		plog.Infof("Resuming the upload of %s", name)
+		resume = true
+	case opts.Overwrite:
+		if err := a.DeleteDisk(name, resourceGroup); err != nil {
+			return "", err
+		}
+		err = a.createUploadDisk(ctx, name, resourceGroup, size, sumHex)
+	default:
+		return "", ErrDiskExists
+	}
+	if err != nil {
+		return "", err
+	}
+
+	grant, err := a.diskClient.BeginGrantAccess(ctx, resourceGroup, name, armcompute.GrantAccessData{
+		Access:            to.Ptr(armcompute.AccessLevelWrite),
+		DurationInSeconds: to.Ptr(int32(uploadSASDuration)),
+	}, nil)
+	if err != nil {
+		return "", fmt.Errorf("granting write access to disk %s: %v", name, err)
+	}
+	access, err := grant.PollUntilDone(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("granting write access to disk %s: %v", name, err)
+	}
+	if access.AccessSAS == nil {
+		return "", fmt.Errorf("no SAS URL granted for disk %s", name)
+	}
+	client, err := pageblob.NewClientWithNoCredential(*access.AccessSAS, nil)
+	if err != nil {
+		return "", err
+	}
+
+	var written []blob.HTTPRange
+	if resume {
+		if written, err = writtenPages(ctx, client); err != nil {
+			return "", err
+		}
+	}
+	// The disk is left open for upload on failure, so a rerun can resume
+	if err := uploadFile(ctx, client, f, size, written, opts.Parallelism); err != nil {
+		return "", fmt.Errorf("uploading pages (rerun to resume): %v", err)
+	}
+
+	// Revoking the access commits the upload
+	revoke, err := a.diskClient.BeginRevokeAccess(ctx, resourceGroup, name, nil)
+	if err != nil {
+		return "", fmt.Errorf("committing disk %s: %v", name, err)
+	}
+	if _, err := revoke.PollUntilDone(ctx, nil); err != nil {
+		return "", fmt.Errorf("committing disk %s: %v", name, err)
+	}
+	disk, err := a.diskClient.Get(ctx, resourceGroup, name, nil)
+	if err != nil {
+		return "", err
+	}
+	return *disk.ID, nil
+}
+
+// createUploadDisk creates a Gen2 Linux disk of size bytes, including the
+// VHD footer, ready to be uploaded to, recording the MD5 of the file being
+// uploaded.
+func (a *API) createUploadDisk(ctx context.Context, name, resourceGroup string, size int64, sumHex string) error {
+	tags := platform.ResourceTags(a.opts.Options, "")
+	tags[uploadMD5Key] = sumHex
+	poller, err := a.diskClient.BeginCreateOrUpdate(ctx, resourceGroup, name, armcompute.Disk{
+		Location: &a.opts.Location,
+		Tags:     a.resourceTags(tags),
+		SKU: &armcompute.DiskSKU{
+			Name: to.Ptr(armcompute.DiskStorageAccountTypesStandardLRS),
+		},
+		Properties: &armcompute.DiskProperties{
+			OSType:           to.Ptr(armcompute.OperatingSystemTypesLinux),
+			HyperVGeneration: to.Ptr(armcompute.HyperVGenerationV2),
+			CreationData: &armcompute.CreationData{
+				CreateOption:    to.Ptr(armcompute.DiskCreateOptionUpload),
+				UploadSizeBytes: to.Ptr(size),
+			},
+		},
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("creating disk %s for upload: %v", name, err)
+	}
+	if _, err := poller.PollUntilDone(ctx, nil); err != nil {
+		return fmt.Errorf("creating disk %s for upload: %v", name, err)
+	}
+	return nil
+}
+
+func diskState(disk armcompute.Disk) armcompute.DiskState {
+	if disk.Properties == nil || disk.Properties.DiskState == nil {
+		return ""
+	}
+	return *disk.Properties.DiskState
+}
+
 // DeleteDisk deletes a managed disk by name from the specified Azure resource group.
 func (a *API) DeleteDisk(name, resourceGroup string) error {
 	ctx := context.Background()
@@ -76,32 +225,27 @@ func (a *API) DeleteDisk(name, resourceGroup string) error {
 }
 
 // ParseDisk parses a disk specification string from a kola test and returns the
-// disk size and the Azure disk SKU. The spec format is "<size>:sku=<type>",
+// disk size in GiB and the Azure disk SKU. The spec format is "<size>:sku=<type>",
 // e.g., ["10G:sku=UltraSSD_LRS"] for NVMe disks. If no SKU is specified, "Standard_LRS" is used.
 func (a *API) ParseDisk(spec string) (int64, armcompute.DiskStorageAccountTypes, error) {
 	sku := armcompute.DiskStorageAccountTypes(armcompute.DiskStorageAccountTypesStandardLRS)
-	size, diskmap, err := util.ParseDiskSpec(spec, false)
+	d, err := util.ParseDiskSpec(spec, false, util.DiskKeys{"sku": true})
 	if err != nil {
-		return size, sku, fmt.Errorf("failed to parse disk spec %q: %w", spec, err)
-	}
-	for key, value := range diskmap {
-		switch key {
-		case "sku":
-			normalizedSku := strings.ToUpper(value)
-			foundSku := false
-			for _, validSku := range armcompute.PossibleDiskStorageAccountTypesValues() {
-				if strings.EqualFold(normalizedSku, string(validSku)) {
-					sku = validSku
-					foundSku = true
-					break
-				}
-			}
-			if !foundSku {
-				return size, sku, fmt.Errorf("unsupported disk sku: %s", value)
+		return 0, sku, fmt.Errorf("failed to parse disk spec %q: %w", spec, err)
+	}
+	if value, ok := d.Options["sku"]; ok {
+		foundSku := false
+		for _, validSku := range armcompute.PossibleDiskStorageAccountTypesValues() {
+			if strings.EqualFold(value, string(validSku)) {
+				sku = validSku
+				foundSku = true
+				break
 			}
-		default:
-			return size, sku, fmt.Errorf("invalid key: %s", key)
+		}
+		if !foundSku {
+			return 0, sku, fmt.Errorf("failed to parse disk spec %q: %w", spec,
+				d.OptionError("sku", "unsupported disk sku %q", value))
 		}
 	}
-	return size, sku, nil
+	return d.GiB(), sku, nil
 }
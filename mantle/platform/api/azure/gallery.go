@@ -19,6 +19,7 @@ import (
 	"context"
 	"fmt"
 	"runtime"
+	"strings"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
@@ -27,7 +28,53 @@ import (
 	"github.com/coreos/coreos-assembler/mantle/util"
 )
 
-func (a *API) CreateGalleryImage(name, galleryName, resourceGroup, sourceImageID, architecture string) (armcompute.GalleryImageVersion, error) {
+// ParseSecurityType parses a VM security type, TrustedLaunch or
+// ConfidentialVM, ignoring case. The empty string is the standard security
+// type.
+func ParseSecurityType(s string) (armcompute.SecurityTypes, error) {
+	if s == "" {
+		return "", nil
+	}
+	for _, t := range armcompute.PossibleSecurityTypesValues() {
+		if strings.EqualFold(s, string(t)) {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("unknown security type %q; expected TrustedLaunch or ConfidentialVM", s)
+}
+
+// ParseEphemeralOSDisk parses where an ephemeral OS disk is placed,
+// CacheDisk or ResourceDisk, ignoring case. The empty string is a managed
+// OS disk.
+func ParseEphemeralOSDisk(s string) (armcompute.DiffDiskPlacement, error) {
+	if s == "" {
+		return "", nil
+	}
+	for _, p := range armcompute.PossibleDiffDiskPlacementValues() {
+		if strings.EqualFold(s, string(p)) {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("unknown ephemeral OS disk placement %q; expected CacheDisk or ResourceDisk", s)
+}
+
+// CreateGalleryImage creates a Gen2 image definition and version in the
+// gallery from the source image. If securityType is set, the definition
+// requires it of the VMs booted from it.
+func (a *API) CreateGalleryImage(name, galleryName, resourceGroup, sourceImageID, architecture string, securityType armcompute.SecurityTypes) (armcompute.GalleryImageVersion, error) {
+	if err := a.CreateGalleryImageDefinition(name, galleryName, resourceGroup, architecture, securityType); err != nil {
+		return armcompute.GalleryImageVersion{}, err
+	}
+	return a.CreateGalleryImageVersion(name, galleryName, resourceGroup, "1.0.0", GalleryImageVersionOptions{
+		SourceImageID: sourceImageID,
+	})
+}
+
+// CreateGalleryImageDefinition creates the gallery, unless it exists, and a
+// Gen2 image definition in it, to which versions are added with
+// CreateGalleryImageVersion. If securityType is set, the definition
+// requires it of the VMs booted from it.
+func (a *API) CreateGalleryImageDefinition(name, galleryName, resourceGroup, architecture string, securityType armcompute.SecurityTypes) error {
 	ctx := context.Background()
 
 	// Ensure the Azure Shared Image Gallery exists. BeginCreateOrUpdate will create the gallery
@@ -39,11 +86,11 @@ func (a *API) CreateGalleryImage(name, galleryName, resourceGroup, sourceImageID
 		Location: &a.opts.Location,
 	}, nil)
 	if err != nil {
-		return armcompute.GalleryImageVersion{}, err
+		return err
 	}
 	_, err = galleryPoller.PollUntilDone(context.Background(), nil)
 	if err != nil {
-		return armcompute.GalleryImageVersion{}, err
+		return err
 	}
 
 	// enable NVMe support for Gen2 images only. NVMe support is not available on Gen1 images.
@@ -54,6 +101,12 @@ func (a *API) CreateGalleryImage(name, galleryName, resourceGroup, sourceImageID
 			Value: to.Ptr("SCSI,NVMe"),
 		},
 	}
+	if securityType != "" {
+		galleryImageFeatures = append(galleryImageFeatures, &armcompute.GalleryImageFeature{
+			Name:  to.Ptr("SecurityType"),
+			Value: to.Ptr(string(securityType)),
+		})
+	}
 
 	var azureArch armcompute.Architecture
 	if architecture == "" {
@@ -65,12 +118,13 @@ func (a *API) CreateGalleryImage(name, galleryName, resourceGroup, sourceImageID
 	case "arm64", "aarch64":
 		azureArch = armcompute.ArchitectureArm64
 	default:
-		return armcompute.GalleryImageVersion{}, fmt.Errorf("unsupported azure architecture %q", architecture)
+		return fmt.Errorf("unsupported azure architecture %q", architecture)
 	}
 
 	// Create a Gallery Image Definition with the specified Hyper-V generation (V1 or V2).
 	galleryImagePoller, err := a.galImgClient.BeginCreateOrUpdate(ctx, resourceGroup, galleryName, name, armcompute.GalleryImage{
 		Location: &a.opts.Location,
+		Tags:     a.resourceTags(nil),
 		Properties: &armcompute.GalleryImageProperties{
 			OSState:          to.Ptr(armcompute.OperatingSystemStateTypesGeneralized),
 			OSType:           to.Ptr(armcompute.OperatingSystemTypesLinux),
@@ -78,40 +132,151 @@ func (a *API) CreateGalleryImage(name, galleryName, resourceGroup, sourceImageID
 			Identifier: &armcompute.GalleryImageIdentifier{
 				Publisher: &a.opts.Publisher,
 				Offer:     to.Ptr(name),
-				SKU:       to.Ptr(util.RandomName("sku")),
+				SKU:       to.Ptr(util.ResourceName("sku")),
 			},
 			Features:     galleryImageFeatures,
 			Architecture: &azureArch,
 		},
 	}, nil)
 	if err != nil {
-		return armcompute.GalleryImageVersion{}, err
+		return err
 	}
 	_, err = galleryImagePoller.PollUntilDone(context.Background(), nil)
 	if err != nil {
-		return armcompute.GalleryImageVersion{}, err
+		return err
+	}
+
+	return nil
+}
+
+// GalleryImageVersionOptions are the source and publishing settings of a
+// gallery image version.
+type GalleryImageVersionOptions struct {
+	// SourceImageID is the ID of the managed image the version is created
+	// from. One of it, SourceDiskID and SourceBlobURL must be set.
+	SourceImageID string
+	// SourceDiskID is the ID of the managed disk the version is created
+	// from, such as one uploaded with UploadDisk.
+	SourceDiskID string
+	// SourceBlobURL is the URL of a VHD page blob the version is created
+	// from, in the storage account with the ID SourceStorageAccountID.
+	SourceBlobURL          string
+	SourceStorageAccountID string
+	// EndOfLife, if set, is when the version stops being supported.
+	EndOfLife time.Time
+	// ExcludeFromLatest keeps VMs which ask for the latest version of the
+	// image from using this one.
+	ExcludeFromLatest bool
+	// Targets are the regions the version is replicated to besides the
+	// one it's created in, and ReplicaCount and StorageAccountType the
+	// defaults for them if nonzero.
+	Targets            []ReplicationTarget
+	ReplicaCount       int32
+	StorageAccountType string
+}
+
+// StorageAccountID returns the resource ID of the storage account in the
+// resource group.
+func (a *API) StorageAccountID(resourceGroup, storageAccount string) string {
+	return fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Storage/storageAccounts/%s", a.opts.SubscriptionID, resourceGroup, storageAccount)
+}
+
+// CreateGalleryImageVersion creates a version of an existing gallery image
+// definition, and waits for Azure to finish creating it.
+func (a *API) CreateGalleryImageVersion(imageName, galleryName, resourceGroup, version string, opts GalleryImageVersionOptions) (armcompute.GalleryImageVersion, error) {
+	ctx := context.Background()
+
+	sources := 0
+	for _, source := range []string{opts.SourceImageID, opts.SourceDiskID, opts.SourceBlobURL} {
+		if source != "" {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return armcompute.GalleryImageVersion{}, fmt.Errorf("gallery image version can only have one source")
+	}
+	storage := &armcompute.GalleryImageVersionStorageProfile{}
+	switch {
+	case opts.SourceImageID != "":
+		storage.Source = &armcompute.GalleryArtifactVersionSource{
+			ID: to.Ptr(opts.SourceImageID),
+		}
+	case opts.SourceDiskID != "":
+		storage.OSDiskImage = &armcompute.GalleryOSDiskImage{
+			Source: &armcompute.GalleryArtifactVersionSource{
+				ID: to.Ptr(opts.SourceDiskID),
+			},
+		}
+	case opts.SourceBlobURL != "":
+		if opts.SourceStorageAccountID == "" {
+			return armcompute.GalleryImageVersion{}, fmt.Errorf("gallery image version from a blob needs its storage account")
+		}
+		storage.OSDiskImage = &armcompute.GalleryOSDiskImage{
+			Source: &armcompute.GalleryArtifactVersionSource{
+				ID:  to.Ptr(opts.SourceStorageAccountID),
+				URI: to.Ptr(opts.SourceBlobURL),
+			},
+		}
+	default:
+		return armcompute.GalleryImageVersion{}, fmt.Errorf("gallery image version needs a source image, disk or blob")
+	}
+
+	profile := &armcompute.GalleryImageVersionPublishingProfile{}
+	if !opts.EndOfLife.IsZero() {
+		profile.EndOfLifeDate = to.Ptr(opts.EndOfLife)
+	}
+	if opts.ExcludeFromLatest {
+		profile.ExcludeFromLatest = to.Ptr(true)
+	}
+	if opts.ReplicaCount > 0 {
+		profile.ReplicaCount = to.Ptr(opts.ReplicaCount)
+	}
+	if opts.StorageAccountType != "" {
+		storageType, err := ParseStorageAccountType(opts.StorageAccountType)
+		if err != nil {
+			return armcompute.GalleryImageVersion{}, err
+		}
+		profile.StorageAccountType = to.Ptr(storageType)
+	}
+	if len(opts.Targets) > 0 {
+		// The region the version is created in has to be a target too
+		regions := []*armcompute.TargetRegion{{Name: to.Ptr(a.opts.Location)}}
+		for _, t := range opts.Targets {
+			region := &armcompute.TargetRegion{Name: to.Ptr(t.Region)}
+			if t.ReplicaCount > 0 {
+				region.RegionalReplicaCount = to.Ptr(t.ReplicaCount)
+			}
+			if t.StorageAccountType != "" {
+				storageType, err := ParseStorageAccountType(t.StorageAccountType)
+				if err != nil {
+					return armcompute.GalleryImageVersion{}, err
+				}
+				region.StorageAccountType = to.Ptr(storageType)
+			}
+			if SameRegion(t.Region, a.opts.Location) {
+				regions[0] = region
+			} else {
+				regions = append(regions, region)
+			}
+		}
+		profile.TargetRegions = regions
 	}
 
-	// Create a Gallery Image Version
-	versionName := "1.0.0"
-	imageVersionPoller, err := a.galImgVerClient.BeginCreateOrUpdate(ctx, resourceGroup, galleryName, name, versionName, armcompute.GalleryImageVersion{
+	imageVersionPoller, err := a.galImgVerClient.BeginCreateOrUpdate(ctx, resourceGroup, galleryName, imageName, version, armcompute.GalleryImageVersion{
 		Location: &a.opts.Location,
+		Tags:     a.resourceTags(nil),
 		Properties: &armcompute.GalleryImageVersionProperties{
-			StorageProfile: &armcompute.GalleryImageVersionStorageProfile{
-				Source: &armcompute.GalleryArtifactVersionSource{
-					ID: to.Ptr(sourceImageID),
-				},
-			},
+			StorageProfile:    storage,
+			PublishingProfile: profile,
 		},
 	}, nil)
 	if err != nil {
 		return armcompute.GalleryImageVersion{}, err
 	}
-	imageVersionResponse, err := imageVersionPoller.PollUntilDone(context.Background(), nil)
+	imageVersionResponse, err := imageVersionPoller.PollUntilDone(ctx, nil)
 	if err != nil {
 		return armcompute.GalleryImageVersion{}, err
 	}
-
 	return imageVersionResponse.GalleryImageVersion, nil
 }
 
@@ -202,3 +367,184 @@ func (a *API) DeleteGallery(galleryName, resourceGroup string) error {
 	return err
 
 }
+
+// ReplicationTarget is a region to replicate a gallery image version to.
+type ReplicationTarget struct {
+	Region string
+	// ReplicaCount is the number of replicas in the region; if zero, the
+	// image version's default applies.
+	ReplicaCount int32
+	// StorageAccountType, such as Standard_LRS, is how the replicas in
+	// the region are stored; if empty, the image version's default
+	// applies.
+	StorageAccountType string
+}
+
+// ParseStorageAccountType checks that s is a storage account type gallery
+// image versions can be stored with.
+func ParseStorageAccountType(s string) (armcompute.StorageAccountType, error) {
+	for _, t := range armcompute.PossibleStorageAccountTypeValues() {
+		if string(t) == s {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported storage account type %q", s)
+}
+
+// ReplicateGalleryImageVersion replaces the regions the gallery image
+// version is replicated to with targets, and if replicaCount is nonzero,
+// sets the default number of replicas per region. The region the version
+// was created in can't be removed, so it's kept if targets don't include
+// it. Unless wait is set, this returns once Azure accepts the change
+// rather than when the replication is done.
+func (a *API) ReplicateGalleryImageVersion(imageName, galleryName, resourceGroup, version string, targets []ReplicationTarget, replicaCount int32, wait bool) (armcompute.GalleryImageVersion, error) {
+	ctx := context.Background()
+
+	current, err := a.galImgVerClient.Get(ctx, resourceGroup, galleryName, imageName, version, nil)
+	if err != nil {
+		return armcompute.GalleryImageVersion{}, err
+	}
+	if current.Properties == nil {
+		return armcompute.GalleryImageVersion{}, fmt.Errorf("gallery image version %s of %s has no properties", version, imageName)
+	}
+	profile := current.Properties.PublishingProfile
+	if profile == nil {
+		profile = &armcompute.GalleryImageVersionPublishingProfile{}
+	}
+	if replicaCount > 0 {
+		profile.ReplicaCount = to.Ptr(replicaCount)
+	}
+
+	var regions []*armcompute.TargetRegion
+	haveHome := false
+	for _, t := range targets {
+		region := &armcompute.TargetRegion{Name: to.Ptr(t.Region)}
+		if t.ReplicaCount > 0 {
+			region.RegionalReplicaCount = to.Ptr(t.ReplicaCount)
+		}
+		if t.StorageAccountType != "" {
+			storageType, err := ParseStorageAccountType(t.StorageAccountType)
+			if err != nil {
+				return armcompute.GalleryImageVersion{}, err
+			}
+			region.StorageAccountType = to.Ptr(storageType)
+		}
+		if current.Location != nil && SameRegion(t.Region, *current.Location) {
+			haveHome = true
+		}
+		regions = append(regions, region)
+	}
+	if !haveHome && current.Location != nil {
+		for _, r := range profile.TargetRegions {
+			if r.Name != nil && SameRegion(*r.Name, *current.Location) {
+				regions = append([]*armcompute.TargetRegion{r}, regions...)
+				haveHome = true
+				break
+			}
+		}
+		if !haveHome {
+			regions = append([]*armcompute.TargetRegion{{Name: current.Location}}, regions...)
+		}
+	}
+	profile.TargetRegions = regions
+	current.Properties.PublishingProfile = profile
+
+	poller, err := a.galImgVerClient.BeginUpdate(ctx, resourceGroup, galleryName, imageName, version, armcompute.GalleryImageVersionUpdate{
+		Properties: &armcompute.GalleryImageVersionProperties{
+			StorageProfile:    current.Properties.StorageProfile,
+			PublishingProfile: profile,
+		},
+	}, nil)
+	if err != nil {
+		return armcompute.GalleryImageVersion{}, err
+	}
+	if !wait {
+		return current.GalleryImageVersion, nil
+	}
+	if _, err := poller.PollUntilDone(ctx, nil); err != nil {
+		return armcompute.GalleryImageVersion{}, err
+	}
+	return a.waitForGalleryImageReplication(imageName, galleryName, resourceGroup, version)
+}
+
+// FindGalleryImageVersion returns the gallery image version, or nil if it
+// doesn't exist.
+func (a *API) FindGalleryImageVersion(imageName, galleryName, resourceGroup, version string) (*armcompute.GalleryImageVersion, error) {
+	resp, err := a.galImgVerClient.Get(context.Background(), resourceGroup, galleryName, imageName, version, nil)
+	if isNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &resp.GalleryImageVersion, nil
+}
+
+// GalleryImageReplicationStatus returns the gallery image version with its
+// replication status.
+func (a *API) GalleryImageReplicationStatus(imageName, galleryName, resourceGroup, version string) (armcompute.GalleryImageVersion, error) {
+	resp, err := a.galImgVerClient.Get(context.Background(), resourceGroup, galleryName, imageName, version, &armcompute.GalleryImageVersionsClientGetOptions{
+		Expand: to.Ptr(armcompute.ReplicationStatusTypesReplicationStatus),
+	})
+	if err != nil {
+		return armcompute.GalleryImageVersion{}, err
+	}
+	return resp.GalleryImageVersion, nil
+}
+
+// waitForGalleryImageReplication waits for the replication of the gallery
+// image version to every region to finish, logging its progress.
+func (a *API) waitForGalleryImageReplication(imageName, galleryName, resourceGroup, version string) (armcompute.GalleryImageVersion, error) {
+	var img armcompute.GalleryImageVersion
+	err := util.WaitUntilReady(6*time.Hour, 30*time.Second, func() (bool, error) {
+		var err error
+		img, err = a.GalleryImageReplicationStatus(imageName, galleryName, resourceGroup, version)
+		if err != nil {
+			return false, err
+		}
+		if img.Properties == nil || img.Properties.ReplicationStatus == nil || img.Properties.ReplicationStatus.AggregatedState == nil {
+			return false, nil
+		}
+		status := img.Properties.ReplicationStatus
+		switch *status.AggregatedState {
+		case armcompute.AggregatedReplicationStateCompleted:
+			return true, nil
+		case armcompute.AggregatedReplicationStateFailed:
+			return false, fmt.Errorf("replication of %s failed: %s", imageName, replicationFailures(status))
+		}
+		for _, r := range status.Summary {
+			if r.Region != nil && r.Progress != nil {
+				plog.Infof("Replicating %s to %s: %d%%", imageName, *r.Region, *r.Progress)
+			}
+		}
+		return false, nil
+	})
+	return img, err
+}
+
+// replicationFailures describes the regions replication failed in.
+func replicationFailures(status *armcompute.ReplicationStatus) string {
+	var failures []string
+	for _, r := range status.Summary {
+		if r.State == nil || *r.State != armcompute.ReplicationStateFailed {
+			continue
+		}
+		desc := "unknown region"
+		if r.Region != nil {
+			desc = *r.Region
+		}
+		if r.Details != nil {
+			desc += ": " + *r.Details
+		}
+		failures = append(failures, desc)
+	}
+	return strings.Join(failures, "; ")
+}
+
+// SameRegion compares region names, which Azure reports either as
+// display names, such as "West US 2", or as locations, such as "westus2".
+func SameRegion(a, b string) bool {
+	normalize := func(s string) string {
+		return strings.ToLower(strings.ReplaceAll(s, " ", ""))
+	}
+	return normalize(a) == normalize(b)
+}
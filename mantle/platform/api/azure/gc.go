@@ -0,0 +1,304 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+
+	"github.com/coreos/coreos-assembler/mantle/platform"
+)
+
+// GCLocations says where GC looks for what mantle created outside of the
+// kola-cluster resource groups, such as the images uploaded for a test
+// run.
+type GCLocations struct {
+	// ResourceGroup holds the disks, images and galleries to collect;
+	// if empty, only resource groups are collected.
+	ResourceGroup string
+	// StorageAccount, in ResourceGroup, and Container hold the blobs to
+	// collect; if either is empty, blobs are left alone.
+	StorageAccount string
+	Container      string
+}
+
+// gcResources deletes the disks, images, gallery image versions and blobs
+// which mantle tagged and opts selects as garbage. Those without a
+// createdAt tag predate it being set, and are left alone.
+func (a *API) gcResources(opts platform.GCOptions, locations GCLocations) error {
+	if locations.ResourceGroup == "" {
+		return nil
+	}
+	resp, err := a.rgClient.CheckExistence(context.Background(), locations.ResourceGroup, nil)
+	if err != nil {
+		return fmt.Errorf("checking for resource group %s: %v", locations.ResourceGroup, err)
+	}
+	if !resp.Success {
+		plog.Infof("Resource group %s doesn't exist; skipping it", locations.ResourceGroup)
+		return nil
+	}
+
+	if err := a.gcDisks(opts, locations.ResourceGroup); err != nil {
+		return fmt.Errorf("collecting disks: %v", err)
+	}
+	if err := a.gcImages(opts, locations.ResourceGroup); err != nil {
+		return fmt.Errorf("collecting images: %v", err)
+	}
+	if err := a.gcGalleryImages(opts, locations.ResourceGroup); err != nil {
+		return fmt.Errorf("collecting gallery images: %v", err)
+	}
+	if locations.StorageAccount != "" && locations.Container != "" {
+		if err := a.gcBlobs(opts, locations.ResourceGroup, locations.StorageAccount, locations.Container); err != nil {
+			return fmt.Errorf("collecting blobs: %v", err)
+		}
+	}
+	return nil
+}
+
+func (a *API) gcDisks(opts platform.GCOptions, resourceGroup string) error {
+	ctx := context.Background()
+	pager := a.diskClient.NewListByResourceGroupPager(resourceGroup, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, disk := range page.Value {
+			// Disks still attached to a machine go with it
+			if disk.ManagedBy != nil {
+				continue
+			}
+			created, ok := isGarbage(opts, disk.Tags)
+			if !ok {
+				continue
+			}
+			name := *disk.Name
+			err := opts.Collect("disk", name, created, func() error {
+				return a.DeleteDisk(name, resourceGroup)
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (a *API) gcImages(opts platform.GCOptions, resourceGroup string) error {
+	ctx := context.Background()
+	pager := a.imgClient.NewListByResourceGroupPager(resourceGroup, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, image := range page.Value {
+			created, ok := isGarbage(opts, image.Tags)
+			if !ok {
+				continue
+			}
+			name := *image.Name
+			err := opts.Collect("image", name, created, func() error {
+				return a.DeleteImage(name, resourceGroup)
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// gcGalleryImages deletes the image versions which are garbage in the galleries of
+// the resource group, and then the image definitions mantle created which
+// are left without any.
+func (a *API) gcGalleryImages(opts platform.GCOptions, resourceGroup string) error {
+	ctx := context.Background()
+	galleryPager := a.galClient.NewListByResourceGroupPager(resourceGroup, nil)
+	for galleryPager.More() {
+		galleryPage, err := galleryPager.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, gallery := range galleryPage.Value {
+			galleryName := *gallery.Name
+			imagePager := a.galImgClient.NewListByGalleryPager(resourceGroup, galleryName, nil)
+			for imagePager.More() {
+				imagePage, err := imagePager.NextPage(ctx)
+				if err != nil {
+					return err
+				}
+				for _, image := range imagePage.Value {
+					remaining, err := a.gcGalleryImageVersions(opts, resourceGroup, galleryName, *image.Name)
+					if err != nil {
+						return err
+					}
+					if remaining > 0 {
+						continue
+					}
+					created, ok := isGarbage(opts, image.Tags)
+					if !ok {
+						continue
+					}
+					name := *image.Name
+					err = opts.Collect("gallery image", galleryName+"/"+name, created, func() error {
+						poller, err := a.galImgClient.BeginDelete(ctx, resourceGroup, galleryName, name, nil)
+						if err != nil {
+							return err
+						}
+						_, err = poller.PollUntilDone(ctx, nil)
+						return err
+					})
+					if err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// gcGalleryImageVersions deletes the versions of the gallery image which
+// are garbage and returns how many are left, not counting those a dry run
+// would have deleted.
+func (a *API) gcGalleryImageVersions(opts platform.GCOptions, resourceGroup, galleryName, imageName string) (int, error) {
+	ctx := context.Background()
+	remaining := 0
+	pager := a.galImgVerClient.NewListByGalleryImagePager(resourceGroup, galleryName, imageName, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return 0, err
+		}
+		for _, version := range page.Value {
+			created, ok := isGarbage(opts, version.Tags)
+			if !ok {
+				remaining++
+				continue
+			}
+			name := *version.Name
+			err := opts.Collect("gallery image version", galleryName+"/"+imageName+"/"+name, created, func() error {
+				poller, err := a.galImgVerClient.BeginDelete(ctx, resourceGroup, galleryName, imageName, name, nil)
+				if err != nil {
+					return err
+				}
+				_, err = poller.PollUntilDone(ctx, nil)
+				return err
+			})
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+	return remaining, nil
+}
+
+func (a *API) gcBlobs(opts platform.GCOptions, resourceGroup, storageAccount, container string) error {
+	keys, err := a.GetStorageServiceKeys(storageAccount, resourceGroup)
+	if isNotFound(err) {
+		plog.Infof("Storage account %s doesn't exist; skipping it", storageAccount)
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if len(keys.Keys) == 0 {
+		return fmt.Errorf("no keys found for storage account %s", storageAccount)
+	}
+	client, err := getBlockBlobClient(storageAccount, *keys.Keys[0].Value)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	pager := client.NewListBlobsFlatPager(container, &azblob.ListBlobsFlatOptions{
+		Include: azblob.ListBlobsInclude{Metadata: true},
+	})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if bloberror.HasCode(err, bloberror.ContainerNotFound) {
+			plog.Infof("Container %s doesn't exist; skipping it", container)
+			return nil
+		} else if err != nil {
+			return err
+		}
+		for _, blob := range page.Segment.BlobItems {
+			created, ok := isGarbage(opts, blob.Metadata)
+			if !ok {
+				continue
+			}
+			name := *blob.Name
+			err := opts.Collect("blob", container+"/"+name, created, func() error {
+				_, err := client.DeleteBlob(ctx, container, name, nil)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// isGarbage reports whether the tags, or blob metadata, mark a resource
+// as created by mantle and opts selects it as garbage, and when it was
+// created.
+func isGarbage(opts platform.GCOptions, tags map[string]*string) (time.Time, bool) {
+	createdBy, createdAt := tag(tags, "createdBy"), tag(tags, "createdAt")
+	if createdBy != "mantle" || createdAt == "" {
+		return time.Time{}, false
+	}
+	created, err := time.Parse(time.RFC3339, createdAt)
+	if err != nil {
+		plog.Warningf("Ignoring resource with unparseable createdAt %q: %v", createdAt, err)
+		return time.Time{}, false
+	}
+	return created, opts.IsGarbage(created, stringTags(tags))
+}
+
+// stringTags returns the tags with their values dereferenced.
+func stringTags(tags map[string]*string) map[string]string {
+	m := make(map[string]string, len(tags))
+	for k, v := range tags {
+		if v != nil {
+			m[k] = *v
+		}
+	}
+	return m
+}
+
+// tag returns the value of the tag, or "" if it's unset. Blob metadata
+// names are case-insensitive, so tags are matched regardless of case.
+func tag(tags map[string]*string, name string) string {
+	for k, v := range tags {
+		if strings.EqualFold(k, name) && v != nil {
+			return *v
+		}
+	}
+	return ""
+}
+
+func isNotFound(err error) bool {
+	var respErr *azcore.ResponseError
+	return errors.As(err, &respErr) && respErr.StatusCode == http.StatusNotFound
+}
@@ -22,19 +22,38 @@ import (
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
 
+	"github.com/coreos/coreos-assembler/mantle/platform"
 	"github.com/coreos/coreos-assembler/mantle/util"
 )
 
-func (a *API) CreateResourceGroup(prefix string) (string, error) {
-	name := util.RandomName(prefix)
-	tags := map[string]*string{
+// mantleTags are set on the resources mantle creates, so GC can tell
+// which are its own and how old they are.
+func mantleTags() map[string]*string {
+	return map[string]*string{
 		"createdAt": to.Ptr(time.Now().Format(time.RFC3339)),
 		"createdBy": to.Ptr("mantle"),
 	}
+}
+
+// resourceTags returns mantleTags with tags added, or if tags is nil, the
+// tags platform.ResourceTags gives for the options.
+func (a *API) resourceTags(tags map[string]string) map[string]*string {
+	if tags == nil {
+		tags = platform.ResourceTags(a.opts.Options, "")
+	}
+	result := mantleTags()
+	for k, v := range tags {
+		result[k] = to.Ptr(v)
+	}
+	return result
+}
+
+func (a *API) CreateResourceGroup(prefix string, tags map[string]string) (string, error) {
+	name := util.ResourceName(prefix)
 
 	_, err := a.rgClient.CreateOrUpdate(context.Background(), name, armresources.ResourceGroup{
 		Location: to.Ptr(a.opts.Location),
-		Tags:     tags,
+		Tags:     a.resourceTags(tags),
 	}, nil)
 	if err != nil {
 		return "", err
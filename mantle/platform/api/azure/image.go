@@ -23,18 +23,31 @@ import (
 )
 
 func (a *API) CreateImage(name, resourceGroup, blobURI string) (armcompute.Image, error) {
+	return a.createImage(name, resourceGroup, &armcompute.ImageOSDisk{
+		BlobURI: &blobURI,
+	})
+}
+
+// CreateImageFromDisk creates an image from the managed disk with the ID
+// diskID, such as one uploaded with UploadDisk.
+func (a *API) CreateImageFromDisk(name, resourceGroup, diskID string) (armcompute.Image, error) {
+	return a.createImage(name, resourceGroup, &armcompute.ImageOSDisk{
+		ManagedDisk: &armcompute.SubResource{ID: &diskID},
+	})
+}
+
+func (a *API) createImage(name, resourceGroup string, osDisk *armcompute.ImageOSDisk) (armcompute.Image, error) {
 	ctx := context.Background()
+	osDisk.OSType = to.Ptr(armcompute.OperatingSystemTypesLinux)
+	osDisk.OSState = to.Ptr(armcompute.OperatingSystemStateTypesGeneralized)
 	poller, err := a.imgClient.BeginCreateOrUpdate(ctx, resourceGroup, name, armcompute.Image{
 		Name:     &name,
 		Location: &a.opts.Location,
+		Tags:     a.resourceTags(nil),
 		Properties: &armcompute.ImageProperties{
 			HyperVGeneration: to.Ptr(armcompute.HyperVGenerationTypes(armcompute.HyperVGenerationTypesV2)),
 			StorageProfile: &armcompute.ImageStorageProfile{
-				OSDisk: &armcompute.ImageOSDisk{
-					OSType:  to.Ptr(armcompute.OperatingSystemTypesLinux),
-					OSState: to.Ptr(armcompute.OperatingSystemStateTypesGeneralized),
-					BlobURI: &blobURI,
-				},
+				OSDisk: osDisk,
 			},
 		},
 	}, nil)
@@ -23,7 +23,8 @@ import (
 	"io"
 	"math"
 	"math/big"
-	"regexp"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
@@ -50,7 +51,7 @@ func (a *API) getInstance(name, resourceGroup string) (armcompute.VirtualMachine
 	return resp.VirtualMachine, nil
 }
 
-func (a *API) getVMParameters(name, userdata, sshkey, storageAccountURI, size string, ip armnetwork.PublicIPAddress, nic armnetwork.Interface) armcompute.VirtualMachine {
+func (a *API) getVMParameters(name, userdata, sshkey, storageAccountURI, size string, securityType armcompute.SecurityTypes, ephemeral armcompute.DiffDiskPlacement, ip armnetwork.PublicIPAddress, nic armnetwork.Interface, tags map[string]string) armcompute.VirtualMachine {
 
 	// Azure requires that either a username/password be set or an SSH key.
 	//
@@ -110,22 +111,49 @@ func (a *API) getVMParameters(name, userdata, sshkey, storageAccountURI, size st
 	additionalCapabilities := &armcompute.AdditionalCapabilities{
 		UltraSSDEnabled: to.Ptr(true),
 	}
+	osDisk := &armcompute.OSDisk{
+		CreateOption: to.Ptr(armcompute.DiskCreateOptionTypesFromImage),
+	}
+	if ephemeral != "" {
+		// Ephemeral OS disks need read-only caching
+		osDisk.Caching = to.Ptr(armcompute.CachingTypesReadOnly)
+		osDisk.DiffDiskSettings = &armcompute.DiffDiskSettings{
+			Option:    to.Ptr(armcompute.DiffDiskOptionsLocal),
+			Placement: to.Ptr(ephemeral),
+		}
+	}
+	var securityProfile *armcompute.SecurityProfile
+	if securityType != "" {
+		securityProfile = &armcompute.SecurityProfile{
+			SecurityType: to.Ptr(securityType),
+			UefiSettings: &armcompute.UefiSettings{
+				SecureBootEnabled: to.Ptr(true),
+				VTpmEnabled:       to.Ptr(true),
+			},
+		}
+	}
+	if securityType == armcompute.SecurityTypesConfidentialVM {
+		// Confidential VMs encrypt their guest state, and don't
+		// support ultra disks
+		osDisk.ManagedDisk = &armcompute.ManagedDiskParameters{
+			SecurityProfile: &armcompute.VMDiskSecurityProfile{
+				SecurityEncryptionType: to.Ptr(armcompute.SecurityEncryptionTypesVMGuestStateOnly),
+			},
+		}
+		additionalCapabilities = nil
+	}
 	return armcompute.VirtualMachine{
 		Name:     &name,
 		Location: &a.opts.Location,
 		Zones:    []*string{&a.opts.AvailabilityZone},
-		Tags: map[string]*string{
-			"createdBy": to.Ptr("mantle"),
-		},
+		Tags:     a.resourceTags(tags),
 		Properties: &armcompute.VirtualMachineProperties{
 			HardwareProfile: &armcompute.HardwareProfile{
 				VMSize: to.Ptr(armcompute.VirtualMachineSizeTypes(size)),
 			},
 			StorageProfile: &armcompute.StorageProfile{
 				ImageReference: imgRef,
-				OSDisk: &armcompute.OSDisk{
-					CreateOption: to.Ptr(armcompute.DiskCreateOptionTypesFromImage),
-				},
+				OSDisk:         osDisk,
 			},
 			OSProfile: &osProfile,
 			NetworkProfile: &armcompute.NetworkProfile{
@@ -145,11 +173,21 @@ func (a *API) getVMParameters(name, userdata, sshkey, storageAccountURI, size st
 				},
 			},
 			AdditionalCapabilities: additionalCapabilities,
+			SecurityProfile:        securityProfile,
 		},
 	}
 }
 
-func (a *API) CreateInstance(name, userdata, sshkey, resourceGroup, storageAccount string, opts platform.MachineOptions) (*Machine, error) {
+func (a *API) CreateInstance(name, userdata, sshkey, resourceGroup, storageAccount string, opts platform.MachineOptions, tags map[string]string) (*Machine, error) {
+	securityType, err := ParseSecurityType(a.opts.SecurityType)
+	if err != nil {
+		return nil, err
+	}
+	ephemeral, err := ParseEphemeralOSDisk(a.opts.EphemeralOSDisk)
+	if err != nil {
+		return nil, err
+	}
+
 	subnet, err := a.getSubnet(resourceGroup)
 	if err != nil {
 		return nil, fmt.Errorf("preparing network resources: %v", err)
@@ -185,7 +223,7 @@ func (a *API) CreateInstance(name, userdata, sshkey, resourceGroup, storageAccou
 		size = a.opts.Size
 	}
 
-	vmParams := a.getVMParameters(name, userdata, sshkey, fmt.Sprintf("https://%s.blob.core.windows.net/", storageAccount), size, ip, nic)
+	vmParams := a.getVMParameters(name, userdata, sshkey, fmt.Sprintf("https://%s.blob.core.windows.net/", storageAccount), size, securityType, ephemeral, ip, nic, tags)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
 	defer cancel()
@@ -232,7 +270,7 @@ func (a *API) CreateInstance(name, userdata, sshkey, resourceGroup, storageAccou
 		if err != nil {
 			return nil, fmt.Errorf("error parsing additional disk: %v", err)
 		}
-		diskName := util.RandomName(fmt.Sprintf("disk-%d", i))
+		diskName := util.ResourceName(fmt.Sprintf("disk-%d", i))
 		err = a.CreateAndAttachDiskToInstance(*vm.Name, resourceGroup, diskName, sku, int32(size), int32(i))
 		if err != nil {
 			return nil, fmt.Errorf("failed to attach disk to vm: %v", err)
@@ -286,16 +324,24 @@ func (a *API) GetConsoleOutput(name, resourceGroup, storageAccount string) ([]by
 	}
 
 	// Only the full URI to the logs are present in the virtual machine
-	// properties. Parse out the container & file name to use the GetBlockBlob
-	// API call directly.
-	uri := []byte(*consoleURI)
-	containerPat := regexp.MustCompile(`bootdiagnostics-kola[a-z0-9\-]+`)
-	container := string(containerPat.Find(uri))
-	namePat := regexp.MustCompile(`kola-[a-z0-9\-\.]+.serialconsole.log`)
-	blobname := string(namePat.Find(uri))
+	// properties. Its path is the container and file name, which are
+	// used to call the GetBlockBlob API directly.
+	uri, err := url.Parse(*consoleURI)
+	if err != nil {
+		return nil, fmt.Errorf("parsing serial console URI: %v", err)
+	}
+	container, blobname, ok := strings.Cut(strings.TrimPrefix(uri.Path, "/"), "/")
+	if !ok || container == "" || blobname == "" {
+		return nil, fmt.Errorf("unexpected serial console URI %q", *consoleURI)
+	}
 
 	var data io.ReadCloser
-	err = util.Retry(6, 10*time.Second, func() error {
+	err = util.Backoff{
+		Delay:      5 * time.Second,
+		MaxDelay:   20 * time.Second,
+		Jitter:     0.2,
+		MaxElapsed: time.Minute,
+	}.Do(context.Background(), func() error {
 		data, err = a.GetBlockBlob(storageAccount, *key, container, blobname)
 		return err
 	})
@@ -81,7 +81,7 @@ func (a *API) getSubnet(resourceGroup string) (armnetwork.Subnet, error) {
 }
 
 func (a *API) createPublicIP(resourceGroup string) (armnetwork.PublicIPAddress, error) {
-	name := util.RandomName("ip")
+	name := util.ResourceName("ip")
 	ctx := context.Background()
 
 	var ipSKU *armnetwork.PublicIPAddressSKU
@@ -162,8 +162,8 @@ func (a *API) GetPrivateIP(interfaceName, resourceGroup string) (string, error)
 }
 
 func (a *API) createNIC(ip armnetwork.PublicIPAddress, subnet *armnetwork.Subnet, nsg *armnetwork.SecurityGroup, resourceGroup string) (armnetwork.Interface, error) {
-	name := util.RandomName("nic")
-	ipconf := util.RandomName("nic-ipconf")
+	name := util.ResourceName("nic")
+	ipconf := util.ResourceName("nic-ipconf")
 	ctx := context.Background()
 
 	poller, err := a.intClient.BeginCreateOrUpdate(ctx, resourceGroup, name, armnetwork.Interface{
@@ -180,7 +180,7 @@ func (a *API) createNIC(ip armnetwork.PublicIPAddress, subnet *armnetwork.Subnet
 					},
 				},
 			},
-			EnableAcceleratedNetworking: to.Ptr(true),
+			EnableAcceleratedNetworking: to.Ptr(a.opts.AcceleratedNetworking),
 		},
 	}, nil)
 	if err != nil {
@@ -197,7 +197,7 @@ func (a *API) createNIC(ip armnetwork.PublicIPAddress, subnet *armnetwork.Subnet
 }
 
 func (a *API) CreateNSG(resourceGroup string) (armnetwork.SecurityGroup, error) {
-	name := util.RandomName("nsg")
+	name := util.ResourceName("nsg")
 	ctx := context.Background()
 
 	sshRule := &armnetwork.SecurityRule{
@@ -33,6 +33,18 @@ type Options struct {
 	Size             string
 	Location         string
 	AvailabilityZone string
+	// SecurityType, if set, boots machines as TrustedLaunch or
+	// ConfidentialVM, with secure boot and a vTPM. The image and size
+	// must support it.
+	SecurityType string
+	// AcceleratedNetworking gives the machines' NICs SR-IOV virtual
+	// functions, which the guest bonds with their synthetic hv_netvsc
+	// interfaces. The size must support it.
+	AcceleratedNetworking bool
+	// EphemeralOSDisk, if set, places the machines' OS disks on their
+	// local CacheDisk or ResourceDisk rather than on managed disks. The
+	// size's disk must be large enough for the image.
+	EphemeralOSDisk string
 
 	SubscriptionName string
 	SubscriptionID   string
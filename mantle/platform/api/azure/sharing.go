@@ -0,0 +1,191 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute"
+)
+
+// The SDK predates community galleries, so lacks a constant for their
+// sharing permission.
+const gallerySharingPermissionCommunity armcompute.GallerySharingPermissionTypes = "Community"
+
+// CommunityGallery is what's published about a gallery shared with the
+// community.
+type CommunityGallery struct {
+	PublisherURI     string
+	PublisherContact string
+	Eula             string
+	// PublicNamePrefix starts the gallery's public name, which Azure
+	// makes unique by appending a suffix.
+	PublicNamePrefix string
+}
+
+// GallerySharing describes who a gallery is shared with.
+type GallerySharing struct {
+	// Permissions is Private, Groups or Community.
+	Permissions   string
+	Subscriptions []string `json:",omitempty"`
+	Tenants       []string `json:",omitempty"`
+	// PublicNames are the unique names of a community gallery, by which
+	// its images are referred to as
+	// /CommunityGalleries/<name>/Images/<image>/Versions/<version>.
+	PublicNames []string `json:",omitempty"`
+}
+
+// ShareGalleryWithCommunity makes the gallery public as a community
+// gallery; GetGallerySharing then returns its public names.
+func (a *API) ShareGalleryWithCommunity(galleryName, resourceGroup string, info CommunityGallery) error {
+	err := a.setGallerySharingPermissions(galleryName, resourceGroup, &armcompute.SharingProfile{
+		Permissions: to.Ptr(gallerySharingPermissionCommunity),
+		CommunityGalleryInfo: &armcompute.CommunityGalleryInfo{
+			PublisherURI:     to.Ptr(info.PublisherURI),
+			PublisherContact: to.Ptr(info.PublisherContact),
+			Eula:             to.Ptr(info.Eula),
+			PublicNamePrefix: to.Ptr(info.PublicNamePrefix),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return a.updateGallerySharing(galleryName, resourceGroup, armcompute.SharingUpdateOperationTypesEnableCommunity, nil)
+}
+
+// ShareGallery shares the gallery directly with the subscriptions and
+// tenants, in addition to any it's already shared with.
+func (a *API) ShareGallery(galleryName, resourceGroup string, subscriptions, tenants []string) error {
+	current, err := a.GetGallerySharing(galleryName, resourceGroup)
+	if err != nil {
+		return err
+	}
+	if current.Permissions != string(armcompute.GallerySharingPermissionTypesGroups) {
+		err := a.setGallerySharingPermissions(galleryName, resourceGroup, &armcompute.SharingProfile{
+			Permissions: to.Ptr(armcompute.GallerySharingPermissionTypesGroups),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return a.updateGallerySharing(galleryName, resourceGroup, armcompute.SharingUpdateOperationTypesAdd, sharingGroups(subscriptions, tenants))
+}
+
+// UnshareGallery stops sharing the gallery with the subscriptions and
+// tenants.
+func (a *API) UnshareGallery(galleryName, resourceGroup string, subscriptions, tenants []string) error {
+	return a.updateGallerySharing(galleryName, resourceGroup, armcompute.SharingUpdateOperationTypesRemove, sharingGroups(subscriptions, tenants))
+}
+
+// ResetGallerySharing makes the gallery private again.
+func (a *API) ResetGallerySharing(galleryName, resourceGroup string) error {
+	return a.updateGallerySharing(galleryName, resourceGroup, armcompute.SharingUpdateOperationTypesReset, nil)
+}
+
+// GetGallerySharing returns who the gallery is shared with.
+func (a *API) GetGallerySharing(galleryName, resourceGroup string) (GallerySharing, error) {
+	resp, err := a.galClient.Get(context.Background(), resourceGroup, galleryName, &armcompute.GalleriesClientGetOptions{
+		Select: to.Ptr(armcompute.SelectPermissionsPermissions),
+	})
+	if err != nil {
+		return GallerySharing{}, err
+	}
+
+	sharing := GallerySharing{Permissions: string(armcompute.GallerySharingPermissionTypesPrivate)}
+	if resp.Properties == nil || resp.Properties.SharingProfile == nil {
+		return sharing, nil
+	}
+	profile := resp.Properties.SharingProfile
+	if profile.Permissions != nil {
+		sharing.Permissions = string(*profile.Permissions)
+	}
+	for _, g := range profile.Groups {
+		if g.Type == nil {
+			continue
+		}
+		for _, id := range g.IDs {
+			switch *g.Type {
+			case armcompute.SharingProfileGroupTypesSubscriptions:
+				sharing.Subscriptions = append(sharing.Subscriptions, *id)
+			case armcompute.SharingProfileGroupTypesAADTenants:
+				sharing.Tenants = append(sharing.Tenants, *id)
+			}
+		}
+	}
+	if profile.CommunityGalleryInfo != nil {
+		// It's untyped in this version of the SDK, so decode it again
+		var info armcompute.CommunityGalleryInfo
+		buf, err := json.Marshal(profile.CommunityGalleryInfo)
+		if err != nil {
+			return GallerySharing{}, err
+		}
+		if err := json.Unmarshal(buf, &info); err != nil {
+			return GallerySharing{}, fmt.Errorf("parsing community gallery info: %v", err)
+		}
+		for _, name := range info.PublicNames {
+			sharing.PublicNames = append(sharing.PublicNames, *name)
+		}
+	}
+	return sharing, nil
+}
+
+// setGallerySharingPermissions sets the sharing profile of the gallery,
+// which must be done before it can be shared.
+func (a *API) setGallerySharingPermissions(galleryName, resourceGroup string, profile *armcompute.SharingProfile) error {
+	ctx := context.Background()
+	poller, err := a.galClient.BeginUpdate(ctx, resourceGroup, galleryName, armcompute.GalleryUpdate{
+		Properties: &armcompute.GalleryProperties{
+			SharingProfile: profile,
+		},
+	}, nil)
+	if err != nil {
+		return err
+	}
+	_, err = poller.PollUntilDone(ctx, nil)
+	return err
+}
+
+func (a *API) updateGallerySharing(galleryName, resourceGroup string, op armcompute.SharingUpdateOperationTypes, groups []*armcompute.SharingProfileGroup) error {
+	ctx := context.Background()
+	poller, err := a.galShareClient.BeginUpdate(ctx, resourceGroup, galleryName, armcompute.SharingUpdate{
+		OperationType: to.Ptr(op),
+		Groups:        groups,
+	}, nil)
+	if err != nil {
+		return err
+	}
+	_, err = poller.PollUntilDone(ctx, nil)
+	return err
+}
+
+func sharingGroups(subscriptions, tenants []string) []*armcompute.SharingProfileGroup {
+	var groups []*armcompute.SharingProfileGroup
+	if len(subscriptions) > 0 {
+		groups = append(groups, &armcompute.SharingProfileGroup{
+			Type: to.Ptr(armcompute.SharingProfileGroupTypesSubscriptions),
+			IDs:  to.SliceOfPtrs(subscriptions...),
+		})
+	}
+	if len(tenants) > 0 {
+		groups = append(groups, &armcompute.SharingProfileGroup{
+			Type: to.Ptr(armcompute.SharingProfileGroupTypesAADTenants),
+			IDs:  to.SliceOfPtrs(tenants...),
+		})
+	}
+	return groups
+}
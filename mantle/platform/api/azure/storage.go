@@ -16,11 +16,15 @@
 package azure
 
 import (
+	"bytes"
 	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"os"
-	"strings"
+	"sync"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/streaming"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
@@ -44,8 +48,8 @@ func (a *API) GetStorageServiceKeys(account, resourceGroup string) (armstorage.A
 }
 
 func (a *API) CreateStorageAccount(resourceGroup string) (string, error) {
-	// Only lower-case letters & numbers allowed in storage account names
-	name := strings.Replace(util.RandomName("kolasa"), "-", "", -1)
+	// Only up to 24 lower-case letters & numbers allowed in storage account names
+	name := util.CompactResourceName("kolasa")
 	parameters := armstorage.AccountCreateParameters{
 		SKU: &armstorage.SKU{
 			Name: to.Ptr(armstorage.SKUNameStandardLRS),
@@ -93,7 +97,29 @@ func (a *API) PageBlobExists(storageaccount, key, container, blobname string) (b
 	return true, nil
 }
 
-func (a *API) UploadPageBlob(storageaccount, key, file, container, blobname string) error {
+// ErrBlobExists is returned by UploadPageBlob if the blob exists and isn't
+// an interrupted upload of the same file.
+var ErrBlobExists = errors.New("blob exists")
+
+// uploadMD5Key is the metadata recording the MD5 of the file being
+// uploaded to a page blob, which identifies an interrupted upload to
+// resume. The blob's Content-MD5 is only set once the upload completes.
+const uploadMD5Key = "uploadmd5"
+
+// UploadOptions control how UploadPageBlob and UploadDisk upload.
+type UploadOptions struct {
+	// Parallelism is how many chunks are uploaded at once.
+	Parallelism int
+	// Overwrite replaces an existing blob rather than returning
+	// ErrBlobExists.
+	Overwrite bool
+}
+
+// UploadPageBlob uploads the data in file to a page blob, several chunks at
+// a time, with the MD5 of each verified by the service. If the blob is an
+// interrupted upload of the same file, only the chunks it lacks are
+// uploaded.
+func (a *API) UploadPageBlob(storageaccount, key, file, container, blobname string, opts UploadOptions) error {
 	client, err := getPageBlobClient(storageaccount, key, container, blobname)
 	if err != nil {
 		return err
@@ -110,18 +136,56 @@ func (a *API) UploadPageBlob(storageaccount, key, file, container, blobname stri
 	}
 	size := fi.Size()
 
-	// Create the page blob
+	sum, err := fileMD5(f)
+	if err != nil {
+		return err
+	}
+	sumHex := hex.EncodeToString(sum)
+
+	// Create the page blob, unless we're resuming an upload to it
 	ctx := context.Background()
-	_, err = client.Create(ctx, size, nil)
+	var written []blob.HTTPRange
+	props, err := client.GetProperties(ctx, nil)
+	switch {
+	case bloberror.HasCode(err, bloberror.BlobNotFound):
+		err = createPageBlob(ctx, client, size, sumHex)
+	case err != nil:
+		return err
+	case tag(props.Metadata, uploadMD5Key) == sumHex && props.ContentLength != nil && *props.ContentLength == size:
+		if bytes.Equal(props.ContentMD5, sum) {
+			plog.Infof("Blob %s is already uploaded", blobname)
+			return nil
+		}
+		plog.Infof("Resuming the upload of %s", blobname)
+		written, err = writtenPages(ctx, client)
+	case opts.Overwrite:
+		err = createPageBlob(ctx, client, size, sumHex)
+	default:
+		return ErrBlobExists
+	}
 	if err != nil {
 		return err
 	}
 
+	if err := uploadFile(ctx, client, f, size, written, opts.Parallelism); err != nil {
+		return fmt.Errorf("uploading pages (rerun to resume): %v", err)
+	}
+
+	// Mark the upload complete
+	_, err = client.SetHTTPHeaders(ctx, blob.HTTPHeaders{BlobContentMD5: sum}, nil)
+	return err
+}
+
+// uploadFile uploads the data (non-zero) ranges of f to the page blob,
+// except those already written, parallelism chunks at a time, showing
+// progress.
+func uploadFile(ctx context.Context, client *pageblob.Client, f *os.File, size int64, written []blob.HTTPRange, parallelism int) error {
 	// Find the data (non-zero) ranges in the file and then chunk up
 	// those data ranges so they are in 4MiB segments which is the
 	// maxiumum that can be uploaded in one call to UploadPages().
+	// Chunks already written by an interrupted upload are skipped.
 	dataRanges := fibmap.NewFibmapFile(f).SeekDataHole()
-	var chunkedDataRanges []int64
+	var chunks []blob.HTTPRange
 	dataSize, fourMB := int64(0), int64(4*1024*1024)
 	for i := 0; i < len(dataRanges); i += 2 {
 		offset, count := dataRanges[i], dataRanges[i+1]
@@ -132,30 +196,116 @@ func (a *API) UploadPageBlob(storageaccount, key, file, container, blobname stri
 			if (end - offset) < fourMB {
 				chunk = end - offset
 			}
-			chunkedDataRanges = append(chunkedDataRanges, offset, chunk)
+			r := blob.HTTPRange{Offset: offset, Count: chunk}
+			if !rangeWritten(written, r) {
+				chunks = append(chunks, r)
+			}
 			offset += chunk
 		}
 	}
 	fmt.Printf("\nEffective upload size: %d MiB (from %d MiB originally)\n", dataSize/1024/1024, size/1024/1024)
 
-	// Upload the data using UploadPages() and show progress. Use a SectionReader
-	// to give the UploadPages a specific window of data to operate on. Use
-	// streaming.NopCloser to allow passing in a Reader with no Close() implementation.
-	uploaded := int64(0)
-	for i := 0; i < len(chunkedDataRanges); i += 2 {
-		offset, count := chunkedDataRanges[i], chunkedDataRanges[i+1]
-		sr := io.NewSectionReader(f, offset, count)
-		_, err = client.UploadPages(ctx, streaming.NopCloser(sr), blob.HTTPRange{
-			Offset: offset,
-			Count:  count,
-		}, nil)
+	// Upload the chunks using UploadPages() and show progress.
+	uploaded := dataSize
+	for _, r := range chunks {
+		uploaded -= r.Count
+	}
+	var mu sync.Mutex
+	var firstErr error
+	work := make(chan blob.HTTPRange)
+	var wg sync.WaitGroup
+	for i := 0; i < max(parallelism, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range work {
+				err := uploadPages(ctx, client, f, r)
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+				} else if err == nil {
+					uploaded += r.Count
+					fmt.Printf("\033[2K\rProgress: %v%%", uploaded*100/dataSize)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, r := range chunks {
+		mu.Lock()
+		failed := firstErr != nil
+		mu.Unlock()
+		if failed {
+			break
+		}
+		work <- r
+	}
+	close(work)
+	wg.Wait()
+	fmt.Println()
+	return firstErr
+}
+
+// fileMD5 returns the MD5 of f, which identifies an interrupted upload of
+// it to resume.
+func fileMD5(f *os.File) ([]byte, error) {
+	plog.Infof("Computing MD5 of %s", f.Name())
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+func createPageBlob(ctx context.Context, client *pageblob.Client, size int64, sumHex string) error {
+	metadata := mantleTags()
+	metadata[uploadMD5Key] = to.Ptr(sumHex)
+	_, err := client.Create(ctx, size, &pageblob.CreateOptions{
+		Metadata: metadata,
+	})
+	return err
+}
+
+// writtenPages returns the ranges of the page blob which have been written.
+func writtenPages(ctx context.Context, client *pageblob.Client) ([]blob.HTTPRange, error) {
+	var written []blob.HTTPRange
+	pager := client.NewGetPageRangesPager(nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
 		if err != nil {
-			return err
+			return nil, err
+		}
+		for _, r := range page.PageRange {
+			// The end of a page range is inclusive
+			written = append(written, blob.HTTPRange{Offset: *r.Start, Count: *r.End - *r.Start + 1})
+		}
+	}
+	return written, nil
+}
+
+func rangeWritten(written []blob.HTTPRange, r blob.HTTPRange) bool {
+	for _, w := range written {
+		if w.Offset <= r.Offset && r.Offset+r.Count <= w.Offset+w.Count {
+			return true
 		}
-		uploaded += count
-		fmt.Printf("\033[2K\rProgress: %v%%", uploaded*100/dataSize)
 	}
-	return nil
+	return false
+}
+
+// uploadPages uploads the range of the file to the page blob, along with
+// its MD5 for the service to verify.
+func uploadPages(ctx context.Context, client *pageblob.Client, f *os.File, r blob.HTTPRange) error {
+	buf := make([]byte, r.Count)
+	if _, err := f.ReadAt(buf, r.Offset); err != nil {
+		return err
+	}
+	sum := md5.Sum(buf)
+	// Use streaming.NopCloser to allow passing in a Reader with no
+	// Close() implementation.
+	_, err := client.UploadPages(ctx, streaming.NopCloser(bytes.NewReader(buf)), r, &pageblob.UploadPagesOptions{
+		TransactionalValidation: blob.TransferValidationTypeMD5(sum[:]),
+	})
+	return err
 }
 
 func (a *API) DeletePageBlob(storageaccount, key, container, blobname string) error {
@@ -17,7 +17,9 @@ package do
 import (
 	"context"
 	"fmt"
+	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/coreos/pkg/capnslog"
@@ -42,6 +44,12 @@ type Options struct {
 	Profile string
 	// Personal access token (overrides config profile)
 	AccessToken string
+	// Spaces access keys (override config profile); default to
+	// $SPACES_ACCESS_KEY_ID and $SPACES_SECRET_ACCESS_KEY
+	SpacesAccessKey string
+	SpacesSecretKey string
+	// Spaces region slug; defaults to Region
+	SpacesRegion string
 
 	// Region slug (e.g. "sfo2")
 	Region string
@@ -76,6 +84,14 @@ func New(opts *Options) (*API, error) {
 		if opts.AccessToken == "" {
 			opts.AccessToken = profile.AccessToken
 		}
+		if opts.SpacesAccessKey == "" && opts.SpacesSecretKey == "" {
+			opts.SpacesAccessKey = profile.SpacesAccessKey
+			opts.SpacesSecretKey = profile.SpacesSecretKey
+		}
+	}
+	if opts.SpacesAccessKey == "" && opts.SpacesSecretKey == "" {
+		opts.SpacesAccessKey = os.Getenv("SPACES_ACCESS_KEY_ID")
+		opts.SpacesSecretKey = os.Getenv("SPACES_SECRET_ACCESS_KEY")
 	}
 
 	ctx := context.TODO()
@@ -133,12 +149,23 @@ func (a *API) PreflightCheck(ctx context.Context) error {
 	return nil
 }
 
-func (a *API) CreateDroplet(ctx context.Context, name string, sshKeyID int, userdata string) (*godo.Droplet, error) {
+// CreateDroplet creates a droplet tagged "mantle" and with tags, named
+// by platform.TagNames.
+func (a *API) CreateDroplet(ctx context.Context, name string, sshKeyID int, userdata string, tags map[string]string) (*godo.Droplet, error) {
 	var droplet *godo.Droplet
 	var err error
-	// DO frequently gives us 422 errors saying "Please try again". Retry every 10 seconds
+	// DO frequently gives us 422 errors saying "Please try again". Retry
 	// for up to 5 min
-	err = util.RetryConditional(5*6, 10*time.Second, shouldRetry, func() error {
+	err = util.Backoff{
+		Delay:       5 * time.Second,
+		MaxDelay:    30 * time.Second,
+		Jitter:      0.2,
+		MaxElapsed:  5 * time.Minute,
+		ShouldRetry: shouldRetry,
+		OnRetry: func(attempt int, err error, delay time.Duration) {
+			plog.Errorf("Error creating droplet: %v. Retrying in %v...", err, delay.Round(time.Second))
+		},
+	}.Do(ctx, func() error {
 		droplet, _, err = a.c.Droplets.Create(ctx, &godo.DropletCreateRequest{
 			Name:              name,
 			Region:            a.opts.Region,
@@ -148,11 +175,8 @@ func (a *API) CreateDroplet(ctx context.Context, name string, sshKeyID int, user
 			IPv6:              !a.opts.DisableIPv6,
 			PrivateNetworking: true,
 			UserData:          userdata,
-			Tags:              []string{"mantle"},
+			Tags:              append([]string{"mantle"}, platform.TagNames(tags)...),
 		})
-		if err != nil {
-			plog.Errorf("Error creating droplet: %v. Retrying...", err)
-		}
 		return err
 	})
 	if err != nil {
@@ -198,6 +222,25 @@ func (a *API) listDropletsWithTag(ctx context.Context, tag string) ([]godo.Dropl
 	}
 }
 
+func (a *API) listImagesWithTag(ctx context.Context, tag string) ([]godo.Image, error) {
+	page := godo.ListOptions{
+		Page:    1,
+		PerPage: 200,
+	}
+	var ret []godo.Image
+	for {
+		images, _, err := a.c.Images.ListByTag(ctx, tag, &page)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, images...)
+		if len(images) < page.PerPage {
+			return ret, nil
+		}
+		page.Page += 1
+	}
+}
+
 func (a *API) GetDroplet(ctx context.Context, dropletID int) (*godo.Droplet, error) {
 	droplet, _, err := a.c.Droplets.Get(ctx, dropletID)
 	if err != nil {
@@ -244,7 +287,9 @@ func (a *API) DeleteDroplet(ctx context.Context, dropletID int) error {
 	return nil
 }
 
-func (a *API) CreateCustomImage(ctx context.Context, name string, url string) (*godo.Image, error) {
+// CreateCustomImage imports the image at url, tagged with tags, named by
+// platform.TagNames, and waits for it to become available.
+func (a *API) CreateCustomImage(ctx context.Context, name string, url string, tags map[string]string) (*godo.Image, error) {
 	var image *godo.Image
 	var err error
 	image, _, err = a.c.Images.Create(ctx, &godo.CustomImageCreateRequest{
@@ -252,20 +297,24 @@ func (a *API) CreateCustomImage(ctx context.Context, name string, url string) (*
 		Url:          url,
 		Region:       a.opts.Region,
 		Distribution: "Fedora",
-		Tags:         []string{"mantle"},
+		Tags:         append([]string{"mantle"}, platform.TagNames(tags)...),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("couldn't create image: %v", err)
 	}
 	imageID := image.ID
 
-	err = util.WaitUntilReady(10*time.Minute, 10*time.Second, func() (bool, error) {
+	// Imports of large images are slow
+	err = util.WaitUntilReady(30*time.Minute, 10*time.Second, func() (bool, error) {
 		var err error
 		// update image in closure
 		image, _, err = a.c.Images.GetByID(ctx, imageID)
 		if err != nil {
 			return false, err
 		}
+		if image.Status == "deleted" {
+			return false, fmt.Errorf("import failed: %s", image.ErrorMessage)
+		}
 		return image.Status == "available", nil
 	})
 	if err != nil {
@@ -340,6 +389,36 @@ func (a *API) AddKey(ctx context.Context, name, key string) (int, error) {
 	return sshKey.ID, nil
 }
 
+// legacyRunKeyPrefix starts the names of the SSH keys added for a run
+// before they were named by util.ResourceName, which are followed by their
+// creation time in seconds since the epoch.
+const legacyRunKeyPrefix = "mantle-"
+
+// AddRunKey adds an SSH key for purpose for the duration of a run, which
+// GC deletes if the run leaves it behind. Keys have no tags or creation
+// time, so its name records when it was added.
+func (a *API) AddRunKey(ctx context.Context, purpose, key string) (int, error) {
+	return a.AddKey(ctx, util.ResourceName(purpose), key)
+}
+
+// runKeyCreated returns when the key named name was added by AddRunKey,
+// if it was.
+func runKeyCreated(name string) (time.Time, bool) {
+	if info, ok := util.ParseResourceName(name); ok && info.FromMantle() {
+		return info.Created, true
+	}
+	rest, ok := strings.CutPrefix(name, legacyRunKeyPrefix)
+	if !ok {
+		return time.Time{}, false
+	}
+	value, _, _ := strings.Cut(rest, "-")
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(seconds, 0), true
+}
+
 func (a *API) DeleteKey(ctx context.Context, keyID int) error {
 	_, err := a.c.Keys.DeleteByID(ctx, keyID)
 	if err != nil {
@@ -367,9 +446,9 @@ func (a *API) ListKeys(ctx context.Context) ([]godo.Key, error) {
 	}
 }
 
-func (a *API) GC(ctx context.Context, gracePeriod time.Duration) error {
-	threshold := time.Now().Add(-gracePeriod)
-
+// GC deletes the droplets and SSH keys mantle created for runs, and the
+// images it created with an expiry, which opts selects as garbage.
+func (a *API) GC(ctx context.Context, opts platform.GCOptions) error {
 	droplets, err := a.listDropletsWithTag(ctx, "mantle")
 	if err != nil {
 		return fmt.Errorf("listing droplets: %v", err)
@@ -383,12 +462,60 @@ func (a *API) GC(ctx context.Context, gracePeriod time.Duration) error {
 		if err != nil {
 			return fmt.Errorf("couldn't parse %q: %v", droplet.Created, err)
 		}
-		if created.After(threshold) {
+		if !opts.IsGarbage(created, platform.TagsFromNames(droplet.Tags)) {
 			continue
 		}
 
-		if err := a.DeleteDroplet(ctx, droplet.ID); err != nil {
-			return fmt.Errorf("couldn't delete droplet %d: %v", droplet.ID, err)
+		id := droplet.ID
+		err = opts.Collect("droplet", strconv.Itoa(id), created, func() error {
+			return a.DeleteDroplet(ctx, id)
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't delete droplet %d: %v", id, err)
+		}
+	}
+
+	keys, err := a.ListKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("listing SSH keys: %v", err)
+	}
+	for _, key := range keys {
+		created, ok := runKeyCreated(key.Name)
+		if !ok || !opts.IsGarbage(created, nil) {
+			continue
+		}
+		id := key.ID
+		err = opts.Collect("SSH key", strconv.Itoa(id), created, func() error {
+			return a.DeleteKey(ctx, id)
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't delete SSH key %d: %v", id, err)
+		}
+	}
+
+	images, err := a.listImagesWithTag(ctx, "mantle")
+	if err != nil {
+		return fmt.Errorf("listing images: %v", err)
+	}
+	for _, image := range images {
+		// Images are kept unless they were created to expire
+		tags := platform.TagsFromNames(image.Tags)
+		if _, ok := platform.Expiry(tags); !ok {
+			continue
+		}
+		created, err := time.Parse(time.RFC3339, image.Created)
+		if err != nil {
+			return fmt.Errorf("couldn't parse %q: %v", image.Created, err)
+		}
+		if !opts.IsGarbage(created, tags) {
+			continue
+		}
+		id := image.ID
+		err = opts.Collect("image", strconv.Itoa(id), created, func() error {
+			return a.DeleteImage(ctx, id)
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't delete image %d: %v", id, err)
 		}
 	}
 	return nil
@@ -0,0 +1,94 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Spaces is S3-compatible object storage, which images are uploaded to
+// for DigitalOcean to import them from.
+
+package do
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+func (a *API) spacesClient() (*s3.S3, error) {
+	if a.opts.SpacesAccessKey == "" || a.opts.SpacesSecretKey == "" {
+		return nil, fmt.Errorf("no Spaces access key given; set it in the config file or $SPACES_ACCESS_KEY_ID and $SPACES_SECRET_ACCESS_KEY")
+	}
+	region := a.opts.SpacesRegion
+	if region == "" {
+		region = a.opts.Region
+	}
+	sess, err := session.NewSession(&aws.Config{
+		Credentials: credentials.NewStaticCredentials(a.opts.SpacesAccessKey, a.opts.SpacesSecretKey, ""),
+		Endpoint:    aws.String(fmt.Sprintf("https://%s.digitaloceanspaces.com", region)),
+		// Spaces ignores the region in signatures, but the SDK needs one
+		Region: aws.String("us-east-1"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating Spaces session: %v", err)
+	}
+	return s3.New(sess), nil
+}
+
+// UploadObject uploads r to the Spaces bucket and returns a URL which
+// reads it for the given duration, without the bucket being public.
+func (a *API) UploadObject(r io.Reader, bucket, key string, expiry time.Duration) (string, error) {
+	client, err := a.spacesClient()
+	if err != nil {
+		return "", err
+	}
+
+	plog.Infof("Uploading %s/%s to Spaces", bucket, key)
+	_, err = s3manager.NewUploaderWithClient(client).Upload(&s3manager.UploadInput{
+		Body:   r,
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return "", fmt.Errorf("uploading %s/%s: %v", bucket, key, err)
+	}
+
+	req, _ := client.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	url, err := req.Presign(expiry)
+	if err != nil {
+		return "", fmt.Errorf("signing URL of %s/%s: %v", bucket, key, err)
+	}
+	return url, nil
+}
+
+func (a *API) DeleteObject(bucket, key string) error {
+	client, err := a.spacesClient()
+	if err != nil {
+		return err
+	}
+	_, err = client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("deleting %s/%s: %v", bucket, key, err)
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The Equinix Metal API is used directly, as documented at
+// https://deploy.equinix.com/developers/api/metal/, since there's no
+// vendored client for it.
+
+package equinix
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/coreos/pkg/capnslog"
+
+	"github.com/coreos/coreos-assembler/mantle/platform"
+)
+
+const endpoint = "https://api.equinix.com/metal/v1"
+
+var (
+	plog = capnslog.NewPackageLogger("github.com/coreos/coreos-assembler/mantle", "platform/api/equinix")
+)
+
+type Options struct {
+	*platform.Options
+
+	// API token; defaults to $METAL_AUTH_TOKEN
+	Token string
+	// Project ID; defaults to $METAL_PROJECT_ID
+	Project string
+
+	// Metro (e.g. "da")
+	Metro string
+	// Plan (e.g. "c3.small.x86", or "c3.large.arm64" for aarch64)
+	Plan string
+
+	// IPXEScriptURL is the iPXE script servers boot with. If empty, it's
+	// that IPXEScript writes, which kola serves from ServeAddress.
+	IPXEScriptURL string
+	// ArtifactURL is the base URL of the live artifacts the iPXE script
+	// boots, Kernel, Initramfs and Rootfs.
+	ArtifactURL string
+	Kernel      string
+	Initramfs   string
+	Rootfs      string
+	// ServeAddress, if set, is the address kola serves the live artifacts
+	// in ArtifactDir and the iPXE script on, which ArtifactURL must reach
+	// from the internet.
+	ServeAddress string
+	ArtifactDir  string
+	// InstallDevice, if set, is the disk the live system installs to
+	// before the server boots from it; otherwise, servers run the live
+	// system.
+	InstallDevice string
+	// Console is the kernel console argument; the default depends on the
+	// architecture.
+	Console string
+}
+
+type API struct {
+	opts   *Options
+	client *http.Client
+}
+
+func New(opts *Options) (*API, error) {
+	if opts.Token == "" {
+		opts.Token = os.Getenv("METAL_AUTH_TOKEN")
+	}
+	if opts.Token == "" {
+		return nil, fmt.Errorf("no Equinix Metal API token given; set --token or $METAL_AUTH_TOKEN")
+	}
+	if opts.Project == "" {
+		opts.Project = os.Getenv("METAL_PROJECT_ID")
+	}
+	if opts.Project == "" {
+		return nil, fmt.Errorf("no Equinix Metal project given; set --project or $METAL_PROJECT_ID")
+	}
+	return &API{
+		opts:   opts,
+		client: &http.Client{Timeout: 2 * time.Minute},
+	}, nil
+}
+
+// Error is an error response from the API.
+type Error struct {
+	StatusCode int
+	Errors     []string `json:"errors"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d: %s", e.StatusCode, strings.Join(e.Errors, "; "))
+}
+
+func isNotFound(err error) bool {
+	apiErr, ok := err.(*Error)
+	return ok && apiErr.StatusCode == http.StatusNotFound
+}
+
+// request sends body as JSON and decodes the response into result, if
+// they're non-nil.
+func (a *API) request(method, path string, body, result interface{}) error {
+	var data []byte
+	if body != nil {
+		var err error
+		data, err = json.Marshal(body)
+		if err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, endpoint+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Auth-Token", a.opts.Token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		apiErr := Error{StatusCode: resp.StatusCode}
+		_ = json.NewDecoder(resp.Body).Decode(&apiErr)
+		return &apiErr
+	}
+	if result != nil {
+		return json.NewDecoder(resp.Body).Decode(result)
+	}
+	return nil
+}
+
+func (a *API) PreflightCheck() error {
+	return a.request(http.MethodGet, "/projects/"+a.opts.Project, nil, nil)
+}
+
+// AddKey adds an SSH key to the project, which servers and the serial
+// over SSH console accept, and returns its ID.
+func (a *API) AddKey(name, key string) (string, error) {
+	var resp struct {
+		ID string `json:"id"`
+	}
+	body := map[string]interface{}{
+		"label": name,
+		"key":   key,
+	}
+	if err := a.request(http.MethodPost, "/projects/"+a.opts.Project+"/ssh-keys", body, &resp); err != nil {
+		return "", fmt.Errorf("adding SSH key: %v", err)
+	}
+	return resp.ID, nil
+}
+
+func (a *API) DeleteKey(id string) error {
+	err := a.request(http.MethodDelete, "/ssh-keys/"+id, nil, nil)
+	if err != nil && !isNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// GC deletes the servers mantle created which opts selects as garbage.
+func (a *API) GC(opts platform.GCOptions) error {
+	for page := 1; ; page++ {
+		var resp struct {
+			Devices []Device `json:"devices"`
+			Meta    struct {
+				LastPage int `json:"last_page"`
+			} `json:"meta"`
+		}
+		path := fmt.Sprintf("/projects/%s/devices?tag=%s&per_page=100&page=%d", a.opts.Project, mantleTag, page)
+		if err := a.request(http.MethodGet, path, nil, &resp); err != nil {
+			return fmt.Errorf("listing servers: %v", err)
+		}
+		for _, device := range resp.Devices {
+			if !opts.IsGarbage(device.Created, platform.TagsFromNames(device.Tags)) {
+				continue
+			}
+			id := device.ID
+			err := opts.Collect("server", id, device.Created, func() error {
+				return a.DeleteDevice(id)
+			})
+			if err != nil {
+				return fmt.Errorf("deleting server %s: %v", id, err)
+			}
+		}
+		if page >= resp.Meta.LastPage {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package equinix
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/coreos/coreos-assembler/mantle/platform"
+	"github.com/coreos/coreos-assembler/mantle/util"
+)
+
+// The tag of servers created by mantle, used to garbage collect them.
+const mantleTag = "mantle"
+
+// Device is a bare-metal server.
+type Device struct {
+	ID          string    `json:"id"`
+	Hostname    string    `json:"hostname"`
+	State       string    `json:"state"`
+	Created     time.Time `json:"created_at"`
+	Tags        []string  `json:"tags"`
+	IPAddresses []struct {
+		Address       string `json:"address"`
+		Public        bool   `json:"public"`
+		AddressFamily int    `json:"address_family"`
+	} `json:"ip_addresses"`
+	// SOS is the host of the serial over SSH console, which is logged
+	// into as the ID of the server.
+	SOS string `json:"sos"`
+}
+
+// PublicIP returns the server's public IPv4 address.
+func (d *Device) PublicIP() string {
+	for _, ip := range d.IPAddresses {
+		if ip.Public && ip.AddressFamily == 4 {
+			return ip.Address
+		}
+	}
+	return ""
+}
+
+// PrivateIP returns the server's private IPv4 address, or its public one
+// if it has none.
+func (d *Device) PrivateIP() string {
+	for _, ip := range d.IPAddresses {
+		if !ip.Public && ip.AddressFamily == 4 {
+			return ip.Address
+		}
+	}
+	return d.PublicIP()
+}
+
+// CreateDevice creates a server which boots the iPXE script in the options
+// with the user data, and waits for it to be provisioned. It's tagged with
+// tags, named by platform.TagNames.
+func (a *API) CreateDevice(hostname, userdata, sshKeyID string, tags map[string]string) (*Device, error) {
+	body := map[string]interface{}{
+		"hostname":         hostname,
+		"metro":            a.opts.Metro,
+		"plan":             a.opts.Plan,
+		"operating_system": "custom_ipxe",
+		"ipxe_script_url":  a.opts.IPXEScriptURL,
+		// The live system boots every time unless it installs itself
+		"always_pxe":    a.opts.InstallDevice == "",
+		"billing_cycle": "hourly",
+		"userdata":      userdata,
+		"tags":          append([]string{mantleTag}, platform.TagNames(tags)...),
+	}
+	if sshKeyID != "" {
+		body["project_ssh_keys"] = []string{sshKeyID}
+	}
+	var device Device
+	if err := a.request(http.MethodPost, "/projects/"+a.opts.Project+"/devices", body, &device); err != nil {
+		return nil, fmt.Errorf("creating server: %v", err)
+	}
+
+	// Provisioning bare metal is slow
+	err := util.WaitUntilReady(30*time.Minute, 15*time.Second, func() (bool, error) {
+		d, err := a.GetDevice(device.ID)
+		if err != nil {
+			return false, err
+		}
+		device = *d
+		switch device.State {
+		case "active":
+			return true, nil
+		case "failed":
+			return false, fmt.Errorf("provisioning failed")
+		}
+		return false, nil
+	})
+	if err != nil {
+		if errDelete := a.DeleteDevice(device.ID); errDelete != nil {
+			return nil, fmt.Errorf("deleting server: %v after waiting for it to be provisioned: %v", errDelete, err)
+		}
+		return nil, fmt.Errorf("waiting for server to be provisioned: %v", err)
+	}
+	return &device, nil
+}
+
+func (a *API) GetDevice(id string) (*Device, error) {
+	var device Device
+	if err := a.request(http.MethodGet, "/devices/"+id, nil, &device); err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+// DeleteDevice deletes the server, succeeding if it doesn't exist.
+func (a *API) DeleteDevice(id string) error {
+	err := a.request(http.MethodDelete, "/devices/"+id+"?force_delete=true", nil, nil)
+	if err != nil && !isNotFound(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package equinix
+
+import (
+	"fmt"
+	"strings"
+)
+
+// IPXEScript returns the iPXE script which boots the live system from the
+// artifacts in the options. The live system runs the Ignition config in
+// the user data, or if InstallDevice is set, installs to it without one,
+// leaving the installed system to fetch the config on its first boot.
+func IPXEScript(opts *Options) (string, error) {
+	if opts.ArtifactURL == "" || opts.Kernel == "" || opts.Initramfs == "" || opts.Rootfs == "" {
+		return "", fmt.Errorf("the URL of the live artifacts and their names are required")
+	}
+	console := opts.Console
+	if console == "" {
+		console = defaultConsole(opts)
+	}
+	base := strings.TrimSuffix(opts.ArtifactURL, "/")
+	args := []string{
+		"initrd=main",
+		"coreos.live.rootfs_url=" + base + "/" + opts.Rootfs,
+		"ignition.firstboot",
+		"console=" + console,
+	}
+	if opts.InstallDevice != "" {
+		args = append(args,
+			"ignition.platform.id=metal",
+			"coreos.inst.install_dev="+opts.InstallDevice,
+			"coreos.inst.platform_id=packet")
+	} else {
+		args = append(args, "ignition.platform.id=packet")
+	}
+	return fmt.Sprintf("#!ipxe\nkernel %s/%s %s\ninitrd --name main %s/%s\nboot\n",
+		base, opts.Kernel, strings.Join(args, " "), base, opts.Initramfs), nil
+}
+
+// defaultConsole returns the serial console of the servers for the
+// architecture, which the serial over SSH console is attached to.
+func defaultConsole(opts *Options) string {
+	if opts.Options != nil && opts.CosaBuildArch == "aarch64" {
+		return "ttyAMA0,115200"
+	}
+	return "ttyS1,115200n8"
+}
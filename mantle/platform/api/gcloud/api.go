@@ -19,7 +19,6 @@ import (
 	"context"
 	"google.golang.org/api/option"
 	"net/http"
-	"time"
 
 	"github.com/coreos/pkg/capnslog"
 	"google.golang.org/api/compute/v1"
@@ -43,6 +42,10 @@ type Options struct {
 	JSONKeyFile      string
 	ServiceAuth      bool
 	ConfidentialType string
+	// ShieldedVM, if set, lists the Shielded VM features to enable,
+	// secure-boot, vtpm and integrity-monitoring; the others are
+	// disabled. If empty, GCP's defaults apply.
+	ShieldedVM []string
 	*platform.Options
 }
 
@@ -104,6 +107,8 @@ func (a *API) Client() *http.Client {
 	return a.client
 }
 
-func (a *API) GC(gracePeriod time.Duration) error {
-	return a.gcInstances(gracePeriod)
+// GC terminates the instances mantle created which opts selects as
+// garbage. Tags are matched against the instances' labels.
+func (a *API) GC(opts platform.GCOptions) error {
+	return a.gcInstances(opts)
 }
@@ -15,7 +15,6 @@
 package gcloud
 
 import (
-	"crypto/rand"
 	"fmt"
 	"strings"
 	"time"
@@ -26,33 +25,21 @@ import (
 	"google.golang.org/api/compute/v1"
 )
 
-func (a *API) vmname() string {
-	b := make([]byte, 10)
-	if _, err := rand.Read(b); err != nil {
-		plog.Error(err)
-	}
-	return fmt.Sprintf("%s-%x", a.options.BaseName, b)
-}
-
 // ["5G:channel=nvme"], by default the disk type is local-ssd
 func ParseDisk(spec string, zone string) (*compute.AttachedDisk, error) {
 	var diskInterface string
 
-	size, diskmap, err := util.ParseDiskSpec(spec, false)
+	d, err := util.ParseDiskSpec(spec, false, util.DiskKeys{"channel": true})
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse disk spec %q: %w", spec, err)
 	}
-	for key, value := range diskmap {
-		switch key {
-		case "channel":
-			switch value {
-			case "nvme", "scsi":
-				diskInterface = strings.ToUpper(value)
-			default:
-				return nil, fmt.Errorf("invalid channel value: %q", value)
-			}
+	if value, ok := d.Options["channel"]; ok {
+		switch value {
+		case "nvme", "scsi":
+			diskInterface = strings.ToUpper(value)
 		default:
-			return nil, fmt.Errorf("invalid key %q", key)
+			return nil, fmt.Errorf("failed to parse disk spec %q: %w", spec,
+				d.OptionError("channel", "invalid channel %q; channels are nvme and scsi", value))
 		}
 	}
 
@@ -63,18 +50,44 @@ func ParseDisk(spec string, zone string) (*compute.AttachedDisk, error) {
 		Interface:  diskInterface,
 		InitializeParams: &compute.AttachedDiskInitializeParams{
 			DiskType:   "/zones/" + zone + "/diskTypes/local-ssd",
-			DiskSizeGb: size,
+			DiskSizeGb: d.GiB(),
 		},
 	}, nil
 }
 
 // Taken from: https://github.com/golang/build/blob/master/buildlet/gce.go
-func (a *API) mkinstance(userdata, name string, keys []*agent.Key, opts platform.MachineOptions, useServiceAcct bool) (*compute.Instance, error) {
+// shieldedInstanceConfig enables the listed Shielded VM features, and
+// disables the others. The image must be UEFI_COMPATIBLE.
+func shieldedInstanceConfig(features []string) (*compute.ShieldedInstanceConfig, error) {
+	config := &compute.ShieldedInstanceConfig{
+		// Send the disabled features too, since some default to on
+		ForceSendFields: []string{"EnableSecureBoot", "EnableVtpm", "EnableIntegrityMonitoring"},
+	}
+	for _, f := range features {
+		switch f {
+		case "secure-boot":
+			config.EnableSecureBoot = true
+		case "vtpm":
+			config.EnableVtpm = true
+		case "integrity-monitoring":
+			config.EnableIntegrityMonitoring = true
+		default:
+			return nil, fmt.Errorf("unknown Shielded VM feature %q, should be: secure-boot, vtpm, integrity-monitoring", f)
+		}
+	}
+	// https://cloud.google.com/compute/shielded-vm/docs/shielded-vm#integrity-monitoring
+	if config.EnableIntegrityMonitoring && !config.EnableVtpm {
+		return nil, fmt.Errorf("Shielded VM integrity-monitoring requires vtpm")
+	}
+	return config, nil
+}
+
+func (a *API) mkinstance(userdata, name string, keys []*agent.Key, opts platform.MachineOptions, useServiceAcct bool, tags map[string]string) (*compute.Instance, error) {
 	mantle := "mantle"
 	metadataItems := []*compute.MetadataItems{
 		{
-			// this should be done with a label instead, but
-			// our old vendored Go binding doesn't support those
+			// GC finds mantle's instances by this, which predates
+			// them being labeled
 			Key:   "created-by",
 			Value: &mantle,
 		},
@@ -92,10 +105,15 @@ func (a *API) mkinstance(userdata, name string, keys []*agent.Key, opts platform
 	}
 
 	instancePrefix := "https://www.googleapis.com/compute/v1/projects/" + a.options.Project
+	machineType := a.options.MachineType
+	if opts.InstanceType != "" {
+		machineType = opts.InstanceType
+	}
 
 	instance := &compute.Instance{
 		Name:        name,
-		MachineType: instancePrefix + "/zones/" + a.options.Zone + "/machineTypes/" + a.options.MachineType,
+		Labels:      platform.SanitizeTags(tags),
+		MachineType: instancePrefix + "/zones/" + a.options.Zone + "/machineTypes/" + machineType,
 		Metadata: &compute.Metadata{
 			Items: metadataItems,
 		},
@@ -162,8 +180,20 @@ func (a *API) mkinstance(userdata, name string, keys []*agent.Key, opts platform
 			return nil, fmt.Errorf("Does not support confidential type %s, should be: sev, sev_snp, tdx\n", a.options.ConfidentialType)
 		}
 	}
+	if len(a.options.ShieldedVM) > 0 {
+		config, err := shieldedInstanceConfig(a.options.ShieldedVM)
+		if err != nil {
+			return nil, err
+		}
+		instance.ShieldedInstanceConfig = config
+	}
+	if opts.NestedVirt {
+		instance.AdvancedMachineFeatures = &compute.AdvancedMachineFeatures{
+			EnableNestedVirtualization: true,
+		}
+	}
 	// metal instances can only have a TERMINATE maintenance policy
-	if strings.HasSuffix(a.options.MachineType, "metal") {
+	if strings.HasSuffix(machineType, "metal") {
 		instance.Scheduling = &compute.Scheduling{
 			OnHostMaintenance: "TERMINATE",
 		}
@@ -180,10 +210,11 @@ func (a *API) mkinstance(userdata, name string, keys []*agent.Key, opts platform
 	return instance, nil
 }
 
-// CreateInstance creates a Google Compute Engine instance.
-func (a *API) CreateInstance(userdata string, keys []*agent.Key, opts platform.MachineOptions, useServiceAcct bool) (*compute.Instance, error) {
-	name := a.vmname()
-	inst, err := a.mkinstance(userdata, name, keys, opts, useServiceAcct)
+// CreateInstance creates a Google Compute Engine instance labeled with
+// tags, sanitized with platform.SanitizeTags.
+func (a *API) CreateInstance(userdata string, keys []*agent.Key, opts platform.MachineOptions, useServiceAcct bool, tags map[string]string) (*compute.Instance, error) {
+	name := util.ResourceName(a.options.BaseName)
+	inst, err := a.mkinstance(userdata, name, keys, opts, useServiceAcct, tags)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create instance %q: %w", name, err)
 	}
@@ -251,6 +282,17 @@ func (a *API) GetConsoleOutput(name string) (string, error) {
 	return out.Contents, nil
 }
 
+// GetConsoleOutputFrom returns the console output of an instance from
+// byte offset start onwards, or from the oldest output GCE still keeps if
+// that was discarded. The output's Start and Next give the offsets read.
+func (a *API) GetConsoleOutputFrom(name string, start int64) (*compute.SerialPortOutput, error) {
+	out, err := a.compute.Instances.GetSerialPortOutput(a.options.Project, a.options.Zone, name).Start(start).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve console output for %q: %v", name, err)
+	}
+	return out, nil
+}
+
 // Taken from: https://github.com/golang/build/blob/master/buildlet/gce.go
 func InstanceIPs(inst *compute.Instance) (intIP, extIP string) {
 	for _, iface := range inst.NetworkInterfaces {
@@ -266,16 +308,14 @@ func InstanceIPs(inst *compute.Instance) (intIP, extIP string) {
 	return
 }
 
-func (a *API) gcInstances(gracePeriod time.Duration) error {
-	threshold := time.Now().Add(-gracePeriod)
-
+func (a *API) gcInstances(opts platform.GCOptions) error {
 	list, err := a.compute.Instances.List(a.options.Project, a.options.Zone).Do()
 	if err != nil {
 		return err
 	}
 	for _, instance := range list.Items {
-		// check metadata because our vendored Go binding
-		// doesn't support labels
+		// check metadata because instances created before they
+		// were labeled only have that
 		if instance.Metadata == nil {
 			continue
 		}
@@ -294,7 +334,7 @@ func (a *API) gcInstances(gracePeriod time.Duration) error {
 		if err != nil {
 			return fmt.Errorf("couldn't parse %q: %v", instance.CreationTimestamp, err)
 		}
-		if created.After(threshold) {
+		if !opts.IsGarbage(created, instance.Labels) {
 			continue
 		}
 
@@ -303,7 +343,10 @@ func (a *API) gcInstances(gracePeriod time.Duration) error {
 			continue
 		}
 
-		if err := a.TerminateInstance(instance.Name); err != nil {
+		err = opts.Collect("instance", instance.Name, created, func() error {
+			return a.TerminateInstance(instance.Name)
+		})
+		if err != nil {
 			return fmt.Errorf("couldn't terminate instance %q: %v", instance.Name, err)
 		}
 	}
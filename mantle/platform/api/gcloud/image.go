@@ -16,11 +16,13 @@ package gcloud
 
 import (
 	"fmt"
+	"net/http"
 	"runtime"
 	"strings"
 
 	"golang.org/x/net/context"
 	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
 )
 
 type DeprecationState string
@@ -39,6 +41,9 @@ type ImageSpec struct {
 	Name         string
 	Description  string
 	Licenses     []string // short names
+	// GuestOSFeatures are enabled on top of the defaults for the
+	// architecture.
+	GuestOSFeatures []string
 }
 
 const endpointPrefix = "https://www.googleapis.com/compute/v1/"
@@ -86,50 +91,14 @@ func (a *API) CreateImage(spec *ImageSpec, overwrite bool) (*compute.Operation,
 		}
 	}
 
-	// https://cloud.google.com/compute/docs/images/create-custom#guest-os-features
-	features := []*compute.GuestOsFeature{
-		// https://cloud.google.com/compute/docs/images/create-delete-deprecate-private-images
-		{
-			Type: "VIRTIO_SCSI_MULTIQUEUE",
-		},
-		// RHEL supports this since 8.4; TODO share logic here with
-		// https://github.com/osbuild/osbuild-composer/blob/c6570f6c94149b47f2f8e2f82d7467d6b96755bb/internal/cloud/gcp/compute.go#L16
-		{
-			Type: "SEV_CAPABLE",
-		},
-		{
-			Type: "GVNIC",
-		},
-		{
-			Type: "UEFI_COMPATIBLE",
-		},
-		// https://cloud.google.com/blog/products/identity-security/rsa-snp-vm-more-confidential
-		{
-			Type: "SEV_SNP_CAPABLE",
-		},
-		// https://cloud.google.com/compute/docs/networking/using-idpf
-		{
-			Type: "IDPF",
-		},
-		// https://cloud.google.com/blog/products/identity-security/confidential-vms-on-intel-cpus-your-datas-new-intelligent-defense
-		{
-			Type: "TDX_CAPABLE",
-		},
-	}
-
-	if spec.Architecture == "" {
-		spec.Architecture = runtime.GOARCH
+	arch, features, err := guestOSFeatures(spec.Architecture)
+	if err != nil {
+		return nil, nil, err
 	}
-	switch spec.Architecture {
-	case "amd64", "x86_64":
-		spec.Architecture = "X86_64"
-		// Enables support for live migration of AMD SEV SNP capable images in GCP.
-		// See: https://git.kernel.org/pub/scm/linux/kernel/git/tip/tip.git/commit/?id=ac3f9c9f1b37edaa7d1a9b908bc79d843955a1a2
-		features = append(features, &compute.GuestOsFeature{Type: "SEV_LIVE_MIGRATABLE_V2"})
-	case "arm64", "aarch64":
-		spec.Architecture = "ARM64"
-	default:
-		return nil, nil, fmt.Errorf("unsupported gcp architecture %q", spec.Architecture)
+	for _, f := range spec.GuestOSFeatures {
+		if !hasGuestOSFeature(features, f) {
+			features = append(features, &compute.GuestOsFeature{Type: f})
+		}
 	}
 
 	if overwrite {
@@ -150,8 +119,10 @@ func (a *API) CreateImage(spec *ImageSpec, overwrite bool) (*compute.Operation,
 		}
 	}
 
+	// Setting the family on creation means the family only resolves to
+	// the image once it's ready
 	image := &compute.Image{
-		Architecture:    spec.Architecture,
+		Architecture:    arch,
 		Family:          spec.Family,
 		Name:            spec.Name,
 		Description:     spec.Description,
@@ -173,6 +144,62 @@ func (a *API) CreateImage(spec *ImageSpec, overwrite bool) (*compute.Operation,
 	return op, a.NewPending(op.Name, doable), nil
 }
 
+// guestOSFeatures returns the GCP name of the architecture and the guest OS
+// features images for it support, which default to those of the host.
+func guestOSFeatures(architecture string) (string, []*compute.GuestOsFeature, error) {
+	// https://cloud.google.com/compute/docs/images/create-custom#guest-os-features
+	features := []*compute.GuestOsFeature{
+		// https://cloud.google.com/compute/docs/images/create-delete-deprecate-private-images
+		{
+			Type: "VIRTIO_SCSI_MULTIQUEUE",
+		},
+		{
+			Type: "GVNIC",
+		},
+		{
+			Type: "UEFI_COMPATIBLE",
+		},
+		// https://cloud.google.com/compute/docs/networking/using-idpf
+		{
+			Type: "IDPF",
+		},
+	}
+
+	if architecture == "" {
+		architecture = runtime.GOARCH
+	}
+	switch architecture {
+	case "amd64", "x86_64":
+		// The confidential computing features are x86-only
+		features = append(features,
+			// RHEL supports this since 8.4; TODO share logic here with
+			// https://github.com/osbuild/osbuild-composer/blob/c6570f6c94149b47f2f8e2f82d7467d6b96755bb/internal/cloud/gcp/compute.go#L16
+			&compute.GuestOsFeature{Type: "SEV_CAPABLE"},
+			// https://cloud.google.com/blog/products/identity-security/rsa-snp-vm-more-confidential
+			&compute.GuestOsFeature{Type: "SEV_SNP_CAPABLE"},
+			// https://cloud.google.com/blog/products/identity-security/confidential-vms-on-intel-cpus-your-datas-new-intelligent-defense
+			&compute.GuestOsFeature{Type: "TDX_CAPABLE"},
+			// Enables support for live migration of AMD SEV SNP capable images in GCP.
+			// See: https://git.kernel.org/pub/scm/linux/kernel/git/tip/tip.git/commit/?id=ac3f9c9f1b37edaa7d1a9b908bc79d843955a1a2
+			&compute.GuestOsFeature{Type: "SEV_LIVE_MIGRATABLE_V2"},
+		)
+		return "X86_64", features, nil
+	case "arm64", "aarch64":
+		return "ARM64", features, nil
+	default:
+		return "", nil, fmt.Errorf("unsupported gcp architecture %q", architecture)
+	}
+}
+
+func hasGuestOSFeature(features []*compute.GuestOsFeature, feature string) bool {
+	for _, f := range features {
+		if f.Type == feature {
+			return true
+		}
+	}
+	return false
+}
+
 func (a *API) ListImages(ctx context.Context, prefix string, family string) ([]*compute.Image, error) {
 	var images []*compute.Image
 	listReq := a.compute.Images.List(a.options.Project)
@@ -192,6 +219,38 @@ func (a *API) ListImages(ctx context.Context, prefix string, family string) ([]*
 	return images, nil
 }
 
+// FindImage returns the image, or nil if it doesn't exist.
+func (a *API) FindImage(name string) (*compute.Image, error) {
+	image, err := a.compute.Images.Get(a.options.Project, name).Do()
+	if err != nil {
+		if gerr, ok := err.(*googleapi.Error); ok && gerr.Code == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("getting image %s: %v", name, err)
+	}
+	return image, nil
+}
+
+// IsImagePublic returns whether all authenticated users can use the image,
+// as SetImagePublic allows.
+func (a *API) IsImagePublic(name string) (bool, error) {
+	policy, err := a.compute.Images.GetIamPolicy(a.options.Project, name).Do()
+	if err != nil {
+		return false, fmt.Errorf("Getting image %s IAM policy failed: %v", name, err)
+	}
+	for _, binding := range policy.Bindings {
+		if binding.Role != "roles/compute.imageUser" {
+			continue
+		}
+		for _, member := range binding.Members {
+			if member == "allAuthenticatedUsers" {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
 func (a *API) GetPendingForImage(image *compute.Image) (*Pending, error) {
 	op := a.compute.GlobalOperations.List(a.options.Project)
 	op.Filter(fmt.Sprintf("(targetId eq %v) (operationType eq insert)", image.Id))
@@ -229,6 +288,76 @@ func (a *API) DeprecateImage(name string, state DeprecationState, replacement st
 	return a.NewPending(op.Name, opReq), nil
 }
 
+// PromoteImage makes the image the active one in its family, deprecating
+// the family's other active images in favor of it.
+func (a *API) PromoteImage(ctx context.Context, name, family string) error {
+	images, err := a.ListImages(ctx, "", family)
+	if err != nil {
+		return err
+	}
+
+	// Make sure the specified image exists in the specified image family
+	found := false
+	for _, image := range images {
+		if image.Name == name {
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("the image (%v) must be in the image family (%v)", name, family)
+	}
+
+	// First undeprecate the image we want to promote
+	if err := a.setDeprecationState(name, DeprecationStateActive, ""); err != nil {
+		return err
+	}
+
+	// Next deprecate all other images in the image family
+	// that need to be deprecated.
+	for _, image := range images {
+		// don't deprecate the image we just undeprecated
+		if image.Name == name {
+			continue
+		}
+		// Some debug messages which are useful when needed.
+		// This triggers the deprecation lint in golangci-lint because the
+		// docstring for the `Deprecated` field starts with "Deprecated: ". The
+		// docstring was tweaked to not trigger this, so we can drop this in the
+		// next vendor bump. See:
+		// https://github.com/googleapis/google-api-go-client/issues/767.
+		// nolint
+		if image.Deprecated != nil {
+			plog.Debugf("Deprecation state for %v is %v",
+				image.Name, image.Deprecated.State)
+		} else {
+			plog.Debugf("Deprecation state is nil for %v", image.Name)
+		}
+		// Perform the deprecation if the image is not already deprecated.
+		// We detect if it is active by checking if it either doesn't
+		// have any deprecation state or if it is explicitly ACTIVE.
+		// nolint (see comment above)
+		if image.Deprecated == nil ||
+			image.Deprecated.State == string(DeprecationStateActive) {
+			if err := a.setDeprecationState(image.Name, DeprecationStateDeprecated, name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (a *API) setDeprecationState(name string, state DeprecationState, replacement string) error {
+	plog.Infof("Changing deprecation state of image: %v -> %v", name, state)
+	pending, err := a.DeprecateImage(name, state, replacement)
+	if err == nil {
+		err = pending.Wait()
+	}
+	if err != nil {
+		return fmt.Errorf("changing deprecation state of image failed: %v", err)
+	}
+	return nil
+}
+
 func (a *API) DeleteImage(name string) (*Pending, error) {
 	op, err := a.compute.Images.Delete(a.options.Project, name).Do()
 	if err != nil {
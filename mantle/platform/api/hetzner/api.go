@@ -0,0 +1,224 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The Hetzner Cloud API is used directly, as documented at
+// https://docs.hetzner.cloud, since there's no vendored client for it.
+
+package hetzner
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/coreos/pkg/capnslog"
+
+	"github.com/coreos/coreos-assembler/mantle/platform"
+	"github.com/coreos/coreos-assembler/mantle/util"
+)
+
+const defaultEndpoint = "https://api.hetzner.cloud/v1"
+
+var (
+	plog = capnslog.NewPackageLogger("github.com/coreos/coreos-assembler/mantle", "platform/api/hetzner")
+)
+
+type Options struct {
+	*platform.Options
+
+	// API token of the project; defaults to $HCLOUD_TOKEN
+	Token string
+
+	// Location (e.g. "fsn1")
+	Location string
+	// ServerType (e.g. "cx22", or "cax11" for aarch64)
+	ServerType string
+	// Image ID or name
+	Image string
+}
+
+type API struct {
+	opts     *Options
+	client   *http.Client
+	endpoint string
+}
+
+func New(opts *Options) (*API, error) {
+	if opts.Token == "" {
+		opts.Token = os.Getenv("HCLOUD_TOKEN")
+	}
+	if opts.Token == "" {
+		return nil, fmt.Errorf("no Hetzner Cloud API token given; set --token or $HCLOUD_TOKEN")
+	}
+	return &API{
+		opts:     opts,
+		client:   &http.Client{Timeout: 2 * time.Minute},
+		endpoint: defaultEndpoint,
+	}, nil
+}
+
+// Error is an error response from the API.
+type Error struct {
+	StatusCode int
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+func isNotFound(err error) bool {
+	apiErr, ok := err.(*Error)
+	return ok && apiErr.StatusCode == http.StatusNotFound
+}
+
+// request sends body as JSON and decodes the response into result, if
+// they're non-nil.
+func (a *API) request(method, path string, body, result interface{}) error {
+	var data []byte
+	if body != nil {
+		var err error
+		data, err = json.Marshal(body)
+		if err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, a.endpoint+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+a.opts.Token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var errResp struct {
+			Error Error `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&errResp)
+		errResp.Error.StatusCode = resp.StatusCode
+		return &errResp.Error
+	}
+	if result != nil {
+		return json.NewDecoder(resp.Body).Decode(result)
+	}
+	return nil
+}
+
+// list gets each page of the listing at path, which must have a query,
+// passing the response to page.
+func (a *API) list(path string, page func(resp json.RawMessage) error) error {
+	for next := 1; ; {
+		var resp json.RawMessage
+		if err := a.request(http.MethodGet, fmt.Sprintf("%s&page=%d", path, next), nil, &resp); err != nil {
+			return err
+		}
+		var meta struct {
+			Meta struct {
+				Pagination struct {
+					NextPage *int `json:"next_page"`
+				} `json:"pagination"`
+			} `json:"meta"`
+		}
+		if err := json.Unmarshal(resp, &meta); err != nil {
+			return err
+		}
+		if err := page(resp); err != nil {
+			return err
+		}
+		if meta.Meta.Pagination.NextPage == nil {
+			return nil
+		}
+		next = *meta.Meta.Pagination.NextPage
+	}
+}
+
+func (a *API) PreflightCheck() error {
+	return a.request(http.MethodGet, "/locations", nil, nil)
+}
+
+type action struct {
+	ID     int64  `json:"id"`
+	Status string `json:"status"`
+	Error  *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// waitForAction waits for an asynchronous action, such as powering on a
+// server, to complete.
+func (a *API) waitForAction(act action) error {
+	return util.WaitUntilReady(20*time.Minute, 5*time.Second, func() (bool, error) {
+		var resp struct {
+			Action action `json:"action"`
+		}
+		if err := a.request(http.MethodGet, "/actions/"+strconv.FormatInt(act.ID, 10), nil, &resp); err != nil {
+			return false, err
+		}
+		switch resp.Action.Status {
+		case "success":
+			return true, nil
+		case "error":
+			msg := ""
+			if resp.Action.Error != nil {
+				msg = resp.Action.Error.Message
+			}
+			return false, fmt.Errorf("action %d failed: %s", act.ID, msg)
+		}
+		return false, nil
+	})
+}
+
+// AddKey adds an SSH key to the project and returns its ID.
+func (a *API) AddKey(name, key string) (int64, error) {
+	var resp struct {
+		SSHKey struct {
+			ID int64 `json:"id"`
+		} `json:"ssh_key"`
+	}
+	body := map[string]interface{}{
+		"name":       name,
+		"public_key": key,
+		"labels":     mantleLabels(),
+	}
+	if err := a.request(http.MethodPost, "/ssh_keys", body, &resp); err != nil {
+		return 0, fmt.Errorf("adding SSH key: %v", err)
+	}
+	return resp.SSHKey.ID, nil
+}
+
+func (a *API) DeleteKey(id int64) error {
+	err := a.request(http.MethodDelete, "/ssh_keys/"+strconv.FormatInt(id, 10), nil, nil)
+	if err != nil && !isNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+func mantleLabels() map[string]string {
+	return map[string]string{
+		"created-by": "mantle",
+	}
+}
@@ -0,0 +1,229 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hetzner
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coreos/coreos-assembler/mantle/platform"
+)
+
+// newTestAPI returns an API talking to a fake server with the given
+// routes, which are "METHOD /path" patterns, and a function returning the
+// requests made.
+func newTestAPI(t *testing.T, routes map[string]http.HandlerFunc) (*API, func() []string) {
+	var mu sync.Mutex
+	var requests []string
+	mux := http.NewServeMux()
+	for pattern, h := range routes {
+		mux.HandleFunc(pattern, h)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer token" {
+			t.Errorf("%s %s: got Authorization %q", r.Method, r.URL.Path, got)
+		}
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	api := &API{
+		opts: &Options{
+			Token:      "token",
+			Location:   "fsn1",
+			ServerType: "cx22",
+			Image:      "1234",
+		},
+		client:   srv.Client(),
+		endpoint: srv.URL,
+	}
+	return api, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), requests...)
+	}
+}
+
+func reply(t *testing.T, status int, body interface{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if body != nil {
+			if err := json.NewEncoder(w).Encode(body); err != nil {
+				t.Error(err)
+			}
+		}
+	}
+}
+
+func TestCreateServer(t *testing.T) {
+	api, requests := newTestAPI(t, map[string]http.HandlerFunc{
+		"POST /servers": func(w http.ResponseWriter, r *http.Request) {
+			var body map[string]interface{}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Error(err)
+			}
+			want := map[string]interface{}{
+				"name":               "kola-test",
+				"server_type":        "cx22",
+				"image":              "1234",
+				"location":           "fsn1",
+				"labels":             map[string]interface{}{"created-by": "mantle", "team": "ci"},
+				"start_after_create": true,
+				"user_data":          "{}",
+				"ssh_keys":           []interface{}{float64(7)},
+			}
+			if !reflect.DeepEqual(body, want) {
+				t.Errorf("got body %v, expected %v", body, want)
+			}
+			reply(t, http.StatusCreated, map[string]interface{}{
+				"server": map[string]interface{}{"id": 42, "status": "initializing"},
+				"action": map[string]interface{}{"id": 1, "status": "running"},
+			})(w, r)
+		},
+		"GET /actions/1": reply(t, http.StatusOK, map[string]interface{}{
+			"action": map[string]interface{}{"id": 1, "status": "success"},
+		}),
+		"GET /servers/42": reply(t, http.StatusOK, map[string]interface{}{
+			"server": map[string]interface{}{
+				"id":         42,
+				"status":     "running",
+				"public_net": map[string]interface{}{"ipv4": map[string]interface{}{"ip": "192.0.2.1"}},
+			},
+		}),
+	})
+
+	server, err := api.CreateServer("kola-test", "{}", 7, map[string]string{"team": "ci"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if server.ID != 42 || server.PublicIP() != "192.0.2.1" || server.PrivateIP() != "192.0.2.1" {
+		t.Errorf("got server %+v", server)
+	}
+	want := []string{"POST /servers", "GET /actions/1", "GET /servers/42"}
+	if got := requests(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got requests %v, expected %v", got, want)
+	}
+}
+
+func TestListImages(t *testing.T) {
+	api, _ := newTestAPI(t, map[string]http.HandlerFunc{
+		"GET /images": func(w http.ResponseWriter, r *http.Request) {
+			q := r.URL.Query()
+			if q.Get("type") != "snapshot" || q.Get("label_selector") != "build=1.2&arch" {
+				t.Errorf("got query %v", q)
+			}
+			if q.Get("page") == "1" {
+				reply(t, http.StatusOK, map[string]interface{}{
+					"images": []map[string]interface{}{{"id": 5}},
+					"meta":   map[string]interface{}{"pagination": map[string]interface{}{"next_page": 2}},
+				})(w, r)
+				return
+			}
+			reply(t, http.StatusOK, map[string]interface{}{
+				"images": []map[string]interface{}{{"id": 6}},
+			})(w, r)
+		},
+	})
+	images, err := api.ListImages("build=1.2&arch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(images) != 2 || images[0].ID != 5 || images[1].ID != 6 {
+		t.Errorf("got images %+v", images)
+	}
+}
+
+func TestGC(t *testing.T) {
+	old := time.Now().Add(-24 * time.Hour)
+	api, requests := newTestAPI(t, map[string]http.HandlerFunc{
+		// The servers span two pages.
+		"GET /servers": func(w http.ResponseWriter, r *http.Request) {
+			switch page := r.URL.Query().Get("page"); page {
+			case "1":
+				reply(t, http.StatusOK, map[string]interface{}{
+					"servers": []map[string]interface{}{
+						{"id": 1, "created": old},
+						{"id": 2, "created": time.Now()},
+					},
+					"meta": map[string]interface{}{"pagination": map[string]interface{}{"page": 1, "next_page": 2}},
+				})(w, r)
+			case "2":
+				reply(t, http.StatusOK, map[string]interface{}{
+					"servers": []map[string]interface{}{{"id": 4, "created": old}},
+					"meta":    map[string]interface{}{"pagination": map[string]interface{}{"page": 2, "next_page": nil}},
+				})(w, r)
+			default:
+				t.Errorf("listed servers page %q", page)
+			}
+		},
+		"DELETE /servers/1": reply(t, http.StatusOK, nil),
+		"DELETE /servers/4": reply(t, http.StatusOK, nil),
+		"GET /ssh_keys": reply(t, http.StatusOK, map[string]interface{}{
+			"ssh_keys": []map[string]interface{}{{"id": 3, "created": old}},
+		}),
+		// Deleting something already gone succeeds.
+		"DELETE /ssh_keys/3": reply(t, http.StatusNotFound, map[string]interface{}{
+			"error": map[string]string{"code": "not_found", "message": "not found"},
+		}),
+	})
+
+	if err := api.GC(platform.GCOptions{GracePeriod: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+	got := requests()
+	sort.Strings(got)
+	want := []string{"DELETE /servers/1", "DELETE /servers/4", "DELETE /ssh_keys/3", "GET /servers", "GET /servers", "GET /ssh_keys"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got requests %v, expected %v", got, want)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	api, _ := newTestAPI(t, map[string]http.HandlerFunc{
+		"GET /servers/1": reply(t, http.StatusForbidden, map[string]interface{}{
+			"error": map[string]string{"code": "forbidden", "message": "insufficient permissions"},
+		}),
+		"DELETE /servers/2": func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "upstream unavailable", http.StatusBadGateway)
+		},
+	})
+
+	_, err := api.GetServer(1)
+	apiErr, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("got error %v, expected an API error", err)
+	}
+	if want := (Error{StatusCode: 403, Code: "forbidden", Message: "insufficient permissions"}); *apiErr != want {
+		t.Errorf("got %+v, expected %+v", *apiErr, want)
+	}
+	if got, want := err.Error(), "403 forbidden: insufficient permissions"; got != want {
+		t.Errorf("got message %q, expected %q", got, want)
+	}
+
+	// Bodies which aren't JSON still give the status.
+	err = api.DeleteServer(2)
+	if apiErr, ok := err.(*Error); !ok || apiErr.StatusCode != http.StatusBadGateway {
+		t.Errorf("got error %v, expected a 502", err)
+	}
+}
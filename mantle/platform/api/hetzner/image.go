@@ -0,0 +1,192 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hetzner
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/coreos/coreos-assembler/mantle/platform"
+	"github.com/coreos/coreos-assembler/mantle/util"
+)
+
+// The image of the server whose disk an uploaded image is written to. It's
+// booted into the rescue system, so any image will do.
+const helperImage = "ubuntu-24.04"
+
+type Image struct {
+	ID          int64             `json:"id"`
+	Description string            `json:"description"`
+	Status      string            `json:"status"`
+	Labels      map[string]string `json:"labels"`
+}
+
+// UploadImage writes the raw disk image at path, which may be xz
+// compressed, to the disk of a temporary server and snapshots it, since
+// Hetzner Cloud has no way to import images. The snapshot is labeled with
+// labels and described by name, and its ID is returned.
+func (a *API) UploadImage(name, path string, labels map[string]string) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return 0, err
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		return 0, err
+	}
+	authorizedKey := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.PublicKey())))
+
+	keyID, err := a.AddKey(name+"-upload", authorizedKey)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err := a.DeleteKey(keyID); err != nil {
+			plog.Errorf("Deleting SSH key %d: %v", keyID, err)
+		}
+	}()
+
+	plog.Infof("Creating server to write image to")
+	server, err := a.createServer(name+"-upload", helperImage, "", 0, false, platform.ResourceTags(a.opts.Options, ""))
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err := a.DeleteServer(server.ID); err != nil {
+			plog.Errorf("Deleting server %d: %v", server.ID, err)
+		}
+	}()
+
+	rescue := map[string]interface{}{
+		"type":     "linux64",
+		"ssh_keys": []int64{keyID},
+	}
+	if err := a.serverAction(server.ID, "enable_rescue", rescue, nil); err != nil {
+		return 0, err
+	}
+	if err := a.serverAction(server.ID, "poweron", nil, nil); err != nil {
+		return 0, err
+	}
+
+	plog.Infof("Writing %s to disk of server %d", path, server.ID)
+	if err := writeDisk(server.PublicIP(), signer, f, strings.HasSuffix(path, ".xz")); err != nil {
+		return 0, fmt.Errorf("writing image: %v", err)
+	}
+
+	if err := a.serverAction(server.ID, "poweroff", nil, nil); err != nil {
+		return 0, err
+	}
+	plog.Infof("Creating snapshot")
+	body := map[string]interface{}{
+		"type":        "snapshot",
+		"description": name,
+		"labels":      labels,
+	}
+	var resp struct {
+		Image Image `json:"image"`
+	}
+	if err := a.serverAction(server.ID, "create_image", body, &resp); err != nil {
+		return 0, err
+	}
+	return resp.Image.ID, nil
+}
+
+// writeDisk connects to the rescue system at ip and writes the image from
+// r to the server's disk.
+func writeDisk(ip string, signer ssh.Signer, r io.Reader, compressed bool) error {
+	config := &ssh.ClientConfig{
+		User: "root",
+		Auth: []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		// The rescue system is throwaway and only trusts our ephemeral key
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         30 * time.Second,
+	}
+	var client *ssh.Client
+	err := util.WaitUntilReady(5*time.Minute, 10*time.Second, func() (bool, error) {
+		var err error
+		client, err = ssh.Dial("tcp", net.JoinHostPort(ip, "22"), config)
+		if err != nil {
+			plog.Debugf("Connecting to rescue system: %v", err)
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil {
+		return fmt.Errorf("connecting to rescue system: %v", err)
+	}
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+	session.Stdin = r
+	cmd := "dd of=/dev/sda bs=4M && sync"
+	if compressed {
+		cmd = "xz -dc | " + cmd
+	}
+	if out, err := session.CombinedOutput(cmd); err != nil {
+		return fmt.Errorf("%v: %s", err, out)
+	}
+	return nil
+}
+
+// ListImages returns the snapshots matching the label selector.
+func (a *API) ListImages(selector string) ([]Image, error) {
+	var images []Image
+	path := "/images?type=snapshot&per_page=50&label_selector=" + url.QueryEscape(selector)
+	err := a.list(path, func(raw json.RawMessage) error {
+		var resp struct {
+			Images []Image `json:"images"`
+		}
+		if err := json.Unmarshal(raw, &resp); err != nil {
+			return err
+		}
+		images = append(images, resp.Images...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+// DeleteImage deletes the snapshot, succeeding if it doesn't exist.
+func (a *API) DeleteImage(id int64) error {
+	err := a.request(http.MethodDelete, "/images/"+strconv.FormatInt(id, 10), nil, nil)
+	if err != nil && !isNotFound(err) {
+		return err
+	}
+	return nil
+}
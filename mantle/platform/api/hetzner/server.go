@@ -0,0 +1,220 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hetzner
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/coreos/coreos-assembler/mantle/platform"
+	"github.com/coreos/coreos-assembler/mantle/util"
+)
+
+type Server struct {
+	ID        int64             `json:"id"`
+	Name      string            `json:"name"`
+	Status    string            `json:"status"`
+	Created   time.Time         `json:"created"`
+	Labels    map[string]string `json:"labels"`
+	PublicNet struct {
+		IPv4 struct {
+			IP string `json:"ip"`
+		} `json:"ipv4"`
+	} `json:"public_net"`
+	PrivateNet []struct {
+		IP string `json:"ip"`
+	} `json:"private_net"`
+}
+
+// PublicIP returns the server's public IPv4 address.
+func (s *Server) PublicIP() string {
+	return s.PublicNet.IPv4.IP
+}
+
+// PrivateIP returns the server's address on its first private network, or
+// its public address if it has none.
+func (s *Server) PrivateIP() string {
+	if len(s.PrivateNet) > 0 {
+		return s.PrivateNet[0].IP
+	}
+	return s.PublicIP()
+}
+
+// CreateServer creates a server of the image in the options, and waits for
+// it to run. Its labels are tags, sanitized with platform.SanitizeTags.
+func (a *API) CreateServer(name, userdata string, sshKeyID int64, tags map[string]string) (*Server, error) {
+	return a.createServer(name, a.opts.Image, userdata, sshKeyID, true, tags)
+}
+
+func (a *API) createServer(name, image, userdata string, sshKeyID int64, start bool, tags map[string]string) (*Server, error) {
+	labels := platform.SanitizeTags(tags)
+	for k, v := range mantleLabels() {
+		labels[k] = v
+	}
+	body := map[string]interface{}{
+		"name":               name,
+		"server_type":        a.opts.ServerType,
+		"image":              image,
+		"location":           a.opts.Location,
+		"labels":             labels,
+		"start_after_create": start,
+	}
+	if userdata != "" {
+		body["user_data"] = userdata
+	}
+	if sshKeyID != 0 {
+		body["ssh_keys"] = []int64{sshKeyID}
+	}
+	var resp struct {
+		Server Server `json:"server"`
+		Action action `json:"action"`
+	}
+	if err := a.request(http.MethodPost, "/servers", body, &resp); err != nil {
+		return nil, fmt.Errorf("creating server: %v", err)
+	}
+	server := resp.Server
+
+	err := a.waitForAction(resp.Action)
+	if err == nil && start {
+		err = util.WaitUntilReady(10*time.Minute, 5*time.Second, func() (bool, error) {
+			s, err := a.GetServer(server.ID)
+			if err != nil {
+				return false, err
+			}
+			server = *s
+			return server.Status == "running", nil
+		})
+	}
+	if err != nil {
+		if errDelete := a.DeleteServer(server.ID); errDelete != nil {
+			return nil, fmt.Errorf("deleting server: %v after waiting for it to run: %v", errDelete, err)
+		}
+		return nil, fmt.Errorf("waiting for server to run: %v", err)
+	}
+	return &server, nil
+}
+
+func (a *API) GetServer(id int64) (*Server, error) {
+	var resp struct {
+		Server Server `json:"server"`
+	}
+	if err := a.request(http.MethodGet, "/servers/"+strconv.FormatInt(id, 10), nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Server, nil
+}
+
+// DeleteServer deletes the server, succeeding if it doesn't exist.
+func (a *API) DeleteServer(id int64) error {
+	err := a.request(http.MethodDelete, "/servers/"+strconv.FormatInt(id, 10), nil, nil)
+	if err != nil && !isNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// serverAction runs an action, such as "poweroff", on the server and waits
+// for it to complete. The response is also decoded into result, if non-nil.
+func (a *API) serverAction(id int64, name string, body, result interface{}) error {
+	var resp struct {
+		Action action `json:"action"`
+	}
+	path := fmt.Sprintf("/servers/%d/actions/%s", id, name)
+	var raw json.RawMessage
+	if err := a.request(http.MethodPost, path, body, &raw); err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	if result != nil {
+		if err := json.Unmarshal(raw, result); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	if err := a.waitForAction(resp.Action); err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	return nil
+}
+
+// GC deletes the servers and SSH keys mantle created which opts selects
+// as garbage. Tags are matched against their labels.
+func (a *API) GC(opts platform.GCOptions) error {
+	// Everything is listed before anything is deleted, so that deletions
+	// don't shift later pages.
+	var servers []Server
+	err := a.list("/servers?label_selector=created-by%3Dmantle&per_page=50", func(raw json.RawMessage) error {
+		var resp struct {
+			Servers []Server `json:"servers"`
+		}
+		if err := json.Unmarshal(raw, &resp); err != nil {
+			return err
+		}
+		servers = append(servers, resp.Servers...)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("listing servers: %v", err)
+	}
+	for _, server := range servers {
+		if !opts.IsGarbage(server.Created, server.Labels) {
+			continue
+		}
+		id := server.ID
+		err := opts.Collect("server", strconv.FormatInt(id, 10), server.Created, func() error {
+			return a.DeleteServer(id)
+		})
+		if err != nil {
+			return fmt.Errorf("deleting server %d: %v", id, err)
+		}
+	}
+
+	type sshKey struct {
+		ID      int64             `json:"id"`
+		Created time.Time         `json:"created"`
+		Labels  map[string]string `json:"labels"`
+	}
+	var keys []sshKey
+	err = a.list("/ssh_keys?label_selector=created-by%3Dmantle&per_page=50", func(raw json.RawMessage) error {
+		var resp struct {
+			SSHKeys []sshKey `json:"ssh_keys"`
+		}
+		if err := json.Unmarshal(raw, &resp); err != nil {
+			return err
+		}
+		keys = append(keys, resp.SSHKeys...)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("listing SSH keys: %v", err)
+	}
+	for _, key := range keys {
+		if !opts.IsGarbage(key.Created, key.Labels) {
+			continue
+		}
+		id := key.ID
+		err := opts.Collect("SSH key", strconv.FormatInt(id, 10), key.Created, func() error {
+			return a.DeleteKey(id)
+		})
+		if err != nil {
+			return fmt.Errorf("deleting SSH key %d: %v", id, err)
+		}
+	}
+	return nil
+}
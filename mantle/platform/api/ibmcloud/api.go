@@ -18,8 +18,11 @@
 package ibmcloud
 
 import (
+	"encoding/json"
 	"fmt"
 	gohttp "net/http"
+	"os"
+	"path/filepath"
 
 	"github.com/IBM-Cloud/bluemix-go"
 	"github.com/IBM-Cloud/bluemix-go/api/resource/resourcev1/catalog"
@@ -55,6 +58,23 @@ type Options struct {
 
 	// Cloud Object storage name to use
 	CloudObjectStorage string
+
+	// PowerVSServiceInstance is the GUID of the Power Virtual Server
+	// workspace to create instances in
+	PowerVSServiceInstance string
+	// PowerVSImage is the ID or name of the image to boot
+	PowerVSImage string
+	// PowerVSNetwork is the ID or name of the network the instances are
+	// reached through; if empty, a public network named "kola" is used.
+	PowerVSNetwork string
+	// PowerVSSysType is the machine type, such as s922 or s1022
+	PowerVSSysType string
+	// PowerVSProcType is shared, capped or dedicated
+	PowerVSProcType   string
+	PowerVSProcessors float64
+	// PowerVSMemory is the memory of the instances in GiB
+	PowerVSMemory      float64
+	PowerVSStorageType string
 }
 
 // Client used to interact with the IBMCloud apis
@@ -70,9 +90,38 @@ type Client struct {
 type API struct {
 	client   *Client
 	s3client *S3Client
+	powervs  *PowerVSClient
 	opts     *Options
 }
 
+type apiKeyFile struct {
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	CreatedAt   string `json:"createdAt,omitempty"`
+	ApiKey      string `json:"apikey"`
+}
+
+// LoadAPIKey reads the API key from a file downloaded when creating it, by
+// default ~/.bluemix/apikey.json
+func LoadAPIKey(credentialsFile string) (string, error) {
+	if credentialsFile == "" {
+		homedir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("getting home directory: %v", err)
+		}
+		credentialsFile = filepath.Join(homedir, ".bluemix/apikey.json")
+	}
+	data, err := os.ReadFile(credentialsFile)
+	if err != nil {
+		return "", fmt.Errorf("reading credentials file: %v", err)
+	}
+	var key apiKeyFile
+	if err := json.Unmarshal(data, &key); err != nil {
+		return "", fmt.Errorf("parsing credentials file %s: %v", credentialsFile, err)
+	}
+	return key.ApiKey, nil
+}
+
 // New creates an IBMCloud API wrapper
 func New(opts *Options) (*API, error) {
 	c := &Client{}
@@ -0,0 +1,472 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The Power Virtual Server API is used directly, as documented at
+// https://cloud.ibm.com/apidocs/power-cloud, since there's no vendored SDK
+// for it.
+
+package ibmcloud
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+
+	"github.com/coreos/coreos-assembler/mantle/platform"
+	"github.com/coreos/coreos-assembler/mantle/util"
+)
+
+// PowerVSClient - to interface with a Power Virtual Server workspace, the
+// service instance holding the images, networks and instances
+type PowerVSClient struct {
+	service *core.BaseService
+	// crn of the workspace, which every request has to carry
+	crn string
+	// cloudInstanceID is the GUID of the workspace
+	cloudInstanceID string
+	// tenantID is the account ID, which owns the SSH keys
+	tenantID string
+	zone     string
+}
+
+// PowerVSInstance is a Power Virtual Server instance.
+type PowerVSInstance struct {
+	ID           string                   `json:"pvmInstanceID"`
+	Name         string                   `json:"serverName"`
+	Status       string                   `json:"status"`
+	CreationDate time.Time                `json:"creationDate"`
+	Networks     []PowerVSInstanceNetwork `json:"networks"`
+	Fault        *struct {
+		Message string `json:"message"`
+	} `json:"fault,omitempty"`
+}
+
+// PowerVSInstanceNetwork is a network interface of an instance.
+type PowerVSInstanceNetwork struct {
+	NetworkID  string `json:"networkID"`
+	IPAddress  string `json:"ipAddress"`
+	ExternalIP string `json:"externalIP"`
+	Type       string `json:"type"`
+}
+
+// PowerVSImage is an image in a Power Virtual Server workspace.
+type PowerVSImage struct {
+	ID           string    `json:"imageID"`
+	Name         string    `json:"name"`
+	State        string    `json:"state"`
+	CreationDate time.Time `json:"creationDate"`
+}
+
+// PowerVSInstanceOptions describe an instance to create.
+type PowerVSInstanceOptions struct {
+	Name    string
+	ImageID string
+	// NetworkID is the network the instance is reachable by SSH over
+	NetworkID string
+	KeyName   string
+	UserData  string
+	// SysType is the machine type, such as s922 or s1022
+	SysType string
+	// ProcType is shared, capped or dedicated
+	ProcType   string
+	Processors float64
+	// MemoryGiB is the instance's memory, in GiB
+	MemoryGiB   float64
+	StorageType string
+}
+
+// PowerVSImportOptions say where in Cloud Object Storage an image to import
+// is, and how to reach it.
+type PowerVSImportOptions struct {
+	// Region is the Cloud Object Storage region of the bucket
+	Region string
+	Bucket string
+	// Object is the name of the image in the bucket, a .ova.gz, .tar.gz
+	// or .ova file
+	Object string
+	// AccessKey and SecretKey are HMAC credentials for the bucket; if
+	// they're unset, the bucket must be public.
+	AccessKey   string
+	SecretKey   string
+	StorageType string
+}
+
+// The PowerVS endpoints are regional, and a workspace is in a zone of one
+// of them.
+var powerVSZoneRegions = map[string]string{
+	"dal":      "us-south",
+	"us-south": "us-south",
+	"wdc":      "us-east",
+	"us-east":  "us-east",
+	"eu-de":    "eu-de",
+	"fra":      "eu-de",
+	"lon":      "lon",
+	"mad":      "mad",
+	"mon":      "mon",
+	"osa":      "osa",
+	"sao":      "sao",
+	"syd":      "syd",
+	"tok":      "tok",
+	"tor":      "tor",
+	"che":      "che",
+}
+
+func powerVSRegion(zone string) (string, error) {
+	// Zones are the region with a number, such as dal10 or eu-de-1
+	prefix := strings.TrimRight(zone, "0123456789")
+	prefix = strings.TrimSuffix(prefix, "-")
+	if region, ok := powerVSZoneRegions[prefix]; ok {
+		return region, nil
+	}
+	return "", fmt.Errorf("unknown Power Virtual Server zone %q", zone)
+}
+
+// NewPowerVSClient looks up the Power Virtual Server workspace with the
+// given GUID and creates a client for it
+func (a *API) NewPowerVSClient(serviceInstanceID string) error {
+	instance, err := a.client.ResourceClientV2.GetInstance(serviceInstanceID)
+	if err != nil {
+		return fmt.Errorf("getting Power Virtual Server workspace %s: %v", serviceInstanceID, err)
+	}
+	region, err := powerVSRegion(instance.Crn.Region)
+	if err != nil {
+		return err
+	}
+
+	authenticator, err := core.NewIamAuthenticatorBuilder().SetApiKey(a.opts.ApiKey).Build()
+	if err != nil {
+		return fmt.Errorf("creating IAM authenticator: %v", err)
+	}
+	service, err := core.NewBaseService(&core.ServiceOptions{
+		URL:           fmt.Sprintf("https://%s.power-iaas.cloud.ibm.com", region),
+		Authenticator: authenticator,
+	})
+	if err != nil {
+		return fmt.Errorf("creating Power Virtual Server client: %v", err)
+	}
+	service.EnableRetries(3, 30*time.Second)
+
+	a.powervs = &PowerVSClient{
+		service:         service,
+		crn:             instance.Crn.String(),
+		cloudInstanceID: serviceInstanceID,
+		tenantID:        instance.Crn.Scope,
+		zone:            instance.Crn.Region,
+	}
+	plog.Infof("Using Power Virtual Server workspace %s in %s", instance.Name, a.powervs.zone)
+	return nil
+}
+
+// powerVSRequest makes a request to the workspace's API; path is relative
+// to the workspace, or to the account if it starts with "tenants/".
+func (a *API) powerVSRequest(method, path string, body, result interface{}) (*core.DetailedResponse, error) {
+	c := a.powervs
+	if c == nil {
+		return nil, fmt.Errorf("Power Virtual Server client not initialized")
+	}
+	prefix := "/pcloud/v1/cloud-instances/" + c.cloudInstanceID + "/"
+	if strings.HasPrefix(path, "tenants/") {
+		prefix = "/pcloud/v1/"
+	}
+	builder := core.NewRequestBuilder(method)
+	if _, err := builder.ResolveRequestURL(c.service.GetServiceURL(), prefix+path, nil); err != nil {
+		return nil, err
+	}
+	builder.AddHeader("CRN", c.crn)
+	builder.AddHeader("Accept", "application/json")
+	if body != nil {
+		if _, err := builder.SetBodyContentJSON(body); err != nil {
+			return nil, err
+		}
+	}
+	req, err := builder.Build()
+	if err != nil {
+		return nil, err
+	}
+	return c.service.Request(req, result)
+}
+
+func isPowerVSNotFound(resp *core.DetailedResponse) bool {
+	return resp != nil && resp.StatusCode == http.StatusNotFound
+}
+
+// CreatePowerVSInstance creates an instance and waits for it to become
+// active.
+func (a *API) CreatePowerVSInstance(opts PowerVSInstanceOptions) (*PowerVSInstance, error) {
+	body := map[string]interface{}{
+		"serverName": opts.Name,
+		"imageID":    opts.ImageID,
+		"networks": []map[string]string{
+			{"networkID": opts.NetworkID},
+		},
+		"sysType":    opts.SysType,
+		"procType":   opts.ProcType,
+		"processors": opts.Processors,
+		"memory":     opts.MemoryGiB,
+		// Ignition reads it from the config drive
+		"userData": base64.StdEncoding.EncodeToString([]byte(opts.UserData)),
+	}
+	if opts.KeyName != "" {
+		body["keyPairName"] = opts.KeyName
+	}
+	if opts.StorageType != "" {
+		body["storageType"] = opts.StorageType
+	}
+	var created []PowerVSInstance
+	if _, err := a.powerVSRequest(http.MethodPost, "pvm-instances", body, &created); err != nil {
+		return nil, fmt.Errorf("creating instance: %v", err)
+	}
+	if len(created) != 1 {
+		return nil, fmt.Errorf("expected 1 instance to be created, got %d", len(created))
+	}
+	id := created[0].ID
+
+	var instance *PowerVSInstance
+	err := util.WaitUntilReady(20*time.Minute, 15*time.Second, func() (bool, error) {
+		var err error
+		instance, err = a.GetPowerVSInstance(id)
+		if err != nil {
+			return false, err
+		}
+		if instance.Status == "ERROR" {
+			msg := ""
+			if instance.Fault != nil {
+				msg = instance.Fault.Message
+			}
+			return false, fmt.Errorf("instance reported ERROR status: %s", msg)
+		}
+		return instance.Status == "ACTIVE", nil
+	})
+	if err != nil {
+		if errDelete := a.DeletePowerVSInstance(id); errDelete != nil {
+			return nil, fmt.Errorf("deleting instance: %v after waiting for it to run: %v", errDelete, err)
+		}
+		return nil, fmt.Errorf("waiting for instance to run: %v", err)
+	}
+	return instance, nil
+}
+
+// GetPowerVSInstance returns the instance with the given ID.
+func (a *API) GetPowerVSInstance(id string) (*PowerVSInstance, error) {
+	var instance PowerVSInstance
+	if _, err := a.powerVSRequest(http.MethodGet, "pvm-instances/"+id, nil, &instance); err != nil {
+		return nil, err
+	}
+	return &instance, nil
+}
+
+// ListPowerVSInstances returns the instances in the workspace.
+func (a *API) ListPowerVSInstances() ([]PowerVSInstance, error) {
+	var list struct {
+		Instances []PowerVSInstance `json:"pvmInstances"`
+	}
+	if _, err := a.powerVSRequest(http.MethodGet, "pvm-instances", nil, &list); err != nil {
+		return nil, err
+	}
+	return list.Instances, nil
+}
+
+// DeletePowerVSInstance deletes the instance, succeeding if it's already
+// gone.
+func (a *API) DeletePowerVSInstance(id string) error {
+	resp, err := a.powerVSRequest(http.MethodDelete, "pvm-instances/"+id, nil, nil)
+	if err != nil && !isPowerVSNotFound(resp) {
+		return err
+	}
+	return nil
+}
+
+// ImportPowerVSImage imports an image from Cloud Object Storage, waits for
+// the import to complete, and returns the image's ID.
+func (a *API) ImportPowerVSImage(name string, opts PowerVSImportOptions) (string, error) {
+	body := map[string]interface{}{
+		"imageName":     name,
+		"bucketName":    opts.Bucket,
+		"imageFilename": opts.Object,
+		"region":        opts.Region,
+		"bucketAccess":  "public",
+	}
+	if opts.AccessKey != "" {
+		body["bucketAccess"] = "private"
+		body["accessKey"] = opts.AccessKey
+		body["secretKey"] = opts.SecretKey
+	}
+	if opts.StorageType != "" {
+		body["storageType"] = opts.StorageType
+	}
+	var job struct {
+		ID string `json:"id"`
+	}
+	if _, err := a.powerVSRequest(http.MethodPost, "cos-images", body, &job); err != nil {
+		return "", fmt.Errorf("starting image import: %v", err)
+	}
+
+	plog.Infof("Waiting for import job %s of image %s", job.ID, name)
+	err := util.WaitUntilReady(2*time.Hour, 30*time.Second, func() (bool, error) {
+		var status struct {
+			Status struct {
+				State   string `json:"state"`
+				Message string `json:"message"`
+			} `json:"status"`
+		}
+		if _, err := a.powerVSRequest(http.MethodGet, "jobs/"+job.ID, nil, &status); err != nil {
+			return false, err
+		}
+		switch status.Status.State {
+		case "completed":
+			return true, nil
+		case "failed":
+			return false, fmt.Errorf("image import failed: %s", status.Status.Message)
+		}
+		return false, nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	image, err := a.FindPowerVSImage(name)
+	if err != nil {
+		return "", err
+	}
+	if image == nil {
+		return "", fmt.Errorf("image %s not found after import", name)
+	}
+	return image.ID, nil
+}
+
+// ListPowerVSImages returns the images in the workspace.
+func (a *API) ListPowerVSImages() ([]PowerVSImage, error) {
+	var list struct {
+		Images []PowerVSImage `json:"images"`
+	}
+	if _, err := a.powerVSRequest(http.MethodGet, "images", nil, &list); err != nil {
+		return nil, err
+	}
+	return list.Images, nil
+}
+
+// FindPowerVSImage returns the image with the given ID or name, or nil if
+// there's none.
+func (a *API) FindPowerVSImage(image string) (*PowerVSImage, error) {
+	images, err := a.ListPowerVSImages()
+	if err != nil {
+		return nil, err
+	}
+	for _, img := range images {
+		if img.ID == image || img.Name == image {
+			return &img, nil
+		}
+	}
+	return nil, nil
+}
+
+// DeletePowerVSImage deletes the image, succeeding if it's already gone.
+func (a *API) DeletePowerVSImage(id string) error {
+	resp, err := a.powerVSRequest(http.MethodDelete, "images/"+id, nil, nil)
+	if err != nil && !isPowerVSNotFound(resp) {
+		return err
+	}
+	return nil
+}
+
+// GetPowerVSNetwork returns the ID of the network with the given ID or
+// name. If network is empty, a public network named "kola", through which
+// instances can be reached over SSH, is used, and created if it doesn't
+// exist.
+func (a *API) GetPowerVSNetwork(network string) (string, error) {
+	var list struct {
+		Networks []struct {
+			ID   string `json:"networkID"`
+			Name string `json:"name"`
+		} `json:"networks"`
+	}
+	if _, err := a.powerVSRequest(http.MethodGet, "networks", nil, &list); err != nil {
+		return "", fmt.Errorf("listing networks: %v", err)
+	}
+	name := network
+	if name == "" {
+		name = "kola"
+	}
+	for _, net := range list.Networks {
+		if net.ID == name || net.Name == name {
+			return net.ID, nil
+		}
+	}
+	if network != "" {
+		return "", fmt.Errorf("network %q not found", network)
+	}
+
+	plog.Infof("Creating public network %s", name)
+	var created struct {
+		ID string `json:"networkID"`
+	}
+	body := map[string]interface{}{
+		"name": name,
+		"type": "pub-vlan",
+	}
+	if _, err := a.powerVSRequest(http.MethodPost, "networks", body, &created); err != nil {
+		return "", fmt.Errorf("creating network: %v", err)
+	}
+	return created.ID, nil
+}
+
+// AddPowerVSKey adds an SSH key to the account, for instances to accept.
+func (a *API) AddPowerVSKey(name, key string) error {
+	body := map[string]string{
+		"name":   name,
+		"sshKey": key,
+	}
+	_, err := a.powerVSRequest(http.MethodPost, "tenants/"+a.powervs.tenantID+"/sshkeys", body, nil)
+	return err
+}
+
+// DeletePowerVSKey deletes an SSH key from the account.
+func (a *API) DeletePowerVSKey(name string) error {
+	resp, err := a.powerVSRequest(http.MethodDelete, "tenants/"+a.powervs.tenantID+"/sshkeys/"+name, nil, nil)
+	if err != nil && !isPowerVSNotFound(resp) {
+		return err
+	}
+	return nil
+}
+
+// PowerVSGC deletes the instances created by kola, whose names are from
+// util.ResourceName or, if older, start with "kola-", which opts selects
+// as garbage. Instances can't be tagged, so they are only garbage if opts
+// has no tags.
+func (a *API) PowerVSGC(opts platform.GCOptions) error {
+	instances, err := a.ListPowerVSInstances()
+	if err != nil {
+		return fmt.Errorf("listing instances: %v", err)
+	}
+	for _, instance := range instances {
+		if info, ok := util.ParseResourceName(instance.Name); !(ok && info.FromMantle()) && !strings.HasPrefix(instance.Name, "kola-") {
+			continue
+		}
+		if !opts.IsGarbage(instance.CreationDate, nil) {
+			continue
+		}
+		id := instance.ID
+		err := opts.Collect("instance", id, instance.CreationDate, func() error {
+			return a.DeletePowerVSInstance(id)
+		})
+		if err != nil {
+			return fmt.Errorf("deleting instance %s: %v", id, err)
+		}
+	}
+	return nil
+}
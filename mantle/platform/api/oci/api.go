@@ -0,0 +1,261 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The Oracle Cloud Infrastructure REST APIs are used directly, with requests
+// signed as described at
+// https://docs.oracle.com/en-us/iaas/Content/API/Concepts/signingrequests.htm,
+// since the OCI Go SDK isn't vendored.
+
+package oci
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coreos/pkg/capnslog"
+
+	"github.com/coreos/coreos-assembler/mantle/auth"
+	"github.com/coreos/coreos-assembler/mantle/platform"
+)
+
+var (
+	plog = capnslog.NewPackageLogger("github.com/coreos/coreos-assembler/mantle", "platform/api/oci")
+)
+
+type Options struct {
+	*platform.Options
+
+	// Config file. Defaults to $HOME/.oci/config.
+	ConfigPath string
+	// Profile name, by default DEFAULT
+	Profile string
+
+	// Region (e.g. "us-ashburn-1"), by default the profile's
+	Region string
+	// CompartmentID is the OCID of the compartment to create resources
+	// in, by default the root compartment of the tenancy
+	CompartmentID string
+
+	// Image OCID
+	Image string
+	// Shape of the instances (e.g. "VM.Standard.E4.Flex")
+	Shape string
+	// OCPUs and MemoryGB size instances of flexible shapes
+	OCPUs    float64
+	MemoryGB float64
+	// SubnetID is the OCID of the subnet instances are attached to; it
+	// must allow SSH from the host running kola.
+	SubnetID string
+	// AvailabilityDomain to create instances in, by default the
+	// region's first
+	AvailabilityDomain string
+}
+
+type API struct {
+	opts   *Options
+	client *http.Client
+
+	keyID string
+	key   *rsa.PrivateKey
+}
+
+// New creates an API for the profile given in the options.
+func New(opts *Options) (*API, error) {
+	profiles, err := auth.ReadOCIConfig(opts.ConfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read OCI config: %v", err)
+	}
+	if opts.Profile == "" {
+		opts.Profile = "DEFAULT"
+	}
+	profile, ok := profiles[opts.Profile]
+	if !ok {
+		return nil, fmt.Errorf("no such profile %q", opts.Profile)
+	}
+	if opts.Region == "" {
+		opts.Region = profile.Region
+	}
+	if opts.Region == "" {
+		return nil, fmt.Errorf("no region given or configured for profile %q", opts.Profile)
+	}
+	if opts.CompartmentID == "" {
+		opts.CompartmentID = profile.Tenancy
+	}
+
+	key, err := readPrivateKey(profile.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &API{
+		opts:   opts,
+		client: &http.Client{},
+		keyID:  fmt.Sprintf("%s/%s/%s", profile.Tenancy, profile.User, profile.Fingerprint),
+		key:    key,
+	}, nil
+}
+
+func readPrivateKey(path string) (*rsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading API signing key: %v", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in API signing key %s", path)
+	}
+	if _, encrypted := block.Headers["Proc-Type"]; encrypted || block.Type == "ENCRYPTED PRIVATE KEY" {
+		return nil, fmt.Errorf("API signing key %s is encrypted, which isn't supported", path)
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing API signing key %s: %v", path, err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("API signing key %s isn't an RSA key", path)
+	}
+	return key, nil
+}
+
+// Error is an error response from an OCI API.
+type Error struct {
+	StatusCode int
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+func isNotFound(err error) bool {
+	apiErr, ok := err.(*Error)
+	return ok && apiErr.StatusCode == http.StatusNotFound
+}
+
+func (a *API) endpoint(service string) string {
+	return fmt.Sprintf("https://%s.%s.oraclecloud.com", service, a.opts.Region)
+}
+
+// request makes a signed request to the service, such as "iaas", sending
+// body as JSON and decoding the response into result, if they're non-nil.
+// If result is a *[]byte, the response is returned as is.
+func (a *API) request(method, service, path string, body, result interface{}) error {
+	var data []byte
+	if body != nil {
+		var err error
+		data, err = json.Marshal(body)
+		if err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, a.endpoint(service)+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return a.do(req, data, true, result)
+}
+
+// do signs and sends the request. If signBody is set, its body, which must
+// be data, is signed too, as all but object uploads must be.
+func (a *API) do(req *http.Request, data []byte, signBody bool, result interface{}) error {
+	if err := a.sign(req, data, signBody); err != nil {
+		return err
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		apiErr := &Error{StatusCode: resp.StatusCode}
+		if body, err := io.ReadAll(resp.Body); err == nil {
+			_ = json.Unmarshal(body, apiErr)
+		}
+		return apiErr
+	}
+	switch r := result.(type) {
+	case nil:
+		return nil
+	case *[]byte:
+		*r, err = io.ReadAll(resp.Body)
+		return err
+	default:
+		return json.NewDecoder(resp.Body).Decode(result)
+	}
+}
+
+func (a *API) sign(req *http.Request, data []byte, signBody bool) error {
+	req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	headers := []string{"(request-target)", "date", "host"}
+	if signBody && (req.Method == http.MethodPost || req.Method == http.MethodPut || req.Method == http.MethodPatch) {
+		sum := sha256.Sum256(data)
+		req.Header.Set("X-Content-SHA256", base64.StdEncoding.EncodeToString(sum[:]))
+		req.Header.Set("Content-Length", strconv.Itoa(len(data)))
+		if req.Header.Get("Content-Type") == "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		headers = append(headers, "x-content-sha256", "content-type", "content-length")
+	}
+
+	var lines []string
+	for _, h := range headers {
+		var value string
+		switch h {
+		case "(request-target)":
+			value = strings.ToLower(req.Method) + " " + req.URL.RequestURI()
+		case "host":
+			value = req.URL.Host
+		default:
+			value = req.Header.Get(h)
+		}
+		lines = append(lines, h+": "+value)
+	}
+	digest := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, a.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return fmt.Errorf("signing request: %v", err)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf(`Signature version="1",keyId="%s",algorithm="rsa-sha256",headers="%s",signature="%s"`,
+		a.keyID, strings.Join(headers, " "), base64.StdEncoding.EncodeToString(signature)))
+	return nil
+}
+
+// PreflightCheck checks that the credentials work.
+func (a *API) PreflightCheck() error {
+	_, err := a.GetNamespace()
+	return err
+}
@@ -0,0 +1,138 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oci
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The requests are the examples in the signing documentation, with their
+// signing strings spelled out; the date is whatever sign sets.
+const (
+	testKeyID = "ocid1.tenancy.oc1..aaaaaaaaba3pv6wkcr4jqae5f15p2b2m2yt2j6rx32uzr4h25vqstifsfdsq/ocid1.user.oc1..aaaaaaaat5nvwcna5j6aqzjcaty5eqbb6qt2jvpkanghtgdaqedqw3rynjq/20:3b:97:13:55:1c:5b:0d:d3:37:d8:50:4e:c5:3a:34"
+	testBody  = `{"compartmentId":"ocid1.compartment.oc1..aaaaaaaam3we6vgnherjq5q2idnccdflvjsnog7mlr6rtdb25gilchfeyjxa","instanceId":"ocid1.instance.oc1.phx.abuw4ljrlsfiqw6vzzxb43vyypt4pkodawglp3wqxjqofakrwvou52gb6s5a","volumeId":"ocid1.volume.oc1.phx.abyhqljrgvttnlx73nmrwfaux7kcvzfs3s66izvxf2h4lgvyndsdsnoiwr5q"}`
+)
+
+var authorizationRe = regexp.MustCompile(`^Signature version="1",keyId="([^"]+)",algorithm="rsa-sha256",headers="([^"]+)",signature="([^"]+)"$`)
+
+func TestSign(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &API{keyID: testKeyID, key: key}
+
+	for _, tt := range []struct {
+		name     string
+		method   string
+		url      string
+		body     string
+		signBody bool
+		headers  string
+		signing  string
+	}{
+		{
+			name:     "GET",
+			method:   http.MethodGet,
+			url:      "https://iaas.us-phoenix-1.oraclecloud.com/20160918/instances?availabilityDomain=Pjwf%3A%20PHX-AD-1&compartmentId=ocid1.compartment.oc1..aaaaaaaam3we6vgnherjq5q2idnccdflvjsnog7mlr6rtdb25gilchfeyjxa&displayName=TeamXInstances&volumeId=ocid1.volume.oc1.phx.abyhqljrgvttnlx73nmrwfaux7kcvzfs3s66izvxf2h4lgvyndsdsnoiwr5q",
+			signBody: true,
+			headers:  "(request-target) date host",
+			signing: "(request-target): get /20160918/instances?availabilityDomain=Pjwf%3A%20PHX-AD-1&compartmentId=ocid1.compartment.oc1..aaaaaaaam3we6vgnherjq5q2idnccdflvjsnog7mlr6rtdb25gilchfeyjxa&displayName=TeamXInstances&volumeId=ocid1.volume.oc1.phx.abyhqljrgvttnlx73nmrwfaux7kcvzfs3s66izvxf2h4lgvyndsdsnoiwr5q\n" +
+				"date: DATE\n" +
+				"host: iaas.us-phoenix-1.oraclecloud.com",
+		},
+		{
+			name:     "POST",
+			method:   http.MethodPost,
+			url:      "https://iaas.us-phoenix-1.oraclecloud.com/20160918/volumeAttachments",
+			body:     testBody,
+			signBody: true,
+			headers:  "(request-target) date host x-content-sha256 content-type content-length",
+			signing: "(request-target): post /20160918/volumeAttachments\n" +
+				"date: DATE\n" +
+				"host: iaas.us-phoenix-1.oraclecloud.com\n" +
+				"x-content-sha256: ZdmHX1UQowEjwSOxy+cC9i+qmHyKKQB0o7mImTm3X8M=\n" +
+				"content-type: application/json\n" +
+				"content-length: 297",
+		},
+		{
+			name:     "empty PUT",
+			method:   http.MethodPut,
+			url:      "https://iaas.us-phoenix-1.oraclecloud.com/20160918/instances/x",
+			signBody: true,
+			headers:  "(request-target) date host x-content-sha256 content-type content-length",
+			signing: "(request-target): put /20160918/instances/x\n" +
+				"date: DATE\n" +
+				"host: iaas.us-phoenix-1.oraclecloud.com\n" +
+				"x-content-sha256: 47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=\n" +
+				"content-type: application/json\n" +
+				"content-length: 0",
+		},
+		{
+			// Object uploads don't sign the body.
+			name:    "upload",
+			method:  http.MethodPut,
+			url:     "https://objectstorage.us-phoenix-1.oraclecloud.com/n/ns/b/bucket/o/image.qcow2",
+			body:    "image",
+			headers: "(request-target) date host",
+			signing: "(request-target): put /n/ns/b/bucket/o/image.qcow2\n" +
+				"date: DATE\n" +
+				"host: objectstorage.us-phoenix-1.oraclecloud.com",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.url, bytes.NewReader([]byte(tt.body)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := a.sign(req, []byte(tt.body), tt.signBody); err != nil {
+				t.Fatal(err)
+			}
+
+			date := req.Header.Get("Date")
+			if d, err := time.Parse(http.TimeFormat, date); err != nil || time.Since(d).Abs() > time.Minute {
+				t.Errorf("bad date %q", date)
+			}
+			m := authorizationRe.FindStringSubmatch(req.Header.Get("Authorization"))
+			if m == nil {
+				t.Fatalf("bad Authorization header %q", req.Header.Get("Authorization"))
+			}
+			if m[1] != testKeyID {
+				t.Errorf("got keyId %q, expected %q", m[1], testKeyID)
+			}
+			if m[2] != tt.headers {
+				t.Errorf("got headers %q, expected %q", m[2], tt.headers)
+			}
+			signature, err := base64.StdEncoding.DecodeString(m[3])
+			if err != nil {
+				t.Fatalf("decoding signature: %v", err)
+			}
+			digest := sha256.Sum256([]byte(strings.Replace(tt.signing, "DATE", date, 1)))
+			if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+				t.Errorf("signature doesn't match signing string: %v", err)
+			}
+		})
+	}
+}
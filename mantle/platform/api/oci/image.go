@@ -0,0 +1,117 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oci
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/coreos/coreos-assembler/mantle/util"
+)
+
+const computeAPI = "/20160918"
+
+// LaunchModes are how instances of an image are launched: NATIVE uses
+// NVMe and SR-IOV, PARAVIRTUALIZED virtio devices, EMULATED emulated
+// devices, and CUSTOM the image's settings.
+var LaunchModes = []string{"NATIVE", "PARAVIRTUALIZED", "EMULATED", "CUSTOM"}
+
+// ParseLaunchMode returns the launch mode named by s, in any case.
+func ParseLaunchMode(s string) (string, error) {
+	for _, mode := range LaunchModes {
+		if strings.EqualFold(s, mode) {
+			return mode, nil
+		}
+	}
+	return "", fmt.Errorf("invalid launch mode %q; expected one of %s", s, strings.Join(LaunchModes, ", "))
+}
+
+type Image struct {
+	ID             string `json:"id"`
+	DisplayName    string `json:"displayName"`
+	LifecycleState string `json:"lifecycleState"`
+	LaunchMode     string `json:"launchMode"`
+}
+
+// CreateImage imports the qcow2 image in the bucket as a custom image
+// whose instances are launched in launchMode, waits for the import to
+// complete, and returns the image's OCID.
+func (a *API) CreateImage(name, bucket, object, launchMode string) (string, error) {
+	namespace, err := a.GetNamespace()
+	if err != nil {
+		return "", err
+	}
+	body := map[string]interface{}{
+		"compartmentId": a.opts.CompartmentID,
+		"displayName":   name,
+		"launchMode":    launchMode,
+		"imageSourceDetails": map[string]string{
+			"sourceType":      "objectStorageTuple",
+			"namespaceName":   namespace,
+			"bucketName":      bucket,
+			"objectName":      object,
+			"sourceImageType": "QCOW2",
+		},
+		"freeformTags": map[string]string{
+			"createdBy": "mantle",
+		},
+	}
+	var image Image
+	if err := a.request(http.MethodPost, "iaas", computeAPI+"/images", body, &image); err != nil {
+		return "", fmt.Errorf("creating image: %v", err)
+	}
+
+	plog.Infof("Waiting for image %s to be imported", image.ID)
+	err = util.WaitUntilReady(time.Hour, 30*time.Second, func() (bool, error) {
+		img, err := a.GetImage(image.ID)
+		if err != nil {
+			return false, err
+		}
+		switch img.LifecycleState {
+		case "AVAILABLE":
+			return true, nil
+		case "DELETED", "DISABLED":
+			return false, fmt.Errorf("image is %s", img.LifecycleState)
+		}
+		return false, nil
+	})
+	if err != nil {
+		if errDelete := a.DeleteImage(image.ID); errDelete != nil {
+			return "", fmt.Errorf("deleting image: %v after waiting for import: %v", errDelete, err)
+		}
+		return "", fmt.Errorf("waiting for import: %v", err)
+	}
+	return image.ID, nil
+}
+
+// GetImage returns the image with the given OCID.
+func (a *API) GetImage(id string) (*Image, error) {
+	var image Image
+	if err := a.request(http.MethodGet, "iaas", computeAPI+"/images/"+id, nil, &image); err != nil {
+		return nil, err
+	}
+	return &image, nil
+}
+
+// DeleteImage deletes the image, succeeding if it doesn't exist.
+func (a *API) DeleteImage(id string) error {
+	err := a.request(http.MethodDelete, "iaas", computeAPI+"/images/"+id, nil, nil)
+	if err != nil && !isNotFound(err) {
+		return err
+	}
+	return nil
+}
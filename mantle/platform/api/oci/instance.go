@@ -0,0 +1,200 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oci
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/coreos/coreos-assembler/mantle/util"
+)
+
+type Instance struct {
+	ID             string `json:"id"`
+	DisplayName    string `json:"displayName"`
+	LifecycleState string `json:"lifecycleState"`
+
+	PublicIP  string `json:"-"`
+	PrivateIP string `json:"-"`
+}
+
+// CreateInstance launches an instance of the image in the options with the
+// given user data, SSH keys and freeform tags, and waits for it to run.
+func (a *API) CreateInstance(name, userdata string, sshKeys []string, tags map[string]string) (*Instance, error) {
+	if a.opts.SubnetID == "" {
+		return nil, fmt.Errorf("a subnet is required to create instances")
+	}
+	ad := a.opts.AvailabilityDomain
+	if ad == "" {
+		var err error
+		ad, err = a.defaultAvailabilityDomain()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	metadata := map[string]string{
+		"user_data": base64.StdEncoding.EncodeToString([]byte(userdata)),
+	}
+	if len(sshKeys) > 0 {
+		metadata["ssh_authorized_keys"] = strings.Join(sshKeys, "\n")
+	}
+	freeformTags := map[string]string{}
+	for k, v := range tags {
+		freeformTags[k] = v
+	}
+	freeformTags["createdBy"] = "mantle"
+	body := map[string]interface{}{
+		"availabilityDomain": ad,
+		"compartmentId":      a.opts.CompartmentID,
+		"displayName":        name,
+		"shape":              a.opts.Shape,
+		"sourceDetails": map[string]string{
+			"sourceType": "image",
+			"imageId":    a.opts.Image,
+		},
+		"createVnicDetails": map[string]interface{}{
+			"subnetId":       a.opts.SubnetID,
+			"assignPublicIp": true,
+		},
+		"metadata":     metadata,
+		"freeformTags": freeformTags,
+	}
+	if strings.HasSuffix(a.opts.Shape, ".Flex") {
+		body["shapeConfig"] = map[string]float64{
+			"ocpus":       a.opts.OCPUs,
+			"memoryInGBs": a.opts.MemoryGB,
+		}
+	}
+
+	var instance Instance
+	if err := a.request(http.MethodPost, "iaas", computeAPI+"/instances", body, &instance); err != nil {
+		return nil, fmt.Errorf("launching instance: %v", err)
+	}
+
+	err := util.WaitUntilReady(10*time.Minute, 10*time.Second, func() (bool, error) {
+		var inst Instance
+		if err := a.request(http.MethodGet, "iaas", computeAPI+"/instances/"+instance.ID, nil, &inst); err != nil {
+			return false, err
+		}
+		switch inst.LifecycleState {
+		case "RUNNING":
+			return true, nil
+		case "TERMINATING", "TERMINATED":
+			return false, fmt.Errorf("instance is %s", inst.LifecycleState)
+		}
+		return false, nil
+	})
+	if err == nil {
+		instance.PublicIP, instance.PrivateIP, err = a.instanceIPs(instance.ID)
+	}
+	if err != nil {
+		if errTerminate := a.TerminateInstance(instance.ID); errTerminate != nil {
+			return nil, fmt.Errorf("terminating instance: %v after waiting for it to run: %v", errTerminate, err)
+		}
+		return nil, fmt.Errorf("waiting for instance to run: %v", err)
+	}
+	return &instance, nil
+}
+
+// instanceIPs returns the public and private addresses of the instance's
+// primary VNIC.
+func (a *API) instanceIPs(id string) (string, string, error) {
+	var attachments []struct {
+		VnicID string `json:"vnicId"`
+	}
+	query := url.Values{
+		"compartmentId": {a.opts.CompartmentID},
+		"instanceId":    {id},
+	}
+	if err := a.request(http.MethodGet, "iaas", computeAPI+"/vnicAttachments?"+query.Encode(), nil, &attachments); err != nil {
+		return "", "", fmt.Errorf("listing VNIC attachments: %v", err)
+	}
+	for _, attachment := range attachments {
+		var vnic struct {
+			IsPrimary bool   `json:"isPrimary"`
+			PublicIP  string `json:"publicIp"`
+			PrivateIP string `json:"privateIp"`
+		}
+		if err := a.request(http.MethodGet, "iaas", computeAPI+"/vnics/"+attachment.VnicID, nil, &vnic); err != nil {
+			return "", "", fmt.Errorf("getting VNIC: %v", err)
+		}
+		if vnic.IsPrimary {
+			return vnic.PublicIP, vnic.PrivateIP, nil
+		}
+	}
+	return "", "", fmt.Errorf("instance %s has no primary VNIC", id)
+}
+
+func (a *API) defaultAvailabilityDomain() (string, error) {
+	var ads []struct {
+		Name string `json:"name"`
+	}
+	query := url.Values{"compartmentId": {a.opts.CompartmentID}}
+	if err := a.request(http.MethodGet, "identity", computeAPI+"/availabilityDomains?"+query.Encode(), nil, &ads); err != nil {
+		return "", fmt.Errorf("listing availability domains: %v", err)
+	}
+	if len(ads) == 0 {
+		return "", fmt.Errorf("no availability domains found in %s", a.opts.Region)
+	}
+	return ads[0].Name, nil
+}
+
+// TerminateInstance terminates the instance and deletes its boot volume.
+func (a *API) TerminateInstance(id string) error {
+	err := a.request(http.MethodDelete, "iaas", computeAPI+"/instances/"+id+"?preserveBootVolume=false", nil, nil)
+	if err != nil && !isNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// GetConsoleOutput returns the serial console output of the instance, by
+// capturing its console history.
+func (a *API) GetConsoleOutput(id string) (string, error) {
+	var history struct {
+		ID             string `json:"id"`
+		LifecycleState string `json:"lifecycleState"`
+	}
+	body := map[string]string{"instanceId": id}
+	if err := a.request(http.MethodPost, "iaas", computeAPI+"/instanceConsoleHistories", body, &history); err != nil {
+		return "", fmt.Errorf("capturing console history: %v", err)
+	}
+	defer func() {
+		if err := a.request(http.MethodDelete, "iaas", computeAPI+"/instanceConsoleHistories/"+history.ID, nil, nil); err != nil {
+			plog.Warningf("deleting console history %s: %v", history.ID, err)
+		}
+	}()
+
+	err := util.WaitUntilReady(2*time.Minute, 5*time.Second, func() (bool, error) {
+		if err := a.request(http.MethodGet, "iaas", computeAPI+"/instanceConsoleHistories/"+history.ID, nil, &history); err != nil {
+			return false, err
+		}
+		return history.LifecycleState == "SUCCEEDED", nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("waiting for console history: %v", err)
+	}
+
+	var data []byte
+	if err := a.request(http.MethodGet, "iaas", computeAPI+"/instanceConsoleHistories/"+history.ID+"/data?length=10000000", nil, &data); err != nil {
+		return "", fmt.Errorf("getting console history: %v", err)
+	}
+	return string(data), nil
+}
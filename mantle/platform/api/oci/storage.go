@@ -0,0 +1,105 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oci
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// GetNamespace returns the Object Storage namespace of the tenancy.
+func (a *API) GetNamespace() (string, error) {
+	var namespace string
+	if err := a.request(http.MethodGet, "objectstorage", "/n/", nil, &namespace); err != nil {
+		return "", fmt.Errorf("getting object storage namespace: %v", err)
+	}
+	return namespace, nil
+}
+
+func objectPath(namespace, bucket, object string) string {
+	return fmt.Sprintf("/n/%s/b/%s/o/%s", url.PathEscape(namespace), url.PathEscape(bucket), url.PathEscape(object))
+}
+
+// ObjectExists reports whether the object is in the bucket.
+func (a *API) ObjectExists(bucket, object string) (bool, error) {
+	namespace, err := a.GetNamespace()
+	if err != nil {
+		return false, err
+	}
+	err = a.request(http.MethodHead, "objectstorage", objectPath(namespace, bucket, object), nil, nil)
+	if isNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// UploadObject uploads the file to the bucket. Unless force is set, an
+// existing object of the same name is left alone.
+func (a *API) UploadObject(path, bucket, object string, force bool) error {
+	if !force {
+		exists, err := a.ObjectExists(bucket, object)
+		if err != nil {
+			return err
+		}
+		if exists {
+			plog.Infof("skipping upload since object exists and force was not set: %s %s", bucket, object)
+			return nil
+		}
+	}
+	namespace, err := a.GetNamespace()
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	plog.Infof("Uploading %s to %s/%s", path, bucket, object)
+	req, err := http.NewRequest(http.MethodPut, a.endpoint("objectstorage")+objectPath(namespace, bucket, object), f)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = info.Size()
+	req.Header.Set("Content-Type", "application/octet-stream")
+	// Object uploads are signed without their body
+	if err := a.do(req, nil, false, nil); err != nil {
+		return fmt.Errorf("uploading object: %v", err)
+	}
+	return nil
+}
+
+// DeleteObject deletes the object, succeeding if it doesn't exist.
+func (a *API) DeleteObject(bucket, object string) error {
+	namespace, err := a.GetNamespace()
+	if err != nil {
+		return err
+	}
+	err = a.request(http.MethodDelete, "objectstorage", objectPath(namespace, bucket, object), nil, nil)
+	if err != nil && !isNotFound(err) {
+		return fmt.Errorf("deleting object: %v", err)
+	}
+	return nil
+}
@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/coreos/pkg/capnslog"
@@ -58,11 +59,16 @@ type Options struct {
 
 	// Region (e.g. "regionOne")
 	Region string
-	// Instance Flavor ID
+	// Instance Flavor ID or name. If empty, the smallest flavor with
+	// enough memory for the machine is chosen; see SelectFlavor.
 	Flavor string
+	// MinRAM is the memory, in MiB, of the flavor chosen when Flavor is
+	// unset.
+	MinRAM int
 	// Image ID
 	Image string
-	// Network ID
+	// Network ID or name. If empty, the first active project network
+	// which isn't external is used.
 	Network string
 	// Domain ID
 	Domain string
@@ -81,6 +87,10 @@ type API struct {
 	imageClient        *gophercloud.ServiceClient
 	networkClient      *gophercloud.ServiceClient
 	blockStorageClient *gophercloud.ServiceClient
+
+	// flavors caches the flavors chosen by SelectFlavor
+	flavorsLock sync.Mutex
+	flavors     map[flavorRequirements]string
 }
 
 // LoadCloudsYAML defines how to load a clouds.yaml file.
@@ -156,6 +166,7 @@ func New(opts *Options) (*API, error) {
 		imageClient:        imageClient,
 		networkClient:      networkClient,
 		blockStorageClient: blockStorageClient,
+		flavors:            make(map[flavorRequirements]string),
 	}
 
 	if a.opts.Flavor != "" {
@@ -273,14 +284,29 @@ func (a *API) PreflightCheck() error {
 	return nil
 }
 
-func (a *API) CreateServer(name, sshKeyID, userdata string) (*Server, error) {
+// CreateServer creates a server. If no flavor was given in the options, the
+// smallest one with at least minRAM MiB of memory, and no less than
+// Options.MinRAM, is used.
+func (a *API) CreateServer(name, sshKeyID, userdata string, minRAM int, tags map[string]string) (*Server, error) {
+	flavorID := a.opts.Flavor
+	if flavorID == "" {
+		if a.opts.MinRAM > minRAM {
+			minRAM = a.opts.MinRAM
+		}
+		var err error
+		flavorID, err = a.SelectFlavor(minRAM, a.arch())
+		if err != nil {
+			return nil, fmt.Errorf("selecting flavor: %v", err)
+		}
+	}
+
 	networkID := a.opts.Network
 	if networkID == "" {
-		networks, err := a.getNetworks()
+		var err error
+		networkID, err = a.defaultNetwork()
 		if err != nil {
 			return nil, fmt.Errorf("getting network: %v", err)
 		}
-		networkID = networks[0].ID
 	}
 
 	securityGroup, err := a.getSecurityGroup()
@@ -290,14 +316,17 @@ func (a *API) CreateServer(name, sshKeyID, userdata string) (*Server, error) {
 
 	// Define options for the new instance. Use keypairs.CreateOptsExt
 	// to add our SSH key to the instance that way.
+	metadata := map[string]string{}
+	for k, v := range tags {
+		metadata[k] = v
+	}
+	metadata["CreatedBy"] = "mantle"
 	serverCreateOpts := keypairs.CreateOptsExt{
 		CreateOptsBuilder: servers.CreateOpts{
-			Name:      name,
-			FlavorRef: a.opts.Flavor,
-			ImageRef:  a.opts.Image,
-			Metadata: map[string]string{
-				"CreatedBy": "mantle",
-			},
+			Name:           name,
+			FlavorRef:      flavorID,
+			ImageRef:       a.opts.Image,
+			Metadata:       metadata,
 			SecurityGroups: []string{securityGroup},
 			Networks: []servers.Network{
 				{
@@ -540,7 +569,14 @@ func (a *API) GetConsoleOutput(id string) (string, error) {
 	return servers.ShowConsoleOutput(a.computeClient, id, servers.ShowConsoleOutputOpts{}).Extract()
 }
 
-func (a *API) UploadImage(name, path, arch, visibility string, protected bool) (string, error) {
+// UploadImage creates an image from the qcow2 file at path, or, if path is
+// empty, has Glance download it from url with its web-download import
+// method. If both are given, url is the fallback for clouds which don't
+// allow uploads, or which fail them.
+func (a *API) UploadImage(name, path, url, arch, visibility string, protected bool) (string, error) {
+	if path == "" && url == "" {
+		return "", fmt.Errorf("either a file or a URL is required")
+	}
 	// Get images.ImageVisibility from given visibility string.
 	// https://github.com/gophercloud/gophercloud/blob/9cf6777318713a51fbdb1238c19d1213712fd8b4/openstack/imageservice/v2/images/types.go#L52-L68
 	var imageVisibility images.ImageVisibility
@@ -556,7 +592,7 @@ func (a *API) UploadImage(name, path, arch, visibility string, protected bool) (
 	default:
 		return "", fmt.Errorf("Invalid given image visibility: %v", visibility)
 	}
-	image, err := images.Create(a.imageClient, images.CreateOpts{
+	createOpts := images.CreateOpts{
 		Name:            name,
 		ContainerFormat: "bare",
 		DiskFormat:      "qcow2",
@@ -565,7 +601,20 @@ func (a *API) UploadImage(name, path, arch, visibility string, protected bool) (
 		Properties: map[string]string{"architecture": arch},
 		Visibility: &imageVisibility,
 		Protected:  &protected,
-	}).Extract()
+	}
+
+	if path != "" {
+		id, err := a.uploadImageData(createOpts, path)
+		if err == nil || url == "" {
+			return id, err
+		}
+		plog.Warningf("Uploading image failed, falling back to web-download from %s: %v", url, err)
+	}
+	return a.importImage(createOpts, url)
+}
+
+func (a *API) uploadImageData(createOpts images.CreateOpts, path string) (string, error) {
+	image, err := images.Create(a.imageClient, createOpts).Extract()
 	if err != nil {
 		return "", fmt.Errorf("creating image: %v", err)
 	}
@@ -590,6 +639,55 @@ func (a *API) UploadImage(name, path, arch, visibility string, protected bool) (
 	return image.ID, nil
 }
 
+// importImage creates an image which Glance downloads from url with the
+// web-download method of its interoperable image import, and waits for it
+// to become active.
+// https://docs.openstack.org/glance/latest/admin/interoperable-image-import.html
+func (a *API) importImage(createOpts images.CreateOpts, url string) (string, error) {
+	image, err := images.Create(a.imageClient, createOpts).Extract()
+	if err != nil {
+		return "", fmt.Errorf("creating image: %v", err)
+	}
+
+	// The vendored gophercloud lacks the imageimport package
+	body := map[string]interface{}{
+		"method": map[string]string{
+			"name": "web-download",
+			"uri":  url,
+		},
+	}
+	_, err = a.imageClient.Post(a.imageClient.ServiceURL("images", image.ID, "import"), body, nil, &gophercloud.RequestOpts{
+		OkCodes: []int{202},
+	})
+	if err == nil {
+		err = util.WaitUntilReady(30*time.Minute, 10*time.Second, func() (bool, error) {
+			img, err := images.Get(a.imageClient, image.ID).Extract()
+			if err != nil {
+				return false, err
+			}
+			switch img.Status {
+			case images.ImageStatusActive:
+				return true, nil
+			case images.ImageStatusKilled, images.ImageStatusDeleted, images.ImageStatusPendingDelete:
+				return false, fmt.Errorf("image reported %s status", img.Status)
+			}
+			// Failed imports leave the image queued, and say so here
+			if failed, ok := img.Properties["os_glance_failed_import"].(string); ok && failed != "" {
+				return false, fmt.Errorf("import by %s failed", failed)
+			}
+			return false, nil
+		})
+	}
+	if err != nil {
+		if errDelete := a.DeleteImage(image.ID, true); errDelete != nil {
+			return "", fmt.Errorf("deleting image: %v after importing image: %v", errDelete, err)
+		}
+		return "", fmt.Errorf("importing image: %v", err)
+	}
+
+	return image.ID, nil
+}
+
 func (a *API) DeleteImage(imageID string, force bool) error {
 	// Detect if the image is protected from deletion. If protected
 	// and force=true then change protection status and delete it.
@@ -691,8 +789,10 @@ func (a *API) listServersWithMetadata(metadata map[string]string) ([]servers.Ser
 	return retServers, nil
 }
 
-func (a *API) GC(gracePeriod time.Duration) error {
-	threshold := time.Now().Add(-gracePeriod)
+// GC deletes the servers, keypairs and volumes mantle created which opts
+// selects as garbage. Tags are matched against their metadata; keypairs
+// have none.
+func (a *API) GC(opts platform.GCOptions) error {
 	// Clean up servers
 	servers, err := a.listServersWithMetadata(map[string]string{
 		"CreatedBy": "mantle",
@@ -701,11 +801,15 @@ func (a *API) GC(gracePeriod time.Duration) error {
 		return err
 	}
 	for _, server := range servers {
-		if strings.Contains(server.Status, "DELETED") || server.Created.After(threshold) {
+		if strings.Contains(server.Status, "DELETED") || !opts.IsGarbage(server.Created, server.Metadata) {
 			continue
 		}
 
-		if err := a.DeleteServer(server.ID); err != nil {
+		id := server.ID
+		err := opts.Collect("server", id, server.Created, func() error {
+			return a.DeleteServer(id)
+		})
+		if err != nil {
 			return fmt.Errorf("couldn't delete server %s: %v", server.ID, err)
 		}
 	}
@@ -715,8 +819,20 @@ func (a *API) GC(gracePeriod time.Duration) error {
 		return err
 	}
 	for _, keypair := range keypairs {
-		if strings.HasPrefix(keypair.Name, "kola-") {
-			if err := a.DeleteKey(keypair.Name); err != nil {
+		// Keypairs don't record when they were created, but their
+		// names do, unless they predate util.ResourceName
+		var created time.Time
+		if info, ok := util.ParseResourceName(keypair.Name); ok && info.FromMantle() {
+			created = info.Created
+		} else if !strings.HasPrefix(keypair.Name, "kola-") {
+			continue
+		}
+		if opts.IsGarbage(created, nil) {
+			name := keypair.Name
+			err := opts.Collect("keypair", name, created, func() error {
+				return a.DeleteKey(name)
+			})
+			if err != nil {
 				return fmt.Errorf("couldn't delete keypair %s: %v", keypair.Name, err)
 			}
 		}
@@ -732,15 +848,19 @@ func (a *API) GC(gracePeriod time.Duration) error {
 		if volume.Status != "available" && !strings.HasPrefix(volume.Status, "error") {
 			continue
 		}
-		// Skip volumes created after the threshold
-		if volume.CreatedAt.After(threshold) {
+		// Skip volumes created within the grace period
+		if !opts.IsGarbage(volume.CreatedAt, volume.Metadata) {
 			continue
 		}
 		// Skip volumes with names that do not start with "kola"
 		if !strings.HasPrefix(volume.Name, "kola") {
 			continue
 		}
-		if err := a.DeleteVolume(volume.ID); err != nil {
+		id := volume.ID
+		err := opts.Collect("volume", id, volume.CreatedAt, func() error {
+			return a.DeleteVolume(id)
+		})
+		if err != nil {
 			return fmt.Errorf("couldn't delete volume %s: %v", volume.ID, err)
 		}
 	}
@@ -0,0 +1,129 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openstack
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/flavors"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/networks"
+)
+
+// The flavor extra spec which restricts it to hosts of an architecture,
+// as baremetal flavors do.
+const flavorArchSpec = "capabilities:cpu_arch"
+
+type flavorRequirements struct {
+	minRAM int
+	arch   string
+}
+
+// SelectFlavor returns the ID of the smallest flavor with at least minRAM
+// MiB of memory which isn't restricted to hosts of an architecture other
+// than arch. Flavors are compared by memory, then vCPUs, then disk.
+func (a *API) SelectFlavor(minRAM int, arch string) (string, error) {
+	req := flavorRequirements{minRAM: minRAM, arch: arch}
+	a.flavorsLock.Lock()
+	defer a.flavorsLock.Unlock()
+	if id, ok := a.flavors[req]; ok {
+		return id, nil
+	}
+
+	pages, err := unwrapPages(flavors.ListDetail(a.computeClient, flavors.ListOpts{MinRAM: minRAM}), false)
+	if err != nil {
+		return "", fmt.Errorf("flavors: %v", err)
+	}
+	candidates, err := flavors.ExtractFlavors(pages)
+	if err != nil {
+		return "", fmt.Errorf("extracting flavors: %v", err)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		x, y := candidates[i], candidates[j]
+		if x.RAM != y.RAM {
+			return x.RAM < y.RAM
+		}
+		if x.VCPUs != y.VCPUs {
+			return x.VCPUs < y.VCPUs
+		}
+		return x.Disk < y.Disk
+	})
+
+	for _, flavor := range candidates {
+		if flavor.RAM < minRAM {
+			continue
+		}
+		if arch != "" {
+			ok, err := a.flavorSupportsArch(flavor.ID, arch)
+			if err != nil {
+				return "", err
+			}
+			if !ok {
+				continue
+			}
+		}
+		plog.Infof("Selected flavor %s (%d MiB, %d vCPUs)", flavor.Name, flavor.RAM, flavor.VCPUs)
+		a.flavors[req] = flavor.ID
+		return flavor.ID, nil
+	}
+	return "", fmt.Errorf("no flavor with at least %d MiB of memory for %s found", minRAM, arch)
+}
+
+// flavorSupportsArch reports whether the flavor isn't restricted to hosts
+// of an architecture other than arch.
+func (a *API) flavorSupportsArch(id, arch string) (bool, error) {
+	specs, err := flavors.ListExtraSpecs(a.computeClient, id).Extract()
+	if err != nil {
+		// Reading extra specs may be forbidden by policy
+		plog.Debugf("Couldn't get extra specs of flavor %s, assuming it suits any architecture: %v", id, err)
+		return true, nil
+	}
+	flavorArch, ok := specs[flavorArchSpec]
+	return !ok || flavorArch == arch, nil
+}
+
+// defaultNetwork returns the ID of the first active network which isn't
+// external, falling back to the first network if there's none.
+func (a *API) defaultNetwork() (string, error) {
+	pages, err := unwrapPages(networks.List(a.networkClient, networks.ListOpts{}), false)
+	if err != nil {
+		return "", fmt.Errorf("networks: %v", err)
+	}
+	// The vendored gophercloud lacks the external network extension, and
+	// embedding networks.Network would hide the field from its
+	// UnmarshalJSON, so decode just what's needed.
+	var nets []struct {
+		ID       string `json:"id"`
+		Status   string `json:"status"`
+		External bool   `json:"router:external"`
+	}
+	if err := networks.ExtractNetworksInto(pages, &nets); err != nil {
+		return "", fmt.Errorf("extracting networks: %v", err)
+	}
+	for _, net := range nets {
+		if !net.External && net.Status == "ACTIVE" {
+			return net.ID, nil
+		}
+	}
+	return nets[0].ID, nil
+}
+
+// arch returns the architecture of the machines to create, if known.
+func (a *API) arch() string {
+	if a.opts.Options == nil {
+		return ""
+	}
+	return a.opts.CosaBuildArch
+}
@@ -0,0 +1,273 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The Vultr API is used directly, as documented at
+// https://www.vultr.com/api/, since there's no vendored client for it.
+
+package vultr
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/coreos/pkg/capnslog"
+
+	"github.com/coreos/coreos-assembler/mantle/platform"
+	"github.com/coreos/coreos-assembler/mantle/util"
+)
+
+const defaultEndpoint = "https://api.vultr.com/v2"
+
+var (
+	plog = capnslog.NewPackageLogger("github.com/coreos/coreos-assembler/mantle", "platform/api/vultr")
+)
+
+type Options struct {
+	*platform.Options
+
+	// API key; defaults to $VULTR_API_KEY
+	Token string
+
+	// Region (e.g. "ewr")
+	Region string
+	// Plan (e.g. "vc2-2c-4gb")
+	Plan string
+	// Image is the ID of the snapshot to create instances from
+	Image string
+}
+
+type API struct {
+	opts     *Options
+	client   *http.Client
+	endpoint string
+}
+
+func New(opts *Options) (*API, error) {
+	if opts.Token == "" {
+		opts.Token = os.Getenv("VULTR_API_KEY")
+	}
+	if opts.Token == "" {
+		return nil, fmt.Errorf("no Vultr API key given; set --token or $VULTR_API_KEY")
+	}
+	return &API{
+		opts:     opts,
+		client:   &http.Client{Timeout: 2 * time.Minute},
+		endpoint: defaultEndpoint,
+	}, nil
+}
+
+// Error is an error response from the API.
+type Error struct {
+	StatusCode int
+	Message    string `json:"error"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d: %s", e.StatusCode, e.Message)
+}
+
+func isNotFound(err error) bool {
+	apiErr, ok := err.(*Error)
+	return ok && apiErr.StatusCode == http.StatusNotFound
+}
+
+// request sends body as JSON and decodes the response into result, if
+// they're non-nil.
+func (a *API) request(method, path string, body, result interface{}) error {
+	var data []byte
+	if body != nil {
+		var err error
+		data, err = json.Marshal(body)
+		if err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, a.endpoint+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+a.opts.Token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		apiErr := &Error{StatusCode: resp.StatusCode}
+		_ = json.NewDecoder(resp.Body).Decode(apiErr)
+		return apiErr
+	}
+	if result != nil {
+		return json.NewDecoder(resp.Body).Decode(result)
+	}
+	return nil
+}
+
+func (a *API) PreflightCheck() error {
+	return a.request(http.MethodGet, "/account", nil, nil)
+}
+
+type Instance struct {
+	ID          string    `json:"id"`
+	Label       string    `json:"label"`
+	Status      string    `json:"status"`
+	PowerStatus string    `json:"power_status"`
+	MainIP      string    `json:"main_ip"`
+	InternalIP  string    `json:"internal_ip"`
+	DateCreated time.Time `json:"date_created"`
+	Tags        []string  `json:"tags"`
+}
+
+// The tag of instances created by mantle, used to garbage collect them.
+const mantleTag = "mantle"
+
+// CreateInstance creates an instance of the snapshot in the options, and
+// waits for it to run. It's tagged with tags, named by platform.TagNames.
+func (a *API) CreateInstance(label, sshKeyID, userdata string, tags map[string]string) (*Instance, error) {
+	body := map[string]interface{}{
+		"region":      a.opts.Region,
+		"plan":        a.opts.Plan,
+		"snapshot_id": a.opts.Image,
+		"label":       label,
+		"hostname":    label,
+		"tags":        append([]string{mantleTag}, platform.TagNames(tags)...),
+	}
+	if userdata != "" {
+		body["user_data"] = base64.StdEncoding.EncodeToString([]byte(userdata))
+	}
+	if sshKeyID != "" {
+		body["sshkey_id"] = []string{sshKeyID}
+	}
+	var resp struct {
+		Instance Instance `json:"instance"`
+	}
+	if err := a.request(http.MethodPost, "/instances", body, &resp); err != nil {
+		return nil, fmt.Errorf("creating instance: %v", err)
+	}
+	instance := resp.Instance
+
+	err := util.WaitUntilReady(10*time.Minute, 10*time.Second, func() (bool, error) {
+		i, err := a.GetInstance(instance.ID)
+		if err != nil {
+			return false, err
+		}
+		instance = *i
+		return instance.Status == "active" && instance.PowerStatus == "running" &&
+			instance.MainIP != "" && instance.MainIP != "0.0.0.0", nil
+	})
+	if err != nil {
+		if errDelete := a.DeleteInstance(instance.ID); errDelete != nil {
+			return nil, fmt.Errorf("deleting instance: %v after waiting for it to run: %v", errDelete, err)
+		}
+		return nil, fmt.Errorf("waiting for instance to run: %v", err)
+	}
+	return &instance, nil
+}
+
+func (a *API) GetInstance(id string) (*Instance, error) {
+	var resp struct {
+		Instance Instance `json:"instance"`
+	}
+	if err := a.request(http.MethodGet, "/instances/"+id, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Instance, nil
+}
+
+// DeleteInstance deletes the instance, succeeding if it doesn't exist.
+func (a *API) DeleteInstance(id string) error {
+	err := a.request(http.MethodDelete, "/instances/"+id, nil, nil)
+	if err != nil && !isNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// AddKey adds an SSH key to the account and returns its ID.
+func (a *API) AddKey(name, key string) (string, error) {
+	var resp struct {
+		SSHKey struct {
+			ID string `json:"id"`
+		} `json:"ssh_key"`
+	}
+	body := map[string]string{
+		"name":    name,
+		"ssh_key": key,
+	}
+	if err := a.request(http.MethodPost, "/ssh-keys", body, &resp); err != nil {
+		return "", fmt.Errorf("adding SSH key: %v", err)
+	}
+	return resp.SSHKey.ID, nil
+}
+
+func (a *API) DeleteKey(id string) error {
+	err := a.request(http.MethodDelete, "/ssh-keys/"+id, nil, nil)
+	if err != nil && !isNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// GC deletes the instances mantle created which opts selects as garbage.
+// Tags are matched against theirs, which may be KEY:VALUE.
+func (a *API) GC(opts platform.GCOptions) error {
+	// Everything is listed before anything is deleted, so that deletions
+	// don't disturb the cursor.
+	var instances []Instance
+	for cursor := ""; ; {
+		var resp struct {
+			Instances []Instance `json:"instances"`
+			Meta      struct {
+				Links struct {
+					Next string `json:"next"`
+				} `json:"links"`
+			} `json:"meta"`
+		}
+		path := "/instances?tag=" + mantleTag + "&per_page=100"
+		if cursor != "" {
+			path += "&cursor=" + url.QueryEscape(cursor)
+		}
+		if err := a.request(http.MethodGet, path, nil, &resp); err != nil {
+			return fmt.Errorf("listing instances: %v", err)
+		}
+		instances = append(instances, resp.Instances...)
+		if resp.Meta.Links.Next == "" {
+			break
+		}
+		cursor = resp.Meta.Links.Next
+	}
+	for _, instance := range instances {
+		if !opts.IsGarbage(instance.DateCreated, platform.TagsFromNames(instance.Tags)) {
+			continue
+		}
+		id := instance.ID
+		err := opts.Collect("instance", id, instance.DateCreated, func() error {
+			return a.DeleteInstance(id)
+		})
+		if err != nil {
+			return fmt.Errorf("deleting instance %s: %v", id, err)
+		}
+	}
+	return nil
+}
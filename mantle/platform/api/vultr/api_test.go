@@ -0,0 +1,210 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vultr
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coreos/coreos-assembler/mantle/platform"
+)
+
+// newTestAPI returns an API talking to a fake server with the given
+// routes, which are "METHOD /path" patterns, and a function returning the
+// requests made.
+func newTestAPI(t *testing.T, routes map[string]http.HandlerFunc) (*API, func() []string) {
+	var mu sync.Mutex
+	var requests []string
+	mux := http.NewServeMux()
+	for pattern, h := range routes {
+		mux.HandleFunc(pattern, h)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer token" {
+			t.Errorf("%s %s: got Authorization %q", r.Method, r.URL.Path, got)
+		}
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	api := &API{
+		opts: &Options{
+			Token:  "token",
+			Region: "ewr",
+			Plan:   "vc2-2c-4gb",
+			Image:  "snap",
+		},
+		client:   srv.Client(),
+		endpoint: srv.URL,
+	}
+	return api, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), requests...)
+	}
+}
+
+func reply(t *testing.T, status int, body interface{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if body != nil {
+			if err := json.NewEncoder(w).Encode(body); err != nil {
+				t.Error(err)
+			}
+		}
+	}
+}
+
+func TestCreateInstance(t *testing.T) {
+	api, requests := newTestAPI(t, map[string]http.HandlerFunc{
+		"POST /instances": func(w http.ResponseWriter, r *http.Request) {
+			var body map[string]interface{}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Error(err)
+			}
+			want := map[string]interface{}{
+				"region":      "ewr",
+				"plan":        "vc2-2c-4gb",
+				"snapshot_id": "snap",
+				"label":       "kola-test",
+				"hostname":    "kola-test",
+				"tags":        []interface{}{"mantle", "team:ci"},
+				"user_data":   base64.StdEncoding.EncodeToString([]byte("{}")),
+				"sshkey_id":   []interface{}{"key"},
+			}
+			if !reflect.DeepEqual(body, want) {
+				t.Errorf("got body %v, expected %v", body, want)
+			}
+			reply(t, http.StatusAccepted, map[string]interface{}{
+				"instance": map[string]interface{}{"id": "abc", "status": "pending"},
+			})(w, r)
+		},
+		"GET /instances/abc": reply(t, http.StatusOK, map[string]interface{}{
+			"instance": map[string]interface{}{
+				"id":           "abc",
+				"status":       "active",
+				"power_status": "running",
+				"main_ip":      "192.0.2.1",
+				"internal_ip":  "10.0.0.1",
+			},
+		}),
+	})
+
+	instance, err := api.CreateInstance("kola-test", "key", "{}", map[string]string{"team": "ci"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if instance.ID != "abc" || instance.MainIP != "192.0.2.1" || instance.InternalIP != "10.0.0.1" {
+		t.Errorf("got instance %+v", instance)
+	}
+	want := []string{"POST /instances", "GET /instances/abc"}
+	if got := requests(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got requests %v, expected %v", got, want)
+	}
+}
+
+func TestGC(t *testing.T) {
+	api, requests := newTestAPI(t, map[string]http.HandlerFunc{
+		"GET /instances": func(w http.ResponseWriter, r *http.Request) {
+			if tag := r.URL.Query().Get("tag"); tag != "mantle" {
+				t.Errorf("listed instances with tag %q", tag)
+			}
+			// The instances span two pages.
+			switch cursor := r.URL.Query().Get("cursor"); cursor {
+			case "":
+				reply(t, http.StatusOK, map[string]interface{}{
+					"instances": []map[string]interface{}{
+						{"id": "old", "date_created": time.Now().Add(-24 * time.Hour), "tags": []string{"mantle", "team:ci"}},
+						{"id": "new", "date_created": time.Now(), "tags": []string{"mantle", "team:ci"}},
+					},
+					"meta": map[string]interface{}{"links": map[string]string{"next": "bmV4dA==", "prev": ""}},
+				})(w, r)
+			case "bmV4dA==":
+				reply(t, http.StatusOK, map[string]interface{}{
+					"instances": []map[string]interface{}{
+						{"id": "other", "date_created": time.Now().Add(-24 * time.Hour), "tags": []string{"mantle", "team:qa"}},
+						{"id": "older", "date_created": time.Now().Add(-48 * time.Hour), "tags": []string{"mantle", "team:ci"}},
+					},
+					"meta": map[string]interface{}{"links": map[string]string{"next": "", "prev": "cHJldg=="}},
+				})(w, r)
+			default:
+				t.Errorf("listed instances with cursor %q", cursor)
+			}
+		},
+		"DELETE /instances/old":   reply(t, http.StatusNoContent, nil),
+		"DELETE /instances/older": reply(t, http.StatusNoContent, nil),
+	})
+
+	if err := api.GC(platform.GCOptions{GracePeriod: time.Hour, Tags: map[string]string{"team": "ci"}}); err != nil {
+		t.Fatal(err)
+	}
+	got := requests()
+	sort.Strings(got)
+	want := []string{"DELETE /instances/old", "DELETE /instances/older", "GET /instances", "GET /instances"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got requests %v, expected %v", got, want)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	api, _ := newTestAPI(t, map[string]http.HandlerFunc{
+		"DELETE /snapshots/gone": reply(t, http.StatusNotFound, map[string]string{"error": "Invalid snapshot ID"}),
+		"DELETE /ssh-keys/key":   reply(t, http.StatusNoContent, nil),
+	})
+	// Deleting something already gone succeeds.
+	if err := api.DeleteSnapshot("gone"); err != nil {
+		t.Errorf("deleting missing snapshot: %v", err)
+	}
+	if err := api.DeleteKey("key"); err != nil {
+		t.Errorf("deleting key: %v", err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	api, _ := newTestAPI(t, map[string]http.HandlerFunc{
+		"GET /instances/abc": reply(t, http.StatusUnauthorized, map[string]string{"error": "Invalid API token."}),
+		"DELETE /instances/abc": func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "upstream unavailable", http.StatusBadGateway)
+		},
+	})
+
+	_, err := api.GetInstance("abc")
+	apiErr, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("got error %v, expected an API error", err)
+	}
+	if want := (Error{StatusCode: 401, Message: "Invalid API token."}); *apiErr != want {
+		t.Errorf("got %+v, expected %+v", *apiErr, want)
+	}
+	if got, want := err.Error(), "401: Invalid API token."; got != want {
+		t.Errorf("got message %q, expected %q", got, want)
+	}
+
+	// Bodies which aren't JSON still give the status.
+	err = api.DeleteInstance("abc")
+	if apiErr, ok := err.(*Error); !ok || apiErr.StatusCode != http.StatusBadGateway {
+		t.Errorf("got error %v, expected a 502", err)
+	}
+}
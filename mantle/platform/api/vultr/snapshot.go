@@ -0,0 +1,77 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vultr
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/coreos/coreos-assembler/mantle/util"
+)
+
+type Snapshot struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+	Status      string `json:"status"`
+}
+
+// CreateSnapshotFromURL imports the raw disk image at url, which must be
+// publicly readable, as a snapshot and waits for it to complete.
+func (a *API) CreateSnapshotFromURL(url, description string) (*Snapshot, error) {
+	body := map[string]string{
+		"url":         url,
+		"description": description,
+	}
+	var resp struct {
+		Snapshot Snapshot `json:"snapshot"`
+	}
+	if err := a.request(http.MethodPost, "/snapshots/create-from-url", body, &resp); err != nil {
+		return nil, fmt.Errorf("creating snapshot: %v", err)
+	}
+	snapshot := resp.Snapshot
+
+	err := util.WaitUntilReady(time.Hour, 15*time.Second, func() (bool, error) {
+		s, err := a.GetSnapshot(snapshot.ID)
+		if err != nil {
+			return false, err
+		}
+		snapshot = *s
+		return snapshot.Status == "complete", nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("waiting for snapshot %s: %v", snapshot.ID, err)
+	}
+	return &snapshot, nil
+}
+
+func (a *API) GetSnapshot(id string) (*Snapshot, error) {
+	var resp struct {
+		Snapshot Snapshot `json:"snapshot"`
+	}
+	if err := a.request(http.MethodGet, "/snapshots/"+id, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Snapshot, nil
+}
+
+// DeleteSnapshot deletes the snapshot, succeeding if it doesn't exist.
+func (a *API) DeleteSnapshot(id string) error {
+	err := a.request(http.MethodDelete, "/snapshots/"+id, nil, nil)
+	if err != nil && !isNotFound(err) {
+		return err
+	}
+	return nil
+}
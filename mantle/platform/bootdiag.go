@@ -0,0 +1,129 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package platform
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// BootCause is the suspected reason a machine never became reachable, as
+// recognized on its console.
+type BootCause string
+
+const (
+	CauseIgnition    BootCause = "Ignition failure"
+	CauseEmergency   BootCause = "emergency target"
+	CauseKernelPanic BootCause = "kernel panic"
+	CauseDHCP        BootCause = "DHCP failure"
+	CauseUnknown     BootCause = "unknown"
+)
+
+// consoleExcerptContext is how many lines around the first recognized line
+// of a console are kept in a BootError's excerpt.
+const consoleExcerptContext = 10
+
+// bootCausePatterns maps console lines to the cause they suggest. They're
+// checked in order, so the more specific causes come first: a failed
+// Ignition stage also ends up in the emergency target.
+var bootCausePatterns = []struct {
+	re    *regexp.Regexp
+	cause BootCause
+}{
+	{regexp.MustCompile(`Kernel panic - not syncing`), CauseKernelPanic},
+	{regexp.MustCompile(`ignition\[\d+\]: .*(failed|error)|Failed to start .*Ignition|ignition-.*\.service: Failed`), CauseIgnition},
+	{regexp.MustCompile(`dhcp4 \(.*\): request timed out|DHCP.*(timed out|timeout|failed)|nm-online.*failed|Failed to start .*Network Manager Wait Online`), CauseDHCP},
+	{regexp.MustCompile(`Reached target .*Emergency Mode|You are in emergency mode|Entering emergency mode`), CauseEmergency},
+}
+
+// BootError is returned when a machine doesn't come up, with what its
+// console suggests went wrong.
+type BootError struct {
+	Machine string
+	Cause   BootCause
+	// Excerpt is the part of the console around the line the cause was
+	// recognized from, or its tail if none was.
+	Excerpt string
+	Err     error
+}
+
+func (e *BootError) Error() string {
+	msg := fmt.Sprintf("machine %q failed to start: %v", e.Machine, e.Err)
+	if e.Cause != CauseUnknown {
+		msg += fmt.Sprintf(" (suspected %s)", e.Cause)
+	}
+	if e.Excerpt != "" {
+		msg += "\nconsole:\n" + e.Excerpt
+	}
+	return msg
+}
+
+func (e *BootError) Unwrap() error {
+	return e.Err
+}
+
+// ConsoleReader is implemented by machines whose console can be read while
+// they're running.
+type ConsoleReader interface {
+	// CurrentConsole returns the machine's console output so far.
+	CurrentConsole() (string, error)
+}
+
+// ClassifyConsole returns the suspected reason a machine with console
+// output console failed to come up, and an excerpt of the console showing
+// it.
+func ClassifyConsole(console string) (BootCause, string) {
+	lines := strings.Split(strings.ReplaceAll(console, "\r", ""), "\n")
+	for _, p := range bootCausePatterns {
+		for i, line := range lines {
+			if p.re.MatchString(line) {
+				return p.cause, consoleExcerpt(lines, i-consoleExcerptContext, i+consoleExcerptContext+1)
+			}
+		}
+	}
+	return CauseUnknown, consoleExcerpt(lines, len(lines)-2*consoleExcerptContext, len(lines))
+}
+
+func consoleExcerpt(lines []string, start, end int) string {
+	if start < 0 {
+		start = 0
+	}
+	if end > len(lines) {
+		end = len(lines)
+	}
+	return strings.TrimSpace(strings.Join(lines[start:end], "\n"))
+}
+
+// diagnoseBoot wraps err, from waiting for m to come up, in a BootError
+// classifying m's console, if it can be read.
+func diagnoseBoot(m Machine, err error) error {
+	reader, ok := m.(ConsoleReader)
+	if !ok {
+		return fmt.Errorf("machine %q failed to start: %v", m.ID(), err)
+	}
+	console, cerr := reader.CurrentConsole()
+	if cerr != nil {
+		plog.Warningf("Reading console of machine %s: %v", m.ID(), cerr)
+		return fmt.Errorf("machine %q failed to start: %v", m.ID(), err)
+	}
+	cause, excerpt := ClassifyConsole(console)
+	return &BootError{
+		Machine: m.ID(),
+		Cause:   cause,
+		Excerpt: excerpt,
+		Err:     err,
+	}
+}
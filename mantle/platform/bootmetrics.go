@@ -0,0 +1,231 @@
+// Copyright 2026 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package platform
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// consoleMarker is a boot milestone recognized on the serial console.
+type consoleMarker string
+
+const (
+	markerGrub   consoleMarker = "grub"
+	markerKernel consoleMarker = "kernel"
+)
+
+// consoleMarkers maps substrings of console output to the milestone they
+// indicate; only the first occurrence of each milestone is recorded.
+var consoleMarkers = []struct {
+	pattern string
+	marker  consoleMarker
+}{
+	{"GNU GRUB", markerGrub},
+	{"Booting `", markerGrub},
+	{"Linux version ", markerKernel},
+}
+
+// systemdAnalyzeRe matches the components of the first line of
+// `systemd-analyze time`, e.g. "1.093s (kernel)".
+var systemdAnalyzeRe = regexp.MustCompile(`([0-9][0-9a-z. ]*?) \((firmware|loader|kernel|initrd|userspace)\)`)
+
+// BootMetrics is the breakdown of a single boot, in seconds. Milestones that
+// could not be determined are omitted.
+type BootMetrics struct {
+	// Wall-clock times since the machine was started
+	TimeToGrub       float64 `json:"time-to-grub,omitempty"`
+	TimeToKernel     float64 `json:"time-to-kernel,omitempty"`
+	TimeToInitramfs  float64 `json:"time-to-initramfs,omitempty"`
+	TimeToSwitchRoot float64 `json:"time-to-switch-root,omitempty"`
+	TimeToSSH        float64 `json:"time-to-ssh,omitempty"`
+
+	// Durations as reported by systemd-analyze in the guest
+	Firmware  float64 `json:"firmware,omitempty"`
+	Loader    float64 `json:"loader,omitempty"`
+	Kernel    float64 `json:"kernel,omitempty"`
+	Initrd    float64 `json:"initrd,omitempty"`
+	Userspace float64 `json:"userspace,omitempty"`
+}
+
+// BootTimer follows a machine's serial console from the time it is started,
+// noting when boot milestones appear so that they can be combined with the
+// guest's own view of the boot to build a BootMetrics.
+type BootTimer struct {
+	consolePath string
+	start       time.Time
+
+	mu       sync.Mutex
+	marks    map[consoleMarker]time.Time
+	sshReady time.Time
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewBootTimer starts following the console file at consolePath, which
+// need not exist yet. The caller should create it right before starting
+// the machine, and must call Stop() when done.
+func NewBootTimer(consolePath string) *BootTimer {
+	t := &BootTimer{
+		consolePath: consolePath,
+		start:       time.Now(),
+		marks:       make(map[consoleMarker]time.Time),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	go t.follow()
+	return t
+}
+
+func (t *BootTimer) follow() {
+	defer close(t.done)
+	followConsole(t.consolePath, t.stop, t.scanLine)
+}
+
+func (t *BootTimer) scanLine(line string, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, m := range consoleMarkers {
+		if _, ok := t.marks[m.marker]; ok {
+			continue
+		}
+		if strings.Contains(line, m.pattern) {
+			t.marks[m.marker] = now
+		}
+	}
+}
+
+// SSHReady records that the machine became reachable over SSH; callers
+// should invoke it once StartMachine() has returned.
+func (t *BootTimer) SSHReady() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.sshReady.IsZero() {
+		t.sshReady = time.Now()
+	}
+}
+
+// Stop stops following the console. It is safe to call multiple times.
+func (t *BootTimer) Stop() {
+	select {
+	case <-t.stop:
+	default:
+		close(t.stop)
+	}
+	<-t.done
+}
+
+// Metrics queries the machine over SSH and returns the boot breakdown.
+func (t *BootTimer) Metrics(m Machine) (*BootMetrics, error) {
+	analyze, err := systemdAnalyzeTime(m)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	since := func(ts time.Time) float64 {
+		if ts.IsZero() {
+			return 0
+		}
+		return ts.Sub(t.start).Seconds()
+	}
+	ret := BootMetrics{
+		TimeToGrub:   since(t.marks[markerGrub]),
+		TimeToKernel: since(t.marks[markerKernel]),
+		TimeToSSH:    since(t.sshReady),
+		Firmware:     analyze["firmware"].Seconds(),
+		Loader:       analyze["loader"].Seconds(),
+		Kernel:       analyze["kernel"].Seconds(),
+		Initrd:       analyze["initrd"].Seconds(),
+		Userspace:    analyze["userspace"].Seconds(),
+	}
+	// systemd measures the initrd phases relative to the kernel starting,
+	// so anchor them at when we saw it on the console.
+	if ret.TimeToKernel > 0 {
+		ret.TimeToInitramfs = ret.TimeToKernel + ret.Kernel
+		if ret.Initrd > 0 {
+			ret.TimeToSwitchRoot = ret.TimeToInitramfs + ret.Initrd
+		}
+	}
+	return &ret, nil
+}
+
+// WriteMetrics writes the boot breakdown of the machine to path as JSON.
+func (t *BootTimer) WriteMetrics(m Machine, path string) error {
+	metrics, err := t.Metrics(m)
+	if err != nil {
+		return err
+	}
+	buf, err := json.MarshalIndent(metrics, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf, 0644)
+}
+
+// systemdAnalyzeTime runs `systemd-analyze time` on the machine and returns
+// the duration of each phase it reports. systemd refuses to answer until
+// boot has finished, so this fails rather than waiting on machines with
+// units still activating.
+func systemdAnalyzeTime(m Machine) (map[string]time.Duration, error) {
+	out, stderr, err := m.SSH("systemd-analyze time")
+	if err != nil {
+		return nil, fmt.Errorf("running systemd-analyze: %v: %s", err, stderr)
+	}
+	return parseSystemdAnalyzeTime(string(out))
+}
+
+// parseSystemdAnalyzeTime parses output of the form:
+// Startup finished in 1.093s (kernel) + 2.5s (initrd) + 1min 10.2s (userspace) = 1min 13.793s
+func parseSystemdAnalyzeTime(out string) (map[string]time.Duration, error) {
+	line, _, _ := strings.Cut(out, "\n")
+	matches := systemdAnalyzeRe.FindAllStringSubmatch(line, -1)
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("unexpected systemd-analyze output: %q", line)
+	}
+	ret := make(map[string]time.Duration)
+	for _, match := range matches {
+		d, err := parseSystemdTimespan(match[1])
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing %s time", match[2])
+		}
+		ret[match[2]] = d
+	}
+	return ret, nil
+}
+
+// parseSystemdTimespan converts systemd's human-readable timespans like
+// "1min 2.303s" into a time.Duration.
+func parseSystemdTimespan(s string) (time.Duration, error) {
+	var total time.Duration
+	for _, field := range strings.Fields(s) {
+		field = strings.Replace(field, "min", "m", 1)
+		d, err := time.ParseDuration(field)
+		if err != nil {
+			return 0, err
+		}
+		total += d
+	}
+	return total, nil
+}
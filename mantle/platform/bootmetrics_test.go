@@ -0,0 +1,95 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package platform
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseSystemdTimespan(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want time.Duration
+		err  bool
+	}{
+		{"1.093s", 1093 * time.Millisecond, false},
+		{"512ms", 512 * time.Millisecond, false},
+		{"1min 2.303s", time.Minute + 2303*time.Millisecond, false},
+		{"1h 2min 3s", time.Hour + 2*time.Minute + 3*time.Second, false},
+		{"", 0, false},
+		{"1 fortnight", 0, true},
+	} {
+		got, err := parseSystemdTimespan(tt.in)
+		if (err != nil) != tt.err {
+			t.Errorf("parseSystemdTimespan(%q) error = %v, want error %v", tt.in, err, tt.err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseSystemdTimespan(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseSystemdAnalyzeTime(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		in   string
+		want map[string]time.Duration
+		err  bool
+	}{
+		{
+			name: "bios",
+			in:   "Startup finished in 1.093s (kernel) + 2.5s (initrd) + 1min 10.2s (userspace) = 1min 13.793s\ngraphical.target reached after 1min 10s in userspace\n",
+			want: map[string]time.Duration{
+				"kernel":    1093 * time.Millisecond,
+				"initrd":    2500 * time.Millisecond,
+				"userspace": time.Minute + 10200*time.Millisecond,
+			},
+		},
+		{
+			name: "uefi",
+			in:   "Startup finished in 2.402s (firmware) + 812ms (loader) + 1.1s (kernel) + 3.2s (initrd) + 9.5s (userspace) = 17.014s",
+			want: map[string]time.Duration{
+				"firmware":  2402 * time.Millisecond,
+				"loader":    812 * time.Millisecond,
+				"kernel":    1100 * time.Millisecond,
+				"initrd":    3200 * time.Millisecond,
+				"userspace": 9500 * time.Millisecond,
+			},
+		},
+		{
+			name: "boot not finished",
+			in:   "Bootup is not yet finished (org.freedesktop.systemd1.Manager.FinishTimestampMonotonic=0).",
+			err:  true,
+		},
+		{
+			name: "empty",
+			in:   "",
+			err:  true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSystemdAnalyzeTime(tt.in)
+			if (err != nil) != tt.err {
+				t.Fatalf("error = %v, want error %v", err, tt.err)
+			}
+			if !tt.err && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
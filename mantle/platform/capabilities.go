@@ -0,0 +1,141 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package platform
+
+import (
+	"strings"
+)
+
+// Capability is something a test can need of machines which not every
+// platform offers. Its value names it in skip reasons.
+type Capability string
+
+const (
+	// CapAdditionalDisks is attaching MachineOptions.AdditionalDisks.
+	CapAdditionalDisks Capability = "additional disks"
+	// CapMultiPathDisk is presenting disks over multiple paths.
+	CapMultiPathDisk Capability = "multipathed disks"
+	// CapAdditionalNics is attaching MachineOptions.AdditionalNics.
+	CapAdditionalNics Capability = "additional NICs"
+	// CapKernelArgs is appending kernel arguments, including first boot
+	// ones.
+	CapKernelArgs Capability = "appending kernel arguments"
+	// CapInstanceType is choosing MachineOptions.InstanceType.
+	CapInstanceType Capability = "changing instance types"
+	// CapUEFI is booting machines with UEFI firmware.
+	CapUEFI Capability = "UEFI firmware"
+	// CapSecureBoot is booting machines with UEFI Secure Boot.
+	CapSecureBoot Capability = "UEFI Secure Boot"
+	// CapNestedVirt is running virtual machines inside machines with
+	// MachineOptions.NestedVirt.
+	CapNestedVirt Capability = "nested virtualization"
+)
+
+// Capabilities describes what a platform's machines support, so that
+// tests needing more are skipped there rather than failing.
+type Capabilities struct {
+	// Features the machines support beyond booting and SSH
+	Features []Capability
+	// Architectures the machines can run; any if empty
+	Architectures []string
+}
+
+// Has returns whether the machines support the feature.
+func (c Capabilities) Has(feature Capability) bool {
+	for _, f := range c.Features {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
+
+// SupportsArch returns whether the machines can run the architecture.
+func (c Capabilities) SupportsArch(arch string) bool {
+	if len(c.Architectures) == 0 {
+		return true
+	}
+	for _, a := range c.Architectures {
+		if a == arch {
+			return true
+		}
+	}
+	return false
+}
+
+// Missing returns the features of needs which the machines don't
+// support, as a list for skip reasons, or "" if they support them all.
+func (c Capabilities) Missing(needs []Capability) string {
+	var missing []string
+	for _, need := range needs {
+		if !c.Has(need) {
+			missing = append(missing, string(need))
+		}
+	}
+	return strings.Join(missing, ", ")
+}
+
+// Needs returns the features machines must support to be created with
+// the options.
+func (o MachineOptions) Needs() []Capability {
+	var needs []Capability
+	if len(o.AdditionalDisks) > 0 {
+		needs = append(needs, CapAdditionalDisks)
+	}
+	if o.MultiPathDisk || hasDiskOption(o.PrimaryDisk, "mpath") {
+		needs = append(needs, CapMultiPathDisk)
+	} else {
+		for _, spec := range o.AdditionalDisks {
+			if hasDiskOption(spec, "mpath") {
+				needs = append(needs, CapMultiPathDisk)
+				break
+			}
+		}
+	}
+	if o.AdditionalNics > 0 {
+		needs = append(needs, CapAdditionalNics)
+	}
+	if o.AppendKernelArgs != "" || o.AppendFirstbootKernelArgs != "" {
+		needs = append(needs, CapKernelArgs)
+	}
+	if o.InstanceType != "" {
+		needs = append(needs, CapInstanceType)
+	}
+	if o.NestedVirt {
+		needs = append(needs, CapNestedVirt)
+	}
+	switch o.Firmware {
+	case "uefi":
+		needs = append(needs, CapUEFI)
+	case "uefi-secure":
+		needs = append(needs, CapUEFI, CapSecureBoot)
+	}
+	return needs
+}
+
+// hasDiskOption returns whether a disk spec, SIZE[:OPTION,...], has the
+// option.
+func hasDiskOption(spec, option string) bool {
+	_, options, ok := strings.Cut(spec, ":")
+	if !ok {
+		return false
+	}
+	for _, o := range strings.Split(options, ",") {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
@@ -17,19 +17,22 @@ package platform
 import (
 	"bufio"
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"net"
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 
-	"github.com/pborman/uuid"
 	"golang.org/x/crypto/ssh"
 	"golang.org/x/crypto/ssh/agent"
 
+	"github.com/coreos/coreos-assembler/mantle/network/journal"
 	platformConf "github.com/coreos/coreos-assembler/mantle/platform/conf"
+	"github.com/coreos/coreos-assembler/mantle/util"
 )
 
 type BaseCluster struct {
@@ -38,6 +41,11 @@ type BaseCluster struct {
 	machmap    map[string]Machine
 	consolemap map[string]string
 
+	// renderserial counts the configs rendered, and secrets are those of
+	// UserDataSecret
+	renderserial uint
+	secrets      map[string]string
+
 	bf    *BaseFlight
 	name  string
 	rconf *RuntimeConfig
@@ -47,6 +55,15 @@ type BaseCluster struct {
 	// is decremented before the machine destroy process begins, and
 	// machmap is updated usually near the end.
 	numMachines int
+	// destroying holds the IDs of the machines which Destroy or
+	// DestroyMachine have started destroying, so that each is only
+	// destroyed once
+	destroying map[string]bool
+	// served maps the userdata FitUserData returned for configs it
+	// serves to their names until BindUserData binds them to machines,
+	// and machServed the IDs of those machines to the names
+	served     map[string]string
+	machServed map[string]string
 }
 
 func NewBaseCluster(bf *BaseFlight, rconf *RuntimeConfig) (*BaseCluster, error) {
@@ -54,9 +71,16 @@ func NewBaseCluster(bf *BaseFlight, rconf *RuntimeConfig) (*BaseCluster, error)
 		bf:         bf,
 		machmap:    make(map[string]Machine),
 		consolemap: make(map[string]string),
-		name:       fmt.Sprintf("%s-%s", bf.baseopts.BaseName, uuid.New()),
+		secrets:    make(map[string]string),
+		destroying: make(map[string]bool),
+		served:     make(map[string]string),
+		machServed: make(map[string]string),
+		name:       util.ResourceName(bf.baseopts.BaseName),
 		rconf:      rconf,
 	}
+	if rconf.MergeJournals {
+		rconf.mergedJournal = journal.NewMerger()
+	}
 
 	return bc, nil
 }
@@ -94,17 +118,11 @@ func (bc *BaseCluster) PasswordSSHClient(ip string, user string, password string
 func (bc *BaseCluster) SSH(m Machine, cmd string) ([]byte, []byte, error) {
 	var stdout bytes.Buffer
 	var stderr bytes.Buffer
-	client, err := bc.SSHClient(m.IP())
-	if err != nil {
-		return nil, nil, err
-	}
-	defer client.Close()
-
-	session, err := client.NewSession()
+	session, done, err := bc.bf.agent.NewSharedSession(m.IP())
 	if err != nil {
 		return nil, nil, err
 	}
-	defer session.Close()
+	defer done()
 
 	session.Stdout = &stdout
 	session.Stderr = &stderr
@@ -173,6 +191,12 @@ func (bc *BaseCluster) DelMach(m Machine) {
 	defer bc.machlock.Unlock()
 	delete(bc.machmap, m.ID())
 	bc.consolemap[m.ID()] = m.ConsoleOutput()
+	// The machine's address may be reused by another
+	bc.bf.agent.CloseSharedClient(m.IP())
+	if name, ok := bc.machServed[m.ID()]; ok {
+		bc.bf.unserveUserData(name)
+		delete(bc.machServed, m.ID())
+	}
 }
 
 func (bc *BaseCluster) AllocateMachineSerial() uint {
@@ -192,9 +216,17 @@ func (bc *BaseCluster) RenderUserData(userdata *platformConf.UserData, ignitionV
 		userdata = platformConf.EmptyIgnition()
 	}
 
-	// hacky solution for unified ignition metadata variables
-	for k, v := range ignitionVars {
-		userdata = userdata.Subst(k, v)
+	if userdata.IsTemplate() {
+		var err error
+		if userdata, err = bc.executeUserData(userdata, ignitionVars); err != nil {
+			return nil, err
+		}
+	} else {
+		// Configs which aren't templates have the variables substituted
+		// by name, as in $public_ipv4
+		for k, v := range ignitionVars {
+			userdata = userdata.Subst(k, v)
+		}
 	}
 
 	confSources := []*platformConf.Conf{}
@@ -236,6 +268,33 @@ func (bc *BaseCluster) RenderUserData(userdata *platformConf.UserData, ignitionV
 		confSources = append(confSources, subConf)
 	}
 
+	if len(bc.rconf.KernelArgs) > 0 {
+		subData, err := kernelArgsIgnition(bc.rconf.KernelArgs)
+		if err != nil {
+			return nil, err
+		}
+		subConf, err := subData.Render(platformConf.FailWarnings)
+		if err != nil {
+			return nil, err
+		}
+		confSources = append(confSources, subConf)
+	}
+
+	if profile := bc.PerformanceProfile(); profile != nil {
+		if err := profile.Validate(); err != nil {
+			return nil, fmt.Errorf("performance profile: %v", err)
+		}
+		subData, err := profile.Ignition()
+		if err != nil {
+			return nil, err
+		}
+		subConf, err := subData.Render(platformConf.FailWarnings)
+		if err != nil {
+			return nil, err
+		}
+		confSources = append(confSources, subConf)
+	}
+
 	// Look at the array of configs we have so far; if there is exactly one,
 	// then we don't need to do any merging.
 	var conf *platformConf.Conf
@@ -277,17 +336,61 @@ func (bc *BaseCluster) RenderUserData(userdata *platformConf.UserData, ignitionV
 		if !conf.ValidConfig() {
 			return nil, fmt.Errorf("invalid ignition config")
 		}
+		if max := bc.bf.baseopts.MaxIgnitionSpec; max != nil {
+			if err := conf.CheckSpecVersion(*max); err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	return conf, nil
 }
 
+// claimDestroy returns whether the caller should destroy m, which is only
+// the case for the first caller.
+func (bc *BaseCluster) claimDestroy(m Machine) bool {
+	bc.machlock.Lock()
+	defer bc.machlock.Unlock()
+	if bc.destroying[m.ID()] {
+		return false
+	}
+	bc.destroying[m.ID()] = true
+	bc.numMachines--
+	return true
+}
+
+// DestroyMachine destroys one of the cluster's machines unless it's
+// already being destroyed, so that it's safe to call while the cluster is
+// being destroyed.
+func (bc *BaseCluster) DestroyMachine(m Machine) {
+	if bc.claimDestroy(m) {
+		m.Destroy()
+	}
+}
+
 // Destroy destroys each machine in the cluster.
 func (bc *BaseCluster) Destroy() {
 	for _, m := range bc.Machines() {
-		bc.numMachines--
-		m.Destroy()
+		bc.DestroyMachine(m)
+	}
+	bc.unserveUnbound()
+	if bc.rconf.mergedJournal != nil && bc.rconf.OutputDir != "" {
+		if err := bc.writeMergedJournal(); err != nil {
+			plog.Errorf("Failed to write merged journal: %v", err)
+		}
+	}
+}
+
+func (bc *BaseCluster) writeMergedJournal() error {
+	f, err := os.Create(filepath.Join(bc.rconf.OutputDir, "journal.txt"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := bc.rconf.mergedJournal.WriteShort(f, time.Local); err != nil {
+		return err
 	}
+	return f.Close()
 }
 
 func (bc *BaseCluster) Distribution() string {
@@ -306,10 +409,25 @@ func (bc *BaseCluster) Name() string {
 	return bc.name
 }
 
+// MachineName returns a new name for a machine of the cluster, recording
+// when and by whom it was created; see util.ResourceName.
+func (bc *BaseCluster) MachineName() string {
+	return util.ResourceName(bc.bf.baseopts.BaseName)
+}
+
 func (bc *BaseCluster) RuntimeConf() RuntimeConfig {
 	return *bc.rconf
 }
 
+// PerformanceProfile returns the profile tuning the cluster's machines: its
+// own, or the one all clusters are given, if any.
+func (bc *BaseCluster) PerformanceProfile() *PerformanceProfile {
+	if bc.rconf.PerformanceProfile != nil {
+		return bc.rconf.PerformanceProfile
+	}
+	return bc.bf.baseopts.PerformanceProfile
+}
+
 func (bc *BaseCluster) ConsoleOutput() map[string]string {
 	ret := map[string]string{}
 	bc.machlock.Lock()
@@ -337,3 +455,16 @@ func (bc *BaseCluster) EarlyRelease() {
 		bc.rconf.EarlyRelease()
 	}
 }
+
+// kernelArgsIgnition returns an Ignition config making sure the kernel
+// arguments are on the kernel command line.
+func kernelArgsIgnition(args []string) (*platformConf.UserData, error) {
+	data, err := json.Marshal(map[string]interface{}{
+		"ignition":        map[string]string{"version": "3.3.0"},
+		"kernelArguments": map[string][]string{"shouldExist": args},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return platformConf.Ignition(string(data)), nil
+}
@@ -0,0 +1,62 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package platform
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+type destroyCountingMachine struct {
+	Machine
+	destroyed atomic.Int32
+}
+
+func (m *destroyCountingMachine) ID() string { return "m1" }
+func (m *destroyCountingMachine) Destroy()   { m.destroyed.Add(1) }
+
+func TestDestroyMachineOnce(t *testing.T) {
+	bc := &BaseCluster{
+		machmap:     make(map[string]Machine),
+		destroying:  make(map[string]bool),
+		rconf:       &RuntimeConfig{},
+		numMachines: 1,
+	}
+	m := &destroyCountingMachine{}
+	bc.machmap[m.ID()] = m
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			bc.DestroyMachine(m)
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		bc.Destroy()
+	}()
+	wg.Wait()
+
+	if n := m.destroyed.Load(); n != 1 {
+		t.Errorf("destroyed %d times, want once", n)
+	}
+	if bc.numMachines != 0 {
+		t.Errorf("numMachines = %d, want 0", bc.numMachines)
+	}
+}
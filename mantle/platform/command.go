@@ -0,0 +1,178 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package platform
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+)
+
+// CommandKillTimeout is how long a canceled command is given to exit
+// after SIGTERM before it's sent SIGKILL.
+var CommandKillTimeout = 10 * time.Second
+
+// pgidMarker prefixes the first line of stderr, in which the remote shell
+// reports its PID. sshd starts each command in a session of its own, so
+// that's also the ID of the command's process group.
+const pgidMarker = "kola-pgid:"
+
+// ExitError reports a remote command which did not exit successfully.
+type ExitError struct {
+	Command string
+	// Status is the exit status, or -1 if the command was killed by a
+	// signal or the status wasn't reported.
+	Status int
+	// Signal is the name of the signal which killed the command, such as
+	// "TERM", if any.
+	Signal string
+}
+
+func (e *ExitError) Error() string {
+	switch {
+	case e.Signal != "":
+		return fmt.Sprintf("%q was killed by signal %s", e.Command, e.Signal)
+	case e.Status < 0:
+		return fmt.Sprintf("%q exited without reporting a status", e.Command)
+	default:
+		return fmt.Sprintf("%q exited with status %d", e.Command, e.Status)
+	}
+}
+
+// RunCommand runs cmd on m over a new SSH connection, copying its output
+// to stdout and stderr as it's produced; either may be nil. If ctx is done
+// first, the command's process group is sent SIGTERM, and SIGKILL if it's
+// still running after CommandKillTimeout, and the error wraps ctx.Err().
+// If the command fails, the error is an *ExitError.
+func RunCommand(ctx context.Context, m Machine, cmd string, stdout, stderr io.Writer) error {
+	client, err := m.SSHClient()
+	if err != nil {
+		return errors.Wrapf(err, "failed creating SSH client")
+	}
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return errors.Wrapf(err, "failed creating SSH session")
+	}
+	defer session.Close()
+
+	if stdout == nil {
+		stdout = io.Discard
+	}
+	if stderr == nil {
+		stderr = io.Discard
+	}
+	pgid := make(chan int, 1)
+	session.Stdout = stdout
+	session.Stderr = &pgidWriter{w: stderr, pgid: pgid}
+	if err := session.Start(fmt.Sprintf("echo %s$$ >&2; %s", pgidMarker, cmd)); err != nil {
+		return errors.Wrapf(err, "starting %q", cmd)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- session.Wait()
+	}()
+	select {
+	case err := <-done:
+		plog.Debugf("Running cmd=%v res=%v", cmd, err)
+		return exitError(cmd, err)
+	case <-ctx.Done():
+	}
+
+	select {
+	case id := <-pgid:
+		killProcessGroup(client, id, "TERM")
+		select {
+		case <-done:
+			return errors.Wrapf(ctx.Err(), "running %q", cmd)
+		case <-time.After(CommandKillTimeout):
+			killProcessGroup(client, id, "KILL")
+		}
+	default:
+		// The shell hasn't got as far as running the command
+	}
+	session.Close()
+	<-done
+	return errors.Wrapf(ctx.Err(), "running %q", cmd)
+}
+
+// exitError converts the result of ssh.Session.Wait into an *ExitError.
+func exitError(cmd string, err error) error {
+	var exitErr *ssh.ExitError
+	var missingErr *ssh.ExitMissingError
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &exitErr):
+		return &ExitError{Command: cmd, Status: exitErr.ExitStatus(), Signal: exitErr.Signal()}
+	case errors.As(err, &missingErr):
+		return &ExitError{Command: cmd, Status: -1}
+	default:
+		return err
+	}
+}
+
+func killProcessGroup(client *ssh.Client, pgid int, signal string) {
+	// The command may have used sudo, so kill with it too
+	out, err := runSSHCommand(client, fmt.Sprintf("sudo kill -s %s -- -%d", signal, pgid))
+	if err != nil {
+		plog.Debugf("killing process group %d with SIG%s: %v: %s", pgid, signal, err, out)
+	}
+}
+
+// pgidWriter picks the process group ID reported by the remote shell out
+// of stderr, and passes the rest through.
+type pgidWriter struct {
+	w    io.Writer
+	pgid chan<- int
+	buf  []byte
+	done bool
+}
+
+func (p *pgidWriter) Write(b []byte) (int, error) {
+	n := len(b)
+	if !p.done {
+		p.buf = append(p.buf, b...)
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			return n, nil
+		}
+		p.done = true
+		b = p.buf
+		if rest, ok := strings.CutPrefix(string(p.buf[:i]), pgidMarker); ok {
+			if id, err := strconv.Atoi(rest); err == nil {
+				p.pgid <- id
+				b = p.buf[i+1:]
+			}
+		}
+		p.buf = nil
+		if len(b) == 0 {
+			return n, nil
+		}
+	}
+	if _, err := p.w.Write(b); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
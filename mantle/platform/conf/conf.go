@@ -84,6 +84,7 @@ type UserData struct {
 	kind      kind
 	data      string
 	extraKeys []*agent.Key // SSH keys to be injected during rendering
+	template  bool         // data is a Go template; see Template
 }
 
 // Conf is a configuration for a CoreOS machine. Only Ignition spec 3 and later
@@ -1290,7 +1291,7 @@ func (c *Conf) CopyKeys(keys []*agent.Key) {
 	c.AddAuthorizedKeys("core", keyStrs)
 }
 
-func (c *Conf) addConfigSourceV3(source string) {
+func (c *Conf) addConfigSourceV3(source string, hash *string) {
 	newConfig := v3types.Config{
 		Ignition: v3types.Ignition{
 			Version: "3.0.0",
@@ -1298,6 +1299,9 @@ func (c *Conf) addConfigSourceV3(source string) {
 				Merge: []v3types.ConfigReference{
 					{
 						Source: &source,
+						Verification: v3types.Verification{
+							Hash: hash,
+						},
 					},
 				},
 			},
@@ -1306,7 +1310,7 @@ func (c *Conf) addConfigSourceV3(source string) {
 	c.MergeV3(newConfig)
 }
 
-func (c *Conf) addConfigSourceV31(source string) {
+func (c *Conf) addConfigSourceV31(source string, hash *string) {
 	var resources []v31types.Resource
 	var headers []v31types.HTTPHeader
 	resources = append(resources, v31types.Resource{
@@ -1314,7 +1318,7 @@ func (c *Conf) addConfigSourceV31(source string) {
 		HTTPHeaders: headers,
 		Source:      &source,
 		Verification: v31types.Verification{
-			Hash: nil,
+			Hash: hash,
 		},
 	})
 	newConfig := v31types.Config{
@@ -1328,7 +1332,7 @@ func (c *Conf) addConfigSourceV31(source string) {
 	c.MergeV31(newConfig)
 }
 
-func (c *Conf) addConfigSourceV32(source string) {
+func (c *Conf) addConfigSourceV32(source string, hash *string) {
 	var resources []v32types.Resource
 	var headers []v32types.HTTPHeader
 	resources = append(resources, v32types.Resource{
@@ -1336,7 +1340,7 @@ func (c *Conf) addConfigSourceV32(source string) {
 		HTTPHeaders: headers,
 		Source:      &source,
 		Verification: v32types.Verification{
-			Hash: nil,
+			Hash: hash,
 		},
 	})
 	newConfig := v32types.Config{
@@ -1350,7 +1354,7 @@ func (c *Conf) addConfigSourceV32(source string) {
 	c.MergeV32(newConfig)
 }
 
-func (c *Conf) addConfigSourceV33(source string) {
+func (c *Conf) addConfigSourceV33(source string, hash *string) {
 	var resources []v33types.Resource
 	var headers []v33types.HTTPHeader
 	resources = append(resources, v33types.Resource{
@@ -1358,7 +1362,7 @@ func (c *Conf) addConfigSourceV33(source string) {
 		HTTPHeaders: headers,
 		Source:      &source,
 		Verification: v33types.Verification{
-			Hash: nil,
+			Hash: hash,
 		},
 	})
 	newConfig := v33types.Config{
@@ -1372,7 +1376,7 @@ func (c *Conf) addConfigSourceV33(source string) {
 	c.MergeV33(newConfig)
 }
 
-func (c *Conf) addConfigSourceV34(source string) {
+func (c *Conf) addConfigSourceV34(source string, hash *string) {
 	var resources []v34types.Resource
 	var headers []v34types.HTTPHeader
 	resources = append(resources, v34types.Resource{
@@ -1380,7 +1384,7 @@ func (c *Conf) addConfigSourceV34(source string) {
 		HTTPHeaders: headers,
 		Source:      &source,
 		Verification: v34types.Verification{
-			Hash: nil,
+			Hash: hash,
 		},
 	})
 	newConfig := v34types.Config{
@@ -1394,7 +1398,7 @@ func (c *Conf) addConfigSourceV34(source string) {
 	c.MergeV34(newConfig)
 }
 
-func (c *Conf) addConfigSourceV35(source string) {
+func (c *Conf) addConfigSourceV35(source string, hash *string) {
 	var resources []v35types.Resource
 	var headers []v35types.HTTPHeader
 	resources = append(resources, v35types.Resource{
@@ -1402,7 +1406,7 @@ func (c *Conf) addConfigSourceV35(source string) {
 		HTTPHeaders: headers,
 		Source:      &source,
 		Verification: v35types.Verification{
-			Hash: nil,
+			Hash: hash,
 		},
 	})
 	newConfig := v35types.Config{
@@ -1416,7 +1420,7 @@ func (c *Conf) addConfigSourceV35(source string) {
 	c.MergeV35(newConfig)
 }
 
-func (c *Conf) addConfigSourceV36exp(source string) {
+func (c *Conf) addConfigSourceV36exp(source string, hash *string) {
 	var resources []v36exptypes.Resource
 	var headers []v36exptypes.HTTPHeader
 	resources = append(resources, v36exptypes.Resource{
@@ -1424,7 +1428,7 @@ func (c *Conf) addConfigSourceV36exp(source string) {
 		HTTPHeaders: headers,
 		Source:      &source,
 		Verification: v36exptypes.Verification{
-			Hash: nil,
+			Hash: hash,
 		},
 	})
 	newConfig := v36exptypes.Config{
@@ -1441,20 +1445,31 @@ func (c *Conf) addConfigSourceV36exp(source string) {
 // AddConfigSource adds an Ignition config to merge (v3) the
 // config available at the `source` URL with the current config.
 func (c *Conf) AddConfigSource(source string) {
+	c.addConfigSource(source, nil)
+}
+
+// AddVerifiedConfigSource is like AddConfigSource, but Ignition only
+// merges the config at `source` if it has the hash, such as
+// "sha512-<hex digest>".
+func (c *Conf) AddVerifiedConfigSource(source, hash string) {
+	c.addConfigSource(source, &hash)
+}
+
+func (c *Conf) addConfigSource(source string, hash *string) {
 	if c.ignitionV3 != nil {
-		c.addConfigSourceV3(source)
+		c.addConfigSourceV3(source, hash)
 	} else if c.ignitionV31 != nil {
-		c.addConfigSourceV31(source)
+		c.addConfigSourceV31(source, hash)
 	} else if c.ignitionV32 != nil {
-		c.addConfigSourceV32(source)
+		c.addConfigSourceV32(source, hash)
 	} else if c.ignitionV33 != nil {
-		c.addConfigSourceV33(source)
+		c.addConfigSourceV33(source, hash)
 	} else if c.ignitionV34 != nil {
-		c.addConfigSourceV34(source)
+		c.addConfigSourceV34(source, hash)
 	} else if c.ignitionV35 != nil {
-		c.addConfigSourceV35(source)
+		c.addConfigSourceV35(source, hash)
 	} else if c.ignitionV36exp != nil {
-		c.addConfigSourceV36exp(source)
+		c.addConfigSourceV36exp(source, hash)
 	}
 }
 
@@ -1525,6 +1540,23 @@ WantedBy=multi-user.target
 	c.AddSystemdUnit(fmt.Sprintf("%s.mount", systemdunit.UnitNameEscape(dest[1:])), content, Enable)
 }
 
+// MountDevice adds an Ignition config to mount a filesystem read-only from
+// a device, such as a disk QemuBuilder.AddDirectoryImage attached.
+func (c *Conf) MountDevice(device, dest string) {
+	content := fmt.Sprintf(`[Unit]
+DefaultDependencies=no
+After=systemd-tmpfiles-setup.service
+Before=basic.target
+[Mount]
+What=%s
+Where=%s
+Options=ro
+[Install]
+WantedBy=multi-user.target
+`, device, dest)
+	c.AddSystemdUnit(fmt.Sprintf("%s.mount", systemdunit.UnitNameEscape(dest[1:])), content, Enable)
+}
+
 func makeGzipDataUrl(data []byte) (string, error) {
 	var buf bytes.Buffer
 	gz, err := gzip.NewWriterLevel(&buf, 9)
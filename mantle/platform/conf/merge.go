@@ -0,0 +1,335 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/coreos/go-semver/semver"
+	v3 "github.com/coreos/ignition/v2/config/v3_0"
+	v3types "github.com/coreos/ignition/v2/config/v3_0/types"
+	v31 "github.com/coreos/ignition/v2/config/v3_1"
+	v32 "github.com/coreos/ignition/v2/config/v3_2"
+	v32types "github.com/coreos/ignition/v2/config/v3_2/types"
+	v33 "github.com/coreos/ignition/v2/config/v3_3"
+	v33types "github.com/coreos/ignition/v2/config/v3_3/types"
+	v34 "github.com/coreos/ignition/v2/config/v3_4"
+	v35 "github.com/coreos/ignition/v2/config/v3_5"
+	v35types "github.com/coreos/ignition/v2/config/v3_5/types"
+	v36exp "github.com/coreos/ignition/v2/config/v3_6_experimental"
+)
+
+// The typed mutators below build a fragment with the oldest spec which
+// supports what they add, and merge it as a child config, so they work
+// with a config of that spec or any later one.
+
+// Version returns the Ignition spec version of the config.
+func (c *Conf) Version() semver.Version {
+	var version string
+	if c.ignitionV3 != nil {
+		version = c.ignitionV3.Ignition.Version
+	} else if c.ignitionV31 != nil {
+		version = c.ignitionV31.Ignition.Version
+	} else if c.ignitionV32 != nil {
+		version = c.ignitionV32.Ignition.Version
+	} else if c.ignitionV33 != nil {
+		version = c.ignitionV33.Ignition.Version
+	} else if c.ignitionV34 != nil {
+		version = c.ignitionV34.Ignition.Version
+	} else if c.ignitionV35 != nil {
+		version = c.ignitionV35.Ignition.Version
+	} else if c.ignitionV36exp != nil {
+		version = c.ignitionV36exp.Ignition.Version
+	}
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return semver.Version{}
+	}
+	return *v
+}
+
+// Merge merges other into the config as a child with Ignition's merging
+// semantics: other's entries replace the config's with the same key, such
+// as the path of a file or the name of a unit or user, and are appended
+// otherwise. other must use the config's spec or an older one, which is
+// translated.
+func (c *Conf) Merge(other *Conf) error {
+	return c.mergeRaw([]byte(other.String()))
+}
+
+// mergeRaw merges the serialized config as a child.
+func (c *Conf) mergeRaw(raw []byte) error {
+	var err error
+	if c.ignitionV3 != nil {
+		child, _, perr := v3.ParseCompatibleVersion(raw)
+		if err = perr; err == nil {
+			c.MergeV3(child)
+		}
+	} else if c.ignitionV31 != nil {
+		child, _, perr := v31.ParseCompatibleVersion(raw)
+		if err = perr; err == nil {
+			c.MergeV31(child)
+		}
+	} else if c.ignitionV32 != nil {
+		child, _, perr := v32.ParseCompatibleVersion(raw)
+		if err = perr; err == nil {
+			c.MergeV32(child)
+		}
+	} else if c.ignitionV33 != nil {
+		child, _, perr := v33.ParseCompatibleVersion(raw)
+		if err = perr; err == nil {
+			c.MergeV33(child)
+		}
+	} else if c.ignitionV34 != nil {
+		child, _, perr := v34.ParseCompatibleVersion(raw)
+		if err = perr; err == nil {
+			c.MergeV34(child)
+		}
+	} else if c.ignitionV35 != nil {
+		child, _, perr := v35.ParseCompatibleVersion(raw)
+		if err = perr; err == nil {
+			c.MergeV35(child)
+		}
+	} else if c.ignitionV36exp != nil {
+		child, _, perr := v36exp.ParseCompatibleVersion(raw)
+		if err = perr; err == nil {
+			c.MergeV36exp(child)
+		}
+	} else {
+		return fmt.Errorf("can't merge into a config which isn't Ignition")
+	}
+	if err != nil {
+		return fmt.Errorf("merging into spec %s config: %v", c.Version(), err)
+	}
+	return nil
+}
+
+// mergeFragment merges a config of spec version, which must be the
+// config's or an older one, as a child.
+func (c *Conf) mergeFragment(fragment interface{}, version string, what string) error {
+	if c.Version().LessThan(*semver.New(version)) {
+		return fmt.Errorf("%s needs Ignition spec %s or later, but the config is spec %s", what, version, c.Version())
+	}
+	raw, err := json.Marshal(fragment)
+	if err != nil {
+		return err
+	}
+	return c.mergeRaw(raw)
+}
+
+// MergeConfigs merges the configs in order, each as a child of those
+// before it, into a new config of the newest spec among them. Unlike
+// MergeAllConfigs, the result has the merged contents rather than
+// references to the configs for Ignition to merge.
+func MergeConfigs(confs []*Conf) (*Conf, error) {
+	if len(confs) == 0 {
+		return nil, fmt.Errorf("no configs to merge")
+	}
+	newest := confs[0].Version()
+	for _, c := range confs[1:] {
+		if v := c.Version(); newest.LessThan(v) {
+			newest = v
+		}
+	}
+	merged, err := Ignition(fmt.Sprintf(`{"ignition": {"version": "%s"}}`, newest)).Render(IgnoreWarnings)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range confs {
+		if err := merged.Merge(c); err != nil {
+			return nil, err
+		}
+	}
+	return merged, nil
+}
+
+// User is a user account for AddUser. Unset fields keep Ignition's
+// defaults.
+type User struct {
+	Name              string
+	PasswordHash      string
+	SSHAuthorizedKeys []string
+	Groups            []string
+	HomeDir           string
+	Shell             string
+	UID               *int
+}
+
+// AddUser adds or, if it exists, modifies a user.
+func (c *Conf) AddUser(user User) error {
+	u := v3types.PasswdUser{
+		Name: user.Name,
+		UID:  user.UID,
+	}
+	if user.PasswordHash != "" {
+		u.PasswordHash = &user.PasswordHash
+	}
+	if user.HomeDir != "" {
+		u.HomeDir = &user.HomeDir
+	}
+	if user.Shell != "" {
+		u.Shell = &user.Shell
+	}
+	for _, key := range user.SSHAuthorizedKeys {
+		u.SSHAuthorizedKeys = append(u.SSHAuthorizedKeys, v3types.SSHAuthorizedKey(key))
+	}
+	for _, group := range user.Groups {
+		u.Groups = append(u.Groups, v3types.Group(group))
+	}
+	return c.mergeFragment(v3types.Config{
+		Ignition: v3types.Ignition{Version: "3.0.0"},
+		Passwd:   v3types.Passwd{Users: []v3types.PasswdUser{u}},
+	}, "3.0.0", "adding a user")
+}
+
+// Filesystem is a filesystem for AddFilesystem.
+type Filesystem struct {
+	Device string
+	// Format, e.g. "xfs"
+	Format string
+	Label  string
+	// Path, if set, is where the filesystem is mounted.
+	Path string
+	// WipeFilesystem creates the filesystem even if one exists.
+	WipeFilesystem bool
+}
+
+// AddFilesystem adds a filesystem.
+func (c *Conf) AddFilesystem(fs Filesystem) error {
+	f := v3types.Filesystem{
+		Device:         fs.Device,
+		Format:         &fs.Format,
+		WipeFilesystem: &fs.WipeFilesystem,
+	}
+	if fs.Label != "" {
+		f.Label = &fs.Label
+	}
+	if fs.Path != "" {
+		f.Path = &fs.Path
+	}
+	return c.mergeFragment(v3types.Config{
+		Ignition: v3types.Ignition{Version: "3.0.0"},
+		Storage:  v3types.Storage{Filesystems: []v3types.Filesystem{f}},
+	}, "3.0.0", "adding a filesystem")
+}
+
+// AddRaid adds a software RAID array of the devices, at /dev/md/NAME, with
+// the level, e.g. "raid1".
+func (c *Conf) AddRaid(name, level string, devices []string) error {
+	raid := v3types.Raid{
+		Name:  name,
+		Level: level,
+	}
+	for _, device := range devices {
+		raid.Devices = append(raid.Devices, v3types.Device(device))
+	}
+	return c.mergeFragment(v3types.Config{
+		Ignition: v3types.Ignition{Version: "3.0.0"},
+		Storage:  v3types.Storage{Raid: []v3types.Raid{raid}},
+	}, "3.0.0", "adding a RAID array")
+}
+
+// Tang is a Tang server a LUKS volume is bound to.
+type Tang struct {
+	URL        string
+	Thumbprint string
+}
+
+// Luks is a LUKS volume for AddLuksDevice. It's bound with Clevis to the
+// TPM2 and Tang servers, of which Threshold are needed to unlock it, or
+// with CEX to a crypto express card.
+type Luks struct {
+	// Name of the opened volume in /dev/mapper
+	Name   string
+	Device string
+	Label  string
+	// WipeVolume creates the volume even if one exists.
+	WipeVolume bool
+
+	TPM2      bool
+	Tang      []Tang
+	Threshold int
+	// CEX binds the volume to a crypto express card; it needs spec 3.5.0.
+	CEX bool
+}
+
+// AddLuksDevice adds a LUKS volume. It needs Ignition spec 3.2.0, or
+// 3.5.0 for CEX.
+func (c *Conf) AddLuksDevice(luks Luks) error {
+	if luks.CEX {
+		enabled := true
+		l := v35types.Luks{
+			Name:       luks.Name,
+			Device:     &luks.Device,
+			WipeVolume: &luks.WipeVolume,
+			Cex:        v35types.Cex{Enabled: &enabled},
+		}
+		if luks.Label != "" {
+			l.Label = &luks.Label
+		}
+		return c.mergeFragment(v35types.Config{
+			Ignition: v35types.Ignition{Version: "3.5.0"},
+			Storage:  v35types.Storage{Luks: []v35types.Luks{l}},
+		}, "3.5.0", "adding a CEX LUKS volume")
+	}
+
+	l := v32types.Luks{
+		Name:       luks.Name,
+		Device:     &luks.Device,
+		WipeVolume: &luks.WipeVolume,
+	}
+	if luks.Label != "" {
+		l.Label = &luks.Label
+	}
+	if luks.TPM2 || len(luks.Tang) > 0 {
+		l.Clevis = &v32types.Clevis{}
+		if luks.TPM2 {
+			l.Clevis.Tpm2 = &luks.TPM2
+		}
+		for _, tang := range luks.Tang {
+			t := v32types.Tang{URL: tang.URL}
+			if tang.Thumbprint != "" {
+				thumbprint := tang.Thumbprint
+				t.Thumbprint = &thumbprint
+			}
+			l.Clevis.Tang = append(l.Clevis.Tang, t)
+		}
+		if luks.Threshold > 0 {
+			l.Clevis.Threshold = &luks.Threshold
+		}
+	}
+	return c.mergeFragment(v32types.Config{
+		Ignition: v32types.Ignition{Version: "3.2.0"},
+		Storage:  v32types.Storage{Luks: []v32types.Luks{l}},
+	}, "3.2.0", "adding a LUKS volume")
+}
+
+// SetKernelArguments makes sure the kernel arguments in shouldExist are
+// present and those in shouldNotExist absent. It needs Ignition spec
+// 3.3.0.
+func (c *Conf) SetKernelArguments(shouldExist, shouldNotExist []string) error {
+	var kargs v33types.KernelArguments
+	for _, arg := range shouldExist {
+		kargs.ShouldExist = append(kargs.ShouldExist, v33types.KernelArgument(arg))
+	}
+	for _, arg := range shouldNotExist {
+		kargs.ShouldNotExist = append(kargs.ShouldNotExist, v33types.KernelArgument(arg))
+	}
+	return c.mergeFragment(v33types.Config{
+		Ignition:        v33types.Ignition{Version: "3.3.0"},
+		KernelArguments: kargs,
+	}, "3.3.0", "setting kernel arguments")
+}
@@ -0,0 +1,163 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/ssh/agent"
+	"gopkg.in/yaml.v3"
+)
+
+// cloudConfigHeader starts cloud-init user data which is a cloud-config,
+// rather than e.g. a script.
+const cloudConfigHeader = "#cloud-config"
+
+// NoCloudSeed is the configuration cloud-init's NoCloud datasource reads
+// from a filesystem labeled "cidata", for distros built with cosa which are
+// provisioned by cloud-init rather than Ignition.
+type NoCloudSeed struct {
+	InstanceID string
+	Hostname   string
+	// UserData is a cloud-config, or another kind of user data cloud-init
+	// accepts; authorized keys can only be added to a cloud-config
+	UserData string
+	// NetworkConfig, if set, is a network config in version 1 or 2 format
+	NetworkConfig string
+
+	authorizedKeys map[string][]string
+	users          []string
+}
+
+// NewNoCloudSeed returns a seed with an empty cloud-config.
+func NewNoCloudSeed(instanceID string) *NoCloudSeed {
+	return &NoCloudSeed{
+		InstanceID:     instanceID,
+		UserData:       cloudConfigHeader + "\n",
+		authorizedKeys: make(map[string][]string),
+	}
+}
+
+// AddAuthorizedKeys adds authorized SSH keys for the given user, who is
+// created with passwordless sudo if the cloud-config doesn't list them.
+func (s *NoCloudSeed) AddAuthorizedKeys(user string, keys []string) {
+	if _, ok := s.authorizedKeys[user]; !ok {
+		s.users = append(s.users, user)
+	}
+	s.authorizedKeys[user] = append(s.authorizedKeys[user], keys...)
+}
+
+// CopyKeys adds the keys as authorized SSH keys for the core user, like
+// Conf.CopyKeys.
+func (s *NoCloudSeed) CopyKeys(keys []*agent.Key) {
+	var keyStrs []string
+	for _, key := range keys {
+		keyStrs = append(keyStrs, key.String())
+	}
+	s.AddAuthorizedKeys("core", keyStrs)
+}
+
+// MetaData returns the seed's meta-data.
+func (s *NoCloudSeed) MetaData() (string, error) {
+	meta := map[string]string{"instance-id": s.InstanceID}
+	if s.Hostname != "" {
+		meta["local-hostname"] = s.Hostname
+	}
+	buf, err := yaml.Marshal(meta)
+	return string(buf), err
+}
+
+// RenderUserData returns the seed's user-data, with the authorized keys
+// added to the cloud-config.
+func (s *NoCloudSeed) RenderUserData() (string, error) {
+	if len(s.users) == 0 {
+		return s.UserData, nil
+	}
+	if !strings.HasPrefix(s.UserData, cloudConfigHeader) {
+		return "", fmt.Errorf("can't add authorized keys to user data which isn't a cloud-config")
+	}
+	var config map[string]interface{}
+	if err := yaml.Unmarshal([]byte(s.UserData), &config); err != nil {
+		return "", fmt.Errorf("parsing cloud-config: %v", err)
+	}
+	if config == nil {
+		config = make(map[string]interface{})
+	}
+	var users []interface{}
+	switch v := config["users"].(type) {
+	case nil:
+		// keep the distro's default user
+		users = []interface{}{"default"}
+	case []interface{}:
+		users = v
+	default:
+		return "", fmt.Errorf("cloud-config users must be a list")
+	}
+	for _, name := range s.users {
+		found := false
+		for _, u := range users {
+			if user, ok := u.(map[string]interface{}); ok && user["name"] == name {
+				keys, _ := user["ssh_authorized_keys"].([]interface{})
+				for _, key := range s.authorizedKeys[name] {
+					keys = append(keys, key)
+				}
+				user["ssh_authorized_keys"] = keys
+				found = true
+			}
+		}
+		if !found {
+			users = append(users, map[string]interface{}{
+				"name":                name,
+				"sudo":                "ALL=(ALL) NOPASSWD:ALL",
+				"ssh_authorized_keys": s.authorizedKeys[name],
+			})
+		}
+	}
+	config["users"] = users
+	buf, err := yaml.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+	return cloudConfigHeader + "\n" + string(buf), nil
+}
+
+// WriteDir writes the seed's user-data, meta-data and network-config to
+// dir, from which the cidata filesystem is made.
+func (s *NoCloudSeed) WriteDir(dir string) error {
+	userData, err := s.RenderUserData()
+	if err != nil {
+		return err
+	}
+	metaData, err := s.MetaData()
+	if err != nil {
+		return err
+	}
+	files := map[string]string{
+		"user-data": userData,
+		"meta-data": metaData,
+	}
+	if s.NetworkConfig != "" {
+		files["network-config"] = s.NetworkConfig
+	}
+	for name, contents := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/vincent-petithory/dataurl"
+)
+
+// redacted replaces the secrets in redacted configs.
+const redacted = "REDACTED"
+
+// RedactMatcher selects secrets to redact from copies of configs written
+// for debugging. A value is redacted if the name of its field matches
+// Field, or if it's a string matching Value. The contents of data URLs are
+// matched decoded, and the whole URL is redacted.
+type RedactMatcher struct {
+	Field *regexp.Regexp
+	Value *regexp.Regexp
+}
+
+// DefaultRedactMatchers match password hashes, private keys, registry
+// auth files, HTTP credentials and lines assigning tokens, secrets or
+// passwords.
+var DefaultRedactMatchers = []RedactMatcher{
+	{Field: regexp.MustCompile(`^passwordHash$`)},
+	{Value: regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY-----`)},
+	{Value: regexp.MustCompile(`"auths"\s*:`)},
+	{Value: regexp.MustCompile(`(?i)^(basic|bearer|token) \S`)},
+	{Value: regexp.MustCompile(`(?i)\b[a-z_]*(token|secret|password|passwd)\s*[:=]\s*\S`)},
+}
+
+// RedactMatchers are the matchers used by Redacted and WriteRedactedFile.
+var RedactMatchers = append([]RedactMatcher(nil), DefaultRedactMatchers...)
+
+// AddRedactPattern adds a matcher redacting strings and the contents of
+// data URLs matching the regular expression.
+func AddRedactPattern(pattern string) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+	RedactMatchers = append(RedactMatchers, RedactMatcher{Value: re})
+	return nil
+}
+
+// Redacted returns the userdata in Conf with the secrets RedactMatchers
+// match replaced, for writing to logs and output directories. The config
+// itself is left intact.
+func (c *Conf) Redacted() (string, error) {
+	if !c.IsIgnition() {
+		return c.String(), nil
+	}
+	dec := json.NewDecoder(strings.NewReader(c.String()))
+	dec.UseNumber()
+	var tree interface{}
+	if err := dec.Decode(&tree); err != nil {
+		return "", fmt.Errorf("parsing config to redact: %v", err)
+	}
+	buf, err := json.Marshal(redact(tree, RedactMatchers))
+	if err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// WriteRedactedFile writes the userdata in Conf to a local file like
+// WriteFile, with its secrets redacted.
+func (c *Conf) WriteRedactedFile(name string) error {
+	data, err := c.Redacted()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(name, []byte(data), 0666)
+}
+
+// redact returns the JSON value with the fields and strings the matchers
+// match replaced.
+func redact(value interface{}, matchers []RedactMatcher) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		compression, _ := v["compression"].(string)
+		for field, child := range v {
+			if s, ok := child.(string); ok && field == "source" {
+				if !matchesValue(matchers, dataURLContents(s, compression)) {
+					continue
+				}
+				if strings.HasPrefix(s, "data:") {
+					v[field] = "data:," + redacted
+				} else {
+					v[field] = redacted
+				}
+				continue
+			}
+			if child != nil && matchesField(matchers, field) {
+				v[field] = redacted
+				continue
+			}
+			v[field] = redact(child, matchers)
+		}
+	case []interface{}:
+		for i, child := range v {
+			v[i] = redact(child, matchers)
+		}
+	case string:
+		if matchesValue(matchers, v) {
+			return redacted
+		}
+	}
+	return value
+}
+
+// dataURLContents returns the decoded contents of a data URL, or the
+// source itself if it's another kind of URL or can't be decoded.
+func dataURLContents(source, compression string) string {
+	if !strings.HasPrefix(source, "data:") {
+		return source
+	}
+	url, err := dataurl.DecodeString(source)
+	if err != nil {
+		return source
+	}
+	if compression != "gzip" {
+		return string(url.Data)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(url.Data))
+	if err != nil {
+		return source
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return source
+	}
+	return string(data)
+}
+
+func matchesField(matchers []RedactMatcher, field string) bool {
+	for _, m := range matchers {
+		if m.Field != nil && m.Field.MatchString(field) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesValue(matchers []RedactMatcher, value string) bool {
+	for _, m := range matchers {
+		if m.Value != nil && m.Value.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/coreos/go-semver/semver"
+)
+
+// specReleases are the Ignition releases which first accepted each stable
+// spec version, newest first.
+var specReleases = []struct {
+	spec    string
+	release string
+}{
+	{"3.5.0", "2.20.0"},
+	{"3.4.0", "2.15.0"},
+	{"3.3.0", "2.11.0"},
+	{"3.2.0", "2.7.0"},
+	{"3.1.0", "2.3.0"},
+	{"3.0.0", "2.0.0"},
+}
+
+// MaxSpecVersion returns the newest stable spec version accepted by the
+// Ignition release, such as "2.19.0".
+func MaxSpecVersion(release string) (semver.Version, error) {
+	v, err := semver.NewVersion(release)
+	if err != nil {
+		return semver.Version{}, fmt.Errorf("parsing Ignition version %q: %v", release, err)
+	}
+	for _, r := range specReleases {
+		if !v.LessThan(*semver.New(r.release)) {
+			return *semver.New(r.spec), nil
+		}
+	}
+	return semver.Version{}, fmt.Errorf("Ignition %s predates spec 3.0.0", release)
+}
+
+// CheckSpecVersion returns an error if the config, or a config it
+// embeds to merge or replace itself with, has a spec version newer than
+// max, which Ignition would refuse at first boot.
+func (c *Conf) CheckSpecVersion(max semver.Version) error {
+	if !c.IsIgnition() {
+		return nil
+	}
+	if v := c.Version(); max.LessThan(v) {
+		return fmt.Errorf("config has Ignition spec %s, but the Ignition under test only accepts up to %s", v, max)
+	}
+	for _, source := range c.embeddedConfigs() {
+		embedded, err := Ignition(source).Render(IgnoreWarnings)
+		if err != nil {
+			return fmt.Errorf("parsing embedded config: %v", err)
+		}
+		if err := embedded.CheckSpecVersion(max); err != nil {
+			return fmt.Errorf("embedded config: %v", err)
+		}
+	}
+	return nil
+}
+
+// embeddedConfigs returns the configs the config merges or replaces
+// itself with from data URLs.
+func (c *Conf) embeddedConfigs() []string {
+	type reference struct {
+		Source      *string `json:"source"`
+		Compression *string `json:"compression"`
+	}
+	var config struct {
+		Ignition struct {
+			Config struct {
+				Merge   []reference `json:"merge"`
+				Replace reference   `json:"replace"`
+			} `json:"config"`
+		} `json:"ignition"`
+	}
+	if err := json.Unmarshal([]byte(c.String()), &config); err != nil {
+		return nil
+	}
+	var configs []string
+	refs := append(config.Ignition.Config.Merge, config.Ignition.Config.Replace)
+	for _, ref := range refs {
+		if ref.Source == nil || !strings.HasPrefix(*ref.Source, "data:") {
+			continue
+		}
+		var compression string
+		if ref.Compression != nil {
+			compression = *ref.Compression
+		}
+		configs = append(configs, dataURLContents(*ref.Source, compression))
+	}
+	return configs
+}
@@ -0,0 +1,54 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// Template returns a copy of the userdata marked as a Go template, which
+// RenderUserData executes with the variables of the machine it's rendered
+// for before parsing it. Templates are strict: referencing a variable or
+// function which doesn't exist fails. Userdata which isn't marked is never
+// executed, so configs containing "{{" elsewhere are left alone.
+func (u *UserData) Template() *UserData {
+	ret := *u
+	ret.template = true
+	return &ret
+}
+
+// IsTemplate returns whether the userdata is marked as a Go template.
+func (u *UserData) IsTemplate() bool {
+	return u.template
+}
+
+// Execute executes the userdata template with the data and functions, and
+// returns the result, which is no longer a template.
+func (u *UserData) Execute(data interface{}, funcs template.FuncMap) (*UserData, error) {
+	tmpl, err := template.New("userdata").Funcs(funcs).Option("missingkey=error").Parse(u.data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing userdata template: %v", err)
+	}
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("executing userdata template: %v", err)
+	}
+	ret := *u
+	ret.data = buf.String()
+	ret.template = false
+	return &ret, nil
+}
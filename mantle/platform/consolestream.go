@@ -0,0 +1,265 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package platform
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ConsoleLine is a line of a machine's console, with when it appeared.
+type ConsoleLine struct {
+	Time time.Time
+	Text string
+}
+
+// String formats the line as ConsoleStream readers return it: the time in
+// RFC 3339 format, a space, and the text.
+func (l ConsoleLine) String() string {
+	return l.Time.UTC().Format(time.RFC3339Nano) + " " + l.Text
+}
+
+// ParseConsoleLine parses a line returned by a ConsoleStream reader,
+// without its newline.
+func ParseConsoleLine(s string) (ConsoleLine, error) {
+	stamp, text, ok := strings.Cut(s, " ")
+	if !ok {
+		return ConsoleLine{}, fmt.Errorf("console line %q has no timestamp", s)
+	}
+	t, err := time.Parse(time.RFC3339Nano, stamp)
+	if err != nil {
+		return ConsoleLine{}, fmt.Errorf("parsing console line timestamp: %v", err)
+	}
+	return ConsoleLine{Time: t, Text: text}, nil
+}
+
+// ConsoleStreamer is implemented by machines whose console can be followed
+// while they're running.
+type ConsoleStreamer interface {
+	// ConsoleStream returns a reader of the machine's console from the
+	// start, one timestamped line at a time (see ParseConsoleLine). Reads
+	// block until more of the console arrives, and return io.EOF once the
+	// machine is destroyed.
+	ConsoleStream() io.ReadCloser
+}
+
+// ConsoleLog follows the console file of a running machine, recording when
+// each line appeared.
+type ConsoleLog struct {
+	mu      sync.Mutex
+	changed *sync.Cond
+	lines   []ConsoleLine
+	stopped bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewConsoleLog starts following the console file at consolePath, which
+// need not exist yet. The caller should create it right before starting
+// the machine, and must call Stop() once the machine is gone.
+func NewConsoleLog(consolePath string) *ConsoleLog {
+	l := &ConsoleLog{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	l.changed = sync.NewCond(&l.mu)
+	go func() {
+		defer close(l.done)
+		followConsole(consolePath, l.stop, l.add)
+		l.mu.Lock()
+		l.stopped = true
+		l.mu.Unlock()
+		l.changed.Broadcast()
+	}()
+	return l
+}
+
+func (l *ConsoleLog) add(line string, now time.Time) {
+	l.mu.Lock()
+	l.lines = append(l.lines, ConsoleLine{Time: now, Text: strings.TrimSuffix(line, "\r")})
+	l.mu.Unlock()
+	l.changed.Broadcast()
+}
+
+// Lines returns the lines of the console so far.
+func (l *ConsoleLog) Lines() []ConsoleLine {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]ConsoleLine(nil), l.lines...)
+}
+
+// next blocks until the console has a line after the first n, returning
+// false if it's stopped or the reader is closed first.
+func (l *ConsoleLog) next(n int, closed *bool) (ConsoleLine, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for n >= len(l.lines) && !l.stopped && !*closed {
+		l.changed.Wait()
+	}
+	if n >= len(l.lines) || *closed {
+		return ConsoleLine{}, false
+	}
+	return l.lines[n], true
+}
+
+// NewReader returns a reader of the console from the start; see
+// ConsoleStreamer.
+func (l *ConsoleLog) NewReader() io.ReadCloser {
+	return &consoleReader{log: l}
+}
+
+// Stop stops following the console, after reading what's left of it, and
+// ends its readers. It is safe to call multiple times.
+func (l *ConsoleLog) Stop() {
+	select {
+	case <-l.stop:
+	default:
+		close(l.stop)
+	}
+	<-l.done
+}
+
+type consoleReader struct {
+	log     *ConsoleLog
+	n       int
+	pending []byte
+	closed  bool
+}
+
+func (r *consoleReader) Read(p []byte) (int, error) {
+	if len(r.pending) == 0 {
+		line, ok := r.log.next(r.n, &r.closed)
+		if !ok {
+			return 0, io.EOF
+		}
+		r.n++
+		r.pending = []byte(line.String() + "\n")
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+func (r *consoleReader) Close() error {
+	r.log.mu.Lock()
+	r.closed = true
+	r.log.mu.Unlock()
+	r.log.changed.Broadcast()
+	return nil
+}
+
+// WaitForConsole blocks until a line of m's console matches re, returning
+// it, or ctx is done.
+func WaitForConsole(ctx context.Context, m Machine, re *regexp.Regexp) (ConsoleLine, error) {
+	streamer, ok := m.(ConsoleStreamer)
+	if !ok {
+		return ConsoleLine{}, fmt.Errorf("console of machine %s can't be followed", m.ID())
+	}
+	stream := streamer.ConsoleStream()
+	found := make(chan ConsoleLine, 1)
+	go func() {
+		defer close(found)
+		scanner := bufio.NewScanner(stream)
+		for scanner.Scan() {
+			line, err := ParseConsoleLine(scanner.Text())
+			if err == nil && re.MatchString(line.Text) {
+				found <- line
+				return
+			}
+		}
+	}()
+	defer stream.Close()
+	select {
+	case line, ok := <-found:
+		if !ok {
+			return ConsoleLine{}, fmt.Errorf("console of machine %s ended without matching %q", m.ID(), re)
+		}
+		return line, nil
+	case <-ctx.Done():
+		return ConsoleLine{}, fmt.Errorf("waiting for %q on console of machine %s: %v", re, m.ID(), ctx.Err())
+	}
+}
+
+// followConsole calls fn with each line of the console file at path as it
+// appears, until stop is closed, after which what's left of the file is
+// read. The file needn't exist yet.
+func followConsole(path string, stop <-chan struct{}, fn func(line string, now time.Time)) {
+	var f *os.File
+	defer func() {
+		if f != nil {
+			f.Close()
+		}
+	}()
+	var pending []byte
+	buf := make([]byte, 32*1024)
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	stopping := false
+	for {
+		select {
+		case <-stop:
+			stopping = true
+		case <-ticker.C:
+		}
+		if f == nil {
+			var err error
+			if f, err = os.Open(path); err != nil {
+				// qemu hasn't created it yet
+				if stopping {
+					return
+				}
+				continue
+			}
+		}
+		for {
+			n, err := f.Read(buf)
+			if n > 0 {
+				now := time.Now()
+				pending = append(pending, buf[:n]...)
+				for {
+					idx := bytes.IndexByte(pending, '\n')
+					if idx < 0 {
+						break
+					}
+					fn(string(pending[:idx]), now)
+					pending = pending[idx+1:]
+				}
+			}
+			if err == io.EOF || n == 0 {
+				break
+			} else if err != nil {
+				plog.Debugf("reading console %s: %v", path, err)
+				return
+			}
+		}
+		// Don't let a console that never emits a newline grow unbounded
+		if len(pending) > len(buf) || (stopping && len(pending) > 0) {
+			fn(string(pending), time.Now())
+			pending = nil
+		}
+		if stopping {
+			return
+		}
+	}
+}
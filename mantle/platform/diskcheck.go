@@ -0,0 +1,135 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package platform
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/coreos/coreos-assembler/mantle/system/exec"
+)
+
+// installWrittenPartitions are the partitions the install or the first boot
+// write to, so which differ from the metal image: the config, kernel
+// arguments and network config go to boot, and Ignition and growing the
+// filesystem change root.
+var installWrittenPartitions = map[string]bool{
+	"boot": true,
+	"root": true,
+}
+
+var partitionDeviceRe = regexp.MustCompile(`^(/dev/[a-z]+)([0-9]+)$`)
+
+// guestfish runs a one-shot guestfish with the disk image attached
+// read-only, and returns the output lines of the commands.
+func guestfish(image, format string, sectorSize int, commands ...string) ([]string, error) {
+	args := []string{"--ro"}
+	if sectorSize != 0 {
+		args = append(args, fmt.Sprintf("--blocksize=%d", sectorSize))
+	}
+	args = append(args, "--format="+format, "-a", image, "run")
+	for _, c := range commands {
+		args = append(args, ":")
+		args = append(args, strings.Fields(c)...)
+	}
+	cmd := exec.Command("guestfish", args...)
+	// see newGuestfish
+	cmd.Env = append(os.Environ(), "LIBGUESTFS_BACKEND=direct")
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "running guestfish on %s", image)
+	}
+	return strings.Split(strings.TrimSpace(string(out)), "\n"), nil
+}
+
+// PartitionChecksums returns the sha256 of each partition of a GPT disk
+// image, by partition name.
+func PartitionChecksums(image, format string, sectorSize int) (map[string]string, error) {
+	parts, err := guestfish(image, format, sectorSize, "list-partitions")
+	if err != nil {
+		return nil, err
+	}
+	var commands []string
+	for _, part := range parts {
+		m := partitionDeviceRe.FindStringSubmatch(part)
+		if m == nil {
+			return nil, fmt.Errorf("unexpected partition %q in %s", part, image)
+		}
+		commands = append(commands, fmt.Sprintf("part-get-name %s %s", m[1], m[2]), "checksum-device sha256 "+part)
+	}
+	out, err := guestfish(image, format, sectorSize, commands...)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) != 2*len(parts) {
+		return nil, fmt.Errorf("expected a name and checksum of %d partitions of %s, got %q", len(parts), image, out)
+	}
+	sums := make(map[string]string)
+	for i := 0; i < len(out); i += 2 {
+		sums[out[i]] = out[i+1]
+	}
+	return sums, nil
+}
+
+// VerifyInstalledDisk compares the partitions of the disk installed to
+// which neither the install nor the first boot write to against those of
+// the metal image, to catch corruption which booting doesn't reveal. The
+// machine must not be running.
+func (inst *Install) VerifyInstalledDisk(disk *Disk) error {
+	artifact, metalSectorSize := "metal", 0
+	if inst.Native4k {
+		artifact, metalSectorSize = "metal4k", 4096
+	}
+	metal, err := inst.CosaBuild.RequireDecompressedArtifact(artifact, inst.ImageCache)
+	if err != nil {
+		return err
+	}
+	want, err := PartitionChecksums(metal, "raw", metalSectorSize)
+	if err != nil {
+		return errors.Wrapf(err, "checksumming metal image")
+	}
+	got, err := PartitionChecksums(disk.Path(), "qcow2", disk.SectorSize)
+	if err != nil {
+		return errors.Wrapf(err, "checksumming installed disk")
+	}
+
+	var compared, mismatched []string
+	for name, sum := range want {
+		if installWrittenPartitions[name] {
+			continue
+		}
+		compared = append(compared, name)
+		if got[name] != sum {
+			mismatched = append(mismatched, name)
+		}
+	}
+	sort.Strings(compared)
+	sort.Strings(mismatched)
+	if len(mismatched) > 0 {
+		return fmt.Errorf("partitions %s of the installed disk differ from the metal image", strings.Join(mismatched, ", "))
+	}
+	if len(compared) == 0 {
+		plog.Noticef("No partitions of the installed disk to compare to the metal image")
+	} else {
+		plog.Noticef("Partitions %s of the installed disk match the metal image", strings.Join(compared, ", "))
+	}
+	return nil
+}
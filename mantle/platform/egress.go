@@ -0,0 +1,262 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package platform
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	pcapMagic     = 0xa1b2c3d4
+	pcapMagicNsec = 0xa1b23c4d
+	linkTypeEther = 1
+
+	etherTypeIPv4 = 0x0800
+	etherTypeIPv6 = 0x86dd
+	etherTypeVLAN = 0x8100
+
+	protoICMP   = 1
+	protoTCP    = 6
+	protoUDP    = 17
+	protoICMPv6 = 58
+
+	tcpFlagSYN = 0x02
+	tcpFlagACK = 0x10
+)
+
+var (
+	// slirpMAC is the MAC of QEMU's user-mode network stack, which
+	// everything the guest receives comes from
+	slirpMAC = net.HardwareAddr{0x52, 0x55, 0x0a, 0x00, 0x02, 0x02}
+	// the host and the DNS server in QEMU's user-mode network
+	slirpHosts = []net.IP{net.ParseIP("10.0.2.2"), net.ParseIP("fec0::2")}
+	slirpDNS   = []net.IP{net.ParseIP("10.0.2.3"), net.ParseIP("fec0::3")}
+)
+
+// EgressAttempt is an attempt by a machine to reach beyond the host: a
+// connection, a datagram or a ping to an outside address, or a DNS query.
+type EgressAttempt struct {
+	// Proto is "tcp", "udp", "icmp" or "dns"
+	Proto string
+	// Dst is the address reached, or for DNS the name queried
+	Dst  string
+	Port uint16
+}
+
+func (a EgressAttempt) String() string {
+	switch a.Proto {
+	case "dns":
+		return fmt.Sprintf("DNS query for %s", a.Dst)
+	case "icmp":
+		return fmt.Sprintf("ping to %s", a.Dst)
+	}
+	return fmt.Sprintf("%s to %s", a.Proto, net.JoinHostPort(a.Dst, fmt.Sprint(a.Port)))
+}
+
+// EgressAuditor is implemented by machines whose traffic is captured when
+// their cluster's RuntimeConfig.AuditEgress is set.
+type EgressAuditor interface {
+	// EgressAttempts returns the machine's attempts to reach beyond the
+	// host, once it's destroyed.
+	EgressAttempts() ([]EgressAttempt, error)
+}
+
+// ReadEgressCaptures returns the attempts to reach beyond the host in the
+// captures which QemuBuilder.CaptureDir made in dir, without duplicates.
+func ReadEgressCaptures(dir string) ([]EgressAttempt, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pcap"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	var attempts []EgressAttempt
+	seen := make(map[EgressAttempt]bool)
+	for _, path := range paths {
+		found, err := readEgressCapture(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %v", path, err)
+		}
+		for _, a := range found {
+			if !seen[a] {
+				seen[a] = true
+				attempts = append(attempts, a)
+			}
+		}
+	}
+	return attempts, nil
+}
+
+// readEgressCapture reads a pcap file of Ethernet frames on a user-mode
+// network.
+func readEgressCapture(path string) ([]EgressAttempt, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var header [24]byte
+	if _, err := io.ReadFull(f, header[:]); err != nil {
+		return nil, fmt.Errorf("reading header: %v", err)
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	switch order.Uint32(header[0:]) {
+	case pcapMagic, pcapMagicNsec:
+	default:
+		order = binary.BigEndian
+		if magic := order.Uint32(header[0:]); magic != pcapMagic && magic != pcapMagicNsec {
+			return nil, fmt.Errorf("not a pcap file")
+		}
+	}
+	if linkType := order.Uint32(header[20:]); linkType != linkTypeEther {
+		return nil, fmt.Errorf("unsupported link type %d", linkType)
+	}
+
+	var attempts []EgressAttempt
+	for {
+		var record [16]byte
+		if _, err := io.ReadFull(f, record[:]); err == io.EOF {
+			return attempts, nil
+		} else if err != nil {
+			// qemu was killed while writing the packet
+			return attempts, nil
+		}
+		frame := make([]byte, order.Uint32(record[8:]))
+		if _, err := io.ReadFull(f, frame); err != nil {
+			return attempts, nil
+		}
+		if a, ok := parseEgressFrame(frame); ok {
+			attempts = append(attempts, a)
+		}
+	}
+}
+
+// parseEgressFrame returns the attempt to reach beyond the host which the
+// frame is, if it is one.
+func parseEgressFrame(frame []byte) (EgressAttempt, bool) {
+	if len(frame) < 14 {
+		return EgressAttempt{}, false
+	}
+	// only what the guest sends
+	if net.HardwareAddr(frame[6:12]).String() == slirpMAC.String() {
+		return EgressAttempt{}, false
+	}
+	etherType := binary.BigEndian.Uint16(frame[12:])
+	payload := frame[14:]
+	if etherType == etherTypeVLAN && len(payload) >= 4 {
+		etherType = binary.BigEndian.Uint16(payload[2:])
+		payload = payload[4:]
+	}
+
+	var dst net.IP
+	var proto byte
+	switch etherType {
+	case etherTypeIPv4:
+		if len(payload) < 20 {
+			return EgressAttempt{}, false
+		}
+		ihl := int(payload[0]&0x0f) * 4
+		if ihl < 20 || len(payload) < ihl {
+			return EgressAttempt{}, false
+		}
+		// only first fragments have the transport header
+		if binary.BigEndian.Uint16(payload[6:])&0x1fff != 0 {
+			return EgressAttempt{}, false
+		}
+		dst = net.IP(payload[16:20])
+		proto = payload[9]
+		payload = payload[ihl:]
+	case etherTypeIPv6:
+		if len(payload) < 40 {
+			return EgressAttempt{}, false
+		}
+		dst = net.IP(payload[24:40])
+		proto = payload[6]
+		payload = payload[40:]
+	default:
+		return EgressAttempt{}, false
+	}
+	if !dst.IsGlobalUnicast() || dst.Equal(net.IPv4bcast) || containsIP(slirpHosts, dst) {
+		return EgressAttempt{}, false
+	}
+
+	switch proto {
+	case protoTCP:
+		if len(payload) < 14 {
+			return EgressAttempt{}, false
+		}
+		// only the SYNs opening connections
+		if flags := payload[13]; flags&tcpFlagSYN == 0 || flags&tcpFlagACK != 0 {
+			return EgressAttempt{}, false
+		}
+		return EgressAttempt{Proto: "tcp", Dst: dst.String(), Port: binary.BigEndian.Uint16(payload[2:])}, true
+	case protoUDP:
+		if len(payload) < 8 {
+			return EgressAttempt{}, false
+		}
+		port := binary.BigEndian.Uint16(payload[2:])
+		if port == 53 && containsIP(slirpDNS, dst) {
+			if name, ok := parseDNSQuestion(payload[8:]); ok {
+				return EgressAttempt{Proto: "dns", Dst: name}, true
+			}
+		}
+		return EgressAttempt{Proto: "udp", Dst: dst.String(), Port: port}, true
+	case protoICMP, protoICMPv6:
+		// echo requests
+		if len(payload) < 1 || (proto == protoICMP && payload[0] != 8) || (proto == protoICMPv6 && payload[0] != 128) {
+			return EgressAttempt{}, false
+		}
+		return EgressAttempt{Proto: "icmp", Dst: dst.String()}, true
+	}
+	return EgressAttempt{}, false
+}
+
+// parseDNSQuestion returns the name in the first question of the DNS
+// message.
+func parseDNSQuestion(msg []byte) (string, bool) {
+	if len(msg) < 12 || binary.BigEndian.Uint16(msg[4:]) == 0 {
+		return "", false
+	}
+	var labels []string
+	for i := 12; i < len(msg); {
+		n := int(msg[i])
+		if n == 0 {
+			return strings.Join(labels, "."), len(labels) > 0
+		}
+		// questions aren't compressed
+		if n&0xc0 != 0 || i+1+n > len(msg) {
+			return "", false
+		}
+		labels = append(labels, string(msg[i+1:i+1+n]))
+		i += 1 + n
+	}
+	return "", false
+}
+
+func containsIP(ips []net.IP, ip net.IP) bool {
+	for _, i := range ips {
+		if i.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
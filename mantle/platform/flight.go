@@ -16,13 +16,12 @@
 package platform
 
 import (
-	"fmt"
 	"sync"
 
-	"github.com/pborman/uuid"
 	"golang.org/x/crypto/ssh/agent"
 
 	"github.com/coreos/coreos-assembler/mantle/network"
+	"github.com/coreos/coreos-assembler/mantle/util"
 )
 
 type BaseFlight struct {
@@ -34,6 +33,9 @@ type BaseFlight struct {
 	baseopts *Options
 
 	agent *network.SSHAgent
+
+	userdataLock   sync.Mutex
+	userdataServer *userDataServer
 }
 
 func NewBaseFlight(opts *Options, platform Name) (*BaseFlight, error) {
@@ -48,7 +50,7 @@ func NewBaseFlightWithDialer(opts *Options, platform Name, dialer network.Dialer
 
 	bf := &BaseFlight{
 		clustermap: make(map[string]Cluster),
-		name:       fmt.Sprintf("%s-%s", opts.BaseName, uuid.New()),
+		name:       util.ResourceName(opts.BaseName),
 		platform:   platform,
 		baseopts:   opts,
 		agent:      agent,
@@ -65,6 +67,12 @@ func (bf *BaseFlight) Platform() Name {
 	return bf.platform
 }
 
+// Capabilities returns the capabilities of platforms which don't describe
+// their own: none beyond booting and SSH, on any architecture.
+func (bf *BaseFlight) Capabilities() Capabilities {
+	return Capabilities{}
+}
+
 func (bf *BaseFlight) Clusters() []Cluster {
 	bf.clusterlock.Lock()
 	defer bf.clusterlock.Unlock()
@@ -87,11 +95,29 @@ func (bf *BaseFlight) DelCluster(c Cluster) {
 	delete(bf.clustermap, c.Name())
 }
 
+// SSHAgentSocket returns the path of the socket of the flight's SSH agent,
+// which holds the keys its machines accept.
+func (bf *BaseFlight) SSHAgentSocket() string {
+	return bf.agent.Socket
+}
+
 func (bf *BaseFlight) Keys() ([]*agent.Key, error) {
 	return bf.agent.List()
 }
 
-// Destroy destroys each Cluster in the Flight and closes the SSH agent.
+// UseKey adds the private key to the flight's SSH agent and logs into
+// machines as user, for machines which already accept the key rather than
+// being given the agent's.
+func (bf *BaseFlight) UseKey(key interface{}, user string) error {
+	if err := bf.agent.Add(agent.AddedKey{PrivateKey: key}); err != nil {
+		return err
+	}
+	bf.agent.User = user
+	return nil
+}
+
+// Destroy destroys each Cluster in the Flight, closes the SSH agent and
+// stops serving userdata.
 func (bf *BaseFlight) Destroy() {
 	for _, c := range bf.Clusters() {
 		c.Destroy()
@@ -100,4 +126,8 @@ func (bf *BaseFlight) Destroy() {
 	if err := bf.agent.Close(); err != nil {
 		plog.Errorf("Error closing agent: %v", err)
 	}
+
+	if bf.userdataServer != nil {
+		bf.userdataServer.listener.Close()
+	}
 }
@@ -0,0 +1,115 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package platform
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// GCOptions select which of the resources mantle created on a platform
+// its garbage collector collects, and how.
+type GCOptions struct {
+	// GracePeriod is how long ago resources must have been created to be
+	// garbage.
+	GracePeriod time.Duration
+	// Tags are tags, or labels, garbage must have besides the one marking
+	// it as created by mantle. Resources which can't be tagged are only
+	// garbage if there are none.
+	Tags map[string]string
+	// DryRun reports the garbage without deleting it.
+	DryRun bool
+	// Report, if set, records the garbage collected.
+	Report *GCReport
+}
+
+// IsGarbage reports whether a resource created by mantle at created, and
+// with the given tags, is garbage. Resources with an expiry tag are
+// garbage once it passes, and others once the grace period does. Those
+// whose creation time is unknown are taken to be older than any grace
+// period.
+func (o GCOptions) IsGarbage(created time.Time, tags map[string]string) bool {
+	if expiry, ok := Expiry(tags); ok {
+		if expiry.After(time.Now()) {
+			return false
+		}
+	} else if !created.IsZero() && created.After(time.Now().Add(-o.GracePeriod)) {
+		return false
+	}
+	for k, v := range o.Tags {
+		if value, ok := tags[k]; !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Collect deletes a resource of a kind, such as "instance", with del,
+// unless this is a dry run, and records it in the report.
+func (o GCOptions) Collect(kind, id string, created time.Time, del func() error) error {
+	if o.DryRun {
+		plog.Infof("Would delete %s %s", kind, id)
+	} else {
+		plog.Infof("Deleting %s %s", kind, id)
+		if err := del(); err != nil {
+			return err
+		}
+	}
+	if o.Report != nil {
+		o.Report.add(GCResource{
+			Kind:    kind,
+			ID:      id,
+			Created: created,
+		})
+	}
+	return nil
+}
+
+// GCResource is a resource a garbage collector deleted, or would have in
+// a dry run.
+type GCResource struct {
+	Kind    string    `json:"kind"`
+	ID      string    `json:"id"`
+	Created time.Time `json:"created"`
+}
+
+// GCReport records the resources collected by garbage collectors, which
+// may be running concurrently.
+type GCReport struct {
+	mu        sync.Mutex
+	resources []GCResource
+}
+
+func (r *GCReport) add(res GCResource) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.resources = append(r.resources, res)
+}
+
+// Resources returns the resources collected, sorted by kind and ID.
+func (r *GCReport) Resources() []GCResource {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	resources := make([]GCResource, len(r.resources))
+	copy(resources, r.resources)
+	sort.Slice(resources, func(i, j int) bool {
+		if resources[i].Kind != resources[j].Kind {
+			return resources[i].Kind < resources[j].Kind
+		}
+		return resources[i].ID < resources[j].ID
+	})
+	return resources
+}
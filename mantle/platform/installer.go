@@ -0,0 +1,117 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package platform
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/coreos/coreos-assembler/mantle/system/exec"
+)
+
+// installerImagePrefix marks a HostInstaller spec as a container image
+const installerImagePrefix = "oci://"
+
+// CoreOSInstaller is a coreos-installer run on the host.
+type CoreOSInstaller struct {
+	// Path is the binary, or if empty, coreos-installer in $PATH.
+	Path string
+	// Image, if set, is a container image coreos-installer is run from
+	// with podman instead of a binary.
+	Image string
+}
+
+// HostInstaller is the coreos-installer which modifies ISOs on the host,
+// embedding configs, network keyfiles and kernel arguments and extracting
+// minimal ISOs.
+var HostInstaller CoreOSInstaller
+
+// ParseCoreOSInstaller parses a coreos-installer spec: empty for the one in
+// $PATH, oci://IMAGE for a container image, or the path of a binary.
+func ParseCoreOSInstaller(spec string) (CoreOSInstaller, error) {
+	if image, ok := strings.CutPrefix(spec, installerImagePrefix); ok {
+		if image == "" {
+			return CoreOSInstaller{}, fmt.Errorf("no image in coreos-installer %q", spec)
+		}
+		return CoreOSInstaller{Image: image}, nil
+	}
+	if spec != "" {
+		if _, err := os.Stat(spec); err != nil {
+			return CoreOSInstaller{}, fmt.Errorf("coreos-installer: %v", err)
+		}
+	}
+	return CoreOSInstaller{Path: spec}, nil
+}
+
+// String returns the installer's spec.
+func (i CoreOSInstaller) String() string {
+	switch {
+	case i.Image != "":
+		return installerImagePrefix + i.Image
+	case i.Path != "":
+		return i.Path
+	default:
+		return "coreos-installer"
+	}
+}
+
+// Argv returns the command line running the installer with the arguments.
+// Images get the directories of absolute paths in the arguments, and the
+// working directory, mounted at the same paths, and stdin.
+func (i CoreOSInstaller) Argv(args ...string) []string {
+	if i.Image == "" {
+		if i.Path != "" {
+			return append([]string{i.Path}, args...)
+		}
+		return append([]string{"coreos-installer"}, args...)
+	}
+	argv := []string{"podman", "run", "--rm", "-i", "--security-opt", "label=disable"}
+	var dirs []string
+	if wd, err := os.Getwd(); err == nil {
+		dirs = append(dirs, wd)
+		argv = append(argv, "-w", wd)
+	}
+	for _, arg := range args {
+		if filepath.IsAbs(arg) {
+			dirs = append(dirs, filepath.Dir(arg))
+		}
+	}
+	sort.Strings(dirs)
+	for i, dir := range dirs {
+		if i == 0 || dir != dirs[i-1] {
+			argv = append(argv, "-v", dir+":"+dir)
+		}
+	}
+	return append(append(argv, i.Image), args...)
+}
+
+// Command returns a command running the installer with the arguments.
+func (i CoreOSInstaller) Command(args ...string) *exec.ExecCmd {
+	argv := i.Argv(args...)
+	return exec.Command(argv[0], argv[1:]...)
+}
+
+// Version returns the installer's version, as `coreos-installer --version`
+// prints it.
+func (i CoreOSInstaller) Version() (string, error) {
+	out, err := i.Command("--version").Output()
+	if err != nil {
+		return "", fmt.Errorf("getting version of %s: %v", i, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,138 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package platform
+
+import (
+	"fmt"
+	"strings"
+)
+
+// InstanceProfile describes an instance type of a cloud platform, so that
+// tests can ask for the resources their machines need rather than for
+// instance types, which differ between platforms and architectures.
+type InstanceProfile struct {
+	Type string `yaml:"type"`
+	// Arch is the architecture the type runs; any if empty
+	Arch string `yaml:"arch,omitempty"`
+	// Memory is in MiB
+	Memory     int  `yaml:"memory"`
+	CPUs       int  `yaml:"cpus"`
+	NestedVirt bool `yaml:"nestedVirt,omitempty"`
+}
+
+// Fits returns whether the profile's machines have what machines with the
+// options need.
+func (p InstanceProfile) Fits(o MachineOptions) bool {
+	return p.Memory >= o.MinMemory && p.CPUs >= o.MinCPUs && (p.NestedVirt || !o.NestedVirt)
+}
+
+// SizedByProfile returns whether machines with the options need resources
+// which decide their instance type.
+func (o MachineOptions) SizedByProfile() bool {
+	return o.MinMemory > 0 || o.MinCPUs > 0 || o.NestedVirt
+}
+
+// SelectInstanceProfile returns the profile machines with the options
+// are created with on an architecture: that of the default instance type
+// if it fits them, or else the first of the profiles which does. It fails
+// if none does.
+func SelectInstanceProfile(profiles []InstanceProfile, arch, defaultType string, o MachineOptions) (InstanceProfile, error) {
+	var first *InstanceProfile
+	for i, p := range profiles {
+		if (p.Arch != "" && p.Arch != arch) || !p.Fits(o) {
+			continue
+		}
+		if p.Type == defaultType {
+			return p, nil
+		}
+		if first == nil {
+			first = &profiles[i]
+		}
+	}
+	if first == nil {
+		return InstanceProfile{}, fmt.Errorf("no %s instance profile has %s", arch, o.describeNeeds())
+	}
+	return *first, nil
+}
+
+// hasProfile returns whether an instance type has a profile for the
+// architecture.
+func hasProfile(profiles []InstanceProfile, arch, instanceType string) bool {
+	for _, p := range profiles {
+		if p.Type == instanceType && (p.Arch == "" || p.Arch == arch) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasNestedVirtProfile returns whether any of the flight's instance
+// profiles for the build's architecture offers nested virtualization, for
+// platforms to include CapNestedVirt in their capabilities.
+func (bf *BaseFlight) HasNestedVirtProfile() bool {
+	for _, p := range bf.baseopts.InstanceProfiles {
+		if p.NestedVirt && (p.Arch == "" || p.Arch == bf.baseopts.CosaBuildArch) {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveInstanceType returns the instance type machines with the
+// options are created with on an architecture of a platform whose
+// machines are otherwise of defaultType: the options' own, or if they
+// need resources, that of the instance profile SelectInstanceProfile
+// picks, or else defaultType. A default type with no profile, such as one
+// chosen for confidential computing, is kept, since what it lacks isn't
+// known. It fails if no profile fits.
+func ResolveInstanceType(profiles []InstanceProfile, arch, defaultType string, options MachineOptions) (string, error) {
+	if options.InstanceType != "" {
+		return options.InstanceType, nil
+	}
+	if !options.SizedByProfile() || !hasProfile(profiles, arch, defaultType) {
+		return defaultType, nil
+	}
+	p, err := SelectInstanceProfile(profiles, arch, defaultType, options)
+	if err != nil {
+		return "", err
+	}
+	if p.Type != defaultType {
+		plog.Debugf("Using instance type %s for %s", p.Type, options.describeNeeds())
+	}
+	return p.Type, nil
+}
+
+// InstanceType returns the instance type the cluster's machines with the
+// options are created with, given the flight's instance profiles; see
+// ResolveInstanceType.
+func (bc *BaseCluster) InstanceType(options MachineOptions, defaultType string) (string, error) {
+	return ResolveInstanceType(bc.bf.baseopts.InstanceProfiles, bc.bf.baseopts.CosaBuildArch, defaultType, options)
+}
+
+// describeNeeds describes the resources machines with the options need,
+// for messages.
+func (o MachineOptions) describeNeeds() string {
+	var needs []string
+	if o.MinMemory > 0 {
+		needs = append(needs, fmt.Sprintf("%d MiB of memory", o.MinMemory))
+	}
+	if o.MinCPUs > 0 {
+		needs = append(needs, fmt.Sprintf("%d CPUs", o.MinCPUs))
+	}
+	if o.NestedVirt {
+		needs = append(needs, "nested virtualization")
+	}
+	return strings.Join(needs, ", ")
+}
@@ -0,0 +1,171 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instrument
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/coreos/coreos-assembler/mantle/platform/conf"
+)
+
+// InstallCompletionUnit is the unit of the installed system which signals
+// that the install completed. The checks of the installed system run
+// before it, so it's only reached once they pass.
+const InstallCompletionUnit = "coreos-test-installer.service"
+
+var checkUnit = `[Unit]
+Description=TestISO Check: %s
+OnFailure=emergency.target
+OnFailureJobMode=isolate
+%s[Service]
+Type=oneshot
+RemainAfterExit=yes
+StandardOutput=kmsg+console
+StandardError=kmsg+console
+%s[Install]
+RequiredBy=%s
+`
+
+// InstallContext describes an install, for checks to decide whether they
+// apply to it.
+type InstallContext struct {
+	// MultiPath is set if the system is installed to a multipath disk.
+	MultiPath bool
+	// Container is set if the live system installs from the
+	// coreos-installer container image.
+	Container bool
+}
+
+// Check is an assertion about an install, run as a oneshot unit of the
+// live system after coreos-installer, or of the installed system on its
+// first boot. A failing check enters the emergency target, which fails
+// the install.
+type Check struct {
+	// Name is the check's name; its unit is coreos-test-check-NAME.service.
+	Name        string
+	Description string
+	// Live runs the check on the live system once coreos-installer is
+	// done, rather than on the installed system.
+	Live bool
+	// Applies reports whether the check applies to an install, or if nil,
+	// it applies to all of them.
+	Applies func(InstallContext) bool
+	// Deps are [Unit] lines the check needs, such as ordering after the
+	// unit whose work it checks.
+	Deps []string
+	// Commands are the check's ExecStart commands; it passes if they all
+	// succeed.
+	Commands []string
+}
+
+var checks []Check
+
+// RegisterCheck adds a check to those ApplyChecks adds to installs.
+func RegisterCheck(c Check) {
+	for _, other := range checks {
+		if other.Name == c.Name {
+			panic(fmt.Sprintf("check %q already registered", c.Name))
+		}
+	}
+	checks = append(checks, c)
+}
+
+// Checks returns the registered checks.
+func Checks() []Check {
+	return append([]Check(nil), checks...)
+}
+
+// Unit returns the name of the check's unit.
+func (c Check) Unit() string {
+	return fmt.Sprintf("coreos-test-check-%s.service", c.Name)
+}
+
+func (c Check) render() string {
+	var deps, commands strings.Builder
+	for _, dep := range c.Deps {
+		deps.WriteString(dep + "\n")
+	}
+	requiredBy := "multi-user.target"
+	if c.Live {
+		deps.WriteString("After=coreos-installer.service\nBefore=coreos-installer.target\n")
+		requiredBy = "coreos-installer.target"
+	} else {
+		deps.WriteString("Before=" + InstallCompletionUnit + "\n")
+	}
+	for _, cmd := range c.Commands {
+		commands.WriteString("ExecStart=" + cmd + "\n")
+	}
+	return fmt.Sprintf(checkUnit, c.Description, deps.String(), commands.String(), requiredBy)
+}
+
+// ApplyChecks adds the registered checks which apply to the install to the
+// configs of its live and installed systems.
+func ApplyChecks(live, target *conf.Conf, ctx InstallContext) {
+	for _, c := range checks {
+		if c.Applies != nil && !c.Applies(ctx) {
+			continue
+		}
+		config := target
+		if c.Live {
+			config = live
+		}
+		config.AddSystemdUnit(c.Unit(), c.render(), conf.Enable)
+	}
+}
+
+func init() {
+	RegisterCheck(Check{
+		Name:        "install-complete",
+		Description: "coreos-installer completed",
+		Live:        true,
+		// the container installer reboots as soon as it's done
+		Applies:  func(ctx InstallContext) bool { return !ctx.Container },
+		Commands: []string{`/usr/bin/journalctl -b --no-pager -t coreos-installer-service --grep "Install complete"`},
+	})
+	RegisterCheck(Check{
+		Name:        "partitions",
+		Description: "installed partition layout",
+		Deps:        []string{"RequiresMountsFor=/boot"},
+		Commands: []string{
+			`/bin/bash -c '[[ $(findmnt -nvro LABEL /boot) == boot ]]'`,
+			`/bin/bash -c '[[ $(findmnt -nvro LABEL /sysroot) == root ]]'`,
+		},
+	})
+	RegisterCheck(Check{
+		Name:        "no-ignition",
+		Description: "Ignition first boot cleaned up",
+		Deps:        []string{"After=coreos-ignition-firstboot-complete.service", "RequiresMountsFor=/boot"},
+		Commands: []string{
+			"/bin/sh -c '[ ! -e /boot/ignition ]'",
+			"/bin/sh -c '! grep -q ignition.firstboot /boot/loader/entries/*.conf'",
+		},
+	})
+	RegisterCheck(Check{
+		Name:        "bootupd",
+		Description: "bootupd status clean",
+		Deps:        []string{"ConditionPathExists=/usr/bin/bootupctl", "ConditionArchitecture=!s390x", "RequiresMountsFor=/boot"},
+		Commands: []string{
+			"/usr/bin/bootupctl status",
+			`/bin/bash -c '[[ -z $(bootupctl status --print-if-available) ]]'`,
+		},
+	})
+	RegisterCheck(Check{
+		Name:        "multipath",
+		Description: "root is multipathed",
+		Applies:     func(ctx InstallContext) bool { return ctx.MultiPath },
+		Commands:    []string{`/bin/bash -c '[[ $(findmnt -nvro SOURCE /sysroot) == /dev/mapper/mpatha4 ]]'`},
+	})
+}
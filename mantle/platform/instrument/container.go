@@ -0,0 +1,106 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instrument
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/coreos/coreos-assembler/mantle/platform/conf"
+)
+
+const (
+	// DefaultInstallerImage is the coreos-installer container image which
+	// installing from any Linux system is documented with
+	DefaultInstallerImage = "quay.io/coreos/coreos-installer:release"
+
+	// ContainerInstallerUnit is the unit of the live system which installs
+	// with the container image
+	ContainerInstallerUnit = "coreos-installer-container.service"
+
+	// ContainerInstallerDir is where the files given to the container are
+	// written. It's mounted at the same path in the container, so paths in
+	// the installer config refer to the same files in both.
+	ContainerInstallerDir = "/var/opt/coreos-installer"
+)
+
+var containerInstallerUnit = `[Unit]
+Description=TestISO Install From Container
+Wants=network-online.target
+After=network-online.target
+%sOnFailure=emergency.target
+OnFailureJobMode=isolate
+[Service]
+Type=oneshot
+RemainAfterExit=yes
+StandardOutput=kmsg+console
+StandardError=kmsg+console
+ExecStart=%s
+ExecStart=/usr/bin/systemctl --no-block reboot
+[Install]
+RequiredBy=multi-user.target
+`
+
+// ContainerInstaller runs coreos-installer from its container image with
+// podman, as when installing from any Linux system, instead of the live
+// system's coreos-installer.service.
+type ContainerInstaller struct {
+	// Image is the coreos-installer container image, or if empty,
+	// DefaultInstallerImage.
+	Image string
+	// Auth, if set, is the registry auth file podman pulls the image with,
+	// in the containers-auth.json(5) format.
+	Auth string
+}
+
+// ApplyContainer adds the probes to the config of a live system like
+// Apply, but has the container installer install with the installer
+// config rather than coreos-installer.service. The files the installer
+// config refers to must be in ContainerInstallerDir. Once installed, the
+// live system reboots, as coreos-installer.target does.
+func (o Options) ApplyContainer(config *conf.Conf, installer *InstallerConfig, container *ContainerInstaller) error {
+	if err := o.Apply(config, nil); err != nil {
+		return err
+	}
+	data, err := o.RenderInstallerConfig(installer)
+	if err != nil {
+		return err
+	}
+	configPath := ContainerInstallerDir + "/config.yaml"
+	config.AddFile(configPath, data, 0644)
+
+	image := container.Image
+	if image == "" {
+		image = DefaultInstallerImage
+	}
+	args := []string{"/usr/bin/podman", "run", "--pull=always", "--privileged", "--rm",
+		"-v", "/dev:/dev", "-v", "/run/udev:/run/udev",
+		"-v", ContainerInstallerDir + ":" + ContainerInstallerDir + ":z"}
+	if container.Auth != "" {
+		authPath := ContainerInstallerDir + "/auth.json"
+		config.AddFile(authPath, container.Auth, 0600)
+		args = append(args, "--authfile", authPath)
+	}
+	args = append(args, image, "install", "--config-file", configPath)
+
+	// coreos-installer.target, which requires boot-started.service, isn't
+	// reached without coreos-installer.service
+	var deps string
+	if o.BootStarted {
+		deps = "Requires=boot-started.service\nAfter=boot-started.service\n"
+	}
+	config.AddSystemdUnit(ContainerInstallerUnit, fmt.Sprintf(containerInstallerUnit, deps, strings.Join(args, " ")), conf.Enable)
+	return nil
+}
@@ -0,0 +1,184 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package instrument adds the probes tests observe machines with to their
+// Ignition configs: console autologin, a signal when the live system
+// starts, coreos-installer settings and debug kernel arguments. Each can be
+// enabled on its own.
+package instrument
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	coreosarch "github.com/coreos/stream-metadata-go/arch"
+	"gopkg.in/yaml.v2"
+
+	"github.com/coreos/coreos-assembler/mantle/platform/conf"
+)
+
+const (
+	// BootStartedChannel is the virtio-serial port the BootStarted probe
+	// writes BootStartedSignal to
+	BootStartedChannel = "bootstarted"
+	BootStartedSignal  = "boot-started-OK"
+
+	// InstallerConfigPath is where the coreos-installer config is written
+	InstallerConfigPath = "/etc/coreos/installer.d/mantle.yaml"
+)
+
+var (
+	// TODO expose this as an API that can be used by cosa too
+	serialConsoles = map[string]string{
+		"x86_64":  "ttyS0,115200n8",
+		"ppc64le": "hvc0",
+		"aarch64": "ttyAMA0",
+		"s390x":   "ttysclp0",
+	}
+
+	bootStartedUnit = fmt.Sprintf(`[Unit]
+	Description=TestISO Boot Started
+	Requires=dev-virtio\\x2dports-%s.device
+	OnFailure=emergency.target
+	OnFailureJobMode=isolate
+	[Service]
+	Type=oneshot
+	RemainAfterExit=yes
+	ExecStart=/bin/sh -c '/usr/bin/echo %s >/dev/virtio-ports/%s'
+	[Install]
+	RequiredBy=coreos-installer.target
+	`, BootStartedChannel, BootStartedSignal, BootStartedChannel)
+
+	// Sometimes the logs that stream from various virtio streams can be
+	// incomplete because they depend on services inside the guest.
+	// When you are debugging earlyboot/initramfs issues this can be
+	// problematic, so these log everything to the console.
+	debugKargs = []string{"systemd.log_color=0", "systemd.log_level=debug",
+		"systemd.journald.forward_to_console=1",
+		"systemd.journald.max_level_console=debug"}
+)
+
+// SerialConsole returns the serial console of QEMU machines of the
+// architecture, as a console kernel argument.
+func SerialConsole(arch string) string {
+	return serialConsoles[arch]
+}
+
+// InstallerConfig is a coreos-installer config file.
+type InstallerConfig struct {
+	ImageURL     string   `yaml:"image-url,omitempty"`
+	IgnitionFile string   `yaml:"ignition-file,omitempty"`
+	Insecure     bool     `yaml:",omitempty"`
+	AppendKargs  []string `yaml:"append-karg,omitempty"`
+	CopyNetwork  bool     `yaml:"copy-network,omitempty"`
+	DestDevice   string   `yaml:"dest-device,omitempty"`
+	Console      []string `yaml:"console,omitempty"`
+}
+
+// Options selects the probes added to a config.
+type Options struct {
+	// AutoLogin logs the consoles in as the core user.
+	AutoLogin bool
+	// BootStarted makes the live system write BootStartedSignal to the
+	// BootStartedChannel virtio-serial port before it installs.
+	BootStarted bool
+	// Console, if set, is the console coreos-installer gives the installed
+	// system.
+	Console string
+	// Debug logs systemd and the journal to the console, in the live
+	// system through Kargs and in the installed one through
+	// coreos-installer.
+	Debug bool
+}
+
+// Default returns the probes of the install tests: all of them, with the
+// serial console of the architecture, and Debug if $COSA_TESTISO_DEBUG is
+// set.
+func Default() Options {
+	_, debug := os.LookupEnv("COSA_TESTISO_DEBUG")
+	return Options{
+		AutoLogin:   true,
+		BootStarted: true,
+		Console:     SerialConsole(coreosarch.CurrentRpmArch()),
+		Debug:       debug,
+	}
+}
+
+// Probes are the names of the probes Parse accepts.
+var Probes = []string{"autologin", "boot-started", "console", "debug"}
+
+// Parse returns the options enabling the named probes, out of Probes. The
+// console is the serial console of the architecture.
+func Parse(names []string) (Options, error) {
+	var o Options
+	for _, name := range names {
+		switch name {
+		case "autologin":
+			o.AutoLogin = true
+		case "boot-started":
+			o.BootStarted = true
+		case "console":
+			o.Console = SerialConsole(coreosarch.CurrentRpmArch())
+		case "debug":
+			o.Debug = true
+		default:
+			return Options{}, fmt.Errorf("unknown probe %q; probes are %s", name, strings.Join(Probes, ", "))
+		}
+	}
+	return o, nil
+}
+
+// Kargs returns the kernel arguments of the live system the options add.
+func (o Options) Kargs() []string {
+	if o.Debug {
+		return append([]string(nil), debugKargs...)
+	}
+	return []string{}
+}
+
+// Apply adds the probes to the config of a live system. If installer is
+// set, it's written as the coreos-installer config, with the console and
+// kernel arguments of the options added to it.
+func (o Options) Apply(config *conf.Conf, installer *InstallerConfig) error {
+	if o.AutoLogin {
+		config.AddAutoLogin()
+	}
+	if o.BootStarted {
+		config.AddSystemdUnit("boot-started.service", bootStartedUnit, conf.Enable)
+	}
+	if installer != nil {
+		data, err := o.RenderInstallerConfig(installer)
+		if err != nil {
+			return err
+		}
+		config.AddFile(InstallerConfigPath, data, 0644)
+	}
+	return nil
+}
+
+// RenderInstallerConfig returns the installer config with the console and
+// kernel arguments of the options added to it.
+func (o Options) RenderInstallerConfig(installer *InstallerConfig) (string, error) {
+	ic := *installer
+	if o.Console != "" {
+		ic.Console = append(ic.Console, o.Console)
+	}
+	ic.AppendKargs = append(append([]string(nil), ic.AppendKargs...), o.Kargs()...)
+	data, err := yaml.Marshal(ic)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,163 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package platform
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// InventoryFile is the name of the file in a machine's output directory
+// holding its Inventory.
+const InventoryFile = "inventory.json"
+
+// Inventory is the hardware a machine sees, as reported by the guest and,
+// for emulated machines, by the emulator, so that tests can check that the
+// options they requested took effect.
+type Inventory struct {
+	CPUModel string `json:"cpu-model,omitempty"`
+	// Driver of each network interface backed by a device, by interface
+	NICDrivers map[string]string `json:"nic-drivers,omitempty"`
+	// Bus each disk is attached through, e.g. "virtio", "nvme" or "scsi",
+	// by disk
+	DiskBuses map[string]string `json:"disk-buses,omitempty"`
+	// "uefi" or "bios"; unset on architectures with neither
+	Firmware   string `json:"firmware,omitempty"`
+	TPM        bool   `json:"tpm"`
+	SecureBoot bool   `json:"secure-boot"`
+	// What the emulator presents, for machines which can tell
+	Emulator *EmulatorInventory `json:"emulator,omitempty"`
+}
+
+// EmulatorInventory is the hardware an emulator presents to a machine.
+type EmulatorInventory struct {
+	// QOM type of the CPUs, e.g. "host-x86_64-cpu"
+	CPUType string `json:"cpu-type,omitempty"`
+	// Types of the devices added on the command line or hotplugged, e.g.
+	// "virtio-net-pci", sorted
+	Devices   []string `json:"devices"`
+	TPMModels []string `json:"tpm-models,omitempty"`
+}
+
+// EmulatorInspector is implemented by machines whose emulator can describe
+// the hardware it presents to them.
+type EmulatorInspector interface {
+	EmulatorInventory() (*EmulatorInventory, error)
+}
+
+// inventoryScript prints the guest's view of its hardware as key=value
+// lines, with the interface or disk first in the value where there are
+// several. Disks only on the block subsystem, like zram, aren't hardware.
+const inventoryScript = `
+echo "cpu=$(lscpu | sed -n 's/^Model name: *//p' | head -n1)"
+for d in /sys/class/net/*/device/driver; do
+	[ -e "$d" ] || continue
+	i=${d#/sys/class/net/}
+	echo "nic=${i%%/*} $(basename "$(readlink -f "$d")")"
+done
+lsblk -dnr -o NAME,TYPE,SUBSYSTEMS,TRAN | while read -r name type subsystems tran; do
+	[ "$type" = disk ] && [ "$subsystems" != block ] && echo "disk=$name ${tran:-$subsystems}"
+done
+if [ -d /sys/firmware/efi ]; then
+	echo firmware=uefi
+else
+	case $(uname -m) in x86_64|i?86) echo firmware=bios;; esac
+fi
+if [ -e /dev/tpm0 ] || [ -e /dev/tpmrm0 ]; then echo tpm=true; fi
+for f in /sys/firmware/efi/efivars/SecureBoot-*; do
+	[ "$(od -An -tu1 -j4 -N1 "$f" 2>/dev/null | tr -d ' ')" = 1 ] && echo secureboot=true
+done
+true
+`
+
+// GatherInventory queries the machine over SSH, and its emulator if it has
+// one, for the hardware it sees.
+func GatherInventory(m Machine) (*Inventory, error) {
+	out, stderr, err := m.SSH(inventoryScript)
+	if err != nil {
+		return nil, fmt.Errorf("gathering inventory: %v: %s", err, stderr)
+	}
+	inv := parseInventory(string(out))
+
+	if ei, ok := m.(EmulatorInspector); ok {
+		if inv.Emulator, err = ei.EmulatorInventory(); err != nil {
+			return nil, err
+		}
+	}
+	return inv, nil
+}
+
+// parseInventory parses the output of inventoryScript.
+func parseInventory(out string) *Inventory {
+	inv := &Inventory{}
+	for _, line := range strings.Split(out, "\n") {
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "cpu":
+			inv.CPUModel = value
+		case "nic":
+			if name, driver, ok := strings.Cut(value, " "); ok {
+				if inv.NICDrivers == nil {
+					inv.NICDrivers = make(map[string]string)
+				}
+				inv.NICDrivers[name] = driver
+			}
+		case "disk":
+			if name, bus, ok := strings.Cut(value, " "); ok {
+				if inv.DiskBuses == nil {
+					inv.DiskBuses = make(map[string]string)
+				}
+				inv.DiskBuses[name] = diskBus(bus)
+			}
+		case "firmware":
+			inv.Firmware = value
+		case "tpm":
+			inv.TPM = value == "true"
+		case "secureboot":
+			inv.SecureBoot = value == "true"
+		}
+	}
+	return inv
+}
+
+// diskBus returns the bus of a disk from its lsblk transport or, when lsblk
+// doesn't know it, its subsystems, e.g. "block:scsi:virtio:pci" is "scsi".
+func diskBus(s string) string {
+	for _, subsystem := range strings.Split(s, ":") {
+		if subsystem != "block" && subsystem != "" {
+			return subsystem
+		}
+	}
+	return s
+}
+
+// WriteInventory gathers the machine's inventory and writes it to path as
+// JSON.
+func WriteInventory(m Machine, path string) error {
+	inv, err := GatherInventory(m)
+	if err != nil {
+		return err
+	}
+	buf, err := json.MarshalIndent(inv, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf, 0644)
+}
@@ -35,10 +35,27 @@ type Journal struct {
 	journal     io.WriteCloser
 	journalRaw  io.WriteCloser
 	journalPath string
+	formatter   *journalFormatter
 	recorder    *journal.Recorder
 	cancel      context.CancelFunc
 }
 
+// journalFormatter also adds entries to the cluster's merged journal, if
+// it has one.
+type journalFormatter struct {
+	journal.Formatter
+	merged journal.Formatter
+}
+
+func (f *journalFormatter) WriteEntry(entry journal.Entry) error {
+	if f.merged != nil {
+		if err := f.merged.WriteEntry(entry); err != nil {
+			return err
+		}
+	}
+	return f.Formatter.WriteEntry(entry)
+}
+
 // wrapper that also closes the underlying file
 type gzWriteCloser struct {
 	*gzip.Writer
@@ -80,14 +97,22 @@ func NewJournal(dir string) (*Journal, error) {
 		Writer:     jrz,
 	}
 
+	formatter := &journalFormatter{Formatter: journal.ShortWriter(j)}
 	return &Journal{
 		journal:     j,
 		journalRaw:  jrzc,
-		recorder:    journal.NewRecorder(journal.ShortWriter(j), jrzc),
+		formatter:   formatter,
+		recorder:    journal.NewRecorder(formatter, jrzc),
 		journalPath: p,
 	}, nil
 }
 
+// SkipExisting makes the journal start with the entries written after it
+// starts, rather than those of the whole boot.
+func (j *Journal) SkipExisting() {
+	j.recorder.SkipExisting()
+}
+
 // Start begins/resumes streaming the system journal to journal.txt.
 func (j *Journal) Start(ctx context.Context, m Machine, oldBootId string) error {
 	if j.cancel != nil {
@@ -97,6 +122,10 @@ func (j *Journal) Start(ctx context.Context, m Machine, oldBootId string) error
 	}
 	ctx, cancel := context.WithCancel(ctx)
 
+	if merged := m.RuntimeConf().mergedJournal; merged != nil && j.formatter.merged == nil {
+		j.formatter.merged = merged.Formatter(m.ID())
+	}
+
 	start := func() error {
 		if oldBootId != "" {
 			bootId, err := GetMachineBootId(m)
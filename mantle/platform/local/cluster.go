@@ -37,8 +37,14 @@ func (lc *LocalCluster) NewCommand(name string, arg ...string) exec.Cmd {
 	return cmd
 }
 
-func (lc *LocalCluster) NewTap(bridge string) (tap *TunTap, err error) {
-	nsExit, err := ns.Enter(lc.flight.nshandle)
+func (lc *LocalCluster) NewTap(bridge string) (*TunTap, error) {
+	return newBridgeTap(lc.flight.nshandle, bridge, 0)
+}
+
+// newBridgeTap adds a tap to the bridge in the network namespace, with the
+// MTU if it's set.
+func newBridgeTap(nshandle netns.NsHandle, bridge string, mtu int) (tap *TunTap, err error) {
+	nsExit, err := ns.Enter(nshandle)
 	if err != nil {
 		return nil, err
 	}
@@ -51,6 +57,12 @@ func (lc *LocalCluster) NewTap(bridge string) (tap *TunTap, err error) {
 		return nil, fmt.Errorf("tap failed: %v", err)
 	}
 
+	if mtu > 0 {
+		if err = netlink.LinkSetMTU(tap, mtu); err != nil {
+			return nil, fmt.Errorf("tap mtu failed: %v", err)
+		}
+	}
+
 	err = netlink.LinkSetUp(tap)
 	if err != nil {
 		return nil, fmt.Errorf("tap up failed: %v", err)
@@ -0,0 +1,477 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package local
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"text/template"
+
+	"github.com/coreos/pkg/capnslog"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+
+	"github.com/coreos/coreos-assembler/mantle/network"
+	"github.com/coreos/coreos-assembler/mantle/platform"
+	"github.com/coreos/coreos-assembler/mantle/system/ns"
+	"github.com/coreos/coreos-assembler/mantle/util"
+)
+
+const (
+	labBridge        = "labbr0"
+	defaultLabSubnet = "192.168.100.0/24"
+	defaultLabDomain = "lab"
+	// labFirstHost is the offset in the subnet of the first host's
+	// address; addresses from the middle of the subnet are leased to
+	// unknown machines.
+	labFirstHost = 10
+
+	labConfig = `
+keep-in-foreground
+log-facility=-
+pid-file=
+{{if .Debug}}
+log-queries
+log-dhcp
+{{else}}
+quiet-dhcp
+{{end}}
+
+{{range .Networks}}
+interface={{.Interface}}
+{{end}}
+bind-interfaces
+no-resolv
+no-hosts
+domain={{.Domain}}
+local=/{{.Domain}}/
+expand-hosts
+addn-hosts={{.Dir}}/hosts
+
+{{range .Networks}}
+dhcp-range={{.RangeStart}},{{.RangeEnd}},{{.Netmask}},1h
+{{end}}
+# no router, so machines keep reaching the outside through their other NICs
+dhcp-option=option:router
+dhcp-option=option:dns-server,{{.Gateway}}
+dhcp-hostsfile={{.Dir}}/dhcp-hosts
+leasefile-ro
+
+{{if .TFTPDir}}
+enable-tftp
+tftp-root={{.TFTPDir}}
+# client architectures 7 and 9 are x86_64 UEFI, 11 aarch64 UEFI
+dhcp-match=set:efi,option:client-arch,7
+dhcp-match=set:efi,option:client-arch,9
+dhcp-match=set:efi,option:client-arch,11
+{{if .BIOSBootFile}}dhcp-boot=tag:!efi,{{.BIOSBootFile}}{{end}}
+{{if .UEFIBootFile}}dhcp-boot=tag:efi,{{.UEFIBootFile}}{{end}}
+{{end}}
+`
+)
+
+var labTemplate = template.Must(template.New("lab").Parse(labConfig))
+
+// LabOptions configure a lab network.
+type LabOptions struct {
+	// Subnet is the network's IPv4 subnet, by default 192.168.100.0/24.
+	Subnet string
+	// Domain is the DNS domain of the network's hosts, by default "lab".
+	Domain string
+	// TFTPDir, if set, is served over TFTP, and machines PXE booting are
+	// offered BIOSBootFile or UEFIBootFile from it.
+	TFTPDir      string
+	BIOSBootFile string
+	UEFIBootFile string
+	// HTTPDir, if set, is served over HTTP on port 80 of the gateway.
+	HTTPDir string
+	// MTU, if set, is the MTU of the network and its NICs, such as 9000
+	// for jumbo frames.
+	MTU int
+	// VLANs are tagged networks on the same bridge, on which the host
+	// also has an address and serves DHCP.
+	VLANs []LabVLAN
+}
+
+// LabVLAN is a VLAN of a lab network.
+type LabVLAN struct {
+	ID int
+	// Subnet is the VLAN's IPv4 subnet, which must be at least a /24;
+	// the host is its first address.
+	Subnet string
+}
+
+// labNetwork is a subnet the host serves DHCP on.
+type labNetwork struct {
+	Interface string
+	Gateway   net.IP
+	Subnet    *net.IPNet
+}
+
+func (n labNetwork) size() int {
+	ones, bits := n.Subnet.Mask.Size()
+	return 1 << (bits - ones)
+}
+
+func (n labNetwork) Netmask() string {
+	return net.IP(n.Subnet.Mask).String()
+}
+
+// RangeStart and RangeEnd bound the addresses leased to unknown machines,
+// the upper half of the subnet.
+func (n labNetwork) RangeStart() net.IP {
+	return nthIP(n.Subnet, n.size()/2)
+}
+
+func (n labNetwork) RangeEnd() net.IP {
+	return nthIP(n.Subnet, n.size()-2)
+}
+
+// parseLabSubnet parses an IPv4 subnet of at least a /24.
+func parseLabSubnet(s string) (*net.IPNet, error) {
+	ip, subnet, err := net.ParseCIDR(s)
+	if err != nil || ip.To4() == nil {
+		return nil, fmt.Errorf("invalid IPv4 subnet %q", s)
+	}
+	if ones, _ := subnet.Mask.Size(); ones > 24 {
+		return nil, fmt.Errorf("subnet %s is smaller than a /24", s)
+	}
+	return subnet, nil
+}
+
+// LabHost is a machine with a static lease on a lab network, which is
+// resolvable as its name in the network's domain.
+type LabHost struct {
+	Name string
+	MAC  net.HardwareAddr
+	IP   net.IP
+}
+
+// Lab is a private network, like a libvirt network, for tests provisioning
+// machines as in a datacenter rather than with QEMU's user-mode
+// networking. It's a bridge in its own network namespace, on which the
+// host serves DHCP, DNS and TFTP with dnsmasq and HTTP, and which QEMU
+// machines are attached to with taps. It needs the privileges to create
+// network namespaces.
+type Lab struct {
+	// Gateway is the host's address on the network, at which it serves
+	// DNS, TFTP and HTTP.
+	Gateway net.IP
+	// Domain is the DNS domain of the network's hosts.
+	Domain string
+	// HTTPURL is the base URL of the HTTPDir, if it's served.
+	HTTPURL string
+
+	opts     LabOptions
+	subnet   *net.IPNet
+	networks []labNetwork
+	nshandle netns.NsHandle
+	dir      string
+	dnsmasq  *ns.Cmd
+	listener net.Listener
+
+	lock  sync.Mutex
+	hosts []*LabHost
+	taps  []*TunTap
+}
+
+// NewLab creates a lab network with no hosts.
+func NewLab(opts LabOptions) (*Lab, error) {
+	if opts.Subnet == "" {
+		opts.Subnet = defaultLabSubnet
+	}
+	if opts.Domain == "" {
+		opts.Domain = defaultLabDomain
+	}
+	subnet, err := parseLabSubnet(opts.Subnet)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Lab{
+		Gateway:  nthIP(subnet, 1),
+		Domain:   opts.Domain,
+		opts:     opts,
+		subnet:   subnet,
+		nshandle: netns.None(),
+	}
+	l.networks = []labNetwork{{Interface: labBridge, Gateway: l.Gateway, Subnet: subnet}}
+	for _, vlan := range opts.VLANs {
+		if vlan.ID < 1 || vlan.ID > 4094 {
+			return nil, fmt.Errorf("invalid VLAN ID %d", vlan.ID)
+		}
+		vlanSubnet, err := parseLabSubnet(vlan.Subnet)
+		if err != nil {
+			return nil, fmt.Errorf("VLAN %d: %v", vlan.ID, err)
+		}
+		l.networks = append(l.networks, labNetwork{
+			Interface: fmt.Sprintf("%s.%d", labBridge, vlan.ID),
+			Gateway:   nthIP(vlanSubnet, 1),
+			Subnet:    vlanSubnet,
+		})
+	}
+	l.dir, err = os.MkdirTemp("/var/tmp", "mantle-lab")
+	if err != nil {
+		return nil, err
+	}
+	// dnsmasq drops privileges before rereading the hosts files
+	if err := os.Chmod(l.dir, 0755); err != nil {
+		l.Close()
+		return nil, err
+	}
+	for _, name := range []string{"hosts", "dhcp-hosts"} {
+		if err := os.WriteFile(filepath.Join(l.dir, name), nil, 0644); err != nil {
+			l.Close()
+			return nil, err
+		}
+	}
+	l.nshandle, err = ns.Create()
+	if err != nil {
+		l.Close()
+		return nil, fmt.Errorf("creating network namespace: %v", err)
+	}
+	if err := l.setupBridge(); err != nil {
+		l.Close()
+		return nil, err
+	}
+	if err := l.startDnsmasq(); err != nil {
+		l.Close()
+		return nil, err
+	}
+	if opts.HTTPDir != "" {
+		if err := l.serveHTTP(); err != nil {
+			l.Close()
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// nthIP returns the nth address of the subnet.
+func nthIP(subnet *net.IPNet, n int) net.IP {
+	ip := make(net.IP, 4)
+	copy(ip, subnet.IP.To4())
+	for i := 3; i >= 0 && n > 0; i-- {
+		n += int(ip[i])
+		ip[i] = byte(n % 256)
+		n /= 256
+	}
+	return ip
+}
+
+func (l *Lab) setupBridge() (err error) {
+	nsExit, err := ns.Enter(l.nshandle)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if exitErr := nsExit(); err == nil {
+			err = exitErr
+		}
+	}()
+
+	lo, err := netlink.LinkByName("lo")
+	if err != nil {
+		return err
+	}
+	if err := netlink.LinkSetUp(lo); err != nil {
+		return fmt.Errorf("setting up loopback: %v", err)
+	}
+	// set explicitly, the bridge's MTU isn't lowered to its ports'
+	br := &netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Name: labBridge, MTU: l.opts.MTU}}
+	if err := netlink.LinkAdd(br); err != nil {
+		return fmt.Errorf("adding bridge: %v", err)
+	}
+	for i, n := range l.networks {
+		var link netlink.Link = br
+		if i > 0 {
+			// the VLAN's packets are tagged on the bridge
+			link = &netlink.Vlan{
+				LinkAttrs: netlink.LinkAttrs{
+					Name:        n.Interface,
+					ParentIndex: br.Attrs().Index,
+					MTU:         l.opts.MTU,
+				},
+				VlanId: l.opts.VLANs[i-1].ID,
+			}
+			if err := netlink.LinkAdd(link); err != nil {
+				return fmt.Errorf("adding VLAN %s: %v", n.Interface, err)
+			}
+		}
+		addr := &netlink.Addr{IPNet: &net.IPNet{IP: n.Gateway, Mask: n.Subnet.Mask}}
+		if err := netlink.AddrAdd(link, addr); err != nil {
+			return fmt.Errorf("adding address of %s: %v", n.Interface, err)
+		}
+		if err := netlink.LinkSetUp(link); err != nil {
+			return fmt.Errorf("setting up %s: %v", n.Interface, err)
+		}
+	}
+	return nil
+}
+
+// VLANGateway returns the host's address on the VLAN.
+func (l *Lab) VLANGateway(id int) (net.IP, error) {
+	for i, vlan := range l.opts.VLANs {
+		if vlan.ID == id {
+			return l.networks[i+1].Gateway, nil
+		}
+	}
+	return nil, fmt.Errorf("lab network has no VLAN %d", id)
+}
+
+// dnsmasqConfig returns dnsmasq's config.
+func (l *Lab) dnsmasqConfig() (string, error) {
+	var buf strings.Builder
+	err := labTemplate.Execute(&buf, map[string]interface{}{
+		"Debug":        plog.LevelAt(capnslog.DEBUG),
+		"Networks":     l.networks,
+		"Domain":       l.Domain,
+		"Dir":          l.dir,
+		"Gateway":      l.Gateway,
+		"TFTPDir":      l.opts.TFTPDir,
+		"BIOSBootFile": l.opts.BIOSBootFile,
+		"UEFIBootFile": l.opts.UEFIBootFile,
+	})
+	return buf.String(), err
+}
+
+func (l *Lab) startDnsmasq() error {
+	config, err := l.dnsmasqConfig()
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(l.dir, "dnsmasq.conf")
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		return err
+	}
+	l.dnsmasq = ns.Command(l.nshandle, "dnsmasq", "--conf-file="+path)
+	out, err := l.dnsmasq.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	l.dnsmasq.Stderr = l.dnsmasq.Stdout
+	go util.LogFrom(capnslog.INFO, out)
+	if err := l.dnsmasq.Start(); err != nil {
+		l.dnsmasq = nil
+		return fmt.Errorf("starting dnsmasq: %v", err)
+	}
+	return nil
+}
+
+func (l *Lab) serveHTTP() (err error) {
+	nsExit, err := ns.Enter(l.nshandle)
+	if err != nil {
+		return err
+	}
+	l.listener, err = net.Listen("tcp", net.JoinHostPort(l.Gateway.String(), "80"))
+	if exitErr := nsExit(); err == nil {
+		err = exitErr
+	}
+	if err != nil {
+		return err
+	}
+	l.HTTPURL = "http://" + l.Gateway.String()
+	go func() {
+		err := http.Serve(l.listener, http.FileServer(http.Dir(l.opts.HTTPDir)))
+		plog.Debugf("stopped serving lab HTTP: %v", err)
+	}()
+	return nil
+}
+
+// AddHost gives a machine named name a static lease and DNS record,
+// returning its MAC and IP address.
+func (l *Lab) AddHost(name string) (*LabHost, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	for _, h := range l.hosts {
+		if h.Name == name {
+			return nil, fmt.Errorf("lab host %s already exists", name)
+		}
+	}
+	n := labFirstHost + len(l.hosts)
+	if n >= l.networks[0].size()/2 {
+		return nil, fmt.Errorf("no addresses left for lab host %s", name)
+	}
+	ip := nthIP(l.subnet, n)
+	host := &LabHost{
+		Name: name,
+		// locally administered, and unique in the network
+		MAC: net.HardwareAddr{0x52, 0x54, 0x00, ip[1], ip[2], ip[3]},
+		IP:  ip,
+	}
+	l.hosts = append(l.hosts, host)
+
+	var hosts, dhcpHosts strings.Builder
+	for _, h := range l.hosts {
+		fmt.Fprintf(&hosts, "%s %s.%s %s\n", h.IP, h.Name, l.Domain, h.Name)
+		fmt.Fprintf(&dhcpHosts, "%s,%s,%s\n", h.MAC, h.IP, h.Name)
+	}
+	if err := os.WriteFile(filepath.Join(l.dir, "hosts"), []byte(hosts.String()), 0644); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(l.dir, "dhcp-hosts"), []byte(dhcpHosts.String()), 0644); err != nil {
+		return nil, err
+	}
+	// dnsmasq rereads its hosts files on SIGHUP
+	if err := l.dnsmasq.Process.Signal(syscall.SIGHUP); err != nil {
+		return nil, fmt.Errorf("reloading dnsmasq: %v", err)
+	}
+	return host, nil
+}
+
+// Nic returns a NIC for the host on the network, to attach a QEMU machine
+// with platform.QemuMachineOptions.TapNics.
+func (l *Lab) Nic(host *LabHost) (platform.TapNic, error) {
+	tap, err := newBridgeTap(l.nshandle, labBridge, l.opts.MTU)
+	if err != nil {
+		return platform.TapNic{}, err
+	}
+	l.lock.Lock()
+	l.taps = append(l.taps, tap)
+	l.lock.Unlock()
+	return platform.TapNic{Tap: tap.File, MAC: host.MAC.String(), MTU: l.opts.MTU}, nil
+}
+
+// Dialer returns a dialer reaching the network's hosts from the host, such
+// as for SSH.
+func (l *Lab) Dialer() *network.NsDialer {
+	return network.NewNsDialer(l.nshandle)
+}
+
+// Close stops serving the network and removes it.
+func (l *Lab) Close() error {
+	if l.listener != nil {
+		l.listener.Close()
+	}
+	if l.dnsmasq != nil {
+		if err := l.dnsmasq.Kill(); err != nil {
+			plog.Errorf("Error killing dnsmasq: %v", err)
+		}
+	}
+	for _, tap := range l.taps {
+		tap.Close()
+	}
+	// the bridge goes with the namespace
+	if l.nshandle.IsOpen() {
+		l.nshandle.Close()
+	}
+	return os.RemoveAll(l.dir)
+}
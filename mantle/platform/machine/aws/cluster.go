@@ -15,9 +15,7 @@
 package aws
 
 import (
-	"encoding/base64"
 	"errors"
-	"fmt"
 	"os"
 	"path/filepath"
 
@@ -57,13 +55,14 @@ func (ac *cluster) NewMachineWithOptions(userdata *conf.UserData, options platfo
 		return nil, errors.New("platform aws does not support appending firstboot kernel arguments")
 	}
 
-	if options.InstanceType != "" {
-		return nil, errors.New("platform aws does not support changing instance types")
+	instanceType, err := ac.InstanceType(options, ac.flight.instanceType)
+	if err != nil {
+		return nil, err
 	}
 
 	conf, err := ac.RenderUserData(userdata, map[string]string{
-		"$public_ipv4":  "${COREOS_EC2_IPV4_PUBLIC}",
-		"$private_ipv4": "${COREOS_EC2_IPV4_LOCAL}",
+		platform.UserDataPublicIPv4:  "${COREOS_EC2_IPV4_PUBLIC}",
+		platform.UserDataPrivateIPv4: "${COREOS_EC2_IPV4_LOCAL}",
 	})
 	if err != nil {
 		return nil, err
@@ -73,21 +72,11 @@ func (ac *cluster) NewMachineWithOptions(userdata *conf.UserData, options platfo
 	if !ac.RuntimeConf().NoSSHKeyInMetadata {
 		keyname = ac.flight.Name()
 	}
-	// Compress via gzip if needed
-	ud := conf.String()
-	encoding := base64.StdEncoding.EncodeToString([]byte(ud))
-	if len([]byte(encoding)) > MaxUserDataSize {
-		ud, err = conf.MaybeCompress()
-		if err != nil {
-			return nil, err
-		}
-		// Check if config is still too large
-		encoding = base64.StdEncoding.EncodeToString([]byte(ud))
-		if len([]byte(encoding)) > MaxUserDataSize {
-			fmt.Printf("WARNING: compressed userdata exceeds expected limit of %d\n", MaxUserDataSize)
-		}
+	ud, err := ac.FitUserData(conf, userDataLimit)
+	if err != nil {
+		return nil, err
 	}
-	instances, err := ac.flight.api.CreateInstances(ac.Name(), keyname, ud, 1, int64(options.MinDiskSize), !ac.RuntimeConf().NoInstanceCreds)
+	instances, err := ac.flight.api.CreateInstances(ac.Name(), keyname, ud, instanceType, 1, int64(options.MinDiskSize), !ac.RuntimeConf().NoInstanceCreds, ac.ResourceTags())
 	if err != nil {
 		return nil, err
 	}
@@ -96,6 +85,7 @@ func (ac *cluster) NewMachineWithOptions(userdata *conf.UserData, options platfo
 		cluster: ac,
 		mach:    instances[0],
 	}
+	ac.BindUserData(mach.ID(), ud)
 
 	mach.dir = filepath.Join(ac.RuntimeConf().OutputDir, mach.ID())
 	if err := os.Mkdir(mach.dir, 0777); err != nil {
@@ -104,7 +94,7 @@ func (ac *cluster) NewMachineWithOptions(userdata *conf.UserData, options platfo
 	}
 
 	confPath := filepath.Join(mach.dir, "user-data")
-	if err := conf.WriteFile(confPath); err != nil {
+	if err := conf.WriteRedactedFile(confPath); err != nil {
 		mach.Destroy()
 		return nil, err
 	}
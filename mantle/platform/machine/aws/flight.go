@@ -16,8 +16,6 @@
 package aws
 
 import (
-	"encoding/base64"
-
 	"github.com/coreos/pkg/capnslog"
 
 	"github.com/coreos/coreos-assembler/mantle/platform"
@@ -31,12 +29,15 @@ const (
 
 var (
 	plog = capnslog.NewPackageLogger("github.com/coreos/coreos-assembler/mantle", "platform/machine/aws")
+
+	userDataLimit = platform.UserDataLimit{MaxSize: MaxUserDataSize, Base64: true}
 )
 
 type flight struct {
 	*platform.BaseFlight
-	api      *aws.API
-	keyAdded bool
+	api          *aws.API
+	instanceType string
+	keyAdded     bool
 }
 
 // NewFlight creates an instance of a Flight suitable for spawning
@@ -57,8 +58,9 @@ func NewFlight(opts *aws.Options) (platform.Flight, error) {
 	}
 
 	af := &flight{
-		BaseFlight: bf,
-		api:        api,
+		BaseFlight:   bf,
+		api:          api,
+		instanceType: opts.InstanceType,
 	}
 
 	keys, err := af.Keys()
@@ -93,28 +95,19 @@ func (af *flight) NewCluster(rconf *platform.RuntimeConfig) (platform.Cluster, e
 	return ac, nil
 }
 
-func (af *flight) ConfigTooLarge(ud conf.UserData) bool {
-	config, err := ud.Render(conf.IgnoreWarnings)
-	if err != nil {
-		return true
+func (af *flight) Capabilities() platform.Capabilities {
+	caps := platform.Capabilities{
+		Features:      []platform.Capability{platform.CapInstanceType},
+		Architectures: []string{"x86_64", "aarch64"},
 	}
-	configData := config.String()
-	encoding := base64.StdEncoding.EncodeToString([]byte(configData))
-	if len([]byte(encoding)) > MaxUserDataSize {
-		configData, err = config.MaybeCompress()
-		if err != nil {
-			return true
-		}
-		// Check if config is still too large
-		encoding = base64.StdEncoding.EncodeToString([]byte(configData))
-		if len([]byte(encoding)) > MaxUserDataSize {
-			// Config is too large
-			return true
-		}
+	if af.HasNestedVirtProfile() {
+		caps.Features = append(caps.Features, platform.CapNestedVirt)
 	}
+	return caps
+}
 
-	// Config is not too large
-	return false
+func (af *flight) ConfigTooLarge(ud conf.UserData) bool {
+	return af.UserDataTooLarge(ud, userDataLimit)
 }
 
 func (af *flight) Destroy() {
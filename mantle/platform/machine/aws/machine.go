@@ -16,8 +16,6 @@ package aws
 
 import (
 	"fmt"
-	"os"
-	"path/filepath"
 	"strings"
 	"time"
 
@@ -64,6 +62,14 @@ func (am *machine) SSH(cmd string) ([]byte, []byte, error) {
 	return am.cluster.SSH(am, cmd)
 }
 
+func (am *machine) Upload(localPath, remotePath string) error {
+	return platform.UploadToMachine(am, localPath, remotePath)
+}
+
+func (am *machine) Download(remotePath, localPath string) error {
+	return platform.DownloadFromMachine(am, remotePath, localPath)
+}
+
 func (am *machine) IgnitionError() error {
 	return nil
 }
@@ -148,17 +154,7 @@ func (am *machine) saveConsole(origConsole string) error {
 		am.console = origConsole + "\n\n8<------------------------\n\n" + am.console
 	}
 
-	path := filepath.Join(am.dir, "console.txt")
-	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0644)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-	if _, err := f.WriteString(am.console); err != nil {
-		return err
-	}
-
-	return nil
+	return platform.SaveConsole(am.dir, am.console)
 }
 
 func (am *machine) JournalOutput() string {
@@ -15,9 +15,7 @@
 package azure
 
 import (
-	"crypto/rand"
 	"errors"
-	"fmt"
 	"os"
 	"path/filepath"
 
@@ -33,14 +31,6 @@ type cluster struct {
 	StorageAccount string
 }
 
-func (ac *cluster) vmname() string {
-	b := make([]byte, 5)
-	if _, err := rand.Read(b); err != nil {
-		plog.Errorf("failed to generate a random vmname: %v", err)
-	}
-	return fmt.Sprintf("%s-%x", ac.Name()[0:13], b)
-}
-
 func (ac *cluster) NewMachine(userdata *conf.UserData) (platform.Machine, error) {
 	return ac.NewMachineWithOptions(userdata, platform.MachineOptions{})
 }
@@ -58,15 +48,25 @@ func (ac *cluster) NewMachineWithOptions(userdata *conf.UserData, options platfo
 	if options.AppendFirstbootKernelArgs != "" {
 		return nil, errors.New("platform azure does not support appending firstboot kernel arguments")
 	}
+	instanceType, err := ac.InstanceType(options, ac.flight.size)
+	if err != nil {
+		return nil, err
+	}
+	options.InstanceType = instanceType
 
 	conf, err := ac.RenderUserData(userdata, map[string]string{
-		"$private_ipv4": "${COREOS_AZURE_IPV4_DYNAMIC}",
+		platform.UserDataPrivateIPv4: "${COREOS_AZURE_IPV4_DYNAMIC}",
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	instance, err := ac.flight.api.CreateInstance(ac.vmname(), conf.String(), ac.sshKey, ac.ResourceGroup, ac.StorageAccount, options)
+	ud, err := ac.FitUserData(conf, userDataLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	instance, err := ac.flight.api.CreateInstance(ac.MachineName(), ud, ac.sshKey, ac.ResourceGroup, ac.StorageAccount, options, ac.ResourceTags())
 	if err != nil {
 		return nil, err
 	}
@@ -75,6 +75,7 @@ func (ac *cluster) NewMachineWithOptions(userdata *conf.UserData, options platfo
 		cluster: ac,
 		mach:    instance,
 	}
+	ac.BindUserData(mach.ID(), ud)
 
 	mach.dir = filepath.Join(ac.RuntimeConf().OutputDir, mach.ID())
 	if err := os.Mkdir(mach.dir, 0777); err != nil {
@@ -83,7 +84,7 @@ func (ac *cluster) NewMachineWithOptions(userdata *conf.UserData, options platfo
 	}
 
 	confPath := filepath.Join(mach.dir, "user-data")
-	if err := conf.WriteFile(confPath); err != nil {
+	if err := conf.WriteRedactedFile(confPath); err != nil {
 		mach.Destroy()
 		return nil, err
 	}
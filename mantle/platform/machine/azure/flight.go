@@ -25,15 +25,21 @@ import (
 
 const (
 	Platform platform.Name = "azure"
+
+	// The largest user data a VM can be given
+	MaxUserDataSize = 256 * 1024
 )
 
 var (
 	plog = capnslog.NewPackageLogger("github.com/coreos/coreos-assembler/mantle", "platform/machine/azure")
+
+	userDataLimit = platform.UserDataLimit{MaxSize: MaxUserDataSize, Base64: true}
 )
 
 type flight struct {
 	*platform.BaseFlight
 	api    *azure.API
+	size   string
 	SSHKey string
 }
 
@@ -57,6 +63,7 @@ func NewFlight(opts *azure.Options) (platform.Flight, error) {
 	af := &flight{
 		BaseFlight: bf,
 		api:        api,
+		size:       opts.Size,
 	}
 
 	keys, err := af.Keys()
@@ -86,7 +93,7 @@ func (af *flight) NewCluster(rconf *platform.RuntimeConfig) (platform.Cluster, e
 		ac.sshKey = af.SSHKey
 	}
 
-	ac.ResourceGroup, err = af.api.CreateResourceGroup("kola-cluster")
+	ac.ResourceGroup, err = af.api.CreateResourceGroup("kola-cluster", ac.ResourceTags())
 	if err != nil {
 		return nil, err
 	}
@@ -112,10 +119,19 @@ func (af *flight) NewCluster(rconf *platform.RuntimeConfig) (platform.Cluster, e
 	return ac, nil
 }
 
-func (af *flight) ConfigTooLarge(ud conf.UserData) bool {
+func (af *flight) Capabilities() platform.Capabilities {
+	caps := platform.Capabilities{
+		Features:      []platform.Capability{platform.CapAdditionalDisks, platform.CapInstanceType},
+		Architectures: []string{"x86_64", "aarch64"},
+	}
+	if af.HasNestedVirtProfile() {
+		caps.Features = append(caps.Features, platform.CapNestedVirt)
+	}
+	return caps
+}
 
-	// not implemented
-	return false
+func (af *flight) ConfigTooLarge(ud conf.UserData) bool {
+	return af.UserDataTooLarge(ud, userDataLimit)
 }
 
 func (af *flight) Destroy() {
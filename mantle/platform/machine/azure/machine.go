@@ -16,8 +16,6 @@ package azure
 
 import (
 	"fmt"
-	"os"
-	"path/filepath"
 	"time"
 
 	"golang.org/x/crypto/ssh"
@@ -74,6 +72,14 @@ func (am *machine) SSH(cmd string) ([]byte, []byte, error) {
 	return am.cluster.SSH(am, cmd)
 }
 
+func (am *machine) Upload(localPath, remotePath string) error {
+	return platform.UploadToMachine(am, localPath, remotePath)
+}
+
+func (am *machine) Download(remotePath, localPath string) error {
+	return platform.DownloadFromMachine(am, remotePath, localPath)
+}
+
 func (am *machine) IgnitionError() error {
 	return nil
 }
@@ -136,18 +142,7 @@ func (am *machine) saveConsole() error {
 		return err
 	}
 
-	path := filepath.Join(am.dir, "console.txt")
-	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0666)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-	_, err = f.Write(am.console)
-	if err != nil {
-		return fmt.Errorf("failed writing console to file: %v", err)
-	}
-
-	return nil
+	return platform.SaveConsole(am.dir, string(am.console))
 }
 
 func (am *machine) JournalOutput() string {
@@ -16,7 +16,6 @@ package do
 
 import (
 	"context"
-	"crypto/rand"
 	"errors"
 	"fmt"
 	"os"
@@ -57,14 +56,19 @@ func (dc *cluster) NewMachineWithOptions(userdata *conf.UserData, options platfo
 	}
 
 	conf, err := dc.RenderUserData(userdata, map[string]string{
-		"$public_ipv4":  "${COREOS_DIGITALOCEAN_IPV4_PUBLIC_0}",
-		"$private_ipv4": "${COREOS_DIGITALOCEAN_IPV4_PRIVATE_0}",
+		platform.UserDataPublicIPv4:  "${COREOS_DIGITALOCEAN_IPV4_PUBLIC_0}",
+		platform.UserDataPrivateIPv4: "${COREOS_DIGITALOCEAN_IPV4_PRIVATE_0}",
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	droplet, err := dc.flight.api.CreateDroplet(context.TODO(), dc.vmname(), dc.sshKeyID, conf.String())
+	ud, err := dc.FitUserData(conf, userDataLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	droplet, err := dc.flight.api.CreateDroplet(context.TODO(), dc.MachineName(), dc.sshKeyID, ud, dc.ResourceTags())
 	if err != nil {
 		return nil, err
 	}
@@ -73,6 +77,7 @@ func (dc *cluster) NewMachineWithOptions(userdata *conf.UserData, options platfo
 		cluster: dc,
 		droplet: droplet,
 	}
+	dc.BindUserData(mach.ID(), ud)
 	mach.publicIP, err = droplet.PublicIPv4()
 	if mach.publicIP == "" || err != nil {
 		mach.Destroy()
@@ -91,7 +96,7 @@ func (dc *cluster) NewMachineWithOptions(userdata *conf.UserData, options platfo
 	}
 
 	confPath := filepath.Join(dir, "user-data")
-	if err := conf.WriteFile(confPath); err != nil {
+	if err := conf.WriteRedactedFile(confPath); err != nil {
 		mach.Destroy()
 		return nil, err
 	}
@@ -115,14 +120,6 @@ func (dc *cluster) NewMachineWithOptions(userdata *conf.UserData, options platfo
 	return mach, nil
 }
 
-func (dc *cluster) vmname() string {
-	b := make([]byte, 5)
-	if _, err := rand.Read(b); err != nil {
-		plog.Errorf("failed to generate a random vmname: %v", err)
-	}
-	return fmt.Sprintf("%s-%x", dc.Name()[0:13], b)
-}
-
 func (dc *cluster) Destroy() {
 	dc.BaseCluster.Destroy()
 	dc.flight.DelCluster(dc)
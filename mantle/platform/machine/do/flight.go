@@ -27,10 +27,15 @@ import (
 
 const (
 	Platform platform.Name = "do"
+
+	// The largest user data a droplet can be given
+	MaxUserDataSize = 64 * 1024
 )
 
 var (
 	plog = capnslog.NewPackageLogger("github.com/coreos/coreos-assembler/mantle", "platform/machine/do")
+
+	userDataLimit = platform.UserDataLimit{MaxSize: MaxUserDataSize}
 )
 
 type flight struct {
@@ -61,7 +66,7 @@ func NewFlight(opts *do.Options) (platform.Flight, error) {
 		df.Destroy()
 		return nil, err
 	}
-	df.sshKeyID, err = df.api.AddKey(context.TODO(), df.Name(), keys[0].String())
+	df.sshKeyID, err = df.api.AddRunKey(context.TODO(), "kola", keys[0].String())
 	if err != nil {
 		df.Destroy()
 		return nil, err
@@ -74,7 +79,7 @@ func NewFlight(opts *do.Options) (platform.Flight, error) {
 		df.Destroy()
 		return nil, err
 	}
-	df.fakeSSHKeyID, err = df.api.AddKey(context.TODO(), df.Name()+"-fake", key)
+	df.fakeSSHKeyID, err = df.api.AddRunKey(context.TODO(), "kola-fake", key)
 	if err != nil {
 		df.Destroy()
 		return nil, err
@@ -107,10 +112,14 @@ func (df *flight) NewCluster(rconf *platform.RuntimeConfig) (platform.Cluster, e
 	return dc, nil
 }
 
-func (af *flight) ConfigTooLarge(ud conf.UserData) bool {
+func (df *flight) Capabilities() platform.Capabilities {
+	return platform.Capabilities{
+		Architectures: []string{"x86_64"},
+	}
+}
 
-	// not implemented
-	return false
+func (af *flight) ConfigTooLarge(ud conf.UserData) bool {
+	return af.UserDataTooLarge(ud, userDataLimit)
 }
 
 func (df *flight) Destroy() {
@@ -61,6 +61,14 @@ func (dm *machine) SSH(cmd string) ([]byte, []byte, error) {
 	return dm.cluster.SSH(dm, cmd)
 }
 
+func (dm *machine) Upload(localPath, remotePath string) error {
+	return platform.UploadToMachine(dm, localPath, remotePath)
+}
+
+func (dm *machine) Download(remotePath, localPath string) error {
+	return platform.DownloadFromMachine(dm, remotePath, localPath)
+}
+
 func (dm *machine) IgnitionError() error {
 	return nil
 }
@@ -0,0 +1,133 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package equinix
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/coreos/coreos-assembler/mantle/platform/api/equinix"
+)
+
+// console records the serial console of a server from its serial over SSH
+// console, which only shows what's written while connected.
+type console struct {
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	client *ssh.Client
+	done   chan struct{}
+}
+
+// startConsole connects to the serial over SSH console of the server in
+// the background, retrying until it's reachable.
+func startConsole(ec *cluster, device *equinix.Device) *console {
+	c := &console{done: make(chan struct{})}
+	if device.SOS == "" {
+		plog.Warningf("Server %s has no serial over SSH console", device.ID)
+		return c
+	}
+	go func() {
+		for {
+			err := c.record(ec, device)
+			if err == nil {
+				return
+			}
+			plog.Debugf("Connecting to console of server %s: %v", device.ID, err)
+			select {
+			case <-c.done:
+				return
+			case <-time.After(10 * time.Second):
+			}
+		}
+	}()
+	return c
+}
+
+// record copies the console into the buffer until it's closed.
+func (c *console) record(ec *cluster, device *equinix.Device) error {
+	client, err := ec.UserSSHClient(device.SOS, device.ID)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	select {
+	case <-c.done:
+		c.mu.Unlock()
+		client.Close()
+		return nil
+	default:
+	}
+	c.client = client
+	c.mu.Unlock()
+
+	session, err := client.NewSession()
+	if err != nil {
+		client.Close()
+		return err
+	}
+	if err := session.RequestPty("vt100", 25, 80, ssh.TerminalModes{}); err != nil {
+		client.Close()
+		return err
+	}
+	out, err := session.StdoutPipe()
+	if err != nil {
+		client.Close()
+		return err
+	}
+	if err := session.Shell(); err != nil {
+		client.Close()
+		return err
+	}
+	_, err = io.Copy(c, out)
+	select {
+	case <-c.done:
+		return nil
+	default:
+		// Reconnect if the console went away while the server lives
+		return err
+	}
+}
+
+func (c *console) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.buf.Write(p)
+}
+
+// Close stops recording the console.
+func (c *console) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	select {
+	case <-c.done:
+		return
+	default:
+	}
+	close(c.done)
+	if c.client != nil {
+		c.client.Close()
+	}
+}
+
+// Output returns what was recorded of the console.
+func (c *console) Output() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.buf.String()
+}
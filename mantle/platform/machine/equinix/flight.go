@@ -0,0 +1,174 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package equinix
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/coreos/pkg/capnslog"
+
+	"github.com/coreos/coreos-assembler/mantle/platform"
+	"github.com/coreos/coreos-assembler/mantle/platform/api/equinix"
+	"github.com/coreos/coreos-assembler/mantle/platform/conf"
+)
+
+const (
+	Platform platform.Name = "equinix"
+
+	// The largest user data a server can be given
+	MaxUserDataSize = 64 * 1024
+)
+
+var (
+	plog = capnslog.NewPackageLogger("github.com/coreos/coreos-assembler/mantle", "platform/machine/equinix")
+
+	userDataLimit = platform.UserDataLimit{MaxSize: MaxUserDataSize}
+)
+
+type flight struct {
+	*platform.BaseFlight
+	api      *equinix.API
+	sshKeyID string
+	server   *http.Server
+}
+
+func NewFlight(opts *equinix.Options) (platform.Flight, error) {
+	api, err := equinix.New(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	bf, err := platform.NewBaseFlight(opts.Options, Platform)
+	if err != nil {
+		return nil, err
+	}
+
+	ef := &flight{
+		BaseFlight: bf,
+		api:        api,
+	}
+
+	if opts.IPXEScriptURL == "" {
+		if opts.ServeAddress == "" {
+			ef.Destroy()
+			return nil, fmt.Errorf("an iPXE script URL is required unless the live artifacts are served")
+		}
+		opts.IPXEScriptURL = strings.TrimSuffix(opts.ArtifactURL, "/") + "/boot.ipxe"
+	}
+	if opts.ServeAddress != "" {
+		if err := ef.serveArtifacts(opts); err != nil {
+			ef.Destroy()
+			return nil, err
+		}
+	}
+
+	keys, err := ef.Keys()
+	if err != nil {
+		ef.Destroy()
+		return nil, err
+	}
+	ef.sshKeyID, err = ef.api.AddKey(ef.Name(), keys[0].String())
+	if err != nil {
+		ef.Destroy()
+		return nil, err
+	}
+
+	return ef, nil
+}
+
+// serveArtifacts serves the live artifacts and the iPXE script which boots
+// them, so servers can fetch them from the machine kola runs on.
+func (ef *flight) serveArtifacts(opts *equinix.Options) error {
+	script, err := equinix.IPXEScript(opts)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/boot.ipxe", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte(script))
+	})
+	for _, name := range []string{opts.Kernel, opts.Initramfs, opts.Rootfs} {
+		path := filepath.Join(opts.ArtifactDir, name)
+		mux.HandleFunc("/"+name, func(w http.ResponseWriter, r *http.Request) {
+			plog.Debugf("Serving %s to %s", path, r.RemoteAddr)
+			http.ServeFile(w, r, path)
+		})
+	}
+
+	listener, err := net.Listen("tcp", opts.ServeAddress)
+	if err != nil {
+		return fmt.Errorf("serving live artifacts: %v", err)
+	}
+	ef.server = &http.Server{Handler: mux}
+	go func() {
+		if err := ef.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			plog.Errorf("Serving live artifacts: %v", err)
+		}
+	}()
+	plog.Infof("Serving live artifacts from %s on %s as %s", opts.ArtifactDir, listener.Addr(), opts.ArtifactURL)
+	return nil
+}
+
+func (ef *flight) NewCluster(rconf *platform.RuntimeConfig) (platform.Cluster, error) {
+	bc, err := platform.NewBaseCluster(ef.BaseFlight, rconf)
+	if err != nil {
+		return nil, err
+	}
+
+	ec := &cluster{
+		BaseCluster: bc,
+		flight:      ef,
+	}
+	if !rconf.NoSSHKeyInMetadata {
+		ec.sshKeyID = ef.sshKeyID
+	}
+
+	ef.AddCluster(ec)
+
+	return ec, nil
+}
+
+func (ef *flight) Capabilities() platform.Capabilities {
+	return platform.Capabilities{
+		Architectures: []string{"x86_64", "aarch64"},
+	}
+}
+
+func (ef *flight) ConfigTooLarge(ud conf.UserData) bool {
+	return ef.UserDataTooLarge(ud, userDataLimit)
+}
+
+func (ef *flight) Destroy() {
+	if ef.sshKeyID != "" {
+		if err := ef.api.DeleteKey(ef.sshKeyID); err != nil {
+			plog.Errorf("Error deleting key %v: %v", ef.sshKeyID, err)
+		}
+	}
+
+	// Destroy the machines before the artifacts they boot go away
+	ef.BaseFlight.Destroy()
+
+	if ef.server != nil {
+		if err := ef.server.Shutdown(context.Background()); err != nil {
+			plog.Errorf("Error stopping artifact server: %v", err)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package equinix
+
+import (
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/coreos/coreos-assembler/mantle/platform"
+	"github.com/coreos/coreos-assembler/mantle/platform/api/equinix"
+)
+
+type machine struct {
+	cluster *cluster
+	device  *equinix.Device
+	journal *platform.Journal
+	console *console
+}
+
+func (em *machine) ID() string {
+	return em.device.ID
+}
+
+func (em *machine) IP() string {
+	return em.device.PublicIP()
+}
+
+func (em *machine) PrivateIP() string {
+	return em.device.PrivateIP()
+}
+
+func (em *machine) RuntimeConf() platform.RuntimeConfig {
+	return em.cluster.RuntimeConf()
+}
+
+func (em *machine) SSHClient() (*ssh.Client, error) {
+	return em.cluster.SSHClient(em.IP())
+}
+
+func (em *machine) PasswordSSHClient(user string, password string) (*ssh.Client, error) {
+	return em.cluster.PasswordSSHClient(em.IP(), user, password)
+}
+
+func (em *machine) SSH(cmd string) ([]byte, []byte, error) {
+	return em.cluster.SSH(em, cmd)
+}
+
+func (em *machine) Upload(localPath, remotePath string) error {
+	return platform.UploadToMachine(em, localPath, remotePath)
+}
+
+func (em *machine) Download(remotePath, localPath string) error {
+	return platform.DownloadFromMachine(em, remotePath, localPath)
+}
+
+func (em *machine) IgnitionError() error {
+	return nil
+}
+
+func (em *machine) Start() error {
+	return platform.StartMachine(em, em.journal)
+}
+
+func (em *machine) Reboot() error {
+	return platform.RebootMachine(em, em.journal)
+}
+
+func (em *machine) WaitForReboot(timeout time.Duration, oldBootId string) error {
+	return platform.WaitForMachineReboot(em, em.journal, timeout, oldBootId)
+}
+
+func (em *machine) Destroy() {
+	if em.console != nil {
+		em.console.Close()
+	}
+
+	if err := em.cluster.flight.api.DeleteDevice(em.device.ID); err != nil {
+		plog.Errorf("Error deleting server %v: %v", em.device.ID, err)
+	}
+
+	if em.journal != nil {
+		em.journal.Destroy()
+	}
+
+	em.cluster.DelMach(em)
+}
+
+func (em *machine) ConsoleOutput() string {
+	if em.console == nil {
+		return ""
+	}
+	return em.console.Output()
+}
+
+func (em *machine) JournalOutput() string {
+	if em.journal == nil {
+		return ""
+	}
+
+	data, err := em.journal.Read()
+	if err != nil {
+		plog.Errorf("Reading journal for server %v: %v", em.device.ID, err)
+	}
+	return string(data)
+}
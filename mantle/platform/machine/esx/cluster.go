@@ -15,9 +15,7 @@
 package esx
 
 import (
-	"crypto/rand"
 	"errors"
-	"fmt"
 	"os"
 	"path/filepath"
 
@@ -30,14 +28,6 @@ type cluster struct {
 	flight *flight
 }
 
-func (ec *cluster) vmname() string {
-	b := make([]byte, 5)
-	if _, err := rand.Read(b); err != nil {
-		plog.Errorf("failed to generate a random vmname: %v", err)
-	}
-	return fmt.Sprintf("%s-%x", ec.Name(), b)
-}
-
 func (ec *cluster) NewMachine(userdata *platformConf.UserData) (platform.Machine, error) {
 	return ec.NewMachineWithOptions(userdata, platform.MachineOptions{})
 }
@@ -63,8 +53,8 @@ func (ec *cluster) NewMachineWithOptions(userdata *platformConf.UserData, option
 	}
 
 	conf, err := ec.RenderUserData(userdata, map[string]string{
-		"$public_ipv4":  "${COREOS_ESX_IPV4_PUBLIC_0}",
-		"$private_ipv4": "${COREOS_ESX_IPV4_PRIVATE_0}",
+		platform.UserDataPublicIPv4:  "${COREOS_ESX_IPV4_PUBLIC_0}",
+		platform.UserDataPrivateIPv4: "${COREOS_ESX_IPV4_PRIVATE_0}",
 	})
 	if err != nil {
 		return nil, err
@@ -79,7 +69,7 @@ Environment=OUTPUT=/run/metadata/coreos
 ExecStart=/usr/bin/mkdir --parent /run/metadata
 ExecStart=/usr/bin/bash -c 'echo "COREOS_ESX_IPV4_PRIVATE_0=$(ip addr show ens192 | grep -Po "inet \K[\d.]+")\nCOREOS_ESX_IPV4_PUBLIC_0=$(ip addr show ens192 | grep -Po "inet \K[\d.]+")" > ${OUTPUT}'`, platformConf.NoState)
 
-	instance, err := ec.flight.api.CreateDevice(ec.vmname(), conf)
+	instance, err := ec.flight.api.CreateDevice(ec.MachineName(), conf)
 	if err != nil {
 		return nil, err
 	}
@@ -96,7 +86,7 @@ ExecStart=/usr/bin/bash -c 'echo "COREOS_ESX_IPV4_PRIVATE_0=$(ip addr show ens19
 	}
 
 	confPath := filepath.Join(mach.dir, "user-data")
-	if err := conf.WriteFile(confPath); err != nil {
+	if err := conf.WriteRedactedFile(confPath); err != nil {
 		mach.Destroy()
 		return nil, err
 	}
@@ -57,6 +57,12 @@ func NewFlight(opts *esx.Options) (platform.Flight, error) {
 	return ef, nil
 }
 
+func (ef *flight) Capabilities() platform.Capabilities {
+	return platform.Capabilities{
+		Architectures: []string{"x86_64"},
+	}
+}
+
 func (af *flight) ConfigTooLarge(ud conf.UserData) bool {
 
 	// not implemented
@@ -15,15 +15,12 @@
 package esx
 
 import (
-	"os"
-	"path/filepath"
 	"time"
 
 	"golang.org/x/crypto/ssh"
 
 	"github.com/coreos/coreos-assembler/mantle/platform"
 	"github.com/coreos/coreos-assembler/mantle/platform/api/esx"
-	"github.com/pkg/errors"
 )
 
 type machine struct {
@@ -62,6 +59,14 @@ func (em *machine) SSH(cmd string) ([]byte, []byte, error) {
 	return em.cluster.SSH(em, cmd)
 }
 
+func (em *machine) Upload(localPath, remotePath string) error {
+	return platform.UploadToMachine(em, localPath, remotePath)
+}
+
+func (em *machine) Download(remotePath, localPath string) error {
+	return platform.DownloadFromMachine(em, remotePath, localPath)
+}
+
 func (em *machine) IgnitionError() error {
 	return nil
 }
@@ -109,18 +114,7 @@ func (em *machine) saveConsole() error {
 		return err
 	}
 
-	path := filepath.Join(em.dir, "console.txt")
-	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0666)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-	_, err = f.WriteString(em.console)
-	if err != nil {
-		return errors.Wrapf(err, "failed writing console to file")
-	}
-
-	return nil
+	return platform.SaveConsole(em.dir, em.console)
 }
 
 func (em *machine) JournalOutput() string {
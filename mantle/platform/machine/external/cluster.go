@@ -0,0 +1,137 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package external
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/coreos/coreos-assembler/mantle/platform"
+	"github.com/coreos/coreos-assembler/mantle/platform/conf"
+)
+
+type cluster struct {
+	*platform.BaseCluster
+	flight *flight
+}
+
+func (ec *cluster) NewMachine(userdata *conf.UserData) (platform.Machine, error) {
+	return ec.NewMachineWithOptions(userdata, platform.MachineOptions{})
+}
+
+func (ec *cluster) NewMachineWithOptions(userdata *conf.UserData, options platform.MachineOptions) (platform.Machine, error) {
+	if len(options.AdditionalDisks) > 0 {
+		return nil, errors.New("external platforms do not yet support additional disks")
+	}
+	if options.MultiPathDisk {
+		return nil, errors.New("external platforms do not support multipathed disks")
+	}
+	if options.AdditionalNics > 0 {
+		return nil, errors.New("external platforms do not support additional nics")
+	}
+	if options.AppendKernelArgs != "" {
+		return nil, errors.New("external platforms do not support appending kernel arguments")
+	}
+	if options.AppendFirstbootKernelArgs != "" {
+		return nil, errors.New("external platforms do not support appending firstboot kernel arguments")
+	}
+
+	conf, err := ec.RenderUserData(userdata, map[string]string{
+		platform.UserDataHostIP: ec.flight.hostIP,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	if !ec.RuntimeConf().NoSSHKeyInMetadata {
+		agentKeys, err := ec.Keys()
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range agentKeys {
+			keys = append(keys, key.String())
+		}
+	}
+
+	var resp createMachineResponse
+	err = ec.flight.driver.call("create-machine", request{
+		Name:              ec.MachineName(),
+		Ignition:          conf.String(),
+		OutputDir:         ec.RuntimeConf().OutputDir,
+		InstanceType:      options.InstanceType,
+		MinMemory:         options.MinMemory,
+		MinDiskSize:       options.MinDiskSize,
+		SSHAuthorizedKeys: keys,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	// Without an ID, there's nothing to destroy
+	if resp.ID == "" {
+		return nil, fmt.Errorf("platform driver returned no ID for machine")
+	}
+
+	mach := &machine{
+		cluster:   ec,
+		id:        resp.ID,
+		publicIP:  resp.IP,
+		privateIP: resp.PrivateIP,
+	}
+	if mach.publicIP == "" {
+		mach.Destroy()
+		return nil, fmt.Errorf("platform driver returned no IP address for machine %s", mach.id)
+	}
+	if mach.privateIP == "" {
+		mach.privateIP = mach.publicIP
+	}
+
+	mach.dir = filepath.Join(ec.RuntimeConf().OutputDir, mach.ID())
+	if err := os.Mkdir(mach.dir, 0777); err != nil {
+		mach.Destroy()
+		return nil, err
+	}
+
+	confPath := filepath.Join(mach.dir, "ignition.json")
+	if err := conf.WriteRedactedFile(confPath); err != nil {
+		mach.Destroy()
+		return nil, err
+	}
+
+	if mach.journal, err = platform.NewJournal(mach.dir); err != nil {
+		mach.Destroy()
+		return nil, err
+	}
+
+	// Run StartMachine, which blocks on the machine being booted up enough
+	// for SSH access, but only if the caller didn't tell us not to.
+	if !options.SkipStartMachine {
+		if err := platform.StartMachine(mach, mach.journal); err != nil {
+			mach.Destroy()
+			return nil, err
+		}
+	}
+
+	ec.AddMach(mach)
+
+	return mach, nil
+}
+
+func (ec *cluster) Destroy() {
+	ec.BaseCluster.Destroy()
+	ec.flight.DelCluster(ec)
+}
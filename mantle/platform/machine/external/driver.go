@@ -0,0 +1,146 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package external implements platforms which live outside of mantle as
+// driver executables, so that e.g. Nutanix or oVirt support can be
+// maintained out of tree.
+//
+// kola invokes the driver once per operation as `DRIVER <operation>`,
+// writing a JSON request to its stdin and reading a JSON response from its
+// stdout. A non-zero exit status means the operation failed; the driver's
+// stderr is included in the error. Every request carries the "flight" name
+// and the "options" given with --platform-driver-opt. The operations are:
+//
+//	create-flight    {} -> {"host-ip"}
+//	destroy-flight   {} -> {}
+//	create-machine   {"name", "ignition", "output-dir", "instance-type",
+//	                  "min-memory", "min-disk-size", "ssh-authorized-keys"}
+//	                 -> {"id", "ip", "private-ip"}
+//	destroy-machine  {"id"} -> {}
+//	console-output   {"id"} -> {"output"}
+//
+// Machines must boot the given Ignition config and be reachable over SSH at
+// the returned IP; everything else kola does through SSH. The optional
+// "host-ip" is the address machines reach the host running kola at, which
+// configs can use as the .HostIP template variable.
+package external
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/coreos/coreos-assembler/mantle/platform"
+)
+
+// DriverPrefix is the prefix of driver executables found in $PATH; the
+// driver for platform "foo" is named kola-platform-foo.
+const DriverPrefix = "kola-platform-"
+
+// Options configures an external platform.
+type Options struct {
+	*platform.Options
+
+	// Driver is the path to the driver executable
+	Driver string
+	// DriverOptions are passed through to the driver, as key=value
+	DriverOptions []string
+}
+
+// FindDriver returns the path of the driver for a platform in $PATH.
+func FindDriver(name string) (string, error) {
+	return exec.LookPath(DriverPrefix + name)
+}
+
+type driver struct {
+	path    string
+	flight  string
+	options map[string]string
+}
+
+type request struct {
+	Flight  string            `json:"flight"`
+	Options map[string]string `json:"options,omitempty"`
+
+	ID string `json:"id,omitempty"`
+
+	Name              string   `json:"name,omitempty"`
+	Ignition          string   `json:"ignition,omitempty"`
+	OutputDir         string   `json:"output-dir,omitempty"`
+	InstanceType      string   `json:"instance-type,omitempty"`
+	MinMemory         int      `json:"min-memory,omitempty"`
+	MinDiskSize       int      `json:"min-disk-size,omitempty"`
+	SSHAuthorizedKeys []string `json:"ssh-authorized-keys,omitempty"`
+}
+
+type createFlightResponse struct {
+	HostIP string `json:"host-ip"`
+}
+
+type createMachineResponse struct {
+	ID        string `json:"id"`
+	IP        string `json:"ip"`
+	PrivateIP string `json:"private-ip"`
+}
+
+type consoleOutputResponse struct {
+	Output string `json:"output"`
+}
+
+func newDriver(opts *Options, flight string) (*driver, error) {
+	d := &driver{
+		path:    opts.Driver,
+		flight:  flight,
+		options: make(map[string]string),
+	}
+	for _, opt := range opts.DriverOptions {
+		k, v, ok := strings.Cut(opt, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid driver option %q; expected key=value", opt)
+		}
+		d.options[k] = v
+	}
+	return d, nil
+}
+
+// call runs an operation, decoding its response into resp if non-nil.
+func (d *driver) call(op string, req request, resp interface{}) error {
+	req.Flight = d.flight
+	req.Options = d.options
+	buf, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(d.path, op)
+	cmd.Stdin = bytes.NewReader(buf)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("platform driver %s %s: %v: %s", d.path, op, err, strings.TrimSpace(stderr.String()))
+	}
+	if stderr.Len() > 0 {
+		plog.Debugf("platform driver %s: %s", op, strings.TrimSpace(stderr.String()))
+	}
+	// Drivers may print nothing for responses without fields they set
+	if resp == nil || len(bytes.TrimSpace(stdout.Bytes())) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(stdout.Bytes(), resp); err != nil {
+		return fmt.Errorf("parsing platform driver %s response: %v", op, err)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package external
+
+import (
+	"github.com/coreos/pkg/capnslog"
+
+	"github.com/coreos/coreos-assembler/mantle/platform"
+	"github.com/coreos/coreos-assembler/mantle/platform/conf"
+)
+
+var (
+	plog = capnslog.NewPackageLogger("github.com/coreos/coreos-assembler/mantle", "platform/machine/external")
+)
+
+type flight struct {
+	*platform.BaseFlight
+	driver *driver
+	// hostIP is the address machines reach the host at, if the driver
+	// knows it
+	hostIP string
+}
+
+// NewFlight creates a flight for the platform pltfrm, as implemented by the
+// driver in opts.
+func NewFlight(opts *Options, pltfrm string) (platform.Flight, error) {
+	bf, err := platform.NewBaseFlight(opts.Options, platform.Name(pltfrm))
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := newDriver(opts, bf.Name())
+	if err != nil {
+		bf.Destroy()
+		return nil, err
+	}
+	var resp createFlightResponse
+	if err := d.call("create-flight", request{}, &resp); err != nil {
+		bf.Destroy()
+		return nil, err
+	}
+
+	return &flight{
+		BaseFlight: bf,
+		driver:     d,
+		hostIP:     resp.HostIP,
+	}, nil
+}
+
+func (ef *flight) NewCluster(rconf *platform.RuntimeConfig) (platform.Cluster, error) {
+	bc, err := platform.NewBaseCluster(ef.BaseFlight, rconf)
+	if err != nil {
+		return nil, err
+	}
+
+	ec := &cluster{
+		BaseCluster: bc,
+		flight:      ef,
+	}
+
+	ef.AddCluster(ec)
+
+	return ec, nil
+}
+
+func (ef *flight) Capabilities() platform.Capabilities {
+	return platform.Capabilities{
+		Features: []platform.Capability{platform.CapInstanceType},
+	}
+}
+
+func (ef *flight) ConfigTooLarge(ud conf.UserData) bool {
+	// not implemented
+	return false
+}
+
+func (ef *flight) Destroy() {
+	ef.BaseFlight.Destroy()
+
+	if err := ef.driver.call("destroy-flight", request{}, nil); err != nil {
+		plog.Errorf("Error destroying flight: %v", err)
+	}
+}
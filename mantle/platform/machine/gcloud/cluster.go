@@ -49,18 +49,25 @@ func (gc *cluster) NewMachineWithOptions(userdata *conf.UserData, options platfo
 	if options.AppendFirstbootKernelArgs != "" {
 		return nil, errors.New("platform gcp does not support appending firstboot kernel arguments")
 	}
-	if options.InstanceType != "" {
-		return nil, errors.New("platform gcp does not support changing instance types")
+	instanceType, err := gc.InstanceType(options, gc.flight.machineType)
+	if err != nil {
+		return nil, err
 	}
+	options.InstanceType = instanceType
 
 	conf, err := gc.RenderUserData(userdata, map[string]string{
-		"$public_ipv4":  "${COREOS_GCE_IP_EXTERNAL_0}",
-		"$private_ipv4": "${COREOS_GCE_IP_LOCAL_0}",
+		platform.UserDataPublicIPv4:  "${COREOS_GCE_IP_EXTERNAL_0}",
+		platform.UserDataPrivateIPv4: "${COREOS_GCE_IP_LOCAL_0}",
 	})
 	if err != nil {
 		return nil, err
 	}
 
+	ud, err := gc.FitUserData(conf, userDataLimit)
+	if err != nil {
+		return nil, err
+	}
+
 	var keys []*agent.Key
 	if !gc.RuntimeConf().NoSSHKeyInMetadata {
 		keys, err = gc.Keys()
@@ -69,7 +76,7 @@ func (gc *cluster) NewMachineWithOptions(userdata *conf.UserData, options platfo
 		}
 	}
 
-	instance, err := gc.flight.api.CreateInstance(conf.String(), keys, options, !gc.RuntimeConf().NoInstanceCreds)
+	instance, err := gc.flight.api.CreateInstance(ud, keys, options, !gc.RuntimeConf().NoInstanceCreds, gc.ResourceTags())
 	if err != nil {
 		return nil, err
 	}
@@ -82,6 +89,7 @@ func (gc *cluster) NewMachineWithOptions(userdata *conf.UserData, options platfo
 		intIP: intip,
 		extIP: extip,
 	}
+	gc.BindUserData(gm.ID(), ud)
 
 	gm.dir = filepath.Join(gc.RuntimeConf().OutputDir, gm.ID())
 	if err := os.Mkdir(gm.dir, 0777); err != nil {
@@ -89,8 +97,13 @@ func (gc *cluster) NewMachineWithOptions(userdata *conf.UserData, options platfo
 		return nil, err
 	}
 
+	if err := gm.startConsole(); err != nil {
+		gm.Destroy()
+		return nil, err
+	}
+
 	confPath := filepath.Join(gm.dir, "user-data")
-	if err := conf.WriteFile(confPath); err != nil {
+	if err := conf.WriteRedactedFile(confPath); err != nil {
 		gm.Destroy()
 		return nil, err
 	}
@@ -26,15 +26,21 @@ import (
 
 type flight struct {
 	*platform.BaseFlight
-	api *gcloud.API
+	api         *gcloud.API
+	machineType string
 }
 
 const (
 	Platform platform.Name = "gcloud"
+
+	// The largest user data an instance can be given
+	MaxUserDataSize = 256 * 1024
 )
 
 var (
 	plog = capnslog.NewPackageLogger("github.com/coreos/coreos-assembler/mantle", "platform/machine/gcloud")
+
+	userDataLimit = platform.UserDataLimit{MaxSize: MaxUserDataSize}
 )
 
 func NewFlight(opts *gcloud.Options) (platform.Flight, error) {
@@ -49,17 +55,27 @@ func NewFlight(opts *gcloud.Options) (platform.Flight, error) {
 	}
 
 	gf := &flight{
-		BaseFlight: bf,
-		api:        api,
+		BaseFlight:  bf,
+		api:         api,
+		machineType: opts.MachineType,
 	}
 
 	return gf, nil
 }
 
-func (af *flight) ConfigTooLarge(ud conf.UserData) bool {
+func (gf *flight) Capabilities() platform.Capabilities {
+	caps := platform.Capabilities{
+		Features:      []platform.Capability{platform.CapAdditionalDisks, platform.CapInstanceType},
+		Architectures: []string{"x86_64", "aarch64"},
+	}
+	if gf.HasNestedVirtProfile() {
+		caps.Features = append(caps.Features, platform.CapNestedVirt)
+	}
+	return caps
+}
 
-	// not implemented
-	return false
+func (af *flight) ConfigTooLarge(ud conf.UserData) bool {
+	return af.UserDataTooLarge(ud, userDataLimit)
 }
 
 func (gf *flight) NewCluster(rconf *platform.RuntimeConfig) (platform.Cluster, error) {
@@ -15,8 +15,10 @@
 package gcloud
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"golang.org/x/crypto/ssh"
@@ -24,6 +26,12 @@ import (
 	"github.com/coreos/coreos-assembler/mantle/platform"
 )
 
+// consolePollInterval is how often the serial console is read while a
+// machine runs. GCE only keeps the last 1 MiB of it, so reading it once
+// when the machine is destroyed would lose the start of long runs, and
+// all of it if kola is killed.
+const consolePollInterval = 10 * time.Second
+
 type machine struct {
 	gc      *cluster
 	name    string
@@ -32,6 +40,12 @@ type machine struct {
 	dir     string
 	journal *platform.Journal
 	console string
+
+	consoleFile *os.File
+	consoleBuf  strings.Builder
+	consoleNext int64
+	consoleStop chan struct{}
+	consoleDone chan struct{}
 }
 
 func (gm *machine) ID() string {
@@ -62,6 +76,14 @@ func (gm *machine) SSH(cmd string) ([]byte, []byte, error) {
 	return gm.gc.SSH(gm, cmd)
 }
 
+func (gm *machine) Upload(localPath, remotePath string) error {
+	return platform.UploadToMachine(gm, localPath, remotePath)
+}
+
+func (gm *machine) Download(remotePath, localPath string) error {
+	return platform.DownloadFromMachine(gm, remotePath, localPath)
+}
+
 func (gm *machine) IgnitionError() error {
 	return nil
 }
@@ -98,24 +120,71 @@ func (gm *machine) ConsoleOutput() string {
 	return gm.console
 }
 
-func (gm *machine) saveConsole() error {
-	var err error
-	gm.console, err = gm.gc.flight.api.GetConsoleOutput(gm.name)
+// startConsole creates console.txt and copies the serial console to it
+// until saveConsole is called.
+func (gm *machine) startConsole() error {
+	f, err := os.OpenFile(filepath.Join(gm.dir, "console.txt"), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
 	if err != nil {
 		return err
 	}
+	gm.consoleFile = f
+	gm.consoleStop = make(chan struct{})
+	gm.consoleDone = make(chan struct{})
+	go func() {
+		defer close(gm.consoleDone)
+		ticker := time.NewTicker(consolePollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-gm.consoleStop:
+				return
+			case <-ticker.C:
+				// The instance may not be running yet, or be
+				// rebooting; the next poll catches up
+				if err := gm.pollConsole(); err != nil {
+					plog.Debugf("Reading console for instance %v: %v", gm.ID(), err)
+				}
+			}
+		}
+	}()
+	return nil
+}
 
-	path := filepath.Join(gm.dir, "console.txt")
-	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0644)
+// pollConsole appends the console output since the last poll to
+// console.txt.
+func (gm *machine) pollConsole() error {
+	out, err := gm.gc.flight.api.GetConsoleOutputFrom(gm.name, gm.consoleNext)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-	if _, err := f.WriteString(gm.console); err != nil {
+	var data string
+	if out.Start > gm.consoleNext {
+		data = fmt.Sprintf("\n[%d bytes of console output were discarded by GCE]\n", out.Start-gm.consoleNext)
+	}
+	data += out.Contents
+	gm.consoleNext = out.Next
+	gm.consoleBuf.WriteString(data)
+	_, err = gm.consoleFile.WriteString(data)
+	return err
+}
+
+func (gm *machine) saveConsole() error {
+	if gm.consoleFile == nil {
+		// There's no output dir to write console.txt to
+		var err error
+		gm.console, err = gm.gc.flight.api.GetConsoleOutput(gm.name)
 		return err
 	}
 
-	return nil
+	close(gm.consoleStop)
+	<-gm.consoleDone
+	err := gm.pollConsole()
+	gm.console = gm.consoleBuf.String()
+	if cerr := gm.consoleFile.Close(); err == nil {
+		err = cerr
+	}
+	gm.consoleFile = nil
+	return err
 }
 
 func (gm *machine) JournalOutput() string {
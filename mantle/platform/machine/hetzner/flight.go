@@ -0,0 +1,111 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hetzner
+
+import (
+	"github.com/coreos/pkg/capnslog"
+
+	"github.com/coreos/coreos-assembler/mantle/platform"
+	"github.com/coreos/coreos-assembler/mantle/platform/api/hetzner"
+	"github.com/coreos/coreos-assembler/mantle/platform/conf"
+)
+
+const (
+	Platform platform.Name = "hetzner"
+
+	// The largest user data a server can be given
+	MaxUserDataSize = 32 * 1024
+)
+
+var (
+	plog = capnslog.NewPackageLogger("github.com/coreos/coreos-assembler/mantle", "platform/machine/hetzner")
+
+	userDataLimit = platform.UserDataLimit{MaxSize: MaxUserDataSize}
+)
+
+type flight struct {
+	*platform.BaseFlight
+	api      *hetzner.API
+	sshKeyID int64
+}
+
+func NewFlight(opts *hetzner.Options) (platform.Flight, error) {
+	api, err := hetzner.New(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	bf, err := platform.NewBaseFlight(opts.Options, Platform)
+	if err != nil {
+		return nil, err
+	}
+
+	hf := &flight{
+		BaseFlight: bf,
+		api:        api,
+	}
+
+	keys, err := hf.Keys()
+	if err != nil {
+		hf.Destroy()
+		return nil, err
+	}
+	hf.sshKeyID, err = hf.api.AddKey(hf.Name(), keys[0].String())
+	if err != nil {
+		hf.Destroy()
+		return nil, err
+	}
+
+	return hf, nil
+}
+
+func (hf *flight) NewCluster(rconf *platform.RuntimeConfig) (platform.Cluster, error) {
+	bc, err := platform.NewBaseCluster(hf.BaseFlight, rconf)
+	if err != nil {
+		return nil, err
+	}
+
+	hc := &cluster{
+		BaseCluster: bc,
+		flight:      hf,
+	}
+	if !rconf.NoSSHKeyInMetadata {
+		hc.sshKeyID = hf.sshKeyID
+	}
+
+	hf.AddCluster(hc)
+
+	return hc, nil
+}
+
+func (hf *flight) Capabilities() platform.Capabilities {
+	return platform.Capabilities{
+		Architectures: []string{"x86_64", "aarch64"},
+	}
+}
+
+func (hf *flight) ConfigTooLarge(ud conf.UserData) bool {
+	return hf.UserDataTooLarge(ud, userDataLimit)
+}
+
+func (hf *flight) Destroy() {
+	if hf.sshKeyID != 0 {
+		if err := hf.api.DeleteKey(hf.sshKeyID); err != nil {
+			plog.Errorf("Error deleting key %v: %v", hf.sshKeyID, err)
+		}
+	}
+
+	hf.BaseFlight.Destroy()
+}
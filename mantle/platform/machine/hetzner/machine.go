@@ -0,0 +1,112 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hetzner
+
+import (
+	"strconv"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/coreos/coreos-assembler/mantle/platform"
+	"github.com/coreos/coreos-assembler/mantle/platform/api/hetzner"
+)
+
+type machine struct {
+	cluster *cluster
+	server  *hetzner.Server
+	journal *platform.Journal
+}
+
+func (hm *machine) ID() string {
+	return strconv.FormatInt(hm.server.ID, 10)
+}
+
+func (hm *machine) IP() string {
+	return hm.server.PublicIP()
+}
+
+func (hm *machine) PrivateIP() string {
+	return hm.server.PrivateIP()
+}
+
+func (hm *machine) RuntimeConf() platform.RuntimeConfig {
+	return hm.cluster.RuntimeConf()
+}
+
+func (hm *machine) SSHClient() (*ssh.Client, error) {
+	return hm.cluster.SSHClient(hm.IP())
+}
+
+func (hm *machine) PasswordSSHClient(user string, password string) (*ssh.Client, error) {
+	return hm.cluster.PasswordSSHClient(hm.IP(), user, password)
+}
+
+func (hm *machine) SSH(cmd string) ([]byte, []byte, error) {
+	return hm.cluster.SSH(hm, cmd)
+}
+
+func (hm *machine) Upload(localPath, remotePath string) error {
+	return platform.UploadToMachine(hm, localPath, remotePath)
+}
+
+func (hm *machine) Download(remotePath, localPath string) error {
+	return platform.DownloadFromMachine(hm, remotePath, localPath)
+}
+
+func (hm *machine) IgnitionError() error {
+	return nil
+}
+
+func (hm *machine) Start() error {
+	return platform.StartMachine(hm, hm.journal)
+}
+
+func (hm *machine) Reboot() error {
+	return platform.RebootMachine(hm, hm.journal)
+}
+
+func (hm *machine) WaitForReboot(timeout time.Duration, oldBootId string) error {
+	return platform.WaitForMachineReboot(hm, hm.journal, timeout, oldBootId)
+}
+
+func (hm *machine) Destroy() {
+	if err := hm.cluster.flight.api.DeleteServer(hm.server.ID); err != nil {
+		plog.Errorf("Error deleting server %v: %v", hm.server.ID, err)
+	}
+
+	if hm.journal != nil {
+		hm.journal.Destroy()
+	}
+
+	hm.cluster.DelMach(hm)
+}
+
+func (hm *machine) ConsoleOutput() string {
+	// Hetzner Cloud provides only a VNC console, not its output
+	return ""
+}
+
+func (hm *machine) JournalOutput() string {
+	if hm.journal == nil {
+		return ""
+	}
+
+	data, err := hm.journal.Read()
+	if err != nil {
+		plog.Errorf("Reading journal for server %v: %v", hm.server.ID, err)
+	}
+	return string(data)
+}
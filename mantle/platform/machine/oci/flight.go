@@ -0,0 +1,97 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oci
+
+import (
+	"fmt"
+
+	"github.com/coreos/pkg/capnslog"
+
+	"github.com/coreos/coreos-assembler/mantle/platform"
+	"github.com/coreos/coreos-assembler/mantle/platform/api/oci"
+	"github.com/coreos/coreos-assembler/mantle/platform/conf"
+)
+
+const (
+	Platform platform.Name = "oci"
+
+	// The largest user data an instance can be given
+	MaxUserDataSize = 32000
+)
+
+var (
+	plog = capnslog.NewPackageLogger("github.com/coreos/coreos-assembler/mantle", "platform/machine/oci")
+
+	userDataLimit = platform.UserDataLimit{MaxSize: MaxUserDataSize, Base64: true}
+)
+
+type flight struct {
+	*platform.BaseFlight
+	api *oci.API
+}
+
+// NewFlight creates an instance of a Flight suitable for spawning
+// instances on Oracle Cloud Infrastructure.
+func NewFlight(opts *oci.Options) (platform.Flight, error) {
+	if opts.Image == "" {
+		return nil, fmt.Errorf("an image is required")
+	}
+	api, err := oci.New(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	bf, err := platform.NewBaseFlight(opts.Options, Platform)
+	if err != nil {
+		return nil, err
+	}
+
+	return &flight{
+		BaseFlight: bf,
+		api:        api,
+	}, nil
+}
+
+// NewCluster creates an instance of a Cluster suitable for spawning
+// instances on Oracle Cloud Infrastructure.
+func (of *flight) NewCluster(rconf *platform.RuntimeConfig) (platform.Cluster, error) {
+	bc, err := platform.NewBaseCluster(of.BaseFlight, rconf)
+	if err != nil {
+		return nil, err
+	}
+
+	oc := &cluster{
+		BaseCluster: bc,
+		flight:      of,
+	}
+
+	of.AddCluster(oc)
+
+	return oc, nil
+}
+
+func (of *flight) Capabilities() platform.Capabilities {
+	return platform.Capabilities{
+		Architectures: []string{"x86_64", "aarch64"},
+	}
+}
+
+func (of *flight) ConfigTooLarge(ud conf.UserData) bool {
+	return of.UserDataTooLarge(ud, userDataLimit)
+}
+
+func (of *flight) Destroy() {
+	of.BaseFlight.Destroy()
+}
@@ -0,0 +1,126 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oci
+
+import (
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/coreos/coreos-assembler/mantle/platform"
+	"github.com/coreos/coreos-assembler/mantle/platform/api/oci"
+)
+
+type machine struct {
+	cluster  *cluster
+	instance *oci.Instance
+	dir      string
+	journal  *platform.Journal
+	console  string
+}
+
+func (om *machine) ID() string {
+	return om.instance.ID
+}
+
+func (om *machine) IP() string {
+	return om.instance.PublicIP
+}
+
+func (om *machine) PrivateIP() string {
+	return om.instance.PrivateIP
+}
+
+func (om *machine) RuntimeConf() platform.RuntimeConfig {
+	return om.cluster.RuntimeConf()
+}
+
+func (om *machine) SSHClient() (*ssh.Client, error) {
+	return om.cluster.SSHClient(om.IP())
+}
+
+func (om *machine) PasswordSSHClient(user string, password string) (*ssh.Client, error) {
+	return om.cluster.PasswordSSHClient(om.IP(), user, password)
+}
+
+func (om *machine) SSH(cmd string) ([]byte, []byte, error) {
+	return om.cluster.SSH(om, cmd)
+}
+
+func (om *machine) Upload(localPath, remotePath string) error {
+	return platform.UploadToMachine(om, localPath, remotePath)
+}
+
+func (om *machine) Download(remotePath, localPath string) error {
+	return platform.DownloadFromMachine(om, remotePath, localPath)
+}
+
+func (om *machine) IgnitionError() error {
+	return nil
+}
+
+func (om *machine) Start() error {
+	return platform.StartMachine(om, om.journal)
+}
+
+func (om *machine) Reboot() error {
+	return platform.RebootMachine(om, om.journal)
+}
+
+func (om *machine) WaitForReboot(timeout time.Duration, oldBootId string) error {
+	return platform.WaitForMachineReboot(om, om.journal, timeout, oldBootId)
+}
+
+func (om *machine) Destroy() {
+	if err := om.saveConsole(); err != nil {
+		plog.Errorf("Error saving console for instance %v: %v", om.ID(), err)
+	}
+
+	if err := om.cluster.flight.api.TerminateInstance(om.ID()); err != nil {
+		plog.Errorf("Error terminating instance %v: %v", om.ID(), err)
+	}
+
+	if om.journal != nil {
+		om.journal.Destroy()
+	}
+
+	om.cluster.DelMach(om)
+}
+
+func (om *machine) ConsoleOutput() string {
+	return om.console
+}
+
+func (om *machine) saveConsole() error {
+	var err error
+	om.console, err = om.cluster.flight.api.GetConsoleOutput(om.ID())
+	if err != nil {
+		return err
+	}
+
+	return platform.SaveConsole(om.dir, om.console)
+}
+
+func (om *machine) JournalOutput() string {
+	if om.journal == nil {
+		return ""
+	}
+
+	data, err := om.journal.Read()
+	if err != nil {
+		plog.Errorf("Reading journal for instance %v: %v", om.ID(), err)
+	}
+	return string(data)
+}
@@ -15,9 +15,7 @@
 package openstack
 
 import (
-	"crypto/rand"
 	"errors"
-	"fmt"
 	"os"
 	"path/filepath"
 
@@ -55,18 +53,23 @@ func (oc *cluster) NewMachineWithOptions(userdata *conf.UserData, options platfo
 	}
 
 	conf, err := oc.RenderUserData(userdata, map[string]string{
-		"$public_ipv4":  "${COREOS_OPENSTACK_IPV4_PUBLIC}",
-		"$private_ipv4": "${COREOS_OPENSTACK_IPV4_LOCAL}",
+		platform.UserDataPublicIPv4:  "${COREOS_OPENSTACK_IPV4_PUBLIC}",
+		platform.UserDataPrivateIPv4: "${COREOS_OPENSTACK_IPV4_LOCAL}",
 	})
 	if err != nil {
 		return nil, err
 	}
 
+	ud, err := oc.FitUserData(conf, userDataLimit)
+	if err != nil {
+		return nil, err
+	}
+
 	var keyname string
 	if !oc.RuntimeConf().NoSSHKeyInMetadata {
 		keyname = oc.flight.Name()
 	}
-	instance, err := oc.flight.api.CreateServer(oc.vmname(), keyname, conf.String())
+	instance, err := oc.flight.api.CreateServer(oc.MachineName(), keyname, ud, options.MinMemory, oc.ResourceTags())
 	if err != nil {
 		return nil, err
 	}
@@ -75,6 +78,7 @@ func (oc *cluster) NewMachineWithOptions(userdata *conf.UserData, options platfo
 		cluster: oc,
 		mach:    instance,
 	}
+	oc.BindUserData(mach.ID(), ud)
 
 	mach.dir = filepath.Join(oc.RuntimeConf().OutputDir, mach.ID())
 	if err := os.Mkdir(mach.dir, 0777); err != nil {
@@ -83,7 +87,7 @@ func (oc *cluster) NewMachineWithOptions(userdata *conf.UserData, options platfo
 	}
 
 	confPath := filepath.Join(mach.dir, "user-data")
-	if err := conf.WriteFile(confPath); err != nil {
+	if err := conf.WriteRedactedFile(confPath); err != nil {
 		mach.Destroy()
 		return nil, err
 	}
@@ -107,14 +111,6 @@ func (oc *cluster) NewMachineWithOptions(userdata *conf.UserData, options platfo
 	return mach, nil
 }
 
-func (oc *cluster) vmname() string {
-	b := make([]byte, 5)
-	if _, err := rand.Read(b); err != nil {
-		plog.Errorf("failed to generate a random vmname: %v", err)
-	}
-	return fmt.Sprintf("%s-%x", oc.Name()[0:13], b)
-}
-
 func (oc *cluster) Destroy() {
 	oc.BaseCluster.Destroy()
 	oc.flight.DelCluster(oc)
@@ -24,10 +24,15 @@ import (
 
 const (
 	Platform platform.Name = "openstack"
+
+	// The largest user data a server can be given
+	MaxUserDataSize = 64 * 1024
 )
 
 var (
 	plog = capnslog.NewPackageLogger("github.com/coreos/coreos-assembler/mantle", "platform/machine/openstack")
+
+	userDataLimit = platform.UserDataLimit{MaxSize: MaxUserDataSize, Base64: true}
 )
 
 type flight struct {
@@ -88,9 +93,7 @@ func (of *flight) NewCluster(rconf *platform.RuntimeConfig) (platform.Cluster, e
 }
 
 func (af *flight) ConfigTooLarge(ud conf.UserData) bool {
-
-	// not implemented
-	return false
+	return af.UserDataTooLarge(ud, userDataLimit)
 }
 
 func (of *flight) Destroy() {
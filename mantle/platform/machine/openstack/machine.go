@@ -16,8 +16,6 @@ package openstack
 
 import (
 	"fmt"
-	"os"
-	"path/filepath"
 	"time"
 
 	"golang.org/x/crypto/ssh"
@@ -83,6 +81,14 @@ func (om *machine) SSH(cmd string) ([]byte, []byte, error) {
 	return om.cluster.SSH(om, cmd)
 }
 
+func (om *machine) Upload(localPath, remotePath string) error {
+	return platform.UploadToMachine(om, localPath, remotePath)
+}
+
+func (om *machine) Download(remotePath, localPath string) error {
+	return platform.DownloadFromMachine(om, remotePath, localPath)
+}
+
 func (om *machine) IgnitionError() error {
 	return nil
 }
@@ -126,17 +132,7 @@ func (om *machine) saveConsole() error {
 		return fmt.Errorf("Error retrieving console log for %v: %v", om.ID(), err)
 	}
 
-	path := filepath.Join(om.dir, "console.txt")
-	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0644)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-	if _, err := f.WriteString(om.console); err != nil {
-		return err
-	}
-
-	return nil
+	return platform.SaveConsole(om.dir, om.console)
 }
 
 func (om *machine) JournalOutput() string {
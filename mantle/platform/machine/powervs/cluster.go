@@ -0,0 +1,134 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package powervs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/coreos/coreos-assembler/mantle/platform"
+	"github.com/coreos/coreos-assembler/mantle/platform/api/ibmcloud"
+	"github.com/coreos/coreos-assembler/mantle/platform/conf"
+)
+
+type cluster struct {
+	*platform.BaseCluster
+	flight *flight
+}
+
+func (pc *cluster) NewMachine(userdata *conf.UserData) (platform.Machine, error) {
+	return pc.NewMachineWithOptions(userdata, platform.MachineOptions{})
+}
+
+func (pc *cluster) NewMachineWithOptions(userdata *conf.UserData, options platform.MachineOptions) (platform.Machine, error) {
+	if len(options.AdditionalDisks) > 0 {
+		return nil, errors.New("platform powervs does not yet support additional disks")
+	}
+	if options.MultiPathDisk {
+		return nil, errors.New("platform powervs does not support multipathed disks")
+	}
+	if options.AdditionalNics > 0 {
+		return nil, errors.New("platform powervs does not support additional nics")
+	}
+	if options.AppendKernelArgs != "" {
+		return nil, errors.New("platform powervs does not support appending kernel arguments")
+	}
+	if options.AppendFirstbootKernelArgs != "" {
+		return nil, errors.New("platform powervs does not support appending firstboot kernel arguments")
+	}
+
+	conf, err := pc.RenderUserData(userdata, map[string]string{})
+	if err != nil {
+		return nil, err
+	}
+
+	ud, err := pc.FitUserData(conf, userDataLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := pc.flight.opts
+	instanceOpts := ibmcloud.PowerVSInstanceOptions{
+		Name:        pc.MachineName(),
+		ImageID:     pc.flight.imageID,
+		NetworkID:   pc.flight.networkID,
+		UserData:    ud,
+		SysType:     opts.PowerVSSysType,
+		ProcType:    opts.PowerVSProcType,
+		Processors:  opts.PowerVSProcessors,
+		MemoryGiB:   opts.PowerVSMemory,
+		StorageType: opts.PowerVSStorageType,
+	}
+	if options.InstanceType != "" {
+		instanceOpts.SysType = options.InstanceType
+	}
+	if options.MinMemory != 0 && float64(options.MinMemory)/1024 > instanceOpts.MemoryGiB {
+		instanceOpts.MemoryGiB = float64((options.MinMemory + 1023) / 1024)
+	}
+	if !pc.RuntimeConf().NoSSHKeyInMetadata {
+		instanceOpts.KeyName = pc.flight.Name()
+	}
+	instance, err := pc.flight.api.CreatePowerVSInstance(instanceOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	mach := &machine{
+		cluster:  pc,
+		instance: instance,
+	}
+	pc.BindUserData(mach.ID(), ud)
+	if mach.IP() == "" {
+		mach.Destroy()
+		return nil, fmt.Errorf("instance %s has no address on network %s", instance.ID, pc.flight.networkID)
+	}
+
+	mach.dir = filepath.Join(pc.RuntimeConf().OutputDir, mach.ID())
+	if err := os.Mkdir(mach.dir, 0777); err != nil {
+		mach.Destroy()
+		return nil, err
+	}
+
+	confPath := filepath.Join(mach.dir, "user-data")
+	if err := conf.WriteRedactedFile(confPath); err != nil {
+		mach.Destroy()
+		return nil, err
+	}
+
+	if mach.journal, err = platform.NewJournal(mach.dir); err != nil {
+		mach.Destroy()
+		return nil, err
+	}
+
+	// Run StartMachine, which blocks on the machine being booted up enough
+	// for SSH access, but only if the caller didn't tell us not to.
+	if !options.SkipStartMachine {
+		if err := platform.StartMachine(mach, mach.journal); err != nil {
+			mach.Destroy()
+			return nil, err
+		}
+	}
+
+	pc.AddMach(mach)
+
+	return mach, nil
+}
+
+func (pc *cluster) Destroy() {
+	pc.BaseCluster.Destroy()
+	pc.flight.DelCluster(pc)
+}
@@ -0,0 +1,147 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package powervs
+
+import (
+	"fmt"
+
+	"github.com/coreos/pkg/capnslog"
+
+	"github.com/coreos/coreos-assembler/mantle/platform"
+	"github.com/coreos/coreos-assembler/mantle/platform/api/ibmcloud"
+	"github.com/coreos/coreos-assembler/mantle/platform/conf"
+)
+
+const (
+	Platform platform.Name = "powervs"
+
+	// The largest user data an instance can be given
+	MaxUserDataSize = 63 * 1024
+)
+
+var (
+	plog = capnslog.NewPackageLogger("github.com/coreos/coreos-assembler/mantle", "platform/machine/powervs")
+
+	userDataLimit = platform.UserDataLimit{MaxSize: MaxUserDataSize, Base64: true}
+)
+
+type flight struct {
+	*platform.BaseFlight
+	api       *ibmcloud.API
+	opts      *ibmcloud.Options
+	imageID   string
+	networkID string
+	keyAdded  bool
+}
+
+// NewFlight creates an instance of a Flight suitable for spawning
+// instances on IBM Power Virtual Server.
+func NewFlight(opts *ibmcloud.Options) (platform.Flight, error) {
+	if opts.ApiKey == "" {
+		key, err := ibmcloud.LoadAPIKey(opts.CredentialsFile)
+		if err != nil {
+			return nil, err
+		}
+		opts.ApiKey = key
+	}
+	if opts.PowerVSServiceInstance == "" {
+		return nil, fmt.Errorf("a Power Virtual Server workspace is required")
+	}
+
+	api, err := ibmcloud.New(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := api.NewPowerVSClient(opts.PowerVSServiceInstance); err != nil {
+		return nil, err
+	}
+
+	image, err := api.FindPowerVSImage(opts.PowerVSImage)
+	if err != nil {
+		return nil, fmt.Errorf("finding image: %v", err)
+	}
+	if image == nil {
+		return nil, fmt.Errorf("image %q not found", opts.PowerVSImage)
+	}
+	networkID, err := api.GetPowerVSNetwork(opts.PowerVSNetwork)
+	if err != nil {
+		return nil, err
+	}
+
+	bf, err := platform.NewBaseFlight(opts.Options, Platform)
+	if err != nil {
+		return nil, err
+	}
+
+	pf := &flight{
+		BaseFlight: bf,
+		api:        api,
+		opts:       opts,
+		imageID:    image.ID,
+		networkID:  networkID,
+	}
+
+	keys, err := pf.Keys()
+	if err != nil {
+		pf.Destroy()
+		return nil, err
+	}
+	if err := api.AddPowerVSKey(pf.Name(), keys[0].String()); err != nil {
+		pf.Destroy()
+		return nil, err
+	}
+	pf.keyAdded = true
+
+	return pf, nil
+}
+
+// NewCluster creates an instance of a Cluster suitable for spawning
+// instances on IBM Power Virtual Server.
+func (pf *flight) NewCluster(rconf *platform.RuntimeConfig) (platform.Cluster, error) {
+	bc, err := platform.NewBaseCluster(pf.BaseFlight, rconf)
+	if err != nil {
+		return nil, err
+	}
+
+	pc := &cluster{
+		BaseCluster: bc,
+		flight:      pf,
+	}
+
+	pf.AddCluster(pc)
+
+	return pc, nil
+}
+
+func (pf *flight) Capabilities() platform.Capabilities {
+	return platform.Capabilities{
+		Features:      []platform.Capability{platform.CapInstanceType},
+		Architectures: []string{"ppc64le"},
+	}
+}
+
+func (pf *flight) ConfigTooLarge(ud conf.UserData) bool {
+	return pf.UserDataTooLarge(ud, userDataLimit)
+}
+
+func (pf *flight) Destroy() {
+	if pf.keyAdded {
+		if err := pf.api.DeletePowerVSKey(pf.Name()); err != nil {
+			plog.Errorf("Error deleting key %v: %v", pf.Name(), err)
+		}
+	}
+
+	pf.BaseFlight.Destroy()
+}
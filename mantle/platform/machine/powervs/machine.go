@@ -0,0 +1,128 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package powervs
+
+import (
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/coreos/coreos-assembler/mantle/platform"
+	"github.com/coreos/coreos-assembler/mantle/platform/api/ibmcloud"
+)
+
+type machine struct {
+	cluster  *cluster
+	instance *ibmcloud.PowerVSInstance
+	dir      string
+	journal  *platform.Journal
+}
+
+func (pm *machine) ID() string {
+	return pm.instance.ID
+}
+
+// IP returns the address the instance is reachable at on the flight's
+// network, which is public.
+func (pm *machine) IP() string {
+	for _, net := range pm.instance.Networks {
+		if net.NetworkID != pm.cluster.flight.networkID {
+			continue
+		}
+		if net.ExternalIP != "" {
+			return net.ExternalIP
+		}
+		return net.IPAddress
+	}
+	return ""
+}
+
+func (pm *machine) PrivateIP() string {
+	for _, net := range pm.instance.Networks {
+		if net.NetworkID == pm.cluster.flight.networkID {
+			return net.IPAddress
+		}
+	}
+	return ""
+}
+
+func (pm *machine) RuntimeConf() platform.RuntimeConfig {
+	return pm.cluster.RuntimeConf()
+}
+
+func (pm *machine) SSHClient() (*ssh.Client, error) {
+	return pm.cluster.SSHClient(pm.IP())
+}
+
+func (pm *machine) PasswordSSHClient(user string, password string) (*ssh.Client, error) {
+	return pm.cluster.PasswordSSHClient(pm.IP(), user, password)
+}
+
+func (pm *machine) SSH(cmd string) ([]byte, []byte, error) {
+	return pm.cluster.SSH(pm, cmd)
+}
+
+func (pm *machine) Upload(localPath, remotePath string) error {
+	return platform.UploadToMachine(pm, localPath, remotePath)
+}
+
+func (pm *machine) Download(remotePath, localPath string) error {
+	return platform.DownloadFromMachine(pm, remotePath, localPath)
+}
+
+func (pm *machine) IgnitionError() error {
+	return nil
+}
+
+func (pm *machine) Start() error {
+	return platform.StartMachine(pm, pm.journal)
+}
+
+func (pm *machine) Reboot() error {
+	return platform.RebootMachine(pm, pm.journal)
+}
+
+func (pm *machine) WaitForReboot(timeout time.Duration, oldBootId string) error {
+	return platform.WaitForMachineReboot(pm, pm.journal, timeout, oldBootId)
+}
+
+func (pm *machine) Destroy() {
+	if err := pm.cluster.flight.api.DeletePowerVSInstance(pm.ID()); err != nil {
+		plog.Errorf("Error deleting instance %v: %v", pm.ID(), err)
+	}
+
+	if pm.journal != nil {
+		pm.journal.Destroy()
+	}
+
+	pm.cluster.DelMach(pm)
+}
+
+func (pm *machine) ConsoleOutput() string {
+	// Power Virtual Server only provides an interactive console
+	return ""
+}
+
+func (pm *machine) JournalOutput() string {
+	if pm.journal == nil {
+		return ""
+	}
+
+	data, err := pm.journal.Read()
+	if err != nil {
+		plog.Errorf("Reading journal for instance %v: %v", pm.ID(), err)
+	}
+	return string(data)
+}
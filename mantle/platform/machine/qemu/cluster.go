@@ -15,13 +15,12 @@
 package qemu
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
-
-	"sync"
 	"time"
 
 	"github.com/pborman/uuid"
@@ -40,7 +39,6 @@ type Cluster struct {
 	*platform.BaseCluster
 	flight *flight
 
-	mu          sync.Mutex
 	tearingDown bool
 }
 
@@ -58,6 +56,21 @@ func (qc *Cluster) NewMachineWithOptions(userdata *conf.UserData, options platfo
 	})
 }
 
+// noCloudSeed returns the cloud-init NoCloud seed of a machine, with the
+// cluster's SSH keys unless they're to be left out of the user data.
+func (qc *Cluster) noCloudSeed(id, hostname string) (*conf.NoCloudSeed, error) {
+	seed := conf.NewNoCloudSeed(id)
+	seed.Hostname = hostname
+	if !qc.RuntimeConf().NoSSHKeyInUserData {
+		keys, err := qc.Keys()
+		if err != nil {
+			return nil, err
+		}
+		seed.CopyKeys(keys)
+	}
+	return seed, nil
+}
+
 func (qc *Cluster) NewMachineWithQemuOptions(userdata *conf.UserData, options platform.QemuMachineOptions) (platform.Machine, error) {
 	id := uuid.New()
 
@@ -66,16 +79,18 @@ func (qc *Cluster) NewMachineWithQemuOptions(userdata *conf.UserData, options pl
 		return nil, err
 	}
 
-	// hacky solution for cloud config ip substitution
-	// NOTE: escaping is not supported
-	qc.mu.Lock()
-
-	conf, err := qc.RenderUserData(userdata, map[string]string{})
+	// Machines reach the host at the gateway of user-mode networking
+	conf, err := qc.RenderUserData(userdata, map[string]string{
+		platform.UserDataHostIP: "10.0.2.2",
+	})
 	if err != nil {
-		qc.mu.Unlock()
 		return nil, err
 	}
-	qc.mu.Unlock()
+	if options.Proxy != nil {
+		if conf, err = mergeProxyConfig(conf, options.Proxy, qc.RuntimeConf().WarningsAction); err != nil {
+			return nil, errors.Wrapf(err, "configuring proxy")
+		}
+	}
 
 	journal, err := platform.NewJournal(dir)
 	if err != nil {
@@ -100,10 +115,27 @@ func (qc *Cluster) NewMachineWithQemuOptions(userdata *conf.UserData, options pl
 		}
 	}
 
-	var confPath string
+	if qc.flight.opts.Kdump {
+		if !conf.IsIgnition() {
+			return nil, fmt.Errorf("kdump requires an Ignition config")
+		}
+		if qm.kdumpDir, err = setupKdump(conf, builder, dir); err != nil {
+			return nil, errors.Wrapf(err, "setting up kdump")
+		}
+	}
+
+	if len(options.BindMounts) > 0 && !conf.IsIgnition() {
+		return nil, fmt.Errorf("bind mounts require an Ignition config")
+	}
+	for _, mount := range options.BindMounts {
+		builder.MountHost(mount.Source, mount.Dest, mount.ReadOnly)
+		conf.MountHost(mount.Dest, mount.ReadOnly)
+	}
+
+	// The machine gets the config itself; the copy kept for debugging
+	// has its secrets redacted.
 	if conf.IsIgnition() {
-		confPath = filepath.Join(dir, "ignition.json")
-		if err := conf.WriteFile(confPath); err != nil {
+		if err := conf.WriteRedactedFile(filepath.Join(dir, "ignition.json")); err != nil {
 			return nil, err
 		}
 	} else if conf.IsEmpty() {
@@ -111,7 +143,9 @@ func (qc *Cluster) NewMachineWithQemuOptions(userdata *conf.UserData, options pl
 		return nil, fmt.Errorf("qemu only supports Ignition or empty configs")
 	}
 
-	builder.ConfigFile = confPath
+	if conf.IsIgnition() {
+		builder.SetConfig(conf)
+	}
 	defer builder.Close()
 	builder.UUID = qm.id
 	if qc.flight.opts.Arch != "" {
@@ -122,9 +156,25 @@ func (qc *Cluster) NewMachineWithQemuOptions(userdata *conf.UserData, options pl
 	if qc.flight.opts.Firmware != "" {
 		builder.Firmware = qc.flight.opts.Firmware
 	}
+	builder.FirmwareCode = qc.flight.opts.FirmwareCode
+	builder.FirmwareVars = qc.flight.opts.FirmwareVars
+	builder.PetitbootImage = qc.flight.opts.PetitbootImage
 	builder.Swtpm = qc.flight.opts.Swtpm
 	builder.Hostname = fmt.Sprintf("qemu%d", qc.BaseCluster.AllocateMachineSerial())
 	builder.ConsoleFile = qm.consolePath
+	if qc.flight.opts.NoCloud {
+		seed, err := qc.noCloudSeed(qm.id, builder.Hostname)
+		if err != nil {
+			return nil, err
+		}
+		if err := builder.AddNoCloudSeed(seed); err != nil {
+			return nil, err
+		}
+	}
+	if qc.flight.opts.TelemetryInterval > 0 {
+		builder.TelemetryFile = filepath.Join(dir, "telemetry.json")
+		builder.TelemetryInterval = qc.flight.opts.TelemetryInterval
+	}
 
 	// This one doesn't support configuring the path because we can't
 	// reliably change the Ignition config here...
@@ -145,6 +195,13 @@ func (qc *Cluster) NewMachineWithQemuOptions(userdata *conf.UserData, options pl
 		builder.MemoryMiB = 4096 // SE needs at least 4GB
 	}
 
+	if profile := qc.PerformanceProfile(); profile != nil && builder.Processors >= 0 && builder.Processors < profile.MinCPUs() {
+		builder.Processors = profile.MinCPUs()
+	}
+	if builder.Processors >= 0 && builder.Processors < options.MinCPUs {
+		builder.Processors = options.MinCPUs
+	}
+
 	var primaryDisk platform.Disk
 	if options.PrimaryDisk != "" {
 		var diskp *platform.Disk
@@ -163,6 +220,9 @@ func (qc *Cluster) NewMachineWithQemuOptions(userdata *conf.UserData, options pl
 	if qc.flight.opts.Nvme || options.Nvme {
 		primaryDisk.Channel = "nvme"
 	}
+	if qc.flight.opts.Dasd {
+		primaryDisk.Channel = "dasd"
+	}
 	if qc.flight.opts.Native4k {
 		primaryDisk.SectorSize = 4096
 	} else if qc.flight.opts.Disk512e {
@@ -200,6 +260,9 @@ func (qc *Cluster) NewMachineWithQemuOptions(userdata *conf.UserData, options pl
 	if options.AdditionalNics > 0 {
 		builder.AddAdditionalNics(options.AdditionalNics)
 	}
+	for _, nic := range options.TapNics {
+		builder.AddTapNic(nic)
+	}
 	if options.AppendKernelArgs != "" {
 		builder.AppendKernelArgs = options.AppendKernelArgs
 	}
@@ -209,23 +272,40 @@ func (qc *Cluster) NewMachineWithQemuOptions(userdata *conf.UserData, options pl
 	if !qc.RuntimeConf().InternetAccess {
 		builder.RestrictNetworking = true
 	}
+	if qc.RuntimeConf().AuditEgress {
+		qm.captureDir = filepath.Join(dir, "capture")
+		if err := os.Mkdir(qm.captureDir, 0777); err != nil {
+			return nil, err
+		}
+		builder.CaptureDir = qm.captureDir
+	}
 	if options.Firmware != "" {
 		builder.Firmware = options.Firmware
 	}
+	builder.FirmwareVarsFile = options.FirmwareVarsFile
+
+	qm.consoleLog = platform.NewConsoleLog(qm.consolePath)
+	var bootTimer *platform.BootTimer
+	if qc.flight.opts.BootMetrics {
+		bootTimer = platform.NewBootTimer(qm.consolePath)
+		defer bootTimer.Stop()
+	}
 
 	inst, err := builder.Exec()
 	if err != nil {
+		qm.consoleLog.Stop()
 		return nil, err
 	}
 	qm.inst = inst
 
-	err = util.Retry(6, 5*time.Second, func() error {
+	err = util.Backoff{
+		Delay:      500 * time.Millisecond,
+		MaxDelay:   5 * time.Second,
+		MaxElapsed: 30 * time.Second,
+	}.Do(context.Background(), func() error {
 		var err error
 		qm.ip, err = inst.SSHAddress()
-		if err != nil {
-			return err
-		}
-		return nil
+		return err
 	})
 	if err != nil {
 		return nil, err
@@ -238,6 +318,15 @@ func (qc *Cluster) NewMachineWithQemuOptions(userdata *conf.UserData, options pl
 			qm.Destroy()
 			return nil, err
 		}
+		if bootTimer != nil {
+			bootTimer.SSHReady()
+			if err := bootTimer.WriteMetrics(qm, filepath.Join(dir, "boot-metrics.json")); err != nil {
+				plog.Warningf("Failed to gather boot metrics for %s: %v", qm.ID(), err)
+			}
+		}
+		if err := platform.WriteInventory(qm, filepath.Join(dir, platform.InventoryFile)); err != nil {
+			plog.Warningf("Failed to gather inventory for %s: %v", qm.ID(), err)
+		}
 	}
 
 	qc.AddMach(qm)
@@ -261,3 +350,20 @@ func (qc *Cluster) Destroy() {
 	qc.BaseCluster.Destroy()
 	qc.flight.DelCluster(qc)
 }
+
+// mergeProxyConfig returns the config with the proxy's merged in.
+func mergeProxyConfig(c *conf.Conf, proxy *platform.ProxyConfig, warnings conf.WarningsAction) (*conf.Conf, error) {
+	proxyData, err := proxy.Ignition()
+	if err != nil {
+		return nil, err
+	}
+	proxyConf, err := proxyData.Render(conf.FailWarnings)
+	if err != nil {
+		return nil, err
+	}
+	merged, err := conf.MergeAllConfigs([]*conf.Conf{c, proxyConf})
+	if err != nil {
+		return nil, err
+	}
+	return merged.Render(warnings)
+}
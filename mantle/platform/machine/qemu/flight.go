@@ -15,6 +15,11 @@
 package qemu
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
 	"github.com/coreos/pkg/capnslog"
 
 	"github.com/coreos/coreos-assembler/mantle/platform"
@@ -37,12 +42,22 @@ type Options struct {
 	Firmware string
 	Memory   string
 	Arch     string
+	// FirmwareCode and FirmwareVars replace the distro's UEFI firmware
+	// image and variable store template
+	FirmwareCode string
+	FirmwareVars string
+	// PetitbootImage is the petitboot zImage booted by the opal firmware
+	PetitbootImage string
+	// NoCloud attaches a cloud-init NoCloud seed with the SSH keys to
+	// machines, for distros which are provisioned by cloud-init
+	NoCloud bool
 
 	NbdDisk       bool
 	MultiPathDisk bool
 	Native4k      bool
 	Disk512e      bool
 	Nvme          bool
+	Dasd          bool
 
 	//Option to create a temporary software TPM - true by default
 	Swtpm bool
@@ -58,6 +73,18 @@ type Options struct {
 	// Option to create IBM cex based luks encryption
 	Cex bool
 
+	// Kdump enables kdump on each machine, saving the vmcore of a kernel
+	// crash to the kdump directory in the machine's output directory
+	Kdump bool
+
+	// TelemetryInterval, if nonzero, is how often to sample each
+	// machine's resource usage into telemetry.json
+	TelemetryInterval time.Duration
+
+	// BootMetrics writes the breakdown of each machine's boot to
+	// boot-metrics.json once it's reachable over SSH
+	BootMetrics bool
+
 	*platform.Options
 }
 
@@ -84,6 +111,36 @@ func NewFlight(opts *Options) (platform.Flight, error) {
 	return qf, nil
 }
 
+func (qf *flight) Capabilities() platform.Capabilities {
+	caps := platform.Capabilities{
+		Features: []platform.Capability{
+			platform.CapAdditionalDisks,
+			platform.CapMultiPathDisk,
+			platform.CapAdditionalNics,
+			platform.CapKernelArgs,
+			platform.CapUEFI,
+			platform.CapSecureBoot,
+		},
+	}
+	if hostNestedVirt() {
+		caps.Features = append(caps.Features, platform.CapNestedVirt)
+	}
+	return caps
+}
+
+// hostNestedVirt returns whether KVM on the host lets its guests run
+// guests of their own.
+func hostNestedVirt() bool {
+	for _, module := range []string{"kvm_intel", "kvm_amd", "kvm_hv", "kvm"} {
+		buf, err := os.ReadFile(filepath.Join("/sys/module", module, "parameters/nested"))
+		if err == nil {
+			value := strings.TrimSpace(string(buf))
+			return value == "Y" || value == "1"
+		}
+	}
+	return false
+}
+
 func (af *flight) ConfigTooLarge(ud conf.UserData) bool {
 
 	// not implemented
@@ -0,0 +1,75 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package qemu
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/coreos/coreos-assembler/mantle/platform"
+	"github.com/coreos/coreos-assembler/mantle/platform/conf"
+)
+
+const (
+	// kdumpTag is the virtiofs tag of the directory shared with the
+	// guest as its kdump target.
+	kdumpTag = "kola-kdump"
+
+	// kdumpCrashKernelMiB is reserved for the kdump kernel, which needs
+	// room for its initramfs and makedumpfile.
+	kdumpCrashKernelMiB = 512
+)
+
+// setupKdump configures the machine to dump the vmcore of a kernel crash
+// to a kdump directory in dir, shared with it over virtiofs, and returns
+// the path of that directory. The crash kernel memory is reserved on top
+// of the machine's memory.
+func setupKdump(c *conf.Conf, builder *platform.QemuBuilder, dir string) (string, error) {
+	dumpDir := filepath.Join(dir, "kdump")
+	if err := os.Mkdir(dumpDir, 0777); err != nil {
+		return "", err
+	}
+	builder.MountHost(dumpDir, kdumpTag, false)
+	builder.CrashKernelMiB = kdumpCrashKernelMiB
+
+	c.AddFile("/etc/kdump.conf", fmt.Sprintf(`virtiofs %s
+path /
+core_collector makedumpfile -l --message-level 1 -d 31
+`, kdumpTag), 0644)
+	// Presets are applied on first boot, which leaves kdump.service's unit
+	// file alone
+	c.AddFile("/etc/systemd/system-preset/40-kola-kdump.preset", "enable kdump.service\n", 0644)
+	return dumpDir, nil
+}
+
+// findVmcores returns the vmcores saved to dumpDir.
+func findVmcores(dumpDir string) ([]string, error) {
+	var vmcores []string
+	err := filepath.WalkDir(dumpDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// Alongside the vmcore is vmcore-dmesg.txt, which isn't a dump;
+		// an interrupted dump is left as vmcore-incomplete
+		if d.Type().IsRegular() && strings.HasPrefix(d.Name(), "vmcore") && !strings.HasPrefix(d.Name(), "vmcore-dmesg") {
+			vmcores = append(vmcores, path)
+		}
+		return nil
+	})
+	return vmcores, err
+}
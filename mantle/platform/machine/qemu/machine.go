@@ -17,7 +17,10 @@ package qemu
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"os"
+	"sync"
 	"time"
 
 	"golang.org/x/crypto/ssh"
@@ -31,8 +34,18 @@ type machine struct {
 	inst        *platform.QemuInstance
 	journal     *platform.Journal
 	consolePath string
+	consoleLog  *platform.ConsoleLog
+	destroyOnce sync.Once
 	console     string
 	ip          string
+	// kdumpDir is where the vmcore of a kernel crash is saved, if kdump
+	// is enabled
+	kdumpDir string
+	// captureDir is where the machine's traffic is captured, if its
+	// egress is audited
+	captureDir string
+	egress     []platform.EgressAttempt
+	egressErr  error
 }
 
 func (m *machine) ID() string {
@@ -63,6 +76,14 @@ func (m *machine) SSH(cmd string) ([]byte, []byte, error) {
 	return m.qc.SSH(m, cmd)
 }
 
+func (m *machine) Upload(localPath, remotePath string) error {
+	return platform.UploadToMachine(m, localPath, remotePath)
+}
+
+func (m *machine) Download(remotePath, localPath string) error {
+	return platform.DownloadFromMachine(m, remotePath, localPath)
+}
+
 func (m *machine) IgnitionError() error {
 	ctx := context.Background()
 	buf, err := m.inst.WaitIgnitionError(ctx)
@@ -87,11 +108,32 @@ func (m *machine) WaitForReboot(timeout time.Duration, oldBootId string) error {
 	return platform.WaitForMachineReboot(m, m.journal, timeout, oldBootId)
 }
 
+// Destroy destroys the instance. It's safe to call more than once and
+// concurrently, e.g. by a test and the control API.
 func (m *machine) Destroy() {
+	m.destroyOnce.Do(m.destroy)
+}
+
+func (m *machine) destroy() {
 	m.inst.Destroy()
+	m.consoleLog.Stop()
 
 	m.journal.Destroy()
 
+	if m.kdumpDir != "" {
+		vmcores, err := findVmcores(m.kdumpDir)
+		if err != nil {
+			plog.Errorf("Error looking for vmcores of instance %v: %v", m.ID(), err)
+		}
+		for _, vmcore := range vmcores {
+			plog.Warningf("Instance %v crashed; saved vmcore to %s", m.ID(), vmcore)
+		}
+	}
+
+	if m.captureDir != "" {
+		m.egress, m.egressErr = platform.ReadEgressCaptures(m.captureDir)
+	}
+
 	if buf, err := os.ReadFile(m.consolePath); err == nil {
 		m.console = string(buf)
 	} else {
@@ -101,10 +143,36 @@ func (m *machine) Destroy() {
 	m.qc.DelMach(m)
 }
 
+// ConsoleStream returns a reader following the instance's console.
+func (m *machine) ConsoleStream() io.ReadCloser {
+	return m.consoleLog.NewReader()
+}
+
+// CurrentConsole returns the instance's console output so far.
+func (m *machine) CurrentConsole() (string, error) {
+	buf, err := os.ReadFile(m.consolePath)
+	return string(buf), err
+}
+
+// HostForwardedPorts returns the ports forwarded from the host to the
+// machine.
+func (m *machine) HostForwardedPorts() []platform.HostForwardPort {
+	return m.inst.HostForwardedPorts()
+}
+
 func (m *machine) ConsoleOutput() string {
 	return m.console
 }
 
+// EgressAttempts returns the machine's attempts to reach beyond the host,
+// once it's destroyed.
+func (m *machine) EgressAttempts() ([]platform.EgressAttempt, error) {
+	if m.captureDir == "" {
+		return nil, fmt.Errorf("egress of instance %v isn't audited", m.ID())
+	}
+	return m.egress, m.egressErr
+}
+
 func (m *machine) JournalOutput() string {
 	if m.journal == nil {
 		return ""
@@ -117,6 +185,16 @@ func (m *machine) JournalOutput() string {
 	return string(data)
 }
 
+// HotplugDisk attaches a new empty disk to the running instance.
+func (m *machine) HotplugDisk(size string) (string, error) {
+	return m.inst.HotplugDisk(size)
+}
+
+// EmulatorInventory describes the hardware QEMU presents to the instance.
+func (m *machine) EmulatorInventory() (*platform.EmulatorInventory, error) {
+	return m.inst.EmulatorInventory()
+}
+
 func (m *machine) RemovePrimaryBlockDevice() error {
 	return m.inst.RemovePrimaryBlockDevice()
 }
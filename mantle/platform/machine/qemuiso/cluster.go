@@ -15,12 +15,11 @@
 package qemuiso
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
-
-	"sync"
 	"time"
 
 	"github.com/pborman/uuid"
@@ -38,8 +37,6 @@ import (
 type Cluster struct {
 	*platform.BaseCluster
 	flight *flight
-
-	mu sync.Mutex
 }
 
 func (qc *Cluster) NewMachine(userdata *conf.UserData) (platform.Machine, error) {
@@ -66,21 +63,18 @@ func (qc *Cluster) NewMachineWithQemuOptions(userdata *conf.UserData, options pl
 		return nil, err
 	}
 
-	// hacky solution for cloud config ip substitution
-	// NOTE: escaping is not supported
-	qc.mu.Lock()
-
-	conf, err := qc.RenderUserData(userdata, map[string]string{})
+	// Machines reach the host at the gateway of user-mode networking
+	conf, err := qc.RenderUserData(userdata, map[string]string{
+		platform.UserDataHostIP: "10.0.2.2",
+	})
 	if err != nil {
-		qc.mu.Unlock()
 		return nil, err
 	}
-	qc.mu.Unlock()
 
-	var confPath string
+	// The machine gets the config itself; the copy kept for debugging
+	// has its secrets redacted.
 	if conf.IsIgnition() {
-		confPath = filepath.Join(dir, "ignition.json")
-		if err := conf.WriteFile(confPath); err != nil {
+		if err := conf.WriteRedactedFile(filepath.Join(dir, "ignition.json")); err != nil {
 			return nil, err
 		}
 	} else if conf.IsEmpty() {
@@ -101,7 +95,9 @@ func (qc *Cluster) NewMachineWithQemuOptions(userdata *conf.UserData, options pl
 	}
 
 	builder := platform.NewQemuBuilder()
-	builder.ConfigFile = confPath
+	if conf.IsIgnition() {
+		builder.SetConfig(conf)
+	}
 	defer builder.Close()
 	builder.UUID = qm.id
 	if qc.flight.opts.Firmware != "" {
@@ -145,19 +141,22 @@ func (qc *Cluster) NewMachineWithQemuOptions(userdata *conf.UserData, options pl
 		builder.AppendFirstbootKernelArgs = options.AppendFirstbootKernelArgs
 	}
 
+	qm.consoleLog = platform.NewConsoleLog(qm.consolePath)
 	inst, err := builder.Exec()
 	if err != nil {
+		qm.consoleLog.Stop()
 		return nil, err
 	}
 	qm.inst = inst
 
-	err = util.Retry(6, 5*time.Second, func() error {
+	err = util.Backoff{
+		Delay:      500 * time.Millisecond,
+		MaxDelay:   5 * time.Second,
+		MaxElapsed: 30 * time.Second,
+	}.Do(context.Background(), func() error {
 		var err error
 		qm.ip, err = inst.SSHAddress()
-		if err != nil {
-			return err
-		}
-		return nil
+		return err
 	})
 	if err != nil {
 		return nil, err
@@ -59,6 +59,16 @@ func NewFlight(opts *Options) (platform.Flight, error) {
 	return qf, nil
 }
 
+func (qf *flight) Capabilities() platform.Capabilities {
+	return platform.Capabilities{
+		Features: []platform.Capability{
+			platform.CapAdditionalDisks,
+			platform.CapAdditionalNics,
+			platform.CapKernelArgs,
+		},
+	}
+}
+
 func (af *flight) ConfigTooLarge(ud conf.UserData) bool {
 
 	// not implemented
@@ -17,7 +17,9 @@ package qemuiso
 import (
 	"context"
 	"errors"
+	"io"
 	"os"
+	"sync"
 	"time"
 
 	"golang.org/x/crypto/ssh"
@@ -31,6 +33,8 @@ type machine struct {
 	inst        *platform.QemuInstance
 	journal     *platform.Journal
 	consolePath string
+	consoleLog  *platform.ConsoleLog
+	destroyOnce sync.Once
 	console     string
 	ip          string
 }
@@ -63,6 +67,14 @@ func (m *machine) SSH(cmd string) ([]byte, []byte, error) {
 	return m.qc.SSH(m, cmd)
 }
 
+func (m *machine) Upload(localPath, remotePath string) error {
+	return platform.UploadToMachine(m, localPath, remotePath)
+}
+
+func (m *machine) Download(remotePath, localPath string) error {
+	return platform.DownloadFromMachine(m, remotePath, localPath)
+}
+
 func (m *machine) IgnitionError() error {
 	ctx := context.Background()
 	buf, err := m.inst.WaitIgnitionError(ctx)
@@ -87,8 +99,15 @@ func (m *machine) WaitForReboot(timeout time.Duration, oldBootId string) error {
 	return platform.WaitForMachineReboot(m, m.journal, timeout, oldBootId)
 }
 
+// Destroy destroys the instance. It's safe to call more than once and
+// concurrently, e.g. by a test and the control API.
 func (m *machine) Destroy() {
+	m.destroyOnce.Do(m.destroy)
+}
+
+func (m *machine) destroy() {
 	m.inst.Destroy()
+	m.consoleLog.Stop()
 
 	m.journal.Destroy()
 
@@ -101,6 +120,17 @@ func (m *machine) Destroy() {
 	m.qc.DelMach(m)
 }
 
+// ConsoleStream returns a reader following the instance's console.
+func (m *machine) ConsoleStream() io.ReadCloser {
+	return m.consoleLog.NewReader()
+}
+
+// CurrentConsole returns the instance's console output so far.
+func (m *machine) CurrentConsole() (string, error) {
+	buf, err := os.ReadFile(m.consolePath)
+	return string(buf), err
+}
+
 func (m *machine) ConsoleOutput() string {
 	return m.console
 }
@@ -116,3 +146,8 @@ func (m *machine) JournalOutput() string {
 	}
 	return string(data)
 }
+
+// EmulatorInventory describes the hardware QEMU presents to the instance.
+func (m *machine) EmulatorInventory() (*platform.EmulatorInventory, error) {
+	return m.inst.EmulatorInventory()
+}
@@ -0,0 +1,81 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reuse
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/coreos/coreos-assembler/mantle/platform"
+	"github.com/coreos/coreos-assembler/mantle/platform/conf"
+)
+
+type cluster struct {
+	*platform.BaseCluster
+	flight *flight
+}
+
+func (rc *cluster) NewMachine(userdata *conf.UserData) (platform.Machine, error) {
+	return rc.NewMachineWithOptions(userdata, platform.MachineOptions{})
+}
+
+// NewMachineWithOptions returns the existing machine. The config isn't
+// applied, since it's long past its first boot.
+func (rc *cluster) NewMachineWithOptions(userdata *conf.UserData, options platform.MachineOptions) (platform.Machine, error) {
+	if needs := options.Needs(); len(needs) > 0 {
+		return nil, fmt.Errorf("reused machines don't support %s", needs[0])
+	}
+	if len(rc.Machines()) > 0 {
+		return nil, fmt.Errorf("there's only one machine to reuse")
+	}
+
+	host := rc.flight.opts.Host
+	mach := &machine{
+		cluster: rc,
+		// the output directory is named by it
+		id:   "reused-" + strings.NewReplacer(":", "_", "[", "", "]", "").Replace(host),
+		host: host,
+	}
+
+	mach.dir = filepath.Join(rc.RuntimeConf().OutputDir, mach.ID())
+	if err := os.Mkdir(mach.dir, 0777); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if mach.journal, err = platform.NewJournal(mach.dir); err != nil {
+		return nil, err
+	}
+	// the journal of the boot is long and not the test's
+	mach.journal.SkipExisting()
+
+	if !options.SkipStartMachine {
+		if err := platform.StartMachine(mach, mach.journal); err != nil {
+			mach.journal.Destroy()
+			return nil, err
+		}
+	}
+
+	rc.AddMach(mach)
+
+	return mach, nil
+}
+
+func (rc *cluster) Destroy() {
+	rc.BaseCluster.Destroy()
+	rc.flight.DelCluster(rc)
+}
@@ -0,0 +1,106 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package reuse is a platform whose only machine is an existing one, such
+// as real hardware or a long-lived lab system, which tests that don't need
+// to provision their own run against.
+package reuse
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/coreos/pkg/capnslog"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/coreos/coreos-assembler/mantle/platform"
+	"github.com/coreos/coreos-assembler/mantle/platform/conf"
+)
+
+const defaultUser = "core"
+
+var (
+	plog = capnslog.NewPackageLogger("github.com/coreos/coreos-assembler/mantle", "platform/machine/reuse")
+)
+
+type Options struct {
+	*platform.Options
+
+	// Host is the machine's address, as HOST[:PORT]
+	Host string
+	// KeyFile is the SSH private key the machine accepts
+	KeyFile string
+	// User is the user logged in as; defaults to core
+	User string
+}
+
+type flight struct {
+	*platform.BaseFlight
+	opts *Options
+}
+
+func NewFlight(opts *Options) (platform.Flight, error) {
+	if opts.Host == "" {
+		return nil, fmt.Errorf("no machine to reuse given")
+	}
+	if opts.KeyFile == "" {
+		return nil, fmt.Errorf("no SSH key for the machine to reuse given")
+	}
+	if opts.User == "" {
+		opts.User = defaultUser
+	}
+	data, err := os.ReadFile(opts.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	key, err := ssh.ParseRawPrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing SSH key %s: %v", opts.KeyFile, err)
+	}
+
+	bf, err := platform.NewBaseFlight(opts.Options, "reuse")
+	if err != nil {
+		return nil, err
+	}
+	if err := bf.UseKey(key, opts.User); err != nil {
+		bf.Destroy()
+		return nil, err
+	}
+
+	return &flight{
+		BaseFlight: bf,
+		opts:       opts,
+	}, nil
+}
+
+func (rf *flight) NewCluster(rconf *platform.RuntimeConfig) (platform.Cluster, error) {
+	bc, err := platform.NewBaseCluster(rf.BaseFlight, rconf)
+	if err != nil {
+		return nil, err
+	}
+
+	rc := &cluster{
+		BaseCluster: bc,
+		flight:      rf,
+	}
+
+	rf.AddCluster(rc)
+
+	return rc, nil
+}
+
+func (rf *flight) ConfigTooLarge(ud conf.UserData) bool {
+	// configs aren't applied
+	return false
+}
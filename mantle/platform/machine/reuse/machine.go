@@ -0,0 +1,109 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reuse
+
+import (
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/coreos/coreos-assembler/mantle/platform"
+)
+
+// machine is the existing machine; destroying it only stops recording its
+// journal.
+type machine struct {
+	cluster *cluster
+	id      string
+	host    string
+	dir     string
+	journal *platform.Journal
+}
+
+func (rm *machine) ID() string {
+	return rm.id
+}
+
+func (rm *machine) IP() string {
+	return rm.host
+}
+
+func (rm *machine) PrivateIP() string {
+	return rm.host
+}
+
+func (rm *machine) RuntimeConf() platform.RuntimeConfig {
+	return rm.cluster.RuntimeConf()
+}
+
+func (rm *machine) SSHClient() (*ssh.Client, error) {
+	return rm.cluster.SSHClient(rm.IP())
+}
+
+func (rm *machine) PasswordSSHClient(user string, password string) (*ssh.Client, error) {
+	return rm.cluster.PasswordSSHClient(rm.IP(), user, password)
+}
+
+func (rm *machine) SSH(cmd string) ([]byte, []byte, error) {
+	return rm.cluster.SSH(rm, cmd)
+}
+
+func (rm *machine) Upload(localPath, remotePath string) error {
+	return platform.UploadToMachine(rm, localPath, remotePath)
+}
+
+func (rm *machine) Download(remotePath, localPath string) error {
+	return platform.DownloadFromMachine(rm, remotePath, localPath)
+}
+
+func (rm *machine) IgnitionError() error {
+	return nil
+}
+
+func (rm *machine) Start() error {
+	return platform.StartMachine(rm, rm.journal)
+}
+
+func (rm *machine) Reboot() error {
+	return platform.RebootMachine(rm, rm.journal)
+}
+
+func (rm *machine) WaitForReboot(timeout time.Duration, oldBootId string) error {
+	return platform.WaitForMachineReboot(rm, rm.journal, timeout, oldBootId)
+}
+
+func (rm *machine) Destroy() {
+	if rm.journal != nil {
+		rm.journal.Destroy()
+	}
+	rm.cluster.DelMach(rm)
+}
+
+// ConsoleOutput returns nothing, since the console isn't reachable.
+func (rm *machine) ConsoleOutput() string {
+	return ""
+}
+
+func (rm *machine) JournalOutput() string {
+	if rm.journal == nil {
+		return ""
+	}
+
+	data, err := rm.journal.Read()
+	if err != nil {
+		plog.Errorf("Reading journal for machine %v: %v", rm.id, err)
+	}
+	return string(data)
+}
@@ -0,0 +1,110 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vultr
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/coreos/coreos-assembler/mantle/platform"
+	"github.com/coreos/coreos-assembler/mantle/platform/conf"
+)
+
+type cluster struct {
+	*platform.BaseCluster
+	flight   *flight
+	sshKeyID string
+}
+
+func (vc *cluster) NewMachine(userdata *conf.UserData) (platform.Machine, error) {
+	return vc.NewMachineWithOptions(userdata, platform.MachineOptions{})
+}
+
+func (vc *cluster) NewMachineWithOptions(userdata *conf.UserData, options platform.MachineOptions) (platform.Machine, error) {
+	if len(options.AdditionalDisks) > 0 {
+		return nil, errors.New("platform vultr does not yet support additional disks")
+	}
+	if options.MultiPathDisk {
+		return nil, errors.New("platform vultr does not support multipathed disks")
+	}
+	if options.AdditionalNics > 0 {
+		return nil, errors.New("platform vultr does not support additional nics")
+	}
+	if options.AppendKernelArgs != "" {
+		return nil, errors.New("platform vultr does not support appending kernel arguments")
+	}
+	if options.AppendFirstbootKernelArgs != "" {
+		return nil, errors.New("platform vultr does not support appending firstboot kernel arguments")
+	}
+	if options.InstanceType != "" {
+		return nil, errors.New("platform vultr does not support changing instance types")
+	}
+
+	conf, err := vc.RenderUserData(userdata, map[string]string{})
+	if err != nil {
+		return nil, err
+	}
+
+	instance, err := vc.flight.api.CreateInstance(vc.MachineName(), vc.sshKeyID, conf.String(), vc.ResourceTags())
+	if err != nil {
+		return nil, err
+	}
+
+	mach := &machine{
+		cluster:  vc,
+		instance: instance,
+	}
+	if mach.IP() == "" {
+		mach.Destroy()
+		return nil, fmt.Errorf("instance %s has no public IP address", instance.ID)
+	}
+
+	dir := filepath.Join(vc.RuntimeConf().OutputDir, mach.ID())
+	if err := os.Mkdir(dir, 0777); err != nil {
+		mach.Destroy()
+		return nil, err
+	}
+
+	confPath := filepath.Join(dir, "user-data")
+	if err := conf.WriteRedactedFile(confPath); err != nil {
+		mach.Destroy()
+		return nil, err
+	}
+
+	if mach.journal, err = platform.NewJournal(dir); err != nil {
+		mach.Destroy()
+		return nil, err
+	}
+
+	// Run StartMachine, which blocks on the machine being booted up enough
+	// for SSH access, but only if the caller didn't tell us not to.
+	if !options.SkipStartMachine {
+		if err := platform.StartMachine(mach, mach.journal); err != nil {
+			mach.Destroy()
+			return nil, err
+		}
+	}
+
+	vc.AddMach(mach)
+
+	return mach, nil
+}
+
+func (vc *cluster) Destroy() {
+	vc.BaseCluster.Destroy()
+	vc.flight.DelCluster(vc)
+}
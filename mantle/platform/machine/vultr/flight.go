@@ -0,0 +1,108 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vultr
+
+import (
+	"github.com/coreos/pkg/capnslog"
+
+	"github.com/coreos/coreos-assembler/mantle/platform"
+	"github.com/coreos/coreos-assembler/mantle/platform/api/vultr"
+	"github.com/coreos/coreos-assembler/mantle/platform/conf"
+)
+
+const (
+	Platform platform.Name = "vultr"
+)
+
+var (
+	plog = capnslog.NewPackageLogger("github.com/coreos/coreos-assembler/mantle", "platform/machine/vultr")
+)
+
+type flight struct {
+	*platform.BaseFlight
+	api      *vultr.API
+	sshKeyID string
+}
+
+func NewFlight(opts *vultr.Options) (platform.Flight, error) {
+	api, err := vultr.New(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	bf, err := platform.NewBaseFlight(opts.Options, Platform)
+	if err != nil {
+		return nil, err
+	}
+
+	vf := &flight{
+		BaseFlight: bf,
+		api:        api,
+	}
+
+	keys, err := vf.Keys()
+	if err != nil {
+		vf.Destroy()
+		return nil, err
+	}
+	vf.sshKeyID, err = vf.api.AddKey(vf.Name(), keys[0].String())
+	if err != nil {
+		vf.Destroy()
+		return nil, err
+	}
+
+	return vf, nil
+}
+
+func (vf *flight) NewCluster(rconf *platform.RuntimeConfig) (platform.Cluster, error) {
+	bc, err := platform.NewBaseCluster(vf.BaseFlight, rconf)
+	if err != nil {
+		return nil, err
+	}
+
+	vc := &cluster{
+		BaseCluster: bc,
+		flight:      vf,
+	}
+	if !rconf.NoSSHKeyInMetadata {
+		vc.sshKeyID = vf.sshKeyID
+	}
+
+	vf.AddCluster(vc)
+
+	return vc, nil
+}
+
+func (vf *flight) Capabilities() platform.Capabilities {
+	return platform.Capabilities{
+		Architectures: []string{"x86_64"},
+	}
+}
+
+func (vf *flight) ConfigTooLarge(ud conf.UserData) bool {
+
+	// not implemented
+	return false
+}
+
+func (vf *flight) Destroy() {
+	if vf.sshKeyID != "" {
+		if err := vf.api.DeleteKey(vf.sshKeyID); err != nil {
+			plog.Errorf("Error deleting key %v: %v", vf.sshKeyID, err)
+		}
+	}
+
+	vf.BaseFlight.Destroy()
+}
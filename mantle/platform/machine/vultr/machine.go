@@ -0,0 +1,115 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vultr
+
+import (
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/coreos/coreos-assembler/mantle/platform"
+	"github.com/coreos/coreos-assembler/mantle/platform/api/vultr"
+)
+
+type machine struct {
+	cluster  *cluster
+	instance *vultr.Instance
+	journal  *platform.Journal
+}
+
+func (vm *machine) ID() string {
+	return vm.instance.ID
+}
+
+func (vm *machine) IP() string {
+	return vm.instance.MainIP
+}
+
+func (vm *machine) PrivateIP() string {
+	// Instances only have an internal IP on a VPC
+	if vm.instance.InternalIP != "" {
+		return vm.instance.InternalIP
+	}
+	return vm.IP()
+}
+
+func (vm *machine) RuntimeConf() platform.RuntimeConfig {
+	return vm.cluster.RuntimeConf()
+}
+
+func (vm *machine) SSHClient() (*ssh.Client, error) {
+	return vm.cluster.SSHClient(vm.IP())
+}
+
+func (vm *machine) PasswordSSHClient(user string, password string) (*ssh.Client, error) {
+	return vm.cluster.PasswordSSHClient(vm.IP(), user, password)
+}
+
+func (vm *machine) SSH(cmd string) ([]byte, []byte, error) {
+	return vm.cluster.SSH(vm, cmd)
+}
+
+func (vm *machine) Upload(localPath, remotePath string) error {
+	return platform.UploadToMachine(vm, localPath, remotePath)
+}
+
+func (vm *machine) Download(remotePath, localPath string) error {
+	return platform.DownloadFromMachine(vm, remotePath, localPath)
+}
+
+func (vm *machine) IgnitionError() error {
+	return nil
+}
+
+func (vm *machine) Start() error {
+	return platform.StartMachine(vm, vm.journal)
+}
+
+func (vm *machine) Reboot() error {
+	return platform.RebootMachine(vm, vm.journal)
+}
+
+func (vm *machine) WaitForReboot(timeout time.Duration, oldBootId string) error {
+	return platform.WaitForMachineReboot(vm, vm.journal, timeout, oldBootId)
+}
+
+func (vm *machine) Destroy() {
+	if err := vm.cluster.flight.api.DeleteInstance(vm.instance.ID); err != nil {
+		plog.Errorf("Error deleting instance %v: %v", vm.instance.ID, err)
+	}
+
+	if vm.journal != nil {
+		vm.journal.Destroy()
+	}
+
+	vm.cluster.DelMach(vm)
+}
+
+func (vm *machine) ConsoleOutput() string {
+	// Vultr provides only a VNC console, not its output
+	return ""
+}
+
+func (vm *machine) JournalOutput() string {
+	if vm.journal == nil {
+		return ""
+	}
+
+	data, err := vm.journal.Read()
+	if err != nil {
+		plog.Errorf("Reading journal for instance %v: %v", vm.instance.ID, err)
+	}
+	return string(data)
+}
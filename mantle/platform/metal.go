@@ -16,6 +16,7 @@ package platform
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
 	"io"
 	"net"
@@ -27,9 +28,12 @@ import (
 
 	coreosarch "github.com/coreos/stream-metadata-go/arch"
 	"github.com/pkg/errors"
-	"gopkg.in/yaml.v2"
+	"golang.org/x/crypto/ssh"
 
+	"github.com/coreos/coreos-assembler/mantle/network/tftp"
 	"github.com/coreos/coreos-assembler/mantle/platform/conf"
+	"github.com/coreos/coreos-assembler/mantle/platform/instrument"
+	"github.com/coreos/coreos-assembler/mantle/system/cleanup"
 	"github.com/coreos/coreos-assembler/mantle/system/exec"
 	"github.com/coreos/coreos-assembler/mantle/util"
 )
@@ -37,36 +41,11 @@ import (
 const (
 	// defaultQemuHostIPv4 is documented in `man qemu-kvm`, under the `-netdev` option
 	defaultQemuHostIPv4 = "10.0.2.2"
-
-	bootStartedSignal = "boot-started-OK"
 )
 
 // TODO derive this from docs, or perhaps include kargs in cosa metadata?
 var baseKargs = []string{"rd.neednet=1", "ip=dhcp", "ignition.firstboot", "ignition.platform.id=metal"}
 
-var (
-	// TODO expose this as an API that can be used by cosa too
-	consoleKernelArgument = map[string]string{
-		"x86_64":  "ttyS0,115200n8",
-		"ppc64le": "hvc0",
-		"aarch64": "ttyAMA0",
-		"s390x":   "ttysclp0",
-	}
-
-	bootStartedUnit = fmt.Sprintf(`[Unit]
-	Description=TestISO Boot Started
-	Requires=dev-virtio\\x2dports-bootstarted.device
-	OnFailure=emergency.target
-	OnFailureJobMode=isolate
-	[Service]
-	Type=oneshot
-	RemainAfterExit=yes
-	ExecStart=/bin/sh -c '/usr/bin/echo %s >/dev/virtio-ports/bootstarted'
-	[Install]
-	RequiredBy=coreos-installer.target
-	`, bootStartedSignal)
-)
-
 // NewMetalQemuBuilderDefault returns a QEMU builder instance with some
 // defaults set up for bare metal.
 func NewMetalQemuBuilderDefault() *QemuBuilder {
@@ -85,6 +64,41 @@ type Install struct {
 	MultiPathDisk   bool
 	PxeAppendRootfs bool
 	NmKeyfiles      map[string]string
+	// Instrument selects the probes added to the live system, or if nil,
+	// instrument.Default(). BootStarted is always added, since installs
+	// wait for it.
+	Instrument *instrument.Options
+	// Runner runs the tools which set up installs, or if nil,
+	// HelperRunner.
+	Runner *exec.Runner
+	// Container, if set, makes ISO installs run coreos-installer from its
+	// container image rather than the live system's binary. It needs
+	// network access to the registry, so can't be used offline.
+	Container *instrument.ContainerInstaller
+	// SignatureFile, if set, is the GPG signature of the metal image
+	// served next to it, rather than the one in the build.
+	SignatureFile string
+	// Customize, if set, makes ISO installs prepare the ISO with a single
+	// `coreos-installer iso customize`, as most users do, rather than
+	// embedding each part separately.
+	Customize bool
+	// ImageCache, if set, is where compressed images checked against the
+	// installed disk are decompressed.
+	ImageCache *util.ImageCache
+	// SSHKeyFile, if set, is the private key of one of the core user's
+	// authorized keys in the installed system's config. Its SSH port is
+	// then forwarded from the host, so that the InstalledMachine works
+	// as a Machine.
+	SSHKeyFile string
+	// NativeTFTP, if set, serves PXE installs' files with mantle's TFTP
+	// server rather than QEMU's, which negotiates larger blocks and
+	// windows and logs each transfer. QEMU forwards the guest's requests
+	// to port 69 of the host's loopback; if that can't be bound, QEMU's
+	// server is used.
+	NativeTFTP bool
+	// TFTPFaults, if set, are injected into the native TFTP server's
+	// transfers.
+	TFTPFaults *tftp.Faults
 
 	// These are set by the install path
 	kargs        []string
@@ -92,54 +106,41 @@ type Install struct {
 	liveIgnition conf.Conf
 }
 
+// InstalledMachine is the machine an install ran on. Once the installed
+// system is up, it works as a Machine if the install had an SSHKeyFile.
 type InstalledMachine struct {
 	Tempdir                 string
 	QemuInst                *QemuInstance
 	BootStartedErrorChannel chan error
+	// OutputDir is where the journal is recorded once the machine is
+	// started; defaults to Tempdir
+	OutputDir string
+
+	sshSigner ssh.Signer
+	journal   *Journal
 }
 
-// Check that artifact has been built and locally exists
+// Check that artifacts have been built and match their checksums, fetching
+// those missing locally if the build has a RemoteURL
 func (inst *Install) checkArtifactsExist(artifacts []string) error {
-	version := inst.CosaBuild.Meta.OstreeVersion
 	for _, name := range artifacts {
-		artifact, err := inst.CosaBuild.Meta.GetArtifact(name)
-		if err != nil {
-			return fmt.Errorf("Missing artifact %s for %s build: %s", name, version, err)
-		}
-		path := filepath.Join(inst.CosaBuild.Dir, artifact.Path)
-		if _, err := os.Stat(path); err != nil {
-			if os.IsNotExist(err) {
-				return fmt.Errorf("Missing local file for artifact %s for build %s", name, version)
-			}
+		if _, err := inst.CosaBuild.RequireArtifact(name); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
 func (inst *Install) PXE(kargs []string, liveIgnition, ignition conf.Conf, offline bool) (*InstalledMachine, error) {
-	artifacts := []string{"live-kernel", "live-rootfs"}
+	artifacts := []string{"live-kernel", "live-initramfs", "live-rootfs"}
 	if err := inst.checkArtifactsExist(artifacts); err != nil {
 		return nil, err
 	}
 
-	installerConfig := installerConfig{
-		Console:     []string{consoleKernelArgument[coreosarch.CurrentRpmArch()]},
-		AppendKargs: renderCosaTestIsoDebugKargs(),
-	}
-	installerConfigData, err := yaml.Marshal(installerConfig)
-	if err != nil {
-		return nil, err
-	}
-	mode := 0644
-
-	// XXX: https://github.com/coreos/coreos-installer/issues/1171
-	if coreosarch.CurrentRpmArch() != "s390x" {
-		liveIgnition.AddFile("/etc/coreos/installer.d/mantle.yaml", string(installerConfigData), mode)
-	}
-
-	inst.kargs = append(renderCosaTestIsoDebugKargs(), kargs...)
+	inst.kargs = append(inst.probes().Kargs(), kargs...)
 	inst.ignition = ignition
 	inst.liveIgnition = liveIgnition
+	inst.applyChecks()
 
 	mach, err := inst.runPXE(&kernelSetup{
 		kernel:    inst.CosaBuild.Meta.BuildArtifacts.LiveKernel.Path,
@@ -153,14 +154,192 @@ func (inst *Install) PXE(kargs []string, liveIgnition, ignition conf.Conf, offli
 	return mach, nil
 }
 
-func (inst *InstalledMachine) Destroy() error {
+func (inst *InstalledMachine) Destroy() {
+	if inst.journal != nil {
+		inst.journal.Destroy()
+	}
 	if inst.QemuInst != nil {
 		inst.QemuInst.Destroy()
 		inst.QemuInst = nil
 	}
 	if inst.Tempdir != "" {
-		return os.RemoveAll(inst.Tempdir)
+		if err := removeTempdir(inst.Tempdir); err != nil {
+			plog.Errorf("Error removing tempdir of installed machine: %v", err)
+		}
+		inst.Tempdir = ""
+	}
+}
+
+func (inst *InstalledMachine) ID() string {
+	if inst.QemuInst == nil {
+		return "installed"
+	}
+	return fmt.Sprintf("installed-%d", inst.QemuInst.Pid())
+}
+
+// IP returns the host address forwarded to the installed system's SSH.
+func (inst *InstalledMachine) IP() string {
+	if inst.QemuInst == nil {
+		return ""
+	}
+	addr, err := inst.QemuInst.SSHAddress()
+	if err != nil {
+		return ""
+	}
+	return addr
+}
+
+func (inst *InstalledMachine) PrivateIP() string {
+	return inst.IP()
+}
+
+func (inst *InstalledMachine) RuntimeConf() RuntimeConfig {
+	return RuntimeConfig{
+		OutputDir: inst.outputDir(),
+	}
+}
+
+func (inst *InstalledMachine) outputDir() string {
+	if inst.OutputDir != "" {
+		return inst.OutputDir
+	}
+	return inst.Tempdir
+}
+
+// IgnitionError returns nil, since the installed system is only used once
+// it's up.
+func (inst *InstalledMachine) IgnitionError() error {
+	return nil
+}
+
+func (inst *InstalledMachine) sshClient(user string, auth ssh.AuthMethod) (*ssh.Client, error) {
+	addr := inst.IP()
+	if addr == "" {
+		return nil, fmt.Errorf("SSH of installed machine isn't forwarded; its install needs an SSHKeyFile")
+	}
+	return ssh.Dial("tcp", addr, &ssh.ClientConfig{
+		User:            user,
+		Auth:            []ssh.AuthMethod{auth},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	})
+}
+
+func (inst *InstalledMachine) SSHClient() (*ssh.Client, error) {
+	if inst.sshSigner == nil {
+		return nil, fmt.Errorf("installed machine has no SSH key; its install needs an SSHKeyFile")
+	}
+	return inst.sshClient("core", ssh.PublicKeys(inst.sshSigner))
+}
+
+func (inst *InstalledMachine) PasswordSSHClient(user string, password string) (*ssh.Client, error) {
+	return inst.sshClient(user, ssh.Password(password))
+}
+
+// SSH runs the command over a new SSH connection, returning its stdout and
+// stderr with surrounding whitespace trimmed.
+func (inst *InstalledMachine) SSH(cmd string) ([]byte, []byte, error) {
+	client, err := inst.SSHClient()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer client.Close()
+	session, err := client.NewSession()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer session.Close()
+
+	var stdout, stderr bytes.Buffer
+	session.Stdout = &stdout
+	session.Stderr = &stderr
+	err = session.Run(cmd)
+	plog.Debugf("Running cmd=%v res=%v", cmd, err)
+	return bytes.TrimSpace(stdout.Bytes()), bytes.TrimSpace(stderr.Bytes()), err
+}
+
+func (inst *InstalledMachine) Upload(localPath, remotePath string) error {
+	return UploadToMachine(inst, localPath, remotePath)
+}
+
+func (inst *InstalledMachine) Download(remotePath, localPath string) error {
+	return DownloadFromMachine(inst, remotePath, localPath)
+}
+
+// Start waits for the installed system to be reachable, recording its
+// journal, and checks it like other machines.
+func (inst *InstalledMachine) Start() error {
+	if inst.journal == nil {
+		dir := filepath.Join(inst.outputDir(), inst.ID())
+		if err := os.MkdirAll(dir, 0777); err != nil {
+			return err
+		}
+		journal, err := NewJournal(dir)
+		if err != nil {
+			return err
+		}
+		inst.journal = journal
+	}
+	return StartMachine(inst, inst.journal)
+}
+
+func (inst *InstalledMachine) Reboot() error {
+	return RebootMachine(inst, inst.journal)
+}
+
+func (inst *InstalledMachine) WaitForReboot(timeout time.Duration, oldBootId string) error {
+	return WaitForMachineReboot(inst, inst.journal, timeout, oldBootId)
+}
+
+// ConsoleOutput returns nothing; the console is the install's.
+func (inst *InstalledMachine) ConsoleOutput() string {
+	return ""
+}
+
+func (inst *InstalledMachine) JournalOutput() string {
+	if inst.journal == nil {
+		return ""
 	}
+	data, err := inst.journal.Read()
+	if err != nil {
+		plog.Errorf("Reading journal for installed machine %v: %v", inst.ID(), err)
+	}
+	return string(data)
+}
+
+// sshKey returns the key of the install's SSHKeyFile, if it has one.
+func (inst *Install) sshKey() (ssh.Signer, error) {
+	if inst.SSHKeyFile == "" {
+		return nil, nil
+	}
+	buf, err := os.ReadFile(inst.SSHKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := ssh.ParsePrivateKey(buf)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing %s", inst.SSHKeyFile)
+	}
+	return signer, nil
+}
+
+// pxeSubnetPool is where the subnets of PXE booted instances' user-mode
+// networks are allocated.
+var pxeSubnetPool = &net.IPNet{IP: net.IPv4(192, 168, 64, 0).To4(), Mask: net.CIDRMask(18, 32)}
+
+// subnetIP returns the nth address of the IPv4 subnet.
+func subnetIP(subnet *net.IPNet, n int) net.IP {
+	ip := make(net.IP, 4)
+	copy(ip, subnet.IP.To4())
+	ip[3] += byte(n)
+	return ip
+}
+
+// removeTempdir removes a temporary directory registered for cleanup.
+func removeTempdir(dir string) error {
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	cleanup.Default.DoneDir(dir)
 	return nil
 }
 
@@ -169,7 +348,9 @@ type kernelSetup struct {
 }
 
 type pxeSetup struct {
-	tftpipaddr    string
+	tftpipaddr string
+	// subnet is the user-mode network's, if it isn't QEMU's default
+	subnet        *net.IPNet
 	boottype      string
 	networkdevice string
 	bootindex     string
@@ -186,6 +367,8 @@ type installerRun struct {
 	builddir string
 	tempdir  string
 	tftpdir  string
+	// tftpServer is the native TFTP server, if one is serving tftpdir
+	tftpServer *tftp.Server
 
 	metalimg  string
 	metalname string
@@ -204,14 +387,48 @@ func absSymlink(src, dest string) error {
 	return os.Symlink(src, dest)
 }
 
-// setupMetalImage creates a symlink to the metal image.
-func setupMetalImage(builddir, metalimg, destdir string) (string, error) {
-	if err := absSymlink(filepath.Join(builddir, metalimg), filepath.Join(destdir, metalimg)); err != nil {
+// setupMetalImage creates a symlink to the metal image, and to its
+// signature, if it has one, which installs fetching the image verify it
+// with unless they're insecure.
+func (inst *Install) setupMetalImage(metalimg, destdir string) (string, error) {
+	if err := absSymlink(filepath.Join(inst.CosaBuild.Dir, metalimg), filepath.Join(destdir, metalimg)); err != nil {
 		return "", err
 	}
+	if sig := inst.metalSignature(metalimg); sig != "" {
+		if err := absSymlink(sig, filepath.Join(destdir, metalimg+".sig")); err != nil {
+			return "", err
+		}
+	}
 	return metalimg, nil
 }
 
+// MetalSignature returns the GPG signature of the metal image installs
+// verify it with, or "" if it has none.
+func (inst *Install) MetalSignature() string {
+	metal := inst.CosaBuild.Meta.BuildArtifacts.Metal
+	if inst.Native4k {
+		metal = inst.CosaBuild.Meta.BuildArtifacts.Metal4KNative
+	}
+	if metal == nil {
+		return inst.SignatureFile
+	}
+	return inst.metalSignature(metal.Path)
+}
+
+// metalSignature returns the GPG signature of the metal image: the
+// SignatureFile if set, or the one next to the image in the build, or ""
+// if it has none.
+func (inst *Install) metalSignature(metalimg string) string {
+	if inst.SignatureFile != "" {
+		return inst.SignatureFile
+	}
+	sig := filepath.Join(inst.CosaBuild.Dir, metalimg+".sig")
+	if _, err := os.Stat(sig); err != nil {
+		return ""
+	}
+	return sig
+}
+
 func (inst *Install) setup(kern *kernelSetup) (*installerRun, error) {
 	var artifacts []string
 	if inst.Native4k {
@@ -229,10 +446,11 @@ func (inst *Install) setup(kern *kernelSetup) (*installerRun, error) {
 	if err != nil {
 		return nil, err
 	}
+	cleanup.Default.AddDir(tempdir)
 	cleanupTempdir := true
 	defer func() {
 		if cleanupTempdir {
-			os.RemoveAll(tempdir)
+			removeTempdir(tempdir) //nolint // Ignore errors
 		}
 	}()
 
@@ -245,9 +463,14 @@ func (inst *Install) setup(kern *kernelSetup) (*installerRun, error) {
 	if err := inst.ignition.WriteFile(filepath.Join(tftpdir, "config.ign")); err != nil {
 		return nil, err
 	}
-	// This code will ensure to add an SSH key to `pxe-live.ign` config.
-	inst.liveIgnition.AddAutoLogin()
-	inst.liveIgnition.AddSystemdUnit("boot-started.service", bootStartedUnit, conf.Enable)
+	// XXX: https://github.com/coreos/coreos-installer/issues/1171
+	var installerConfig *instrument.InstallerConfig
+	if coreosarch.CurrentRpmArch() != "s390x" {
+		installerConfig = &instrument.InstallerConfig{}
+	}
+	if err := inst.probes().Apply(&inst.liveIgnition, installerConfig); err != nil {
+		return nil, err
+	}
 	if err := inst.liveIgnition.WriteFile(filepath.Join(tftpdir, "pxe-live.ign")); err != nil {
 		return nil, err
 	}
@@ -275,13 +498,12 @@ func (inst *Install) setup(kern *kernelSetup) (*installerRun, error) {
 	} else {
 		metalimg = inst.CosaBuild.Meta.BuildArtifacts.Metal.Path
 	}
-	metalname, err := setupMetalImage(builddir, metalimg, tftpdir)
+	metalname, err := inst.setupMetalImage(metalimg, tftpdir)
 	if err != nil {
 		return nil, errors.Wrapf(err, "setting up metal image")
 	}
 
 	pxe := pxeSetup{}
-	pxe.tftpipaddr = "192.168.76.2"
 	switch coreosarch.CurrentRpmArch() {
 	case "x86_64":
 		pxe.networkdevice = "e1000"
@@ -303,9 +525,17 @@ func (inst *Install) setup(kern *kernelSetup) (*installerRun, error) {
 		pxe.pxeimagepath = "/boot/efi/EFI/fedora/grubaa64.efi"
 		pxe.bootindex = "1"
 	case "ppc64le":
-		pxe.boottype = "grub"
 		pxe.networkdevice = "virtio-net-pci"
-		pxe.bootfile = "/boot/grub2/powerpc-ieee1275/core.elf"
+		if builder.Firmware == "opal" {
+			// petitboot understands pxelinux configs directly; point
+			// it at ours via the DHCP bootfile
+			pxe.boottype = "petitboot"
+			pxe.bootfile = "/pxelinux.cfg/default"
+			pxe.bootindex = "1"
+		} else {
+			pxe.boottype = "grub"
+			pxe.bootfile = "/boot/grub2/powerpc-ieee1275/core.elf"
+		}
 	case "s390x":
 		pxe.boottype = "pxe"
 		pxe.networkdevice = "virtio-net-ccw"
@@ -314,6 +544,17 @@ func (inst *Install) setup(kern *kernelSetup) (*installerRun, error) {
 	default:
 		return nil, fmt.Errorf("Unsupported arch %s" + coreosarch.CurrentRpmArch())
 	}
+	if pxe.tftpipaddr == "" {
+		// allocated so that concurrent runs on the host don't use the same
+		a, err := builder.Allocator()
+		if err != nil {
+			return nil, err
+		}
+		if pxe.subnet, err = a.Subnet(pxeSubnetPool, 24); err != nil {
+			return nil, errors.Wrapf(err, "allocating PXE subnet")
+		}
+		pxe.tftpipaddr = subnetIP(pxe.subnet, 2).String()
+	}
 
 	mux := http.NewServeMux()
 	mux.Handle("/", http.FileServer(http.Dir(tftpdir)))
@@ -328,6 +569,11 @@ func (inst *Install) setup(kern *kernelSetup) (*installerRun, error) {
 	}()
 	baseurl := fmt.Sprintf("http://%s:%d", pxe.tftpipaddr, port)
 
+	var tftpServer *tftp.Server
+	if inst.NativeTFTP {
+		tftpServer = startTFTPServer(tftpdir, inst.TFTPFaults)
+	}
+
 	cleanupTempdir = false // Transfer ownership
 	return &installerRun{
 		inst: inst,
@@ -337,6 +583,8 @@ func (inst *Install) setup(kern *kernelSetup) (*installerRun, error) {
 		tftpdir:  tftpdir,
 		builddir: builddir,
 
+		tftpServer: tftpServer,
+
 		metalimg:  metalimg,
 		metalname: metalname,
 
@@ -347,8 +595,34 @@ func (inst *Install) setup(kern *kernelSetup) (*installerRun, error) {
 	}, nil
 }
 
+// probes returns the instrumentation of the live system.
+// applyChecks adds the registered post-install checks which apply to the
+// install to its configs.
+func (inst *Install) applyChecks() {
+	instrument.ApplyChecks(&inst.liveIgnition, &inst.ignition, instrument.InstallContext{
+		MultiPath: inst.MultiPathDisk,
+		Container: inst.Container != nil,
+	})
+}
+
+func (inst *Install) runner() *exec.Runner {
+	if inst.Runner != nil {
+		return inst.Runner
+	}
+	return HelperRunner
+}
+
+func (inst *Install) probes() instrument.Options {
+	probes := instrument.Default()
+	if inst.Instrument != nil {
+		probes = *inst.Instrument
+	}
+	probes.BootStarted = true
+	return probes
+}
+
 func renderBaseKargs() []string {
-	return append(baseKargs, fmt.Sprintf("console=%s", consoleKernelArgument[coreosarch.CurrentRpmArch()]))
+	return append(baseKargs, fmt.Sprintf("console=%s", instrument.SerialConsole(coreosarch.CurrentRpmArch())))
 }
 
 func renderInstallKargs(t *installerRun, offline bool) []string {
@@ -357,31 +631,47 @@ func renderInstallKargs(t *installerRun, offline bool) []string {
 	if !offline {
 		args = append(args, fmt.Sprintf("coreos.inst.image_url=%s/%s", t.baseurl, t.metalname))
 	}
-	// FIXME - ship signatures by default too
+	// otherwise the image is verified with the signature served next to it
 	if t.inst.Insecure {
 		args = append(args, "coreos.inst.insecure")
 	}
 	return args
 }
 
-// Sometimes the logs that stream from various virtio streams can be
-// incomplete because they depend on services inside the guest.
-// When you are debugging earlyboot/initramfs issues this can be
-// problematic. Let's add a hook here to enable more debugging.
-func renderCosaTestIsoDebugKargs() []string {
-	if _, ok := os.LookupEnv("COSA_TESTISO_DEBUG"); ok {
-		return []string{"systemd.log_color=0", "systemd.log_level=debug",
-			"systemd.journald.forward_to_console=1",
-			"systemd.journald.max_level_console=debug"}
-	} else {
-		return []string{}
+// startTFTPServer serves tftpdir on port 69 of the host's loopback, where
+// QEMU's user-mode network forwards the guest's TFTP requests unless it
+// serves them itself. It returns nil if the port can't be bound, e.g.
+// without privileges or when another run is using it.
+func startTFTPServer(tftpdir string, faults *tftp.Faults) *tftp.Server {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 69})
+	if err != nil {
+		plog.Warningf("Falling back to QEMU's TFTP server: %v", err)
+		return nil
+	}
+	server := tftp.NewServer(tftpdir)
+	server.Faults = faults
+	server.Log = func(t tftp.Transfer) {
+		if t.Err != nil {
+			plog.Warningf("TFTP %s", t)
+		} else {
+			plog.Infof("TFTP %s", t)
+		}
 	}
+	go func() {
+		if err := server.Serve(conn); err != nil {
+			plog.Errorf("TFTP server: %v", err)
+		}
+	}()
+	return server
 }
 
 func (t *installerRun) destroy() error {
 	t.builder.Close()
+	if t.tftpServer != nil {
+		t.tftpServer.Close()
+	}
 	if t.tempdir != "" {
-		return os.RemoveAll(t.tempdir)
+		return removeTempdir(t.tempdir)
 	}
 	return nil
 }
@@ -393,6 +683,22 @@ func (t *installerRun) completePxeSetup(kargs []string) error {
 	kargsStr := strings.Join(kargs, " ")
 
 	switch t.pxe.boottype {
+	case "petitboot":
+		pxeconfigdir := filepath.Join(t.tftpdir, "pxelinux.cfg")
+		if err := os.Mkdir(pxeconfigdir, 0777); err != nil {
+			return errors.Wrapf(err, "creating dir %s", pxeconfigdir)
+		}
+		pxeconfig := []byte(fmt.Sprintf(`
+		DEFAULT pxeboot
+		LABEL pxeboot
+			KERNEL %s
+			INITRD %s
+			APPEND %s
+		`, t.kern.kernel, t.kern.initramfs, kargsStr))
+		pxeconfig_path := filepath.Join(pxeconfigdir, "default")
+		if err := os.WriteFile(pxeconfig_path, pxeconfig, 0777); err != nil {
+			return errors.Wrapf(err, "writing file %s", pxeconfig_path)
+		}
 	case "pxe":
 		pxeconfigdir := filepath.Join(t.tftpdir, "pxelinux.cfg")
 		if err := os.Mkdir(pxeconfigdir, 0777); err != nil {
@@ -420,34 +726,28 @@ func (t *installerRun) completePxeSetup(kargs []string) error {
 		if t.pxe.pxeimagepath == "" {
 			kernelpath := filepath.Join(t.tftpdir, t.kern.kernel)
 			initrdpath := filepath.Join(t.tftpdir, t.kern.initramfs)
-			err := exec.Command("/usr/bin/mk-s390image", kernelpath, "-r", initrdpath,
-				"-p", filepath.Join(pxeconfigdir, "default"), filepath.Join(t.tftpdir, pxeimages[0])).Run()
+			_, err := t.inst.runner().Run("/usr/bin/mk-s390image", kernelpath, "-r", initrdpath,
+				"-p", filepath.Join(pxeconfigdir, "default"), filepath.Join(t.tftpdir, pxeimages[0]))
 			if err != nil {
-				return errors.Wrap(err, "running mk-s390image")
+				return errors.Wrap(err, "creating s390x PXE image")
 			}
 		} else {
 			for _, img := range pxeimages {
 				srcpath := filepath.Join("/usr/share/syslinux", img)
-				cp_cmd := exec.Command("/usr/lib/coreos-assembler/cp-reflink", srcpath, t.tftpdir)
-				cp_cmd.Stderr = os.Stderr
-				if err := cp_cmd.Run(); err != nil {
-					return errors.Wrapf(err, "running cp-reflink %s %s", srcpath, t.tftpdir)
+				if err := stageFile(srcpath, t.tftpdir); err != nil {
+					return errors.Wrap(err, "copying syslinux image")
 				}
 			}
 		}
 		t.pxe.bootfile = "/" + pxeimages[0]
 	case "grub":
-		grub2_mknetdir_cmd := exec.Command("grub2-mknetdir", "--net-directory="+t.tftpdir)
-		grub2_mknetdir_cmd.Stderr = os.Stderr
-		if err := grub2_mknetdir_cmd.Run(); err != nil {
-			return errors.Wrap(err, "running grub2-mknetdir")
+		if _, err := t.inst.runner().Run("grub2-mknetdir", "--net-directory="+t.tftpdir); err != nil {
+			return errors.Wrap(err, "setting up GRUB netboot directory")
 		}
 		if t.pxe.pxeimagepath != "" {
 			dstpath := filepath.Join(t.tftpdir, "boot/grub2")
-			cp_cmd := exec.Command("/usr/lib/coreos-assembler/cp-reflink", t.pxe.pxeimagepath, dstpath)
-			cp_cmd.Stderr = os.Stderr
-			if err := cp_cmd.Run(); err != nil {
-				return errors.Wrapf(err, "running cp-reflink %s %s", t.pxe.pxeimagepath, dstpath)
+			if err := stageFile(t.pxe.pxeimagepath, dstpath); err != nil {
+				return errors.Wrap(err, "copying GRUB image")
 			}
 		}
 		if err := os.WriteFile(filepath.Join(t.tftpdir, "boot/grub2/grub.cfg"), []byte(fmt.Sprintf(`
@@ -478,7 +778,7 @@ func switchBootOrderSignal(qinst *QemuInstance, bootstartedchan *os.File, booter
 			err = errors.New("process killed")
 		}
 		if err != nil {
-			*booterrchan <- errors.Wrapf(err, "QEMU unexpectedly exited while waiting for %s", bootStartedSignal)
+			*booterrchan <- errors.Wrapf(err, "QEMU unexpectedly exited while waiting for %s", instrument.BootStartedSignal)
 		}
 	}()
 	go func() {
@@ -490,7 +790,7 @@ func switchBootOrderSignal(qinst *QemuInstance, bootstartedchan *os.File, booter
 				// to give a chance for .Wait() above to feed the channel with a
 				// better error
 				time.Sleep(1 * time.Second)
-				*booterrchan <- fmt.Errorf("Got EOF from boot started channel, %s expected", bootStartedSignal)
+				*booterrchan <- fmt.Errorf("Got EOF from boot started channel, %s expected", instrument.BootStartedSignal)
 			} else {
 				*booterrchan <- errors.Wrapf(err, "reading from boot started channel")
 			}
@@ -498,7 +798,7 @@ func switchBootOrderSignal(qinst *QemuInstance, bootstartedchan *os.File, booter
 		}
 		line := strings.TrimSpace(l)
 		// switch the boot order here, we are well into the installation process - only for aarch64 and s390x
-		if line == bootStartedSignal {
+		if line == instrument.BootStartedSignal {
 			if err := qinst.SwitchBootOrder(); err != nil {
 				*booterrchan <- errors.Wrapf(err, "switching boot order failed")
 				return
@@ -531,16 +831,37 @@ func cat(outfile string, infiles ...string) error {
 
 func (t *installerRun) run() (*QemuInstance, error) {
 	builder := t.builder
-	netdev := fmt.Sprintf("%s,netdev=mynet0,mac=52:54:00:12:34:56", t.pxe.networkdevice)
+	a, err := builder.Allocator()
+	if err != nil {
+		return nil, err
+	}
+	mac, err := a.MAC()
+	if err != nil {
+		return nil, err
+	}
+	netdev := fmt.Sprintf("%s,netdev=mynet0,mac=%s", t.pxe.networkdevice, mac)
 	if t.pxe.bootindex == "" {
 		builder.Append("-boot", "once=n")
 	} else {
 		netdev += fmt.Sprintf(",bootindex=%s", t.pxe.bootindex)
 	}
 	builder.Append("-device", netdev)
-	usernetdev := fmt.Sprintf("user,id=mynet0,tftp=%s,bootfile=%s", t.tftpdir, t.pxe.bootfile)
-	if t.pxe.tftpipaddr != "10.0.2.2" {
-		usernetdev += ",net=192.168.76.0/24,dhcpstart=192.168.76.9"
+	usernetdev := "user,id=mynet0"
+	if t.tftpServer == nil {
+		usernetdev += fmt.Sprintf(",tftp=%s", t.tftpdir)
+	}
+	usernetdev += fmt.Sprintf(",bootfile=%s", t.pxe.bootfile)
+	if t.pxe.subnet != nil {
+		usernetdev += fmt.Sprintf(",net=%s,dhcpstart=%s", t.pxe.subnet, subnetIP(t.pxe.subnet, 9))
+	}
+	var forwarded []HostForwardPort
+	if t.inst.SSHKeyFile != "" {
+		port, err := a.Port()
+		if err != nil {
+			return nil, err
+		}
+		forwarded = append(forwarded, HostForwardPort{Service: "ssh", HostPort: port, GuestPort: 22})
+		usernetdev += fmt.Sprintf(",hostfwd=tcp:127.0.0.1:%d-:22", port)
 	}
 	builder.Append("-netdev", usernetdev)
 
@@ -548,6 +869,7 @@ func (t *installerRun) run() (*QemuInstance, error) {
 	if err != nil {
 		return nil, err
 	}
+	inst.hostForwardedPorts = forwarded
 	return inst, nil
 }
 
@@ -560,7 +882,7 @@ func (inst *Install) runPXE(kern *kernelSetup, offline bool) (*InstalledMachine,
 		err = t.destroy()
 	}()
 
-	bootStartedChan, err := inst.Builder.VirtioChannelRead("bootstarted")
+	bootStartedChan, err := inst.Builder.VirtioChannelRead(instrument.BootStartedChannel)
 	if err != nil {
 		return nil, errors.Wrapf(err, "setting up bootstarted virtio-serial channel")
 	}
@@ -573,6 +895,10 @@ func (inst *Install) runPXE(kern *kernelSetup, offline bool) (*InstalledMachine,
 	if err := t.completePxeSetup(kargs); err != nil {
 		return nil, errors.Wrapf(err, "completing PXE setup")
 	}
+	signer, err := inst.sshKey()
+	if err != nil {
+		return nil, err
+	}
 	qinst, err := t.run()
 	if err != nil {
 		return nil, errors.Wrapf(err, "running PXE install")
@@ -580,23 +906,14 @@ func (inst *Install) runPXE(kern *kernelSetup, offline bool) (*InstalledMachine,
 	tempdir := t.tempdir
 	t.tempdir = "" // Transfer ownership
 	instmachine := InstalledMachine{
-		QemuInst: qinst,
-		Tempdir:  tempdir,
+		QemuInst:  qinst,
+		Tempdir:   tempdir,
+		sshSigner: signer,
 	}
 	switchBootOrderSignal(qinst, bootStartedChan, &instmachine.BootStartedErrorChannel)
 	return &instmachine, nil
 }
 
-type installerConfig struct {
-	ImageURL     string   `yaml:"image-url,omitempty"`
-	IgnitionFile string   `yaml:"ignition-file,omitempty"`
-	Insecure     bool     `yaml:",omitempty"`
-	AppendKargs  []string `yaml:"append-karg,omitempty"`
-	CopyNetwork  bool     `yaml:"copy-network,omitempty"`
-	DestDevice   string   `yaml:"dest-device,omitempty"`
-	Console      []string `yaml:"console,omitempty"`
-}
-
 func (inst *Install) InstallViaISOEmbed(kargs []string, liveIgnition, targetIgnition conf.Conf, outdir string, offline, minimal bool) (*InstalledMachine, error) {
 	artifacts := []string{"live-iso"}
 	if inst.Native4k {
@@ -613,16 +930,31 @@ func (inst *Install) InstallViaISOEmbed(kargs []string, liveIgnition, targetIgni
 	if offline && len(inst.NmKeyfiles) > 0 {
 		return nil, fmt.Errorf("Cannot use `--add-nm-keyfile` with offline mode")
 	}
+	if inst.Container != nil {
+		if offline {
+			return nil, fmt.Errorf("Cannot install from a container offline")
+		}
+		// the container can't see the live system's network config
+		if len(inst.NmKeyfiles) > 0 {
+			return nil, fmt.Errorf("Cannot use `--add-nm-keyfile` when installing from a container")
+		}
+		if inst.MultiPathDisk {
+			return nil, fmt.Errorf("Cannot install to a multipath disk from a container")
+		}
+	}
 
-	installerConfig := installerConfig{
+	installerConfig := instrument.InstallerConfig{
 		IgnitionFile: "/var/opt/pointer.ign",
 		DestDevice:   "/dev/vda",
-		AppendKargs:  renderCosaTestIsoDebugKargs(),
+	}
+	if inst.Container != nil {
+		installerConfig.IgnitionFile = filepath.Join(instrument.ContainerInstallerDir, "pointer.ign")
 	}
 
+	probes := inst.probes()
 	// XXX: https://github.com/coreos/coreos-installer/issues/1171
-	if coreosarch.CurrentRpmArch() != "s390x" {
-		installerConfig.Console = []string{consoleKernelArgument[coreosarch.CurrentRpmArch()]}
+	if coreosarch.CurrentRpmArch() == "s390x" {
+		probes.Console = ""
 	}
 
 	if inst.MultiPathDisk {
@@ -631,18 +963,20 @@ func (inst *Install) InstallViaISOEmbed(kargs []string, liveIgnition, targetIgni
 		installerConfig.AppendKargs = append(installerConfig.AppendKargs, "rd.multipath=default", "root=/dev/disk/by-label/dm-mpath-root", "rw")
 	}
 
-	inst.kargs = append(renderCosaTestIsoDebugKargs(), kargs...)
+	inst.kargs = append(probes.Kargs(), kargs...)
 	inst.ignition = targetIgnition
 	inst.liveIgnition = liveIgnition
+	inst.applyChecks()
 
 	tempdir, err := os.MkdirTemp("/var/tmp", "mantle-metal")
 	if err != nil {
 		return nil, err
 	}
+	cleanup.Default.AddDir(tempdir)
 	cleanupTempdir := true
 	defer func() {
 		if cleanupTempdir {
-			os.RemoveAll(tempdir)
+			removeTempdir(tempdir) //nolint // Ignore errors
 		}
 	}()
 
@@ -650,7 +984,7 @@ func (inst *Install) InstallViaISOEmbed(kargs []string, liveIgnition, targetIgni
 		return nil, err
 	}
 	// and write it once more in the output dir for debugging
-	if err := inst.ignition.WriteFile(filepath.Join(outdir, "config-target.ign")); err != nil {
+	if err := inst.ignition.WriteRedactedFile(filepath.Join(outdir, "config-target.ign")); err != nil {
 		return nil, err
 	}
 
@@ -661,11 +995,9 @@ func (inst *Install) InstallViaISOEmbed(kargs []string, liveIgnition, targetIgni
 	// This is a bit awkward; we copy here, but QemuBuilder will also copy
 	// again (in `setupIso()`). I didn't want to lower the NM keyfile stuff
 	// into QemuBuilder. And plus, both tempdirs should be in /var/tmp so
-	// the `cp --reflink=auto` that QemuBuilder does should just reflink.
+	// both copies should just reflink.
 	newIso := filepath.Join(tempdir, "install.iso")
-	cmd := exec.Command("cp", "--reflink=auto", srcisopath, newIso)
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
+	if err := stageFile(srcisopath, newIso); err != nil {
 		return nil, errors.Wrapf(err, "copying iso")
 	}
 	// Make it writable so we can modify it
@@ -680,7 +1012,7 @@ func (inst *Install) InstallViaISOEmbed(kargs []string, liveIgnition, targetIgni
 	} else {
 		metalimg = inst.CosaBuild.Meta.BuildArtifacts.Metal.Path
 	}
-	metalname, err := setupMetalImage(builddir, metalimg, tempdir)
+	metalname, err := inst.setupMetalImage(metalimg, tempdir)
 	if err != nil {
 		return nil, errors.Wrapf(err, "setting up metal image")
 	}
@@ -725,11 +1057,11 @@ func (inst *Install) InstallViaISOEmbed(kargs []string, liveIgnition, targetIgni
 			// Ideally we'd use the coreos-installer of the target build here, because it's part
 			// of the test workflow, but that's complex... Sadly, probably easiest is to spin up
 			// a VM just to get the minimal ISO.
-			cmd := exec.Command("coreos-installer", "iso", "extract", "minimal-iso", srcisopath,
+			argv := HostInstaller.Argv("iso", "extract", "minimal-iso", srcisopath,
 				minisopath, "--output-rootfs", rootfs_path, "--rootfs-url", baseurl+"/rootfs.img")
-			cmd.Stderr = os.Stderr
-			if err := cmd.Run(); err != nil {
-				return nil, errors.Wrapf(err, "running coreos-installer iso extract minimal")
+			_, err := inst.runner().Run(argv[0], argv[1:]...)
+			if err != nil {
+				return nil, errors.Wrapf(err, "extracting minimal iso")
 			}
 			srcisopath = minisopath
 		}
@@ -747,62 +1079,15 @@ func (inst *Install) InstallViaISOEmbed(kargs []string, liveIgnition, targetIgni
 		serializedTargetConfig = targetConfig.String()
 
 		// also save pointer config into the output dir for debugging
-		if err := targetConfig.WriteFile(filepath.Join(outdir, "config-target-pointer.ign")); err != nil {
+		if err := targetConfig.WriteRedactedFile(filepath.Join(outdir, "config-target-pointer.ign")); err != nil {
 			return nil, err
 		}
 	}
 
-	var keyfileArgs []string
-	for nmName, nmContents := range inst.NmKeyfiles {
-		path := filepath.Join(tempdir, nmName)
-		if err := os.WriteFile(path, []byte(nmContents), 0600); err != nil {
-			return nil, err
-		}
-		keyfileArgs = append(keyfileArgs, "--keyfile", path)
-	}
-	if len(keyfileArgs) > 0 {
-
-		args := []string{"iso", "network", "embed", srcisopath}
-		args = append(args, keyfileArgs...)
-		cmd = exec.Command("coreos-installer", args...)
-		cmd.Stderr = os.Stderr
-		if err := cmd.Run(); err != nil {
-			return nil, errors.Wrapf(err, "running coreos-installer iso network embed")
-		}
-
-		installerConfig.CopyNetwork = true
-
-		// force networking on in the initrd to verify the keyfile was used
-		inst.kargs = append(inst.kargs, "rd.neednet=1")
-	}
-
-	if len(inst.kargs) > 0 {
-		args := []string{"iso", "kargs", "modify", srcisopath}
-		for _, karg := range inst.kargs {
-			args = append(args, "--append", karg)
-		}
-		cmd = exec.Command("coreos-installer", args...)
-		cmd.Stderr = os.Stderr
-		if err := cmd.Run(); err != nil {
-			return nil, errors.Wrapf(err, "running coreos-installer iso kargs")
-		}
-	}
-
 	if inst.Insecure {
 		installerConfig.Insecure = true
 	}
 
-	installerConfigData, err := yaml.Marshal(installerConfig)
-	if err != nil {
-		return nil, err
-	}
-	mode := 0644
-
-	inst.liveIgnition.AddSystemdUnit("boot-started.service", bootStartedUnit, conf.Enable)
-	inst.liveIgnition.AddFile(installerConfig.IgnitionFile, serializedTargetConfig, mode)
-	inst.liveIgnition.AddFile("/etc/coreos/installer.d/mantle.yaml", string(installerConfigData), mode)
-	inst.liveIgnition.AddAutoLogin()
-
 	if inst.MultiPathDisk {
 		inst.liveIgnition.AddSystemdUnit("coreos-installer-multipath.service", `[Unit]
 Description=TestISO Enable Multipath
@@ -819,17 +1104,29 @@ Requires=dev-mapper-mpatha.device
 After=dev-mapper-mpatha.device`)
 	}
 
+	if inst.Customize {
+		err = inst.customizeIso(srcisopath, tempdir, serializedTargetConfig, installerConfig, probes)
+	} else {
+		err = inst.embedIso(srcisopath, tempdir, serializedTargetConfig, installerConfig, probes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
 	qemubuilder := inst.Builder
-	bootStartedChan, err := qemubuilder.VirtioChannelRead("bootstarted")
+	bootStartedChan, err := qemubuilder.VirtioChannelRead(instrument.BootStartedChannel)
 	if err != nil {
 		return nil, err
 	}
 
-	qemubuilder.SetConfig(&inst.liveIgnition)
+	// iso customize embeds the live config itself
+	if !inst.Customize {
+		qemubuilder.SetConfig(&inst.liveIgnition)
+	}
 
 	// also save live config into the output dir for debugging
 	liveConfigPath := filepath.Join(outdir, "config-live.ign")
-	if err := inst.liveIgnition.WriteFile(liveConfigPath); err != nil {
+	if err := inst.liveIgnition.WriteRedactedFile(liveConfigPath); err != nil {
 		return nil, err
 	}
 
@@ -842,6 +1139,18 @@ After=dev-mapper-mpatha.device`)
 	if !offline {
 		qemubuilder.UsermodeNetworking = true
 	}
+	signer, err := inst.sshKey()
+	if err != nil {
+		return nil, err
+	}
+	if signer != nil {
+		// offline installs still get a network to reach SSH through,
+		// but it's restricted to the host
+		if offline {
+			qemubuilder.RestrictNetworking = true
+		}
+		qemubuilder.EnableUsermodeNetworking([]HostForwardPort{{Service: "ssh", GuestPort: 22}}, "")
+	}
 
 	qinst, err := qemubuilder.Exec()
 	if err != nil {
@@ -849,9 +1158,127 @@ After=dev-mapper-mpatha.device`)
 	}
 	cleanupTempdir = false // Transfer ownership
 	instmachine := InstalledMachine{
-		QemuInst: qinst,
-		Tempdir:  tempdir,
+		QemuInst:  qinst,
+		Tempdir:   tempdir,
+		OutputDir: outdir,
+		sshSigner: signer,
 	}
 	switchBootOrderSignal(qinst, bootStartedChan, &instmachine.BootStartedErrorChannel)
 	return &instmachine, nil
 }
+
+// embedIso prepares the ISO for the install with the coreos-installer
+// commands embedding each part, adding the target config and installer
+// config to the live config, which QemuBuilder embeds.
+func (inst *Install) embedIso(isopath, tempdir, targetConfig string, installerConfig instrument.InstallerConfig, probes instrument.Options) error {
+	var keyfileArgs []string
+	for nmName, nmContents := range inst.NmKeyfiles {
+		path := filepath.Join(tempdir, nmName)
+		if err := os.WriteFile(path, []byte(nmContents), 0600); err != nil {
+			return err
+		}
+		keyfileArgs = append(keyfileArgs, "--keyfile", path)
+	}
+	if len(keyfileArgs) > 0 {
+
+		args := []string{"iso", "network", "embed", isopath}
+		args = append(args, keyfileArgs...)
+		argv := HostInstaller.Argv(args...)
+		if _, err := inst.runner().Run(argv[0], argv[1:]...); err != nil {
+			return errors.Wrapf(err, "embedding network keyfiles")
+		}
+
+		installerConfig.CopyNetwork = true
+
+		// force networking on in the initrd to verify the keyfile was used
+		inst.kargs = append(inst.kargs, "rd.neednet=1")
+	}
+
+	if len(inst.kargs) > 0 {
+		args := []string{"iso", "kargs", "modify", isopath}
+		for _, karg := range inst.kargs {
+			args = append(args, "--append", karg)
+		}
+		argv := HostInstaller.Argv(args...)
+		if _, err := inst.runner().Run(argv[0], argv[1:]...); err != nil {
+			return errors.Wrapf(err, "modifying iso kargs")
+		}
+	}
+
+	inst.liveIgnition.AddFile(installerConfig.IgnitionFile, targetConfig, 0644)
+	if inst.Container != nil {
+		return probes.ApplyContainer(&inst.liveIgnition, &installerConfig, inst.Container)
+	}
+	return probes.Apply(&inst.liveIgnition, &installerConfig)
+}
+
+// customizePreInstall and customizePostInstall are the scripts customized
+// ISOs run around coreos-installer; the install fails if one does.
+var customizePreInstall = `#!/bin/bash
+set -xeuo pipefail
+touch /run/testiso-pre-install
+`
+
+var customizePostInstall = `#!/bin/bash
+set -xeuo pipefail
+test -e /run/testiso-pre-install
+udevadm settle
+test -e /dev/disk/by-label/root || test -e /dev/disk/by-label/dm-mpath-root
+`
+
+// customizeIso prepares the ISO for the install with a single
+// `coreos-installer iso customize`, the way most users do, embedding the
+// live config, target config, installer config, network keyfiles, kernel
+// arguments and pre- and post-install scripts.
+func (inst *Install) customizeIso(isopath, tempdir, targetConfig string, installerConfig instrument.InstallerConfig, probes instrument.Options) error {
+	if inst.Container != nil {
+		return fmt.Errorf("Cannot customize the iso when installing from a container")
+	}
+	args := []string{"iso", "customize", "--dest-device", installerConfig.DestDevice}
+	installerConfig.DestDevice = ""
+	installerConfig.IgnitionFile = ""
+
+	if err := probes.Apply(&inst.liveIgnition, nil); err != nil {
+		return err
+	}
+	installerData, err := probes.RenderInstallerConfig(&installerConfig)
+	if err != nil {
+		return err
+	}
+	files := map[string]string{
+		"live.ign":        inst.liveIgnition.String(),
+		"dest.ign":        targetConfig,
+		"installer.yaml":  installerData,
+		"pre-install.sh":  customizePreInstall,
+		"post-install.sh": customizePostInstall,
+	}
+	for nmName, nmContents := range inst.NmKeyfiles {
+		files[nmName] = nmContents
+		args = append(args, "--network-keyfile", filepath.Join(tempdir, nmName))
+	}
+	if len(inst.NmKeyfiles) > 0 {
+		// force networking on in the initrd to verify the keyfile was used
+		inst.kargs = append(inst.kargs, "rd.neednet=1")
+	}
+	for _, karg := range inst.kargs {
+		args = append(args, "--live-karg-append", karg)
+	}
+	for name, contents := range files {
+		if err := os.WriteFile(filepath.Join(tempdir, name), []byte(contents), 0600); err != nil {
+			return err
+		}
+	}
+
+	args = append(args,
+		"--live-ignition", filepath.Join(tempdir, "live.ign"),
+		"--dest-ignition", filepath.Join(tempdir, "dest.ign"),
+		"--installer-config", filepath.Join(tempdir, "installer.yaml"),
+		"--pre-install", filepath.Join(tempdir, "pre-install.sh"),
+		"--post-install", filepath.Join(tempdir, "post-install.sh"),
+		isopath)
+	argv := HostInstaller.Argv(args...)
+	if _, err := inst.runner().Run(argv[0], argv[1:]...); err != nil {
+		return errors.Wrapf(err, "customizing iso")
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package osbuild
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/coreos/coreos-assembler/mantle/platform"
+	"github.com/coreos/coreos-assembler/mantle/platform/conf"
+)
+
+// bootChannel is the virtio-serial port the booted system writes its
+// state to.
+const bootChannel = "osbuildboot"
+
+var bootCheckUnit = fmt.Sprintf(`[Unit]
+Description=OSBuild Boot Check
+Requires=dev-virtio\\x2dports-%[1]s.device
+After=dev-virtio\\x2dports-%[1]s.device
+[Service]
+Type=simple
+ExecStart=/bin/sh -c 'echo "$(systemctl is-system-running --wait)" >/dev/virtio-ports/%[1]s'
+[Install]
+WantedBy=multi-user.target
+`, bootChannel)
+
+// Boot boots the image with the builder and config, and waits for the
+// system to finish booting. It fails if Ignition fails, the system is
+// degraded or doesn't boot within the timeout. The config is injected
+// into metal images, which don't fetch it from QEMU.
+func (img *Image) Boot(ctx context.Context, builder *platform.QemuBuilder, config *conf.Conf, timeout time.Duration) error {
+	disk := platform.Disk{
+		BackingFile:   img.Path,
+		BackingFormat: Platforms[img.Platform],
+	}
+	if img.Platform == "metal4k" {
+		disk.SectorSize = 4096
+	}
+	if img.Platform != "qemu" {
+		builder.ForceConfigInjection = true
+	}
+	if err := builder.AddBootDisk(&disk); err != nil {
+		return err
+	}
+	stateChannel, err := builder.VirtioChannelRead(bootChannel)
+	if err != nil {
+		return err
+	}
+	config.AddSystemdUnit("osbuild-boot-check.service", bootCheckUnit, conf.Enable)
+	builder.SetConfig(config)
+
+	inst, err := builder.Exec()
+	if err != nil {
+		return err
+	}
+	defer inst.Destroy()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	errc := make(chan error, 3)
+	go func() {
+		errBuf, err := inst.WaitIgnitionError(ctx)
+		if err == nil && errBuf != "" {
+			err = platform.ErrInitramfsEmergency
+		}
+		if err != nil {
+			errc <- err
+		}
+	}()
+	go func() {
+		err := inst.Wait()
+		if err == nil && inst.Signaled() {
+			err = fmt.Errorf("process killed")
+		}
+		errc <- fmt.Errorf("QEMU exited before the system booted: %v", err)
+	}()
+	go func() {
+		line, err := bufio.NewReader(stateChannel).ReadString('\n')
+		if err != nil {
+			errc <- fmt.Errorf("reading system state: %v", err)
+			return
+		}
+		if state := strings.TrimSpace(line); state != "running" {
+			errc <- fmt.Errorf("system is %s after booting", state)
+			return
+		}
+		errc <- nil
+	}()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("timed out after %v waiting for the system to boot", timeout)
+	}
+}
@@ -0,0 +1,299 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package osbuild builds disk images from the osbuild manifests of a
+// build, as cosa osbuild does, and boots them, so that changes to the
+// manifests are caught by kola rather than only by full builds.
+package osbuild
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	coreosarch "github.com/coreos/stream-metadata-go/arch"
+
+	"github.com/coreos/coreos-assembler/mantle/system/exec"
+	"github.com/coreos/coreos-assembler/mantle/util"
+)
+
+const (
+	// DefaultManifestDir is where cosa installs its osbuild manifests
+	DefaultManifestDir = "/usr/lib/coreos-assembler/osbuild-manifests"
+
+	// DefaultImageSizeMB is the size of images if the config doesn't
+	// give one, the default size of cloud images in image.yaml
+	DefaultImageSizeMB = 10240
+
+	// DefaultTimeout is how long osbuild-mpp and osbuild may each run
+	// by default
+	DefaultTimeout = time.Hour
+
+	// nonRootPartitionsMB is the size of the partitions before the root
+	// filesystem, as cmd-osbuild sizes metal images with
+	nonRootPartitionsMB = 513
+)
+
+// Platforms are the suffixes of the images of the platforms which can be
+// built and booted with QEMU.
+var Platforms = map[string]string{
+	"qemu":    "qcow2",
+	"metal":   "raw",
+	"metal4k": "raw",
+}
+
+// PlatformNames returns the names of Platforms, sorted.
+func PlatformNames() []string {
+	var names []string
+	for name := range Platforms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Config is what the manifests of a build are preprocessed with, as the
+// tmp/runvm-osbuild-config-<build>.json cmd-osbuild writes for
+// runvm-osbuild.
+type Config struct {
+	ArtifactNamePrefix string `json:"artifact-name-prefix"`
+	BuildVersion       string `json:"build-version"`
+	ContainerImgref    string `json:"container-imgref"`
+	ContainerRepo      string `json:"container-repo,omitempty"`
+	ContainerTag       string `json:"container-tag,omitempty"`
+	DeployViaContainer string `json:"deploy-via-container"`
+	OSName             string `json:"osname"`
+	OstreeContainer    string `json:"ostree-container"`
+	OstreeRef          string `json:"ostree-ref"`
+	OstreeRepo         string `json:"ostree-repo"`
+	ExtraKargs         string `json:"extra-kargs-string"`
+	MetalImageSize     string `json:"metal-image-size"`
+	CloudImageSize     string `json:"cloud-image-size"`
+	RootfsSize         string `json:"rootfs-size"`
+}
+
+// LoadConfig reads a config written by cmd-osbuild.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Config
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("parsing %s: %v", path, err)
+	}
+	if c.OstreeContainer == "" || c.OSName == "" {
+		return nil, fmt.Errorf("%s lacks the ostree-container or osname", path)
+	}
+	return &c, nil
+}
+
+// ConfigFromBuild returns the config cmd-osbuild would give the build,
+// deploying its OSTree container. Without the OSTree repo the size of
+// the tree can't be estimated, so images are DefaultImageSizeMB, which
+// fits current builds.
+func ConfigFromBuild(build *util.LocalBuild) (*Config, error) {
+	ociarchive, err := build.RequireArtifact("ostree")
+	if err != nil {
+		return nil, err
+	}
+	size := strconv.Itoa(DefaultImageSizeMB)
+	return &Config{
+		ArtifactNamePrefix: fmt.Sprintf("%s-%s", build.Meta.Name, build.Meta.BuildID),
+		BuildVersion:       build.Meta.BuildID,
+		ContainerImgref:    "ostree-image-signed:oci-archive:/" + filepath.Base(ociarchive),
+		OSName:             build.Meta.Name,
+		OstreeContainer:    ociarchive,
+		MetalImageSize:     size,
+		CloudImageSize:     size,
+		RootfsSize:         strconv.Itoa(DefaultImageSizeMB - nonRootPartitionsMB),
+	}, nil
+}
+
+// defines returns the variables osbuild-mpp preprocesses the manifests
+// with, as runvm-osbuild sets them.
+func (c *Config) defines(arch string) []string {
+	str := func(name, value string) string {
+		// osbuild-mpp parses the values as JSON
+		data, _ := json.Marshal(value)
+		return name + "=" + string(data)
+	}
+	prefix := c.ArtifactNamePrefix
+	if prefix == "" {
+		prefix = "custom-coreos"
+	}
+	version := c.BuildVersion
+	if version == "" {
+		version = "0"
+	}
+	size := func(name, value string, def int) string {
+		if value == "" {
+			value = strconv.Itoa(def)
+		}
+		return name + "=" + value
+	}
+	// the OSTree repo is only used if not deploying the container
+	var ref, repo string
+	if c.DeployViaContainer == "" && c.OstreeRepo != "" {
+		ref = c.OstreeRef
+		repo = "file://" + c.OstreeRepo
+	}
+	return []string{
+		str("arch", arch),
+		str("artifact_name_prefix", prefix),
+		str("build_version", version),
+		str("ostree_ref", ref),
+		str("ostree_repo", repo),
+		str("ociarchive", c.OstreeContainer),
+		str("osname", c.OSName),
+		str("container_imgref", c.ContainerImgref),
+		str("container_repo", c.ContainerRepo),
+		str("container_tag", c.ContainerTag),
+		str("extra_kargs", c.ExtraKargs),
+		size("metal_image_size_mb", c.MetalImageSize, DefaultImageSizeMB),
+		size("cloud_image_size_mb", c.CloudImageSize, DefaultImageSizeMB),
+		size("rootfs_size_mb", c.RootfsSize, DefaultImageSizeMB-nonRootPartitionsMB),
+	}
+}
+
+// imageName returns the name of the image of the platform, as the
+// manifests export it.
+func (c *Config) imageName(platform, arch string) string {
+	prefix := c.ArtifactNamePrefix
+	if prefix == "" {
+		prefix = "custom-coreos"
+	}
+	return fmt.Sprintf("%s-%s.%s.%s", prefix, platform, arch, Platforms[platform])
+}
+
+// Options are what Build builds an image with.
+type Options struct {
+	// ManifestDir is the directory of the manifests, or if empty,
+	// DefaultManifestDir.
+	ManifestDir string
+	Config      *Config
+	// Platform is the platform whose image is built, of Platforms.
+	Platform string
+	// StoreDir, if set, is osbuild's object store, which can be kept to
+	// reuse its checkpoints across builds; otherwise, a temporary one is
+	// used.
+	StoreDir string
+	// Runner runs osbuild-mpp and osbuild, or if nil, one with
+	// DefaultTimeout. Unless it sets Env, they run in a fixed
+	// environment rather than mantle's.
+	Runner *exec.Runner
+}
+
+// Image is a disk image built by osbuild.
+type Image struct {
+	Platform string
+	Path     string
+	// Manifest is the preprocessed manifest osbuild was run with.
+	Manifest string
+}
+
+// Build preprocesses the manifest of the architecture with the config and
+// runs osbuild to export the image of the platform, which is moved to
+// outdir with the preprocessed manifest. osbuild needs the privileges it
+// has in the cosa container.
+func Build(ctx context.Context, opts Options, outdir string) (*Image, error) {
+	if _, ok := Platforms[opts.Platform]; !ok {
+		return nil, fmt.Errorf("unsupported platform %q; platforms are %s", opts.Platform, strings.Join(PlatformNames(), ", "))
+	}
+	if opts.Config == nil {
+		return nil, fmt.Errorf("no config to build %s with", opts.Platform)
+	}
+	arch := coreosarch.CurrentRpmArch()
+	manifestDir := opts.ManifestDir
+	if manifestDir == "" {
+		manifestDir = DefaultManifestDir
+	}
+	mpp := filepath.Join(manifestDir, fmt.Sprintf("coreos.osbuild.%s.mpp.yaml", arch))
+	if _, err := os.Stat(mpp); err != nil {
+		return nil, fmt.Errorf("finding manifest for %s: %v", arch, err)
+	}
+
+	tmpdir, err := os.MkdirTemp(outdir, "osbuild")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpdir)
+	storeDir := opts.StoreDir
+	if storeDir == "" {
+		storeDir = filepath.Join(tmpdir, "store")
+	}
+	runner := &exec.Runner{Timeout: DefaultTimeout, Log: os.Stderr}
+	if opts.Runner != nil {
+		runner = &exec.Runner{
+			Timeout:   opts.Runner.Timeout,
+			Log:       opts.Runner.Log,
+			AuditFile: opts.Runner.AuditFile,
+			Env:       opts.Runner.Env,
+		}
+	}
+	if runner.Env == nil {
+		runner.Env = environment(tmpdir)
+	}
+
+	manifest := filepath.Join(outdir, "manifest.json")
+	var args []string
+	for _, define := range opts.Config.defines(arch) {
+		args = append(args, "-D", define)
+	}
+	args = append(args, mpp, manifest)
+	if _, err := runner.RunContext(ctx, "osbuild-mpp", args...); err != nil {
+		return nil, fmt.Errorf("preprocessing manifest: %w", err)
+	}
+
+	exportDir := filepath.Join(tmpdir, "out")
+	_, err = runner.RunContext(ctx, "osbuild",
+		"--out", exportDir,
+		"--store", storeDir,
+		"--checkpoint", "deployed-tree",
+		"--checkpoint", "tree",
+		"--checkpoint", "raw-image",
+		"--export", opts.Platform,
+		manifest)
+	if err != nil {
+		return nil, fmt.Errorf("running osbuild: %w", err)
+	}
+
+	name := opts.Config.imageName(opts.Platform, arch)
+	path := filepath.Join(outdir, name)
+	if err := os.Rename(filepath.Join(exportDir, opts.Platform, name), path); err != nil {
+		return nil, fmt.Errorf("finding exported image: %v", err)
+	}
+	return &Image{
+		Platform: opts.Platform,
+		Path:     path,
+		Manifest: manifest,
+	}, nil
+}
+
+// environment returns the environment osbuild runs in, so that builds
+// don't depend on who runs them.
+func environment(tmpdir string) []string {
+	return []string{
+		"PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin",
+		"LANG=C.UTF-8",
+		"HOME=" + tmpdir,
+		"TMPDIR=" + tmpdir,
+	}
+}
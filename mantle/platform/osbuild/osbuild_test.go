@@ -0,0 +1,217 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package osbuild
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	coreosarch "github.com/coreos/stream-metadata-go/arch"
+
+	"github.com/coreos/coreos-assembler/mantle/system/exec"
+)
+
+func TestDefines(t *testing.T) {
+	c := Config{
+		ArtifactNamePrefix: "fedora-coreos-41.20241016.dev.0",
+		BuildVersion:       "41.20241016.dev.0",
+		ContainerImgref:    "ostree-image-signed:oci-archive:/fcos.ociarchive",
+		OSName:             "fedora-coreos",
+		OstreeContainer:    "/srv/builds/fcos.ociarchive",
+		OstreeRef:          "fedora/x86_64/coreos/testing-devel",
+		OstreeRepo:         "/srv/tmp/repo",
+		ExtraKargs:         `mitigations=auto,nosmt quoted="yes"`,
+		MetalImageSize:     "3072",
+		CloudImageSize:     "10240",
+		RootfsSize:         "2559",
+	}
+	defines := c.defines("x86_64")
+	values := make(map[string]string)
+	for _, d := range defines {
+		name, value, ok := strings.Cut(d, "=")
+		if !ok {
+			t.Fatalf("Define without a value: %s", d)
+		}
+		var v interface{}
+		if err := json.Unmarshal([]byte(value), &v); err != nil {
+			t.Errorf("Value of %s isn't JSON: %s", name, value)
+		}
+		values[name] = value
+	}
+	expected := map[string]string{
+		"arch":                `"x86_64"`,
+		"ostree_repo":         `"file:///srv/tmp/repo"`,
+		"ostree_ref":          `"fedora/x86_64/coreos/testing-devel"`,
+		"extra_kargs":         `"mitigations=auto,nosmt quoted=\"yes\""`,
+		"metal_image_size_mb": `3072`,
+		"container_tag":       `""`,
+	}
+	for name, value := range expected {
+		if values[name] != value {
+			t.Errorf("Expected %s=%s, got %s", name, value, values[name])
+		}
+	}
+	if len(defines) != 14 {
+		t.Errorf("Expected 14 defines, got %d", len(defines))
+	}
+	if d := (&Config{}).defines("x86_64"); d[len(d)-1] != "rootfs_size_mb=9727" {
+		t.Errorf("Unexpected default rootfs size: %s", d[len(d)-1])
+	}
+
+	// Deploying the container leaves out the repo
+	c.DeployViaContainer = "1"
+	for _, d := range c.defines("x86_64") {
+		if d == `ostree_repo=""` {
+			return
+		}
+	}
+	t.Errorf("OSTree repo used when deploying the container")
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "runvm-osbuild-config.json")
+	data := `{"artifact-name-prefix": "rhcos-9.6", "osname": "rhcos", "ostree-container": "/srv/rhcos.ociarchive", "metal-image-size": "4096", "unknown": "ignored"}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if c.MetalImageSize != "4096" || c.OSName != "rhcos" {
+		t.Errorf("Unexpected config %+v", c)
+	}
+	if name := c.imageName("metal4k", "s390x"); name != "rhcos-9.6-metal4k.s390x.raw" {
+		t.Errorf("Unexpected image name %s", name)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"osname": "rhcos"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(path); err == nil {
+		t.Errorf("Loaded config without ostree-container")
+	}
+}
+
+// stubs are osbuild-mpp and osbuild which record their arguments and
+// write what the real ones would.
+var stubs = map[string]string{
+	"osbuild-mpp": `#!/bin/sh
+echo "$@" > "$ARGS_DIR/osbuild-mpp"
+for last; do :; done
+echo '{"version": "2"}' > "$last"
+`,
+	"osbuild": `#!/bin/sh
+echo "$@" > "$ARGS_DIR/osbuild"
+env > "$ARGS_DIR/env"
+while [ $# -gt 1 ]; do
+	case "$1" in
+		--out) out=$2; shift;;
+		--export) export=$2; shift;;
+	esac
+	shift
+done
+mkdir -p "$out/$export"
+echo image > "$out/$export/test-$export.$(uname -m | sed 's/^arm64$/aarch64/').qcow2"
+`,
+}
+
+func TestBuild(t *testing.T) {
+	if coreosarch.CurrentRpmArch() != "x86_64" && coreosarch.CurrentRpmArch() != "aarch64" {
+		t.Skip("stubs only name x86_64 and aarch64 images")
+	}
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "bin")
+	manifests := filepath.Join(dir, "manifests")
+	outdir := filepath.Join(dir, "out")
+	for _, d := range []string{bin, manifests, outdir} {
+		if err := os.Mkdir(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, script := range stubs {
+		if err := os.WriteFile(filepath.Join(bin, name), []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mpp := filepath.Join(manifests, "coreos.osbuild."+coreosarch.CurrentRpmArch()+".mpp.yaml")
+	if err := os.WriteFile(mpp, []byte("version: '2'\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The commands are found in mantle's $PATH
+	t.Setenv("PATH", bin+":"+os.Getenv("PATH"))
+	runner := &exec.Runner{
+		AuditFile: filepath.Join(outdir, "commands.log"),
+		Env:       []string{"PATH=" + os.Getenv("PATH"), "ARGS_DIR=" + dir},
+	}
+	opts := Options{
+		ManifestDir: manifests,
+		Config:      &Config{ArtifactNamePrefix: "test", OSName: "fedora-coreos", OstreeContainer: "/fcos.ociarchive"},
+		Platform:    "qemu",
+		Runner:      runner,
+	}
+	img, err := Build(context.Background(), opts, outdir)
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	expected := &Image{
+		Platform: "qemu",
+		Path:     filepath.Join(outdir, "test-qemu."+coreosarch.CurrentRpmArch()+".qcow2"),
+		Manifest: filepath.Join(outdir, "manifest.json"),
+	}
+	if !reflect.DeepEqual(img, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, img)
+	}
+	for _, path := range []string{img.Path, img.Manifest, runner.AuditFile} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Missing output: %v", err)
+		}
+	}
+	args, err := os.ReadFile(filepath.Join(dir, "osbuild"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(args), "--export qemu "+img.Manifest) {
+		t.Errorf("Unexpected osbuild arguments %s", args)
+	}
+	env, err := os.ReadFile(filepath.Join(dir, "env"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(env), "HOME=") {
+		t.Errorf("osbuild didn't run in the runner's environment: %s", env)
+	}
+
+	// The temporary store and exports are removed
+	entries, err := os.ReadDir(outdir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Errorf("Expected only the image, manifest and audit log in the output directory, got %v", entries)
+	}
+
+	opts.Platform = "aws"
+	if _, err := Build(context.Background(), opts, outdir); err == nil {
+		t.Errorf("Built unsupported platform")
+	}
+}
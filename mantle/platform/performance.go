@@ -0,0 +1,296 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package platform
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vincent-petithory/dataurl"
+	"gopkg.in/yaml.v2"
+
+	"github.com/coreos/coreos-assembler/mantle/platform/conf"
+)
+
+const (
+	// RealtimeKernelStamp exists on machines once they've switched to the
+	// realtime kernel.
+	RealtimeKernelStamp = "/var/lib/kola-kernel-rt"
+	realtimeKernelUnit  = "kola-kernel-rt.service"
+	realtimeKernelRepo  = "/etc/yum.repos.d/kola-kernel-rt.repo"
+	tunedProfileUnit    = "kola-tuned-profile.service"
+)
+
+var hugepageSizeRE = regexp.MustCompile(`^[1-9][0-9]*[KMG]$`)
+
+// PerformanceProfile tunes machines for latency-sensitive workloads, like
+// the performance profiles of OpenShift's telco configs: it switches to
+// the realtime kernel, reserves hugepages, isolates CPUs and activates a
+// tuned profile. It's applied by the machines' Ignition configs, so it
+// works on every platform.
+type PerformanceProfile struct {
+	// RealtimeKernel replaces the kernel with kernel-rt on first boot,
+	// after which the machine reboots before it can be logged into. The
+	// packages come from RealtimeKernelRepo if it's set, or the machine's
+	// repos.
+	RealtimeKernel     bool   `json:"realtimeKernel,omitempty"     yaml:"realtimeKernel,omitempty"`
+	RealtimeKernelRepo string `json:"realtimeKernelRepo,omitempty" yaml:"realtimeKernelRepo,omitempty"`
+	// Hugepages of HugepageSize, such as 2M or 1G, are reserved at boot.
+	Hugepages    int    `json:"hugepages,omitempty"    yaml:"hugepages,omitempty"`
+	HugepageSize string `json:"hugepageSize,omitempty" yaml:"hugepageSize,omitempty"`
+	// IsolatedCPUs is a CPU list, such as 2-3,6, isolated from the
+	// scheduler, timer ticks and RCU callbacks.
+	IsolatedCPUs string `json:"isolatedCPUs,omitempty" yaml:"isolatedCPUs,omitempty"`
+	// TunedProfile, if set, is activated with tuned-adm. If TunedConf is
+	// set, it's the tuned.conf the profile is created with.
+	TunedProfile string `json:"tunedProfile,omitempty" yaml:"tunedProfile,omitempty"`
+	TunedConf    string `json:"tunedConf,omitempty"    yaml:"tunedConf,omitempty"`
+}
+
+// LoadPerformanceProfile reads a YAML or JSON profile.
+func LoadPerformanceProfile(path string) (*PerformanceProfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p PerformanceProfile
+	if err := yaml.UnmarshalStrict(data, &p); err != nil {
+		return nil, fmt.Errorf("parsing %s: %v", path, err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return &p, nil
+}
+
+// Validate checks that the profile can be applied.
+func (p *PerformanceProfile) Validate() error {
+	if p.RealtimeKernelRepo != "" && !p.RealtimeKernel {
+		return fmt.Errorf("realtime kernel repo given without the realtime kernel")
+	}
+	if p.Hugepages < 0 {
+		return fmt.Errorf("invalid number of hugepages %d", p.Hugepages)
+	}
+	if p.Hugepages > 0 && p.HugepageSize == "" {
+		return fmt.Errorf("hugepages given without their size")
+	}
+	if p.HugepageSize != "" {
+		if _, err := HugepageSizeKB(p.HugepageSize); err != nil {
+			return err
+		}
+	}
+	if p.IsolatedCPUs != "" {
+		if _, err := ParseCPUList(p.IsolatedCPUs); err != nil {
+			return err
+		}
+	}
+	if p.TunedConf != "" && p.TunedProfile == "" {
+		return fmt.Errorf("tuned.conf given without a tuned profile")
+	}
+	if strings.ContainsAny(p.TunedProfile, "/ ") || p.TunedProfile == "." || p.TunedProfile == ".." {
+		return fmt.Errorf("invalid tuned profile name %q", p.TunedProfile)
+	}
+	return nil
+}
+
+// HugepageSizeKB returns the size of hugepages, such as 2M, in kB, as
+// their sysfs directories are named.
+func HugepageSizeKB(size string) (int, error) {
+	if !hugepageSizeRE.MatchString(size) {
+		return 0, fmt.Errorf("invalid hugepage size %q", size)
+	}
+	n, err := strconv.Atoi(size[:len(size)-1])
+	if err != nil {
+		return 0, fmt.Errorf("invalid hugepage size %q", size)
+	}
+	switch size[len(size)-1] {
+	case 'M':
+		n *= 1024
+	case 'G':
+		n *= 1024 * 1024
+	}
+	return n, nil
+}
+
+// ParseCPUList parses a kernel CPU list, such as 2-3,6, into the sorted
+// CPUs it lists.
+func ParseCPUList(list string) ([]int, error) {
+	seen := make(map[int]bool)
+	var cpus []int
+	for _, r := range strings.Split(strings.TrimSpace(list), ",") {
+		first, last, isRange := strings.Cut(r, "-")
+		start, err := strconv.Atoi(first)
+		if err != nil || start < 0 {
+			return nil, fmt.Errorf("invalid CPU list %q", list)
+		}
+		end := start
+		if isRange {
+			end, err = strconv.Atoi(last)
+			if err != nil || end < start {
+				return nil, fmt.Errorf("invalid CPU list %q", list)
+			}
+		}
+		for cpu := start; cpu <= end; cpu++ {
+			if !seen[cpu] {
+				seen[cpu] = true
+				cpus = append(cpus, cpu)
+			}
+		}
+	}
+	sort.Ints(cpus)
+	return cpus, nil
+}
+
+// MinCPUs returns how many CPUs machines need for the profile: enough to
+// have the highest isolated CPU, and one which isn't isolated for
+// housekeeping.
+func (p *PerformanceProfile) MinCPUs() int {
+	cpus, err := ParseCPUList(p.IsolatedCPUs)
+	if p.IsolatedCPUs == "" || err != nil {
+		return 1
+	}
+	highest := cpus[len(cpus)-1]
+	if len(cpus) == highest+1 {
+		// every CPU up to the highest is isolated
+		return highest + 2
+	}
+	return highest + 1
+}
+
+// KernelArgs returns the kernel arguments the profile boots with.
+func (p *PerformanceProfile) KernelArgs() []string {
+	var args []string
+	if p.HugepageSize != "" {
+		args = append(args, "default_hugepagesz="+p.HugepageSize, "hugepagesz="+p.HugepageSize)
+		if p.Hugepages > 0 {
+			args = append(args, fmt.Sprintf("hugepages=%d", p.Hugepages))
+		}
+	}
+	if p.IsolatedCPUs != "" {
+		for _, arg := range []string{"isolcpus", "nohz_full", "rcu_nocbs"} {
+			args = append(args, arg+"="+p.IsolatedCPUs)
+		}
+	}
+	return args
+}
+
+// realtimeKernelScript replaces each installed kernel package with its
+// kernel-rt counterpart.
+const realtimeKernelScript = `#!/bin/bash
+set -euo pipefail
+remove=()
+install=()
+for pkg in kernel kernel-core kernel-modules kernel-modules-core kernel-modules-extra; do
+    if rpm -q "$pkg" >/dev/null; then
+        remove+=("$pkg")
+        install+=(--install "${pkg/kernel/kernel-rt}")
+    fi
+done
+rpm-ostree override remove "${remove[@]}" "${install[@]}"
+touch ` + RealtimeKernelStamp + `
+systemctl reboot
+`
+
+// Ignition returns the Ignition config applying the profile, which is
+// merged into the machines' configs.
+func (p *PerformanceProfile) Ignition() (*conf.UserData, error) {
+	type file struct {
+		Path     string                 `json:"path"`
+		Mode     int                    `json:"mode"`
+		Contents map[string]interface{} `json:"contents"`
+	}
+	type unit struct {
+		Name     string `json:"name"`
+		Enabled  bool   `json:"enabled"`
+		Contents string `json:"contents"`
+	}
+	var files []file
+	var units []unit
+	addFile := func(path, contents string, mode int) {
+		files = append(files, file{
+			Path:     path,
+			Mode:     mode,
+			Contents: map[string]interface{}{"source": dataurl.EncodeBytes([]byte(contents))},
+		})
+	}
+
+	if p.RealtimeKernel {
+		script := "/usr/local/bin/kola-kernel-rt"
+		addFile(script, realtimeKernelScript, 0755)
+		if p.RealtimeKernelRepo != "" {
+			addFile(realtimeKernelRepo, fmt.Sprintf("[kola-kernel-rt]\nname=kernel-rt\nbaseurl=%s\nenabled=1\ngpgcheck=0\n", p.RealtimeKernelRepo), 0644)
+		}
+		// sshd waits, so that tests start on the realtime kernel
+		units = append(units, unit{
+			Name:    realtimeKernelUnit,
+			Enabled: true,
+			Contents: fmt.Sprintf(`[Unit]
+Description=Switch to the realtime kernel
+ConditionPathExists=!%s
+Wants=network-online.target
+After=network-online.target
+Before=sshd.service
+[Service]
+Type=oneshot
+ExecStart=%s
+[Install]
+WantedBy=multi-user.target
+`, RealtimeKernelStamp, script),
+		})
+	}
+	if p.TunedProfile != "" {
+		if p.TunedConf != "" {
+			addFile(fmt.Sprintf("/etc/tuned/%s/tuned.conf", p.TunedProfile), p.TunedConf, 0644)
+		}
+		units = append(units, unit{
+			Name:    tunedProfileUnit,
+			Enabled: true,
+			Contents: fmt.Sprintf(`[Unit]
+Description=Activate the %s tuned profile
+Requires=tuned.service
+After=tuned.service
+[Service]
+Type=oneshot
+RemainAfterExit=yes
+ExecStart=/usr/sbin/tuned-adm profile %s
+[Install]
+WantedBy=multi-user.target
+`, p.TunedProfile, p.TunedProfile),
+		})
+	}
+
+	config := map[string]interface{}{
+		"ignition": map[string]string{"version": "3.3.0"},
+	}
+	if args := p.KernelArgs(); len(args) > 0 {
+		config["kernelArguments"] = map[string][]string{"shouldExist": args}
+	}
+	if len(files) > 0 {
+		config["storage"] = map[string]interface{}{"files": files}
+	}
+	if len(units) > 0 {
+		config["systemd"] = map[string]interface{}{"units": units}
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	return conf.Ignition(string(data)), nil
+}
@@ -19,19 +19,19 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/coreos/go-semver/semver"
 	"github.com/coreos/pkg/capnslog"
-	"github.com/kballard/go-shellquote"
 	"github.com/pkg/errors"
 	"golang.org/x/crypto/ssh"
 	"golang.org/x/net/context"
 
+	"github.com/coreos/coreos-assembler/mantle/network/journal"
 	"github.com/coreos/coreos-assembler/mantle/platform/conf"
 	"github.com/coreos/coreos-assembler/mantle/util"
 )
@@ -69,6 +69,14 @@ type Machine interface {
 	// SSH runs a single command over a new SSH connection.
 	SSH(cmd string) ([]byte, []byte, error)
 
+	// Upload copies a local file or directory to the machine via
+	// platform.UploadToMachine().
+	Upload(localPath, remotePath string) error
+
+	// Download copies a file or directory from the machine via
+	// platform.DownloadFromMachine().
+	Download(remotePath, localPath string) error
+
 	// Start sets up the journal and performs sanity checks via platform.StartMachine().
 	Start() error
 
@@ -127,6 +135,10 @@ type Cluster interface {
 	// SSHOnTestFailure returns whether the cluster should Manhole into
 	// a machine when a MustSSH call fails
 	SSHOnTestFailure() bool
+
+	// UserDataSecret returns the random secret named name which userdata
+	// templates rendered in the cluster are given.
+	UserDataSecret(name string) string
 }
 
 // Flight represents a group of Clusters within a single platform.
@@ -147,6 +159,9 @@ type Flight interface {
 	// large for the platform
 	ConfigTooLarge(ud conf.UserData) bool
 
+	// Capabilities describes what the platform's machines support.
+	Capabilities() Capabilities
+
 	// Destroy terminates each cluster and frees any other associated
 	// resources.  It should log any failures; since they are not
 	// actionable, it does not return an error.
@@ -158,6 +173,8 @@ type MachineOptions struct {
 	PrimaryDisk               string
 	AdditionalDisks           []string
 	MinMemory                 int
+	MinCPUs                   int
+	NestedVirt                bool
 	MinDiskSize               int
 	AdditionalNics            int
 	AppendKernelArgs          string
@@ -184,6 +201,9 @@ type Options struct {
 	CosaWorkdir   string
 	CosaBuildId   string
 	CosaBuildArch string
+	// CosaBuildsURL, if set, is the URL of the builds directory in an
+	// object store, from which missing artifacts of the build are fetched.
+	CosaBuildsURL string
 
 	UseWarnExitCode77 bool
 
@@ -198,7 +218,37 @@ type Options struct {
 
 	SSHOnTestFailure bool
 
+	// MergeJournals interleaves the journals of the machines of multi-machine
+	// tests into one
+	MergeJournals bool
+
 	ExtendTimeoutPercent uint
+
+	// UserDataURL, if set, is the base URL of UserDataServeAddress, from
+	// which configs too large for a platform's userdata are served to
+	// machines, which are passed pointer configs fetching them instead
+	UserDataURL          string
+	UserDataServeAddress string
+
+	// MaxIgnitionSpec, if set, is the newest Ignition spec version the OS
+	// under test accepts; rendering newer configs fails
+	MaxIgnitionSpec *semver.Version
+
+	// Tags are stamped on the resources created, besides those mantle
+	// always adds; see ResourceTags
+	Tags map[string]string
+	// ResourceLifetime, if set, is how long after their creation
+	// resources are stamped to expire
+	ResourceLifetime time.Duration
+
+	// InstanceProfiles are the instance types of the platform which
+	// machines needing more memory or CPUs than its default type, or
+	// nested virtualization, are given; see BaseCluster.InstanceType
+	InstanceProfiles []InstanceProfile
+
+	// PerformanceProfile, if set, tunes all machines, unless their
+	// cluster has its own
+	PerformanceProfile *PerformanceProfile
 }
 
 // RuntimeConfig contains cluster-specific configuration.
@@ -213,10 +263,31 @@ type RuntimeConfig struct {
 
 	// InternetAccess is true if the cluster should be Internet connected
 	InternetAccess bool
-	EarlyRelease   func()
+	// AuditEgress captures the traffic of machines which support it, so
+	// their attempts to reach the Internet can be checked after they're
+	// destroyed; see EgressAuditor
+	AuditEgress  bool
+	EarlyRelease func()
 
 	// whether a Manhole into a machine should be created on detected failure
 	SSHOnTestFailure bool
+
+	// MergeJournals interleaves the journals of all of the cluster's
+	// machines into journal.txt in OutputDir
+	MergeJournals bool
+	mergedJournal *journal.Merger
+
+	// TestName is the name of the test the cluster is for, which its
+	// resources are tagged with
+	TestName string
+
+	// KernelArgs are added to the kernel command line of the cluster's
+	// machines by Ignition, which reboots them once on first boot, for
+	// when they can't be appended as the machines are created
+	KernelArgs []string
+
+	// PerformanceProfile, if set, tunes the cluster's machines
+	PerformanceProfile *PerformanceProfile
 }
 
 // Wrap a StdoutPipe as a io.ReadCloser
@@ -322,57 +393,6 @@ func InstallFile(in io.Reader, m Machine, to string) error {
 	return nil
 }
 
-// CopyDirToMachine synchronizes the local contents of inputdir to the
-// remote destdir.
-func CopyDirToMachine(inputdir string, m Machine, destdir string) error {
-	out, stderr, err := m.SSH(fmt.Sprintf("sudo mkdir -p %s", shellquote.Join(destdir)))
-	if err != nil {
-		return errors.Wrapf(err, "failed creating directory %s: %q: %s", destdir, out, stderr)
-	}
-
-	client, err := m.SSHClient()
-	if err != nil {
-		return errors.Wrapf(err, "failed creating SSH client")
-	}
-
-	defer client.Close()
-
-	session, err := client.NewSession()
-	if err != nil {
-		return errors.Wrapf(err, "failed creating SSH session")
-	}
-
-	defer session.Close()
-
-	// Use compression level 1 for speed
-	compressArgv := []string{"-c", "tar chf - . | gzip -1"}
-
-	clientCmd := exec.Command("/bin/sh", compressArgv...)
-	clientCmd.Dir = inputdir
-	stdout, err := clientCmd.StdoutPipe()
-	if err != nil {
-		return err
-	}
-	defer stdout.Close()
-	err = clientCmd.Start()
-	if err != nil {
-		return err
-	}
-
-	session.Stdin = stdout
-	out, err = session.CombinedOutput(fmt.Sprintf("sudo tar -xz -C %s -f -", shellquote.Join(destdir)))
-	if err != nil {
-		return errors.Wrapf(err, "executing remote untar: %q", out)
-	}
-
-	err = clientCmd.Wait()
-	if err != nil {
-		return errors.Wrapf(err, "local tar")
-	}
-
-	return nil
-}
-
 // NewMachines spawns n instances in cluster c, with
 // each instance passed the same userdata.
 func NewMachines(c Cluster, userdata *conf.UserData, n int, options MachineOptions) ([]Machine, error) {
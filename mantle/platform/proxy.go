@@ -0,0 +1,106 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package platform
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/vincent-petithory/dataurl"
+
+	"github.com/coreos/coreos-assembler/mantle/platform/conf"
+)
+
+const (
+	// ProxyCAPath is where the CA of a ProxyConfig is installed, from
+	// which coreos-update-ca-trust.service adds it to the trust store on
+	// boot.
+	ProxyCAPath  = "/etc/pki/ca-trust/source/anchors/kola-proxy.pem"
+	proxyDropin  = "/etc/systemd/system.conf.d/10-kola-proxy.conf"
+	proxyEnvFile = "/etc/environment"
+)
+
+// ProxyConfig configures a machine to reach the Internet through a proxy,
+// as behind a corporate one: services get the proxy variables from
+// systemd, and SSH sessions from /etc/environment.
+type ProxyConfig struct {
+	// HTTPProxy and HTTPSProxy are the URLs of the proxies for HTTP and
+	// HTTPS; NoProxy lists the hosts reached directly.
+	HTTPProxy  string
+	HTTPSProxy string
+	NoProxy    string
+	// CACert, if set, is the PEM of a CA the machine trusts, such as that
+	// of a proxy intercepting HTTPS.
+	CACert []byte
+}
+
+// Env returns the proxy variables, in both the lower and upper case which
+// different programs read.
+func (p *ProxyConfig) Env() []string {
+	var env []string
+	add := func(name, value string) {
+		if value != "" {
+			env = append(env, name+"="+value, strings.ToUpper(name)+"="+value)
+		}
+	}
+	add("http_proxy", p.HTTPProxy)
+	add("https_proxy", p.HTTPSProxy)
+	add("no_proxy", p.NoProxy)
+	return env
+}
+
+// Ignition returns the config setting up the proxy.
+func (p *ProxyConfig) Ignition() (*conf.UserData, error) {
+	env := p.Env()
+	if len(env) == 0 {
+		return nil, fmt.Errorf("proxy config has no proxies")
+	}
+	var quoted []string
+	for _, v := range env {
+		quoted = append(quoted, fmt.Sprintf("%q", v))
+	}
+	files := []map[string]interface{}{
+		{
+			"path": proxyDropin,
+			"mode": 0644,
+			"contents": map[string]string{
+				"source": dataurl.EncodeBytes([]byte("[Manager]\nDefaultEnvironment=" + strings.Join(quoted, " ") + "\n")),
+			},
+		},
+		{
+			// pam_env sets it in SSH sessions
+			"path": proxyEnvFile,
+			"append": []map[string]string{{
+				"source": dataurl.EncodeBytes([]byte(strings.Join(env, "\n") + "\n")),
+			}},
+		},
+	}
+	if len(p.CACert) > 0 {
+		files = append(files, map[string]interface{}{
+			"path":     ProxyCAPath,
+			"mode":     0644,
+			"contents": map[string]string{"source": dataurl.EncodeBytes(p.CACert)},
+		})
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		"ignition": map[string]string{"version": "3.0.0"},
+		"storage":  map[string]interface{}{"files": files},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return conf.Ignition(string(data)), nil
+}
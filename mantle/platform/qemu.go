@@ -44,12 +44,15 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/coreos/coreos-assembler/mantle/network/alloc"
 	"github.com/coreos/coreos-assembler/mantle/platform/conf"
+	"github.com/coreos/coreos-assembler/mantle/platform/instrument"
 	"github.com/coreos/coreos-assembler/mantle/util"
 	coreosarch "github.com/coreos/stream-metadata-go/arch"
 	"github.com/digitalocean/go-qemu/qmp"
 
 	"github.com/coreos/coreos-assembler/mantle/system"
+	"github.com/coreos/coreos-assembler/mantle/system/cleanup"
 	"github.com/coreos/coreos-assembler/mantle/system/exec"
 	"github.com/pkg/errors"
 )
@@ -57,6 +60,13 @@ import (
 var (
 	// ErrInitramfsEmergency is the marker error returned upon node blocking in emergency mode in initramfs.
 	ErrInitramfsEmergency = errors.New("entered emergency.target in initramfs")
+
+	// HelperRunner runs the tools which set up instances and installs,
+	// such as qemu-img and coreos-installer. Their output goes to stderr.
+	HelperRunner = &exec.Runner{
+		Timeout: 10 * time.Minute,
+		Log:     os.Stderr,
+	}
 )
 
 // HostForwardPort contains details about port-forwarding for the VM.
@@ -66,6 +76,13 @@ type HostForwardPort struct {
 	GuestPort int
 }
 
+// BindMount is a host directory mounted in a machine over virtiofs.
+type BindMount struct {
+	Source   string
+	Dest     string
+	ReadOnly bool
+}
+
 // QemuMachineOptions is specialized MachineOption struct for QEMU.
 type QemuMachineOptions struct {
 	MachineOptions
@@ -75,6 +92,29 @@ type QemuMachineOptions struct {
 	Firmware            string
 	Nvme                bool
 	Cex                 bool
+	// FirmwareVarsFile, if set, is where the machine's UEFI variable store
+	// is kept, so that it can be booted again with the same variables; see
+	// QemuBuilder.FirmwareVarsFile
+	FirmwareVarsFile string
+	// TapNics are NICs attached to tap devices, besides the one with
+	// user-mode networking
+	TapNics []TapNic
+	// Proxy, if set, configures the machine to reach the Internet through
+	// a proxy
+	Proxy *ProxyConfig
+	// BindMounts are host directories mounted in the machine, which needs
+	// an Ignition config
+	BindMounts []BindMount
+}
+
+// TapNic is a NIC attached to a tap device, such as one on the bridge of
+// a lab network.
+type TapNic struct {
+	Tap *os.File
+	MAC string
+	// MTU, if set, is advertised to the guest, such as 9000 for jumbo
+	// frames; the tap's must match
+	MTU int
 }
 
 // QEMUMachine represents a qemu instance.
@@ -87,12 +127,20 @@ type QEMUMachine interface {
 	RemovePrimaryBlockDevice() error
 }
 
+// DiskHotplugger is implemented by machines which can have disks attached
+// while they're running.
+type DiskHotplugger interface {
+	// HotplugDisk attaches a new empty disk of size, e.g. "5G", returning
+	// its path in the machine.
+	HotplugDisk(size string) (string, error)
+}
+
 // Disk holds the details of a virtual disk.
 type Disk struct {
 	Size              string   // disk image size in bytes, optional suffixes "K", "M", "G", "T" allowed.
 	BackingFile       string   // raw disk image to use.
 	BackingFormat     string   // qcow2, raw, etc.  If unspecified will be autodetected.
-	Channel           string   // virtio (default), nvme, scsi
+	Channel           string   // virtio (default), nvme, scsi, dasd (s390x only)
 	DeviceOpts        []string // extra options to pass to qemu -device. "serial=XXXX" makes disks show up as /dev/disk/by-id/virtio-<serial>
 	DriveOpts         []string // extra options to pass to -drive
 	SectorSize        int      // if not 0, override disk sector size
@@ -106,6 +154,25 @@ type Disk struct {
 	nbdServCmd     exec.Cmd // command to serve the disk
 }
 
+// Path returns the file backing the disk, once it's been added to a
+// builder.
+func (disk *Disk) Path() string {
+	return disk.dstFileName
+}
+
+// diskKeys are the options of qemu disk specs.
+var diskKeys = util.DiskKeys{
+	"channel": true,
+	"4k":      false,
+	"512e":    false,
+	"mpath":   false,
+	"serial":  true,
+	"wwn":     true,
+}
+
+// ParseDisk parses a disk spec, as util.ParseDiskSpec, with the options
+// channel=<virtio|nvme|scsi|dasd>, 4k, 512e, mpath, serial=<serial> and
+// wwn=<integer>.
 func ParseDisk(spec string, allowNoSize bool) (*Disk, error) {
 	var channel string
 	sectorSize := 0
@@ -114,15 +181,21 @@ func ParseDisk(spec string, allowNoSize bool) (*Disk, error) {
 	multipathed := false
 	var wwn uint64
 
-	size, diskmap, err := util.ParseDiskSpec(spec, allowNoSize)
+	d, err := util.ParseDiskSpec(spec, allowNoSize, diskKeys)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse disk spec %q: %w", spec, err)
 	}
 
-	for key, value := range diskmap {
+	for key, value := range d.Options {
 		switch key {
 		case "channel":
-			channel = value
+			switch value {
+			case "virtio", "nvme", "scsi", "dasd":
+				channel = value
+			default:
+				return nil, fmt.Errorf("failed to parse disk spec %q: %w", spec,
+					d.OptionError(key, "invalid channel %q; channels are virtio, nvme, scsi and dasd", value))
+			}
 		case "4k":
 			sectorSize = 4096
 		case "512e":
@@ -136,16 +209,15 @@ func ParseDisk(spec string, allowNoSize bool) (*Disk, error) {
 		case "wwn":
 			wwn, err = strconv.ParseUint(value, 10, 64)
 			if err != nil {
-				return nil, fmt.Errorf("invalid value %s for wwn. Must be an integer", value)
+				return nil, fmt.Errorf("failed to parse disk spec %q: %w", spec,
+					d.OptionError(key, "invalid wwn %q; must be an integer", value))
 			}
-		default:
-			return nil, fmt.Errorf("invalid key %q", key)
 		}
 	}
 
 	sizeStr := ""
-	if size > 0 {
-		sizeStr = fmt.Sprintf("%dG", size)
+	if d.Size > 0 {
+		sizeStr = strconv.FormatInt(d.Size, 10)
 	}
 	return &Disk{
 		Size:              sizeStr,
@@ -173,11 +245,26 @@ type QemuInstance struct {
 	// Helpers are child processes such as nbd or virtiofsd that should be lifecycle bound to qemu
 	helpers            []exec.Cmd
 	hostForwardedPorts []HostForwardPort
+	// pids are of the processes registered for cleanup should the run die
+	pids []int
+	// alloc holds the host resources allocated to the instance
+	alloc *alloc.Allocator
 
 	journalPipe *os.File
 
 	qmpSocket     *qmp.SocketMonitor
 	qmpSocketPath string
+	// hotpluggedDisks counts the disks added by HotplugDisk
+	hotpluggedDisks int
+
+	telemetry *telemetryCollector
+}
+
+// registerProcess registers the started process to be killed should the
+// run die before the instance is destroyed.
+func (inst *QemuInstance) registerProcess(cmd exec.Cmd) {
+	inst.pids = append(inst.pids, cmd.Pid())
+	cleanup.Default.AddProcess(cmd.Pid())
 }
 
 // Signaled returns whether QEMU process was signaled.
@@ -196,6 +283,12 @@ func (inst *QemuInstance) Kill() error {
 	return inst.qemu.Kill()
 }
 
+// HostForwardedPorts returns the ports forwarded from the host to the
+// instance, with the host ports chosen for those requested as 0.
+func (inst *QemuInstance) HostForwardedPorts() []HostForwardPort {
+	return append([]HostForwardPort(nil), inst.hostForwardedPorts...)
+}
+
 // SSHAddress returns the IP address with the forwarded port (host-side).
 func (inst *QemuInstance) SSHAddress() (string, error) {
 	for _, fwdPorts := range inst.hostForwardedPorts {
@@ -292,6 +385,12 @@ func (inst *QemuInstance) WaitAll(ctx context.Context) error {
 
 // Destroy kills the instance and associated sidecar processes.
 func (inst *QemuInstance) Destroy() {
+	if inst.telemetry != nil {
+		if err := inst.telemetry.finish(); err != nil {
+			plog.Errorf("Error writing telemetry for qemu instance %v: %v", inst.Pid(), err)
+		}
+		inst.telemetry = nil
+	}
 	if inst.qmpSocket != nil {
 		inst.qmpSocket.Disconnect() //nolint // Ignore Errors
 		inst.qmpSocket = nil
@@ -317,10 +416,21 @@ func (inst *QemuInstance) Destroy() {
 		}
 	}
 	inst.helpers = nil
+	for _, pid := range inst.pids {
+		cleanup.Default.DoneProcess(pid)
+	}
+	inst.pids = nil
+
+	if inst.alloc != nil {
+		inst.alloc.Close()
+		inst.alloc = nil
+	}
 
 	if inst.tempdir != "" {
 		if err := os.RemoveAll(inst.tempdir); err != nil {
 			plog.Errorf("Error removing tempdir: %v", err)
+		} else {
+			cleanup.Default.DoneDir(inst.tempdir)
 		}
 	}
 }
@@ -445,6 +555,27 @@ func (inst *QemuInstance) RemovePrimaryBlockDevice() (err2 error) {
 	return nil
 }
 
+// HotplugDisk creates an empty disk of size, e.g. "5G", and attaches it to
+// the running instance, returning its path in the guest. The machine's bus
+// must support hotplugging, which the PCIe root bus of q35 and aarch64
+// machines doesn't.
+func (inst *QemuInstance) HotplugDisk(size string) (string, error) {
+	inst.hotpluggedDisks++
+	id := fmt.Sprintf("hotplug%d", inst.hotpluggedDisks)
+	path := filepath.Join(inst.tempdir, id+".qcow2")
+	if _, err := HelperRunner.Run("qemu-img", "create", "-f", "qcow2", "-o", "nocow=on", path, size); err != nil {
+		return "", errors.Wrapf(err, "creating disk %s", id)
+	}
+	driver, _, _ := strings.Cut(virtio(inst.architecture, "blk", ""), ",")
+	if err := inst.addBlockDevice(id, path); err != nil {
+		return "", err
+	}
+	if err := inst.addDevice(driver, id); err != nil {
+		return "", err
+	}
+	return "/dev/disk/by-id/virtio-" + id, nil
+}
+
 // A directory mounted from the host into the guest, via 9p or virtiofs
 type HostMount struct {
 	src      string
@@ -474,6 +605,19 @@ type QemuBuilder struct {
 	Swtpm      bool
 	Pdeathsig  bool
 	Argv       []string
+	// FirmwareCode and FirmwareVars, if set, replace the distro's UEFI
+	// firmware image and the variable store template respectively, e.g.
+	// to boot a custom firmware build
+	FirmwareCode string
+	FirmwareVars string
+	// FirmwareVarsFile, if set, is the UEFI variable store to use in place
+	// rather than a fresh copy of the template, so that boot entries and
+	// other variables persist across instances. It's created from the
+	// template if it doesn't exist.
+	FirmwareVarsFile string
+	// PetitbootImage is the petitboot zImage the opal firmware boots as
+	// its bootloader
+	PetitbootImage string
 
 	// AppendKernelArgs are appended to the bootloader config
 	AppendKernelArgs string
@@ -481,10 +625,20 @@ type QemuBuilder struct {
 	// AppendFirstbootKernelArgs are written to /boot/ignition
 	AppendFirstbootKernelArgs string
 
+	// CrashKernelMiB, if nonzero, is reserved for a kdump kernel with the
+	// crashkernel karg; the guest is given that much more memory so it's
+	// left with MemoryMiB.
+	CrashKernelMiB int
+
 	Hostname string
 
 	InheritConsole bool
 
+	// TelemetryFile, if set along with TelemetryInterval, is where a JSON
+	// timeseries of the instance's resource usage is written on Destroy()
+	TelemetryFile     string
+	TelemetryInterval time.Duration
+
 	iso         *bootIso
 	isoAsDisk   bool
 	primaryDisk *Disk
@@ -493,6 +647,9 @@ type QemuBuilder struct {
 
 	// tempdir holds our temporary files
 	tempdir string
+	// alloc holds the host resources allocated to the instance, which it
+	// takes over
+	alloc *alloc.Allocator
 
 	// ignition is a config object that can be used instead of
 	// ConfigFile.
@@ -500,9 +657,12 @@ type QemuBuilder struct {
 	ignitionSet      bool
 	ignitionRendered bool
 
-	UsermodeNetworking        bool
-	usermodeNetworkingAddr    string
-	RestrictNetworking        bool
+	UsermodeNetworking     bool
+	usermodeNetworkingAddr string
+	RestrictNetworking     bool
+	// CaptureDir, if set, is where the traffic of each user-mode NIC is
+	// captured, as <netdev>.pcap, to audit it with ReadEgressCaptures
+	CaptureDir                string
 	requestedHostForwardPorts []HostForwardPort
 	additionalNics            int
 	netbootP                  string
@@ -552,10 +712,27 @@ func (builder *QemuBuilder) ensureTempdir() error {
 	if err != nil {
 		return err
 	}
+	cleanup.Default.AddDir(tempdir)
 	builder.tempdir = tempdir
 	return nil
 }
 
+// Allocator returns the allocator of the host resources the instance uses,
+// such as forwarded ports, so that they don't collide with those of other
+// instances, including other processes'. They're released when the
+// instance is destroyed.
+func (builder *QemuBuilder) Allocator() (*alloc.Allocator, error) {
+	if builder.alloc != nil {
+		return builder.alloc, nil
+	}
+	a, err := alloc.New(alloc.DefaultDir)
+	if err != nil {
+		return nil, err
+	}
+	builder.alloc = a
+	return a, nil
+}
+
 // SetConfig injects Ignition; this can be used in place of ConfigFile.
 func (builder *QemuBuilder) SetConfig(config *conf.Conf) {
 	if builder.ignitionRendered {
@@ -639,18 +816,44 @@ func (builder *QemuBuilder) AddAdditionalNics(additionalNics int) {
 	builder.additionalNics = additionalNics
 }
 
+// AddTapNic adds a NIC attached to the tap device, which is passed to
+// qemu.
+func (builder *QemuBuilder) AddTapNic(nic TapNic) {
+	// files passed to qemu start at fd 3
+	fd := 3 + len(builder.fds)
+	builder.fds = append(builder.fds, nic.Tap)
+	id := fmt.Sprintf("tap%d", fd)
+	device := fmt.Sprintf("netdev=%s,mac=%s", id, nic.MAC)
+	if nic.MTU > 0 {
+		device += fmt.Sprintf(",host_mtu=%d", nic.MTU)
+	}
+	builder.Append("-netdev", fmt.Sprintf("tap,id=%s,fd=%d", id, fd),
+		"-device", virtio(builder.architecture, "net", device))
+}
+
 func (builder *QemuBuilder) setupNetworking() error {
 	netdev := "user,id=eth0"
 	for i := range builder.requestedHostForwardPorts {
-		address := fmt.Sprintf(":%d", builder.requestedHostForwardPorts[i].HostPort)
-		// Possible race condition between getting the port here and using it
-		// with qemu -- trade off for simpler port management
-		l, err := net.Listen("tcp", address)
-		if err != nil {
-			return err
+		if builder.requestedHostForwardPorts[i].HostPort == 0 {
+			a, err := builder.Allocator()
+			if err != nil {
+				return err
+			}
+			port, err := a.Port()
+			if err != nil {
+				return err
+			}
+			builder.requestedHostForwardPorts[i].HostPort = port
+		} else {
+			// Possible race condition between checking the port here and
+			// using it with qemu -- trade off for simpler port management
+			address := fmt.Sprintf(":%d", builder.requestedHostForwardPorts[i].HostPort)
+			l, err := net.Listen("tcp", address)
+			if err != nil {
+				return err
+			}
+			l.Close()
 		}
-		l.Close()
-		builder.requestedHostForwardPorts[i].HostPort = l.Addr().(*net.TCPAddr).Port
 		netdev += fmt.Sprintf(",hostfwd=tcp:127.0.0.1:%d-:%d",
 			builder.requestedHostForwardPorts[i].HostPort,
 			builder.requestedHostForwardPorts[i].GuestPort)
@@ -692,9 +895,19 @@ func (builder *QemuBuilder) setupNetworking() error {
 	}
 
 	builder.Append("-netdev", netdev, "-device", virtio(builder.architecture, "net", "netdev=eth0"))
+	builder.captureNetdev("eth0")
 	return nil
 }
 
+// captureNetdev captures the netdev's traffic in CaptureDir, if it's set.
+func (builder *QemuBuilder) captureNetdev(id string) {
+	if builder.CaptureDir == "" {
+		return
+	}
+	path := filepath.Join(builder.CaptureDir, id+".pcap")
+	builder.Append("-object", fmt.Sprintf("filter-dump,id=dump-%s,netdev=%s,file=%s", id, id, path))
+}
+
 func (builder *QemuBuilder) setupAdditionalNetworking() error {
 	macCounter := 0
 	netOffset := 30
@@ -704,8 +917,12 @@ func (builder *QemuBuilder) setupAdditionalNetworking() error {
 		macSuffix := fmt.Sprintf("%02x", macCounter)
 
 		netdev := fmt.Sprintf("user,id=eth%s,dhcpstart=10.0.2.%s", idSuffix, netSuffix)
+		if builder.RestrictNetworking {
+			netdev += ",restrict=on"
+		}
 		device := virtio(builder.architecture, "net", fmt.Sprintf("netdev=eth%s,mac=52:55:00:d1:56:%s", idSuffix, macSuffix))
 		builder.Append("-netdev", netdev, "-device", device)
+		builder.captureNetdev("eth" + idSuffix)
 		macCounter++
 	}
 
@@ -740,9 +957,9 @@ func (builder *QemuBuilder) SetSecureExecution(gpgkey string, hostkey string, co
 			if err != nil {
 				return fmt.Errorf("creating hostkey: %v", err)
 			}
-			c := exec.Command("openssl", "req", "-x509", "-sha512", "-nodes", "-days", "1", "-subj", "/C=US/O=IBM/CN=secex",
+			_, err = HelperRunner.Run("openssl", "req", "-x509", "-sha512", "-nodes", "-days", "1", "-subj", "/C=US/O=IBM/CN=secex",
 				"-newkey", "ec", "-pkeyopt", "ec_paramgen_curve:secp521r1", "-out", dummy.Name())
-			if err := c.Run(); err != nil {
+			if err != nil {
 				return fmt.Errorf("generating hostkey: %v", err)
 			}
 			hostkey = dummy.Name()
@@ -773,8 +990,8 @@ func (builder *QemuBuilder) encryptIgnitionConfig() error {
 	}
 	gpgMutex.Lock()
 	defer gpgMutex.Unlock()
-	c := exec.Command("gpg", "--recipient-file", builder.ignitionPubKey, "--yes", "--output", crypted.Name(), "--armor", "--encrypt", builder.ConfigFile)
-	if err := c.Run(); err != nil {
+	_, err = HelperRunner.Run("gpg", "--recipient-file", builder.ignitionPubKey, "--yes", "--output", crypted.Name(), "--armor", "--encrypt", builder.ConfigFile)
+	if err != nil {
 		return fmt.Errorf("encrypting %s: %v", crypted.Name(), err)
 	}
 	builder.ConfigFile = crypted.Name()
@@ -788,6 +1005,86 @@ func (builder *QemuBuilder) MountHost(source, dest string, readonly bool) {
 	builder.hostMounts = append(builder.hostMounts, HostMount{src: source, dest: dest, readonly: readonly})
 }
 
+// Formats of the images AddDirectoryImage packs directories into
+const (
+	DirectoryImageSquashfs = "squashfs"
+	DirectoryImageErofs    = "erofs"
+)
+
+// DirectoryImageDevice returns the path in the guest of the disk
+// AddDirectoryImage attaches with the name.
+func DirectoryImageDevice(name string) string {
+	return "/dev/disk/by-id/virtio-" + name
+}
+
+// NoCloudSeedDevice is the path in the guest of the disk AddNoCloudSeed
+// attaches.
+const NoCloudSeedDevice = "/dev/disk/by-label/cidata"
+
+// AddNoCloudSeed writes a cloud-init NoCloud seed to an ISO 9660 image
+// labeled cidata and attaches it as a read-only disk, for guests which are
+// provisioned by cloud-init rather than, or as well as, Ignition.
+func (builder *QemuBuilder) AddNoCloudSeed(seed *conf.NoCloudSeed) error {
+	if err := builder.ensureTempdir(); err != nil {
+		return err
+	}
+	dir := filepath.Join(builder.tempdir, "nocloud")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return err
+	}
+	if err := seed.WriteDir(dir); err != nil {
+		return errors.Wrapf(err, "writing NoCloud seed")
+	}
+	image := filepath.Join(builder.tempdir, "nocloud.iso")
+	if _, err := HelperRunner.Run("genisoimage", "-quiet", "-output", image, "-volid", "cidata", "-joliet", "-rock", dir); err != nil {
+		return errors.Wrapf(err, "creating NoCloud seed image")
+	}
+	return builder.AddDisk(&Disk{
+		BackingFile:   image,
+		BackingFormat: "raw",
+		DeviceOpts:    []string{"serial=cidata"},
+		DriveOpts:     []string{"readonly=on"},
+	})
+}
+
+// AddDirectoryImage packs a host directory into a read-only filesystem
+// image, squashfs or erofs, and attaches it as a read-only disk with the
+// name as its serial, so that it's DirectoryImageDevice(name) in the
+// guest. erofs images also get the name as their label. Unlike MountHost,
+// this needs no helper process or guest support for virtiofs, and reads
+// are faster, but the directory is copied when the image is packed. Names
+// are at most 20 characters, which is what virtio serials hold.
+func (builder *QemuBuilder) AddDirectoryImage(source, name, format string) error {
+	if len(name) == 0 || len(name) > 20 {
+		return fmt.Errorf("directory image name %q must be 1 to 20 characters", name)
+	}
+	if _, err := os.Stat(source); err != nil {
+		return errors.Wrapf(err, "directory image source")
+	}
+	if err := builder.ensureTempdir(); err != nil {
+		return err
+	}
+	image := filepath.Join(builder.tempdir, fmt.Sprintf("%s.%s", name, format))
+	switch format {
+	case DirectoryImageSquashfs:
+		if _, err := HelperRunner.Run("mksquashfs", source, image, "-noappend", "-all-root", "-quiet"); err != nil {
+			return errors.Wrapf(err, "packing %s", source)
+		}
+	case DirectoryImageErofs:
+		if _, err := HelperRunner.Run("mkfs.erofs", "-L", name, "--all-root", image, source); err != nil {
+			return errors.Wrapf(err, "packing %s", source)
+		}
+	default:
+		return fmt.Errorf("unknown directory image format %q", format)
+	}
+	return builder.AddDisk(&Disk{
+		BackingFile:   image,
+		BackingFormat: "raw",
+		DeviceOpts:    []string{"serial=" + name},
+		DriveOpts:     []string{"readonly=on"},
+	})
+}
+
 // supportsFwCfg if the target system supports injecting
 // Ignition via the qemu -fw_cfg option.
 func (builder *QemuBuilder) supportsFwCfg() bool {
@@ -823,6 +1120,9 @@ func (builder *QemuBuilder) supportsSwtpm() bool {
 	case "s390x":
 		// s390x does not support a backend for TPM
 		return false
+	case "ppc64le":
+		// tpm-spapr is only available on pseries
+		return builder.Firmware != "opal"
 	}
 	return true
 }
@@ -1103,10 +1403,7 @@ func (disk *Disk) prepare(builder *QemuBuilder) error {
 	if disk.Size != "" {
 		imgOpts = append(imgOpts, disk.Size)
 	}
-	qemuImg := exec.Command("qemu-img", imgOpts...)
-	qemuImg.Stderr = os.Stderr
-
-	if err := qemuImg.Run(); err != nil {
+	if _, err := HelperRunner.Run("qemu-img", imgOpts...); err != nil {
 		return err
 	}
 
@@ -1135,7 +1432,45 @@ func (disk *Disk) prepare(builder *QemuBuilder) error {
 	return nil
 }
 
+// eckdBytesPerCylinder is the capacity of one cylinder of an IBM 3390 DASD
+// formatted with 4k blocks: 15 tracks per cylinder, 12 records per track.
+const eckdBytesPerCylinder = 15 * 12 * 4096
+
+// dasdGeometry returns the `-device` options giving a virtio-blk-ccw disk
+// the CHS geometry of an IBM 3390 DASD.  QEMU doesn't emulate DASDs, so the
+// guest still sees a virtio disk, without the DASD driver or its ioctls:
+// fdasd and the s390x-eckd layouts don't work on it.  This only checks that
+// installs cope with the geometry and 4k blocks of a DASD.
+func dasdGeometry(path string) (string, error) {
+	info, err := util.GetImageInfo(path)
+	if err != nil {
+		return "", errors.Wrapf(err, "querying size of %s", path)
+	}
+	cyls := info.VirtualSize / eckdBytesPerCylinder
+	if cyls == 0 {
+		return "", fmt.Errorf("disk too small for DASD geometry: %d bytes", info.VirtualSize)
+	}
+	// The largest 3390 model (3390-54) has 65520 cylinders, which is
+	// also about the limit QEMU accepts.
+	if cyls > 65520 {
+		cyls = 65520
+	}
+	return fmt.Sprintf("cyls=%d,heads=15,secs=12", cyls), nil
+}
+
 func (builder *QemuBuilder) addDiskImpl(disk *Disk, primary bool) error {
+	if disk.Channel == "dasd" {
+		if builder.architecture != "s390x" {
+			return fmt.Errorf("DASD geometry is only supported on s390x")
+		}
+		if disk.MultiPathDisk {
+			return fmt.Errorf("DASD geometry does not support multipath")
+		}
+		// DASDs are always low-level formatted with 4k blocks
+		if disk.SectorSize == 0 {
+			disk.SectorSize = 4096
+		}
+	}
 	if err := disk.prepare(builder); err != nil {
 		return err
 	}
@@ -1149,8 +1484,9 @@ func (builder *QemuBuilder) addDiskImpl(disk *Disk, primary bool) error {
 				return errors.Wrapf(err, "rendering ignition")
 			}
 			requiresInjection := builder.ConfigFile != "" && builder.ForceConfigInjection
-			if requiresInjection || builder.AppendFirstbootKernelArgs != "" || builder.AppendKernelArgs != "" {
-				if err := setupPreboot(builder.architecture, builder.ConfigFile, builder.AppendFirstbootKernelArgs, builder.AppendKernelArgs,
+			kargs := builder.kernelArgs()
+			if requiresInjection || builder.AppendFirstbootKernelArgs != "" || kargs != "" {
+				if err := setupPreboot(builder.architecture, builder.ConfigFile, builder.AppendFirstbootKernelArgs, kargs,
 					disk.dstFileName, disk.SectorSize); err != nil {
 					return errors.Wrapf(err, "ignition injection with guestfs failed")
 				}
@@ -1250,6 +1586,13 @@ func (builder *QemuBuilder) addDiskImpl(disk *Disk, primary bool) error {
 		}
 
 	} else {
+		if channel == "dasd" {
+			geometry, err := dasdGeometry(disk.dstFileName)
+			if err != nil {
+				return err
+			}
+			opts += "," + geometry
+		}
 		if !disk.NbdDisk {
 			// In the non-multipath/nbd case we can just unlink the disk now
 			// and avoid leaking space if we get Ctrl-C'd (though it's best if
@@ -1258,7 +1601,7 @@ func (builder *QemuBuilder) addDiskImpl(disk *Disk, primary bool) error {
 		}
 		disk.dstFileName = ""
 		switch channel {
-		case "virtio":
+		case "virtio", "dasd":
 			builder.Append("-device", virtio(builder.architecture, "blk", fmt.Sprintf("drive=%s%s", id, opts)))
 		case "nvme":
 			builder.Append("-device", fmt.Sprintf("nvme,drive=%s%s", id, opts))
@@ -1351,14 +1694,22 @@ func (builder *QemuBuilder) finalize() {
 	builder.finalized = true
 }
 
+// kernelArgs returns the kargs to append to the bootloader config.
+func (builder *QemuBuilder) kernelArgs() string {
+	if builder.CrashKernelMiB == 0 {
+		return builder.AppendKernelArgs
+	}
+	return strings.TrimSpace(fmt.Sprintf("%s crashkernel=%dM", builder.AppendKernelArgs, builder.CrashKernelMiB))
+}
+
 // Append appends additional arguments for QEMU.
 func (builder *QemuBuilder) Append(args ...string) {
 	builder.Argv = append(builder.Argv, args...)
 }
 
 // baseQemuArgs takes a board and returns the basic qemu
-// arguments needed for the current architecture.
-func baseQemuArgs(arch string, memoryMiB int) ([]string, error) {
+// arguments needed for the current architecture and firmware.
+func baseQemuArgs(arch, firmware string, memoryMiB int) ([]string, error) {
 	// memoryDevice is the object identifier we use for the backing RAM
 	const memoryDevice = "mem"
 
@@ -1367,7 +1718,8 @@ func baseQemuArgs(arch string, memoryMiB int) ([]string, error) {
 	// The machine argument needs to reference our memory device; see below
 	machineArg := "memory-backend=" + memoryDevice
 	accel := "accel=kvm"
-	if _, ok := os.LookupEnv("COSA_NO_KVM"); ok || hostArch != arch {
+	// The PowerNV machine (which is what runs OPAL) can only be emulated
+	if _, ok := os.LookupEnv("COSA_NO_KVM"); ok || hostArch != arch || firmware == "opal" {
 		accel = "accel=tcg"
 		kvm = false
 	}
@@ -1390,11 +1742,20 @@ func baseQemuArgs(arch string, memoryMiB int) ([]string, error) {
 			"-machine", "s390-ccw-virtio," + machineArg,
 		}
 	case "ppc64le":
-		ret = []string{
-			"qemu-system-ppc64",
-			// kvm-type=HV ensures we use bare metal KVM and not "user mode"
-			// https://www.qemu.org/docs/master/system/ppc/pseries.html
-			"-machine", "pseries,kvm-type=HV,ic-mode=xics," + machineArg,
+		if firmware == "opal" {
+			// Bare metal POWER9 running skiboot, like a PowerNV/OpenPOWER
+			// box.  https://www.qemu.org/docs/master/system/ppc/powernv.html
+			ret = []string{
+				"qemu-system-ppc64",
+				"-machine", "powernv9," + machineArg,
+			}
+		} else {
+			ret = []string{
+				"qemu-system-ppc64",
+				// kvm-type=HV ensures we use bare metal KVM and not "user mode"
+				// https://www.qemu.org/docs/master/system/ppc/pseries.html
+				"-machine", "pseries,kvm-type=HV,ic-mode=xics," + machineArg,
+			}
 		}
 	default:
 		return nil, fmt.Errorf("architecture %s not supported for qemu", arch)
@@ -1415,60 +1776,121 @@ func baseQemuArgs(arch string, memoryMiB int) ([]string, error) {
 	return ret, nil
 }
 
+// uefiVarsSize is the size QEMU expects the aarch64 variable store to be.
+const uefiVarsSize = 67108864
+
 func (builder *QemuBuilder) setupUefi(secureBoot bool) error {
+	var code, varsTemplate string
 	switch coreosarch.CurrentRpmArch() {
 	case "x86_64":
 		varsVariant := ""
 		if secureBoot {
 			varsVariant = ".secboot"
 		}
-		varsSrc, err := os.Open(fmt.Sprintf("/usr/share/edk2/ovmf/OVMF_VARS%s.fd", varsVariant))
-		if err != nil {
-			return err
-		}
-		defer varsSrc.Close()
-		vars, err := os.CreateTemp("", "mantle-qemu")
-		if err != nil {
-			return err
-		}
-		if _, err := io.Copy(vars, varsSrc); err != nil {
-			return err
-		}
-		_, err = vars.Seek(0, 0)
-		if err != nil {
-			return err
-		}
-
-		fdset := builder.AddFd(vars)
-		builder.Append("-drive", fmt.Sprintf("file=/usr/share/edk2/ovmf/OVMF_CODE%s.fd,if=pflash,format=raw,unit=0,readonly=on,auto-read-only=off", varsVariant))
-		builder.Append("-drive", fmt.Sprintf("file=%s,if=pflash,format=raw,unit=1,readonly=off,auto-read-only=off", fdset))
-		builder.Append("-machine", "q35")
+		code = fmt.Sprintf("/usr/share/edk2/ovmf/OVMF_CODE%s.fd", varsVariant)
+		varsTemplate = fmt.Sprintf("/usr/share/edk2/ovmf/OVMF_VARS%s.fd", varsVariant)
 	case "aarch64":
 		if secureBoot {
 			return fmt.Errorf("architecture %s doesn't have support for secure boot in kola", coreosarch.CurrentRpmArch())
 		}
-		vars, err := os.CreateTemp("", "mantle-qemu")
-		if err != nil {
-			return err
+		// The variable store starts out empty
+		code = "/usr/share/edk2/aarch64/QEMU_EFI-silent-pflash.raw"
+	default:
+		panic(fmt.Sprintf("Architecture %s doesn't have support for UEFI in qemu.", coreosarch.CurrentRpmArch()))
+	}
+	if builder.FirmwareCode != "" {
+		code = builder.FirmwareCode
+	}
+	if builder.FirmwareVars != "" {
+		varsTemplate = builder.FirmwareVars
+	}
+
+	vars, err := builder.uefiVars(varsTemplate)
+	if err != nil {
+		return errors.Wrapf(err, "setting up UEFI variable store")
+	}
+	fdset := builder.AddFd(vars)
+	builder.Append("-drive", fmt.Sprintf("file=%s,if=pflash,format=raw,unit=0,readonly=on,auto-read-only=off", code))
+	builder.Append("-drive", fmt.Sprintf("file=%s,if=pflash,format=raw,unit=1,readonly=off,auto-read-only=off", fdset))
+	if coreosarch.CurrentRpmArch() == "x86_64" {
+		builder.Append("-machine", "q35")
+	}
+	return nil
+}
+
+// uefiVars opens the UEFI variable store: FirmwareVarsFile if it exists,
+// or else a copy of template, or an empty store if there's none, in
+// FirmwareVarsFile or a temporary file.
+func (builder *QemuBuilder) uefiVars(template string) (*os.File, error) {
+	if builder.FirmwareVarsFile != "" {
+		vars, err := os.OpenFile(builder.FirmwareVarsFile, os.O_RDWR, 0)
+		if err == nil {
+			plog.Debugf("Reusing UEFI variable store %s", builder.FirmwareVarsFile)
+			return vars, nil
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	var vars *os.File
+	var err error
+	if builder.FirmwareVarsFile != "" {
+		vars, err = os.OpenFile(builder.FirmwareVarsFile, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+	} else {
+		vars, err = os.CreateTemp("", "mantle-qemu")
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Don't leave a partial store behind to be reused later
+	fail := func(err error) (*os.File, error) {
+		vars.Close()
+		if builder.FirmwareVarsFile != "" {
+			os.Remove(builder.FirmwareVarsFile)
 		}
-		//67108864 bytes is expected size of the "VARS" by qemu
-		err = vars.Truncate(67108864)
+		return nil, err
+	}
+	if template != "" {
+		varsSrc, err := os.Open(template)
 		if err != nil {
-			return err
+			return fail(err)
 		}
-
-		_, err = vars.Seek(0, 0)
+		defer varsSrc.Close()
+		if _, err := io.Copy(vars, varsSrc); err != nil {
+			return fail(err)
+		}
+	}
+	if coreosarch.CurrentRpmArch() == "aarch64" {
+		// pad the store to the size qemu expects
+		info, err := vars.Stat()
 		if err != nil {
-			return err
+			return fail(err)
 		}
-
-		fdset := builder.AddFd(vars)
-		builder.Append("-drive", "file=/usr/share/edk2/aarch64/QEMU_EFI-silent-pflash.raw,if=pflash,format=raw,unit=0,readonly=on,auto-read-only=off")
-		builder.Append("-drive", fmt.Sprintf("file=%s,if=pflash,format=raw,unit=1,readonly=off,auto-read-only=off", fdset))
-	default:
-		panic(fmt.Sprintf("Architecture %s doesn't have support for UEFI in qemu.", coreosarch.CurrentRpmArch()))
+		if info.Size() < uefiVarsSize {
+			if err := vars.Truncate(uefiVarsSize); err != nil {
+				return fail(err)
+			}
+		}
+	}
+	if _, err := vars.Seek(0, 0); err != nil {
+		return fail(err)
 	}
+	return vars, nil
+}
 
+// setupOpal configures the petitboot bootloader for the PowerNV machine.
+// qemu ships skiboot (OPAL) and loads it by default, but unlike real
+// hardware there's no PNOR flash with petitboot in it, so we have to pass
+// a petitboot zImage as the kernel. It stays loaded across reboots, just
+// like the bootloader on a real machine would.
+func (builder *QemuBuilder) setupOpal() error {
+	if builder.PetitbootImage == "" {
+		return errors.New("cannot use OPAL firmware without a petitboot image")
+	}
+	if _, err := os.Stat(builder.PetitbootImage); err != nil {
+		return errors.Wrapf(err, "accessing petitboot image")
+	}
+	builder.Append("-kernel", builder.PetitbootImage)
 	return nil
 }
 
@@ -1476,7 +1898,7 @@ func (builder *QemuBuilder) setupUefi(secureBoot bool) error {
 // https://github.com/coreos/coreos-installer/pull/341. Can be dropped once
 // that PR is in all the cosa branches we care about.
 func coreosInstallerSupportsISOKargs() (bool, error) {
-	cmd := exec.Command("coreos-installer", "iso", "--help")
+	cmd := HostInstaller.Command("iso", "--help")
 	cmd.Stderr = os.Stderr
 	var outb bytes.Buffer
 	cmd.Stdout = &outb
@@ -1495,9 +1917,7 @@ func (builder *QemuBuilder) setupIso() error {
 	// in the same filesystem as the source so that reflinks (if available)
 	// will work
 	isoEmbeddedPath := filepath.Join(builder.tempdir, "install.iso")
-	cpcmd := exec.Command("cp", "--reflink=auto", builder.iso.path, isoEmbeddedPath)
-	cpcmd.Stderr = os.Stderr
-	if err := cpcmd.Run(); err != nil {
+	if err := stageFile(builder.iso.path, isoEmbeddedPath); err != nil {
 		return errors.Wrapf(err, "copying iso")
 	}
 	// Make it writable so we can modify it
@@ -1512,7 +1932,7 @@ func (builder *QemuBuilder) setupIso() error {
 		if err != nil {
 			return err
 		}
-		instCmd := exec.Command("coreos-installer", "iso", "ignition", "embed", isoEmbeddedPath)
+		instCmd := HostInstaller.Command("iso", "ignition", "embed", isoEmbeddedPath)
 		instCmd.Stdin = configf
 		instCmd.Stderr = os.Stderr
 		if err := instCmd.Run(); err != nil {
@@ -1524,14 +1944,14 @@ func (builder *QemuBuilder) setupIso() error {
 	if kargsSupported, err := coreosInstallerSupportsISOKargs(); err != nil {
 		return err
 	} else if kargsSupported {
-		allargs := fmt.Sprintf("console=%s %s", consoleKernelArgument[coreosarch.CurrentRpmArch()], builder.AppendKernelArgs)
-		instCmdKargs := exec.Command("coreos-installer", "iso", "kargs", "modify", "--append", allargs, isoEmbeddedPath)
+		allargs := fmt.Sprintf("console=%s %s", instrument.SerialConsole(coreosarch.CurrentRpmArch()), builder.kernelArgs())
+		instCmdKargs := HostInstaller.Command("iso", "kargs", "modify", "--append", allargs, isoEmbeddedPath)
 		var stderrb bytes.Buffer
 		instCmdKargs.Stderr = &stderrb
 		if err := instCmdKargs.Run(); err != nil {
 			// Don't make this a hard error if it's just for console; we
 			// may be operating on an old live ISO
-			if len(builder.AppendKernelArgs) > 0 {
+			if len(builder.kernelArgs()) > 0 {
 				return errors.Wrapf(err, "running `coreos-installer iso kargs modify`; old CoreOS ISO?")
 			}
 			// Only actually emit a warning if we expected it to be supported
@@ -1539,7 +1959,7 @@ func (builder *QemuBuilder) setupIso() error {
 			plog.Warningf("running coreos-installer iso kargs modify: %v: %q", err, stderr)
 			plog.Warning("likely targeting an old CoreOS ISO; ignoring...")
 		}
-	} else if len(builder.AppendKernelArgs) > 0 {
+	} else if len(builder.kernelArgs()) > 0 {
 		return fmt.Errorf("coreos-installer does not support appending kernel args")
 	}
 
@@ -1710,7 +2130,7 @@ func (builder *QemuBuilder) Exec() (*QemuInstance, error) {
 		}
 	}()
 
-	argv, err := baseQemuArgs(builder.architecture, builder.MemoryMiB)
+	argv, err := baseQemuArgs(builder.architecture, builder.Firmware, builder.MemoryMiB+builder.CrashKernelMiB)
 	if err != nil {
 		return nil, err
 	}
@@ -1748,6 +2168,18 @@ func (builder *QemuBuilder) Exec() (*QemuInstance, error) {
 		if coreosarch.CurrentRpmArch() != "x86_64" {
 			return nil, fmt.Errorf("unknown firmware: %s", builder.Firmware)
 		}
+	case "slof":
+		// This is the qemu default for pseries, i.e. a PowerVM/KVM guest
+		if builder.architecture != "ppc64le" {
+			return nil, fmt.Errorf("unknown firmware: %s", builder.Firmware)
+		}
+	case "opal":
+		if builder.architecture != "ppc64le" {
+			return nil, fmt.Errorf("unknown firmware: %s", builder.Firmware)
+		}
+		if err := builder.setupOpal(); err != nil {
+			return nil, err
+		}
 	default:
 		return nil, fmt.Errorf("unknown firmware: %s", builder.Firmware)
 	}
@@ -1809,6 +2241,7 @@ func (builder *QemuBuilder) Exec() (*QemuInstance, error) {
 			if err := cmd.Start(); err != nil {
 				return nil, errors.Wrapf(err, "spawing nbd server")
 			}
+			inst.registerProcess(cmd)
 			inst.helpers = append(inst.helpers, cmd)
 		}
 	}
@@ -1854,6 +2287,7 @@ func (builder *QemuBuilder) Exec() (*QemuInstance, error) {
 		if err = inst.swtpm.Start(); err != nil {
 			return nil, err
 		}
+		inst.registerProcess(inst.swtpm)
 		// We need to wait until the swtpm starts up
 		err = util.Retry(10, 500*time.Millisecond, func() error {
 			_, err := os.Stat(swtpmSock)
@@ -1915,6 +2349,7 @@ func (builder *QemuBuilder) Exec() (*QemuInstance, error) {
 			if err := p.Start(); err != nil {
 				return nil, fmt.Errorf("failed to start virtiofsd")
 			}
+			inst.registerProcess(p)
 			virtiofsHelpers[virtiofsdSocket] = p
 		}
 		// Loop waiting for the sockets to appear
@@ -1983,12 +2418,15 @@ func (builder *QemuBuilder) Exec() (*QemuInstance, error) {
 	if err = inst.qemu.Start(); err != nil {
 		return nil, err
 	}
+	inst.registerProcess(inst.qemu)
 
 	plog.Debugf("Started qemu (%v) with args: %v", inst.qemu.Pid(), argv)
 
-	// Transfer ownership of the tempdir
+	// Transfer ownership of the tempdir and allocations
 	inst.tempdir = builder.tempdir
 	builder.tempdir = ""
+	inst.alloc = builder.alloc
+	builder.alloc = nil
 	cleanupInst = false
 
 	// Connect to the QMP socket which allows us to control qemu.  We wait up to 30s
@@ -2009,6 +2447,11 @@ func (builder *QemuBuilder) Exec() (*QemuInstance, error) {
 		return nil, fmt.Errorf("failed to connect over qmp to qemu instance")
 	}
 
+	if builder.TelemetryFile != "" && builder.TelemetryInterval > 0 {
+		inst.telemetry = newTelemetryCollector(&inst, builder.TelemetryFile, builder.TelemetryInterval)
+		inst.telemetry.start()
+	}
+
 	// Hacky code to test https://github.com/openshift/os/pull/1346
 	if timeout, ok := os.LookupEnv("COSA_TEST_CDROM_UNPLUG"); ok {
 		val, err := time.ParseDuration(timeout)
@@ -2051,6 +2494,10 @@ func (builder *QemuBuilder) Exec() (*QemuInstance, error) {
 
 // Close drops all resources owned by the builder.
 func (builder *QemuBuilder) Close() {
+	if builder.alloc != nil {
+		builder.alloc.Close()
+		builder.alloc = nil
+	}
 	if builder.fds == nil {
 		return
 	}
@@ -2060,7 +2507,9 @@ func (builder *QemuBuilder) Close() {
 	builder.fds = nil
 
 	if builder.tempdir != "" {
-		os.RemoveAll(builder.tempdir)
+		if err := os.RemoveAll(builder.tempdir); err == nil {
+			cleanup.Default.DoneDir(builder.tempdir)
+		}
 	}
 }
 
@@ -19,6 +19,8 @@ package platform
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/pkg/errors"
 )
@@ -53,7 +55,12 @@ func (inst *QemuInstance) runQmpCommand(cmd string) ([]byte, error) {
 
 // listDevices used the qmp socket to query which for device and their names.
 func (inst *QemuInstance) listDevices() (*QOMDev, error) {
-	listcmd := `{ "execute": "qom-list", "arguments": { "path": "/machine/peripheral-anon" } }`
+	return inst.listQOMChildren("/machine/peripheral-anon")
+}
+
+// listQOMChildren uses the qmp socket to list the QOM objects under path.
+func (inst *QemuInstance) listQOMChildren(path string) (*QOMDev, error) {
+	listcmd := fmt.Sprintf(`{ "execute": "qom-list", "arguments": { "path": "%s" } }`, path)
 	out, err := inst.runQmpCommand(listcmd)
 	if err != nil {
 		return nil, errors.Wrapf(err, "Running QMP qom-list command")
@@ -99,3 +106,88 @@ func (inst *QemuInstance) deleteBlockDevice(device string) error {
 	}
 	return nil
 }
+
+// addBlockDevice uses the qmp socket to open a qcow2 image as a block node.
+func (inst *QemuInstance) addBlockDevice(node, path string) error {
+	cmd := fmt.Sprintf(`{ "execute": "blockdev-add", "arguments": { "driver": "qcow2", "node-name": "%s", "file": { "driver": "file", "filename": "%s" } } }`,
+		node, path)
+	if _, err := inst.runQmpCommand(cmd); err != nil {
+		return errors.Wrapf(err, "Adding block node %s", node)
+	}
+	return nil
+}
+
+// addDevice uses the qmp socket to attach a block node to the guest as a
+// disk with the node's name as its id and serial.
+func (inst *QemuInstance) addDevice(driver, node string) error {
+	cmd := fmt.Sprintf(`{ "execute": "device_add", "arguments": { "driver": "%s", "drive": "%[2]s", "id": "%[2]s", "serial": "%[2]s" } }`,
+		driver, node)
+	if _, err := inst.runQmpCommand(cmd); err != nil {
+		return errors.Wrapf(err, "Attaching disk %s", node)
+	}
+	return nil
+}
+
+// EmulatorInventory uses the qmp socket to describe the CPUs, devices and
+// TPMs presented to the guest.
+func (inst *QemuInstance) EmulatorInventory() (*EmulatorInventory, error) {
+	inv := EmulatorInventory{Devices: []string{}}
+	// Devices given an id on the command line or when hotplugged are under
+	// peripheral, the rest under peripheral-anon.
+	for _, path := range []string{"/machine/peripheral", "/machine/peripheral-anon"} {
+		devs, err := inst.listQOMChildren(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range devs.Return {
+			if t, ok := strings.CutPrefix(d.Type, "child<"); ok {
+				inv.Devices = append(inv.Devices, strings.TrimSuffix(t, ">"))
+			}
+		}
+	}
+	sort.Strings(inv.Devices)
+
+	out, err := inst.runQmpCommand(`{ "execute": "query-cpus-fast" }`)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Running QMP query-cpus-fast command")
+	}
+	var cpus struct {
+		Return []struct {
+			QOMPath string `json:"qom-path"`
+		} `json:"return"`
+	}
+	if err := json.Unmarshal(out, &cpus); err != nil {
+		return nil, errors.Wrapf(err, "De-serializing QMP query-cpus-fast output")
+	}
+	if len(cpus.Return) > 0 {
+		cmd := fmt.Sprintf(`{ "execute": "qom-get", "arguments": { "path": "%s", "property": "type" } }`, cpus.Return[0].QOMPath)
+		out, err := inst.runQmpCommand(cmd)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Getting type of CPU %s", cpus.Return[0].QOMPath)
+		}
+		var cpuType struct {
+			Return string `json:"return"`
+		}
+		if err := json.Unmarshal(out, &cpuType); err != nil {
+			return nil, errors.Wrapf(err, "De-serializing QMP qom-get output")
+		}
+		inv.CPUType = cpuType.Return
+	}
+
+	out, err = inst.runQmpCommand(`{ "execute": "query-tpm" }`)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Running QMP query-tpm command")
+	}
+	var tpms struct {
+		Return []struct {
+			Model string `json:"model"`
+		} `json:"return"`
+	}
+	if err := json.Unmarshal(out, &tpms); err != nil {
+		return nil, errors.Wrapf(err, "De-serializing QMP query-tpm output")
+	}
+	for _, t := range tpms.Return {
+		inv.TPMModels = append(inv.TPMModels, t.Model)
+	}
+	return &inv, nil
+}
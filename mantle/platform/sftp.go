@@ -0,0 +1,471 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package platform
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+)
+
+// A minimal client for version 3 of the SFTP protocol
+// (draft-ietf-secsh-filexfer-02), which is what OpenSSH speaks. Requests
+// are issued one at a time, except for file writes, which are pipelined.
+
+const (
+	sftpPacketInit    = 1
+	sftpPacketVersion = 2
+	sftpPacketOpen    = 3
+	sftpPacketClose   = 4
+	sftpPacketRead    = 5
+	sftpPacketWrite   = 6
+	sftpPacketSetstat = 9
+	sftpPacketOpendir = 11
+	sftpPacketReaddir = 12
+	sftpPacketMkdir   = 14
+	sftpPacketStat    = 17
+	sftpPacketStatus  = 101
+	sftpPacketHandle  = 102
+	sftpPacketData    = 103
+	sftpPacketName    = 104
+	sftpPacketAttrs   = 105
+
+	sftpOpenRead  = 0x1
+	sftpOpenWrite = 0x2
+	sftpOpenCreat = 0x8
+	sftpOpenTrunc = 0x10
+
+	sftpAttrSize        = 0x1
+	sftpAttrUIDGID      = 0x2
+	sftpAttrPermissions = 0x4
+	sftpAttrACModTime   = 0x8
+	sftpAttrExtended    = 0x80000000
+
+	sftpStatusOK  = 0
+	sftpStatusEOF = 1
+
+	// sftpMaxPacket bounds the packets we accept, well above the 32KiB of
+	// data plus headers that sftp-server sends.
+	sftpMaxPacket = 256 * 1024
+
+	// sftpMaxInFlight bounds the writes sent ahead of their replies, as
+	// the -R option of OpenSSH's sftp does and with the same default.
+	// Waiting for each reply in turn limits a transfer to one chunk per
+	// round trip.
+	sftpMaxInFlight = 64
+)
+
+// sftpServerCommand runs the server with root privileges, so files can be
+// written anywhere as they can with InstallFile.
+const sftpServerCommand = `sudo sh -c 'for s in /usr/libexec/openssh/sftp-server /usr/lib/openssh/sftp-server; do [ -x "$s" ] && exec "$s"; done; exit 127'`
+
+type sftpStatusError struct {
+	code    uint32
+	message string
+}
+
+func (e *sftpStatusError) Error() string {
+	return fmt.Sprintf("sftp: %s (status %d)", e.message, e.code)
+}
+
+// sftpAttrs holds the file attributes we care about.
+type sftpAttrs struct {
+	size uint64
+	mode uint32
+}
+
+func (a sftpAttrs) isDir() bool {
+	return a.mode&0170000 == 0040000
+}
+
+func (a sftpAttrs) isRegular() bool {
+	return a.mode&0170000 == 0100000
+}
+
+func (a sftpAttrs) isSymlink() bool {
+	return a.mode&0170000 == 0120000
+}
+
+type sftpDirEntry struct {
+	name  string
+	attrs sftpAttrs
+}
+
+// sftpPacket builds the payload of a request.
+type sftpPacket []byte
+
+func (p sftpPacket) uint32(v uint32) sftpPacket {
+	return binary.BigEndian.AppendUint32(p, v)
+}
+
+func (p sftpPacket) uint64(v uint64) sftpPacket {
+	return binary.BigEndian.AppendUint64(p, v)
+}
+
+func (p sftpPacket) string(s string) sftpPacket {
+	return append(p.uint32(uint32(len(s))), s...)
+}
+
+func (p sftpPacket) bytes(b []byte) sftpPacket {
+	return append(p.uint32(uint32(len(b))), b...)
+}
+
+func (p sftpPacket) permissions(mode uint32) sftpPacket {
+	return p.uint32(sftpAttrPermissions).uint32(mode)
+}
+
+// sftpReply parses the payload of a reply, recording the first error.
+type sftpReply struct {
+	b   []byte
+	err error
+}
+
+func (r *sftpReply) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if len(r.b) < n {
+		r.err = errors.New("sftp: short packet")
+		return nil
+	}
+	b := r.b[:n]
+	r.b = r.b[n:]
+	return b
+}
+
+func (r *sftpReply) uint32() uint32 {
+	if b := r.next(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *sftpReply) uint64() uint64 {
+	if b := r.next(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (r *sftpReply) string() string {
+	n := r.uint32()
+	return string(r.next(int(n)))
+}
+
+func (r *sftpReply) attrs() sftpAttrs {
+	var a sftpAttrs
+	flags := r.uint32()
+	if flags&sftpAttrSize != 0 {
+		a.size = r.uint64()
+	}
+	if flags&sftpAttrUIDGID != 0 {
+		r.next(8)
+	}
+	if flags&sftpAttrPermissions != 0 {
+		a.mode = r.uint32()
+	}
+	if flags&sftpAttrACModTime != 0 {
+		r.next(8)
+	}
+	if flags&sftpAttrExtended != 0 {
+		for n := r.uint32(); n > 0 && r.err == nil; n-- {
+			r.string()
+			r.string()
+		}
+	}
+	return a
+}
+
+type sftpClient struct {
+	session *ssh.Session
+	w       io.WriteCloser
+	r       *bufio.Reader
+	id      uint32
+}
+
+// newSFTPClient starts sftp-server over client. It fails if the machine
+// has no sftp-server.
+func newSFTPClient(client *ssh.Client) (*sftpClient, error) {
+	session, err := client.NewSession()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed creating SSH session")
+	}
+	w, err := session.StdinPipe()
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	r, err := session.StdoutPipe()
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	if err := session.Start(sftpServerCommand); err != nil {
+		session.Close()
+		return nil, errors.Wrapf(err, "starting sftp-server")
+	}
+	c := &sftpClient{session: session, w: w, r: bufio.NewReader(r)}
+
+	if err := c.send(sftpPacketInit, sftpPacket{}.uint32(3)); err != nil {
+		c.Close()
+		return nil, err
+	}
+	typ, _, err := c.recv()
+	if err != nil {
+		c.Close()
+		return nil, errors.Wrapf(err, "starting sftp-server")
+	}
+	if typ != sftpPacketVersion {
+		c.Close()
+		return nil, fmt.Errorf("sftp: unexpected packet type %d during handshake", typ)
+	}
+	return c, nil
+}
+
+// Close stops the server.
+func (c *sftpClient) Close() error {
+	c.w.Close()
+	// sftp-server exits non-zero if the connection is cut short, which
+	// is of no interest to us.
+	_ = c.session.Wait()
+	return c.session.Close()
+}
+
+func (c *sftpClient) send(typ byte, payload sftpPacket) error {
+	pkt := sftpPacket{}.uint32(uint32(1 + len(payload)))
+	pkt = append(pkt, typ)
+	pkt = append(pkt, payload...)
+	_, err := c.w.Write(pkt)
+	return err
+}
+
+func (c *sftpClient) recv() (byte, *sftpReply, error) {
+	var hdr [4]byte
+	if _, err := io.ReadFull(c.r, hdr[:]); err != nil {
+		return 0, nil, err
+	}
+	n := binary.BigEndian.Uint32(hdr[:])
+	if n < 1 || n > sftpMaxPacket {
+		return 0, nil, fmt.Errorf("sftp: invalid packet length %d", n)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(c.r, b); err != nil {
+		return 0, nil, err
+	}
+	return b[0], &sftpReply{b: b[1:]}, nil
+}
+
+// request sends a request and returns the type and the payload of its
+// reply, past the request ID.
+func (c *sftpClient) request(typ byte, payload sftpPacket) (byte, *sftpReply, error) {
+	c.id++
+	if err := c.send(typ, append(sftpPacket{}.uint32(c.id), payload...)); err != nil {
+		return 0, nil, err
+	}
+	rtyp, reply, err := c.recv()
+	if err != nil {
+		return 0, nil, err
+	}
+	if id := reply.uint32(); reply.err == nil && id != c.id {
+		return 0, nil, fmt.Errorf("sftp: got reply to request %d, expected %d", id, c.id)
+	}
+	return rtyp, reply, reply.err
+}
+
+// sftpStatus converts a status reply into an error; any other reply is
+// unexpected.
+func sftpStatus(typ byte, reply *sftpReply) error {
+	if typ != sftpPacketStatus {
+		return fmt.Errorf("sftp: unexpected packet type %d", typ)
+	}
+	code := reply.uint32()
+	message := reply.string()
+	if reply.err != nil {
+		return reply.err
+	}
+	if code == sftpStatusOK {
+		return nil
+	}
+	if code == sftpStatusEOF {
+		return io.EOF
+	}
+	return &sftpStatusError{code: code, message: message}
+}
+
+func (c *sftpClient) simple(typ byte, payload sftpPacket) error {
+	rtyp, reply, err := c.request(typ, payload)
+	if err != nil {
+		return err
+	}
+	return sftpStatus(rtyp, reply)
+}
+
+func (c *sftpClient) handle(typ byte, payload sftpPacket) (string, error) {
+	rtyp, reply, err := c.request(typ, payload)
+	if err != nil {
+		return "", err
+	}
+	if rtyp != sftpPacketHandle {
+		return "", sftpStatus(rtyp, reply)
+	}
+	h := reply.string()
+	return h, reply.err
+}
+
+// Stat returns the attributes of path, following symlinks.
+func (c *sftpClient) Stat(path string) (sftpAttrs, error) {
+	rtyp, reply, err := c.request(sftpPacketStat, sftpPacket{}.string(path))
+	if err != nil {
+		return sftpAttrs{}, err
+	}
+	if rtyp != sftpPacketAttrs {
+		return sftpAttrs{}, sftpStatus(rtyp, reply)
+	}
+	a := reply.attrs()
+	return a, reply.err
+}
+
+// Mkdir creates the directory path.
+func (c *sftpClient) Mkdir(path string, mode uint32) error {
+	return c.simple(sftpPacketMkdir, sftpPacket{}.string(path).permissions(mode))
+}
+
+// Chmod sets the permissions of path.
+func (c *sftpClient) Chmod(path string, mode uint32) error {
+	return c.simple(sftpPacketSetstat, sftpPacket{}.string(path).permissions(mode))
+}
+
+// Open opens path with the given SSH_FXF_* flags, returning its handle.
+func (c *sftpClient) Open(path string, flags uint32, mode uint32) (string, error) {
+	return c.handle(sftpPacketOpen, sftpPacket{}.string(path).uint32(flags).permissions(mode))
+}
+
+// CloseHandle closes a file or directory handle.
+func (c *sftpClient) CloseHandle(h string) error {
+	return c.simple(sftpPacketClose, sftpPacket{}.string(h))
+}
+
+// WriteFrom writes the contents of r to the file h in chunks of
+// transferChunkSize, keeping up to sftpMaxInFlight writes outstanding.
+func (c *sftpClient) WriteFrom(h string, r io.Reader) error {
+	pending := make(map[uint32]bool)
+	var firstErr error
+	// ack reads the reply to one write. A failed write is remembered
+	// rather than returned, so the replies still in flight are drained
+	// and the connection stays usable.
+	ack := func() error {
+		rtyp, reply, err := c.recv()
+		if err != nil {
+			return err
+		}
+		id := reply.uint32()
+		if reply.err != nil {
+			return reply.err
+		}
+		if !pending[id] {
+			return fmt.Errorf("sftp: got reply to unexpected request %d", id)
+		}
+		delete(pending, id)
+		if err := sftpStatus(rtyp, reply); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		return nil
+	}
+
+	buf := make([]byte, transferChunkSize)
+	var offset uint64
+	for firstErr == nil {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			if len(pending) == sftpMaxInFlight {
+				if err := ack(); err != nil {
+					return err
+				}
+			}
+			c.id++
+			if err := c.send(sftpPacketWrite, sftpPacket{}.uint32(c.id).string(h).uint64(offset).bytes(buf[:n])); err != nil {
+				return err
+			}
+			pending[c.id] = true
+			offset += uint64(n)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		} else if err != nil {
+			firstErr = err
+		}
+	}
+	for len(pending) > 0 {
+		if err := ack(); err != nil {
+			return err
+		}
+	}
+	return firstErr
+}
+
+// Read reads up to n bytes at offset from the file h, returning io.EOF at
+// the end of the file.
+func (c *sftpClient) Read(h string, offset uint64, n uint32) ([]byte, error) {
+	rtyp, reply, err := c.request(sftpPacketRead, sftpPacket{}.string(h).uint64(offset).uint32(n))
+	if err != nil {
+		return nil, err
+	}
+	if rtyp != sftpPacketData {
+		return nil, sftpStatus(rtyp, reply)
+	}
+	data := reply.string()
+	return []byte(data), reply.err
+}
+
+// ReadDir lists the directory path, excluding "." and "..".
+func (c *sftpClient) ReadDir(path string) ([]sftpDirEntry, error) {
+	h, err := c.handle(sftpPacketOpendir, sftpPacket{}.string(path))
+	if err != nil {
+		return nil, err
+	}
+	defer c.CloseHandle(h)
+
+	var entries []sftpDirEntry
+	for {
+		rtyp, reply, err := c.request(sftpPacketReaddir, sftpPacket{}.string(h))
+		if err != nil {
+			return nil, err
+		}
+		if rtyp != sftpPacketName {
+			if err := sftpStatus(rtyp, reply); err != io.EOF {
+				if err == nil {
+					err = fmt.Errorf("sftp: unexpected status listing %s", path)
+				}
+				return nil, err
+			}
+			return entries, nil
+		}
+		for n := reply.uint32(); n > 0 && reply.err == nil; n-- {
+			name := reply.string()
+			reply.string() // longname
+			attrs := reply.attrs()
+			if name != "." && name != ".." {
+				entries = append(entries, sftpDirEntry{name: name, attrs: attrs})
+			}
+		}
+		if reply.err != nil {
+			return nil, reply.err
+		}
+	}
+}
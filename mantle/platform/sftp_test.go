@@ -0,0 +1,117 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package platform
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+)
+
+// fakeSFTPServer answers WRITE and CLOSE requests, storing what's written
+// and failing writes at failOffset.
+type fakeSFTPServer struct {
+	r          io.Reader
+	w          io.Writer
+	failOffset uint64
+	data       []byte
+}
+
+func (s *fakeSFTPServer) serve() {
+	for {
+		var hdr [4]byte
+		if _, err := io.ReadFull(s.r, hdr[:]); err != nil {
+			return
+		}
+		b := make([]byte, binary.BigEndian.Uint32(hdr[:]))
+		if _, err := io.ReadFull(s.r, b); err != nil {
+			return
+		}
+		req := &sftpReply{b: b[1:]}
+		id := req.uint32()
+		code := uint32(sftpStatusOK)
+		if b[0] == sftpPacketWrite {
+			req.string()
+			offset := req.uint64()
+			data := req.string()
+			if offset == s.failOffset {
+				code = 4 // SSH_FX_FAILURE
+			} else {
+				if end := int(offset) + len(data); end > len(s.data) {
+					s.data = append(s.data, make([]byte, end-len(s.data))...)
+				}
+				copy(s.data[offset:], data)
+			}
+		}
+		payload := sftpPacket{}.uint32(id).uint32(code).string("status").string("")
+		pkt := append(sftpPacket{}.uint32(uint32(1+len(payload))), sftpPacketStatus)
+		if _, err := s.w.Write(append(pkt, payload...)); err != nil {
+			return
+		}
+	}
+}
+
+type chanWriter chan []byte
+
+func (w chanWriter) Write(b []byte) (int, error) {
+	w <- append([]byte(nil), b...)
+	return len(b), nil
+}
+
+func newFakeSFTPClient(failOffset uint64) (*sftpClient, *fakeSFTPServer) {
+	reqR, reqW := io.Pipe()
+	replyR, replyW := io.Pipe()
+	// Buffer the replies as an SSH channel would, or the server blocks
+	// on its first reply while the client is still sending.
+	replies := make(chan []byte, 2*sftpMaxInFlight)
+	go func() {
+		for b := range replies {
+			replyW.Write(b)
+		}
+	}()
+	s := &fakeSFTPServer{r: reqR, w: chanWriter(replies), failOffset: failOffset}
+	go s.serve()
+	return &sftpClient{w: reqW, r: bufio.NewReader(replyR)}, s
+}
+
+func TestSFTPWriteFrom(t *testing.T) {
+	// Enough chunks to fill the window several times, with a short
+	// final one.
+	src := make([]byte, (3*sftpMaxInFlight+1)*transferChunkSize+123)
+	for i := range src {
+		src[i] = byte(i * 7)
+	}
+
+	c, s := newFakeSFTPClient(^uint64(0))
+	if err := c.WriteFrom("h", bytes.NewReader(src)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(s.data, src) {
+		t.Errorf("wrote %d bytes that differ from the %d-byte source", len(s.data), len(src))
+	}
+
+	// A failed write is reported once the replies in flight are drained,
+	// leaving the connection usable.
+	c, _ = newFakeSFTPClient(5 * transferChunkSize)
+	err := c.WriteFrom("h", bytes.NewReader(src))
+	if serr, ok := err.(*sftpStatusError); !ok || serr.code != 4 {
+		t.Fatalf("got error %v, expected SSH_FX_FAILURE", err)
+	}
+	if err := c.CloseHandle("h"); err != nil {
+		t.Errorf("connection unusable after failed write: %v", err)
+	}
+}
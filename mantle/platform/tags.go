@@ -0,0 +1,133 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package platform
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The tags, or labels, mantle stamps on the resources it creates. Their
+// keys and values are restricted to what every platform accepts once
+// passed through SanitizeTags.
+const (
+	// TagCreatedBy marks resources created by mantle, with the value
+	// CreatedByMantle.
+	TagCreatedBy    = "created-by"
+	CreatedByMantle = "mantle"
+	// TagBuildID is the ID of the build being tested.
+	TagBuildID = "build-id"
+	// TagTest is the name of the test the resource was created for.
+	TagTest = "kola-test"
+	// TagExpires is when the resource becomes garbage, in seconds since
+	// the epoch.
+	TagExpires = "expires"
+)
+
+// ResourceTags returns the tags to stamp on a resource created for test,
+// if any, with opts: created-by, the build ID and expiry if known, and the
+// user's tags, which can't override the others.
+func ResourceTags(opts *Options, test string) map[string]string {
+	tags := make(map[string]string)
+	if opts != nil {
+		for k, v := range opts.Tags {
+			tags[k] = v
+		}
+		if opts.CosaBuildId != "" {
+			tags[TagBuildID] = opts.CosaBuildId
+		}
+		if opts.ResourceLifetime > 0 {
+			expires := time.Now().Add(opts.ResourceLifetime)
+			tags[TagExpires] = strconv.FormatInt(expires.Unix(), 10)
+		}
+	}
+	if test != "" {
+		tags[TagTest] = test
+	}
+	tags[TagCreatedBy] = CreatedByMantle
+	return tags
+}
+
+// ResourceTags returns the tags to stamp on the resources created for the
+// cluster.
+func (bc *BaseCluster) ResourceTags() map[string]string {
+	return ResourceTags(bc.bf.baseopts, bc.rconf.TestName)
+}
+
+// Expiry returns when the tags say a resource becomes garbage, if they do.
+func Expiry(tags map[string]string) (time.Time, bool) {
+	value, ok := tags[TagExpires]
+	if !ok {
+		return time.Time{}, false
+	}
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		plog.Warningf("Ignoring unparseable %s tag %q: %v", TagExpires, value, err)
+		return time.Time{}, false
+	}
+	return time.Unix(seconds, 0), true
+}
+
+// SanitizeTags returns the tags made acceptable to platforms which only
+// allow labels of up to 63 lowercase letters, digits, dashes and
+// underscores, such as GCP: uppercase letters are lowered, anything else
+// is replaced with underscores, and the result is truncated.
+func SanitizeTags(tags map[string]string) map[string]string {
+	sanitized := make(map[string]string, len(tags))
+	for k, v := range tags {
+		sanitized[sanitizeTag(k)] = sanitizeTag(v)
+	}
+	return sanitized
+}
+
+func sanitizeTag(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		}
+		return '_'
+	}, s)
+	if len(s) > 63 {
+		s = s[:63]
+	}
+	return s
+}
+
+// TagNames returns the tags as the bare names of platforms whose tags have
+// no values, such as DigitalOcean: KEY:VALUE, sanitized with SanitizeTags.
+func TagNames(tags map[string]string) []string {
+	var names []string
+	for k, v := range SanitizeTags(tags) {
+		names = append(names, k+":"+v)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// TagsFromNames is the inverse of TagNames: it maps KEY:VALUE names to KEY
+// and VALUE, and any other name to itself and "".
+func TagsFromNames(names []string) map[string]string {
+	tags := make(map[string]string, len(names))
+	for _, name := range names {
+		k, v, _ := strings.Cut(name, ":")
+		tags[k] = v
+	}
+	return tags
+}
@@ -0,0 +1,241 @@
+// Copyright 2026 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Periodic resource usage sampling of a running qemu instance, so that
+// regressions in boot-time resource consumption can be spotted across builds.
+// Network traffic isn't sampled: with usermode networking qemu proxies it in
+// our namespace, and QMP has no per-NIC counters.
+
+package platform
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// clockTicks is USER_HZ, which is fixed at 100 on all the architectures we support.
+const clockTicks = 100
+
+// BlockSample holds the I/O counters of a single guest block device.
+type BlockSample struct {
+	Device  string `json:"device"`
+	RdBytes uint64 `json:"rd-bytes"`
+	WrBytes uint64 `json:"wr-bytes"`
+	RdOps   uint64 `json:"rd-operations"`
+	WrOps   uint64 `json:"wr-operations"`
+}
+
+// ResourceSample is a single point in a machine's resource timeseries. All
+// counters are cumulative since qemu started.
+type ResourceSample struct {
+	Timestamp  time.Time     `json:"timestamp"`
+	CPUSeconds float64       `json:"cpu-seconds"`
+	RSSBytes   uint64        `json:"rss-bytes"`
+	Disks      []BlockSample `json:"disks,omitempty"`
+}
+
+// ResourceTimeseries is what gets written out for each machine.
+type ResourceTimeseries struct {
+	Interval string           `json:"interval"`
+	Samples  []ResourceSample `json:"samples"`
+}
+
+type qmpBlockStats struct {
+	Return []struct {
+		Device   string `json:"device"`
+		Qdev     string `json:"qdev"`
+		NodeName string `json:"node-name"`
+		Stats    struct {
+			RdBytes uint64 `json:"rd_bytes"`
+			WrBytes uint64 `json:"wr_bytes"`
+			RdOps   uint64 `json:"rd_operations"`
+			WrOps   uint64 `json:"wr_operations"`
+		} `json:"stats"`
+	} `json:"return"`
+}
+
+// telemetryCollector polls a qemu instance until stopped.
+type telemetryCollector struct {
+	inst     *QemuInstance
+	path     string
+	interval time.Duration
+
+	mu      sync.Mutex
+	samples []ResourceSample
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func newTelemetryCollector(inst *QemuInstance, path string, interval time.Duration) *telemetryCollector {
+	return &telemetryCollector{
+		inst:     inst,
+		path:     path,
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+func (t *telemetryCollector) start() {
+	go func() {
+		defer close(t.done)
+		ticker := time.NewTicker(t.interval)
+		defer ticker.Stop()
+		for {
+			t.collect()
+			select {
+			case <-t.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// finish stops polling and writes out the timeseries.  It must be called
+// before the QMP socket is torn down.
+func (t *telemetryCollector) finish() error {
+	close(t.stop)
+	<-t.done
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	buf, err := json.MarshalIndent(ResourceTimeseries{
+		Interval: t.interval.String(),
+		Samples:  t.samples,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(t.path, buf, 0644)
+}
+
+func (t *telemetryCollector) collect() {
+	sample := ResourceSample{Timestamp: time.Now().UTC()}
+	pid := t.inst.Pid()
+	var err error
+	if sample.CPUSeconds, err = procCPUSeconds(pid); err != nil {
+		// Most likely qemu is exiting; nothing worth recording
+		plog.Debugf("telemetry: %v", err)
+		return
+	}
+	if sample.RSSBytes, err = procRSSBytes(pid); err != nil {
+		plog.Debugf("telemetry: %v", err)
+		return
+	}
+	if sample.Disks, err = t.inst.blockSamples(); err != nil {
+		plog.Debugf("telemetry: %v", err)
+	}
+
+	t.mu.Lock()
+	t.samples = append(t.samples, sample)
+	t.mu.Unlock()
+}
+
+// blockSamples queries the per-device I/O counters over QMP.
+func (inst *QemuInstance) blockSamples() ([]BlockSample, error) {
+	out, err := inst.runQmpCommand(`{ "execute": "query-blockstats" }`)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Running QMP query-blockstats command")
+	}
+	var stats qmpBlockStats
+	if err := json.Unmarshal(out, &stats); err != nil {
+		return nil, errors.Wrapf(err, "De-serializing QMP query-blockstats output")
+	}
+	var ret []BlockSample
+	for _, s := range stats.Return {
+		name := s.Qdev
+		if name == "" {
+			name = s.Device
+		}
+		if name == "" {
+			name = s.NodeName
+		}
+		ret = append(ret, BlockSample{
+			Device:  name,
+			RdBytes: s.Stats.RdBytes,
+			WrBytes: s.Stats.WrBytes,
+			RdOps:   s.Stats.RdOps,
+			WrOps:   s.Stats.WrOps,
+		})
+	}
+	return ret, nil
+}
+
+// procCPUSeconds returns the user+system time consumed by a process.
+func procCPUSeconds(pid int) (float64, error) {
+	return readCPUSeconds(fmt.Sprintf("/proc/%d/stat", pid))
+}
+
+// readCPUSeconds parses the user+system time from a /proc/<pid>/stat
+// file.
+func readCPUSeconds(path string) (float64, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	// The command name is in parens and may contain spaces, so
+	// only split after it.
+	s := string(buf)
+	idx := strings.LastIndexByte(s, ')')
+	if idx < 0 {
+		return 0, fmt.Errorf("malformed %s", path)
+	}
+	// Fields after the command start at field 3 (state); utime and stime
+	// are fields 14 and 15.
+	fields := strings.Fields(s[idx+1:])
+	if len(fields) < 13 {
+		return 0, fmt.Errorf("malformed %s", path)
+	}
+	utime, err := strconv.ParseUint(fields[11], 10, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "parsing utime")
+	}
+	stime, err := strconv.ParseUint(fields[12], 10, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "parsing stime")
+	}
+	return float64(utime+stime) / clockTicks, nil
+}
+
+// procRSSBytes returns the resident set size of a process.
+func procRSSBytes(pid int) (uint64, error) {
+	return readRSSBytes(fmt.Sprintf("/proc/%d/statm", pid))
+}
+
+// readRSSBytes parses the resident set size from a /proc/<pid>/statm
+// file.
+func readRSSBytes(path string) (uint64, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(string(buf))
+	if len(fields) < 2 {
+		return 0, fmt.Errorf("malformed %s", path)
+	}
+	pages, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "parsing rss")
+	}
+	return pages * uint64(os.Getpagesize()), nil
+}
@@ -0,0 +1,104 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package platform
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeProcFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "stat")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadCPUSeconds(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		stat    string
+		seconds float64
+		err     bool
+	}{
+		{
+			name:    "qemu",
+			stat:    "4242 (qemu-system-x86) S 1 4242 4242 0 -1 4194624 52143 0 12 0 1234 567 0 0 20 0 5 0 98765 4294967296 262144 18446744073709551615 0 0 0 0 0 4096 0 0 0 0 17 3 0 0 0 0 0\n",
+			seconds: 18.01,
+		},
+		{
+			// The command may contain spaces and parentheses.
+			name:    "odd command",
+			stat:    "7 (a) b (c) R 1 7 7 0 -1 0 0 0 0 0 250 50 0 0 20 0 1 0 1 0 0\n",
+			seconds: 3,
+		},
+		{name: "no command", stat: "7 a R 1\n", err: true},
+		{name: "truncated", stat: "7 (a) R 1 7 7 0 -1 0 0 0 0 0 250\n", err: true},
+		{name: "bad utime", stat: "7 (a) R 1 7 7 0 -1 0 0 0 0 0 x 50 0\n", err: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			seconds, err := readCPUSeconds(writeProcFile(t, tt.stat))
+			if tt.err {
+				if err == nil {
+					t.Errorf("got %v seconds, expected an error", seconds)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if seconds != tt.seconds {
+				t.Errorf("got %v seconds, expected %v", seconds, tt.seconds)
+			}
+		})
+	}
+}
+
+func TestReadRSSBytes(t *testing.T) {
+	page := uint64(os.Getpagesize())
+	for _, tt := range []struct {
+		name  string
+		statm string
+		bytes uint64
+		err   bool
+	}{
+		{name: "qemu", statm: "1048576 262144 4096 2048 0 300000 0\n", bytes: 262144 * page},
+		{name: "truncated", statm: "1048576\n", err: true},
+		{name: "bad rss", statm: "1048576 -1 4096\n", err: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			bytes, err := readRSSBytes(writeProcFile(t, tt.statm))
+			if tt.err {
+				if err == nil {
+					t.Errorf("got %d bytes, expected an error", bytes)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bytes != tt.bytes {
+				t.Errorf("got %d bytes, expected %d", bytes, tt.bytes)
+			}
+		})
+	}
+
+	// Our own process reads as well as fixtures do.
+	if bytes, err := procRSSBytes(os.Getpid()); err != nil || bytes == 0 {
+		t.Errorf("reading our own RSS: %d, %v", bytes, err)
+	}
+}
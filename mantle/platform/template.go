@@ -0,0 +1,109 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package platform
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/coreos/coreos-assembler/mantle/platform/conf"
+)
+
+// UserDataVars are the variables userdata marked with conf.Template is
+// executed with, such as {{.MachineIndex}}. Templates can also call
+// {{secret "name"}}; see UserDataSecret.
+type UserDataVars struct {
+	// MachineIndex counts the configs rendered in the cluster, from 0
+	MachineIndex uint
+	// ClusterID is the unique name of the cluster
+	ClusterID string
+	// PublicIPv4 and PrivateIPv4 are the machine's addresses, as
+	// references resolved on the machine at boot such as Afterburn's
+	// environment variables, or "" on platforms without them
+	PublicIPv4  string
+	PrivateIPv4 string
+	// HostIP is the address machines reach the host running kola at, or
+	// "" if they can't
+	HostIP string
+	// SSHKeys are the public SSH keys kola connects with, in
+	// authorized_keys format
+	SSHKeys []string
+}
+
+// The names of the variables platforms pass RenderUserData, which are
+// substituted as they are in userdata which isn't a template
+const (
+	UserDataPublicIPv4  = "$public_ipv4"
+	UserDataPrivateIPv4 = "$private_ipv4"
+	UserDataHostIP      = "$host_ip"
+)
+
+// executeUserData executes the userdata template with the variables of
+// the next machine of the cluster.
+func (bc *BaseCluster) executeUserData(userdata *conf.UserData, ignitionVars map[string]string) (*conf.UserData, error) {
+	keys, err := bc.bf.Keys()
+	if err != nil {
+		return nil, err
+	}
+	vars := UserDataVars{
+		ClusterID:   bc.Name(),
+		PublicIPv4:  ignitionVars[UserDataPublicIPv4],
+		PrivateIPv4: ignitionVars[UserDataPrivateIPv4],
+		HostIP:      ignitionVars[UserDataHostIP],
+	}
+	for _, key := range keys {
+		vars.SSHKeys = append(vars.SSHKeys, strings.TrimSpace(key.String()))
+	}
+	bc.machlock.Lock()
+	vars.MachineIndex = bc.renderserial
+	bc.renderserial++
+	bc.machlock.Unlock()
+
+	return userdata.Execute(vars, template.FuncMap{
+		"secret": bc.userDataSecret,
+	})
+}
+
+// UserDataSecret returns the random secret templates rendered in the
+// cluster get from {{secret "name"}}, so tests can check that machines
+// were given it, or share it between them.
+func (bc *BaseCluster) UserDataSecret(name string) string {
+	secret, err := bc.userDataSecret(name)
+	if err != nil {
+		panic(err)
+	}
+	return secret
+}
+
+func (bc *BaseCluster) userDataSecret(name string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("secrets need a name")
+	}
+	bc.machlock.Lock()
+	defer bc.machlock.Unlock()
+	if secret, ok := bc.secrets[name]; ok {
+		return secret, nil
+	}
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	secret := hex.EncodeToString(buf)
+	bc.secrets[name] = secret
+	return secret, nil
+}
@@ -0,0 +1,589 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package platform
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kballard/go-shellquote"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	// transferChunkSize is the size of each SFTP read and write; it's
+	// the largest that every SFTP server is required to accept.
+	transferChunkSize = 32 * 1024
+
+	// transferProgressInterval is how often the progress of a long
+	// transfer is logged.
+	transferProgressInterval = 10 * time.Second
+)
+
+// UploadToMachine copies localPath, a file or a directory, to remotePath on
+// m. Directories are copied recursively, following symlinks, and files
+// which already exist are replaced. The copies are owned by root. SFTP is
+// used if the machine has an sftp-server, and tar otherwise; either way,
+// the result is checked against the SHA-256 digests of the local files.
+func UploadToMachine(m Machine, localPath, remotePath string) error {
+	files, err := walkLocal(localPath)
+	if err != nil {
+		return err
+	}
+	remotePath = path.Clean(remotePath)
+
+	client, err := m.SSHClient()
+	if err != nil {
+		return errors.Wrapf(err, "failed creating SSH client")
+	}
+	defer client.Close()
+
+	dir := path.Dir(remotePath)
+	if out, err := runSSHCommand(client, fmt.Sprintf("sudo mkdir -p %s", shellquote.Join(dir))); err != nil {
+		return errors.Wrapf(err, "failed creating directory %s: %q", dir, out)
+	}
+
+	var total int64
+	for _, f := range files {
+		total += f.size
+	}
+	progress := newTransferProgress(fmt.Sprintf("uploading %s to %s", localPath, m.ID()), total)
+	if c, sftpErr := newSFTPClient(client); sftpErr == nil {
+		err = sftpUpload(c, files, localPath, remotePath, progress)
+		c.Close()
+	} else {
+		plog.Debugf("%s: falling back to tar for upload: %v", m.ID(), sftpErr)
+		err = tarUpload(client, files, localPath, remotePath, progress)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "uploading %s to %s", localPath, remotePath)
+	}
+	progress.finish()
+
+	local, err := localChecksums(localPath, files)
+	if err != nil {
+		return err
+	}
+	remote, err := remoteChecksums(client, remotePath)
+	if err != nil {
+		return err
+	}
+	return verifyTransfer(local, remote)
+}
+
+// DownloadFromMachine copies remotePath, a file or a directory, on m to
+// localPath, in the manner of UploadToMachine.
+func DownloadFromMachine(m Machine, remotePath, localPath string) error {
+	remotePath = path.Clean(remotePath)
+
+	client, err := m.SSHClient()
+	if err != nil {
+		return errors.Wrapf(err, "failed creating SSH client")
+	}
+	defer client.Close()
+
+	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+		return err
+	}
+
+	progress := newTransferProgress(fmt.Sprintf("downloading %s from %s", remotePath, m.ID()), -1)
+	if c, sftpErr := newSFTPClient(client); sftpErr == nil {
+		err = sftpDownload(c, remotePath, localPath, progress)
+		c.Close()
+	} else {
+		plog.Debugf("%s: falling back to tar for download: %v", m.ID(), sftpErr)
+		err = tarDownload(client, remotePath, localPath, progress)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "downloading %s to %s", remotePath, localPath)
+	}
+	progress.finish()
+
+	remote, err := remoteChecksums(client, remotePath)
+	if err != nil {
+		return err
+	}
+	files, err := walkLocal(localPath)
+	if err != nil {
+		return err
+	}
+	local, err := localChecksums(localPath, files)
+	if err != nil {
+		return err
+	}
+	return verifyTransfer(remote, local)
+}
+
+// transferFile is a file or directory in a tree being transferred.
+type transferFile struct {
+	rel  string // slash-separated path from the root; "." for the root
+	dir  bool
+	mode os.FileMode
+	size int64
+}
+
+// walkLocal lists the tree at root, parents first, following symlinks.
+func walkLocal(root string) ([]transferFile, error) {
+	var files []transferFile
+	var walk func(rel string) error
+	walk = func(rel string) error {
+		p := filepath.Join(root, filepath.FromSlash(rel))
+		fi, err := os.Stat(p)
+		if err != nil {
+			return err
+		}
+		switch {
+		case fi.IsDir():
+			files = append(files, transferFile{rel: rel, dir: true, mode: fi.Mode().Perm()})
+			entries, err := os.ReadDir(p)
+			if err != nil {
+				return err
+			}
+			for _, e := range entries {
+				if err := walk(path.Join(rel, e.Name())); err != nil {
+					return err
+				}
+			}
+		case fi.Mode().IsRegular():
+			files = append(files, transferFile{rel: rel, mode: fi.Mode().Perm(), size: fi.Size()})
+		default:
+			return fmt.Errorf("%s: unsupported file type %v", p, fi.Mode().Type())
+		}
+		return nil
+	}
+	return files, walk(".")
+}
+
+// transferProgress periodically logs how much of a transfer is done.
+type transferProgress struct {
+	desc  string
+	total int64 // -1 if unknown
+	done  int64
+	start time.Time
+	last  time.Time
+}
+
+func newTransferProgress(desc string, total int64) *transferProgress {
+	now := time.Now()
+	return &transferProgress{desc: desc, total: total, start: now, last: now}
+}
+
+func (p *transferProgress) add(n int) {
+	p.done += int64(n)
+	if time.Since(p.last) < transferProgressInterval {
+		return
+	}
+	p.last = time.Now()
+	if p.total >= 0 {
+		plog.Infof("%s: %.1f of %.1f MiB", p.desc, mib(p.done), mib(p.total))
+	} else {
+		plog.Infof("%s: %.1f MiB", p.desc, mib(p.done))
+	}
+}
+
+func (p *transferProgress) finish() {
+	plog.Debugf("%s: %.1f MiB in %v", p.desc, mib(p.done), time.Since(p.start).Round(time.Millisecond))
+}
+
+func mib(n int64) float64 {
+	return float64(n) / (1 << 20)
+}
+
+type progressReader struct {
+	r io.Reader
+	p *transferProgress
+}
+
+func (pr *progressReader) Read(b []byte) (int, error) {
+	n, err := pr.r.Read(b)
+	pr.p.add(n)
+	return n, err
+}
+
+// sftpFile adapts an open SFTP handle to io.Reader.
+type sftpFile struct {
+	c      *sftpClient
+	h      string
+	offset uint64
+}
+
+func (f *sftpFile) Read(b []byte) (int, error) {
+	data, err := f.c.Read(f.h, f.offset, uint32(min(len(b), transferChunkSize)))
+	n := copy(b, data)
+	f.offset += uint64(n)
+	return n, err
+}
+
+func sftpUpload(c *sftpClient, files []transferFile, localPath, remotePath string, progress *transferProgress) error {
+	for _, f := range files {
+		dst := path.Join(remotePath, f.rel)
+		if f.dir {
+			if err := c.Mkdir(dst, uint32(f.mode)); err != nil {
+				if attrs, statErr := c.Stat(dst); statErr != nil || !attrs.isDir() {
+					return errors.Wrapf(err, "creating %s", dst)
+				}
+			}
+		} else {
+			src := filepath.Join(localPath, filepath.FromSlash(f.rel))
+			if err := sftpUploadFile(c, src, dst, f.mode, progress); err != nil {
+				return err
+			}
+		}
+		// Existing files keep their mode when opened, and new ones are
+		// subject to the umask.
+		if err := c.Chmod(dst, uint32(f.mode)); err != nil {
+			return errors.Wrapf(err, "setting mode of %s", dst)
+		}
+	}
+	return nil
+}
+
+func sftpUploadFile(c *sftpClient, src, dst string, mode os.FileMode, progress *transferProgress) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	h, err := c.Open(dst, sftpOpenWrite|sftpOpenCreat|sftpOpenTrunc, uint32(mode))
+	if err != nil {
+		return errors.Wrapf(err, "opening %s", dst)
+	}
+	err = c.WriteFrom(h, &progressReader{r: in, p: progress})
+	if closeErr := c.CloseHandle(h); err == nil {
+		err = closeErr
+	}
+	return errors.Wrapf(err, "writing %s", dst)
+}
+
+func sftpDownload(c *sftpClient, remotePath, localPath string, progress *transferProgress) error {
+	attrs, err := c.Stat(remotePath)
+	if err != nil {
+		return errors.Wrapf(err, "reading %s", remotePath)
+	}
+	return sftpDownloadEntry(c, remotePath, localPath, attrs, progress)
+}
+
+func sftpDownloadEntry(c *sftpClient, src, dst string, attrs sftpAttrs, progress *transferProgress) error {
+	mode := os.FileMode(attrs.mode).Perm()
+	switch {
+	case attrs.isDir():
+		if err := os.MkdirAll(dst, 0700); err != nil {
+			return err
+		}
+		entries, err := c.ReadDir(src)
+		if err != nil {
+			return errors.Wrapf(err, "listing %s", src)
+		}
+		for _, e := range entries {
+			if strings.Contains(e.name, "/") {
+				return fmt.Errorf("invalid file name %q in %s", e.name, src)
+			}
+			child := path.Join(src, e.name)
+			attrs := e.attrs
+			if attrs.isSymlink() {
+				if attrs, err = c.Stat(child); err != nil {
+					return errors.Wrapf(err, "reading %s", child)
+				}
+			}
+			if err := sftpDownloadEntry(c, child, filepath.Join(dst, e.name), attrs, progress); err != nil {
+				return err
+			}
+		}
+		// Set the mode last, in case it doesn't allow writing
+		return os.Chmod(dst, mode)
+	case attrs.isRegular():
+		h, err := c.Open(src, sftpOpenRead, 0)
+		if err != nil {
+			return errors.Wrapf(err, "opening %s", src)
+		}
+		defer c.CloseHandle(h)
+		return writeLocalFile(dst, mode, &progressReader{r: &sftpFile{c: c, h: h}, p: progress})
+	default:
+		return fmt.Errorf("%s: unsupported file type %#o", src, attrs.mode&0170000)
+	}
+}
+
+// writeLocalFile replaces dst with the contents of r.
+func writeLocalFile(dst string, mode os.FileMode, r io.Reader) error {
+	f, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Chmod(dst, mode)
+}
+
+// tarUpload streams the files through tar, for machines without an SFTP
+// server.
+func tarUpload(client *ssh.Client, files []transferFile, localPath, remotePath string, progress *transferProgress) error {
+	session, err := client.NewSession()
+	if err != nil {
+		return errors.Wrapf(err, "failed creating SSH session")
+	}
+	defer session.Close()
+
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		return err
+	}
+	var out bytes.Buffer
+	session.Stdout = &out
+	session.Stderr = &out
+	if err := session.Start(fmt.Sprintf("sudo tar -x -C %s -f -", shellquote.Join(path.Dir(remotePath)))); err != nil {
+		return err
+	}
+
+	err = writeTar(stdin, files, localPath, path.Base(remotePath), progress)
+	stdin.Close()
+	if waitErr := session.Wait(); waitErr != nil {
+		return errors.Wrapf(waitErr, "executing remote untar: %q", out.String())
+	}
+	return err
+}
+
+// writeTar writes an archive of the files, with top as the name of the
+// root.
+func writeTar(w io.Writer, files []transferFile, localPath, top string, progress *transferProgress) error {
+	tw := tar.NewWriter(w)
+	now := time.Now()
+	for _, f := range files {
+		hdr := &tar.Header{
+			Name:    path.Join(top, f.rel),
+			Mode:    int64(f.mode),
+			ModTime: now,
+		}
+		if f.dir {
+			hdr.Typeflag = tar.TypeDir
+			hdr.Name += "/"
+		} else {
+			hdr.Typeflag = tar.TypeReg
+			hdr.Size = f.size
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if f.dir {
+			continue
+		}
+		in, err := os.Open(filepath.Join(localPath, filepath.FromSlash(f.rel)))
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(tw, &progressReader{r: in, p: progress})
+		in.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+// tarDownload streams the files through tar, for machines without an SFTP
+// server.
+func tarDownload(client *ssh.Client, remotePath, localPath string, progress *transferProgress) error {
+	session, err := client.NewSession()
+	if err != nil {
+		return errors.Wrapf(err, "failed creating SSH session")
+	}
+	defer session.Close()
+
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	var stderr bytes.Buffer
+	session.Stderr = &stderr
+	top := path.Base(remotePath)
+	if err := session.Start(fmt.Sprintf("sudo tar -c -h --hard-dereference -C %s -f - %s", shellquote.Join(path.Dir(remotePath)), shellquote.Join(top))); err != nil {
+		return err
+	}
+
+	err = readTar(&progressReader{r: stdout, p: progress}, top, localPath)
+	if err != nil {
+		// Let tar exit rather than block writing to us
+		_, _ = io.Copy(io.Discard, stdout)
+	}
+	if waitErr := session.Wait(); waitErr != nil {
+		return errors.Wrapf(waitErr, "executing remote tar: %q", stderr.String())
+	}
+	return err
+}
+
+// readTar extracts an archive whose root is named top to localPath.
+func readTar(r io.Reader, top, localPath string) error {
+	type dir struct {
+		path string
+		mode os.FileMode
+	}
+	var dirs []dir
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		name := path.Clean(hdr.Name)
+		rel := "."
+		if name != top {
+			var ok bool
+			if rel, ok = strings.CutPrefix(name, top+"/"); !ok || !filepath.IsLocal(rel) {
+				return fmt.Errorf("unexpected path %q in archive", hdr.Name)
+			}
+		}
+		dst := filepath.Join(localPath, filepath.FromSlash(rel))
+		mode := os.FileMode(hdr.Mode).Perm()
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(dst, 0700); err != nil {
+				return err
+			}
+			dirs = append(dirs, dir{dst, mode})
+		case tar.TypeReg:
+			if err := writeLocalFile(dst, mode, tr); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%s: unsupported file type %q", hdr.Name, hdr.Typeflag)
+		}
+	}
+
+	// Set the mode of directories last, in case it doesn't allow writing
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := os.Chmod(dirs[i].path, dirs[i].mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// localChecksums returns the SHA-256 digests of the regular files, keyed
+// by their path from the root.
+func localChecksums(root string, files []transferFile) (map[string]string, error) {
+	sums := make(map[string]string)
+	for _, f := range files {
+		if f.dir {
+			continue
+		}
+		in, err := os.Open(filepath.Join(root, filepath.FromSlash(f.rel)))
+		if err != nil {
+			return nil, err
+		}
+		h := sha256.New()
+		_, err = io.Copy(h, in)
+		in.Close()
+		if err != nil {
+			return nil, err
+		}
+		sums[f.rel] = hex.EncodeToString(h.Sum(nil))
+	}
+	return sums, nil
+}
+
+// sha256sum escapes file names containing these characters, and marks
+// the line with a leading backslash.
+var sha256sumUnescaper = strings.NewReplacer(`\\`, `\`, `\n`, "\n", `\r`, "\r")
+
+// remoteChecksums returns the SHA-256 digests of the regular files under
+// root on the machine, keyed by their path from the root.
+func remoteChecksums(client *ssh.Client, root string) (map[string]string, error) {
+	session, err := client.NewSession()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed creating SSH session")
+	}
+	defer session.Close()
+
+	var stderr bytes.Buffer
+	session.Stderr = &stderr
+	out, err := session.Output(fmt.Sprintf("sudo find -L %s -type f -exec sha256sum -- {} +", shellquote.Join(root)))
+	if err != nil {
+		return nil, errors.Wrapf(err, "computing checksums of %s: %q", root, stderr.String())
+	}
+
+	sums := make(map[string]string)
+	prefix := strings.TrimSuffix(root, "/") + "/"
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		escaped := strings.HasPrefix(line, `\`)
+		sum, name, ok := strings.Cut(strings.TrimPrefix(line, `\`), " ")
+		if !ok {
+			return nil, fmt.Errorf("unexpected sha256sum output %q", line)
+		}
+		name = strings.TrimPrefix(strings.TrimPrefix(name, " "), "*")
+		if escaped {
+			name = sha256sumUnescaper.Replace(name)
+		}
+		rel := "."
+		if name != root {
+			rel = strings.TrimPrefix(name, prefix)
+		}
+		sums[rel] = sum
+	}
+	return sums, scanner.Err()
+}
+
+// verifyTransfer checks that each file in src made it to dst intact.
+func verifyTransfer(src, dst map[string]string) error {
+	rels := make([]string, 0, len(src))
+	for rel := range src {
+		rels = append(rels, rel)
+	}
+	sort.Strings(rels)
+	for _, rel := range rels {
+		got, ok := dst[rel]
+		if !ok {
+			return fmt.Errorf("%s is missing after transfer", rel)
+		}
+		if got != src[rel] {
+			return fmt.Errorf("checksum mismatch for %s after transfer: expected %s, got %s", rel, src[rel], got)
+		}
+	}
+	return nil
+}
+
+// runSSHCommand runs cmd in a new session on client, returning its
+// combined output.
+func runSSHCommand(client *ssh.Client, cmd string) ([]byte, error) {
+	session, err := client.NewSession()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed creating SSH session")
+	}
+	defer session.Close()
+	return session.CombinedOutput(cmd)
+}
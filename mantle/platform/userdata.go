@@ -0,0 +1,219 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package platform
+
+import (
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/coreos/coreos-assembler/mantle/platform/conf"
+)
+
+// UserDataLimit is the size of the userdata a platform accepts.
+type UserDataLimit struct {
+	// MaxSize is the largest userdata accepted in bytes, or 0 if there's
+	// no limit
+	MaxSize int
+	// Base64 is whether the limit applies to the base64 encoding of the
+	// userdata
+	Base64 bool
+}
+
+func (l UserDataLimit) fits(data string) bool {
+	if l.MaxSize == 0 {
+		return true
+	}
+	size := len(data)
+	if l.Base64 {
+		size = base64.StdEncoding.EncodedLen(size)
+	}
+	return size <= l.MaxSize
+}
+
+// fitUserData returns the serialized config if it fits the limit, or
+// else a config embedding it gzipped if that does, or "" if neither does.
+func fitUserData(config *conf.Conf, limit UserDataLimit) (string, error) {
+	data := config.String()
+	if limit.fits(data) {
+		return data, nil
+	}
+	data, err := config.MaybeCompress()
+	if err != nil {
+		return "", err
+	}
+	if limit.fits(data) {
+		return data, nil
+	}
+	return "", nil
+}
+
+// UserDataTooLarge returns whether the userdata can't be passed to
+// machines within the limit, even compressed or served from
+// Options.UserDataURL.
+func (bf *BaseFlight) UserDataTooLarge(ud conf.UserData, limit UserDataLimit) bool {
+	if bf.baseopts.UserDataURL != "" {
+		return false
+	}
+	config, err := ud.Render(conf.IgnoreWarnings)
+	if err != nil {
+		return true
+	}
+	data, err := fitUserData(config, limit)
+	return err != nil || data == ""
+}
+
+// FitUserData returns the userdata to pass to a machine of the config
+// within the limit. Configs too large are gzipped, and if they're still
+// too large and Options.UserDataURL is set, served from there and fetched
+// by a pointer config which verifies their hash.
+func (bc *BaseCluster) FitUserData(config *conf.Conf, limit UserDataLimit) (string, error) {
+	data, err := fitUserData(config, limit)
+	if err != nil {
+		return "", err
+	}
+	if data != "" {
+		return data, nil
+	}
+	if bc.bf.baseopts.UserDataURL == "" {
+		return "", fmt.Errorf("config of %d bytes is too large for userdata even compressed; set a URL to serve it from", len(config.String()))
+	}
+	data = config.String()
+	name, source, err := bc.bf.serveUserData(data)
+	if err != nil {
+		return "", err
+	}
+	pointer, err := conf.EmptyIgnition().Render(conf.FailWarnings)
+	if err != nil {
+		return "", err
+	}
+	// The config is fetched over plain HTTP, so make sure it's ours
+	sum := sha512.Sum512([]byte(data))
+	pointer.AddVerifiedConfigSource(source, "sha512-"+hex.EncodeToString(sum[:]))
+	plog.Infof("Config too large for userdata; serving it from %s", source)
+	bc.machlock.Lock()
+	bc.served[pointer.String()] = name
+	bc.machlock.Unlock()
+	return pointer.String(), nil
+}
+
+// BindUserData records that the machine with the ID was created with
+// userdata from FitUserData, so that any config served for it stops being
+// served once the machine is destroyed. Configs never bound are served
+// until the cluster is destroyed.
+func (bc *BaseCluster) BindUserData(id, userdata string) {
+	bc.machlock.Lock()
+	defer bc.machlock.Unlock()
+	if name, ok := bc.served[userdata]; ok {
+		bc.machServed[id] = name
+		delete(bc.served, userdata)
+	}
+}
+
+// unserveUnbound stops serving the configs never bound to a machine, such
+// as those of machines which failed to be created.
+func (bc *BaseCluster) unserveUnbound() {
+	bc.machlock.Lock()
+	defer bc.machlock.Unlock()
+	for userdata, name := range bc.served {
+		bc.bf.unserveUserData(name)
+		delete(bc.served, userdata)
+	}
+}
+
+// userDataServer serves configs too large for userdata to machines.
+type userDataServer struct {
+	mu      sync.Mutex
+	configs map[string]string
+	// prefix is the path of the userdata URL, without a trailing slash
+	prefix   string
+	listener net.Listener
+}
+
+// serveUserData serves the config, starting the server on the first call,
+// and returns its name and URL. The URL is unguessable, since configs may
+// contain secrets.
+func (bf *BaseFlight) serveUserData(config string) (string, string, error) {
+	bf.userdataLock.Lock()
+	defer bf.userdataLock.Unlock()
+
+	if bf.userdataServer == nil {
+		base, err := url.Parse(bf.baseopts.UserDataURL)
+		if err != nil {
+			return "", "", fmt.Errorf("parsing userdata URL: %v", err)
+		}
+		listener, err := net.Listen("tcp", bf.baseopts.UserDataServeAddress)
+		if err != nil {
+			return "", "", fmt.Errorf("serving userdata: %v", err)
+		}
+		s := &userDataServer{
+			configs:  make(map[string]string),
+			prefix:   strings.TrimSuffix(base.Path, "/"),
+			listener: listener,
+		}
+		go func() {
+			if err := http.Serve(listener, s); err != nil && !errors.Is(err, net.ErrClosed) {
+				plog.Errorf("Serving userdata: %v", err)
+			}
+		}()
+		plog.Noticef("Serving userdata too large for the platform on %s", listener.Addr())
+		bf.userdataServer = s
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	name := hex.EncodeToString(buf) + ".ign"
+	bf.userdataServer.mu.Lock()
+	bf.userdataServer.configs[name] = config
+	bf.userdataServer.mu.Unlock()
+	return name, strings.TrimSuffix(bf.baseopts.UserDataURL, "/") + "/" + name, nil
+}
+
+// unserveUserData stops serving the config with the name.
+func (bf *BaseFlight) unserveUserData(name string) {
+	bf.userdataLock.Lock()
+	defer bf.userdataLock.Unlock()
+	if bf.userdataServer == nil {
+		return
+	}
+	bf.userdataServer.mu.Lock()
+	delete(bf.userdataServer.configs, name)
+	bf.userdataServer.mu.Unlock()
+}
+
+func (s *userDataServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// The URL's path is stripped, unless a proxy in front of us already
+	// did
+	name := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, s.prefix), "/")
+	s.mu.Lock()
+	config, ok := s.configs[name]
+	s.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprint(w, config)
+}
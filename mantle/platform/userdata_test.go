@@ -0,0 +1,134 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package platform
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/coreos/coreos-assembler/mantle/network"
+	"github.com/coreos/coreos-assembler/mantle/platform/conf"
+)
+
+func TestUserDataServerPath(t *testing.T) {
+	for _, tt := range []struct {
+		prefix string
+		path   string
+		found  bool
+	}{
+		{"", "/a.ign", true},
+		{"", "/b.ign", false},
+		{"/kola", "/kola/a.ign", true},
+		// a proxy stripped the prefix
+		{"/kola", "/a.ign", true},
+		{"/kola", "/kola/b.ign", false},
+		{"/kola/userdata", "/kola/userdata/a.ign", true},
+	} {
+		t.Run(tt.prefix+tt.path, func(t *testing.T) {
+			s := &userDataServer{
+				configs: map[string]string{"a.ign": "{}"},
+				prefix:  tt.prefix,
+			}
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+			if found := w.Code == http.StatusOK; found != tt.found {
+				t.Errorf("got status %d, expected found %v", w.Code, tt.found)
+			}
+		})
+	}
+}
+
+type userDataMachine struct {
+	Machine
+}
+
+func (m *userDataMachine) ID() string            { return "m1" }
+func (m *userDataMachine) IP() string            { return "" }
+func (m *userDataMachine) ConsoleOutput() string { return "" }
+
+func TestUserDataUnserved(t *testing.T) {
+	bf := &BaseFlight{
+		baseopts: &Options{
+			UserDataURL:          "http://example.com/kola/",
+			UserDataServeAddress: "127.0.0.1:0",
+		},
+		agent: &network.SSHAgent{},
+	}
+	defer func() {
+		if bf.userdataServer != nil {
+			bf.userdataServer.listener.Close()
+		}
+	}()
+	bc := &BaseCluster{
+		bf:         bf,
+		machmap:    make(map[string]Machine),
+		consolemap: make(map[string]string),
+		destroying: make(map[string]bool),
+		served:     make(map[string]string),
+		machServed: make(map[string]string),
+		rconf:      &RuntimeConfig{},
+	}
+	limit := UserDataLimit{MaxSize: 1}
+
+	// fetch returns whether the config behind the pointer userdata is
+	// served, fetching it as a machine would with the URL's path
+	fetch := func(ud string) bool {
+		i := strings.Index(ud, "http://example.com")
+		j := strings.Index(ud[i:], ".ign")
+		path := strings.TrimPrefix(ud[i:i+j+len(".ign")], "http://example.com")
+		resp, err := http.Get("http://" + bf.userdataServer.listener.Addr().String() + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode == http.StatusOK
+	}
+
+	config, err := conf.EmptyIgnition().Render(conf.FailWarnings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bound, err := bc.FitUserData(config, limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unbound, err := bc.FitUserData(config, limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bc.BindUserData("m1", bound)
+	if !fetch(bound) || !fetch(unbound) {
+		t.Fatalf("configs not served")
+	}
+
+	bc.DelMach(&userDataMachine{})
+	if fetch(bound) {
+		t.Errorf("config served after its machine was destroyed")
+	}
+	if !fetch(unbound) {
+		t.Errorf("unbound config not served before the cluster was destroyed")
+	}
+
+	bc.Destroy()
+	if fetch(unbound) {
+		t.Errorf("unbound config served after the cluster was destroyed")
+	}
+}
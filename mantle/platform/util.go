@@ -24,6 +24,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/coreos/coreos-assembler/mantle/system"
 	"github.com/pkg/errors"
 	"golang.org/x/crypto/ssh"
 	"golang.org/x/term"
@@ -178,7 +179,7 @@ func WaitForMachineReboot(m Machine, j *Journal, timeout time.Duration, oldBootI
 
 func StartMachineAfterReboot(m Machine, j *Journal, oldBootId string) error {
 	if err := j.Start(context.TODO(), m, oldBootId); err != nil {
-		return fmt.Errorf("machine %q failed to start: %v", m.ID(), err)
+		return diagnoseBoot(m, err)
 	}
 	if err := CheckMachine(context.TODO(), m); err != nil {
 		return fmt.Errorf("machine %q failed basic checks: %v", m.ID(), err)
@@ -216,6 +217,16 @@ func GetMachineBootId(m Machine) (string, error) {
 	return strings.TrimSpace(string(stdout)), nil
 }
 
+// SaveConsole writes a machine's console output, retrieved from its
+// platform, to console.txt in its output directory. Nothing is written if
+// the machine has no output directory, as when creating it failed.
+func SaveConsole(dir, console string) error {
+	if dir == "" {
+		return nil
+	}
+	return os.WriteFile(filepath.Join(dir, "console.txt"), []byte(console), 0644)
+}
+
 // GenerateFakeKey generates a SSH key pair, returns the public key, and
 // discards the private key. This is useful for droplets that don't need a
 // public key, since DO & Azure insists on requiring one.
@@ -230,3 +241,14 @@ func GenerateFakeKey() (string, error) {
 	}
 	return string(ssh.MarshalAuthorizedKey(sshKey)), nil
 }
+
+// stageFile copies src to dest, reflinking it if possible, and logs how it
+// was copied.
+func stageFile(src, dest string) error {
+	method, err := system.StageFile(src, dest)
+	if err != nil {
+		return err
+	}
+	plog.Debugf("Copied %s to %s (%s)", src, dest, method)
+	return nil
+}
@@ -0,0 +1,416 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package streammeta renders stream metadata from coreos-assembler builds,
+// validates it, and compares it with published streams. It replaces the
+// Python generate-release-meta translation: builds are turned into a
+// release, as in release.json, whose architectures then make up the
+// stream.
+package streammeta
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/coreos/stream-metadata-go/release"
+	relrhcos "github.com/coreos/stream-metadata-go/release/rhcos"
+
+	cosa "github.com/coreos/coreos-assembler/pkg/builds"
+)
+
+// DefaultBaseURL is the prefix of the artifacts of Fedora CoreOS streams.
+const DefaultBaseURL = "https://builds.coreos.fedoraproject.org/prod/streams"
+
+// ReleaseOptions controls how builds are rendered into a release.
+type ReleaseOptions struct {
+	// Stream is the stream name; by default it's the branch of the
+	// build's config.
+	Stream string
+	// BaseURL is the prefix of artifact locations, which are
+	// <BaseURL>/<stream>/builds/<build>/<arch>/<path>; by default
+	// DefaultBaseURL.
+	BaseURL string
+	// NoSignatures omits the artifacts' signatures, such as for
+	// pre-release metadata.
+	NoSignatures bool
+}
+
+// The platforms whose disk images are published from the build's images of
+// the same name.
+var diskPlatforms = []string{"aliyun", "applehv", "aws", "azure", "azurestack", "digitalocean", "exoscale", "gcp", "hetzner", "hyperv", "ibmcloud", "kubevirt", "metal", "nutanix", "openstack", "powervs", "qemu", "virtualbox", "vmware", "vultr", "qemu-secex"}
+
+// Release renders the builds, one per architecture, into a release.
+func Release(builds []*cosa.Build, opts ReleaseOptions) (*release.Release, error) {
+	rel := &release.Release{}
+	for _, build := range builds {
+		if err := AddBuild(rel, build, opts); err != nil {
+			return nil, err
+		}
+	}
+	return rel, nil
+}
+
+// AddBuild adds the build to the release, which it must be part of. If
+// the release already has the build's architecture, the platforms the
+// build adds are merged in, and those it has must be the same.
+func AddBuild(rel *release.Release, build *cosa.Build, opts ReleaseOptions) error {
+	if opts.BaseURL == "" {
+		opts.BaseURL = DefaultBaseURL
+	}
+	streamName := opts.Stream
+	if streamName == "" && build.ContainerConfigGit != nil {
+		streamName = build.ContainerConfigGit.Branch
+	}
+	if streamName == "" {
+		return fmt.Errorf("build %s has no config branch to name the stream after", build.BuildID)
+	}
+	if err := ensureSame(&rel.Release, build.BuildID, "release"); err != nil {
+		return err
+	}
+	if err := ensureSame(&rel.Stream, streamName, "stream"); err != nil {
+		return err
+	}
+	arch := build.Architecture
+	if arch == "" {
+		return fmt.Errorf("build %s has no architecture", build.BuildID)
+	}
+
+	r := releaseBuilder{build: build, opts: opts, stream: streamName}
+	relArch, err := r.arch()
+	if err != nil {
+		return fmt.Errorf("build %s for %s: %v", build.BuildID, arch, err)
+	}
+	if rel.Architectures == nil {
+		rel.Architectures = make(map[string]release.Arch)
+	}
+	existing, ok := rel.Architectures[arch]
+	if !ok {
+		rel.Architectures[arch] = *relArch
+		return nil
+	}
+	if err := mergeMedia(&existing.Media, &relArch.Media); err != nil {
+		return fmt.Errorf("build %s for %s: %v", build.BuildID, arch, err)
+	}
+	rel.Architectures[arch] = existing
+	return nil
+}
+
+// ensureSame sets the field to the value, or checks that it's already
+// the value, since the builds of a release must agree.
+func ensureSame(field *string, value, what string) error {
+	if *field == "" {
+		*field = value
+	} else if *field != value {
+		return fmt.Errorf("builds aren't for the same %s (%s != %s)", what, *field, value)
+	}
+	return nil
+}
+
+// mergeMedia merges the platforms of from into to, failing if they both
+// have one which differs.
+func mergeMedia(to, from *release.Media) error {
+	tv := reflect.ValueOf(to).Elem()
+	fv := reflect.ValueOf(from).Elem()
+	for i := 0; i < fv.NumField(); i++ {
+		f := fv.Field(i)
+		if f.IsNil() {
+			continue
+		}
+		t := tv.Field(i)
+		if t.IsNil() {
+			t.Set(f)
+		} else if !reflect.DeepEqual(t.Interface(), f.Interface()) {
+			name := strings.Split(fv.Type().Field(i).Tag.Get("json"), ",")[0]
+			return fmt.Errorf("differing content for media type %q", name)
+		}
+	}
+	return nil
+}
+
+// releaseBuilder renders a build into a release architecture.
+type releaseBuilder struct {
+	build  *cosa.Build
+	opts   ReleaseOptions
+	stream string
+}
+
+func (r *releaseBuilder) artifact(a *cosa.Artifact) *release.Artifact {
+	location := fmt.Sprintf("%s/%s/builds/%s/%s/%s", strings.TrimSuffix(r.opts.BaseURL, "/"), r.stream, r.build.BuildID, r.build.Architecture, a.Path)
+	signature := location + ".sig"
+	if r.opts.NoSignatures {
+		signature = ""
+	}
+	return &release.Artifact{
+		Location:           location,
+		Signature:          signature,
+		Sha256:             a.Sha256,
+		UncompressedSha256: a.UncompressedSha256,
+	}
+}
+
+// extension returns the format of the artifact's file, the part of its
+// name after the platform and architecture, such as "raw.xz".
+func (r *releaseBuilder) extension(a *cosa.Artifact, platform string) (string, error) {
+	marker := platform + "." + r.build.Architecture + "."
+	i := strings.Index(a.Path, marker)
+	if i < 0 {
+		return "", fmt.Errorf("can't find the format of %s artifact %s", platform, a.Path)
+	}
+	return a.Path[i+len(marker):], nil
+}
+
+func (r *releaseBuilder) arch() (*release.Arch, error) {
+	build := r.build
+	relArch := &release.Arch{Commit: build.OstreeCommit}
+
+	// Within the CoreOS pipelines, base-oscontainer is always set, but
+	// OKD only needs the boot images
+	if build.BaseOsContainer != nil {
+		image, err := containerImage(build.BuildID, build.BaseOsContainer)
+		if err != nil {
+			return nil, fmt.Errorf("base-oscontainer: %v", err)
+		}
+		relArch.OciImage = image
+	}
+
+	var artifacts map[string]*cosa.Artifact
+	if build.BuildArtifacts != nil {
+		artifacts = build.Artifacts()
+	}
+	media := &relArch.Media
+	for _, platform := range diskPlatforms {
+		a, ok := artifacts[platform]
+		if !ok {
+			continue
+		}
+		ext, err := r.extension(a, platform)
+		if err != nil {
+			return nil, err
+		}
+		base := platformBase(media, platform)
+		base.Artifacts = map[string]release.ImageFormat{
+			ext: {Disk: r.artifact(a)},
+		}
+	}
+
+	if len(build.AlibabaAliyunUploads) > 0 {
+		platformBase(media, "aliyun")
+		media.Aliyun.Images = make(map[string]release.CloudImage)
+		for _, image := range build.AlibabaAliyunUploads {
+			media.Aliyun.Images[image.Region] = release.CloudImage{Image: image.ImageID}
+		}
+	}
+	if len(build.Amis) > 0 {
+		platformBase(media, "aws")
+		media.Aws.Images = make(map[string]release.CloudImage)
+		for _, ami := range build.Amis {
+			media.Aws.Images[ami.Region] = release.CloudImage{Image: ami.Hvm}
+		}
+	}
+	if len(build.IbmCloud) > 0 {
+		platformBase(media, "ibmcloud")
+		media.Ibmcloud.Images = ibmCloudImages(build.IbmCloud)
+	}
+	if len(build.PowerVirtualServer) > 0 {
+		platformBase(media, "powervs")
+		media.PowerVS.Images = ibmCloudImages(build.PowerVirtualServer)
+	}
+	// The URL of the GCP image isn't published
+	if build.Gcp != nil {
+		platformBase(media, "gcp")
+		media.Gcp.Image = &release.GcpImage{
+			Project: build.Gcp.ImageProject,
+			Family:  build.Gcp.ImageFamily,
+			Name:    build.Gcp.ImageName,
+		}
+	}
+	if build.KubevirtContainer != nil {
+		image, err := containerImage(build.BuildID, build.KubevirtContainer)
+		if err != nil {
+			return nil, fmt.Errorf("kubevirt: %v", err)
+		}
+		platformBase(media, "kubevirt")
+		media.KubeVirt.Image = image
+	}
+
+	if build.Azure != nil {
+		relArch.RHELCoreOSExtensions = &relrhcos.Extensions{
+			AzureDisk: &relrhcos.AzureDisk{URL: build.Azure.URL},
+		}
+	}
+	if len(build.AwsWinLi) > 0 {
+		if relArch.RHELCoreOSExtensions == nil {
+			relArch.RHELCoreOSExtensions = &relrhcos.Extensions{}
+		}
+		winli := &relrhcos.AwsWinLi{Images: make(map[string]relrhcos.CloudImage)}
+		for _, ami := range build.AwsWinLi {
+			winli.Images[ami.Region] = relrhcos.CloudImage{Image: ami.Hvm}
+		}
+		relArch.RHELCoreOSExtensions.AwsWinLi = winli
+	}
+
+	if err := r.metal(platformBase(media, "metal"), artifacts); err != nil {
+		return nil, err
+	}
+	return relArch, nil
+}
+
+// metal adds the metal artifacts other than the disk image: the 4K native
+// image alongside the 512b one, and the live and legacy installer media.
+func (r *releaseBuilder) metal(metal *release.PlatformBase, artifacts map[string]*cosa.Artifact) error {
+	if metal.Artifacts == nil {
+		metal.Artifacts = make(map[string]release.ImageFormat)
+	}
+	if a, ok := artifacts["metal4k"]; ok {
+		ext, err := r.extension(a, "metal4k")
+		if err != nil {
+			return err
+		}
+		metal.Artifacts["4k."+ext] = release.ImageFormat{Disk: r.artifact(a)}
+	}
+	if a, ok := artifacts["iso"]; ok {
+		metal.Artifacts["installer.iso"] = release.ImageFormat{Disk: r.artifact(a)}
+	}
+	if a, ok := artifacts["live-iso"]; ok {
+		metal.Artifacts["iso"] = release.ImageFormat{Disk: r.artifact(a)}
+	}
+
+	pxe := func(format string, kernel, initramfs, rootfs string) {
+		var f release.ImageFormat
+		if a, ok := artifacts[kernel]; ok {
+			f.Kernel = r.artifact(a)
+		}
+		if a, ok := artifacts[initramfs]; ok {
+			f.Initramfs = r.artifact(a)
+		}
+		if a, ok := artifacts[rootfs]; ok {
+			f.Rootfs = r.artifact(a)
+		}
+		if f != (release.ImageFormat{}) {
+			metal.Artifacts[format] = f
+		}
+	}
+	pxe("installer-pxe", "kernel", "initramfs", "")
+	pxe("pxe", "live-kernel", "live-initramfs", "live-rootfs")
+	return nil
+}
+
+// platformBase returns the artifacts of the platform in the media, adding
+// the platform if it's missing.
+func platformBase(media *release.Media, platform string) *release.PlatformBase {
+	base := func(p **release.PlatformBase) *release.PlatformBase {
+		if *p == nil {
+			*p = &release.PlatformBase{}
+		}
+		return *p
+	}
+	switch platform {
+	case "aliyun":
+		if media.Aliyun == nil {
+			media.Aliyun = &release.PlatformAliyun{}
+		}
+		return &media.Aliyun.PlatformBase
+	case "applehv":
+		return base(&media.AppleHV)
+	case "aws":
+		if media.Aws == nil {
+			media.Aws = &release.PlatformAws{}
+		}
+		return &media.Aws.PlatformBase
+	case "azure":
+		return base(&media.Azure)
+	case "azurestack":
+		return base(&media.AzureStack)
+	case "digitalocean":
+		return base(&media.Digitalocean)
+	case "exoscale":
+		return base(&media.Exoscale)
+	case "gcp":
+		if media.Gcp == nil {
+			media.Gcp = &release.PlatformGcp{}
+		}
+		return &media.Gcp.PlatformBase
+	case "hetzner":
+		return base(&media.Hetzner)
+	case "hyperv":
+		return base(&media.HyperV)
+	case "ibmcloud":
+		if media.Ibmcloud == nil {
+			media.Ibmcloud = &release.PlatformIBMCloud{}
+		}
+		return &media.Ibmcloud.PlatformBase
+	case "kubevirt":
+		if media.KubeVirt == nil {
+			media.KubeVirt = &release.PlatformKubeVirt{}
+		}
+		return &media.KubeVirt.PlatformBase
+	case "metal":
+		return base(&media.Metal)
+	case "nutanix":
+		return base(&media.Nutanix)
+	case "openstack":
+		return base(&media.Openstack)
+	case "powervs":
+		if media.PowerVS == nil {
+			media.PowerVS = &release.PlatformIBMCloud{}
+		}
+		return &media.PowerVS.PlatformBase
+	case "qemu":
+		return base(&media.Qemu)
+	case "qemu-secex":
+		return base(&media.QemuSecex)
+	case "virtualbox":
+		return base(&media.VirtualBox)
+	case "vmware":
+		return base(&media.Vmware)
+	case "vultr":
+		return base(&media.Vultr)
+	}
+	panic(fmt.Sprintf("unknown platform %q", platform))
+}
+
+func ibmCloudImages(uploads []cosa.Cloudartifact) map[string]release.IBMCloudImage {
+	images := make(map[string]release.IBMCloudImage)
+	for _, upload := range uploads {
+		images[upload.Region] = release.IBMCloudImage{
+			Object: upload.Object,
+			Bucket: upload.Bucket,
+			Url:    upload.URL,
+		}
+	}
+	return images
+}
+
+// containerImage returns the container image referenced both by its
+// floating tag, the one without the build ID, and by its digest.
+func containerImage(buildID string, image *cosa.PrimaryImage) (*release.ContainerImage, error) {
+	var floating string
+	for _, tag := range image.Tags {
+		if strings.Contains(string(tag), buildID) {
+			continue
+		}
+		if floating != "" {
+			return nil, fmt.Errorf("multiple floating tags within %v", image.Tags)
+		}
+		floating = string(tag)
+	}
+	if floating == "" {
+		return nil, fmt.Errorf("no floating tag within %v", image.Tags)
+	}
+	return &release.ContainerImage{
+		Image:     image.Image + ":" + floating,
+		DigestRef: image.Image + "@" + image.Digest,
+	}, nil
+}
@@ -0,0 +1,298 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package streammeta
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/coreos/stream-metadata-go/release"
+	"github.com/coreos/stream-metadata-go/stream"
+)
+
+// ReadSource reads a local file, or fetches an http(s) URL.
+func ReadSource(source string) ([]byte, error) {
+	if !strings.HasPrefix(source, "https://") && !strings.HasPrefix(source, "http://") {
+		return os.ReadFile(source)
+	}
+	resp, err := http.Get(source)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: %s", source, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// Stream renders the releases into stream metadata. If base is non-nil,
+// the releases update its architectures; otherwise, they make up a new
+// stream named name, and must be for different architectures.
+func Stream(name string, base *stream.Stream, releases []*release.Release, generator string) (*stream.Stream, error) {
+	var st stream.Stream
+	if base != nil {
+		st = *base
+		arches := make(map[string]stream.Arch, len(base.Architectures))
+		for arch, a := range base.Architectures {
+			arches[arch] = a
+		}
+		st.Architectures = arches
+	} else {
+		if name == "" {
+			return nil, fmt.Errorf("no stream name given")
+		}
+		st = stream.Stream{
+			Stream:        name,
+			Architectures: make(map[string]stream.Arch),
+		}
+	}
+	st.Metadata = stream.Metadata{
+		LastModified: time.Now().UTC().Format(time.RFC3339),
+		Generator:    generator,
+	}
+	for _, rel := range releases {
+		for arch, a := range rel.ToStreamArchitectures() {
+			if _, ok := st.Architectures[arch]; ok && base == nil {
+				return nil, fmt.Errorf("duplicate architecture %s", arch)
+			}
+			st.Architectures[arch] = a
+		}
+	}
+	return &st, nil
+}
+
+var sha256Re = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// Validate parses stream metadata, which must match the stream-metadata-go
+// schema with no unknown fields, and checks that it's complete: that it
+// has a name and architectures, and that every artifact has a location and
+// checksum, and every image a release. All the problems found are
+// returned.
+func Validate(data []byte) (*stream.Stream, []error) {
+	var st stream.Stream
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&st); err != nil {
+		return nil, []error{fmt.Errorf("parsing stream metadata: %v", err)}
+	}
+
+	var errs []error
+	fail := func(path, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s: %s", path, fmt.Sprintf(format, args...)))
+	}
+	if st.Stream == "" {
+		fail("stream", "missing")
+	}
+	if _, err := time.Parse(time.RFC3339, st.Metadata.LastModified); err != nil {
+		fail("metadata.last-modified", "not an RFC 3339 time: %q", st.Metadata.LastModified)
+	}
+	if len(st.Architectures) == 0 {
+		fail("architectures", "empty")
+	}
+	for _, arch := range sortedKeys(st.Architectures) {
+		a := st.Architectures[arch]
+		path := "architectures." + arch
+		if len(a.Artifacts) == 0 {
+			fail(path+".artifacts", "empty")
+		}
+		for _, platform := range sortedKeys(a.Artifacts) {
+			pa := a.Artifacts[platform]
+			ppath := path + ".artifacts." + platform
+			if pa.Release == "" {
+				fail(ppath+".release", "missing")
+			}
+			for _, format := range sortedKeys(pa.Formats) {
+				f := pa.Formats[format]
+				fpath := ppath + ".formats." + format
+				if f == (stream.ImageFormat{}) {
+					fail(fpath, "no artifacts")
+				}
+				for name, artifact := range map[string]*stream.Artifact{"disk": f.Disk, "kernel": f.Kernel, "initramfs": f.Initramfs, "rootfs": f.Rootfs} {
+					if artifact != nil {
+						validateArtifact(fpath+"."+name, artifact, fail)
+					}
+				}
+			}
+		}
+		validateImages(path+".images", &a.Images, fail)
+	}
+	return &st, errs
+}
+
+func validateArtifact(path string, a *stream.Artifact, fail func(path, format string, args ...interface{})) {
+	if u, err := url.Parse(a.Location); err != nil || u.Scheme != "https" || u.Host == "" {
+		fail(path+".location", "not an https URL: %q", a.Location)
+	}
+	if a.Signature != "" && a.Signature != a.Location+".sig" {
+		fail(path+".signature", "%q isn't the location with .sig appended", a.Signature)
+	}
+	if !sha256Re.MatchString(a.Sha256) {
+		fail(path+".sha256", "not a sha256 checksum: %q", a.Sha256)
+	}
+	if a.UncompressedSha256 != "" && !sha256Re.MatchString(a.UncompressedSha256) {
+		fail(path+".uncompressed-sha256", "not a sha256 checksum: %q", a.UncompressedSha256)
+	}
+}
+
+func validateImages(path string, images *stream.Images, fail func(path, format string, args ...interface{})) {
+	regions := func(platform string, r *stream.ReplicatedImage) {
+		if r == nil {
+			return
+		}
+		for _, region := range sortedKeys(r.Regions) {
+			image := r.Regions[region]
+			if image.Release == "" || image.Image == "" {
+				fail(path+"."+platform+".regions."+region, "missing release or image")
+			}
+		}
+	}
+	objects := func(platform string, r *stream.ReplicatedObject) {
+		if r == nil {
+			return
+		}
+		for _, region := range sortedKeys(r.Regions) {
+			object := r.Regions[region]
+			if object.Release == "" || object.Object == "" || object.Bucket == "" {
+				fail(path+"."+platform+".regions."+region, "missing release, object or bucket")
+			}
+		}
+	}
+	regions("aliyun", images.Aliyun)
+	regions("aws", images.Aws)
+	objects("ibmcloud", images.Ibmcloud)
+	objects("powervs", images.PowerVS)
+	if gcp := images.Gcp; gcp != nil && (gcp.Release == "" || gcp.Project == "" || gcp.Name == "") {
+		fail(path+".gcp", "missing release, project or name")
+	}
+	if kv := images.KubeVirt; kv != nil && (kv.Release == "" || kv.Image == "" || kv.DigestRef == "") {
+		fail(path+".kubevirt", "missing release, image or digest-ref")
+	}
+}
+
+// Change is a difference between two streams, at the dotted JSON path of a
+// value. Old is empty for added values, and New for removed ones.
+type Change struct {
+	Path string
+	Old  string
+	New  string
+}
+
+func (c Change) String() string {
+	switch {
+	case c.Old == "":
+		return fmt.Sprintf("+ %s: %s", c.Path, c.New)
+	case c.New == "":
+		return fmt.Sprintf("- %s: %s", c.Path, c.Old)
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", c.Path, c.Old, c.New)
+	}
+}
+
+// Diff returns the changes from one stream to another, sorted by
+// path. The metadata, which changes whenever a stream is generated, is
+// ignored.
+func Diff(from, to *stream.Stream) ([]Change, error) {
+	oldValues, err := flatten(from)
+	if err != nil {
+		return nil, err
+	}
+	newValues, err := flatten(to)
+	if err != nil {
+		return nil, err
+	}
+	var changes []Change
+	for path, o := range oldValues {
+		if n := newValues[path]; n != o {
+			changes = append(changes, Change{Path: path, Old: o, New: n})
+		}
+	}
+	for path, n := range newValues {
+		if _, ok := oldValues[path]; !ok {
+			changes = append(changes, Change{Path: path, New: n})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+// flatten returns the leaf values of the stream by dotted JSON path.
+func flatten(st *stream.Stream) (map[string]string, error) {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return nil, err
+	}
+	var tree map[string]interface{}
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+	delete(tree, "metadata")
+	values := make(map[string]string)
+	var walk func(prefix string, v interface{})
+	walk = func(prefix string, v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			for k, child := range v {
+				walk(strings.TrimPrefix(prefix+"."+k, "."), child)
+			}
+		case []interface{}:
+			for i, child := range v {
+				walk(fmt.Sprintf("%s.%d", prefix, i), child)
+			}
+		case nil:
+		default:
+			if s := fmt.Sprint(v); s != "" {
+				values[prefix] = s
+			}
+		}
+	}
+	walk("", tree)
+	return values, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// AWSImages returns the IDs of the AMIs the stream points to, in every
+// architecture and region, sorted.
+func AWSImages(st *stream.Stream) []string {
+	ids := make(map[string]bool)
+	for _, arch := range st.Architectures {
+		if arch.Images.Aws == nil {
+			continue
+		}
+		for _, image := range arch.Images.Aws.Regions {
+			if image.Image != "" {
+				ids[image.Image] = true
+			}
+		}
+	}
+	return sortedKeys(ids)
+}
@@ -0,0 +1,189 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package streammeta
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/coreos/stream-metadata-go/release"
+
+	cosa "github.com/coreos/coreos-assembler/pkg/builds"
+)
+
+const testSha256 = "e0e4548df88a35d5854d052281c5deedad16f286f82cb2c23f2f9dea494834ac"
+
+func testBuild(arch string) *cosa.Build {
+	artifact := func(path string) *cosa.Artifact {
+		return &cosa.Artifact{Path: path, Sha256: testSha256}
+	}
+	prefix := "fedora-coreos-41.1-"
+	return &cosa.Build{
+		BuildID:            "41.1",
+		Architecture:       arch,
+		OstreeCommit:       "abc",
+		ContainerConfigGit: &cosa.Git{Branch: "testing-devel"},
+		BuildArtifacts: &cosa.BuildArtifacts{
+			Metal:         artifact(prefix + "metal." + arch + ".raw.xz"),
+			Metal4KNative: artifact(prefix + "metal4k." + arch + ".raw.xz"),
+			LiveIso:       artifact(prefix + "live-iso." + arch + ".iso"),
+			LiveKernel:    artifact(prefix + "live-kernel." + arch),
+			LiveRootfs:    artifact(prefix + "live-rootfs." + arch + ".img"),
+			Qemu:          artifact(prefix + "qemu." + arch + ".qcow2.xz"),
+		},
+		Amis: []cosa.Amis{{Region: "us-east-1", Hvm: "ami-1"}},
+		BaseOsContainer: &cosa.PrimaryImage{
+			Image:  "quay.io/fedora/fedora-coreos",
+			Digest: "sha256:" + testSha256,
+			Tags:   []cosa.PrimaryImageTag{"41.1", "testing-devel"},
+		},
+	}
+}
+
+func TestRelease(t *testing.T) {
+	rel, err := Release([]*cosa.Build{testBuild("x86_64"), testBuild("aarch64")}, ReleaseOptions{NoSignatures: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rel.Release != "41.1" || rel.Stream != "testing-devel" || len(rel.Architectures) != 2 {
+		t.Fatalf("unexpected release %+v", rel)
+	}
+	arch := rel.Architectures["x86_64"]
+	if arch.OciImage == nil || arch.OciImage.Image != "quay.io/fedora/fedora-coreos:testing-devel" {
+		t.Errorf("unexpected OCI image %+v", arch.OciImage)
+	}
+	metal := arch.Media.Metal.Artifacts
+	for _, format := range []string{"raw.xz", "4k.raw.xz", "iso", "pxe"} {
+		if _, ok := metal[format]; !ok {
+			t.Errorf("metal format %s missing from %v", format, metal)
+		}
+	}
+	disk := metal["raw.xz"].Disk
+	if disk.Location != DefaultBaseURL+"/testing-devel/builds/41.1/x86_64/fedora-coreos-41.1-metal.x86_64.raw.xz" || disk.Signature != "" {
+		t.Errorf("unexpected disk %+v", disk)
+	}
+	if metal["pxe"].Initramfs != nil || metal["pxe"].Rootfs == nil {
+		t.Errorf("unexpected PXE artifacts %+v", metal["pxe"])
+	}
+	if arch.Media.Aws.Images["us-east-1"].Image != "ami-1" {
+		t.Errorf("unexpected AWS images %+v", arch.Media.Aws)
+	}
+
+	other := testBuild("x86_64")
+	other.BuildID = "41.2"
+	if err := AddBuild(rel, other, ReleaseOptions{}); err == nil {
+		t.Errorf("added build of another release")
+	}
+	other = testBuild("x86_64")
+	other.BuildArtifacts.Qemu.Sha256 = strings.Repeat("0", 64)
+	if err := AddBuild(rel, other, ReleaseOptions{NoSignatures: true}); err == nil {
+		t.Errorf("merged differing qemu media")
+	}
+}
+
+func TestStream(t *testing.T) {
+	var releases []*release.Release
+	for _, arch := range []string{"x86_64", "aarch64"} {
+		rel, err := Release([]*cosa.Build{testBuild(arch)}, ReleaseOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		releases = append(releases, rel)
+	}
+	if _, err := Stream("testing-devel", nil, append(releases, releases[0]), "test"); err == nil {
+		t.Errorf("generated stream with duplicate architecture")
+	}
+	st, err := Stream("testing-devel", nil, releases, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, errs := Validate(data); len(errs) > 0 {
+		t.Fatalf("generated stream is invalid: %v", errs)
+	}
+
+	// Updating a stream only replaces the architectures of the releases
+	update := testBuild("x86_64")
+	update.BuildID = "41.2"
+	update.BaseOsContainer.Tags = []cosa.PrimaryImageTag{"41.2", "testing-devel"}
+	rel, err := Release([]*cosa.Build{update}, ReleaseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated, err := Stream("", st, []*release.Release{rel}, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Architectures["x86_64"].Artifacts["metal"].Release != "41.2" || updated.Architectures["aarch64"].Artifacts["metal"].Release != "41.1" {
+		t.Errorf("unexpected updated stream %+v", updated.Architectures)
+	}
+	if st.Architectures["x86_64"].Artifacts["metal"].Release != "41.1" {
+		t.Errorf("base stream was modified")
+	}
+
+	changes, err := Diff(st, updated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) == 0 {
+		t.Fatalf("no changes found")
+	}
+	for _, c := range changes {
+		if !strings.HasPrefix(c.Path, "architectures.x86_64.") || c.Old == "" || c.New == "" {
+			t.Errorf("unexpected change %s", c)
+		}
+	}
+	if changes, _ := Diff(st, st); len(changes) != 0 {
+		t.Errorf("changes between identical streams: %v", changes)
+	}
+
+	// Both architectures' builds have the same AMI
+	if amis := AWSImages(st); len(amis) != 1 || amis[0] != "ami-1" {
+		t.Errorf("unexpected AMIs %v", amis)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	rel, err := Release([]*cosa.Build{testBuild("x86_64")}, ReleaseOptions{BaseURL: "http://example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rel.Architectures["x86_64"].Media.Metal.Artifacts["iso"].Disk.Sha256 = "bogus"
+	st, err := Stream("testing-devel", nil, []*release.Release{rel}, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, errs := Validate(data)
+	var msgs []string
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+	all := strings.Join(msgs, "\n")
+	if !strings.Contains(all, "metal.formats.iso.disk.sha256: not a sha256 checksum") || !strings.Contains(all, "not an https URL") {
+		t.Errorf("unexpected errors:\n%s", all)
+	}
+
+	if _, errs := Validate([]byte(`{"stream": "x", "unknown": 1}`)); len(errs) != 1 || !strings.Contains(errs[0].Error(), "unknown") {
+		t.Errorf("unknown field not rejected: %v", errs)
+	}
+}
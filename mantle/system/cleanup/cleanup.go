@@ -0,0 +1,295 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cleanup records the processes and temporary directories a run
+// creates in a state file in its output directory, so that those leaked by
+// a run which was interrupted, e.g. killed by CI, can be reaped later.
+//
+// The state file is a log of JSON lines, appended to as resources are
+// created and released, so that it's intact whenever the run dies.
+package cleanup
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// StateFile is the name of the state file in a run's output directory.
+const StateFile = "cleanup.jsonl"
+
+// Default is the registry of the current run, if any. Registering with a
+// nil registry does nothing.
+var Default *Registry
+
+const (
+	opOwner = "owner"
+	opAdd   = "add"
+	opDone  = "done"
+)
+
+type entry struct {
+	Op string `json:"op"`
+	// Pid and Start identify a process; Start is its start time in clock
+	// ticks since boot, so that a reused PID isn't mistaken for it
+	Pid   int    `json:"pid,omitempty"`
+	Start uint64 `json:"start,omitempty"`
+	// Path is a directory
+	Path string `json:"path,omitempty"`
+}
+
+// key identifies the resource of the entry.
+func (e entry) key() string {
+	if e.Path != "" {
+		return "dir " + e.Path
+	}
+	return fmt.Sprintf("process %d", e.Pid)
+}
+
+type Registry struct {
+	mu          sync.Mutex
+	f           *os.File
+	outstanding map[string]bool
+	// err is the first error recording to the state file
+	err error
+}
+
+// Open creates the state file in the run's output directory dir.
+func Open(dir string) (*Registry, error) {
+	f, err := os.OpenFile(filepath.Join(dir, StateFile), os.O_WRONLY|os.O_CREATE|os.O_TRUNC|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	r := &Registry{
+		f:           f,
+		outstanding: make(map[string]bool),
+	}
+	start, err := processStart(os.Getpid())
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r.record(entry{Op: opOwner, Pid: os.Getpid(), Start: start})
+	if r.err != nil {
+		f.Close()
+		return nil, r.err
+	}
+	return r, nil
+}
+
+func (r *Registry) record(e entry) {
+	buf, err := json.Marshal(e)
+	if err == nil {
+		_, err = r.f.Write(append(buf, '\n'))
+	}
+	if err != nil && r.err == nil {
+		r.err = err
+	}
+	switch e.Op {
+	case opAdd:
+		r.outstanding[e.key()] = true
+	case opDone:
+		delete(r.outstanding, e.key())
+	}
+}
+
+func (r *Registry) recordLocked(e entry) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return
+	}
+	r.record(e)
+}
+
+// AddProcess records a process which must be killed if the run dies.
+func (r *Registry) AddProcess(pid int) {
+	if r == nil {
+		return
+	}
+	start, err := processStart(pid)
+	if err != nil {
+		// it's already gone
+		return
+	}
+	r.recordLocked(entry{Op: opAdd, Pid: pid, Start: start})
+}
+
+// DoneProcess records that the process was killed.
+func (r *Registry) DoneProcess(pid int) {
+	r.recordLocked(entry{Op: opDone, Pid: pid})
+}
+
+// AddDir records a directory which must be removed if the run dies.
+func (r *Registry) AddDir(path string) {
+	if path, err := filepath.Abs(path); err == nil {
+		r.recordLocked(entry{Op: opAdd, Path: path})
+	}
+}
+
+// DoneDir records that the directory was removed.
+func (r *Registry) DoneDir(path string) {
+	if path, err := filepath.Abs(path); err == nil {
+		r.recordLocked(entry{Op: opDone, Path: path})
+	}
+}
+
+// Close closes the state file, and removes it if every resource was
+// released. It returns the first error recording to it.
+func (r *Registry) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return r.err
+	}
+	if err := r.f.Close(); err != nil && r.err == nil {
+		r.err = err
+	}
+	if len(r.outstanding) == 0 {
+		os.Remove(r.f.Name())
+	}
+	r.f = nil
+	return r.err
+}
+
+// processStart returns the start time of the process, in clock ticks since
+// boot.
+func processStart(pid int) (uint64, error) {
+	buf, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, err
+	}
+	// the command may contain spaces and parentheses, but is followed
+	// by the last ')'
+	i := bytes.LastIndexByte(buf, ')')
+	if i < 0 {
+		return 0, fmt.Errorf("malformed stat of process %d", pid)
+	}
+	// the fields after the command start at the state, field 3, and
+	// the start time is field 22
+	fields := strings.Fields(string(buf[i+1:]))
+	if len(fields) < 20 {
+		return 0, fmt.Errorf("malformed stat of process %d", pid)
+	}
+	return strconv.ParseUint(fields[19], 10, 64)
+}
+
+// alive returns whether the process is still the one which started then.
+func alive(pid int, start uint64) bool {
+	s, err := processStart(pid)
+	return err == nil && s == start
+}
+
+// Reap kills the processes and removes the directories the state file in
+// the output directory dir lists as outstanding, unless the run which
+// wrote it is still alive, and then removes the state file. It returns the
+// resources it reaped.
+func Reap(dir string) ([]string, error) {
+	path := filepath.Join(dir, StateFile)
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var owner *entry
+	var order []string
+	outstanding := make(map[string]entry)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			// the run may have died mid-write
+			continue
+		}
+		switch e.Op {
+		case opOwner:
+			owner = &e
+		case opAdd:
+			if _, ok := outstanding[e.key()]; !ok {
+				order = append(order, e.key())
+			}
+			outstanding[e.key()] = e
+		case opDone:
+			delete(outstanding, e.key())
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading %s: %v", path, err)
+	}
+	if owner != nil && alive(owner.Pid, owner.Start) {
+		return nil, nil
+	}
+
+	var reaped []string
+	var dirs []entry
+	// kill processes before removing the directories they use
+	for _, k := range order {
+		e, ok := outstanding[k]
+		if !ok {
+			continue
+		}
+		if e.Path != "" {
+			dirs = append(dirs, e)
+			continue
+		}
+		if !alive(e.Pid, e.Start) {
+			continue
+		}
+		if err := syscall.Kill(e.Pid, syscall.SIGKILL); err != nil && err != syscall.ESRCH {
+			return reaped, fmt.Errorf("killing process %d: %v", e.Pid, err)
+		}
+		reaped = append(reaped, k)
+	}
+	for _, e := range dirs {
+		if _, err := os.Stat(e.Path); os.IsNotExist(err) {
+			continue
+		}
+		if err := os.RemoveAll(e.Path); err != nil {
+			return reaped, err
+		}
+		reaped = append(reaped, e.key())
+	}
+	return reaped, os.Remove(path)
+}
+
+// ReapAll reaps the state files in the output directories in base, such as
+// _kola_temp.
+func ReapAll(base string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(base, "*", StateFile))
+	if err != nil {
+		return nil, err
+	}
+	var reaped []string
+	for _, path := range paths {
+		r, err := Reap(filepath.Dir(path))
+		reaped = append(reaped, r...)
+		if err != nil {
+			return reaped, err
+		}
+	}
+	return reaped, nil
+}
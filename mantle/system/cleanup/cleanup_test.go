@@ -0,0 +1,138 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cleanup
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeState writes the state file of a run into the output directory.
+func writeState(t *testing.T, out string, entries ...entry) {
+	f, err := os.Create(filepath.Join(out, StateFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	enc := json.NewEncoder(f)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReap(t *testing.T) {
+	cmd := exec.Command("sleep", "60")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cmd.Process.Kill()
+	start, err := processStart(cmd.Process.Pid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaked := filepath.Join(t.TempDir(), "leaked")
+	removed := filepath.Join(t.TempDir(), "removed")
+	for _, dir := range []string{leaked, removed} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out := filepath.Join(t.TempDir(), "qemu-run")
+	if err := os.Mkdir(out, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeState(t, out,
+		// no process started then has this PID now, so the run is gone
+		entry{Op: opOwner, Pid: os.Getpid(), Start: 0},
+		entry{Op: opAdd, Path: leaked},
+		entry{Op: opAdd, Pid: cmd.Process.Pid, Start: start},
+		entry{Op: opAdd, Path: removed},
+		entry{Op: opDone, Path: removed},
+		// a process which was replaced by another with its PID
+		entry{Op: opAdd, Pid: os.Getpid(), Start: 0},
+	)
+
+	reaped, err := ReapAll(filepath.Dir(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{fmt.Sprintf("process %d", cmd.Process.Pid), "dir " + leaked}
+	if !reflect.DeepEqual(reaped, expected) {
+		t.Errorf("reaped %v, expected %v", reaped, expected)
+	}
+	if err := cmd.Wait(); err == nil {
+		t.Error("process wasn't killed")
+	}
+	if _, err := os.Stat(leaked); !os.IsNotExist(err) {
+		t.Errorf("%s wasn't removed", leaked)
+	}
+	if _, err := os.Stat(removed); err != nil {
+		t.Errorf("%s was reaped although it was released: %v", removed, err)
+	}
+	if _, err := os.Stat(filepath.Join(out, StateFile)); !os.IsNotExist(err) {
+		t.Error("state file wasn't removed")
+	}
+}
+
+func TestReapLiveRun(t *testing.T) {
+	out := t.TempDir()
+	r, err := Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	r.AddDir(dir)
+	defer r.Close()
+
+	reaped, err := Reap(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reaped) > 0 {
+		t.Errorf("reaped %v of a run which is alive", reaped)
+	}
+	if _, err := os.Stat(dir); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestClose(t *testing.T) {
+	out := t.TempDir()
+	r, err := Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	r.AddDir(dir)
+	r.DoneDir(dir)
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(out, StateFile)); !os.IsNotExist(err) {
+		t.Error("state file of a run which released everything wasn't removed")
+	}
+
+	// registering with no registry does nothing
+	var none *Registry
+	none.AddDir(dir)
+	none.DoneDir(dir)
+}
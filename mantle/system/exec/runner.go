@@ -0,0 +1,185 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exec
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How much of the end of a failed command's stderr its error includes.
+const stderrTailSize = 2048
+
+// Runner runs helper commands to completion, such as the tools used to
+// set up installs, so that their failures are easy to diagnose.
+type Runner struct {
+	// Timeout, if non-zero, is how long commands may run before they're
+	// killed.
+	Timeout time.Duration
+	// Log, if set, receives the commands' stdout and stderr as they run.
+	Log io.Writer
+	// AuditFile, if set, is appended with a line for each command, with
+	// its duration and result.
+	AuditFile string
+	// Env, if set, is the whole environment of the commands, rather than
+	// mantle's.
+	Env []string
+
+	auditLock sync.Mutex
+}
+
+// CommandError is a command which the Runner couldn't run, or which
+// failed.
+type CommandError struct {
+	Args []string
+	// ExitCode is the command's exit status, or -1 if it didn't exit,
+	// because it wasn't started or was killed.
+	ExitCode int
+	// TimedOut is whether the command was killed for running longer
+	// than the Runner's Timeout.
+	TimedOut bool
+	// Stderr is the end of the command's stderr.
+	Stderr string
+	Err    error
+}
+
+func (e *CommandError) Error() string {
+	msg := fmt.Sprintf("running %s: %v", quoteArgs(e.Args), e.Err)
+	if stderr := strings.TrimSpace(e.Stderr); stderr != "" {
+		msg += ": " + stderr
+	}
+	return msg
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// Run runs the command and returns its stdout.
+func (r *Runner) Run(name string, args ...string) ([]byte, error) {
+	return r.RunContext(context.Background(), name, args...)
+}
+
+// RunContext runs the command, killing it if ctx is done first, and
+// returns its stdout. If the command fails, the error is a
+// *CommandError.
+func (r *Runner) RunContext(ctx context.Context, name string, args ...string) ([]byte, error) {
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+	argv := append([]string{name}, args...)
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if r.Env != nil {
+		cmd.Env = r.Env
+	}
+	if r.Log != nil {
+		cmd.Stdout = io.MultiWriter(&stdout, r.Log)
+		cmd.Stderr = io.MultiWriter(&stderr, r.Log)
+	}
+
+	start := time.Now()
+	err := cmd.Run()
+	duration := time.Since(start)
+
+	var cmdErr *CommandError
+	if err != nil {
+		cmdErr = &CommandError{
+			Args:     argv,
+			ExitCode: -1,
+			Stderr:   tail(stderr.Bytes(), stderrTailSize),
+			Err:      err,
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			cmdErr.ExitCode = exitErr.ExitCode()
+		}
+		if r.Timeout > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			cmdErr.TimedOut = true
+			cmdErr.Err = fmt.Errorf("timed out after %v", r.Timeout)
+		}
+	}
+	if auditErr := r.audit(argv, start, duration, cmdErr); auditErr != nil && cmdErr == nil {
+		return stdout.Bytes(), auditErr
+	}
+	if cmdErr != nil {
+		return stdout.Bytes(), cmdErr
+	}
+	return stdout.Bytes(), nil
+}
+
+// audit appends a line for the command to the AuditFile.
+func (r *Runner) audit(argv []string, start time.Time, duration time.Duration, cmdErr *CommandError) error {
+	if r.AuditFile == "" {
+		return nil
+	}
+	result := "ok"
+	if cmdErr != nil {
+		result = cmdErr.Err.Error()
+	}
+	line := fmt.Sprintf("%s %v [%s] %s\n", start.UTC().Format(time.RFC3339), duration.Round(time.Millisecond), result, quoteArgs(argv))
+
+	r.auditLock.Lock()
+	defer r.auditLock.Unlock()
+	f, err := os.OpenFile(r.AuditFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("opening command audit file: %v", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(line); err != nil {
+		return fmt.Errorf("writing command audit file: %v", err)
+	}
+	return nil
+}
+
+// quoteArgs joins the arguments, quoting those which need it, so that
+// the command line can be pasted into a shell.
+func quoteArgs(argv []string) string {
+	quoted := make([]string, len(argv))
+	for i, arg := range argv {
+		if arg == "" || strings.ContainsAny(arg, " \t\n\"'\\$`*?;&|<>()") {
+			quoted[i] = strconv.Quote(arg)
+		} else {
+			quoted[i] = arg
+		}
+	}
+	return strings.Join(quoted, " ")
+}
+
+// tail returns at most the last n bytes of b, starting on a line.
+func tail(b []byte, n int) string {
+	if len(b) <= n {
+		return string(b)
+	}
+	b = b[len(b)-n:]
+	if i := bytes.IndexByte(b, '\n'); i >= 0 {
+		b = b[i+1:]
+	}
+	return string(b)
+}
@@ -0,0 +1,113 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exec
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunnerOutput(t *testing.T) {
+	var log bytes.Buffer
+	r := &Runner{Log: &log}
+	out, err := r.Run("sh", "-c", "echo out; echo err >&2")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if string(out) != "out\n" {
+		t.Errorf("Unexpected stdout %q", out)
+	}
+	if !strings.Contains(log.String(), "out\n") || !strings.Contains(log.String(), "err\n") {
+		t.Errorf("Output missing from log %q", log.String())
+	}
+}
+
+func TestRunnerEnv(t *testing.T) {
+	t.Setenv("MANTLE_RUNNER_TEST", "inherited")
+	r := &Runner{Env: []string{"PATH=" + os.Getenv("PATH"), "FOO=bar"}}
+	out, err := r.Run("sh", "-c", `echo "$FOO:$MANTLE_RUNNER_TEST"`)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if string(out) != "bar:\n" {
+		t.Errorf("Unexpected stdout %q", out)
+	}
+}
+
+func TestRunnerExitCode(t *testing.T) {
+	r := &Runner{}
+	_, err := r.Run("sh", "-c", "echo first >&2; echo second >&2; exit 3")
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		t.Fatalf("Expected a CommandError, got %v", err)
+	}
+	if cmdErr.ExitCode != 3 {
+		t.Errorf("Expected exit code 3, got %d", cmdErr.ExitCode)
+	}
+	if cmdErr.TimedOut {
+		t.Errorf("Command didn't time out")
+	}
+	if !strings.HasSuffix(err.Error(), "exit status 3: first\nsecond") {
+		t.Errorf("Unexpected error %q", err)
+	}
+
+	_, err = r.Run("/nonexistent")
+	if !errors.As(err, &cmdErr) || cmdErr.ExitCode != -1 {
+		t.Errorf("Expected a CommandError without an exit code, got %v", err)
+	}
+}
+
+func TestRunnerTimeout(t *testing.T) {
+	r := &Runner{Timeout: 100 * time.Millisecond}
+	start := time.Now()
+	_, err := r.Run("sleep", "3600")
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) || !cmdErr.TimedOut {
+		t.Fatalf("Expected a timeout, got %v", err)
+	}
+	if time.Since(start) > time.Minute {
+		t.Errorf("Command wasn't killed")
+	}
+}
+
+func TestRunnerAudit(t *testing.T) {
+	auditFile := filepath.Join(t.TempDir(), "commands.log")
+	r := &Runner{AuditFile: auditFile}
+	if _, err := r.Run("true", "a b"); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if _, err := r.Run("false"); err == nil {
+		t.Fatalf("Run succeeded unexpectedly")
+	}
+	data, err := os.ReadFile(auditFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 audit lines, got %q", data)
+	}
+	if !strings.HasSuffix(lines[0], `[ok] true "a b"`) {
+		t.Errorf("Unexpected audit line %q", lines[0])
+	}
+	if !strings.HasSuffix(lines[1], "[exit status 1] false") {
+		t.Errorf("Unexpected audit line %q", lines[1])
+	}
+}
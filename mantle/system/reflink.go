@@ -0,0 +1,161 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package system
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+)
+
+// CopyMethod is how StageFile copied a file.
+type CopyMethod string
+
+const (
+	// CopyReflink shares the source's extents, so costs no space or I/O
+	CopyReflink CopyMethod = "reflink"
+	// CopyOffload copies in the kernel with copy_file_range(2), which
+	// some filesystems offload to the storage
+	CopyOffload CopyMethod = "copy_file_range"
+	// CopyReadWrite reads and writes the data in userspace
+	CopyReadWrite CopyMethod = "read/write"
+)
+
+// StageFile copies the regular file src to dest like `cp -a
+// --reflink=auto`: it reflinks it if the filesystem can, falls back to
+// copy_file_range(2) and then to reading and writing it, and keeps its mode
+// and modification time. If dest is a directory, the file is copied into
+// it. An existing file at dest is replaced. It returns how the file was
+// copied.
+func StageFile(src, dest string) (CopyMethod, error) {
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer srcFile.Close()
+	info, err := srcFile.Stat()
+	if err != nil {
+		return "", err
+	}
+	if !info.Mode().IsRegular() {
+		return "", fmt.Errorf("Not a regular file: %s", src)
+	}
+	if destInfo, err := os.Stat(dest); err == nil && destInfo.IsDir() {
+		dest = filepath.Join(dest, filepath.Base(src))
+	}
+
+	// write a temporary file and rename it so that readers never see a
+	// partial copy
+	destFile, err := os.CreateTemp(filepath.Dir(dest), "."+filepath.Base(dest)+".*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(destFile.Name())
+	method, err := copyFileData(destFile, srcFile, info.Size())
+	if errClose := destFile.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		return "", fmt.Errorf("copying %s to %s: %w", src, dest, err)
+	}
+	if err := os.Chmod(destFile.Name(), info.Mode().Perm()); err != nil {
+		return "", err
+	}
+	if err := os.Chtimes(destFile.Name(), info.ModTime(), info.ModTime()); err != nil {
+		return "", err
+	}
+	if err := os.Rename(destFile.Name(), dest); err != nil {
+		return "", err
+	}
+	return method, nil
+}
+
+// copyFileData copies the size bytes of src to the empty dest with the
+// cheapest method which works.
+func copyFileData(dest, src *os.File, size int64) (CopyMethod, error) {
+	err := unix.IoctlFileClone(int(dest.Fd()), int(src.Fd()))
+	if err == nil {
+		return CopyReflink, nil
+	} else if !isUnsupported(err) {
+		return "", fmt.Errorf("reflinking: %w", err)
+	}
+
+	var copied int64
+	for copied < size {
+		n, err := unix.CopyFileRange(int(src.Fd()), nil, int(dest.Fd()), nil, int(size-copied), 0)
+		if err != nil {
+			if copied == 0 && isUnsupported(err) {
+				break
+			}
+			return "", fmt.Errorf("copy_file_range: %w", err)
+		}
+		if n == 0 {
+			// the file shrank
+			break
+		}
+		copied += int64(n)
+	}
+	if copied > 0 || size == 0 {
+		return CopyOffload, nil
+	}
+
+	// hide dest's ReadFrom, which would try copy_file_range(2) again
+	if _, err := io.Copy(struct{ io.Writer }{dest}, src); err != nil {
+		return "", err
+	}
+	return CopyReadWrite, nil
+}
+
+// isUnsupported returns whether err is how reflinking or copy_file_range(2)
+// report that the filesystems, or the kernel, can't do it.
+func isUnsupported(err error) bool {
+	for _, errno := range []unix.Errno{unix.EOPNOTSUPP, unix.ENOTSUP, unix.EXDEV, unix.EINVAL, unix.ENOTTY, unix.ENOSYS, unix.EPERM} {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+	return false
+}
+
+// SupportsReflink returns whether files in dir can be reflinked, by trying
+// it.
+func SupportsReflink(dir string) (bool, error) {
+	src, err := os.CreateTemp(dir, ".reflink-check.*")
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(src.Name())
+	defer src.Close()
+	if _, err := src.WriteString("reflink check\n"); err != nil {
+		return false, err
+	}
+	dest, err := os.CreateTemp(dir, ".reflink-check.*")
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(dest.Name())
+	defer dest.Close()
+	err = unix.IoctlFileClone(int(dest.Fd()), int(src.Fd()))
+	if err == nil {
+		return true, nil
+	} else if isUnsupported(err) {
+		return false, nil
+	}
+	return false, fmt.Errorf("reflinking in %s: %w", dir, err)
+}
@@ -0,0 +1,81 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package system
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStageFile(t *testing.T) {
+	data := []byte("test")
+	tmp := t.TempDir()
+
+	src := filepath.Join(tmp, "src")
+	if err := os.WriteFile(src, data, 0640); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(src, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	reflinks, err := SupportsReflink(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	copy1 := filepath.Join(tmp, "copy1")
+	method, err := StageFile(src, copy1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkFile(t, copy1, data, 0640)
+	if reflinks && method != CopyReflink {
+		t.Errorf("Copied with %s on a filesystem supporting reflinks", method)
+	}
+	if info, err := os.Stat(copy1); err != nil {
+		t.Fatal(err)
+	} else if !info.ModTime().Equal(mtime) {
+		t.Errorf("Unexpected mtime: %s != %s", info.ModTime(), mtime)
+	}
+
+	// into a directory, replacing what's there
+	dir := filepath.Join(tmp, "dir")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "src"), []byte("old contents"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := StageFile(src, dir); err != nil {
+		t.Fatal(err)
+	}
+	checkFile(t, filepath.Join(dir, "src"), data, 0640)
+
+	empty := filepath.Join(tmp, "empty")
+	if err := os.WriteFile(empty, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := StageFile(empty, filepath.Join(tmp, "copy2")); err != nil {
+		t.Fatal(err)
+	}
+	checkFile(t, filepath.Join(tmp, "copy2"), nil, 0600)
+
+	if _, err := StageFile(tmp, filepath.Join(tmp, "copy3")); err == nil {
+		t.Error("Staged a directory")
+	}
+}
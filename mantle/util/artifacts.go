@@ -0,0 +1,218 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	cosa "github.com/coreos/coreos-assembler/pkg/builds"
+)
+
+// LocalArtifact is an artifact of a local build.
+type LocalArtifact struct {
+	// Name is the artifact's key in meta.json, such as "live-iso".
+	Name string
+	Arch string
+	// Path is the artifact's local file.
+	Path string
+	*cosa.Artifact
+}
+
+// ChecksumError is an artifact whose file doesn't match its checksum in
+// meta.json.
+type ChecksumError struct {
+	Name     string
+	Path     string
+	Expected string
+	Actual   string
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("checksum mismatch for artifact %s (%s): expected sha256 %s, got %s", e.Name, e.Path, e.Expected, e.Actual)
+}
+
+// Artifacts returns the artifacts defined in the build, sorted by name,
+// whether or not their files exist locally.
+func (b *LocalBuild) Artifacts() []LocalArtifact {
+	var ret []LocalArtifact
+	for name, a := range b.Meta.Artifacts() {
+		ret = append(ret, b.localArtifact(name, a))
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Name < ret[j].Name })
+	return ret
+}
+
+// FindArtifacts returns the artifacts of the build of the given types,
+// their keys in meta.json such as "qemu" or "live-iso", sorted by name.
+// With no types, it returns them all.
+func (b *LocalBuild) FindArtifacts(types ...string) []LocalArtifact {
+	if len(types) == 0 {
+		return b.Artifacts()
+	}
+	var ret []LocalArtifact
+	for _, a := range b.Artifacts() {
+		for _, typ := range types {
+			if a.Name == typ {
+				ret = append(ret, a)
+				break
+			}
+		}
+	}
+	return ret
+}
+
+// Artifact returns the artifact of the build with the name.
+func (b *LocalBuild) Artifact(name string) (*LocalArtifact, error) {
+	a, err := b.Meta.GetArtifact(name)
+	if err != nil {
+		return nil, fmt.Errorf("artifact %s is missing from build %s: %w", name, b.Meta.BuildID, err)
+	}
+	local := b.localArtifact(name, a)
+	return &local, nil
+}
+
+func (b *LocalBuild) localArtifact(name string, a *cosa.Artifact) LocalArtifact {
+	return LocalArtifact{
+		Name:     name,
+		Arch:     b.Arch,
+		Path:     filepath.Join(b.Dir, a.Path),
+		Artifact: a,
+	}
+}
+
+// artifactCheck is the fetching and verification of an artifact, made
+// once however many callers require it.
+type artifactCheck struct {
+	once sync.Once
+	err  error
+}
+
+// RequireArtifact returns the local path of the artifact, after fetching
+// it from RemoteURL if it's missing, and verifying its checksum. Each
+// artifact is checked once, and a failure is returned to every caller;
+// different artifacts are checked in parallel.
+func (b *LocalBuild) RequireArtifact(name string) (string, error) {
+	a, err := b.Artifact(name)
+	if err != nil {
+		return "", err
+	}
+
+	b.verifyLock.Lock()
+	check, ok := b.verified[name]
+	if !ok {
+		if b.verified == nil {
+			b.verified = make(map[string]*artifactCheck)
+		}
+		check = &artifactCheck{}
+		b.verified[name] = check
+	}
+	b.verifyLock.Unlock()
+
+	check.once.Do(func() {
+		check.err = b.checkArtifact(a)
+	})
+	if check.err != nil {
+		return "", check.err
+	}
+	return a.Path, nil
+}
+
+func (b *LocalBuild) checkArtifact(a *LocalArtifact) error {
+	if exists, err := PathExists(a.Path); err != nil {
+		return err
+	} else if !exists {
+		if b.RemoteURL == "" {
+			return fmt.Errorf("artifact %s of build %s isn't present locally at %s", a.Name, b.Meta.BuildID, a.Path)
+		}
+		if err := b.fetch(a); err != nil {
+			return err
+		}
+	}
+	return a.Verify()
+}
+
+// Verify checks that the artifact's file matches its sha256 in meta.json.
+// Artifacts without one aren't checked.
+func (a *LocalArtifact) Verify() error {
+	if a.Sha256 == "" {
+		return nil
+	}
+	f, err := os.Open(a.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return fmt.Errorf("reading artifact %s: %v", a.Name, err)
+	}
+	if actual := hex.EncodeToString(h.Sum(nil)); actual != a.Sha256 {
+		return &ChecksumError{Name: a.Name, Path: a.Path, Expected: a.Sha256, Actual: actual}
+	}
+	return nil
+}
+
+// fetch downloads the artifact from RemoteURL, verifying it before moving
+// it into place.
+func (b *LocalBuild) fetch(a *LocalArtifact) error {
+	url := strings.TrimSuffix(b.RemoteURL, "/") + "/" + a.Artifact.Path
+	plog.Noticef("Fetching artifact %s from %s", a.Name, url)
+
+	client := &http.Client{Timeout: time.Hour}
+	resp, err := client.Get(url)
+	if err != nil {
+		return fmt.Errorf("fetching artifact %s: %v", a.Name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetching artifact %s from %s: %s", a.Name, url, resp.Status)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(a.Path), "."+filepath.Base(a.Path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = io.Copy(tmp, resp.Body)
+	if errClose := tmp.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		return fmt.Errorf("fetching artifact %s: %v", a.Name, err)
+	}
+
+	fetched := *a
+	fetched.Path = tmp.Name()
+	if err := fetched.Verify(); err != nil {
+		if checksumErr, ok := err.(*ChecksumError); ok {
+			checksumErr.Path = url
+		}
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), a.Path)
+}
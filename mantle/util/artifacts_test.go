@@ -0,0 +1,159 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func sha256Hex(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+// writeTestBuild writes a build of the artifacts, mapping their types to
+// their contents, for each architecture. Only the contents of local are
+// written.
+func writeTestBuild(t *testing.T, root string, arches []string, artifacts, local map[string]string) {
+	for _, arch := range arches {
+		dir := filepath.Join(root, "builds", "1", arch)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		images := map[string]interface{}{}
+		for typ, data := range artifacts {
+			images[typ] = map[string]string{"path": typ + ".img", "sha256": sha256Hex(data)}
+		}
+		for typ, data := range local {
+			if err := os.WriteFile(filepath.Join(dir, typ+".img"), []byte(data), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		meta, err := json.Marshal(map[string]interface{}{"buildid": "1", "images": images})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "meta.json"), meta, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFindArtifacts(t *testing.T) {
+	root := t.TempDir()
+	artifacts := map[string]string{"qemu": "q", "metal": "m", "live-iso": "l"}
+	writeTestBuild(t, root, []string{"x86_64", "aarch64"}, artifacts, nil)
+
+	arches, err := GetLocalBuildArches(root, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"aarch64", "x86_64"}; !reflect.DeepEqual(arches, want) {
+		t.Errorf("got arches %v, expected %v", arches, want)
+	}
+
+	for _, arch := range arches {
+		b, err := GetLocalBuild(root, "1", arch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, tt := range []struct {
+			types []string
+			want  []string
+		}{
+			{nil, []string{"live-iso", "metal", "qemu"}},
+			{[]string{"qemu", "live-iso"}, []string{"live-iso", "qemu"}},
+			{[]string{"aws"}, nil},
+		} {
+			var got []string
+			for _, a := range b.FindArtifacts(tt.types...) {
+				if a.Arch != arch || a.Path != filepath.Join(root, "builds", "1", arch, a.Name+".img") {
+					t.Errorf("%s: got %s for %s at %s", arch, a.Name, a.Arch, a.Path)
+				}
+				got = append(got, a.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s: types %v: got %v, expected %v", arch, tt.types, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestRequireArtifact(t *testing.T) {
+	root := t.TempDir()
+	artifacts := map[string]string{"qemu": "qemu image", "metal": "metal image", "live-iso": "iso"}
+	writeTestBuild(t, root, []string{"x86_64"}, artifacts, map[string]string{
+		"qemu":     "qemu image",
+		"live-iso": "corrupted",
+	})
+
+	var fetches atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		if r.URL.Path != "/metal.img" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("metal image"))
+	}))
+	defer srv.Close()
+
+	b, err := GetLocalBuild(root, "1", "x86_64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.RemoteURL = srv.URL + "/"
+
+	if path, err := b.RequireArtifact("qemu"); err != nil || path != filepath.Join(b.Dir, "qemu.img") {
+		t.Errorf("qemu: got %q, %v", path, err)
+	}
+
+	var checksumErr *ChecksumError
+	if _, err := b.RequireArtifact("live-iso"); !errors.As(err, &checksumErr) {
+		t.Errorf("live-iso: got error %v, expected a checksum mismatch", err)
+	} else if checksumErr.Actual != sha256Hex("corrupted") {
+		t.Errorf("live-iso: got checksum %s", checksumErr.Actual)
+	}
+
+	// Concurrent callers share one fetch.
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := b.RequireArtifact("metal"); err != nil {
+				t.Errorf("metal: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("fetched %d times, expected once", n)
+	}
+	if data, err := os.ReadFile(filepath.Join(b.Dir, "metal.img")); err != nil || string(data) != "metal image" {
+		t.Errorf("metal not fetched into place: %q, %v", data, err)
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DiskKeys are the options a platform accepts in disk specs, mapped to
+// whether they take a value.
+type DiskKeys map[string]bool
+
+// DiskSpec is a parsed disk specification.
+type DiskSpec struct {
+	// Size is in bytes, or zero if the spec has none.
+	Size int64
+	// Options maps option keys to their values, which are empty for
+	// options without one.
+	Options map[string]string
+
+	spec string
+	// positions maps option keys to the offset of their value, or of
+	// the key for options without one.
+	positions map[string]int
+}
+
+// DiskSpecError is an invalid disk spec. Pos is the byte offset in Spec
+// of the problem.
+type DiskSpecError struct {
+	Spec string
+	Pos  int
+	Msg  string
+}
+
+func (e *DiskSpecError) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Pos+1, e.Msg)
+}
+
+var diskSizeRe = regexp.MustCompile(`^([0-9]+(?:\.[0-9]+)?)([MGT])$`)
+
+var diskSizeUnits = map[string]float64{
+	"M": 1 << 20,
+	"G": 1 << 30,
+	"T": 1 << 40,
+}
+
+// ParseDiskSpec parses a disk specification. The format is:
+// <size>[:<opt1>,<opt2>,...], like "5G:channel=nvme" or "1.5T:4k". Sizes
+// are in binary units, M, G or T, and may be fractional. Options are
+// checked against keys.
+func ParseDiskSpec(spec string, allowNoSize bool, keys DiskKeys) (*DiskSpec, error) {
+	d := &DiskSpec{
+		Options:   map[string]string{},
+		spec:      spec,
+		positions: map[string]int{},
+	}
+	fail := func(pos int, format string, args ...interface{}) error {
+		return &DiskSpecError{Spec: spec, Pos: pos, Msg: fmt.Sprintf(format, args...)}
+	}
+
+	size, opts, hasOpts := strings.Cut(spec, ":")
+	if size == "" {
+		if !allowNoSize {
+			return nil, fail(0, "no size given")
+		}
+	} else {
+		m := diskSizeRe.FindStringSubmatch(size)
+		if m == nil {
+			return nil, fail(0, "invalid size %q; expected a number followed by M, G or T", size)
+		}
+		n, err := strconv.ParseFloat(m[1], 64)
+		if err != nil {
+			return nil, fail(0, "invalid size %q: %v", size, err)
+		}
+		bytes := math.Ceil(n * diskSizeUnits[m[2]])
+		if bytes <= 0 {
+			return nil, fail(0, "size must be positive")
+		}
+		if bytes > math.MaxInt64 {
+			return nil, fail(0, "size %q is too large", size)
+		}
+		d.Size = int64(bytes)
+	}
+	if !hasOpts {
+		return d, nil
+	}
+
+	pos := len(size) + 1
+	for _, opt := range strings.Split(opts, ",") {
+		key, value, hasValue := strings.Cut(opt, "=")
+		switch {
+		case key == "":
+			return nil, fail(pos, "empty option")
+		case strings.Contains(opt, ":"):
+			return nil, fail(pos+strings.Index(opt, ":"), "unexpected ':'")
+		}
+		takesValue, ok := keys[key]
+		switch {
+		case !ok:
+			return nil, fail(pos, "unknown option %q%s", key, keys.suggest(key))
+		case takesValue && !hasValue:
+			return nil, fail(pos, "option %q needs a value", key)
+		case !takesValue && hasValue:
+			return nil, fail(pos+len(key), "option %q doesn't take a value", key)
+		}
+		if _, dup := d.Options[key]; dup {
+			return nil, fail(pos, "option %q given more than once", key)
+		}
+		d.Options[key] = value
+		d.positions[key] = pos
+		if hasValue {
+			d.positions[key] += len(key) + 1
+		}
+		pos += len(opt) + 1
+	}
+	return d, nil
+}
+
+// GiB returns the size in GiB, rounded up.
+func (d *DiskSpec) GiB() int64 {
+	return (d.Size + (1<<30 - 1)) >> 30
+}
+
+// OptionError returns an error about the value of the option key, at its
+// position in the spec.
+func (d *DiskSpec) OptionError(key, format string, args ...interface{}) error {
+	return &DiskSpecError{Spec: d.spec, Pos: d.positions[key], Msg: fmt.Sprintf(format, args...)}
+}
+
+// suggest returns a hint naming the key closest to the unknown one, if
+// one is close enough to be a typo, or otherwise the keys there are.
+func (keys DiskKeys) suggest(unknown string) string {
+	var names []string
+	for k := range keys {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		return "; no options are supported"
+	}
+	best, bestDist := "", 3
+	for _, k := range names {
+		if dist := editDistance(unknown, k); dist < bestDist {
+			best, bestDist = k, dist
+		}
+	}
+	if best != "" {
+		return fmt.Sprintf("; did you mean %q?", best)
+	}
+	return "; options are " + strings.Join(names, ", ")
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
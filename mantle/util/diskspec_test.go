@@ -0,0 +1,102 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+var testDiskKeys = DiskKeys{
+	"4k":      false,
+	"channel": true,
+	"serial":  true,
+}
+
+func TestParseDiskSpec(t *testing.T) {
+	for _, tt := range []struct {
+		spec        string
+		allowNoSize bool
+		keys        DiskKeys
+		size        int64
+		options     map[string]string
+		err         string
+	}{
+		{spec: "5G", size: 5 << 30, options: map[string]string{}},
+		{spec: "1.5T", size: 3 << 39, options: map[string]string{}},
+		{spec: "0.5M", size: 1 << 19, options: map[string]string{}},
+		{spec: "512M:4k,serial=x", size: 512 << 20, options: map[string]string{"4k": "", "serial": "x"}},
+		{spec: "5G:channel=nvme", size: 5 << 30, options: map[string]string{"channel": "nvme"}},
+		{spec: ":serial=x", allowNoSize: true, options: map[string]string{"serial": "x"}},
+
+		{spec: "", err: "column 1: no size given"},
+		{spec: ":serial=x", err: "column 1: no size given"},
+		{spec: "5X", err: `column 1: invalid size "5X"; expected a number followed by M, G or T`},
+		{spec: "5", err: `column 1: invalid size "5"; expected a number followed by M, G or T`},
+		{spec: "0G", err: "column 1: size must be positive"},
+		{spec: "99999999999T", err: `column 1: size "99999999999T" is too large`},
+		{spec: "5G:", err: "column 4: empty option"},
+		{spec: "5G:4k,,serial=x", err: "column 7: empty option"},
+		{spec: "5G:4k:x", err: "column 6: unexpected ':'"},
+		{spec: "5G:4k,serail=x", err: `column 7: unknown option "serail"; did you mean "serial"?`},
+		{spec: "5G:chanel=nvme", err: `column 4: unknown option "chanel"; did you mean "channel"?`},
+		{spec: "5G:bogus", err: `column 4: unknown option "bogus"; options are 4k, channel, serial`},
+		{spec: "5G:4k", keys: DiskKeys{}, err: `column 4: unknown option "4k"; no options are supported`},
+		{spec: "5G:serial", err: `column 4: option "serial" needs a value`},
+		{spec: "5G:4k=1", err: `column 6: option "4k" doesn't take a value`},
+		{spec: "5G:4k,4k", err: `column 7: option "4k" given more than once`},
+	} {
+		keys := tt.keys
+		if keys == nil {
+			keys = testDiskKeys
+		}
+		d, err := ParseDiskSpec(tt.spec, tt.allowNoSize, keys)
+		if tt.err != "" {
+			if err == nil {
+				t.Errorf("%q: expected error %q", tt.spec, tt.err)
+			} else if err.Error() != tt.err {
+				t.Errorf("%q: got error %q, expected %q", tt.spec, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tt.spec, err)
+			continue
+		}
+		if d.Size != tt.size {
+			t.Errorf("%q: got size %d, expected %d", tt.spec, d.Size, tt.size)
+		}
+		if !reflect.DeepEqual(d.Options, tt.options) {
+			t.Errorf("%q: got options %v, expected %v", tt.spec, d.Options, tt.options)
+		}
+	}
+}
+
+func TestDiskSpecOptionError(t *testing.T) {
+	d, err := ParseDiskSpec("512M:4k,serial=x", false, testDiskKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := d.GiB(), int64(1); got != want {
+		t.Errorf("got %d GiB, expected %d", got, want)
+	}
+	// Errors about an option's value point at the value.
+	if err, want := d.OptionError("serial", "bad serial"), "column 16: bad serial"; err.Error() != want {
+		t.Errorf("got error %q, expected %q", err, want)
+	}
+	if err, want := d.OptionError("4k", "unsupported"), "column 6: unsupported"; err.Error() != want {
+		t.Errorf("got error %q, expected %q", err, want)
+	}
+}
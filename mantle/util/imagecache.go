@@ -0,0 +1,284 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// decompressors are the commands which decompress artifacts to stdout, by
+// the suffix of their files. xz and zstd use all CPUs.
+var decompressors = map[string][]string{
+	".xz":  {"xz", "-dc", "-T0"},
+	".gz":  {"gzip", "-dc"},
+	".zst": {"zstd", "-dc", "-T0"},
+}
+
+// CompressionSuffix returns the suffix of a compressed file which can be
+// decompressed, or "".
+func CompressionSuffix(path string) string {
+	ext := filepath.Ext(path)
+	if _, ok := decompressors[ext]; ok {
+		return ext
+	}
+	return ""
+}
+
+// ImageCache keeps decompressed copies of compressed build artifacts,
+// keyed on the build ID, arch and artifact, so that repeated runs don't
+// decompress multi-gigabyte images each time. Runs on the same host can
+// share it: each entry is locked with flock(2), shared while it's in use
+// and exclusively while it's written, so runs neither decompress the same
+// artifact twice nor evict one another's images. The locks are held until
+// Close() or the process exits.
+type ImageCache struct {
+	Dir string
+	// MaxBytes, if nonzero, is the size the cache is trimmed to after
+	// adding an entry, removing the least recently used entries which
+	// aren't in use
+	MaxBytes int64
+
+	mu   sync.Mutex
+	held map[string]*os.File
+}
+
+// NewImageCache returns a cache in dir, which is created if it doesn't
+// exist.
+func NewImageCache(dir string, maxBytes int64) (*ImageCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &ImageCache{
+		Dir:      dir,
+		MaxBytes: maxBytes,
+		held:     make(map[string]*os.File),
+	}, nil
+}
+
+// Decompressed returns the path of a decompressed copy of the artifact,
+// decompressing it into the cache if it isn't there yet, and verifying it
+// against uncompressedSha256 if that's set. The copy mustn't be modified.
+func (c *ImageCache) Decompressed(buildID, arch string, artifact *LocalArtifact) (string, error) {
+	suffix := CompressionSuffix(artifact.Path)
+	if suffix == "" {
+		return artifact.Path, nil
+	}
+	entry := filepath.Join(c.Dir, fmt.Sprintf("%s-%s-%s", buildID, arch, strings.TrimSuffix(filepath.Base(artifact.Path), suffix)))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.held == nil {
+		return "", fmt.Errorf("image cache is closed")
+	}
+	if _, ok := c.held[entry]; ok {
+		return entry, nil
+	}
+	lock, err := os.OpenFile(entry+".lock", os.O_RDONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return "", err
+	}
+	ok, err := c.use(lock, entry)
+	if err == nil && !ok {
+		err = c.populate(lock, entry, artifact)
+	}
+	if err != nil {
+		lock.Close()
+		return "", err
+	}
+	c.held[entry] = lock
+	if err := c.trim(); err != nil {
+		plog.Warningf("Trimming image cache %s: %v", c.Dir, err)
+	}
+	return entry, nil
+}
+
+// use takes a shared lock on the entry, returning whether it exists. It's
+// marked as used now for the LRU order.
+func (c *ImageCache) use(lock *os.File, entry string) (bool, error) {
+	if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_SH); err != nil {
+		return false, fmt.Errorf("locking %s: %v", lock.Name(), err)
+	}
+	if exists, err := PathExists(entry); err != nil || !exists {
+		return false, err
+	}
+	now := time.Now()
+	if err := os.Chtimes(entry, now, now); err != nil {
+		return false, err
+	}
+	plog.Debugf("Using cached %s", entry)
+	return true, nil
+}
+
+// populate decompresses the artifact into the entry under an exclusive
+// lock, unless another run did in the meantime, then goes back to a shared
+// lock.
+func (c *ImageCache) populate(lock *os.File, entry string, artifact *LocalArtifact) error {
+	if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX); err != nil {
+		return fmt.Errorf("locking %s: %v", lock.Name(), err)
+	}
+	if exists, err := PathExists(entry); err != nil {
+		return err
+	} else if !exists {
+		if err := decompressArtifact(artifact, entry); err != nil {
+			return err
+		}
+	}
+	return syscall.Flock(int(lock.Fd()), syscall.LOCK_SH)
+}
+
+// decompressArtifact decompresses the artifact to dest, verifying it before
+// moving it into place.
+func decompressArtifact(artifact *LocalArtifact, dest string) error {
+	plog.Noticef("Decompressing %s into %s", artifact.Path, filepath.Dir(dest))
+	argv := decompressors[CompressionSuffix(artifact.Path)]
+	tmp, err := os.CreateTemp(filepath.Dir(dest), "."+filepath.Base(dest)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	cmd := exec.Command(argv[0], append(argv[1:], artifact.Path)...)
+	cmd.Stdout = io.MultiWriter(tmp, h)
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	if errClose := tmp.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		return fmt.Errorf("decompressing artifact %s: %v", artifact.Name, err)
+	}
+	if artifact.Artifact != nil && artifact.UncompressedSha256 != "" {
+		if actual := hex.EncodeToString(h.Sum(nil)); actual != artifact.UncompressedSha256 {
+			return &ChecksumError{Name: artifact.Name, Path: dest, Expected: artifact.UncompressedSha256, Actual: actual}
+		}
+	}
+	if err := os.Chmod(tmp.Name(), 0444); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dest)
+}
+
+// trim removes the least recently used entries which no run is using
+// until the cache fits in MaxBytes.
+func (c *ImageCache) trim() error {
+	if c.MaxBytes <= 0 {
+		return nil
+	}
+	locks, err := filepath.Glob(filepath.Join(c.Dir, "*.lock"))
+	if err != nil {
+		return err
+	}
+	type cached struct {
+		path string
+		size int64
+		used time.Time
+	}
+	var entries []cached
+	var total int64
+	for _, lock := range locks {
+		path := strings.TrimSuffix(lock, ".lock")
+		info, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		entries = append(entries, cached{path, info.Size(), info.ModTime()})
+		total += info.Size()
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].used.Before(entries[j].used) })
+	for _, e := range entries {
+		if total <= c.MaxBytes {
+			break
+		}
+		if _, ok := c.held[e.path]; ok {
+			continue
+		}
+		removed, err := removeUnusedEntry(e.path)
+		if err != nil {
+			return err
+		}
+		if removed {
+			plog.Debugf("Evicted %s from image cache", e.path)
+			total -= e.size
+		}
+	}
+	if total > c.MaxBytes {
+		plog.Warningf("Image cache %s is over its limit, but its images are in use", c.Dir)
+	}
+	return nil
+}
+
+// removeUnusedEntry removes the entry if no run holds its lock.
+func removeUnusedEntry(path string) (bool, error) {
+	lock, err := os.OpenFile(path+".lock", os.O_RDONLY, 0)
+	if err != nil {
+		return false, err
+	}
+	defer lock.Close()
+	if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		if err == syscall.EWOULDBLOCK {
+			return false, nil
+		}
+		return false, fmt.Errorf("locking %s: %v", lock.Name(), err)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	return true, nil
+}
+
+// Close releases the cache's entries, which other runs may then evict.
+func (c *ImageCache) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, lock := range c.held {
+		lock.Close()
+	}
+	c.held = nil
+}
+
+// RequireDecompressedArtifact returns the local path of the artifact like
+// RequireArtifact, or if it's compressed, that of a decompressed copy in
+// the cache.
+func (b *LocalBuild) RequireDecompressedArtifact(name string, cache *ImageCache) (string, error) {
+	path, err := b.RequireArtifact(name)
+	if err != nil {
+		return "", err
+	}
+	if CompressionSuffix(path) == "" {
+		return path, nil
+	}
+	if cache == nil {
+		return "", fmt.Errorf("artifact %s of build %s is compressed, and there's no image cache to decompress it into", name, b.Meta.BuildID)
+	}
+	a, err := b.Artifact(name)
+	if err != nil {
+		return "", err
+	}
+	return cache.Decompressed(b.Meta.BuildID, b.Arch, a)
+}
@@ -0,0 +1,177 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The names of the resources mantle creates on clouds are
+//
+//	<purpose>-<owner>-<created>-<random>
+//
+// where the purpose, such as "kola" or "kola-cluster", says what the
+// resource is for, the owner who created it, the creation time is UTC in
+// the form 20060102150405, and the random part is 12 hex digits. They're
+// lower case, and only letters, digits and dashes, which every platform
+// accepts. Garbage collectors parse them with ParseResourceName to find
+// mantle's resources, and when they were created, on platforms which don't
+// record it or let them be tagged.
+//
+// Names only collide if the same owner creates them for the same purpose in
+// the same second and 48 random bits match, which is unlikely even for
+// thousands of resources a second. More would make names of machines too
+// long for PowerVS, which allows 47 characters.
+const (
+	resourceNameTime   = "20060102150405"
+	resourceNameRandom = 6
+	maxOwnerLength     = 12
+)
+
+// mantlePurposes are the purposes of mantle's resources, which garbage
+// collectors may delete: kola's, such as "kola" and "kola-cluster", ore's,
+// and those created without a purpose.
+var mantlePurposes = []string{"kola", "ore", "mantle"}
+
+// ResourceOwner is the owner recorded in resource names: by default the
+// user running mantle, or failing that the host it's running on.
+var ResourceOwner = defaultResourceOwner()
+
+func defaultResourceOwner() string {
+	var owner string
+	if u, err := user.Current(); err == nil {
+		owner = sanitizeName(u.Username, false)
+	}
+	if owner == "" {
+		if host, err := os.Hostname(); err == nil {
+			owner = sanitizeName(host, false)
+		}
+	}
+	if owner == "" {
+		return "unknown"
+	}
+	return owner
+}
+
+// sanitizeName lower-cases name and drops the characters other than
+// letters and digits or, if dashes is set, replaces runs of them with a
+// dash.
+func sanitizeName(name string, dashes bool) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			dash = false
+			b.WriteRune(r)
+		} else {
+			dash = dashes
+		}
+	}
+	return b.String()
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		plog.Errorf("failed to generate a random name: %v", err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// ResourceName returns a new name for a resource created for purpose,
+// recording when and by whom it was created.
+func ResourceName(purpose string) string {
+	purpose = sanitizeName(purpose, true)
+	if purpose == "" {
+		purpose = "mantle"
+	}
+	owner := sanitizeName(ResourceOwner, false)
+	if len(owner) > maxOwnerLength {
+		owner = owner[:maxOwnerLength]
+	}
+	if owner == "" {
+		owner = "unknown"
+	}
+	created := time.Now().UTC().Format(resourceNameTime)
+	return strings.Join([]string{purpose, owner, created, randomHex(resourceNameRandom)}, "-")
+}
+
+// CompactResourceName returns a new name for a resource created for
+// purpose whose name can only be up to 24 letters and digits, such as an
+// Azure storage account. It records when the resource was created, in
+// seconds since the epoch in base 36, but can't be parsed back.
+func CompactResourceName(purpose string) string {
+	created := strconv.FormatInt(time.Now().Unix(), 36)
+	return sanitizeName(purpose, false) + created + randomHex(resourceNameRandom)
+}
+
+// ResourceNameInfo is what a name from ResourceName records.
+type ResourceNameInfo struct {
+	Purpose string
+	Owner   string
+	Created time.Time
+}
+
+// FromMantle returns whether the name's purpose is one of mantle's, so that
+// garbage collectors can leave alone resources of others whose names merely
+// have the same form.
+func (info ResourceNameInfo) FromMantle() bool {
+	for _, p := range mantlePurposes {
+		if info.Purpose == p || strings.HasPrefix(info.Purpose, p+"-") {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseResourceName parses a name from ResourceName, reporting whether it
+// is one.
+func ParseResourceName(name string) (ResourceNameInfo, bool) {
+	fields := strings.Split(name, "-")
+	n := len(fields)
+	if n < 4 {
+		return ResourceNameInfo{}, false
+	}
+	random := fields[n-1]
+	if _, err := hex.DecodeString(random); err != nil || len(random) != 2*resourceNameRandom {
+		return ResourceNameInfo{}, false
+	}
+	created, err := time.Parse(resourceNameTime, fields[n-2])
+	if err != nil {
+		return ResourceNameInfo{}, false
+	}
+	owner := fields[n-3]
+	if owner == "" || owner != sanitizeName(owner, false) {
+		return ResourceNameInfo{}, false
+	}
+	purpose := strings.Join(fields[:n-3], "-")
+	if purpose == "" || purpose != sanitizeName(purpose, true) {
+		return ResourceNameInfo{}, false
+	}
+	return ResourceNameInfo{
+		Purpose: purpose,
+		Owner:   owner,
+		Created: created,
+	}, true
+}
@@ -0,0 +1,160 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResourceNameRoundTrip(t *testing.T) {
+	saved := ResourceOwner
+	defer func() { ResourceOwner = saved }()
+	ResourceOwner = "J.Doe@example"
+
+	for _, tt := range []struct {
+		purpose string
+		want    string
+	}{
+		{"kola", "kola"},
+		{"kola-cluster", "kola-cluster"},
+		{"Kola_Cluster", "kola-cluster"},
+		{"", "mantle"},
+	} {
+		before := time.Now().UTC().Truncate(time.Second)
+		name := ResourceName(tt.purpose)
+		after := time.Now().UTC()
+
+		info, ok := ParseResourceName(name)
+		if !ok {
+			t.Errorf("ParseResourceName(%q) didn't parse", name)
+			continue
+		}
+		if info.Purpose != tt.want {
+			t.Errorf("%q: purpose %q, want %q", name, info.Purpose, tt.want)
+		}
+		if info.Owner != "jdoeexample" {
+			t.Errorf("%q: owner %q, want %q", name, info.Owner, "jdoeexample")
+		}
+		if info.Created.Before(before) || info.Created.After(after) {
+			t.Errorf("%q: created %v, not between %v and %v", name, info.Created, before, after)
+		}
+		if !info.FromMantle() {
+			t.Errorf("%q: not from mantle", name)
+		}
+		if name != strings.ToLower(name) {
+			t.Errorf("%q isn't lower case", name)
+		}
+	}
+}
+
+func TestResourceNameUnique(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		name := ResourceName("kola")
+		if seen[name] {
+			t.Fatalf("%q generated twice", name)
+		}
+		seen[name] = true
+	}
+}
+
+func TestResourceNameOwnerLength(t *testing.T) {
+	saved := ResourceOwner
+	defer func() { ResourceOwner = saved }()
+	ResourceOwner = "averyveryverylongusername"
+
+	info, ok := ParseResourceName(ResourceName("kola"))
+	if !ok {
+		t.Fatal("didn't parse")
+	}
+	if info.Owner != "averyveryver" {
+		t.Errorf("owner %q, want it truncated to %d characters", info.Owner, maxOwnerLength)
+	}
+}
+
+func TestParseResourceName(t *testing.T) {
+	created := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	for _, tt := range []struct {
+		name   string
+		ok     bool
+		want   ResourceNameInfo
+		mantle bool
+	}{
+		{
+			name:   "kola-jdoe-20260102150405-1a2b3c4d5e6f",
+			ok:     true,
+			want:   ResourceNameInfo{Purpose: "kola", Owner: "jdoe", Created: created},
+			mantle: true,
+		},
+		{
+			name:   "kola-cluster-jdoe-20260102150405-1a2b3c4d5e6f",
+			ok:     true,
+			want:   ResourceNameInfo{Purpose: "kola-cluster", Owner: "jdoe", Created: created},
+			mantle: true,
+		},
+		{
+			name:   "ore-0b1e4a2c-jdoe-20260102150405-1a2b3c4d5e6f",
+			ok:     true,
+			want:   ResourceNameInfo{Purpose: "ore-0b1e4a2c", Owner: "jdoe", Created: created},
+			mantle: true,
+		},
+		{
+			// Someone else's resource of the same form
+			name:   "backup-ops-20260102150405-1a2b3c4d5e6f",
+			ok:     true,
+			want:   ResourceNameInfo{Purpose: "backup", Owner: "ops", Created: created},
+			mantle: false,
+		},
+		{
+			// Shares a prefix with a purpose but isn't one
+			name:   "kolas-jdoe-20260102150405-1a2b3c4d5e6f",
+			ok:     true,
+			want:   ResourceNameInfo{Purpose: "kolas", Owner: "jdoe", Created: created},
+			mantle: false,
+		},
+		// Legacy names
+		{name: "kola-0b1e4a2c-64f6-4d7e-9b3a-2c6d8e0f1a2b"},
+		{name: "kola-cluster-1f0e9d8c7b6a5948"},
+		{name: "mantle-1767366245-abcd"},
+		// Near misses
+		{name: "kola-jdoe-20260102150405"},
+		{name: "jdoe-20260102150405-1a2b3c4d5e6f"},
+		{name: "kola-jdoe-20260102150405-1a2b3c4d"},
+		{name: "kola-jdoe-20260102150405-1a2b3c4d5e6g"},
+		{name: "kola-jdoe-20261302150405-1a2b3c4d5e6f"},
+		{name: "kola-jdoe-2026010215040-1a2b3c4d5e6f"},
+		{name: "kola-JDoe-20260102150405-1a2b3c4d5e6f"},
+		{name: "kola--20260102150405-1a2b3c4d5e6f"},
+		{name: "-jdoe-20260102150405-1a2b3c4d5e6f"},
+		{name: "kola_x-jdoe-20260102150405-1a2b3c4d5e6f"},
+	} {
+		info, ok := ParseResourceName(tt.name)
+		if ok != tt.ok {
+			t.Errorf("ParseResourceName(%q) ok = %v, want %v", tt.name, ok, tt.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if info != tt.want {
+			t.Errorf("ParseResourceName(%q) = %+v, want %+v", tt.name, info, tt.want)
+		}
+		if info.FromMantle() != tt.mantle {
+			t.Errorf("ParseResourceName(%q).FromMantle() = %v, want %v", tt.name, info.FromMantle(), tt.mantle)
+		}
+	}
+}
@@ -15,11 +15,13 @@
 package util
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/pkg/errors"
 
@@ -36,6 +38,13 @@ type LocalBuild struct {
 	Dir  string
 	Arch string
 	Meta *cosa.Build
+	// RemoteURL, if set, is the URL of the build's directory in an object
+	// store, from which missing artifacts are fetched.
+	RemoteURL string
+
+	// verifyLock guards verified, the checks of the artifacts required.
+	verifyLock sync.Mutex
+	verified   map[string]*artifactCheck
 }
 
 func IsCosaRoot(root string) (bool, error) {
@@ -122,3 +131,49 @@ func GetLocalBuild(root, buildid, arch string) (*LocalBuild, error) {
 		Meta: cosameta,
 	}, nil
 }
+
+// GetLocalBuildArches returns the architectures the build was built for,
+// sorted.
+func GetLocalBuildArches(root, buildid string) ([]string, error) {
+	if err := RequireCosaRoot(root); err != nil {
+		return nil, err
+	}
+	ents, err := os.ReadDir(filepath.Join(root, "builds", buildid))
+	if err != nil {
+		return nil, err
+	}
+	var arches []string
+	for _, ent := range ents {
+		if !ent.IsDir() {
+			continue
+		}
+		if exists, err := PathExists(filepath.Join(root, "builds", buildid, ent.Name(), "meta.json")); err != nil {
+			return nil, err
+		} else if exists {
+			arches = append(arches, ent.Name())
+		}
+	}
+	return arches, nil
+}
+
+// PackageVersion returns the version of the package in the build, from the
+// rpmdb package list of its commitmeta.json, or "" if it has none.
+func (b *LocalBuild) PackageVersion(name string) (string, error) {
+	buf, err := os.ReadFile(filepath.Join(b.Dir, "commitmeta.json"))
+	if err != nil {
+		return "", err
+	}
+	var meta struct {
+		// Each package is [name, epoch, version, release, arch]
+		Packages [][]string `json:"rpmostree.rpmdb.pkglist"`
+	}
+	if err := json.Unmarshal(buf, &meta); err != nil {
+		return "", errors.Wrapf(err, "parsing commitmeta.json")
+	}
+	for _, pkg := range meta.Packages {
+		if len(pkg) == 5 && pkg[0] == name {
+			return pkg[2], nil
+		}
+	}
+	return "", nil
+}
@@ -15,10 +15,90 @@
 package util
 
 import (
+	"context"
 	"fmt"
+	"math"
+	"math/rand"
 	"time"
 )
 
+// Backoff retries a function with exponentially growing delays, for
+// talking to flaky APIs. The zero value retries immediately and forever,
+// so at least one of MaxAttempts, MaxElapsed or a cancellable context
+// should bound it.
+type Backoff struct {
+	// Delay is the delay before the first retry.
+	Delay time.Duration
+	// Multiplier scales the delay after each retry; 0 means 2, and 1
+	// keeps it constant.
+	Multiplier float64
+	// MaxDelay, if non-zero, caps the delay.
+	MaxDelay time.Duration
+	// Jitter randomizes each delay by up to this fraction of it either
+	// way, so that clients retrying together spread out.
+	Jitter float64
+	// MaxAttempts, if non-zero, limits how many times f is called.
+	MaxAttempts int
+	// MaxElapsed, if non-zero, limits the time spent; no retry is
+	// started which would begin after it.
+	MaxElapsed time.Duration
+	// ShouldRetry, if set, returns whether an error is worth retrying;
+	// others are returned immediately.
+	ShouldRetry func(err error) bool
+	// OnRetry, if set, is called with the number of the failed attempt,
+	// from 1, its error and the delay before the next one. Otherwise,
+	// retries are logged at debug level.
+	OnRetry func(attempt int, err error, delay time.Duration)
+}
+
+// Do calls f until it succeeds, returns an error ShouldRetry rejects, or
+// the limits are reached, and returns the last error. If ctx is done
+// while waiting to retry, its error is returned, wrapping the last one.
+func (b Backoff) Do(ctx context.Context, f func() error) error {
+	start := time.Now()
+	multiplier := b.Multiplier
+	if multiplier == 0 {
+		multiplier = 2
+	}
+	delay := b.Delay
+	for attempt := 1; ; attempt++ {
+		err := f()
+		if err == nil || (b.ShouldRetry != nil && !b.ShouldRetry(err)) {
+			return err
+		}
+		if b.MaxAttempts > 0 && attempt >= b.MaxAttempts {
+			return err
+		}
+
+		wait := delay
+		if b.MaxDelay > 0 && wait > b.MaxDelay {
+			wait = b.MaxDelay
+		}
+		if b.Jitter > 0 {
+			wait += time.Duration(b.Jitter * (2*rand.Float64() - 1) * float64(wait))
+		}
+		if b.MaxElapsed > 0 && time.Since(start)+wait > b.MaxElapsed {
+			return err
+		}
+		if b.OnRetry != nil {
+			b.OnRetry(attempt, err, wait)
+		} else {
+			plog.Debugf("Attempt %d failed, retrying in %v: %v", attempt, wait.Round(time.Millisecond), err)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w; last error: %w", ctx.Err(), err)
+		case <-timer.C:
+		}
+		if next := float64(delay) * multiplier; (b.MaxDelay == 0 || delay < b.MaxDelay) && next < math.MaxInt64 {
+			delay = time.Duration(next)
+		}
+	}
+}
+
 // Retry calls function f until it has been called attemps times, or succeeds.
 // Retry delays for delay between calls of f. If f does not succeed after
 // attempts calls, the error from the last call is returned.
@@ -32,20 +112,16 @@ func Retry(attempts int, delay time.Duration, f func() error) error {
 // If shouldRetry returns false on the error generated, RetryConditional stops immediately
 // and returns the error
 func RetryConditional(attempts int, delay time.Duration, shouldRetry func(err error) bool, f func() error) error {
-	var err error
-
-	for i := 0; i < attempts; i++ {
-		err = f()
-		if err == nil || !shouldRetry(err) {
-			break
-		}
-
-		if i < attempts-1 {
-			time.Sleep(delay)
-		}
+	if attempts <= 0 {
+		return nil
 	}
-
-	return err
+	return Backoff{
+		Delay:       delay,
+		Multiplier:  1,
+		MaxAttempts: attempts,
+		ShouldRetry: shouldRetry,
+		OnRetry:     func(int, error, time.Duration) {},
+	}.Do(context.Background(), f)
 }
 
 // RetryUntilTimeout calls function f until it succeeds or until
@@ -0,0 +1,190 @@
+// Copyright 2026 Red Hat
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var errFlaky = errors.New("flaky")
+
+// failing returns a function failing the first n calls, and a pointer to
+// the number of calls made.
+func failing(n int) (func() error, *int) {
+	calls := 0
+	return func() error {
+		calls++
+		if calls <= n {
+			return errFlaky
+		}
+		return nil
+	}, &calls
+}
+
+func TestBackoffAttempts(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		failures   int
+		backoff    Backoff
+		wantErr    bool
+		wantCalls  int
+		wantDelays []time.Duration
+	}{
+		{
+			name:       "succeeds",
+			failures:   2,
+			backoff:    Backoff{Delay: time.Millisecond, MaxAttempts: 5},
+			wantCalls:  3,
+			wantDelays: []time.Duration{time.Millisecond, 2 * time.Millisecond},
+		},
+		{
+			name:       "attempt limit",
+			failures:   10,
+			backoff:    Backoff{Delay: time.Millisecond, MaxAttempts: 3},
+			wantErr:    true,
+			wantCalls:  3,
+			wantDelays: []time.Duration{time.Millisecond, 2 * time.Millisecond},
+		},
+		{
+			name:       "max delay",
+			failures:   10,
+			backoff:    Backoff{Delay: time.Millisecond, Multiplier: 3, MaxDelay: 5 * time.Millisecond, MaxAttempts: 4},
+			wantErr:    true,
+			wantCalls:  4,
+			wantDelays: []time.Duration{time.Millisecond, 3 * time.Millisecond, 5 * time.Millisecond},
+		},
+		{
+			name:      "single attempt",
+			failures:  10,
+			backoff:   Backoff{Delay: time.Hour, MaxAttempts: 1},
+			wantErr:   true,
+			wantCalls: 1,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			f, calls := failing(tt.failures)
+			var delays []time.Duration
+			tt.backoff.OnRetry = func(attempt int, err error, delay time.Duration) {
+				if attempt != len(delays)+1 {
+					t.Errorf("OnRetry got attempt %d, expected %d", attempt, len(delays)+1)
+				}
+				delays = append(delays, delay)
+			}
+			err := tt.backoff.Do(context.Background(), f)
+			if tt.wantErr != (err != nil) {
+				t.Errorf("got error %v, expected error %v", err, tt.wantErr)
+			}
+			if *calls != tt.wantCalls {
+				t.Errorf("got %d calls, expected %d", *calls, tt.wantCalls)
+			}
+			if !reflect.DeepEqual(delays, tt.wantDelays) {
+				t.Errorf("got delays %v, expected %v", delays, tt.wantDelays)
+			}
+		})
+	}
+}
+
+func TestBackoffShouldRetry(t *testing.T) {
+	errPermanent := errors.New("permanent")
+	calls := 0
+	err := Backoff{
+		Delay:       time.Hour,
+		ShouldRetry: func(err error) bool { return err != errPermanent },
+	}.Do(context.Background(), func() error {
+		calls++
+		return errPermanent
+	})
+	if err != errPermanent {
+		t.Errorf("got error %v, expected %v", err, errPermanent)
+	}
+	if calls != 1 {
+		t.Errorf("got %d calls, expected 1", calls)
+	}
+}
+
+func TestBackoffMaxElapsed(t *testing.T) {
+	// A retry which would start after MaxElapsed isn't waited for.
+	f, calls := failing(10)
+	start := time.Now()
+	err := Backoff{Delay: time.Hour, MaxElapsed: time.Minute}.Do(context.Background(), f)
+	if err != errFlaky {
+		t.Errorf("got error %v, expected %v", err, errFlaky)
+	}
+	if *calls != 1 {
+		t.Errorf("got %d calls, expected 1", *calls)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("took %v", elapsed)
+	}
+
+	// The second retry would start at 60ms at the earliest.
+	f, calls = failing(10)
+	err = Backoff{Delay: 20 * time.Millisecond, MaxElapsed: 50 * time.Millisecond}.Do(context.Background(), f)
+	if err != errFlaky {
+		t.Errorf("got error %v, expected %v", err, errFlaky)
+	}
+	if *calls != 2 {
+		t.Errorf("got %d calls, expected 2", *calls)
+	}
+}
+
+func TestBackoffContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	f, calls := failing(10)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	err := Backoff{Delay: time.Hour}.Do(ctx, f)
+	if !errors.Is(err, context.Canceled) || !errors.Is(err, errFlaky) {
+		t.Errorf("got error %v, expected it to wrap %v and %v", err, context.Canceled, errFlaky)
+	}
+	if *calls != 1 {
+		t.Errorf("got %d calls, expected 1", *calls)
+	}
+}
+
+func TestRetryConditional(t *testing.T) {
+	if err := RetryConditional(0, time.Hour, nil, func() error { return errFlaky }); err != nil {
+		t.Errorf("no attempts: got error %v", err)
+	}
+
+	// The delay stays fixed rather than growing; doubling would take
+	// 20+40+80ms.
+	const delay = 20 * time.Millisecond
+	f, calls := failing(10)
+	start := time.Now()
+	err := RetryConditional(4, delay, func(error) bool { return true }, f)
+	elapsed := time.Since(start)
+	if err != errFlaky {
+		t.Errorf("got error %v, expected %v", err, errFlaky)
+	}
+	if *calls != 4 {
+		t.Errorf("got %d calls, expected 4", *calls)
+	}
+	if elapsed < 3*delay || elapsed >= 6*delay {
+		t.Errorf("took %v, expected about %v", elapsed, 3*delay)
+	}
+
+	f, calls = failing(10)
+	err = RetryConditional(4, time.Hour, func(error) bool { return false }, f)
+	if err != errFlaky || *calls != 1 {
+		t.Errorf("got error %v after %d calls, expected %v after 1", err, *calls, errFlaky)
+	}
+}
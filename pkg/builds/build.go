@@ -200,6 +200,17 @@ func (build *Build) GetArtifact(artifact string) (*Artifact, error) {
 	return nil, errors.New("artifact " + artifact + " not defined")
 }
 
+// Artifacts returns the artifacts defined in the build by JSON tag.
+func (build *Build) Artifacts() map[string]*Artifact {
+	ret := make(map[string]*Artifact)
+	for name, a := range build.artifacts() {
+		if a.Path != "" {
+			ret[name] = a
+		}
+	}
+	return ret
+}
+
 // IsArtifact takes a path and returns the artifact type and a bool if
 // the artifact is described in the build.
 func (build *Build) IsArtifact(path string) (string, bool) {
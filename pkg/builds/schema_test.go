@@ -120,6 +120,9 @@ func TestArtifact(t *testing.T) {
 		if _, err = b.GetArtifact("aws"); err != nil {
 			t.Fatalf("Failed to get artifact: %v", err)
 		}
+		if _, ok := b.Artifacts()["aws"]; !ok {
+			t.Fatalf("aws artifact not listed")
+		}
 	}
 }
 